@@ -0,0 +1,257 @@
+package datadog
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/VictoriaMetrics/metrics"
+	"github.com/valyala/fastjson"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vlinsert/insertutils"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vlstorage"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/httpserver"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logstorage"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/protoparser/common"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/writeconcurrencylimiter"
+)
+
+// defaultStreamFields contains stream fields for logs ingested via Datadog protocol
+// if `_stream_fields` query arg isn't set.
+var defaultStreamFields = []string{"ddsource", "service", "hostname"}
+
+// RequestHandler processes Datadog insert requests
+func RequestHandler(path string, w http.ResponseWriter, r *http.Request) bool {
+	switch path {
+	case "/api/v2/logs":
+		return handleInsert(r, w)
+	case "/api/v1/validate":
+		// Return fake response for Datadog API key validation request.
+		// See https://docs.datadoghq.com/api/latest/authentication/#validate-api-key
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"valid":true}`)
+		return true
+	default:
+		return false
+	}
+}
+
+// See https://docs.datadoghq.com/api/latest/logs/#send-logs
+func handleInsert(r *http.Request, w http.ResponseWriter) bool {
+	startTime := time.Now()
+	requestsTotal.Inc()
+
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return true
+	}
+
+	reader := r.Body
+	switch r.Header.Get("Content-Encoding") {
+	case "gzip":
+		zr, err := common.GetGzipReader(reader)
+		if err != nil {
+			httpserver.Errorf(w, r, "cannot initialize gzip reader: %s", err)
+			return true
+		}
+		defer common.PutGzipReader(zr)
+		reader = zr
+	case "deflate":
+		zlr, err := common.GetZlibReader(reader)
+		if err != nil {
+			httpserver.Errorf(w, r, "cannot initialize deflate reader: %s", err)
+			return true
+		}
+		defer common.PutZlibReader(zlr)
+		reader = zlr
+	}
+
+	wcr := writeconcurrencylimiter.GetReader(reader)
+	data, err := io.ReadAll(wcr)
+	writeconcurrencylimiter.PutReader(wcr)
+	if err != nil {
+		httpserver.Errorf(w, r, "cannot read request body: %s", err)
+		return true
+	}
+
+	cp, err := insertutils.GetCommonParams(r)
+	if err != nil {
+		httpserver.Errorf(w, r, "cannot parse common params from request: %s", err)
+		return true
+	}
+	if len(cp.StreamFields) == 0 {
+		cp.StreamFields = defaultStreamFields
+	}
+	msgField := "message"
+	if cp.MsgField != "" {
+		msgField = cp.MsgField
+	}
+	if err := vlstorage.CanWriteData(); err != nil {
+		httpserver.Errorf(w, r, "%s", err)
+		return true
+	}
+
+	lr := logstorage.GetLogRows(cp.StreamFields, cp.IgnoreFields)
+	processLogMessage := cp.GetProcessLogMessageFunc(lr)
+	n, err := parseJSONRequest(data, msgField, processLogMessage)
+	vlstorage.MustAddRows(lr)
+	logstorage.PutLogRows(lr)
+	if err != nil {
+		httpserver.Errorf(w, r, "cannot parse Datadog logs request: %s", err)
+		return true
+	}
+
+	rowsIngestedTotal.Add(n)
+
+	// Datadog API responds with 202 Accepted and empty JSON object on success.
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	fmt.Fprintf(w, `{}`)
+
+	// update requestDuration only for successfully parsed requests
+	// There is no need in updating requestDuration for request errors,
+	// since their timings are usually much smaller than the timing for successful request parsing.
+	requestDuration.UpdateDuration(startTime)
+
+	return true
+}
+
+var (
+	requestsTotal     = metrics.NewCounter(`vl_http_requests_total{path="/insert/datadog/api/v2/logs"}`)
+	rowsIngestedTotal = metrics.NewCounter(`vl_rows_ingested_total{type="datadog"}`)
+	requestDuration   = metrics.NewHistogram(`vl_http_request_duration_seconds{path="/insert/datadog/api/v2/logs"}`)
+)
+
+var parserPool fastjson.ParserPool
+
+// parseJSONRequest parses Datadog logs from data and passes them to processLogMessage.
+//
+// data must contain either a JSON array of log entries or a single log entry.
+// Nested objects in log entries are flattened in the same way as for JSON stream API.
+// The value of msgField is stored in the _msg field, while `ddtags` are split into separate fields.
+func parseJSONRequest(data []byte, msgField string, processLogMessage func(timestamp int64, fields []logstorage.Field)) (int, error) {
+	p := parserPool.Get()
+	defer parserPool.Put(p)
+	v, err := p.ParseBytes(data)
+	if err != nil {
+		return 0, fmt.Errorf("cannot parse JSON request body: %w", err)
+	}
+
+	var entries []*fastjson.Value
+	switch v.Type() {
+	case fastjson.TypeArray:
+		entries, _ = v.Array()
+	case fastjson.TypeObject:
+		entries = append(entries, v)
+	default:
+		return 0, fmt.Errorf("request body must contain an array of log entries; got %q", v)
+	}
+
+	jp := logstorage.GetJSONParser()
+	defer logstorage.PutJSONParser(jp)
+
+	currentTimestamp := time.Now().UnixNano()
+	var buf []byte
+	var fields []logstorage.Field
+	var tagsBuf []byte
+	for i, e := range entries {
+		if e.Type() != fastjson.TypeObject {
+			return i, fmt.Errorf("unexpected log entry #%d; want JSON object; got %q", i, e)
+		}
+		buf = e.MarshalTo(buf[:0])
+		if err := jp.ParseLogMessage(buf); err != nil {
+			return i, fmt.Errorf("cannot parse log entry #%d: %w", i, err)
+		}
+
+		ts := int64(0)
+		fields = fields[:0]
+		tagsBuf = tagsBuf[:0]
+		for _, f := range jp.Fields {
+			switch f.Name {
+			case "timestamp":
+				ts, err = parseDatadogTimestamp(f.Value)
+				if err != nil {
+					return i, fmt.Errorf("cannot parse timestamp for log entry #%d: %w", i, err)
+				}
+			case "ddtags":
+				// See https://docs.datadoghq.com/getting_started/tagging/#define-tags
+				fields, tagsBuf = appendTagFields(fields, tagsBuf, f.Value)
+			case msgField:
+				fields = append(fields, logstorage.Field{
+					Name:  "_msg",
+					Value: f.Value,
+				})
+			default:
+				fields = append(fields, f)
+			}
+		}
+		if len(tagsBuf) > 0 {
+			fields = append(fields, logstorage.Field{
+				Name:  "ddtags",
+				Value: bytesutil.ToUnsafeString(tagsBuf),
+			})
+		}
+		if ts == 0 {
+			ts = currentTimestamp
+		}
+		processLogMessage(ts, fields)
+	}
+	return len(entries), nil
+}
+
+// appendTagFields appends fields for `name:value` tags from comma-separated tags to dst.
+//
+// Tags without values are appended to comma-separated tagsBuf.
+func appendTagFields(dst []logstorage.Field, tagsBuf []byte, tags string) ([]logstorage.Field, []byte) {
+	for _, tag := range strings.Split(tags, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(tag, ":")
+		if !ok || name == "" {
+			if len(tagsBuf) > 0 {
+				tagsBuf = append(tagsBuf, ',')
+			}
+			tagsBuf = append(tagsBuf, tag...)
+			continue
+		}
+		dst = append(dst, logstorage.Field{
+			Name:  name,
+			Value: value,
+		})
+	}
+	return dst, tagsBuf
+}
+
+func parseDatadogTimestamp(s string) (int64, error) {
+	if s == "0" || s == "" {
+		// Special case - zero or empty timestamp must be substituted
+		// with the current time by the caller.
+		return 0, nil
+	}
+	if len(s) < len("YYYY-MM-DD") || s[len("YYYY")] != '-' {
+		// Try parsing timestamp in milliseconds
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("cannot parse timestamp in milliseconds from %q: %w", s, err)
+		}
+		if n > int64(math.MaxInt64)/1e6 {
+			return 0, fmt.Errorf("too big timestamp in milliseconds: %d; mustn't exceed %d", n, int64(math.MaxInt64)/1e6)
+		}
+		if n < int64(math.MinInt64)/1e6 {
+			return 0, fmt.Errorf("too small timestamp in milliseconds: %d; must be bigger than %d", n, int64(math.MinInt64)/1e6)
+		}
+		return n * 1e6, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return 0, fmt.Errorf("cannot parse timestamp %q: %w", s, err)
+	}
+	return t.UnixNano(), nil
+}
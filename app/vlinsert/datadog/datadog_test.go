@@ -0,0 +1,111 @@
+package datadog
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logstorage"
+)
+
+func TestParseJSONRequestFailure(t *testing.T) {
+	f := func(s string) {
+		t.Helper()
+		n, err := parseJSONRequest([]byte(s), "message", func(_ int64, _ []logstorage.Field) {
+			t.Fatalf("unexpected call to parseJSONRequest callback!")
+		})
+		if err == nil {
+			t.Fatalf("expecting non-nil error")
+		}
+		if n != 0 {
+			t.Fatalf("unexpected number of parsed lines: %d; want 0", n)
+		}
+	}
+	f(``)
+
+	// Invalid json
+	f(`{`)
+	f(`"foo"`)
+	f(`123`)
+
+	// Invalid type for log entry
+	f(`["foo"]`)
+	f(`[123]`)
+
+	// Invalid timestamp
+	f(`[{"message":"foo","timestamp":"bar"}]`)
+	f(`[{"message":"foo","timestamp":"2024-13-01T00:00:00Z"}]`)
+}
+
+func TestParseJSONRequestSuccess(t *testing.T) {
+	f := func(s, msgField string, resultExpected string) {
+		t.Helper()
+		var lines []string
+		n, err := parseJSONRequest([]byte(s), msgField, func(timestamp int64, fields []logstorage.Field) {
+			var a []string
+			for _, f := range fields {
+				a = append(a, f.String())
+			}
+			line := fmt.Sprintf("_time:%d %s", timestamp, strings.Join(a, " "))
+			lines = append(lines, line)
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if n != len(lines) {
+			t.Fatalf("unexpected number of lines parsed; got %d; want %d", n, len(lines))
+		}
+		result := strings.Join(lines, "\n")
+		if result != resultExpected {
+			t.Fatalf("unexpected result;\ngot\n%s\nwant\n%s", result, resultExpected)
+		}
+	}
+
+	// Empty array
+	f(`[]`, "message", ``)
+
+	// Single log entry outside the array
+	f(`{"message":"foo bar","timestamp":1577836800001}`, "message", `_time:1577836800001000000 "_msg":"foo bar"`)
+
+	// Log entries sent by Datadog Agent
+	f(`[
+	{
+		"message": "GET /foo 200",
+		"status": "info",
+		"timestamp": 1577836800001,
+		"hostname": "host-1",
+		"service": "nginx",
+		"ddsource": "nginx",
+		"ddtags": "env:prod,version:1.2,canary, team:core"
+	},
+	{
+		"message": "error",
+		"timestamp": "2020-01-01T00:00:05Z",
+		"hostname": "host-2",
+		"service": "app",
+		"attrs": {"user": "x", "n": 1}
+	}
+]`, "message", `_time:1577836800001000000 "_msg":"GET /foo 200" "status":"info" "hostname":"host-1" "service":"nginx" "ddsource":"nginx" "env":"prod" "version":"1.2" "team":"core" "ddtags":"canary"
+_time:1577836805000000000 "_msg":"error" "hostname":"host-2" "service":"app" "attrs.user":"x" "attrs.n":"1"`)
+
+	// Custom message field
+	f(`[{"message":"foo","log":"bar","timestamp":1577836800001}]`, "log", `_time:1577836800001000000 "message":"foo" "_msg":"bar"`)
+}
+
+func TestParseDatadogTimestamp(t *testing.T) {
+	f := func(s string, timestampExpected int64) {
+		t.Helper()
+		timestamp, err := parseDatadogTimestamp(s)
+		if err != nil {
+			t.Fatalf("unexpected error when parsing %q: %s", s, err)
+		}
+		if timestamp != timestampExpected {
+			t.Fatalf("unexpected timestamp for %q; got %d; want %d", s, timestamp, timestampExpected)
+		}
+	}
+	f("", 0)
+	f("0", 0)
+	f("1577836800001", 1577836800001000000)
+	f("2020-01-01T00:00:00.5Z", 1577836800500000000)
+	f("2020-01-01T02:00:00+02:00", 1577836800000000000)
+}
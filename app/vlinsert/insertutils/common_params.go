@@ -72,7 +72,10 @@ func GetCommonParams(r *http.Request) (*CommonParams, error) {
 }
 
 // GetProcessLogMessageFunc returns a function, which adds parsed log messages to lr.
+//
+// The ingestion rules from -insert.rulesFile are applied to log messages before adding them to lr.
 func (cp *CommonParams) GetProcessLogMessageFunc(lr *logstorage.LogRows) func(timestamp int64, fields []logstorage.Field) {
+	var irc logstorage.IngestRulesContext
 	return func(timestamp int64, fields []logstorage.Field) {
 		if len(fields) > *MaxFieldsPerLine {
 			rf := logstorage.RowFormatter(fields)
@@ -81,7 +84,17 @@ func (cp *CommonParams) GetProcessLogMessageFunc(lr *logstorage.LogRows) func(ti
 			return
 		}
 
-		lr.MustAdd(cp.TenantID, timestamp, fields)
+		tenantID := cp.TenantID
+		if irs := ingestRulesGlobal.Load(); irs != nil {
+			if !irs.Apply(&irc, tenantID, timestamp, fields, cp.StreamFields) {
+				rowsDroppedTotalIngestRules.Inc()
+				return
+			}
+			tenantID = irc.TenantID
+			fields = irc.Fields
+		}
+
+		lr.MustAdd(tenantID, timestamp, fields)
 		if cp.Debug {
 			s := lr.GetRowString(0)
 			lr.ResetKeepSettings()
@@ -98,3 +111,4 @@ func (cp *CommonParams) GetProcessLogMessageFunc(lr *logstorage.LogRows) func(ti
 
 var rowsDroppedTotalDebug = metrics.NewCounter(`vl_rows_dropped_total{reason="debug"}`)
 var rowsDroppedTotalTooManyFields = metrics.NewCounter(`vl_rows_dropped_total{reason="too_many_fields"}`)
+var rowsDroppedTotalIngestRules = metrics.NewCounter(`vl_rows_dropped_total{reason="ingest_rules"}`)
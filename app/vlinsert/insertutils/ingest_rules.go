@@ -0,0 +1,81 @@
+package insertutils
+
+import (
+	"flag"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/VictoriaMetrics/metrics"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fasttime"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fs/fscore"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logstorage"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/procutil"
+)
+
+var ingestRulesFile = flag.String("insert.rulesFile", "", "Optional path to a file with rules for transforming the ingested log entries before storing them. "+
+	"The path can point either to local file or to http url. "+
+	"See https://docs.victoriametrics.com/VictoriaLogs/data-ingestion/#ingestion-rules . The file is reloaded on SIGHUP signal")
+
+// MustInitIngestRules loads ingestion rules from -insert.rulesFile.
+//
+// It must be called after flag.Parse and before processing the ingested logs.
+func MustInitIngestRules() {
+	// Register SIGHUP handler for config re-read just before loadIngestRules call.
+	// This guarantees that the config will be re-read if the signal arrives during loadIngestRules call.
+	sighupCh := procutil.NewSighupChan()
+
+	irs, err := loadIngestRules()
+	if err != nil {
+		logger.Fatalf("cannot load ingestion rules: %s", err)
+	}
+	ingestRulesGlobal.Store(irs)
+	ingestRulesConfigSuccess.Set(1)
+	ingestRulesConfigTimestamp.Set(fasttime.UnixTimestamp())
+
+	if *ingestRulesFile == "" {
+		return
+	}
+	go func() {
+		for range sighupCh {
+			ingestRulesConfigReloads.Inc()
+			logger.Infof("received SIGHUP; reloading -insert.rulesFile=%q...", *ingestRulesFile)
+			irs, err := loadIngestRules()
+			if err != nil {
+				ingestRulesConfigReloadErrors.Inc()
+				ingestRulesConfigSuccess.Set(0)
+				logger.Errorf("cannot load the updated ingestion rules: %s; preserving the previous rules", err)
+				continue
+			}
+			ingestRulesGlobal.Store(irs)
+			ingestRulesConfigSuccess.Set(1)
+			ingestRulesConfigTimestamp.Set(fasttime.UnixTimestamp())
+			logger.Infof("successfully reloaded -insert.rulesFile=%q", *ingestRulesFile)
+		}
+	}()
+}
+
+var (
+	ingestRulesConfigReloads      = metrics.NewCounter(`vl_ingest_rules_config_reloads_total`)
+	ingestRulesConfigReloadErrors = metrics.NewCounter(`vl_ingest_rules_config_reloads_errors_total`)
+	ingestRulesConfigSuccess      = metrics.NewGauge(`vl_ingest_rules_config_last_reload_successful`, nil)
+	ingestRulesConfigTimestamp    = metrics.NewCounter(`vl_ingest_rules_config_last_reload_success_timestamp_seconds`)
+)
+
+var ingestRulesGlobal atomic.Pointer[logstorage.IngestRules]
+
+func loadIngestRules() (*logstorage.IngestRules, error) {
+	if *ingestRulesFile == "" {
+		return nil, nil
+	}
+	data, err := fscore.ReadFileOrHTTP(*ingestRulesFile)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read -insert.rulesFile=%q: %w", *ingestRulesFile, err)
+	}
+	irs, err := logstorage.ParseIngestRules(data)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse -insert.rulesFile=%q: %w", *ingestRulesFile, err)
+	}
+	return irs, nil
+}
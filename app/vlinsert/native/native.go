@@ -0,0 +1,137 @@
+package native
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/VictoriaMetrics/metrics"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vlstorage"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/encoding"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/flagutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/httpserver"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logstorage"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/writeconcurrencylimiter"
+)
+
+var maxBlockSize = flagutil.NewBytes("insert.maxNativeBlockSize", 64*1024*1024, "The maximum size of a single compressed block, which can be read by /insert/native handler")
+
+// RequestHandler processes /insert/native requests.
+//
+// The request body must contain logs exported via /select/logsql/export/native.
+//
+// See https://docs.victoriametrics.com/victorialogs/data-ingestion/#native-format
+func RequestHandler(w http.ResponseWriter, r *http.Request) bool {
+	startTime := time.Now()
+
+	if r.Method != "POST" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return true
+	}
+
+	requestsTotal.Inc()
+
+	tenantID, err := logstorage.GetTenantIDFromRequest(r)
+	if err != nil {
+		httpserver.Errorf(w, r, "%s", err)
+		return true
+	}
+	if err := vlstorage.CanWriteData(); err != nil {
+		httpserver.Errorf(w, r, "%s", err)
+		return true
+	}
+
+	if err := processRequest(r, tenantID); err != nil {
+		httpserver.Errorf(w, r, "%s", err)
+		return true
+	}
+
+	// update requestDuration only for successfully parsed requests.
+	// There is no need in updating requestDuration for request errors,
+	// since their timings are usually much smaller than the timing for successful request parsing.
+	requestDuration.UpdateDuration(startTime)
+
+	w.WriteHeader(http.StatusNoContent)
+	return true
+}
+
+func processRequest(r *http.Request, tenantID logstorage.TenantID) error {
+	wcr := writeconcurrencylimiter.GetReader(r.Body)
+	defer writeconcurrencylimiter.PutReader(wcr)
+	br := bufio.NewReader(wcr)
+
+	lr := logstorage.GetLogRows(nil, nil)
+	defer logstorage.PutLogRows(lr)
+
+	frame := bbPool.Get()
+	defer bbPool.Put(frame)
+	block := bbPool.Get()
+	defer bbPool.Put(block)
+
+	// The successfully read blocks are stored even if the request contains invalid blocks,
+	// since the client may have sent them before the error occurred at the source.
+	defer vlstorage.MustAddRows(lr)
+
+	for n := 0; ; n++ {
+		ok, err := readBlock(br, frame, block)
+		wcr.DecConcurrency()
+		if err != nil {
+			return fmt.Errorf("cannot read block #%d in /insert/native request: %w", n, err)
+		}
+		if !ok {
+			return nil
+		}
+		rowsLen := lr.Len()
+		if err := lr.AddNativeBlock(tenantID, block.B); err != nil {
+			return fmt.Errorf("cannot unmarshal block #%d in /insert/native request: %w", n, err)
+		}
+		rowsIngestedTotal.Add(lr.Len() - rowsLen)
+
+		if lr.NeedFlush() {
+			vlstorage.MustAddRows(lr)
+			lr.ResetKeepSettings()
+		}
+	}
+}
+
+// readBlock reads the next block from br into block.
+//
+// frame is used as a temporary buffer. false is returned if br has no more blocks.
+func readBlock(br *bufio.Reader, frame, block *bytesutil.ByteBuffer) (bool, error) {
+	var sizeBuf [8]byte
+	if _, err := io.ReadFull(br, sizeBuf[:]); err != nil {
+		if errors.Is(err, io.EOF) {
+			return false, nil
+		}
+		return false, fmt.Errorf("cannot read block size: %w", err)
+	}
+	size := encoding.UnmarshalUint64(sizeBuf[:])
+	if size > uint64(maxBlockSize.IntN()) {
+		return false, fmt.Errorf("too big block size: %d bytes; it mustn't exceed -insert.maxNativeBlockSize=%d bytes", size, maxBlockSize.IntN())
+	}
+
+	frame.B = bytesutil.ResizeNoCopyNoOverallocate(frame.B, int(size))
+	if _, err := io.ReadFull(br, frame.B); err != nil {
+		return false, fmt.Errorf("cannot read block with size %d bytes: %w", size, err)
+	}
+
+	var err error
+	block.B, err = encoding.DecompressZSTD(block.B[:0], frame.B)
+	if err != nil {
+		return false, fmt.Errorf("cannot decompress block: %w", err)
+	}
+	return true, nil
+}
+
+var bbPool bytesutil.ByteBufferPool
+
+var (
+	requestsTotal     = metrics.NewCounter(`vl_http_requests_total{path="/insert/native"}`)
+	rowsIngestedTotal = metrics.NewCounter(`vl_rows_ingested_total{type="native"}`)
+	requestDuration   = metrics.NewHistogram(`vl_http_request_duration_seconds{path="/insert/native"}`)
+)
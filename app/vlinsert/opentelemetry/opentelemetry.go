@@ -0,0 +1,196 @@
+package opentelemetry
+
+import (
+	"io"
+	"mime"
+	"net/http"
+	"time"
+
+	"github.com/VictoriaMetrics/metrics"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vlinsert/insertutils"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vlstorage"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/httpserver"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logstorage"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/protoparser/common"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/protoparser/opentelemetry/pb"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/writeconcurrencylimiter"
+)
+
+// RequestHandler processes OpenTelemetry insert requests
+func RequestHandler(path string, w http.ResponseWriter, r *http.Request) bool {
+	switch path {
+	case "/v1/logs":
+		return handleInsert(r, w)
+	default:
+		return false
+	}
+}
+
+// See https://opentelemetry.io/docs/specs/otlp/#otlphttp-request
+func handleInsert(r *http.Request, w http.ResponseWriter) bool {
+	startTime := time.Now()
+	requestsTotal.Inc()
+
+	isJSON := isJSONContentType(r.Header.Get("Content-Type"))
+
+	reader := r.Body
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		zr, err := common.GetGzipReader(reader)
+		if err != nil {
+			httpserver.Errorf(w, r, "cannot initialize gzip reader: %s", err)
+			return true
+		}
+		defer common.PutGzipReader(zr)
+		reader = zr
+	}
+
+	wcr := writeconcurrencylimiter.GetReader(reader)
+	data, err := io.ReadAll(wcr)
+	writeconcurrencylimiter.PutReader(wcr)
+	if err != nil {
+		httpserver.Errorf(w, r, "cannot read request body: %s", err)
+		return true
+	}
+
+	cp, err := insertutils.GetCommonParams(r)
+	if err != nil {
+		httpserver.Errorf(w, r, "cannot parse common params from request: %s", err)
+		return true
+	}
+	if err := vlstorage.CanWriteData(); err != nil {
+		httpserver.Errorf(w, r, "%s", err)
+		return true
+	}
+
+	var req pb.ExportLogsServiceRequest
+	if isJSON {
+		err = req.UnmarshalJSON(data)
+	} else {
+		err = req.UnmarshalProtobuf(data)
+	}
+	if err != nil {
+		httpserver.Errorf(w, r, "cannot parse OpenTelemetry logs request: %s", err)
+		return true
+	}
+
+	n := pushExportLogsServiceRequest(&req, cp)
+	rowsIngestedTotal.Add(n)
+
+	if isJSON {
+		// The response must be encoded in the same format as the request.
+		// See https://opentelemetry.io/docs/specs/otlp/#otlphttp-response
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte("{}"))
+	} else {
+		w.Header().Set("Content-Type", "application/x-protobuf")
+	}
+
+	// update requestDuration only for successfully parsed requests
+	// There is no need in updating requestDuration for request errors,
+	// since their timings are usually much smaller than the timing for successful request parsing.
+	requestDuration.UpdateDuration(startTime)
+
+	return true
+}
+
+var (
+	requestsTotal     = metrics.NewCounter(`vl_http_requests_total{path="/insert/opentelemetry/v1/logs"}`)
+	rowsIngestedTotal = metrics.NewCounter(`vl_rows_ingested_total{type="opentelemetry"}`)
+	requestDuration   = metrics.NewHistogram(`vl_http_request_duration_seconds{path="/insert/opentelemetry/v1/logs"}`)
+)
+
+// pushExportLogsServiceRequest pushes log records from req to the storage and returns the number of pushed log records.
+//
+// Resource attributes are used as stream fields unless cp.StreamFields is set.
+// isJSONContentType returns true if the given Content-Type header value corresponds to JSON-encoded OTLP request.
+//
+// The value may contain optional parameters such as `application/json; charset=utf-8`.
+func isJSONContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json"
+}
+
+func pushExportLogsServiceRequest(req *pb.ExportLogsServiceRequest, cp *insertutils.CommonParams) int {
+	rowsIngested := 0
+	var streamFields []string
+	for _, rl := range req.ResourceLogs {
+		streamFields = streamFields[:0]
+		if len(cp.StreamFields) > 0 {
+			streamFields = append(streamFields, cp.StreamFields...)
+		} else if rl.Resource != nil {
+			for _, a := range rl.Resource.Attributes {
+				streamFields = append(streamFields, a.Key)
+			}
+		}
+
+		lr := logstorage.GetLogRows(streamFields, cp.IgnoreFields)
+		processLogMessage := cp.GetProcessLogMessageFunc(lr)
+		rowsIngested += pushResourceLogs(rl, processLogMessage)
+		vlstorage.MustAddRows(lr)
+		logstorage.PutLogRows(lr)
+	}
+	return rowsIngested
+}
+
+// pushResourceLogs calls processLogMessage for every log record at rl and returns the number of processed log records.
+func pushResourceLogs(rl *pb.ResourceLogs, processLogMessage func(timestamp int64, fields []logstorage.Field)) int {
+	var commonFields []logstorage.Field
+	if rl.Resource != nil {
+		commonFields = appendAttributesToFields(commonFields, rl.Resource.Attributes)
+	}
+
+	rowsIngested := 0
+	fields := commonFields
+	currentTimestamp := time.Now().UnixNano()
+	for _, sl := range rl.ScopeLogs {
+		for _, lr := range sl.LogRecords {
+			fields = fields[:len(commonFields)]
+			if lr.Body != nil {
+				fields = append(fields, logstorage.Field{
+					Name:  "_msg",
+					Value: lr.Body.FormatString(),
+				})
+			}
+			fields = appendAttributesToFields(fields, lr.Attributes)
+			fields = appendFieldIfNotEmpty(fields, "severity", lr.FormatSeverity())
+			fields = appendFieldIfNotEmpty(fields, "trace_id", lr.TraceID)
+			fields = appendFieldIfNotEmpty(fields, "span_id", lr.SpanID)
+
+			ts := lr.ExtractTimestampNano()
+			if ts == 0 {
+				ts = currentTimestamp
+			}
+			processLogMessage(ts, fields)
+			rowsIngested++
+		}
+	}
+	return rowsIngested
+}
+
+func appendAttributesToFields(dst []logstorage.Field, attributes []*pb.KeyValue) []logstorage.Field {
+	for _, a := range attributes {
+		value := ""
+		if a.Value != nil {
+			value = a.Value.FormatString()
+		}
+		dst = append(dst, logstorage.Field{
+			Name:  a.Key,
+			Value: value,
+		})
+	}
+	return dst
+}
+
+func appendFieldIfNotEmpty(dst []logstorage.Field, name, value string) []logstorage.Field {
+	if value == "" {
+		return dst
+	}
+	return append(dst, logstorage.Field{
+		Name:  name,
+		Value: value,
+	})
+}
@@ -0,0 +1,158 @@
+package opentelemetry
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logstorage"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/protoparser/opentelemetry/pb"
+)
+
+func TestPushResourceLogs(t *testing.T) {
+	f := func(data, resultExpected string) {
+		t.Helper()
+
+		// Verify JSON request
+		var req pb.ExportLogsServiceRequest
+		if err := req.UnmarshalJSON([]byte(data)); err != nil {
+			t.Fatalf("cannot unmarshal JSON request: %s", err)
+		}
+		result := pushRequestToString(&req)
+		if result != resultExpected {
+			t.Fatalf("unexpected result for JSON request;\ngot\n%s\nwant\n%s", result, resultExpected)
+		}
+
+		// Verify protobuf request
+		protoData := req.MarshalProtobuf(nil)
+		var reqProto pb.ExportLogsServiceRequest
+		if err := reqProto.UnmarshalProtobuf(protoData); err != nil {
+			t.Fatalf("cannot unmarshal protobuf request: %s", err)
+		}
+		result = pushRequestToString(&reqProto)
+		if result != resultExpected {
+			t.Fatalf("unexpected result for protobuf request;\ngot\n%s\nwant\n%s", result, resultExpected)
+		}
+	}
+
+	// Empty request
+	f(`{}`, ``)
+	f(`{"resourceLogs":[]}`, ``)
+	f(`{"resourceLogs":[{"scopeLogs":[{"logRecords":[]}]}]}`, ``)
+
+	// Log record without resource attributes
+	f(`{"resourceLogs":[{"scopeLogs":[{"logRecords":[{"timeUnixNano":"1234","body":{"stringValue":"foo bar"}}]}]}]}`,
+		`1234 {"_msg":"foo bar"}`)
+
+	// Log record with resource attributes, log attributes and well-known fields
+	f(`{"resourceLogs":[{
+		"resource":{"attributes":[{"key":"service.name","value":{"stringValue":"app"}},{"key":"host","value":{"stringValue":"h1"}}]},
+		"scopeLogs":[{"logRecords":[
+			{"timeUnixNano":1234,"severityNumber":9,"body":{"stringValue":"first"},"attributes":[{"key":"code","value":{"intValue":"42"}},{"key":"ok","value":{"boolValue":false}}],"traceId":"5B8EFFF798038103D269B633813FC60C","spanId":"EEE19B7EC3C1B174"},
+			{"observedTimeUnixNano":"5678","severityNumber":18,"severityText":"Error","body":{"doubleValue":1.5}}
+		]}]
+	}]}`, `1234 {"service.name":"app","host":"h1","_msg":"first","code":"42","ok":"false","severity":"INFO","trace_id":"5b8efff798038103d269b633813fc60c","span_id":"eee19b7ec3c1b174"}
+5678 {"service.name":"app","host":"h1","_msg":"1.5","severity":"Error"}`)
+
+	// Multiple resources
+	f(`{"resourceLogs":[
+		{"resource":{"attributes":[{"key":"a","value":{"stringValue":"b"}}]},"scopeLogs":[{"logRecords":[{"timeUnixNano":"1","body":{"stringValue":"x"}}]}]},
+		{"resource":{"attributes":[{"key":"c","value":{"stringValue":"d"}}]},"scopeLogs":[{"logRecords":[{"timeUnixNano":"2","body":{"stringValue":"y"},"attributes":[{"key":"n","value":{"intValue":1}}]}]}]}
+	]}`, `1 {"a":"b","_msg":"x"}
+2 {"c":"d","_msg":"y","n":"1"}`)
+}
+
+func TestUnmarshalJSONFailure(t *testing.T) {
+	f := func(data string) {
+		t.Helper()
+
+		var req pb.ExportLogsServiceRequest
+		if err := req.UnmarshalJSON([]byte(data)); err == nil {
+			t.Fatalf("expecting non-nil error")
+		}
+	}
+
+	// invalid JSON
+	f(``)
+	f(`{`)
+	f(`[]`)
+
+	// invalid resourceLogs
+	f(`{"resourceLogs":{}}`)
+	f(`{"resourceLogs":[{"scopeLogs":{}}]}`)
+	f(`{"resourceLogs":[{"resource":{"attributes":{}}}]}`)
+
+	// invalid logRecords
+	f(`{"resourceLogs":[{"scopeLogs":[{"logRecords":{}}]}]}`)
+	f(`{"resourceLogs":[{"scopeLogs":[{"logRecords":[{"timeUnixNano":"foo"}]}]}]}`)
+	f(`{"resourceLogs":[{"scopeLogs":[{"logRecords":[{"timeUnixNano":true}]}]}]}`)
+	f(`{"resourceLogs":[{"scopeLogs":[{"logRecords":[{"traceId":"xyz"}]}]}]}`)
+	f(`{"resourceLogs":[{"scopeLogs":[{"logRecords":[{"body":"foo"}]}]}]}`)
+	f(`{"resourceLogs":[{"scopeLogs":[{"logRecords":[{"body":{"intValue":"foo"}}]}]}]}`)
+	f(`{"resourceLogs":[{"scopeLogs":[{"logRecords":[{"body":{"bytesValue":"!!!"}}]}]}]}`)
+}
+
+func TestUnmarshalProtobufFailure(t *testing.T) {
+	var req pb.ExportLogsServiceRequest
+	if err := req.UnmarshalProtobuf([]byte("foobar")); err == nil {
+		t.Fatalf("expecting non-nil error")
+	}
+}
+
+func TestSeverityNumberString(t *testing.T) {
+	f := func(sn pb.SeverityNumber, resultExpected string) {
+		t.Helper()
+
+		result := sn.String()
+		if result != resultExpected {
+			t.Fatalf("unexpected result for %d; got %q; want %q", sn, result, resultExpected)
+		}
+	}
+
+	f(0, "")
+	f(1, "TRACE")
+	f(4, "TRACE4")
+	f(5, "DEBUG")
+	f(9, "INFO")
+	f(10, "INFO2")
+	f(13, "WARN")
+	f(17, "ERROR")
+	f(21, "FATAL")
+	f(24, "FATAL4")
+	f(25, "")
+}
+
+func TestIsJSONContentType(t *testing.T) {
+	f := func(contentType string, resultExpected bool) {
+		t.Helper()
+
+		result := isJSONContentType(contentType)
+		if result != resultExpected {
+			t.Fatalf("unexpected result for %q; got %v; want %v", contentType, result, resultExpected)
+		}
+	}
+
+	f("application/json", true)
+	f("application/json; charset=utf-8", true)
+	f("Application/JSON;charset=UTF-8", true)
+
+	f("", false)
+	f("application/x-protobuf", false)
+	f("application/jsonl", false)
+	f("application/json; charset", false)
+}
+
+func pushRequestToString(req *pb.ExportLogsServiceRequest) string {
+	var a []string
+	processLogMessage := func(timestamp int64, fields []logstorage.Field) {
+		fieldsStr := make([]string, len(fields))
+		for i, f := range fields {
+			fieldsStr[i] = fmt.Sprintf("%q:%q", f.Name, f.Value)
+		}
+		a = append(a, fmt.Sprintf("%d {%s}", timestamp, strings.Join(fieldsStr, ",")))
+	}
+	for _, rl := range req.ResourceLogs {
+		pushResourceLogs(rl, processLogMessage)
+	}
+	return strings.Join(a, "\n")
+}
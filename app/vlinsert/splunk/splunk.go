@@ -0,0 +1,519 @@
+package splunk
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/VictoriaMetrics/metrics"
+	"github.com/valyala/fastjson"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vlinsert/insertutils"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vlstorage"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/httpserver"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logstorage"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/protoparser/common"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/writeconcurrencylimiter"
+)
+
+// defaultStreamFields contains stream fields for logs ingested via Splunk HEC protocol
+// if `_stream_fields` query arg isn't set.
+var defaultStreamFields = []string{"host", "source", "sourcetype"}
+
+// metadataFields contains the names of Splunk event metadata fields, which are stored as log fields.
+//
+// See https://docs.splunk.com/Documentation/Splunk/latest/Data/FormateventsforHTTPEventCollector#Event_metadata
+var metadataFields = []string{"host", "source", "sourcetype", "index"}
+
+// Splunk HEC status codes.
+//
+// See https://docs.splunk.com/Documentation/Splunk/latest/Data/TroubleshootHTTPEventCollector#Possible_error_codes
+const (
+	codeSuccess           = 0
+	codeNoData            = 5
+	codeInvalidDataFormat = 6
+	codeServerBusy        = 9
+	codeEventRequired     = 12
+	codeEventBlank        = 13
+	codeHealthy           = 17
+)
+
+// RequestHandler processes Splunk HTTP Event Collector insert requests
+//
+// See https://docs.splunk.com/Documentation/Splunk/latest/Data/HECRESTendpoints
+func RequestHandler(path string, w http.ResponseWriter, r *http.Request) bool {
+	path = strings.TrimSuffix(path, "/1.0")
+	switch path {
+	case "/services/collector", "/services/collector/event":
+		return handleEvent(r, w)
+	case "/services/collector/raw":
+		return handleRaw(r, w)
+	case "/services/collector/health":
+		writeResponse(w, http.StatusOK, codeHealthy, "HEC is healthy")
+		return true
+	case "/services/collector/ack":
+		return handleAck(r, w)
+	default:
+		return false
+	}
+}
+
+func handleEvent(r *http.Request, w http.ResponseWriter) bool {
+	startTime := time.Now()
+	eventRequestsTotal.Inc()
+
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return true
+	}
+
+	reader, cleanup, err := getBodyReader(r)
+	if err != nil {
+		httpserver.Errorf(w, r, "%s", err)
+		return true
+	}
+	defer cleanup()
+
+	wcr := writeconcurrencylimiter.GetReader(reader)
+	data, err := io.ReadAll(wcr)
+	writeconcurrencylimiter.PutReader(wcr)
+	if err != nil {
+		httpserver.Errorf(w, r, "cannot read request body: %s", err)
+		return true
+	}
+
+	cp, err := getCommonParams(r)
+	if err != nil {
+		httpserver.Errorf(w, r, "cannot parse common params from request: %s", err)
+		return true
+	}
+	if err := vlstorage.CanWriteData(); err != nil {
+		writeResponse(w, http.StatusServiceUnavailable, codeServerBusy, err.Error())
+		return true
+	}
+	if len(data) == 0 {
+		writeResponse(w, http.StatusBadRequest, codeNoData, "No data")
+		return true
+	}
+
+	lr := logstorage.GetLogRows(cp.StreamFields, cp.IgnoreFields)
+	processLogMessage := cp.GetProcessLogMessageFunc(lr)
+	n, err := parseEventRequest(data, cp.MsgField, processLogMessage)
+	vlstorage.MustAddRows(lr)
+	logstorage.PutLogRows(lr)
+	eventRowsIngestedTotal.Add(n)
+	if err != nil {
+		var pe *parseError
+		if !errors.As(err, &pe) {
+			pe = &parseError{
+				code: codeInvalidDataFormat,
+				text: "Invalid data format",
+				err:  err,
+			}
+		}
+		logger.Warnf("remoteAddr: %s; requestURI: %s; cannot parse Splunk event #%d: %s", httpserver.GetQuotedRemoteAddr(r), httpserver.GetRequestURI(r), n, pe.err)
+		writeInvalidEventResponse(w, pe.code, pe.text, n)
+		return true
+	}
+
+	writeSuccessResponse(w, r)
+
+	// update eventRequestDuration only for successfully parsed requests
+	// There is no need in updating eventRequestDuration for request errors,
+	// since their timings are usually much smaller than the timing for successful request parsing.
+	eventRequestDuration.UpdateDuration(startTime)
+
+	return true
+}
+
+func handleRaw(r *http.Request, w http.ResponseWriter) bool {
+	startTime := time.Now()
+	rawRequestsTotal.Inc()
+
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return true
+	}
+
+	reader, cleanup, err := getBodyReader(r)
+	if err != nil {
+		httpserver.Errorf(w, r, "%s", err)
+		return true
+	}
+	defer cleanup()
+
+	cp, err := getCommonParams(r)
+	if err != nil {
+		httpserver.Errorf(w, r, "cannot parse common params from request: %s", err)
+		return true
+	}
+	if err := vlstorage.CanWriteData(); err != nil {
+		writeResponse(w, http.StatusServiceUnavailable, codeServerBusy, err.Error())
+		return true
+	}
+
+	// Event metadata for raw events is passed via query args.
+	var commonFields []logstorage.Field
+	for _, name := range metadataFields {
+		if v := r.FormValue(name); v != "" {
+			commonFields = append(commonFields, logstorage.Field{
+				Name:  name,
+				Value: v,
+			})
+		}
+	}
+
+	lr := logstorage.GetLogRows(cp.StreamFields, cp.IgnoreFields)
+	processLogMessage := cp.GetProcessLogMessageFunc(lr)
+	n, err := readRawRequest(reader, commonFields, processLogMessage)
+	vlstorage.MustAddRows(lr)
+	logstorage.PutLogRows(lr)
+	rawRowsIngestedTotal.Add(n)
+	if err != nil {
+		logger.Warnf("remoteAddr: %s; requestURI: %s; cannot read raw Splunk event #%d: %s", httpserver.GetQuotedRemoteAddr(r), httpserver.GetRequestURI(r), n, err)
+		writeInvalidEventResponse(w, codeInvalidDataFormat, "Invalid data format", n)
+		return true
+	}
+
+	writeSuccessResponse(w, r)
+
+	// update rawRequestDuration only for successfully parsed requests
+	// There is no need in updating rawRequestDuration for request errors,
+	// since their timings are usually much smaller than the timing for successful request parsing.
+	rawRequestDuration.UpdateDuration(startTime)
+
+	return true
+}
+
+// handleAck processes indexer acknowledgement requests.
+//
+// Logs are added to the storage before the response is returned to the client,
+// so all the requested acks are reported as successful.
+//
+// See https://docs.splunk.com/Documentation/Splunk/latest/Data/AboutHECIDXAck
+func handleAck(r *http.Request, w http.ResponseWriter) bool {
+	ackRequestsTotal.Inc()
+
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		httpserver.Errorf(w, r, "cannot read request body: %s", err)
+		return true
+	}
+	p := parserPool.Get()
+	defer parserPool.Put(p)
+	v, err := p.ParseBytes(data)
+	if err != nil {
+		writeResponse(w, http.StatusBadRequest, codeInvalidDataFormat, "Invalid data format")
+		return true
+	}
+	acks := v.GetArray("acks")
+
+	w.Header().Set("Content-Type", "application/json")
+	fmt.Fprintf(w, `{"acks":{`)
+	n := 0
+	for _, ack := range acks {
+		id, err := ack.Uint64()
+		if err != nil {
+			continue
+		}
+		if n > 0 {
+			fmt.Fprintf(w, `,`)
+		}
+		fmt.Fprintf(w, `"%d":true`, id)
+		n++
+	}
+	fmt.Fprintf(w, `}}`)
+	return true
+}
+
+var (
+	eventRequestsTotal     = metrics.NewCounter(`vl_http_requests_total{path="/insert/splunk/services/collector/event"}`)
+	eventRowsIngestedTotal = metrics.NewCounter(`vl_rows_ingested_total{type="splunk",format="event"}`)
+	eventRequestDuration   = metrics.NewHistogram(`vl_http_request_duration_seconds{path="/insert/splunk/services/collector/event"}`)
+
+	rawRequestsTotal     = metrics.NewCounter(`vl_http_requests_total{path="/insert/splunk/services/collector/raw"}`)
+	rawRowsIngestedTotal = metrics.NewCounter(`vl_rows_ingested_total{type="splunk",format="raw"}`)
+	rawRequestDuration   = metrics.NewHistogram(`vl_http_request_duration_seconds{path="/insert/splunk/services/collector/raw"}`)
+
+	ackRequestsTotal = metrics.NewCounter(`vl_http_requests_total{path="/insert/splunk/services/collector/ack"}`)
+)
+
+func getCommonParams(r *http.Request) (*insertutils.CommonParams, error) {
+	cp, err := insertutils.GetCommonParams(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(cp.StreamFields) == 0 {
+		cp.StreamFields = defaultStreamFields
+	}
+	return cp, nil
+}
+
+func getBodyReader(r *http.Request) (io.Reader, func(), error) {
+	if r.Header.Get("Content-Encoding") != "gzip" {
+		return r.Body, func() {}, nil
+	}
+	zr, err := common.GetGzipReader(r.Body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot initialize gzip reader: %w", err)
+	}
+	return zr, func() { common.PutGzipReader(zr) }, nil
+}
+
+// writeSuccessResponse writes successful response to w.
+//
+// The response contains ackId if the request contains a channel, since Splunk forwarders
+// with enabled indexer acknowledgement expect it.
+func writeSuccessResponse(w http.ResponseWriter, r *http.Request) {
+	channel := r.Header.Get("X-Splunk-Request-Channel")
+	if channel == "" {
+		channel = r.FormValue("channel")
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if channel == "" {
+		fmt.Fprintf(w, `{"text":"Success","code":%d}`, codeSuccess)
+		return
+	}
+	fmt.Fprintf(w, `{"text":"Success","code":%d,"ackId":%d}`, codeSuccess, nextAckID.Add(1))
+}
+
+func writeInvalidEventResponse(w http.ResponseWriter, code int, text string, eventNumber int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	fmt.Fprintf(w, `{"text":%q,"code":%d,"invalid-event-number":%d}`, text, code, eventNumber)
+}
+
+func writeResponse(w http.ResponseWriter, statusCode, code int, text string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	fmt.Fprintf(w, `{"text":%q,"code":%d}`, text, code)
+}
+
+var nextAckID atomic.Uint64
+
+var parserPool fastjson.ParserPool
+
+// parseError is returned from parseEventRequest for events with invalid contents.
+type parseError struct {
+	code int
+	text string
+	err  error
+}
+
+func (pe *parseError) Error() string {
+	return pe.err.Error()
+}
+
+// parseEventRequest parses Splunk HEC events from data and passes them to processLogMessage.
+//
+// It returns the number of successfully parsed events.
+//
+// See https://docs.splunk.com/Documentation/Splunk/latest/Data/FormateventsforHTTPEventCollector
+func parseEventRequest(data []byte, msgField string, processLogMessage func(timestamp int64, fields []logstorage.Field)) (int, error) {
+	jp := logstorage.GetJSONParser()
+	defer logstorage.PutJSONParser(jp)
+
+	currentTimestamp := time.Now().UnixNano()
+	var buf []byte
+	var fields []logstorage.Field
+
+	// Events are concatenated JSON objects, which may be delimited by whitespace.
+	var sc fastjson.Scanner
+	sc.InitBytes(data)
+	n := 0
+	for sc.Next() {
+		v := sc.Value()
+		if v.Type() != fastjson.TypeObject {
+			return n, fmt.Errorf("unexpected event; want JSON object; got %q", v)
+		}
+
+		// Parse event
+		fields = fields[:0]
+		buf = buf[:0]
+		ev := v.Get("event")
+		if ev == nil {
+			return n, &parseError{
+				code: codeEventRequired,
+				text: "Event field is required",
+				err:  fmt.Errorf("missing `event` field in %q", v),
+			}
+		}
+		switch ev.Type() {
+		case fastjson.TypeString:
+			msg := ev.GetStringBytes()
+			if len(msg) == 0 {
+				return n, &parseError{
+					code: codeEventBlank,
+					text: "Event field cannot be blank",
+					err:  fmt.Errorf("empty `event` field in %q", v),
+				}
+			}
+			fields = append(fields, logstorage.Field{
+				Name:  "_msg",
+				Value: bytesutil.ToUnsafeString(msg),
+			})
+		case fastjson.TypeObject:
+			// Structured event - flatten it in the same way as JSON stream API does.
+			buf = ev.MarshalTo(buf)
+			if err := jp.ParseLogMessage(buf); err != nil {
+				return n, fmt.Errorf("cannot parse `event` object: %w", err)
+			}
+			if msgField != "" {
+				jp.RenameField(msgField, "_msg")
+			}
+			fields = append(fields, jp.Fields...)
+		default:
+			start := len(buf)
+			buf = ev.MarshalTo(buf)
+			fields = append(fields, logstorage.Field{
+				Name:  "_msg",
+				Value: bytesutil.ToUnsafeString(buf[start:]),
+			})
+		}
+
+		// Parse event metadata
+		for _, name := range metadataFields {
+			mv := v.Get(name)
+			if mv == nil {
+				continue
+			}
+			var value string
+			value, buf = getStringValue(buf, mv)
+			fields = append(fields, logstorage.Field{
+				Name:  name,
+				Value: value,
+			})
+		}
+
+		// Parse indexed fields
+		if fv := v.Get("fields"); fv != nil {
+			o, err := fv.Object()
+			if err != nil {
+				return n, fmt.Errorf("`fields` must contain an object; got %q", fv)
+			}
+			o.Visit(func(k []byte, v *fastjson.Value) {
+				var value string
+				value, buf = getStringValue(buf, v)
+				fields = append(fields, logstorage.Field{
+					Name:  bytesutil.ToUnsafeString(k),
+					Value: value,
+				})
+			})
+		}
+
+		// Parse timestamp
+		ts := int64(0)
+		if tv := v.Get("time"); tv != nil {
+			var s string
+			s, buf = getStringValue(buf, tv)
+			t, err := parseSplunkTimestamp(s)
+			if err != nil {
+				return n, fmt.Errorf("cannot parse `time` field: %w", err)
+			}
+			ts = t
+		}
+		if ts == 0 {
+			ts = currentTimestamp
+		}
+
+		processLogMessage(ts, fields)
+		n++
+	}
+	if err := sc.Error(); err != nil {
+		return n, fmt.Errorf("cannot parse JSON event: %w", err)
+	}
+	return n, nil
+}
+
+// getStringValue returns string representation for v.
+//
+// Strings are returned as is, while other values are marshaled to JSON at the end of buf.
+func getStringValue(buf []byte, v *fastjson.Value) (string, []byte) {
+	if v.Type() == fastjson.TypeString {
+		return bytesutil.ToUnsafeString(v.GetStringBytes()), buf
+	}
+	start := len(buf)
+	buf = v.MarshalTo(buf)
+	return bytesutil.ToUnsafeString(buf[start:]), buf
+}
+
+// parseSplunkTimestamp parses Unix timestamp in seconds with optional fractional part.
+//
+// Zero is returned for empty or zero timestamp - the caller must substitute it with the current time.
+func parseSplunkTimestamp(s string) (int64, error) {
+	if s == "" || s == "0" {
+		return 0, nil
+	}
+	secsStr, fracStr, _ := strings.Cut(s, ".")
+	secs, err := strconv.ParseInt(secsStr, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("cannot parse timestamp in seconds from %q: %w", s, err)
+	}
+	if secs < 0 || secs > math.MaxInt64/1_000_000_000-1 {
+		return 0, fmt.Errorf("timestamp %q is out of allowed range", s)
+	}
+	nsecs := int64(0)
+	if fracStr != "" {
+		if len(fracStr) > 9 {
+			fracStr = fracStr[:9]
+		}
+		frac, err := strconv.ParseUint(fracStr, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("cannot parse fractional part of timestamp %q: %w", s, err)
+		}
+		nsecs = int64(frac)
+		for i := len(fracStr); i < 9; i++ {
+			nsecs *= 10
+		}
+	}
+	return secs*1e9 + nsecs, nil
+}
+
+// readRawRequest reads raw events from r and passes them to processLogMessage.
+//
+// Every non-empty line is stored as a separate log entry with the current timestamp.
+func readRawRequest(r io.Reader, commonFields []logstorage.Field, processLogMessage func(timestamp int64, fields []logstorage.Field)) (int, error) {
+	wcr := writeconcurrencylimiter.GetReader(r)
+	defer writeconcurrencylimiter.PutReader(wcr)
+
+	lb := lineBufferPool.Get()
+	defer lineBufferPool.Put(lb)
+
+	lb.B = bytesutil.ResizeNoCopyNoOverallocate(lb.B, insertutils.MaxLineSizeBytes.IntN())
+	sc := bufio.NewScanner(wcr)
+	sc.Buffer(lb.B, len(lb.B))
+
+	fields := append([]logstorage.Field{}, commonFields...)
+	n := 0
+	for sc.Scan() {
+		wcr.DecConcurrency()
+		line := sc.Bytes()
+		if len(strings.TrimSpace(bytesutil.ToUnsafeString(line))) == 0 {
+			continue
+		}
+		fields = append(fields[:len(commonFields)], logstorage.Field{
+			Name:  "_msg",
+			Value: bytesutil.ToUnsafeString(line),
+		})
+		processLogMessage(time.Now().UnixNano(), fields)
+		n++
+	}
+	if err := sc.Err(); err != nil {
+		if errors.Is(err, bufio.ErrTooLong) {
+			return n, fmt.Errorf("cannot read raw event, since its size exceeds -insert.maxLineSizeBytes=%d", insertutils.MaxLineSizeBytes.IntN())
+		}
+		return n, err
+	}
+	return n, nil
+}
+
+var lineBufferPool bytesutil.ByteBufferPool
@@ -0,0 +1,143 @@
+package splunk
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logstorage"
+)
+
+func TestParseEventRequestFailure(t *testing.T) {
+	f := func(s string, codeExpected int) {
+		t.Helper()
+		n, err := parseEventRequest([]byte(s), "", func(_ int64, _ []logstorage.Field) {
+			t.Fatalf("unexpected call to parseEventRequest callback!")
+		})
+		if err == nil {
+			t.Fatalf("expecting non-nil error")
+		}
+		if n != 0 {
+			t.Fatalf("unexpected number of parsed events: %d; want 0", n)
+		}
+		code := codeInvalidDataFormat
+		var pe *parseError
+		if errors.As(err, &pe) {
+			code = pe.code
+		}
+		if code != codeExpected {
+			t.Fatalf("unexpected error code; got %d; want %d", code, codeExpected)
+		}
+	}
+
+	// Invalid json
+	f(`{`, codeInvalidDataFormat)
+	f(`"foo"`, codeInvalidDataFormat)
+	f(`[{"event":"foo"}]`, codeInvalidDataFormat)
+
+	// Missing or empty event
+	f(`{}`, codeEventRequired)
+	f(`{"host":"foo"}`, codeEventRequired)
+	f(`{"event":""}`, codeEventBlank)
+
+	// Invalid fields
+	f(`{"event":"foo","fields":"bar"}`, codeInvalidDataFormat)
+
+	// Invalid time
+	f(`{"event":"foo","time":"bar"}`, codeInvalidDataFormat)
+	f(`{"event":"foo","time":-1}`, codeInvalidDataFormat)
+}
+
+func TestParseEventRequestSuccess(t *testing.T) {
+	f := func(s, msgField string, resultExpected string) {
+		t.Helper()
+		var lines []string
+		n, err := parseEventRequest([]byte(s), msgField, func(timestamp int64, fields []logstorage.Field) {
+			var a []string
+			for _, f := range fields {
+				a = append(a, f.String())
+			}
+			line := fmt.Sprintf("_time:%d %s", timestamp, strings.Join(a, " "))
+			lines = append(lines, line)
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if n != len(lines) {
+			t.Fatalf("unexpected number of events parsed; got %d; want %d", n, len(lines))
+		}
+		result := strings.Join(lines, "\n")
+		if result != resultExpected {
+			t.Fatalf("unexpected result;\ngot\n%s\nwant\n%s", result, resultExpected)
+		}
+	}
+
+	// Empty request
+	f(``, "", ``)
+	f(" \n ", "", ``)
+
+	// String event with metadata
+	f(`{"time":1577836800.123,"host":"host-1","source":"/var/log/app.log","sourcetype":"app","index":"main","event":"foo bar"}`, "",
+		`_time:1577836800123000000 "_msg":"foo bar" "host":"host-1" "source":"/var/log/app.log" "sourcetype":"app" "index":"main"`)
+
+	// Multiple events without delimiters and with delimiters
+	f(`{"time":"1577836800","event":"foo"}{"time":1577836801.5,"event":"bar"}
+{"time":1577836802.000000001,"event":123}`, "", `_time:1577836800000000000 "_msg":"foo"
+_time:1577836801500000000 "_msg":"bar"
+_time:1577836802000000001 "_msg":"123"`)
+
+	// Structured event with indexed fields
+	f(`{"time":1577836800,"event":{"message":"foo","user":{"id":"x"}},"fields":{"env":"prod","n":1,"tags":["a","b"]}}`, "message",
+		`_time:1577836800000000000 "_msg":"foo" "user.id":"x" "env":"prod" "n":"1" "tags":"[\"a\",\"b\"]"`)
+	f(`{"time":1577836800,"event":{"message":"foo"}}`, "",
+		`_time:1577836800000000000 "message":"foo"`)
+}
+
+func TestParseSplunkTimestamp(t *testing.T) {
+	f := func(s string, timestampExpected int64) {
+		t.Helper()
+		timestamp, err := parseSplunkTimestamp(s)
+		if err != nil {
+			t.Fatalf("unexpected error when parsing %q: %s", s, err)
+		}
+		if timestamp != timestampExpected {
+			t.Fatalf("unexpected timestamp for %q; got %d; want %d", s, timestamp, timestampExpected)
+		}
+	}
+	f("", 0)
+	f("0", 0)
+	f("1577836800", 1577836800000000000)
+	f("1577836800.1", 1577836800100000000)
+	f("1577836800.123456789", 1577836800123456789)
+	f("1577836800.1234567891", 1577836800123456789)
+}
+
+func TestReadRawRequest(t *testing.T) {
+	commonFields := []logstorage.Field{
+		{
+			Name:  "host",
+			Value: "host-1",
+		},
+	}
+	var lines []string
+	n, err := readRawRequest(strings.NewReader("foo\n\n  \nbar baz\r\n"), commonFields, func(_ int64, fields []logstorage.Field) {
+		var a []string
+		for _, f := range fields {
+			a = append(a, f.String())
+		}
+		lines = append(lines, strings.Join(a, " "))
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if n != 2 {
+		t.Fatalf("unexpected number of events; got %d; want 2", n)
+	}
+	result := strings.Join(lines, "\n")
+	resultExpected := `"host":"host-1" "_msg":"foo"
+"host":"host-1" "_msg":"bar baz"`
+	if result != resultExpected {
+		t.Fatalf("unexpected result;\ngot\n%s\nwant\n%s", result, resultExpected)
+	}
+}
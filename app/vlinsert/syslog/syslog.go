@@ -0,0 +1,557 @@
+package syslog
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/VictoriaMetrics/metrics"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vlinsert/insertutils"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vlstorage"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/cgroup"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/flagutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/ingestserver"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logstorage"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/netutil"
+)
+
+var (
+	syslogTimezone = flag.String("syslog.timezone", "Local", "Timezone to use when parsing timestamps in RFC3164 syslog messages. Timezone must be a valid IANA Time Zone. "+
+		"For example: America/New_York, Europe/Berlin, Etc/GMT+3 . See https://docs.victoriametrics.com/victorialogs/data-ingestion/syslog/")
+
+	listenAddrTCP = flagutil.NewArrayString("syslog.listenAddr.tcp", "Comma-separated list of TCP addresses to listen to for Syslog messages. "+
+		"See https://docs.victoriametrics.com/victorialogs/data-ingestion/syslog/")
+	listenAddrUDP = flagutil.NewArrayString("syslog.listenAddr.udp", "Comma-separated list of UDP addresses to listen to for Syslog messages. "+
+		"See https://docs.victoriametrics.com/victorialogs/data-ingestion/syslog/")
+
+	tenantIDTCP = flagutil.NewArrayString("syslog.tenantID.tcp", "TenantID for logs ingested via the corresponding -syslog.listenAddr.tcp. "+
+		"See https://docs.victoriametrics.com/victorialogs/data-ingestion/syslog/#multitenancy")
+	tenantIDUDP = flagutil.NewArrayString("syslog.tenantID.udp", "TenantID for logs ingested via the corresponding -syslog.listenAddr.udp. "+
+		"See https://docs.victoriametrics.com/victorialogs/data-ingestion/syslog/#multitenancy")
+)
+
+// streamFields contains the fields, which are used as log stream fields for the ingested syslog messages.
+//
+// See https://docs.victoriametrics.com/victorialogs/keyconcepts/#stream-fields
+var streamFields = []string{"hostname", "app_name"}
+
+// flushInterval is the interval for flushing the buffered syslog messages to the storage.
+//
+// Syslog connections may be long-lived and may send messages at low rate,
+// so the buffered messages must be flushed periodically in order to make them visible for querying.
+const flushInterval = time.Second
+
+var (
+	timezone = time.Local
+
+	serversLock sync.Mutex
+	servers     []*server
+)
+
+// MustInit initializes syslog listeners according to the provided command-line flags.
+func MustInit() {
+	if *syslogTimezone != "" {
+		tz, err := time.LoadLocation(*syslogTimezone)
+		if err != nil {
+			logger.Fatalf("cannot parse -syslog.timezone=%q: %s", *syslogTimezone, err)
+		}
+		timezone = tz
+	}
+
+	serversLock.Lock()
+	defer serversLock.Unlock()
+
+	for argIdx, addr := range *listenAddrTCP {
+		tenantID := mustParseTenantID("-syslog.tenantID.tcp", tenantIDTCP.GetOptionalArg(argIdx))
+		servers = append(servers, mustStartTCPServer(addr, tenantID))
+	}
+	for argIdx, addr := range *listenAddrUDP {
+		tenantID := mustParseTenantID("-syslog.tenantID.udp", tenantIDUDP.GetOptionalArg(argIdx))
+		servers = append(servers, mustStartUDPServer(addr, tenantID))
+	}
+}
+
+// MustStop stops syslog listeners started at MustInit.
+func MustStop() {
+	serversLock.Lock()
+	defer serversLock.Unlock()
+
+	for _, s := range servers {
+		s.mustStop()
+	}
+	servers = nil
+}
+
+func mustParseTenantID(flagName, s string) logstorage.TenantID {
+	tenantID, err := logstorage.GetTenantIDFromString(s)
+	if err != nil {
+		logger.Fatalf("cannot parse %s=%q: %s", flagName, s, err)
+	}
+	return tenantID
+}
+
+type server struct {
+	addr     string
+	tenantID logstorage.TenantID
+
+	lnTCP net.Listener
+	lnUDP net.PacketConn
+
+	wg sync.WaitGroup
+	cm ingestserver.ConnsMap
+}
+
+func mustStartTCPServer(addr string, tenantID logstorage.TenantID) *server {
+	logger.Infof("starting TCP syslog server at %q for tenant %s", addr, &tenantID)
+	lnTCP, err := netutil.NewTCPListener("syslog", addr, false, nil)
+	if err != nil {
+		logger.Fatalf("cannot start TCP syslog server at %q: %s", addr, err)
+	}
+
+	s := &server{
+		addr:     addr,
+		tenantID: tenantID,
+		lnTCP:    lnTCP,
+	}
+	s.cm.Init("syslog")
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.serveTCP()
+		logger.Infof("stopped TCP syslog server at %q", addr)
+	}()
+	return s
+}
+
+func mustStartUDPServer(addr string, tenantID logstorage.TenantID) *server {
+	logger.Infof("starting UDP syslog server at %q for tenant %s", addr, &tenantID)
+	lnUDP, err := net.ListenPacket(netutil.GetUDPNetwork(), addr)
+	if err != nil {
+		logger.Fatalf("cannot start UDP syslog server at %q: %s", addr, err)
+	}
+
+	s := &server{
+		addr:     addr,
+		tenantID: tenantID,
+		lnUDP:    lnUDP,
+	}
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.serveUDP()
+		logger.Infof("stopped UDP syslog server at %q", addr)
+	}()
+	return s
+}
+
+func (s *server) mustStop() {
+	if s.lnTCP != nil {
+		logger.Infof("stopping TCP syslog server at %q...", s.addr)
+		if err := s.lnTCP.Close(); err != nil {
+			logger.Errorf("cannot close TCP syslog server: %s", err)
+		}
+		s.cm.CloseAll(0)
+	}
+	if s.lnUDP != nil {
+		logger.Infof("stopping UDP syslog server at %q...", s.addr)
+		if err := s.lnUDP.Close(); err != nil {
+			logger.Errorf("cannot close UDP syslog server: %s", err)
+		}
+	}
+	s.wg.Wait()
+	logger.Infof("syslog server at %q has been stopped", s.addr)
+}
+
+func (s *server) serveTCP() {
+	var wg sync.WaitGroup
+	for {
+		c, err := s.lnTCP.Accept()
+		if err != nil {
+			var ne net.Error
+			if errors.As(err, &ne) {
+				if ne.Temporary() {
+					logger.Errorf("syslog: temporary error when listening for TCP addr %q: %s", s.lnTCP.Addr(), err)
+					time.Sleep(time.Second)
+					continue
+				}
+				if strings.Contains(err.Error(), "use of closed network connection") {
+					break
+				}
+				logger.Fatalf("unrecoverable error when accepting TCP syslog connections: %s", err)
+			}
+			logger.Fatalf("unexpected error when accepting TCP syslog connections: %s", err)
+		}
+		if !s.cm.Add(c) {
+			_ = c.Close()
+			break
+		}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				s.cm.Delete(c)
+				_ = c.Close()
+				wg.Done()
+			}()
+			connsTCPTotal.Inc()
+			if err := processStream(c, s.tenantID); err != nil {
+				errorsTCPTotal.Inc()
+				logger.Errorf("error in TCP syslog conn %q<->%q: %s", c.LocalAddr(), c.RemoteAddr(), err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func (s *server) serveUDP() {
+	gomaxprocs := cgroup.AvailableCPUs()
+	var wg sync.WaitGroup
+	for i := 0; i < gomaxprocs; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			slp := newSyslogLinesProcessor(s.tenantID)
+			defer slp.mustStop()
+
+			var bb bytesutil.ByteBuffer
+			bb.B = bytesutil.ResizeNoCopyNoOverallocate(bb.B, 64*1024)
+			for {
+				bb.Reset()
+				bb.B = bb.B[:cap(bb.B)]
+				n, _, err := s.lnUDP.ReadFrom(bb.B)
+				if err != nil {
+					var ne net.Error
+					if errors.As(err, &ne) {
+						if ne.Temporary() {
+							errorsUDPTotal.Inc()
+							logger.Errorf("syslog: temporary error when listening for UDP addr %q: %s", s.lnUDP.LocalAddr(), err)
+							time.Sleep(time.Second)
+							continue
+						}
+						if strings.Contains(err.Error(), "use of closed network connection") {
+							break
+						}
+					}
+					errorsUDPTotal.Inc()
+					logger.Errorf("cannot read syslog UDP data: %s", err)
+					continue
+				}
+				bb.B = bb.B[:n]
+				packetsUDPTotal.Inc()
+
+				// Every UDP packet contains a single syslog message.
+				// See https://datatracker.ietf.org/doc/html/rfc5426#section-3.1
+				line := bytesutil.ToUnsafeString(bb.B)
+				line = strings.TrimRight(line, "\r\n")
+				slp.processLine(line)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// processStream parses syslog messages from r and stores them in the storage under the given tenantID.
+func processStream(r io.Reader, tenantID logstorage.TenantID) error {
+	slp := newSyslogLinesProcessor(tenantID)
+	defer slp.mustStop()
+
+	slr := getSyslogLineReader(r)
+	defer putSyslogLineReader(slr)
+
+	for slr.nextLine() {
+		slp.processLine(bytesutil.ToUnsafeString(slr.line))
+	}
+	return slr.Error()
+}
+
+// syslogLinesProcessor parses syslog lines and stores them in the storage.
+//
+// The parsed lines are buffered and are periodically flushed to the storage.
+type syslogLinesProcessor struct {
+	mu                sync.Mutex
+	lr                *logstorage.LogRows
+	processLogMessage func(timestamp int64, fields []logstorage.Field)
+
+	stopCh chan struct{}
+	wg     sync.WaitGroup
+}
+
+func newSyslogLinesProcessor(tenantID logstorage.TenantID) *syslogLinesProcessor {
+	cp := &insertutils.CommonParams{
+		TenantID:     tenantID,
+		StreamFields: streamFields,
+	}
+	lr := logstorage.GetLogRows(cp.StreamFields, cp.IgnoreFields)
+	slp := &syslogLinesProcessor{
+		lr:                lr,
+		processLogMessage: cp.GetProcessLogMessageFunc(lr),
+		stopCh:            make(chan struct{}),
+	}
+	slp.wg.Add(1)
+	go func() {
+		defer slp.wg.Done()
+		slp.runFlusher()
+	}()
+	return slp
+}
+
+func (slp *syslogLinesProcessor) runFlusher() {
+	t := time.NewTicker(flushInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-slp.stopCh:
+			return
+		case <-t.C:
+			slp.flush()
+		}
+	}
+}
+
+func (slp *syslogLinesProcessor) flush() {
+	slp.mu.Lock()
+	defer slp.mu.Unlock()
+
+	if slp.lr.Len() > 0 {
+		vlstorage.MustAddRows(slp.lr)
+		slp.lr.ResetKeepSettings()
+	}
+}
+
+func (slp *syslogLinesProcessor) mustStop() {
+	close(slp.stopCh)
+	slp.wg.Wait()
+
+	slp.flush()
+	logstorage.PutLogRows(slp.lr)
+	slp.lr = nil
+}
+
+func (slp *syslogLinesProcessor) processLine(line string) {
+	slp.mu.Lock()
+	defer slp.mu.Unlock()
+
+	if err := processLine(line, time.Now().In(timezone).Year(), timezone, slp.processLogMessage); err != nil {
+		parseErrorsTotal.Inc()
+		logger.Errorf("cannot process syslog message: %s", err)
+	}
+}
+
+func processLine(line string, currentYear int, timezone *time.Location, processLogMessage func(timestamp int64, fields []logstorage.Field)) error {
+	p := logstorage.GetSyslogParser(currentYear, timezone)
+	defer logstorage.PutSyslogParser(p)
+
+	p.Parse(line)
+	ts, err := extractTimestampFromFields("timestamp", p.Fields)
+	if err != nil {
+		return fmt.Errorf("cannot parse timestamp from syslog line %q: %w", line, err)
+	}
+	renameField(p.Fields, "message", "_msg")
+	processLogMessage(ts, p.Fields)
+	rowsIngestedTotal.Inc()
+
+	return nil
+}
+
+func extractTimestampFromFields(timeField string, fields []logstorage.Field) (int64, error) {
+	for i := range fields {
+		f := &fields[i]
+		if f.Name != timeField {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, f.Value)
+		if err != nil {
+			return 0, err
+		}
+		f.Value = ""
+		return t.UnixNano(), nil
+	}
+	return time.Now().UnixNano(), nil
+}
+
+func renameField(fields []logstorage.Field, oldName, newName string) {
+	for i := range fields {
+		f := &fields[i]
+		if f.Name == oldName {
+			f.Name = newName
+			return
+		}
+	}
+}
+
+// syslogLineReader reads syslog messages from the underlying reader.
+//
+// Both octet-counting and non-transparent framing are supported.
+// See https://datatracker.ietf.org/doc/html/rfc6587#section-3.4
+type syslogLineReader struct {
+	line []byte
+
+	br  *bufio.Reader
+	err error
+}
+
+func (slr *syslogLineReader) reset(r io.Reader) {
+	slr.line = slr.line[:0]
+	slr.br.Reset(r)
+	slr.err = nil
+}
+
+// Error returns the last error occurred in slr.
+func (slr *syslogLineReader) Error() error {
+	if slr.err == nil || slr.err == io.EOF {
+		return nil
+	}
+	return slr.err
+}
+
+// nextLine reads the next syslog line from slr and stores it at slr.line.
+//
+// false is returned if the next line cannot be read. Error() must be called in this case
+// in order to verify whether there is an error or just slr stream has been finished.
+func (slr *syslogLineReader) nextLine() bool {
+	if slr.err != nil {
+		return false
+	}
+
+	for {
+		if _, err := slr.br.Peek(1); err != nil {
+			slr.err = err
+			return false
+		}
+		if slr.isOctetCountedLine() {
+			// This is octet-counting method. See https://www.ietf.org/archive/id/draft-gerhards-syslog-plain-tcp-07.html#msgxfer
+			return slr.readOctetCountedLine()
+		}
+
+		// This is non-transparent framing. See https://www.ietf.org/archive/id/draft-gerhards-syslog-plain-tcp-07.html#msgxfer
+		if !slr.readNewlineDelimitedLine() {
+			return false
+		}
+		if len(slr.line) > 0 {
+			return true
+		}
+		// Skip empty lines
+	}
+}
+
+// isOctetCountedLine returns true if the next line at slr starts with `<digits><SP><` prefix.
+//
+// This prefix identifies octet-counting method. See https://datatracker.ietf.org/doc/html/rfc6587#section-3.4.1
+// Lines, which start with digits, but do not contain such a prefix, are read with non-transparent framing.
+func (slr *syslogLineReader) isOctetCountedLine() bool {
+	// Peek bytes one by one in order to avoid blocking on reading bytes past the end of the non-transparent framing line.
+	for n := 1; ; n++ {
+		prefix, err := slr.br.Peek(n)
+		if err != nil {
+			return false
+		}
+		c := prefix[n-1]
+		if c >= '0' && c <= '9' {
+			continue
+		}
+		if c != ' ' || n == 1 {
+			return false
+		}
+		prefix, err = slr.br.Peek(n + 1)
+		if err != nil {
+			return false
+		}
+		return prefix[n] == '<'
+	}
+}
+
+func (slr *syslogLineReader) readOctetCountedLine() bool {
+	msgLenStr, err := slr.br.ReadSlice(' ')
+	if err != nil {
+		slr.err = fmt.Errorf("cannot read message length: %w", err)
+		return false
+	}
+	msgLenStr = msgLenStr[:len(msgLenStr)-1]
+	msgLen, err := strconv.ParseUint(bytesutil.ToUnsafeString(msgLenStr), 10, 64)
+	if err != nil {
+		slr.err = fmt.Errorf("cannot parse message length from %q: %w", msgLenStr, err)
+		return false
+	}
+	if maxMsgLen := insertutils.MaxLineSizeBytes.IntN(); msgLen > uint64(maxMsgLen) {
+		slr.err = fmt.Errorf("cannot read message longer than %d bytes; msgLen=%d", maxMsgLen, msgLen)
+		return false
+	}
+
+	slr.line = bytesutil.ResizeNoCopyNoOverallocate(slr.line, int(msgLen))
+	if _, err := io.ReadFull(slr.br, slr.line); err != nil {
+		slr.err = fmt.Errorf("cannot read message with size %d bytes: %w", msgLen, err)
+		return false
+	}
+	return true
+}
+
+func (slr *syslogLineReader) readNewlineDelimitedLine() bool {
+	slr.line = slr.line[:0]
+	maxLineLen := insertutils.MaxLineSizeBytes.IntN()
+	for {
+		b, err := slr.br.ReadSlice('\n')
+		if len(slr.line)+len(b) > maxLineLen {
+			slr.err = fmt.Errorf("cannot read line longer than -insert.maxLineSizeBytes=%d", maxLineLen)
+			return false
+		}
+		slr.line = append(slr.line, b...)
+		if err == nil {
+			break
+		}
+		if errors.Is(err, bufio.ErrBufferFull) {
+			continue
+		}
+		if errors.Is(err, io.EOF) && len(slr.line) > 0 {
+			// The last line without the trailing newline.
+			break
+		}
+		slr.err = err
+		return false
+	}
+
+	slr.line = bytes.TrimRight(slr.line, "\r\n")
+	return true
+}
+
+func getSyslogLineReader(r io.Reader) *syslogLineReader {
+	v := syslogLineReaderPool.Get()
+	if v == nil {
+		br := bufio.NewReaderSize(r, 64*1024)
+		return &syslogLineReader{
+			br: br,
+		}
+	}
+	slr := v.(*syslogLineReader)
+	slr.reset(r)
+	return slr
+}
+
+func putSyslogLineReader(slr *syslogLineReader) {
+	syslogLineReaderPool.Put(slr)
+}
+
+var syslogLineReaderPool sync.Pool
+
+var (
+	rowsIngestedTotal = metrics.NewCounter(`vl_rows_ingested_total{type="syslog"}`)
+
+	connsTCPTotal  = metrics.NewCounter(`vl_syslog_conns_total{net="tcp"}`)
+	errorsTCPTotal = metrics.NewCounter(`vl_syslog_errors_total{net="tcp"}`)
+
+	packetsUDPTotal = metrics.NewCounter(`vl_syslog_packets_total{net="udp"}`)
+	errorsUDPTotal  = metrics.NewCounter(`vl_syslog_errors_total{net="udp"}`)
+
+	parseErrorsTotal = metrics.NewCounter(`vl_syslog_parse_errors_total`)
+)
@@ -0,0 +1,142 @@
+package syslog
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logstorage"
+)
+
+func TestSyslogLineReader_Success(t *testing.T) {
+	f := func(data string, linesExpected []string) {
+		t.Helper()
+
+		r := bytes.NewBufferString(data)
+		slr := getSyslogLineReader(r)
+		defer putSyslogLineReader(slr)
+
+		var lines []string
+		for slr.nextLine() {
+			lines = append(lines, string(slr.line))
+		}
+		if err := slr.Error(); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if !reflect.DeepEqual(lines, linesExpected) {
+			t.Fatalf("unexpected lines read;\ngot\n%q\nwant\n%q", lines, linesExpected)
+		}
+	}
+
+	f("", nil)
+	f("\n", nil)
+	f("\n\n\n", nil)
+
+	f("foobar", []string{"foobar"})
+	f("foobar\n", []string{"foobar"})
+	f("\n\nfoo\n\nbar\n\n", []string{"foo", "bar"})
+	f("foo\r\nbar\r\n", []string{"foo", "bar"})
+
+	f(`Jun  3 12:08:33 abcd systemd: Starting Update the local ESM caches...`, []string{"Jun  3 12:08:33 abcd systemd: Starting Update the local ESM caches..."})
+
+	data := `Jun  3 12:08:33 abcd systemd: Starting Update the local ESM caches...
+48 <165>Jun  4 12:08:33 abcd systemd[345]: abc defg<123>1 2023-06-03T17:42:12.345Z mymachine.example.com appname 12345 ID47 [exampleSDID@32473 iut="3" eventSource="Application 123 = ] 56" eventID="11211"] This is a test message with structured data.
+`
+	linesExpected := []string{
+		"Jun  3 12:08:33 abcd systemd: Starting Update the local ESM caches...",
+		"<165>Jun  4 12:08:33 abcd systemd[345]: abc defg",
+		`<123>1 2023-06-03T17:42:12.345Z mymachine.example.com appname 12345 ID47 [exampleSDID@32473 iut="3" eventSource="Application 123 = ] 56" eventID="11211"] This is a test message with structured data.`,
+	}
+	f(data, linesExpected)
+
+	// Multiple octet-counted lines
+	f("6 <1>foo3 <2>", []string{"<1>foo", "<2>"})
+	f("6 <1>foo\n3 <2>", []string{"<1>foo", "<2>"})
+
+	// Lines starting with digits without octet-counting prefix
+	f("12", []string{"12"})
+	f("12 ", []string{"12 "})
+	f("12foo bar", []string{"12foo bar"})
+	f("12 foobar\n3 <1>", []string{"12 foobar", "<1>"})
+	f("0 5 hello\n", []string{"0 5 hello"})
+}
+
+func TestSyslogLineReader_Failure(t *testing.T) {
+	f := func(data string) {
+		t.Helper()
+
+		r := bytes.NewBufferString(data)
+		slr := getSyslogLineReader(r)
+		defer putSyslogLineReader(slr)
+
+		if slr.nextLine() {
+			t.Fatalf("expecting failure to read the first line")
+		}
+		if err := slr.Error(); err == nil {
+			t.Fatalf("expecting non-nil error")
+		}
+	}
+
+	// too short message body
+	f("12 <")
+	f("12 <foobar")
+
+	// too big message size
+	f("123456789 <foobar")
+}
+
+func TestProcessLine(t *testing.T) {
+	f := func(line string, timestampExpected int64, resultExpected string) {
+		t.Helper()
+
+		var timestamps []int64
+		var results []string
+		processLogMessage := func(timestamp int64, fields []logstorage.Field) {
+			timestamps = append(timestamps, timestamp)
+
+			a := make([]string, 0, len(fields))
+			for _, f := range fields {
+				if f.Value == "" {
+					continue
+				}
+				a = append(a, fmt.Sprintf("%q:%q", f.Name, f.Value))
+			}
+			results = append(results, "{"+strings.Join(a, ",")+"}")
+		}
+
+		if err := processLine(line, 2023, time.UTC, processLogMessage); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if len(timestamps) != 1 {
+			t.Fatalf("unexpected number of processed lines; got %d; want 1", len(timestamps))
+		}
+		if timestampExpected != 0 && timestamps[0] != timestampExpected {
+			t.Fatalf("unexpected timestamp; got %d; want %d", timestamps[0], timestampExpected)
+		}
+		if results[0] != resultExpected {
+			t.Fatalf("unexpected result;\ngot\n%s\nwant\n%s", results[0], resultExpected)
+		}
+	}
+
+	f("<165>Jun  4 12:08:33 abcd systemd[345]: abc defg", 1685880513000000000,
+		`{"priority":"165","facility":"20","severity":"5","format":"rfc3164","hostname":"abcd","app_name":"systemd","proc_id":"345","_msg":"abc defg"}`)
+	f(`<123>1 2023-06-03T17:42:12.345Z mymachine.example.com appname 12345 ID47 [exampleSDID@32473 iut="3"] This is a test message`, 1685814132345000000,
+		`{"priority":"123","facility":"15","severity":"3","format":"rfc5424","hostname":"mymachine.example.com","app_name":"appname","proc_id":"12345","msg_id":"ID47","exampleSDID@32473.iut":"3","_msg":"This is a test message"}`)
+
+	// Missing timestamp - the current time must be used
+	f("foo bar", 0, `{"format":"rfc3164","_msg":"foo bar"}`)
+}
+
+func TestProcessLineFailure(t *testing.T) {
+	processLogMessage := func(_ int64, _ []logstorage.Field) {
+		t.Fatalf("unexpected call to processLogMessage")
+	}
+
+	// Invalid RFC5424 timestamp
+	if err := processLine(`<123>1 foobar host app - - - msg`, 2023, time.UTC, processLogMessage); err == nil {
+		t.Fatalf("expecting non-nil error")
+	}
+}
@@ -0,0 +1,128 @@
+package logsql
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/httpserver"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logstorage"
+)
+
+// ProcessActiveQueriesRequest handles /select/logsql/active_queries request.
+//
+// It writes a JSON with active queries to w.
+//
+// See https://docs.victoriametrics.com/victorialogs/querying/#active-queries
+func ProcessActiveQueriesRequest(w http.ResponseWriter, _ *http.Request) {
+	aqes := activeQueriesV.GetAll()
+
+	w.Header().Set("Content-Type", "application/json")
+	sort.Slice(aqes, func(i, j int) bool {
+		return aqes[i].startTime.Sub(aqes[j].startTime) < 0
+	})
+	now := time.Now()
+	fmt.Fprintf(w, `{"status":"ok","data":[`)
+	for i, aqe := range aqes {
+		d := now.Sub(aqe.startTime)
+		fmt.Fprintf(w, `{"duration":"%.3fs","id":"%016X","remote_addr":%s,"path":%q,"account_id":%d,"project_id":%d,"query":%q,"start":%q,"end":%q}`,
+			d.Seconds(), aqe.qid, aqe.quotedRemoteAddr, aqe.path, aqe.tenantID.AccountID, aqe.tenantID.ProjectID, aqe.q, aqe.start, aqe.end)
+		if i+1 < len(aqes) {
+			fmt.Fprintf(w, `,`)
+		}
+	}
+	fmt.Fprintf(w, `]}`)
+}
+
+// RegisterActiveQuery registers the query from r in the list of active queries.
+//
+// UnregisterActiveQuery must be called with the returned id when the query is finished.
+func RegisterActiveQuery(r *http.Request) uint64 {
+	return activeQueriesV.Add(r)
+}
+
+// UnregisterActiveQuery removes the query with the given qid from the list of active queries.
+func UnregisterActiveQuery(qid uint64) {
+	activeQueriesV.Remove(qid)
+}
+
+// GetTimeRangeMsecs returns the time range in milliseconds for the query from r.
+//
+// Zero is returned if the query has no start or end args.
+func GetTimeRangeMsecs(r *http.Request) int64 {
+	start, okStart, err := getTimeNsec(r, "start")
+	if err != nil || !okStart {
+		return 0
+	}
+	end, okEnd, err := getTimeNsec(r, "end")
+	if err != nil || !okEnd || end < start {
+		return 0
+	}
+	return (end - start) / 1e6
+}
+
+var activeQueriesV = newActiveQueries()
+
+type activeQueries struct {
+	mu sync.Mutex
+	m  map[uint64]activeQueryEntry
+}
+
+type activeQueryEntry struct {
+	qid              uint64
+	quotedRemoteAddr string
+	path             string
+	tenantID         logstorage.TenantID
+	q                string
+	start            string
+	end              string
+	startTime        time.Time
+}
+
+func newActiveQueries() *activeQueries {
+	return &activeQueries{
+		m: make(map[uint64]activeQueryEntry),
+	}
+}
+
+func (aq *activeQueries) Add(r *http.Request) uint64 {
+	var aqe activeQueryEntry
+	aqe.qid = nextActiveQueryID.Add(1)
+	aqe.quotedRemoteAddr = httpserver.GetQuotedRemoteAddr(r)
+	aqe.path = r.URL.Path
+	aqe.tenantID, _ = logstorage.GetTenantIDFromRequest(r)
+	aqe.q = r.FormValue("query")
+	aqe.start = r.FormValue("start")
+	aqe.end = r.FormValue("end")
+	aqe.startTime = time.Now()
+
+	aq.mu.Lock()
+	aq.m[aqe.qid] = aqe
+	aq.mu.Unlock()
+	return aqe.qid
+}
+
+func (aq *activeQueries) Remove(qid uint64) {
+	aq.mu.Lock()
+	delete(aq.m, qid)
+	aq.mu.Unlock()
+}
+
+func (aq *activeQueries) GetAll() []activeQueryEntry {
+	aq.mu.Lock()
+	aqes := make([]activeQueryEntry, 0, len(aq.m))
+	for _, aqe := range aq.m {
+		aqes = append(aqes, aqe)
+	}
+	aq.mu.Unlock()
+	return aqes
+}
+
+var nextActiveQueryID = func() *atomic.Uint64 {
+	var x atomic.Uint64
+	x.Store(uint64(time.Now().UnixNano()))
+	return &x
+}()
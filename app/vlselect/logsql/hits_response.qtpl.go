@@ -0,0 +1,237 @@
+// Code generated by qtc from "hits_response.qtpl". DO NOT EDIT.
+// See https://github.com/valyala/quicktemplate for details.
+
+//line app/vlselect/logsql/hits_response.qtpl:1
+package logsql
+
+//line app/vlselect/logsql/hits_response.qtpl:1
+import (
+	"sort"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logstorage"
+)
+
+// FieldsForHits formats labels for /select/logsql/hits response
+
+//line app/vlselect/logsql/hits_response.qtpl:10
+import (
+	qtio422016 "io"
+
+	qt422016 "github.com/valyala/quicktemplate"
+)
+
+//line app/vlselect/logsql/hits_response.qtpl:10
+var (
+	_ = qtio422016.Copy
+	_ = qt422016.AcquireByteBuffer
+)
+
+//line app/vlselect/logsql/hits_response.qtpl:10
+func StreamFieldsForHits(qw422016 *qt422016.Writer, columns []logstorage.BlockColumn, rowIdx int) {
+//line app/vlselect/logsql/hits_response.qtpl:10
+	qw422016.N().S(`{`)
+//line app/vlselect/logsql/hits_response.qtpl:12
+	if len(columns) > 0 {
+//line app/vlselect/logsql/hits_response.qtpl:13
+		qw422016.N().Q(columns[0].Name)
+//line app/vlselect/logsql/hits_response.qtpl:13
+		qw422016.N().S(`:`)
+//line app/vlselect/logsql/hits_response.qtpl:13
+		qw422016.N().Q(columns[0].Values[rowIdx])
+//line app/vlselect/logsql/hits_response.qtpl:14
+		for _, c := range columns[1:] {
+//line app/vlselect/logsql/hits_response.qtpl:14
+			qw422016.N().S(`,`)
+//line app/vlselect/logsql/hits_response.qtpl:15
+			qw422016.N().Q(c.Name)
+//line app/vlselect/logsql/hits_response.qtpl:15
+			qw422016.N().S(`:`)
+//line app/vlselect/logsql/hits_response.qtpl:15
+			qw422016.N().Q(c.Values[rowIdx])
+//line app/vlselect/logsql/hits_response.qtpl:16
+		}
+//line app/vlselect/logsql/hits_response.qtpl:17
+	}
+//line app/vlselect/logsql/hits_response.qtpl:17
+	qw422016.N().S(`}`)
+//line app/vlselect/logsql/hits_response.qtpl:19
+}
+
+//line app/vlselect/logsql/hits_response.qtpl:19
+func WriteFieldsForHits(qq422016 qtio422016.Writer, columns []logstorage.BlockColumn, rowIdx int) {
+//line app/vlselect/logsql/hits_response.qtpl:19
+	qw422016 := qt422016.AcquireWriter(qq422016)
+//line app/vlselect/logsql/hits_response.qtpl:19
+	StreamFieldsForHits(qw422016, columns, rowIdx)
+//line app/vlselect/logsql/hits_response.qtpl:19
+	qt422016.ReleaseWriter(qw422016)
+//line app/vlselect/logsql/hits_response.qtpl:19
+}
+
+//line app/vlselect/logsql/hits_response.qtpl:19
+func FieldsForHits(columns []logstorage.BlockColumn, rowIdx int) string {
+//line app/vlselect/logsql/hits_response.qtpl:19
+	qb422016 := qt422016.AcquireByteBuffer()
+//line app/vlselect/logsql/hits_response.qtpl:19
+	WriteFieldsForHits(qb422016, columns, rowIdx)
+//line app/vlselect/logsql/hits_response.qtpl:19
+	qs422016 := string(qb422016.B)
+//line app/vlselect/logsql/hits_response.qtpl:19
+	qt422016.ReleaseByteBuffer(qb422016)
+//line app/vlselect/logsql/hits_response.qtpl:19
+	return qs422016
+//line app/vlselect/logsql/hits_response.qtpl:19
+}
+
+//line app/vlselect/logsql/hits_response.qtpl:21
+func StreamHitsSeries(qw422016 *qt422016.Writer, m map[string]*hitsSeries) {
+//line app/vlselect/logsql/hits_response.qtpl:21
+	qw422016.N().S(`{`)
+//line app/vlselect/logsql/hits_response.qtpl:24
+	sortedKeys := make([]string, 0, len(m))
+	for k := range m {
+		if k != hitsSeriesOtherKey {
+			sortedKeys = append(sortedKeys, k)
+		}
+	}
+	sort.Strings(sortedKeys)
+	if _, ok := m[hitsSeriesOtherKey]; ok {
+		// The series with hits for the rest of fields goes last.
+		sortedKeys = append(sortedKeys, hitsSeriesOtherKey)
+	}
+
+//line app/vlselect/logsql/hits_response.qtpl:35
+	qw422016.N().S(`"hits":[`)
+//line app/vlselect/logsql/hits_response.qtpl:37
+	if len(sortedKeys) > 0 {
+//line app/vlselect/logsql/hits_response.qtpl:38
+		streamhitsSeriesLine(qw422016, m, sortedKeys[0])
+//line app/vlselect/logsql/hits_response.qtpl:39
+		for _, k := range sortedKeys[1:] {
+//line app/vlselect/logsql/hits_response.qtpl:39
+			qw422016.N().S(`,`)
+//line app/vlselect/logsql/hits_response.qtpl:40
+			streamhitsSeriesLine(qw422016, m, k)
+//line app/vlselect/logsql/hits_response.qtpl:41
+		}
+//line app/vlselect/logsql/hits_response.qtpl:42
+	}
+//line app/vlselect/logsql/hits_response.qtpl:42
+	qw422016.N().S(`]}`)
+//line app/vlselect/logsql/hits_response.qtpl:45
+}
+
+//line app/vlselect/logsql/hits_response.qtpl:45
+func WriteHitsSeries(qq422016 qtio422016.Writer, m map[string]*hitsSeries) {
+//line app/vlselect/logsql/hits_response.qtpl:45
+	qw422016 := qt422016.AcquireWriter(qq422016)
+//line app/vlselect/logsql/hits_response.qtpl:45
+	StreamHitsSeries(qw422016, m)
+//line app/vlselect/logsql/hits_response.qtpl:45
+	qt422016.ReleaseWriter(qw422016)
+//line app/vlselect/logsql/hits_response.qtpl:45
+}
+
+//line app/vlselect/logsql/hits_response.qtpl:45
+func HitsSeries(m map[string]*hitsSeries) string {
+//line app/vlselect/logsql/hits_response.qtpl:45
+	qb422016 := qt422016.AcquireByteBuffer()
+//line app/vlselect/logsql/hits_response.qtpl:45
+	WriteHitsSeries(qb422016, m)
+//line app/vlselect/logsql/hits_response.qtpl:45
+	qs422016 := string(qb422016.B)
+//line app/vlselect/logsql/hits_response.qtpl:45
+	qt422016.ReleaseByteBuffer(qb422016)
+//line app/vlselect/logsql/hits_response.qtpl:45
+	return qs422016
+//line app/vlselect/logsql/hits_response.qtpl:45
+}
+
+//line app/vlselect/logsql/hits_response.qtpl:47
+func streamhitsSeriesLine(qw422016 *qt422016.Writer, m map[string]*hitsSeries, k string) {
+//line app/vlselect/logsql/hits_response.qtpl:47
+	qw422016.N().S(`{`)
+//line app/vlselect/logsql/hits_response.qtpl:50
+	hs := m[k]
+	hs.sort()
+	timestamps := hs.timestamps
+	hits := hs.hits
+
+//line app/vlselect/logsql/hits_response.qtpl:54
+	qw422016.N().S(`"fields":`)
+//line app/vlselect/logsql/hits_response.qtpl:56
+	if k == hitsSeriesOtherKey {
+//line app/vlselect/logsql/hits_response.qtpl:56
+		qw422016.N().S(`{}`)
+//line app/vlselect/logsql/hits_response.qtpl:58
+	} else {
+//line app/vlselect/logsql/hits_response.qtpl:59
+		qw422016.N().S(k)
+//line app/vlselect/logsql/hits_response.qtpl:60
+	}
+//line app/vlselect/logsql/hits_response.qtpl:60
+	qw422016.N().S(`,"timestamps":[`)
+//line app/vlselect/logsql/hits_response.qtpl:62
+	if len(timestamps) > 0 {
+//line app/vlselect/logsql/hits_response.qtpl:63
+		qw422016.N().Q(timestamps[0])
+//line app/vlselect/logsql/hits_response.qtpl:64
+		for _, ts := range timestamps[1:] {
+//line app/vlselect/logsql/hits_response.qtpl:64
+			qw422016.N().S(`,`)
+//line app/vlselect/logsql/hits_response.qtpl:65
+			qw422016.N().Q(ts)
+//line app/vlselect/logsql/hits_response.qtpl:66
+		}
+//line app/vlselect/logsql/hits_response.qtpl:67
+	}
+//line app/vlselect/logsql/hits_response.qtpl:67
+	qw422016.N().S(`],"values":[`)
+//line app/vlselect/logsql/hits_response.qtpl:70
+	if len(hits) > 0 {
+//line app/vlselect/logsql/hits_response.qtpl:71
+		qw422016.N().DUL(hits[0])
+//line app/vlselect/logsql/hits_response.qtpl:72
+		for _, v := range hits[1:] {
+//line app/vlselect/logsql/hits_response.qtpl:72
+			qw422016.N().S(`,`)
+//line app/vlselect/logsql/hits_response.qtpl:73
+			qw422016.N().DUL(v)
+//line app/vlselect/logsql/hits_response.qtpl:74
+		}
+//line app/vlselect/logsql/hits_response.qtpl:75
+	}
+//line app/vlselect/logsql/hits_response.qtpl:75
+	qw422016.N().S(`],"total":`)
+//line app/vlselect/logsql/hits_response.qtpl:77
+	qw422016.N().DUL(hs.hitsTotal)
+//line app/vlselect/logsql/hits_response.qtpl:77
+	qw422016.N().S(`}`)
+//line app/vlselect/logsql/hits_response.qtpl:79
+}
+
+//line app/vlselect/logsql/hits_response.qtpl:79
+func writehitsSeriesLine(qq422016 qtio422016.Writer, m map[string]*hitsSeries, k string) {
+//line app/vlselect/logsql/hits_response.qtpl:79
+	qw422016 := qt422016.AcquireWriter(qq422016)
+//line app/vlselect/logsql/hits_response.qtpl:79
+	streamhitsSeriesLine(qw422016, m, k)
+//line app/vlselect/logsql/hits_response.qtpl:79
+	qt422016.ReleaseWriter(qw422016)
+//line app/vlselect/logsql/hits_response.qtpl:79
+}
+
+//line app/vlselect/logsql/hits_response.qtpl:79
+func hitsSeriesLine(m map[string]*hitsSeries, k string) string {
+//line app/vlselect/logsql/hits_response.qtpl:79
+	qb422016 := qt422016.AcquireByteBuffer()
+//line app/vlselect/logsql/hits_response.qtpl:79
+	writehitsSeriesLine(qb422016, m, k)
+//line app/vlselect/logsql/hits_response.qtpl:79
+	qs422016 := string(qb422016.B)
+//line app/vlselect/logsql/hits_response.qtpl:79
+	qt422016.ReleaseByteBuffer(qb422016)
+//line app/vlselect/logsql/hits_response.qtpl:79
+	return qs422016
+//line app/vlselect/logsql/hits_response.qtpl:79
+}
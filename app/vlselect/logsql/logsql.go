@@ -5,52 +5,574 @@ import (
 	"fmt"
 	"math"
 	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/cespare/xxhash/v2"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vlselect/querystats"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vlstorage"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/encoding"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/httpserver"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/httputils"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logstorage"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promutils"
 )
 
-// ProcessQueryRequest handles /select/logsql/query request.
-func ProcessQueryRequest(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+// ProcessFieldNamesRequest handles /select/logsql/field_names request.
+//
+// See https://docs.victoriametrics.com/victorialogs/querying/#querying-field-names
+func ProcessFieldNamesRequest(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	q, tenantIDs, err := parseCommonArgs(r)
+	if err != nil {
+		httpserver.Errorf(w, r, "%s", err)
+		return
+	}
+
+	// Obtain field names for the given query
+	q.Optimize()
+	fieldNames, err := vlstorage.GetFieldNames(ctx, tenantIDs, q)
+	if err != nil {
+		httpserver.Errorf(w, r, "cannot obtain field names: %s", err)
+		return
+	}
+
+	// Write results
+	w.Header().Set("Content-Type", "application/json")
+	WriteValuesWithHitsJSON(w, fieldNames)
+}
+
+// ProcessFieldValuesRequest handles /select/logsql/field_values request.
+//
+// See https://docs.victoriametrics.com/victorialogs/querying/#querying-field-values
+func ProcessFieldValuesRequest(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	q, tenantIDs, err := parseCommonArgs(r)
+	if err != nil {
+		httpserver.Errorf(w, r, "%s", err)
+		return
+	}
+
+	// Parse fieldName query arg
+	fieldName := r.FormValue("field")
+	if fieldName == "" {
+		httpserver.Errorf(w, r, "missing 'field' query arg")
+		return
+	}
+
+	// Parse limit query arg
+	limit, err := httputils.GetInt(r, "limit")
+	if err != nil {
+		httpserver.Errorf(w, r, "%s", err)
+		return
+	}
+	if limit < 0 {
+		limit = 0
+	}
+
+	// Obtain unique values for the given field
+	q.Optimize()
+	values, err := vlstorage.GetFieldValues(ctx, tenantIDs, q, fieldName, uint64(limit))
+	if err != nil {
+		httpserver.Errorf(w, r, "cannot obtain values for field %q: %s", fieldName, err)
+		return
+	}
+
+	// Write results
+	w.Header().Set("Content-Type", "application/json")
+	WriteValuesWithHitsJSON(w, values)
+}
+
+// ProcessStreamLabelNamesRequest handles /select/logsql/stream_label_names request.
+//
+// See https://docs.victoriametrics.com/victorialogs/querying/#querying-stream-label-names
+func ProcessStreamLabelNamesRequest(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	q, tenantIDs, err := parseCommonArgs(r)
+	if err != nil {
+		httpserver.Errorf(w, r, "%s", err)
+		return
+	}
+
+	// Obtain stream label names for the given query
+	q.Optimize()
+	names, err := vlstorage.GetStreamLabelNames(ctx, tenantIDs, q)
+	if err != nil {
+		httpserver.Errorf(w, r, "cannot obtain stream label names: %s", err)
+		return
+	}
+
+	// Write results
+	w.Header().Set("Content-Type", "application/json")
+	WriteValuesWithHitsJSON(w, names)
+}
+
+// ProcessStreamLabelValuesRequest handles /select/logsql/stream_label_values request.
+//
+// See https://docs.victoriametrics.com/victorialogs/querying/#querying-stream-label-values
+func ProcessStreamLabelValuesRequest(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	q, tenantIDs, err := parseCommonArgs(r)
+	if err != nil {
+		httpserver.Errorf(w, r, "%s", err)
+		return
+	}
+
+	// Parse labelName query arg
+	labelName := r.FormValue("label")
+	if labelName == "" {
+		httpserver.Errorf(w, r, "missing 'label' query arg")
+		return
+	}
+
+	// Parse limit query arg
+	limit, err := httputils.GetInt(r, "limit")
+	if err != nil {
+		httpserver.Errorf(w, r, "%s", err)
+		return
+	}
+	if limit < 0 {
+		limit = 0
+	}
+
+	// Obtain stream label values for the given labelName
+	q.Optimize()
+	values, err := vlstorage.GetStreamLabelValues(ctx, tenantIDs, q, labelName, uint64(limit))
+	if err != nil {
+		httpserver.Errorf(w, r, "cannot obtain stream label values for %q: %s", labelName, err)
+		return
+	}
+
+	// Write results
+	w.Header().Set("Content-Type", "application/json")
+	WriteValuesWithHitsJSON(w, values)
+}
+
+// ProcessStreamsRequest handles /select/logsql/streams request.
+//
+// See https://docs.victoriametrics.com/victorialogs/querying/#querying-streams
+func ProcessStreamsRequest(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	q, tenantIDs, err := parseCommonArgs(r)
+	if err != nil {
+		httpserver.Errorf(w, r, "%s", err)
+		return
+	}
+
+	// Parse limit query arg
+	limit, err := httputils.GetInt(r, "limit")
+	if err != nil {
+		httpserver.Errorf(w, r, "%s", err)
+		return
+	}
+	if limit < 0 {
+		limit = 0
+	}
+
+	// Obtain streams for the given query
+	q.Optimize()
+	streams, err := vlstorage.GetStreams(ctx, tenantIDs, q, uint64(limit))
+	if err != nil {
+		httpserver.Errorf(w, r, "cannot obtain streams: %s", err)
+		return
+	}
+
+	// Write results
+	w.Header().Set("Content-Type", "application/json")
+	WriteValuesWithHitsJSON(w, streams)
+}
+
+// ProcessHitsRequest handles /select/logsql/hits request.
+//
+// See https://docs.victoriametrics.com/victorialogs/querying/#querying-hits-stats
+func ProcessHitsRequest(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	q, tenantIDs, err := parseCommonArgs(r)
+	if err != nil {
+		httpserver.Errorf(w, r, "%s", err)
+		return
+	}
+
+	// Obtain step
+	stepStr := r.FormValue("step")
+	if stepStr == "" {
+		stepStr = "1d"
+	}
+	step, err := promutils.ParseDuration(stepStr)
+	if err != nil {
+		httpserver.Errorf(w, r, "cannot parse 'step' arg: %s", err)
+		return
+	}
+	if step <= 0 {
+		httpserver.Errorf(w, r, "'step' must be bigger than zero")
+		return
+	}
+
+	// Obtain offset
+	offsetStr := r.FormValue("offset")
+	if offsetStr == "" {
+		offsetStr = "0s"
+	}
+	offset, err := promutils.ParseDuration(offsetStr)
+	if err != nil {
+		httpserver.Errorf(w, r, "cannot parse 'offset' arg: %s", err)
+		return
+	}
+
+	// Obtain field entries
+	fields := r.Form["field"]
+
+	// Obtain limit on the number of top fields entries.
+	fieldsLimit, err := httputils.GetInt(r, "fields_limit")
+	if err != nil {
+		httpserver.Errorf(w, r, "%s", err)
+		return
+	}
+	if fieldsLimit < 0 {
+		fieldsLimit = 0
+	}
+
+	// Prepare the query
+	q.AddCountByTimePipe(int64(step), int64(offset), fields)
+	q.Optimize()
+
+	var mLock sync.Mutex
+	m := make(map[string]*hitsSeries)
+	writeBlock := func(_ uint, timestamps []int64, columns []logstorage.BlockColumn) {
+		if len(columns) == 0 || len(columns[0].Values) == 0 {
+			return
+		}
+
+		timestampValues := columns[0].Values
+		hitsValues := columns[len(columns)-1].Values
+		columns = columns[1 : len(columns)-1]
+
+		bb := blockResultPool.Get()
+		for i := range timestamps {
+			timestampStr := strings.Clone(timestampValues[i])
+			hitsStr := strings.Clone(hitsValues[i])
+			hits, err := strconv.ParseUint(hitsStr, 10, 64)
+			if err != nil {
+				// This should never happen, since the hits column is generated by count() stats func.
+				continue
+			}
+
+			bb.Reset()
+			WriteFieldsForHits(bb, columns, i)
+
+			mLock.Lock()
+			hs, ok := m[string(bb.B)]
+			if !ok {
+				k := string(bb.B)
+				hs = &hitsSeries{}
+				m[k] = hs
+			}
+			hs.timestamps = append(hs.timestamps, timestampStr)
+			hs.hits = append(hs.hits, hits)
+			hs.hitsTotal += hits
+			mLock.Unlock()
+		}
+		blockResultPool.Put(bb)
+	}
+
+	// Execute the query
+	if err := vlstorage.RunQuery(ctx, tenantIDs, q, writeBlock); err != nil {
+		httpserver.Errorf(w, r, "cannot execute query [%s]: %s", q, err)
+		return
+	}
+
+	m = getTopHitsSeries(m, fieldsLimit)
+
+	// Write response
+	w.Header().Set("Content-Type", "application/json")
+	WriteHitsSeries(w, m)
+}
+
+func getTopHitsSeries(m map[string]*hitsSeries, fieldsLimit int) map[string]*hitsSeries {
+	if fieldsLimit <= 0 || fieldsLimit >= len(m) {
+		return m
+	}
+
+	type fieldsHits struct {
+		fieldsStr string
+		hs        *hitsSeries
+	}
+	a := make([]fieldsHits, 0, len(m))
+	for fieldsStr, hs := range m {
+		a = append(a, fieldsHits{
+			fieldsStr: fieldsStr,
+			hs:        hs,
+		})
+	}
+	sort.Slice(a, func(i, j int) bool {
+		if a[i].hs.hitsTotal == a[j].hs.hitsTotal {
+			return a[i].fieldsStr < a[j].fieldsStr
+		}
+		return a[i].hs.hitsTotal > a[j].hs.hitsTotal
+	})
+
+	hitsOther := make(map[string]uint64)
+	for _, x := range a[fieldsLimit:] {
+		for i, timestampStr := range x.hs.timestamps {
+			hitsOther[timestampStr] += x.hs.hits[i]
+		}
+	}
+	var hsOther hitsSeries
+	for timestampStr, hits := range hitsOther {
+		hsOther.timestamps = append(hsOther.timestamps, timestampStr)
+		hsOther.hits = append(hsOther.hits, hits)
+		hsOther.hitsTotal += hits
+	}
+
+	mNew := make(map[string]*hitsSeries, fieldsLimit+1)
+	for _, x := range a[:fieldsLimit] {
+		mNew[x.fieldsStr] = x.hs
+	}
+	mNew[hitsSeriesOtherKey] = &hsOther
+
+	return mNew
+}
+
+// hitsSeriesOtherKey is the key for the series with hits for all the fields beyond fields_limit at /select/logsql/hits.
+//
+// Real series keys contain JSON-encoded fields, so they are never empty and cannot clash with this key.
+const hitsSeriesOtherKey = ""
+
+type hitsSeries struct {
+	hitsTotal  uint64
+	timestamps []string
+	hits       []uint64
+}
+
+func (hs *hitsSeries) sort() {
+	sort.Sort(hs)
+}
+
+func (hs *hitsSeries) Len() int {
+	return len(hs.timestamps)
+}
+
+func (hs *hitsSeries) Swap(i, j int) {
+	hs.timestamps[i], hs.timestamps[j] = hs.timestamps[j], hs.timestamps[i]
+	hs.hits[i], hs.hits[j] = hs.hits[j], hs.hits[i]
+}
+
+func (hs *hitsSeries) Less(i, j int) bool {
+	return hs.timestamps[i] < hs.timestamps[j]
+}
+
+// ProcessStatsQueryRequest handles /select/logsql/stats_query request.
+//
+// See https://docs.victoriametrics.com/victorialogs/querying/#querying-log-stats
+func ProcessStatsQueryRequest(ctx context.Context, w http.ResponseWriter, r *http.Request) {
 	// Extract tenantID
 	tenantID, err := logstorage.GetTenantIDFromRequest(r)
+	if err != nil {
+		httpserver.Errorf(w, r, "cannot obtain tenantID: %s", err)
+		return
+	}
+	tenantIDs := []logstorage.TenantID{tenantID}
+
+	// Obtain the evaluation time
+	timestamp, ok, err := getTimeNsec(r, "time")
 	if err != nil {
 		httpserver.Errorf(w, r, "%s", err)
 		return
 	}
+	if !ok {
+		timestamp = time.Now().UnixNano()
+	}
 
-	// Parse query
+	// Parse the query at the evaluation time, so relative time filters such as `_time:5m` are evaluated relative to it.
 	qStr := r.FormValue("query")
-	q, err := logstorage.ParseQuery(qStr)
+	q, err := logstorage.ParseQueryAtTimestamp(qStr, timestamp)
 	if err != nil {
 		httpserver.Errorf(w, r, "cannot parse query [%s]: %s", qStr, err)
 		return
 	}
+	byFields, err := q.GetStatsByFields()
+	if err != nil {
+		httpserver.Errorf(w, r, "cannot use query [%s] at /select/logsql/stats_query: %s", q, err)
+		return
+	}
 
-	// Parse optional start and end args
-	start, okStart, err := getTimeNsec(r, "start")
+	// Skip logs after the evaluation time
+	q.AddTimeFilter(math.MinInt64, timestamp)
+	q.Optimize()
+
+	var rowsLock sync.Mutex
+	var rows []statsRow
+	writeBlock := func(_ uint, timestamps []int64, columns []logstorage.BlockColumn) {
+		if len(columns) == 0 || len(columns[0].Values) == 0 {
+			return
+		}
+
+		labelColumns := columns[:len(byFields)]
+		resultColumns := columns[len(byFields):]
+
+		bb := blockResultPool.Get()
+		for i := range timestamps {
+			for _, c := range resultColumns {
+				bb.Reset()
+				WriteStatsQueryMetric(bb, c.Name, labelColumns, i)
+
+				rowsLock.Lock()
+				rows = append(rows, statsRow{
+					metric: string(bb.B),
+					value:  strings.Clone(c.Values[i]),
+				})
+				rowsLock.Unlock()
+			}
+		}
+		blockResultPool.Put(bb)
+	}
+
+	// Execute the query
+	if err := vlstorage.RunQuery(ctx, tenantIDs, q, writeBlock); err != nil {
+		httpserver.Errorf(w, r, "cannot execute query [%s]: %s", q, err)
+		return
+	}
+
+	sort.Slice(rows, func(i, j int) bool {
+		return rows[i].metric < rows[j].metric
+	})
+
+	// Write response
+	w.Header().Set("Content-Type", "application/json")
+	WriteStatsQueryResponse(w, rows, timestamp)
+}
+
+type statsRow struct {
+	metric string
+	value  string
+}
+
+// ProcessStatsQueryRangeRequest handles /select/logsql/stats_query_range request.
+//
+// See https://docs.victoriametrics.com/victorialogs/querying/#querying-log-range-stats
+func ProcessStatsQueryRangeRequest(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	q, tenantIDs, err := parseCommonArgs(r)
 	if err != nil {
 		httpserver.Errorf(w, r, "%s", err)
 		return
 	}
-	end, okEnd, err := getTimeNsec(r, "end")
+
+	// Obtain step
+	stepStr := r.FormValue("step")
+	if stepStr == "" {
+		stepStr = "1d"
+	}
+	step, err := promutils.ParseDuration(stepStr)
 	if err != nil {
-		httpserver.Errorf(w, r, "%s", err)
+		httpserver.Errorf(w, r, "cannot parse 'step' arg: %s", err)
 		return
 	}
-	if okStart || okEnd {
-		if !okStart {
-			start = math.MinInt64
+	if step <= 0 {
+		httpserver.Errorf(w, r, "'step' must be bigger than zero")
+		return
+	}
+
+	// Obtain offset
+	offsetStr := r.FormValue("offset")
+	if offsetStr == "" {
+		offsetStr = "0s"
+	}
+	offset, err := promutils.ParseDuration(offsetStr)
+	if err != nil {
+		httpserver.Errorf(w, r, "cannot parse 'offset' arg: %s", err)
+		return
+	}
+
+	// Prepare the query
+	byFields, err := q.AddStatsByTimeField(int64(step), int64(offset))
+	if err != nil {
+		httpserver.Errorf(w, r, "cannot use query [%s] at /select/logsql/stats_query_range: %s", q, err)
+		return
+	}
+	q.Optimize()
+
+	var mLock sync.Mutex
+	m := make(map[string]*statsSeries)
+	writeBlock := func(_ uint, timestamps []int64, columns []logstorage.BlockColumn) {
+		if len(columns) == 0 || len(columns[0].Values) == 0 {
+			return
 		}
-		if !okEnd {
-			end = math.MaxInt64
+
+		timestampValues := columns[0].Values
+		labelColumns := columns[1 : 1+len(byFields)]
+		resultColumns := columns[1+len(byFields):]
+
+		bb := blockResultPool.Get()
+		for i := range timestamps {
+			t, err := time.Parse(time.RFC3339Nano, timestampValues[i])
+			if err != nil {
+				// This should never happen, since the _time column is generated by `stats by (_time:step)`.
+				continue
+			}
+			timestamp := t.UnixNano()
+
+			for _, c := range resultColumns {
+				value := strings.Clone(c.Values[i])
+
+				bb.Reset()
+				WriteStatsQueryMetric(bb, c.Name, labelColumns, i)
+
+				mLock.Lock()
+				ss, ok := m[string(bb.B)]
+				if !ok {
+					k := string(bb.B)
+					ss = &statsSeries{}
+					m[k] = ss
+				}
+				ss.timestamps = append(ss.timestamps, timestamp)
+				ss.values = append(ss.values, value)
+				mLock.Unlock()
+			}
 		}
-		q.AddTimeFilter(start, end)
+		blockResultPool.Put(bb)
+	}
+
+	// Execute the query
+	if err := vlstorage.RunQuery(ctx, tenantIDs, q, writeBlock); err != nil {
+		httpserver.Errorf(w, r, "cannot execute query [%s]: %s", q, err)
+		return
+	}
+
+	// Write response
+	w.Header().Set("Content-Type", "application/json")
+	WriteStatsQueryRangeResponse(w, m)
+}
+
+type statsSeries struct {
+	timestamps []int64
+	values     []string
+}
+
+func (ss *statsSeries) sort() {
+	sort.Sort(ss)
+}
+
+func (ss *statsSeries) Len() int {
+	return len(ss.timestamps)
+}
+
+func (ss *statsSeries) Swap(i, j int) {
+	ss.timestamps[i], ss.timestamps[j] = ss.timestamps[j], ss.timestamps[i]
+	ss.values[i], ss.values[j] = ss.values[j], ss.values[i]
+}
+
+func (ss *statsSeries) Less(i, j int) bool {
+	return ss.timestamps[i] < ss.timestamps[j]
+}
+
+// ProcessQueryRequest handles /select/logsql/query request.
+//
+// See https://docs.victoriametrics.com/victorialogs/querying/#http-api
+func ProcessQueryRequest(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	q, tenantIDs, err := parseCommonArgs(r)
+	if err != nil {
+		httpserver.Errorf(w, r, "%s", err)
+		return
 	}
 
 	// Parse limit query arg
@@ -64,8 +586,6 @@ func ProcessQueryRequest(ctx context.Context, w http.ResponseWriter, r *http.Req
 	}
 	q.Optimize()
 
-	tenantIDs := []logstorage.TenantID{tenantID}
-
 	bw := getBufferedWriter(w)
 
 	writeBlock := func(_ uint, timestamps []int64, columns []logstorage.BlockColumn) {
@@ -88,13 +608,381 @@ func ProcessQueryRequest(ctx context.Context, w http.ResponseWriter, r *http.Req
 	putBufferedWriter(bw)
 
 	if err != nil {
-		httpserver.Errorf(w, r, "cannot execute query [%s]: %s", qStr, err)
+		httpserver.Errorf(w, r, "cannot execute query [%s]: %s", q, err)
 	}
+}
 
+// ProcessExportNativeRequest handles /select/logsql/export/native request.
+//
+// See https://docs.victoriametrics.com/victorialogs/querying/#exporting-logs-in-native-format
+func ProcessExportNativeRequest(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	q, tenantIDs, err := parseCommonArgs(r)
+	if err != nil {
+		httpserver.Errorf(w, r, "%s", err)
+		return
+	}
+	if !q.CanExportNative() {
+		httpserver.Errorf(w, r, "the query [%s] cannot be used for native export; it may contain only pipes, which process every log entry independently "+
+			"of other log entries; see https://docs.victoriametrics.com/victorialogs/querying/#exporting-logs-in-native-format", q)
+		return
+	}
+	q.Optimize()
+
+	ctxWithCancel, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	bw := getBufferedWriter(w)
+
+	var errExportLock sync.Mutex
+	var errExport error
+	writeBlock := func(_ uint, timestamps []int64, columns []logstorage.BlockColumn) {
+		if len(timestamps) == 0 {
+			return
+		}
+
+		bb := blockResultPool.Get()
+		data, err := logstorage.MarshalNativeBlock(bb.B[:0], columns)
+		bb.B = data
+		if err != nil {
+			errExportLock.Lock()
+			if errExport == nil {
+				errExport = err
+			}
+			errExportLock.Unlock()
+			cancel()
+			blockResultPool.Put(bb)
+			return
+		}
+
+		// Every frame contains 8-byte size of the zstd-compressed native block followed by the block itself.
+		frame := blockResultPool.Get()
+		frame.B = encoding.MarshalUint64(frame.B[:0], 0)
+		frame.B = encoding.CompressZSTDLevel(frame.B, bb.B, 1)
+		_ = encoding.MarshalUint64(frame.B[:0], uint64(len(frame.B)-8))
+		bw.WriteIgnoreErrors(frame.B)
+		blockResultPool.Put(frame)
+		blockResultPool.Put(bb)
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	err = vlstorage.RunQuery(ctxWithCancel, tenantIDs, q, writeBlock)
+
+	bw.FlushIgnoreErrors()
+	putBufferedWriter(bw)
+
+	if errExport != nil {
+		err = errExport
+	}
+	if err != nil {
+		httpserver.Errorf(w, r, "cannot export logs for [%s]: %s", q, err)
+	}
+}
+
+// ProcessDeleteRequest handles /select/logsql/delete request.
+//
+// See https://docs.victoriametrics.com/victorialogs/#deleting-logs
+func ProcessDeleteRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		httpserver.Errorf(w, r, "unsupported method %s; use POST for deleting logs", r.Method)
+		return
+	}
+	if r.FormValue("query") == "" {
+		// Prevent from accidental deletion of all the logs for the tenant.
+		httpserver.Errorf(w, r, "missing `query` arg with the filter for the deleted logs; use `query=*` for deleting all the logs")
+		return
+	}
+	q, tenantIDs, err := parseCommonArgs(r)
+	if err != nil {
+		httpserver.Errorf(w, r, "%s", err)
+		return
+	}
+	if err := vlstorage.DeleteRows(tenantIDs[0], q); err != nil {
+		httpserver.Errorf(w, r, "cannot delete logs matching [%s]: %s", q, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ProcessTopQueriesRequest handles /select/logsql/top_queries request.
+//
+// See https://docs.victoriametrics.com/victorialogs/querying/#top-queries
+func ProcessTopQueriesRequest(w http.ResponseWriter, r *http.Request) {
+	topN, err := httputils.GetInt(r, "topN")
+	if err != nil {
+		httpserver.Errorf(w, r, "%s", err)
+		return
+	}
+	if topN <= 0 {
+		topN = 20
+	}
+	maxLifetimeMsecs, err := httputils.GetDuration(r, "maxLifetime", 10*60*1000)
+	if err != nil {
+		httpserver.Errorf(w, r, "cannot parse `maxLifetime` arg: %s", err)
+		return
+	}
+	maxLifetime := time.Duration(maxLifetimeMsecs) * time.Millisecond
+
+	bb := blockResultPool.Get()
+	querystats.WriteJSONQueryStats(bb, topN, maxLifetime)
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(bb.B)
+	blockResultPool.Put(bb)
+}
+
+// ProcessTailRequest handles /select/logsql/tail request.
+//
+// Every live tailing iteration must be executed in maxQueryDuration.
+//
+// See https://docs.victoriametrics.com/victorialogs/querying/#live-tailing
+func ProcessTailRequest(ctx context.Context, w http.ResponseWriter, r *http.Request, maxQueryDuration time.Duration) {
+	// Extract tenantID
+	tenantID, err := logstorage.GetTenantIDFromRequest(r)
+	if err != nil {
+		httpserver.Errorf(w, r, "cannot obtain tenantID: %s", err)
+		return
+	}
+	tenantIDs := []logstorage.TenantID{tenantID}
+
+	// Parse query
+	qStr := r.FormValue("query")
+	q, err := logstorage.ParseQuery(qStr)
+	if err != nil {
+		httpserver.Errorf(w, r, "cannot parse query [%s]: %s", qStr, err)
+		return
+	}
+	if !q.CanLiveTail() {
+		httpserver.Errorf(w, r, "the query [%s] cannot be used in live tailing; "+
+			"see https://docs.victoriametrics.com/victorialogs/querying/#live-tailing for details", q)
+		return
+	}
+
+	// Parse optional offset and lookback args
+	offset, err := getTailDuration(r, "offset", "5s")
+	if err != nil {
+		httpserver.Errorf(w, r, "%s", err)
+		return
+	}
+	lookback, err := getTailDuration(r, "lookback", "1m")
+	if err != nil {
+		httpserver.Errorf(w, r, "%s", err)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		logger.Panicf("BUG: it is expected that http.ResponseWriter (%T) supports http.Flusher interface", w)
+	}
+
+	w.Header().Set("Content-Type", "application/stream+json; charset=utf-8")
+	flusher.Flush()
+
+	ticker := time.NewTicker(tailRefreshInterval)
+	defer ticker.Stop()
+
+	// Every iteration selects logs on the [end-lookback ... end] time range, where end = now-offset.
+	// Logs may be ingested with delays and out of order, so the time range overlaps with the time range
+	// from the previous iterations. Logs, which were already returned, are skipped by tailProcessor.
+	// This guarantees that every log entry is returned only once, independently of whether it is located
+	// in in-memory part or it has been already flushed to disk.
+	tp := newTailProcessor()
+	bw := getBufferedWriter(w)
+	defer putBufferedWriter(bw)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		end := time.Now().UnixNano() - offset.Nanoseconds()
+		start := end - lookback.Nanoseconds()
+
+		qCopy := q.Clone()
+		qCopy.AddTimeFilter(start, end)
+		qCopy.Optimize()
+
+		ctxIteration, cancel := context.WithTimeout(ctx, maxQueryDuration)
+		err := vlstorage.RunQuery(ctxIteration, tenantIDs, qCopy, tp.writeBlock)
+		if err == nil && ctxIteration.Err() != nil {
+			err = fmt.Errorf("the query couldn't be executed in -search.maxQueryDuration=%s", maxQueryDuration)
+		}
+		cancel()
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			// The response has been already started, so the error cannot be sent to the client.
+			// Log it and close the stream.
+			logger.Warnf("cannot execute tail query [%s]: %s; remoteAddr=%s", qCopy, err, httpserver.GetQuotedRemoteAddr(r))
+			return
+		}
+
+		rows := tp.getNewRows(start)
+		if len(rows) > 0 {
+			bb := blockResultPool.Get()
+			WriteJSONRows(bb, rows)
+			bw.WriteIgnoreErrors(bb.B)
+			blockResultPool.Put(bb)
+			bw.FlushIgnoreErrors()
+			flusher.Flush()
+		}
+	}
+}
+
+// getTailDuration returns non-negative duration from the given arg at r.
+//
+// defaultValue is used if the arg is missing.
+func getTailDuration(r *http.Request, argName, defaultValue string) (time.Duration, error) {
+	s := r.FormValue(argName)
+	if s == "" {
+		s = defaultValue
+	}
+	d, err := promutils.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("cannot parse %q arg: %w", argName, err)
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("%q arg cannot be negative; got %s", argName, s)
+	}
+	return d, nil
+}
+
+// tailRefreshInterval is the interval between live tailing iterations.
+const tailRefreshInterval = time.Second
+
+type tailProcessor struct {
+	mu   sync.Mutex
+	rows []tailRow
+
+	// sentRows contains the number of already returned rows per each key. It is used for skipping the rows
+	// returned at the previous iterations when the time ranges of subsequent iterations overlap.
+	//
+	// The number of rows is tracked per key, since distinct log entries may have identical timestamps and fields.
+	sentRows map[tailRowKey]int
+
+	// seenRows contains the number of rows per each key seen at the current iteration.
+	seenRows map[tailRowKey]int
+}
+
+type tailRow struct {
+	key    tailRowKey
+	fields []logstorage.Field
+}
+
+type tailRowKey struct {
+	timestamp int64
+	hash      uint64
+}
+
+func newTailProcessor() *tailProcessor {
+	return &tailProcessor{
+		sentRows: make(map[tailRowKey]int),
+		seenRows: make(map[tailRowKey]int),
+	}
+}
+
+func (tp *tailProcessor) writeBlock(_ uint, timestamps []int64, columns []logstorage.BlockColumn) {
+	if len(columns) == 0 {
+		return
+	}
+
+	tp.mu.Lock()
+	var b []byte
+	for i, timestamp := range timestamps {
+		fields := make([]logstorage.Field, len(columns))
+		b = b[:0]
+		for j, c := range columns {
+			fields[j] = logstorage.Field{
+				Name:  strings.Clone(c.Name),
+				Value: strings.Clone(c.Values[i]),
+			}
+			b = encoding.MarshalBytes(b, bytesutil.ToUnsafeBytes(c.Name))
+			b = encoding.MarshalBytes(b, bytesutil.ToUnsafeBytes(c.Values[i]))
+		}
+		tp.rows = append(tp.rows, tailRow{
+			key: tailRowKey{
+				timestamp: timestamp,
+				hash:      xxhash.Sum64(b),
+			},
+			fields: fields,
+		})
+	}
+	tp.mu.Unlock()
+}
+
+// getNewRows returns rows collected at the current iteration, which weren't returned before, sorted by timestamps.
+//
+// minTimestamp is the start of the time range for the current iteration. Rows with smaller timestamps
+// cannot be selected anymore, so they are removed from tp.sentRows.
+func (tp *tailProcessor) getNewRows(minTimestamp int64) [][]logstorage.Field {
+	for k := range tp.sentRows {
+		if k.timestamp < minTimestamp {
+			delete(tp.sentRows, k)
+		}
+	}
+
+	sort.SliceStable(tp.rows, func(i, j int) bool {
+		return tp.rows[i].key.timestamp < tp.rows[j].key.timestamp
+	})
+	var rows [][]logstorage.Field
+	for i := range tp.rows {
+		r := &tp.rows[i]
+		n := tp.seenRows[r.key] + 1
+		tp.seenRows[r.key] = n
+		if n <= tp.sentRows[r.key] {
+			// The row has been already returned at the previous iterations.
+			continue
+		}
+		tp.sentRows[r.key] = n
+		rows = append(rows, r.fields)
+	}
+
+	clear(tp.seenRows)
+	clear(tp.rows)
+	tp.rows = tp.rows[:0]
+	return rows
 }
 
 var blockResultPool bytesutil.ByteBufferPool
 
+// parseCommonArgs parses tenantID, query and optional start and end args from r.
+func parseCommonArgs(r *http.Request) (*logstorage.Query, []logstorage.TenantID, error) {
+	// Extract tenantID
+	tenantID, err := logstorage.GetTenantIDFromRequest(r)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot obtain tenantID: %w", err)
+	}
+	tenantIDs := []logstorage.TenantID{tenantID}
+
+	// Parse query
+	qStr := r.FormValue("query")
+	q, err := logstorage.ParseQuery(qStr)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot parse query [%s]: %s", qStr, err)
+	}
+
+	// Parse optional start and end args
+	start, okStart, err := getTimeNsec(r, "start")
+	if err != nil {
+		return nil, nil, err
+	}
+	end, okEnd, err := getTimeNsec(r, "end")
+	if err != nil {
+		return nil, nil, err
+	}
+	if okStart || okEnd {
+		if !okStart {
+			start = math.MinInt64
+		}
+		if !okEnd {
+			end = math.MaxInt64
+		}
+		q.AddTimeFilter(start, end)
+	}
+
+	return q, tenantIDs, nil
+}
+
 func getTimeNsec(r *http.Request, argName string) (int64, bool, error) {
 	s := r.FormValue(argName)
 	if s == "" {
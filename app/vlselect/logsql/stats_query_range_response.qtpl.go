@@ -0,0 +1,185 @@
+// Code generated by qtc from "stats_query_range_response.qtpl". DO NOT EDIT.
+// See https://github.com/valyala/quicktemplate for details.
+
+//line app/vlselect/logsql/stats_query_range_response.qtpl:1
+package logsql
+
+//line app/vlselect/logsql/stats_query_range_response.qtpl:1
+import (
+	"sort"
+)
+
+// StatsQueryRangeResponse generates response for /select/logsql/stats_query_range
+
+//line app/vlselect/logsql/stats_query_range_response.qtpl:8
+import (
+	qtio422016 "io"
+
+	qt422016 "github.com/valyala/quicktemplate"
+)
+
+//line app/vlselect/logsql/stats_query_range_response.qtpl:8
+var (
+	_ = qtio422016.Copy
+	_ = qt422016.AcquireByteBuffer
+)
+
+//line app/vlselect/logsql/stats_query_range_response.qtpl:8
+func StreamStatsQueryRangeResponse(qw422016 *qt422016.Writer, m map[string]*statsSeries) {
+//line app/vlselect/logsql/stats_query_range_response.qtpl:8
+	qw422016.N().S(`{`)
+//line app/vlselect/logsql/stats_query_range_response.qtpl:11
+	sortedKeys := make([]string, 0, len(m))
+	for k := range m {
+		sortedKeys = append(sortedKeys, k)
+	}
+	sort.Strings(sortedKeys)
+
+//line app/vlselect/logsql/stats_query_range_response.qtpl:16
+	qw422016.N().S(`"status":"success","data":{"resultType":"matrix","result":[`)
+//line app/vlselect/logsql/stats_query_range_response.qtpl:21
+	if len(sortedKeys) > 0 {
+//line app/vlselect/logsql/stats_query_range_response.qtpl:22
+		streamstatsSeriesLine(qw422016, m, sortedKeys[0])
+//line app/vlselect/logsql/stats_query_range_response.qtpl:23
+		for _, k := range sortedKeys[1:] {
+//line app/vlselect/logsql/stats_query_range_response.qtpl:23
+			qw422016.N().S(`,`)
+//line app/vlselect/logsql/stats_query_range_response.qtpl:24
+			streamstatsSeriesLine(qw422016, m, k)
+//line app/vlselect/logsql/stats_query_range_response.qtpl:25
+		}
+//line app/vlselect/logsql/stats_query_range_response.qtpl:26
+	}
+//line app/vlselect/logsql/stats_query_range_response.qtpl:26
+	qw422016.N().S(`]}}`)
+//line app/vlselect/logsql/stats_query_range_response.qtpl:30
+}
+
+//line app/vlselect/logsql/stats_query_range_response.qtpl:30
+func WriteStatsQueryRangeResponse(qq422016 qtio422016.Writer, m map[string]*statsSeries) {
+//line app/vlselect/logsql/stats_query_range_response.qtpl:30
+	qw422016 := qt422016.AcquireWriter(qq422016)
+//line app/vlselect/logsql/stats_query_range_response.qtpl:30
+	StreamStatsQueryRangeResponse(qw422016, m)
+//line app/vlselect/logsql/stats_query_range_response.qtpl:30
+	qt422016.ReleaseWriter(qw422016)
+//line app/vlselect/logsql/stats_query_range_response.qtpl:30
+}
+
+//line app/vlselect/logsql/stats_query_range_response.qtpl:30
+func StatsQueryRangeResponse(m map[string]*statsSeries) string {
+//line app/vlselect/logsql/stats_query_range_response.qtpl:30
+	qb422016 := qt422016.AcquireByteBuffer()
+//line app/vlselect/logsql/stats_query_range_response.qtpl:30
+	WriteStatsQueryRangeResponse(qb422016, m)
+//line app/vlselect/logsql/stats_query_range_response.qtpl:30
+	qs422016 := string(qb422016.B)
+//line app/vlselect/logsql/stats_query_range_response.qtpl:30
+	qt422016.ReleaseByteBuffer(qb422016)
+//line app/vlselect/logsql/stats_query_range_response.qtpl:30
+	return qs422016
+//line app/vlselect/logsql/stats_query_range_response.qtpl:30
+}
+
+//line app/vlselect/logsql/stats_query_range_response.qtpl:32
+func streamstatsSeriesLine(qw422016 *qt422016.Writer, m map[string]*statsSeries, k string) {
+//line app/vlselect/logsql/stats_query_range_response.qtpl:32
+	qw422016.N().S(`{`)
+//line app/vlselect/logsql/stats_query_range_response.qtpl:35
+	ss := m[k]
+	ss.sort()
+	timestamps := ss.timestamps
+	values := ss.values
+
+//line app/vlselect/logsql/stats_query_range_response.qtpl:39
+	qw422016.N().S(`"metric":`)
+//line app/vlselect/logsql/stats_query_range_response.qtpl:40
+	qw422016.N().S(k)
+//line app/vlselect/logsql/stats_query_range_response.qtpl:40
+	qw422016.N().S(`,"values":[`)
+//line app/vlselect/logsql/stats_query_range_response.qtpl:42
+	if len(timestamps) > 0 {
+//line app/vlselect/logsql/stats_query_range_response.qtpl:43
+		streamstatsPoint(qw422016, timestamps[0], values[0])
+//line app/vlselect/logsql/stats_query_range_response.qtpl:44
+		for i := range timestamps[1:] {
+//line app/vlselect/logsql/stats_query_range_response.qtpl:44
+			qw422016.N().S(`,`)
+//line app/vlselect/logsql/stats_query_range_response.qtpl:45
+			streamstatsPoint(qw422016, timestamps[i+1], values[i+1])
+//line app/vlselect/logsql/stats_query_range_response.qtpl:46
+		}
+//line app/vlselect/logsql/stats_query_range_response.qtpl:47
+	}
+//line app/vlselect/logsql/stats_query_range_response.qtpl:47
+	qw422016.N().S(`]}`)
+//line app/vlselect/logsql/stats_query_range_response.qtpl:50
+}
+
+//line app/vlselect/logsql/stats_query_range_response.qtpl:50
+func writestatsSeriesLine(qq422016 qtio422016.Writer, m map[string]*statsSeries, k string) {
+//line app/vlselect/logsql/stats_query_range_response.qtpl:50
+	qw422016 := qt422016.AcquireWriter(qq422016)
+//line app/vlselect/logsql/stats_query_range_response.qtpl:50
+	streamstatsSeriesLine(qw422016, m, k)
+//line app/vlselect/logsql/stats_query_range_response.qtpl:50
+	qt422016.ReleaseWriter(qw422016)
+//line app/vlselect/logsql/stats_query_range_response.qtpl:50
+}
+
+//line app/vlselect/logsql/stats_query_range_response.qtpl:50
+func statsSeriesLine(m map[string]*statsSeries, k string) string {
+//line app/vlselect/logsql/stats_query_range_response.qtpl:50
+	qb422016 := qt422016.AcquireByteBuffer()
+//line app/vlselect/logsql/stats_query_range_response.qtpl:50
+	writestatsSeriesLine(qb422016, m, k)
+//line app/vlselect/logsql/stats_query_range_response.qtpl:50
+	qs422016 := string(qb422016.B)
+//line app/vlselect/logsql/stats_query_range_response.qtpl:50
+	qt422016.ReleaseByteBuffer(qb422016)
+//line app/vlselect/logsql/stats_query_range_response.qtpl:50
+	return qs422016
+//line app/vlselect/logsql/stats_query_range_response.qtpl:50
+}
+
+//line app/vlselect/logsql/stats_query_range_response.qtpl:52
+func streamstatsPoint(qw422016 *qt422016.Writer, timestamp int64, value string) {
+//line app/vlselect/logsql/stats_query_range_response.qtpl:52
+	qw422016.N().S(`[`)
+//line app/vlselect/logsql/stats_query_range_response.qtpl:53
+	qw422016.N().F(float64(timestamp) / 1e9)
+//line app/vlselect/logsql/stats_query_range_response.qtpl:53
+	qw422016.N().S(`,`)
+//line app/vlselect/logsql/stats_query_range_response.qtpl:53
+	qw422016.N().Q(value)
+//line app/vlselect/logsql/stats_query_range_response.qtpl:53
+	qw422016.N().S(`]`)
+//line app/vlselect/logsql/stats_query_range_response.qtpl:54
+}
+
+//line app/vlselect/logsql/stats_query_range_response.qtpl:54
+func writestatsPoint(qq422016 qtio422016.Writer, timestamp int64, value string) {
+//line app/vlselect/logsql/stats_query_range_response.qtpl:54
+	qw422016 := qt422016.AcquireWriter(qq422016)
+//line app/vlselect/logsql/stats_query_range_response.qtpl:54
+	streamstatsPoint(qw422016, timestamp, value)
+//line app/vlselect/logsql/stats_query_range_response.qtpl:54
+	qt422016.ReleaseWriter(qw422016)
+//line app/vlselect/logsql/stats_query_range_response.qtpl:54
+}
+
+//line app/vlselect/logsql/stats_query_range_response.qtpl:54
+func statsPoint(timestamp int64, value string) string {
+//line app/vlselect/logsql/stats_query_range_response.qtpl:54
+	qb422016 := qt422016.AcquireByteBuffer()
+//line app/vlselect/logsql/stats_query_range_response.qtpl:54
+	writestatsPoint(qb422016, timestamp, value)
+//line app/vlselect/logsql/stats_query_range_response.qtpl:54
+	qs422016 := string(qb422016.B)
+//line app/vlselect/logsql/stats_query_range_response.qtpl:54
+	qt422016.ReleaseByteBuffer(qb422016)
+//line app/vlselect/logsql/stats_query_range_response.qtpl:54
+	return qs422016
+//line app/vlselect/logsql/stats_query_range_response.qtpl:54
+}
@@ -0,0 +1,166 @@
+// Code generated by qtc from "stats_query_response.qtpl". DO NOT EDIT.
+// See https://github.com/valyala/quicktemplate for details.
+
+//line app/vlselect/logsql/stats_query_response.qtpl:1
+package logsql
+
+//line app/vlselect/logsql/stats_query_response.qtpl:1
+import (
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logstorage"
+)
+
+// StatsQueryMetric formats metric labels for /select/logsql/stats_query and /select/logsql/stats_query_range responses
+
+//line app/vlselect/logsql/stats_query_response.qtpl:8
+import (
+	qtio422016 "io"
+
+	qt422016 "github.com/valyala/quicktemplate"
+)
+
+//line app/vlselect/logsql/stats_query_response.qtpl:8
+var (
+	_ = qtio422016.Copy
+	_ = qt422016.AcquireByteBuffer
+)
+
+//line app/vlselect/logsql/stats_query_response.qtpl:8
+func StreamStatsQueryMetric(qw422016 *qt422016.Writer, name string, columns []logstorage.BlockColumn, rowIdx int) {
+//line app/vlselect/logsql/stats_query_response.qtpl:8
+	qw422016.N().S(`{"__name__":`)
+//line app/vlselect/logsql/stats_query_response.qtpl:10
+	qw422016.N().Q(name)
+//line app/vlselect/logsql/stats_query_response.qtpl:11
+	for _, c := range columns {
+//line app/vlselect/logsql/stats_query_response.qtpl:11
+		qw422016.N().S(`,`)
+//line app/vlselect/logsql/stats_query_response.qtpl:12
+		qw422016.N().Q(c.Name)
+//line app/vlselect/logsql/stats_query_response.qtpl:12
+		qw422016.N().S(`:`)
+//line app/vlselect/logsql/stats_query_response.qtpl:12
+		qw422016.N().Q(c.Values[rowIdx])
+//line app/vlselect/logsql/stats_query_response.qtpl:13
+	}
+//line app/vlselect/logsql/stats_query_response.qtpl:13
+	qw422016.N().S(`}`)
+//line app/vlselect/logsql/stats_query_response.qtpl:15
+}
+
+//line app/vlselect/logsql/stats_query_response.qtpl:15
+func WriteStatsQueryMetric(qq422016 qtio422016.Writer, name string, columns []logstorage.BlockColumn, rowIdx int) {
+//line app/vlselect/logsql/stats_query_response.qtpl:15
+	qw422016 := qt422016.AcquireWriter(qq422016)
+//line app/vlselect/logsql/stats_query_response.qtpl:15
+	StreamStatsQueryMetric(qw422016, name, columns, rowIdx)
+//line app/vlselect/logsql/stats_query_response.qtpl:15
+	qt422016.ReleaseWriter(qw422016)
+//line app/vlselect/logsql/stats_query_response.qtpl:15
+}
+
+//line app/vlselect/logsql/stats_query_response.qtpl:15
+func StatsQueryMetric(name string, columns []logstorage.BlockColumn, rowIdx int) string {
+//line app/vlselect/logsql/stats_query_response.qtpl:15
+	qb422016 := qt422016.AcquireByteBuffer()
+//line app/vlselect/logsql/stats_query_response.qtpl:15
+	WriteStatsQueryMetric(qb422016, name, columns, rowIdx)
+//line app/vlselect/logsql/stats_query_response.qtpl:15
+	qs422016 := string(qb422016.B)
+//line app/vlselect/logsql/stats_query_response.qtpl:15
+	qt422016.ReleaseByteBuffer(qb422016)
+//line app/vlselect/logsql/stats_query_response.qtpl:15
+	return qs422016
+//line app/vlselect/logsql/stats_query_response.qtpl:15
+}
+
+// StatsQueryResponse generates response for /select/logsql/stats_query
+
+//line app/vlselect/logsql/stats_query_response.qtpl:18
+func StreamStatsQueryResponse(qw422016 *qt422016.Writer, rows []statsRow, timestamp int64) {
+//line app/vlselect/logsql/stats_query_response.qtpl:18
+	qw422016.N().S(`{"status":"success","data":{"resultType":"vector","result":[`)
+//line app/vlselect/logsql/stats_query_response.qtpl:24
+	if len(rows) > 0 {
+//line app/vlselect/logsql/stats_query_response.qtpl:25
+		streamstatsRowLine(qw422016, rows[0], timestamp)
+//line app/vlselect/logsql/stats_query_response.qtpl:26
+		for _, r := range rows[1:] {
+//line app/vlselect/logsql/stats_query_response.qtpl:26
+			qw422016.N().S(`,`)
+//line app/vlselect/logsql/stats_query_response.qtpl:27
+			streamstatsRowLine(qw422016, r, timestamp)
+//line app/vlselect/logsql/stats_query_response.qtpl:28
+		}
+//line app/vlselect/logsql/stats_query_response.qtpl:29
+	}
+//line app/vlselect/logsql/stats_query_response.qtpl:29
+	qw422016.N().S(`]}}`)
+//line app/vlselect/logsql/stats_query_response.qtpl:33
+}
+
+//line app/vlselect/logsql/stats_query_response.qtpl:33
+func WriteStatsQueryResponse(qq422016 qtio422016.Writer, rows []statsRow, timestamp int64) {
+//line app/vlselect/logsql/stats_query_response.qtpl:33
+	qw422016 := qt422016.AcquireWriter(qq422016)
+//line app/vlselect/logsql/stats_query_response.qtpl:33
+	StreamStatsQueryResponse(qw422016, rows, timestamp)
+//line app/vlselect/logsql/stats_query_response.qtpl:33
+	qt422016.ReleaseWriter(qw422016)
+//line app/vlselect/logsql/stats_query_response.qtpl:33
+}
+
+//line app/vlselect/logsql/stats_query_response.qtpl:33
+func StatsQueryResponse(rows []statsRow, timestamp int64) string {
+//line app/vlselect/logsql/stats_query_response.qtpl:33
+	qb422016 := qt422016.AcquireByteBuffer()
+//line app/vlselect/logsql/stats_query_response.qtpl:33
+	WriteStatsQueryResponse(qb422016, rows, timestamp)
+//line app/vlselect/logsql/stats_query_response.qtpl:33
+	qs422016 := string(qb422016.B)
+//line app/vlselect/logsql/stats_query_response.qtpl:33
+	qt422016.ReleaseByteBuffer(qb422016)
+//line app/vlselect/logsql/stats_query_response.qtpl:33
+	return qs422016
+//line app/vlselect/logsql/stats_query_response.qtpl:33
+}
+
+//line app/vlselect/logsql/stats_query_response.qtpl:35
+func streamstatsRowLine(qw422016 *qt422016.Writer, r statsRow, timestamp int64) {
+//line app/vlselect/logsql/stats_query_response.qtpl:35
+	qw422016.N().S(`{"metric":`)
+//line app/vlselect/logsql/stats_query_response.qtpl:37
+	qw422016.N().S(r.metric)
+//line app/vlselect/logsql/stats_query_response.qtpl:37
+	qw422016.N().S(`,"value":`)
+//line app/vlselect/logsql/stats_query_response.qtpl:38
+	streamstatsPoint(qw422016, timestamp, r.value)
+//line app/vlselect/logsql/stats_query_response.qtpl:38
+	qw422016.N().S(`}`)
+//line app/vlselect/logsql/stats_query_response.qtpl:40
+}
+
+//line app/vlselect/logsql/stats_query_response.qtpl:40
+func writestatsRowLine(qq422016 qtio422016.Writer, r statsRow, timestamp int64) {
+//line app/vlselect/logsql/stats_query_response.qtpl:40
+	qw422016 := qt422016.AcquireWriter(qq422016)
+//line app/vlselect/logsql/stats_query_response.qtpl:40
+	streamstatsRowLine(qw422016, r, timestamp)
+//line app/vlselect/logsql/stats_query_response.qtpl:40
+	qt422016.ReleaseWriter(qw422016)
+//line app/vlselect/logsql/stats_query_response.qtpl:40
+}
+
+//line app/vlselect/logsql/stats_query_response.qtpl:40
+func statsRowLine(r statsRow, timestamp int64) string {
+//line app/vlselect/logsql/stats_query_response.qtpl:40
+	qb422016 := qt422016.AcquireByteBuffer()
+//line app/vlselect/logsql/stats_query_response.qtpl:40
+	writestatsRowLine(qb422016, r, timestamp)
+//line app/vlselect/logsql/stats_query_response.qtpl:40
+	qs422016 := string(qb422016.B)
+//line app/vlselect/logsql/stats_query_response.qtpl:40
+	qt422016.ReleaseByteBuffer(qb422016)
+//line app/vlselect/logsql/stats_query_response.qtpl:40
+	return qs422016
+//line app/vlselect/logsql/stats_query_response.qtpl:40
+}
@@ -0,0 +1,115 @@
+// Code generated by qtc from "values_with_hits_response.qtpl". DO NOT EDIT.
+// See https://github.com/valyala/quicktemplate for details.
+
+//line app/vlselect/logsql/values_with_hits_response.qtpl:1
+package logsql
+
+//line app/vlselect/logsql/values_with_hits_response.qtpl:1
+import (
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logstorage"
+)
+
+// ValuesWithHitsJSON generates JSON from the given values.
+
+//line app/vlselect/logsql/values_with_hits_response.qtpl:8
+import (
+	qtio422016 "io"
+
+	qt422016 "github.com/valyala/quicktemplate"
+)
+
+//line app/vlselect/logsql/values_with_hits_response.qtpl:8
+var (
+	_ = qtio422016.Copy
+	_ = qt422016.AcquireByteBuffer
+)
+
+//line app/vlselect/logsql/values_with_hits_response.qtpl:8
+func StreamValuesWithHitsJSON(qw422016 *qt422016.Writer, values []logstorage.ValueWithHits) {
+//line app/vlselect/logsql/values_with_hits_response.qtpl:8
+	qw422016.N().S(`{"values":[`)
+//line app/vlselect/logsql/values_with_hits_response.qtpl:11
+	if len(values) > 0 {
+//line app/vlselect/logsql/values_with_hits_response.qtpl:12
+		streamvalueWithHitsJSON(qw422016, values[0])
+//line app/vlselect/logsql/values_with_hits_response.qtpl:13
+		for _, v := range values[1:] {
+//line app/vlselect/logsql/values_with_hits_response.qtpl:13
+			qw422016.N().S(`,`)
+//line app/vlselect/logsql/values_with_hits_response.qtpl:14
+			streamvalueWithHitsJSON(qw422016, v)
+//line app/vlselect/logsql/values_with_hits_response.qtpl:15
+		}
+//line app/vlselect/logsql/values_with_hits_response.qtpl:16
+	}
+//line app/vlselect/logsql/values_with_hits_response.qtpl:16
+	qw422016.N().S(`]}`)
+//line app/vlselect/logsql/values_with_hits_response.qtpl:19
+}
+
+//line app/vlselect/logsql/values_with_hits_response.qtpl:19
+func WriteValuesWithHitsJSON(qq422016 qtio422016.Writer, values []logstorage.ValueWithHits) {
+//line app/vlselect/logsql/values_with_hits_response.qtpl:19
+	qw422016 := qt422016.AcquireWriter(qq422016)
+//line app/vlselect/logsql/values_with_hits_response.qtpl:19
+	StreamValuesWithHitsJSON(qw422016, values)
+//line app/vlselect/logsql/values_with_hits_response.qtpl:19
+	qt422016.ReleaseWriter(qw422016)
+//line app/vlselect/logsql/values_with_hits_response.qtpl:19
+}
+
+//line app/vlselect/logsql/values_with_hits_response.qtpl:19
+func ValuesWithHitsJSON(values []logstorage.ValueWithHits) string {
+//line app/vlselect/logsql/values_with_hits_response.qtpl:19
+	qb422016 := qt422016.AcquireByteBuffer()
+//line app/vlselect/logsql/values_with_hits_response.qtpl:19
+	WriteValuesWithHitsJSON(qb422016, values)
+//line app/vlselect/logsql/values_with_hits_response.qtpl:19
+	qs422016 := string(qb422016.B)
+//line app/vlselect/logsql/values_with_hits_response.qtpl:19
+	qt422016.ReleaseByteBuffer(qb422016)
+//line app/vlselect/logsql/values_with_hits_response.qtpl:19
+	return qs422016
+//line app/vlselect/logsql/values_with_hits_response.qtpl:19
+}
+
+//line app/vlselect/logsql/values_with_hits_response.qtpl:21
+func streamvalueWithHitsJSON(qw422016 *qt422016.Writer, v logstorage.ValueWithHits) {
+//line app/vlselect/logsql/values_with_hits_response.qtpl:21
+	qw422016.N().S(`{"value":`)
+//line app/vlselect/logsql/values_with_hits_response.qtpl:23
+	qw422016.N().Q(v.Value)
+//line app/vlselect/logsql/values_with_hits_response.qtpl:23
+	qw422016.N().S(`,"hits":`)
+//line app/vlselect/logsql/values_with_hits_response.qtpl:24
+	qw422016.N().DUL(v.Hits)
+//line app/vlselect/logsql/values_with_hits_response.qtpl:24
+	qw422016.N().S(`}`)
+//line app/vlselect/logsql/values_with_hits_response.qtpl:26
+}
+
+//line app/vlselect/logsql/values_with_hits_response.qtpl:26
+func writevalueWithHitsJSON(qq422016 qtio422016.Writer, v logstorage.ValueWithHits) {
+//line app/vlselect/logsql/values_with_hits_response.qtpl:26
+	qw422016 := qt422016.AcquireWriter(qq422016)
+//line app/vlselect/logsql/values_with_hits_response.qtpl:26
+	streamvalueWithHitsJSON(qw422016, v)
+//line app/vlselect/logsql/values_with_hits_response.qtpl:26
+	qt422016.ReleaseWriter(qw422016)
+//line app/vlselect/logsql/values_with_hits_response.qtpl:26
+}
+
+//line app/vlselect/logsql/values_with_hits_response.qtpl:26
+func valueWithHitsJSON(v logstorage.ValueWithHits) string {
+//line app/vlselect/logsql/values_with_hits_response.qtpl:26
+	qb422016 := qt422016.AcquireByteBuffer()
+//line app/vlselect/logsql/values_with_hits_response.qtpl:26
+	writevalueWithHitsJSON(qb422016, v)
+//line app/vlselect/logsql/values_with_hits_response.qtpl:26
+	qs422016 := string(qb422016.B)
+//line app/vlselect/logsql/values_with_hits_response.qtpl:26
+	qt422016.ReleaseByteBuffer(qb422016)
+//line app/vlselect/logsql/values_with_hits_response.qtpl:26
+	return qs422016
+//line app/vlselect/logsql/values_with_hits_response.qtpl:26
+}
@@ -1,6 +1,7 @@
 package vlselect
 
 import (
+	"context"
 	"embed"
 	"flag"
 	"fmt"
@@ -9,10 +10,15 @@ import (
 	"time"
 
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vlselect/logsql"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vlselect/querystats"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vlstorage"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vlstorage/netselect"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/cgroup"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/flagutil"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/httpserver"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/httputils"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logstorage"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/timerpool"
 	"github.com/VictoriaMetrics/metrics"
 )
@@ -23,7 +29,12 @@ var (
 		"See also -search.maxQueueDuration")
 	maxQueueDuration = flag.Duration("search.maxQueueDuration", 10*time.Second, "The maximum time the search request waits for execution when -search.maxConcurrentRequests "+
 		"limit is reached; see also -search.maxQueryDuration")
-	maxQueryDuration = flag.Duration("search.maxQueryDuration", time.Second*30, "The maximum duration for query execution")
+	maxQueryDuration  = flag.Duration("search.maxQueryDuration", time.Second*30, "The maximum duration for query execution")
+	maxMemoryPerQuery = flagutil.NewBytes("search.maxMemoryPerQuery", 0, "The maximum amounts of memory a single query may consume for the state of all its stats, sort, uniq and stream_label_* pipes. "+
+		"Queries requiring more memory are rejected. If zero, then the limit is calculated from the available memory. "+
+		"See https://docs.victoriametrics.com/victorialogs/querying/#resource-usage-limits")
+	deleteAuthKey = flagutil.NewPassword("deleteAuthKey", "authKey for logs' deletion via /select/logsql/delete . "+
+		"See https://docs.victoriametrics.com/victorialogs/#deleting-logs")
 )
 
 func getDefaultMaxConcurrentRequests() int {
@@ -43,6 +54,7 @@ func getDefaultMaxConcurrentRequests() int {
 // Init initializes vlselect
 func Init() {
 	concurrencyLimitCh = make(chan struct{}, *maxConcurrentRequests)
+	logstorage.SetMaxMemoryPerQuery(maxMemoryPerQuery.N)
 }
 
 // Stop stops vlselect
@@ -71,6 +83,9 @@ var vmuiFileServer = http.FileServer(http.FS(vmuiFiles))
 // RequestHandler handles select requests for VictoriaLogs
 func RequestHandler(w http.ResponseWriter, r *http.Request) bool {
 	path := r.URL.Path
+	if path == netselect.QueryPath {
+		return internalQueryRequestHandler(w, r)
+	}
 	if !strings.HasPrefix(path, "/select/") {
 		// Skip requests, which do not start with /select/, since these aren't our requests.
 		return false
@@ -99,58 +114,188 @@ func RequestHandler(w http.ResponseWriter, r *http.Request) bool {
 		return true
 	}
 
-	// Limit the number of concurrent queries, which can consume big amounts of CPU.
-	startTime := time.Now()
-	ctx := r.Context()
-	stopCh := ctx.Done()
-	select {
-	case concurrencyLimitCh <- struct{}{}:
-		defer func() { <-concurrencyLimitCh }()
-	default:
-		// Sleep for a while until giving up. This should resolve short bursts in requests.
-		concurrencyLimitReached.Inc()
-		d := getMaxQueryDuration(r)
-		if d > *maxQueueDuration {
-			d = *maxQueueDuration
-		}
-		t := timerpool.Get(d)
-		select {
-		case concurrencyLimitCh <- struct{}{}:
-			timerpool.Put(t)
-			defer func() { <-concurrencyLimitCh }()
-		case <-stopCh:
-			timerpool.Put(t)
-			remoteAddr := httpserver.GetQuotedRemoteAddr(r)
-			requestURI := httpserver.GetRequestURI(r)
-			logger.Infof("client has cancelled the request after %.3f seconds: remoteAddr=%s, requestURI: %q",
-				time.Since(startTime).Seconds(), remoteAddr, requestURI)
+	if path == "/logsql/tail" {
+		// Live tailing requests execute queries every second until the client closes the connection,
+		// so every live tailing request occupies the concurrency limiter during its lifetime.
+		logsqlTailRequests.Inc()
+		if !acquireConcurrencyLimit(w, r, time.Now()) {
 			return true
-		case <-t.C:
-			timerpool.Put(t)
-			concurrencyLimitTimeout.Inc()
-			err := &httpserver.ErrorWithStatusCode{
-				Err: fmt.Errorf("couldn't start executing the request in %.3f seconds, since -search.maxConcurrentRequests=%d concurrent requests "+
-					"are executed. Possible solutions: to reduce query load; to add more compute resources to the server; "+
-					"to increase -search.maxQueueDuration=%s; to increase -search.maxQueryDuration; to increase -search.maxConcurrentRequests",
-					d.Seconds(), *maxConcurrentRequests, maxQueueDuration),
-				StatusCode: http.StatusServiceUnavailable,
-			}
-			httpserver.Errorf(w, r, "%s", err)
+		}
+		defer releaseConcurrencyLimit()
+		httpserver.EnableCORS(w, r)
+		logsql.ProcessTailRequest(r.Context(), w, r, getMaxQueryDuration(r))
+		return true
+	}
+
+	if path == "/logsql/delete" {
+		// Delete requests only register the filter for the deleted logs, so they mustn't occupy the concurrency limiter below.
+		if !httpserver.CheckAuthFlag(w, r, deleteAuthKey.Get(), "deleteAuthKey") {
 			return true
 		}
+		logsqlDeleteRequests.Inc()
+		logsql.ProcessDeleteRequest(w, r)
+		return true
+	}
+
+	if path == "/logsql/active_queries" {
+		logsqlActiveQueriesRequests.Inc()
+		httpserver.EnableCORS(w, r)
+		logsql.ProcessActiveQueriesRequest(w, r)
+		return true
+	}
+
+	if path == "/logsql/top_queries" {
+		logsqlTopQueriesRequests.Inc()
+		httpserver.EnableCORS(w, r)
+		logsql.ProcessTopQueriesRequest(w, r)
+		return true
+	}
+
+	// Limit the number of concurrent queries, which can consume big amounts of CPU.
+	startTime := time.Now()
+	ctx := r.Context()
+	if !acquireConcurrencyLimit(w, r, startTime) {
+		return true
 	}
+	defer releaseConcurrencyLimit()
+
+	// Track the query at /select/logsql/active_queries and /select/logsql/top_queries
+	qid := logsql.RegisterActiveQuery(r)
+	defer func() {
+		logsql.UnregisterActiveQuery(qid)
+		if querystats.Enabled() {
+			querystats.RegisterQuery(r.FormValue("query"), logsql.GetTimeRangeMsecs(r), startTime)
+		}
+	}()
 
 	switch {
+	case path == "/logsql/export/native":
+		logsqlExportNativeRequests.Inc()
+		logsql.ProcessExportNativeRequest(ctx, w, r)
+		return true
+	case path == "/logsql/field_names":
+		logsqlFieldNamesRequests.Inc()
+		httpserver.EnableCORS(w, r)
+		logsql.ProcessFieldNamesRequest(ctx, w, r)
+		return true
+	case path == "/logsql/field_values":
+		logsqlFieldValuesRequests.Inc()
+		httpserver.EnableCORS(w, r)
+		logsql.ProcessFieldValuesRequest(ctx, w, r)
+		return true
+	case path == "/logsql/streams":
+		logsqlStreamsRequests.Inc()
+		httpserver.EnableCORS(w, r)
+		logsql.ProcessStreamsRequest(ctx, w, r)
+		return true
+	case path == "/logsql/stream_label_names":
+		logsqlStreamLabelNamesRequests.Inc()
+		httpserver.EnableCORS(w, r)
+		logsql.ProcessStreamLabelNamesRequest(ctx, w, r)
+		return true
+	case path == "/logsql/stream_label_values":
+		logsqlStreamLabelValuesRequests.Inc()
+		httpserver.EnableCORS(w, r)
+		logsql.ProcessStreamLabelValuesRequest(ctx, w, r)
+		return true
+	case path == "/logsql/hits":
+		logsqlHitsRequests.Inc()
+		httpserver.EnableCORS(w, r)
+		logsql.ProcessHitsRequest(ctx, w, r)
+		return true
 	case path == "/logsql/query":
 		logsqlQueryRequests.Inc()
 		httpserver.EnableCORS(w, r)
 		logsql.ProcessQueryRequest(ctx, w, r)
 		return true
+	case path == "/logsql/stats_query":
+		logsqlStatsQueryRequests.Inc()
+		httpserver.EnableCORS(w, r)
+		logsql.ProcessStatsQueryRequest(ctx, w, r)
+		return true
+	case path == "/logsql/stats_query_range":
+		logsqlStatsQueryRangeRequests.Inc()
+		httpserver.EnableCORS(w, r)
+		logsql.ProcessStatsQueryRangeRequest(ctx, w, r)
+		return true
+	default:
+		return false
+	}
+}
+
+// internalQueryRequestHandler handles requests sent to storage nodes by VictoriaLogs instances with -storageNode command-line flag.
+//
+// These requests are executed under the same limits as /select/logsql/* requests.
+func internalQueryRequestHandler(w http.ResponseWriter, r *http.Request) bool {
+	if !vlstorage.HasLocalStorage() {
+		// The data is stored at -storageNode nodes.
+		return false
+	}
+	if !vlstorage.CheckInternalAuthKey(w, r) {
+		return true
+	}
+
+	startTime := time.Now()
+	if !acquireConcurrencyLimit(w, r, startTime) {
+		return true
+	}
+	defer releaseConcurrencyLimit()
+
+	ctx, cancel := context.WithTimeout(r.Context(), getMaxQueryDuration(r))
+	defer cancel()
+	vlstorage.ProcessInternalQueryRequest(ctx, w, r)
+	return true
+}
+
+// acquireConcurrencyLimit waits until r can be executed according to -search.maxConcurrentRequests limit.
+//
+// It returns false if r cannot be executed. The error is written to w in this case.
+// releaseConcurrencyLimit must be called after r is executed if true is returned.
+func acquireConcurrencyLimit(w http.ResponseWriter, r *http.Request, startTime time.Time) bool {
+	select {
+	case concurrencyLimitCh <- struct{}{}:
+		return true
 	default:
+	}
+
+	// Sleep for a while until giving up. This should resolve short bursts in requests.
+	concurrencyLimitReached.Inc()
+	d := getMaxQueryDuration(r)
+	if d > *maxQueueDuration {
+		d = *maxQueueDuration
+	}
+	t := timerpool.Get(d)
+	select {
+	case concurrencyLimitCh <- struct{}{}:
+		timerpool.Put(t)
+		return true
+	case <-r.Context().Done():
+		timerpool.Put(t)
+		remoteAddr := httpserver.GetQuotedRemoteAddr(r)
+		requestURI := httpserver.GetRequestURI(r)
+		logger.Infof("client has cancelled the request after %.3f seconds: remoteAddr=%s, requestURI: %q",
+			time.Since(startTime).Seconds(), remoteAddr, requestURI)
+		return false
+	case <-t.C:
+		timerpool.Put(t)
+		concurrencyLimitTimeout.Inc()
+		err := &httpserver.ErrorWithStatusCode{
+			Err: fmt.Errorf("couldn't start executing the request in %.3f seconds, since -search.maxConcurrentRequests=%d concurrent requests "+
+				"are executed. Possible solutions: to reduce query load; to add more compute resources to the server; "+
+				"to increase -search.maxQueueDuration=%s; to increase -search.maxQueryDuration; to increase -search.maxConcurrentRequests",
+				d.Seconds(), *maxConcurrentRequests, maxQueueDuration),
+			StatusCode: http.StatusServiceUnavailable,
+		}
+		httpserver.Errorf(w, r, "%s", err)
 		return false
 	}
 }
 
+// releaseConcurrencyLimit releases the concurrency limit obtained via acquireConcurrencyLimit.
+func releaseConcurrencyLimit() {
+	<-concurrencyLimitCh
+}
+
 // getMaxQueryDuration returns the maximum duration for query from r.
 func getMaxQueryDuration(r *http.Request) time.Duration {
 	dms, err := httputils.GetDuration(r, "timeout", 0)
@@ -165,5 +310,18 @@ func getMaxQueryDuration(r *http.Request) time.Duration {
 }
 
 var (
-	logsqlQueryRequests = metrics.NewCounter(`vl_http_requests_total{path="/select/logsql/query"}`)
+	logsqlActiveQueriesRequests     = metrics.NewCounter(`vl_http_requests_total{path="/select/logsql/active_queries"}`)
+	logsqlDeleteRequests            = metrics.NewCounter(`vl_http_requests_total{path="/select/logsql/delete"}`)
+	logsqlExportNativeRequests      = metrics.NewCounter(`vl_http_requests_total{path="/select/logsql/export/native"}`)
+	logsqlFieldNamesRequests        = metrics.NewCounter(`vl_http_requests_total{path="/select/logsql/field_names"}`)
+	logsqlFieldValuesRequests       = metrics.NewCounter(`vl_http_requests_total{path="/select/logsql/field_values"}`)
+	logsqlHitsRequests              = metrics.NewCounter(`vl_http_requests_total{path="/select/logsql/hits"}`)
+	logsqlQueryRequests             = metrics.NewCounter(`vl_http_requests_total{path="/select/logsql/query"}`)
+	logsqlStatsQueryRequests        = metrics.NewCounter(`vl_http_requests_total{path="/select/logsql/stats_query"}`)
+	logsqlStatsQueryRangeRequests   = metrics.NewCounter(`vl_http_requests_total{path="/select/logsql/stats_query_range"}`)
+	logsqlStreamLabelNamesRequests  = metrics.NewCounter(`vl_http_requests_total{path="/select/logsql/stream_label_names"}`)
+	logsqlStreamLabelValuesRequests = metrics.NewCounter(`vl_http_requests_total{path="/select/logsql/stream_label_values"}`)
+	logsqlStreamsRequests           = metrics.NewCounter(`vl_http_requests_total{path="/select/logsql/streams"}`)
+	logsqlTailRequests              = metrics.NewCounter(`vl_http_requests_total{path="/select/logsql/tail"}`)
+	logsqlTopQueriesRequests        = metrics.NewCounter(`vl_http_requests_total{path="/select/logsql/top_queries"}`)
 )
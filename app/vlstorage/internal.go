@@ -0,0 +1,191 @@
+package vlstorage
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/VictoriaMetrics/metrics"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vlstorage/netinsert"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vlstorage/netselect"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/encoding"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/flagutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/httpserver"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logstorage"
+)
+
+var maxInternalInsertRequestSize = flagutil.NewBytes("internalinsert.maxRequestSize", 64*1024*1024, "The maximum size in bytes of a single request "+
+	"with log entries sent from VictoriaLogs instances with -storageNode command-line flag")
+
+// internalRequestHandler handles insert requests sent by VictoriaLogs instances with -storageNode command-line flag.
+//
+// Query requests are handled by vlselect via ProcessInternalQueryRequest, since they must be executed under the same limits as /select/logsql/* requests.
+func internalRequestHandler(w http.ResponseWriter, r *http.Request) bool {
+	if strg == nil {
+		// The data is stored at -storageNode nodes.
+		return false
+	}
+	if r.URL.Path != netinsert.InsertPath {
+		return false
+	}
+	if !CheckInternalAuthKey(w, r) {
+		return true
+	}
+	internalInsertRequests.Inc()
+	if err := processInternalInsertRequest(r); err != nil {
+		internalInsertRequestErrors.Inc()
+		httpserver.Errorf(w, r, "%s", err)
+		return true
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return true
+}
+
+// CheckInternalAuthKey verifies -internalAuthKey for the request to /internal/* endpoint.
+//
+// It returns false and writes the error to w if r mustn't be processed.
+// /internal/* endpoints are disabled if -internalAuthKey isn't set, since they would accept the data and queries from anyone otherwise.
+func CheckInternalAuthKey(w http.ResponseWriter, r *http.Request) bool {
+	authKey := internalAuthKey.Get()
+	if authKey == "" {
+		http.Error(w, "/internal/* endpoints are disabled, since -internalAuthKey command-line flag isn't set; "+
+			"set it to the same value at storage nodes and at VictoriaLogs with -storageNode command-line flag", http.StatusForbidden)
+		return false
+	}
+	return httpserver.CheckAuthFlag(w, r, authKey, "internalAuthKey")
+}
+
+// ProcessInternalQueryRequest processes the query request sent by VictoriaLogs instances with -storageNode command-line flag.
+//
+// The caller must verify the request with CheckInternalAuthKey and limit its concurrency and duration via ctx.
+func ProcessInternalQueryRequest(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	internalQueryRequests.Inc()
+	if err := processInternalQueryRequest(ctx, w, r); err != nil {
+		internalQueryRequestErrors.Inc()
+		httpserver.Errorf(w, r, "%s", err)
+	}
+}
+
+func processInternalInsertRequest(r *http.Request) error {
+	if err := CanWriteData(); err != nil {
+		return err
+	}
+
+	maxRequestSize := maxInternalInsertRequestSize.IntN()
+	bb := bbPool.Get()
+	defer bbPool.Put(bb)
+	lr := io.LimitReader(r.Body, int64(maxRequestSize)+1)
+	if _, err := bb.ReadFrom(lr); err != nil {
+		return fmt.Errorf("cannot read request body: %w", err)
+	}
+	if len(bb.B) > maxRequestSize {
+		return fmt.Errorf("too big request body; it mustn't exceed -internalinsert.maxRequestSize=%d bytes", maxRequestSize)
+	}
+
+	data := bbPool.Get()
+	defer bbPool.Put(data)
+	var err error
+	data.B, err = encoding.DecompressZSTD(data.B[:0], bb.B)
+	if err != nil {
+		return fmt.Errorf("cannot decompress request body: %w", err)
+	}
+
+	rows := logstorage.GetLogRows(nil, nil)
+	defer logstorage.PutLogRows(rows)
+	if err := rows.AddMarshaledRows(data.B); err != nil {
+		return fmt.Errorf("cannot unmarshal log entries: %w", err)
+	}
+	strg.MustAddRows(rows)
+	return nil
+}
+
+func processInternalQueryRequest(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	var tenantIDs []logstorage.TenantID
+	if s := r.FormValue("tenant_ids"); s != "" {
+		for _, tenantIDStr := range strings.Split(s, ",") {
+			tenantID, err := logstorage.GetTenantIDFromString(tenantIDStr)
+			if err != nil {
+				return fmt.Errorf("cannot parse tenant_ids=%q: %w", s, err)
+			}
+			tenantIDs = append(tenantIDs, tenantID)
+		}
+	}
+	timestamp, err := strconv.ParseInt(r.FormValue("timestamp"), 10, 64)
+	if err != nil {
+		return fmt.Errorf("cannot parse timestamp: %w", err)
+	}
+	qStr := r.FormValue("query")
+	q, err := logstorage.ParseRemoteQueryAtTimestamp(qStr, timestamp)
+	if err != nil {
+		return fmt.Errorf("cannot parse query [%s]: %w", qStr, err)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	bw := bufio.NewWriter(w)
+	var bwLock sync.Mutex
+	var errWrite error
+	writeBlock := func(_ uint, timestamps []int64, columns []logstorage.BlockColumn) {
+		if len(timestamps) == 0 {
+			return
+		}
+
+		bb := bbPool.Get()
+		bb.B = logstorage.MarshalBlockColumns(bb.B[:0], timestamps, columns)
+		data := bbPool.Get()
+		data.B = encoding.CompressZSTDLevel(data.B[:0], bb.B, 1)
+		bbPool.Put(bb)
+
+		bwLock.Lock()
+		if errWrite == nil {
+			if err := netselect.WriteFrame(bw, data.B); err != nil {
+				// The client has been disconnected. Stop the query execution.
+				errWrite = err
+				cancel()
+			}
+		}
+		bwLock.Unlock()
+
+		bbPool.Put(data)
+	}
+
+	errQuery := strg.RunQuery(ctx, tenantIDs, q, writeBlock)
+	if errWrite != nil {
+		return nil
+	}
+
+	// Write the end marker followed by the optional error message.
+	// The error message is sent in the response body, since the response status code has been already sent.
+	var errMsg []byte
+	if errQuery != nil {
+		internalQueryRequestErrors.Inc()
+		errMsg = bytesutil.ToUnsafeBytes(errQuery.Error())
+	}
+	if err := netselect.WriteFrame(bw, nil); err != nil {
+		return nil
+	}
+	if err := netselect.WriteFrame(bw, errMsg); err != nil {
+		return nil
+	}
+	_ = bw.Flush()
+	return nil
+}
+
+var bbPool bytesutil.ByteBufferPool
+
+var (
+	internalInsertRequests      = metrics.NewCounter(`vl_http_requests_total{path="/internal/insert"}`)
+	internalInsertRequestErrors = metrics.NewCounter(`vl_http_request_errors_total{path="/internal/insert"}`)
+
+	internalQueryRequests      = metrics.NewCounter(`vl_http_requests_total{path="/internal/select/query"}`)
+	internalQueryRequestErrors = metrics.NewCounter(`vl_http_request_errors_total{path="/internal/select/query"}`)
+)
@@ -6,21 +6,30 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/VictoriaMetrics/metrics"
 
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vlstorage/netinsert"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vlstorage/netselect"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/flagutil"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fs"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/httpserver"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logstorage"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/timeutil"
 )
 
 var (
 	retentionPeriod = flagutil.NewDuration("retentionPeriod", "7d", "Log entries with timestamps older than now-retentionPeriod are automatically deleted; "+
 		"log entries with timestamps outside the retention are also rejected during data ingestion; the minimum supported retention is 1d (one day); "+
 		"see https://docs.victoriametrics.com/VictoriaLogs/#retention")
+	retentionFilters = flagutil.NewArrayString("retentionFilter", "Retention filter in the form '[tenant=<accountID>:<projectID>] [{stream filter}]:<retention>'. "+
+		"Log entries matching the filter are deleted after the given retention. The first matching filter is applied to every log stream. "+
+		"The retention in the filter cannot exceed -retentionPeriod. For example, -retentionFilter='{app=\"nginx\"}:3d' deletes logs for streams with app=\"nginx\" after 3 days; "+
+		"see https://docs.victoriametrics.com/VictoriaLogs/#retention-filters")
 	futureRetention = flagutil.NewDuration("futureRetention", "2d", "Log entries with timestamps bigger than now+futureRetention are rejected during data ingestion; "+
 		"see https://docs.victoriametrics.com/VictoriaLogs/#retention")
 	storageDataPath = flag.String("storageDataPath", "victoria-logs-data", "Path to directory with the VictoriaLogs data; "+
@@ -35,21 +44,55 @@ var (
 		"see https://docs.victoriametrics.com/VictoriaLogs/data-ingestion/ ; see also -logNewStreams")
 	minFreeDiskSpaceBytes = flagutil.NewBytes("storage.minFreeDiskSpaceBytes", 10e6, "The minimum free disk space at -storageDataPath after which "+
 		"the storage stops accepting new data")
+	snapshotAuthKey = flagutil.NewPassword("snapshotAuthKey", "authKey, which must be passed in query string to /snapshot* pages; "+
+		"see https://docs.victoriametrics.com/VictoriaLogs/#backup-and-restore")
+	snapshotsMaxAge = flagutil.NewDuration("snapshotsMaxAge", "0", "Automatically delete snapshots older than -snapshotsMaxAge if it is set to non-zero duration. "+
+		"Make sure that backup process has enough time to finish the backup before the corresponding snapshot is automatically deleted")
+	storageNodeAddrs = flagutil.NewArrayString("storageNode", "Comma-separated addresses of VictoriaLogs storage nodes to store and query the data at. "+
+		"If set, then the data isn't stored locally at -storageDataPath. "+
+		"The ingested logs are spread evenly among the storage nodes by log stream, while queries are executed at all the storage nodes; "+
+		"see https://docs.victoriametrics.com/VictoriaLogs/#cluster-mode")
+	internalAuthKey = flagutil.NewPassword("internalAuthKey", "authKey for /internal/* endpoints at storage nodes. VictoriaLogs with -storageNode command-line flag "+
+		"passes it to storage nodes, so it must be set to the same value at all the nodes. /internal/* endpoints are disabled if this flag isn't set. "+
+		"See https://docs.victoriametrics.com/VictoriaLogs/#cluster-mode")
 )
 
 // Init initializes vlstorage.
 //
 // Stop must be called when vlstorage is no longer needed
 func Init() {
-	if strg != nil {
+	if strg != nil || isClusterMode {
 		logger.Panicf("BUG: Init() has been already called")
 	}
 
+	if len(*storageNodeAddrs) > 0 {
+		if internalAuthKey.Get() == "" {
+			logger.Fatalf("-internalAuthKey must be set when -storageNode is set, since storage nodes reject requests to /internal/* endpoints without -internalAuthKey")
+		}
+		logger.Infof("storing the data at -storageNode=%q", *storageNodeAddrs)
+		netinsert.Init(*storageNodeAddrs, internalAuthKey.Get())
+		netselect.Init(*storageNodeAddrs, internalAuthKey.Get())
+		isClusterMode = true
+		return
+	}
+
 	if retentionPeriod.Duration() < 24*time.Hour {
 		logger.Fatalf("-retentionPeriod cannot be smaller than a day; got %s", retentionPeriod)
 	}
+	var retentionRules []*logstorage.RetentionRule
+	for _, s := range *retentionFilters {
+		rr, err := logstorage.ParseRetentionRule(s)
+		if err != nil {
+			logger.Fatalf("cannot parse -retentionFilter=%q: %s", s, err)
+		}
+		if rr.Retention > retentionPeriod.Duration() {
+			logger.Fatalf("the retention in -retentionFilter=%q cannot exceed -retentionPeriod=%s", s, retentionPeriod)
+		}
+		retentionRules = append(retentionRules, rr)
+	}
 	cfg := &logstorage.StorageConfig{
 		Retention:             retentionPeriod.Duration(),
+		RetentionRules:        retentionRules,
 		FlushInterval:         *inmemoryDataFlushInterval,
 		FutureRetention:       futureRetention.Duration(),
 		LogNewStreams:         *logNewStreams,
@@ -73,10 +116,21 @@ func Init() {
 		writeStorageMetrics(w, strg)
 	})
 	metrics.RegisterSet(storageMetrics)
+
+	initStaleSnapshotsRemover(strg)
 }
 
 // Stop stops vlstorage.
 func Stop() {
+	if isClusterMode {
+		netinsert.Stop()
+		netselect.Stop()
+		isClusterMode = false
+		return
+	}
+
+	stopStaleSnapshotsRemover()
+
 	metrics.UnregisterSet(storageMetrics)
 	storageMetrics = nil
 
@@ -87,8 +141,162 @@ func Stop() {
 var strg *logstorage.Storage
 var storageMetrics *metrics.Set
 
+// isClusterMode is set to true if the data is stored at -storageNode nodes instead of the local storage.
+var isClusterMode bool
+
+// HasLocalStorage returns true if the data is stored locally at -storageDataPath instead of -storageNode nodes.
+func HasLocalStorage() bool {
+	return strg != nil
+}
+
+// RequestHandler is a storage request handler.
+func RequestHandler(w http.ResponseWriter, r *http.Request) bool {
+	path := r.URL.Path
+	if strings.HasPrefix(path, "/internal/") {
+		return internalRequestHandler(w, r)
+	}
+	if !strings.HasPrefix(path, "/snapshot") {
+		return false
+	}
+	if isClusterMode {
+		httpserver.Errorf(w, r, "snapshots must be created directly at -storageNode nodes")
+		return true
+	}
+	if !httpserver.CheckAuthFlag(w, r, snapshotAuthKey.Get(), "snapshotAuthKey") {
+		return true
+	}
+	path = path[len("/snapshot"):]
+
+	switch path {
+	case "/create":
+		snapshotsCreateTotal.Inc()
+		w.Header().Set("Content-Type", "application/json")
+		snapshotName, err := strg.CreateSnapshot()
+		if err != nil {
+			err = fmt.Errorf("cannot create snapshot: %w", err)
+			jsonResponseError(w, err)
+			snapshotsCreateErrorsTotal.Inc()
+			return true
+		}
+		fmt.Fprintf(w, `{"status":"ok","snapshot":%q}`, snapshotName)
+		return true
+	case "/list":
+		snapshotsListTotal.Inc()
+		w.Header().Set("Content-Type", "application/json")
+		snapshots, err := strg.ListSnapshots()
+		if err != nil {
+			err = fmt.Errorf("cannot list snapshots: %w", err)
+			jsonResponseError(w, err)
+			snapshotsListErrorsTotal.Inc()
+			return true
+		}
+		fmt.Fprintf(w, `{"status":"ok","snapshots":[`)
+		if len(snapshots) > 0 {
+			for _, snapshot := range snapshots[:len(snapshots)-1] {
+				fmt.Fprintf(w, "\n%q,", snapshot)
+			}
+			fmt.Fprintf(w, "\n%q\n", snapshots[len(snapshots)-1])
+		}
+		fmt.Fprintf(w, `]}`)
+		return true
+	case "/delete":
+		snapshotsDeleteTotal.Inc()
+		w.Header().Set("Content-Type", "application/json")
+		snapshotName := r.FormValue("snapshot")
+		if err := strg.DeleteSnapshot(snapshotName); err != nil {
+			err = fmt.Errorf("cannot delete snapshot %q: %w", snapshotName, err)
+			jsonResponseError(w, err)
+			snapshotsDeleteErrorsTotal.Inc()
+			return true
+		}
+		fmt.Fprintf(w, `{"status":"ok"}`)
+		return true
+	case "/delete_all":
+		snapshotsDeleteAllTotal.Inc()
+		w.Header().Set("Content-Type", "application/json")
+		snapshots, err := strg.ListSnapshots()
+		if err != nil {
+			err = fmt.Errorf("cannot list snapshots: %w", err)
+			jsonResponseError(w, err)
+			snapshotsDeleteAllErrorsTotal.Inc()
+			return true
+		}
+		for _, snapshotName := range snapshots {
+			if err := strg.DeleteSnapshot(snapshotName); err != nil {
+				err = fmt.Errorf("cannot delete snapshot %q: %w", snapshotName, err)
+				jsonResponseError(w, err)
+				snapshotsDeleteAllErrorsTotal.Inc()
+				return true
+			}
+		}
+		fmt.Fprintf(w, `{"status":"ok"}`)
+		return true
+	default:
+		return false
+	}
+}
+
+func jsonResponseError(w http.ResponseWriter, err error) {
+	logger.Errorf("%s", err)
+	w.WriteHeader(http.StatusInternalServerError)
+	fmt.Fprintf(w, `{"status":"error","msg":%q}`, err)
+}
+
+func initStaleSnapshotsRemover(strg *logstorage.Storage) {
+	staleSnapshotsRemoverCh = make(chan struct{})
+	if snapshotsMaxAge.Duration() <= 0 {
+		return
+	}
+	snapshotsMaxAgeDur := snapshotsMaxAge.Duration()
+	staleSnapshotsRemoverWG.Add(1)
+	go func() {
+		defer staleSnapshotsRemoverWG.Done()
+		d := timeutil.AddJitterToDuration(time.Second * 11)
+		t := time.NewTicker(d)
+		defer t.Stop()
+		for {
+			select {
+			case <-staleSnapshotsRemoverCh:
+				return
+			case <-t.C:
+			}
+			if err := strg.DeleteStaleSnapshots(snapshotsMaxAgeDur); err != nil {
+				// Use logger.Errorf instead of logger.Fatalf in the hope the error is temporary.
+				logger.Errorf("cannot delete stale snapshots: %s", err)
+			}
+		}
+	}()
+}
+
+func stopStaleSnapshotsRemover() {
+	close(staleSnapshotsRemoverCh)
+	staleSnapshotsRemoverWG.Wait()
+}
+
+var (
+	staleSnapshotsRemoverCh chan struct{}
+	staleSnapshotsRemoverWG sync.WaitGroup
+)
+
+var (
+	snapshotsCreateTotal       = metrics.NewCounter(`vl_http_requests_total{path="/snapshot/create"}`)
+	snapshotsCreateErrorsTotal = metrics.NewCounter(`vl_http_request_errors_total{path="/snapshot/create"}`)
+
+	snapshotsListTotal       = metrics.NewCounter(`vl_http_requests_total{path="/snapshot/list"}`)
+	snapshotsListErrorsTotal = metrics.NewCounter(`vl_http_request_errors_total{path="/snapshot/list"}`)
+
+	snapshotsDeleteTotal       = metrics.NewCounter(`vl_http_requests_total{path="/snapshot/delete"}`)
+	snapshotsDeleteErrorsTotal = metrics.NewCounter(`vl_http_request_errors_total{path="/snapshot/delete"}`)
+
+	snapshotsDeleteAllTotal       = metrics.NewCounter(`vl_http_requests_total{path="/snapshot/delete_all"}`)
+	snapshotsDeleteAllErrorsTotal = metrics.NewCounter(`vl_http_request_errors_total{path="/snapshot/delete_all"}`)
+)
+
 // CanWriteData returns non-nil error if it cannot write data to vlstorage.
 func CanWriteData() error {
+	if isClusterMode {
+		return netinsert.CanWriteData()
+	}
 	if strg.IsReadOnly() {
 		return &httpserver.ErrorWithStatusCode{
 			Err: fmt.Errorf("cannot add rows into storage in read-only mode; the storage can be in read-only mode "+
@@ -103,12 +311,65 @@ func CanWriteData() error {
 //
 // It is advised to call CanWriteData() before calling MustAddRows()
 func MustAddRows(lr *logstorage.LogRows) {
+	if isClusterMode {
+		netinsert.MustAddRows(lr)
+		return
+	}
 	strg.MustAddRows(lr)
 }
 
 // RunQuery runs the given q and calls writeBlock for the returned data blocks
 func RunQuery(ctx context.Context, tenantIDs []logstorage.TenantID, q *logstorage.Query, writeBlock func(workerID uint, timestamps []int64, columns []logstorage.BlockColumn)) error {
-	return strg.RunQuery(ctx, tenantIDs, q, writeBlock)
+	return getRunQueryFunc()(ctx, tenantIDs, q, writeBlock)
+}
+
+// getRunQueryFunc returns the function for running queries either at the local storage or at -storageNode nodes.
+func getRunQueryFunc() logstorage.RunQueryFunc {
+	if isClusterMode {
+		return netselect.RunQuery
+	}
+	return strg.RunQuery
+}
+
+// DeleteRows deletes log entries matching q at the given tenantID.
+//
+// The deleted log entries become invisible to queries immediately, while they are physically deleted during background merges.
+func DeleteRows(tenantID logstorage.TenantID, q *logstorage.Query) error {
+	if isClusterMode {
+		return fmt.Errorf("deleting logs isn't supported when -storageNode is set; delete logs directly at -storageNode nodes")
+	}
+	return strg.DeleteRows(tenantID, q)
+}
+
+// GetFieldNames executes q and returns field names seen in results.
+func GetFieldNames(ctx context.Context, tenantIDs []logstorage.TenantID, q *logstorage.Query) ([]logstorage.ValueWithHits, error) {
+	return getRunQueryFunc().GetFieldNames(ctx, tenantIDs, q)
+}
+
+// GetFieldValues executes q and returns unique values for the fieldName seen in results.
+//
+// If limit > 0, then up to limit unique values are returned.
+func GetFieldValues(ctx context.Context, tenantIDs []logstorage.TenantID, q *logstorage.Query, fieldName string, limit uint64) ([]logstorage.ValueWithHits, error) {
+	return getRunQueryFunc().GetFieldValues(ctx, tenantIDs, q, fieldName, limit)
+}
+
+// GetStreams executes q and returns streams seen in query results.
+//
+// If limit > 0, then up to limit unique streams are returned.
+func GetStreams(ctx context.Context, tenantIDs []logstorage.TenantID, q *logstorage.Query, limit uint64) ([]logstorage.ValueWithHits, error) {
+	return getRunQueryFunc().GetStreams(ctx, tenantIDs, q, limit)
+}
+
+// GetStreamLabelNames executes q and returns stream label names seen in results.
+func GetStreamLabelNames(ctx context.Context, tenantIDs []logstorage.TenantID, q *logstorage.Query) ([]logstorage.ValueWithHits, error) {
+	return getRunQueryFunc().GetStreamLabelNames(ctx, tenantIDs, q)
+}
+
+// GetStreamLabelValues executes q and returns stream label values for the given labelName seen in results.
+//
+// If limit > 0, then up to limit stream label values with the biggest number of hits are returned.
+func GetStreamLabelValues(ctx context.Context, tenantIDs []logstorage.TenantID, q *logstorage.Query, labelName string, limit uint64) ([]logstorage.ValueWithHits, error) {
+	return getRunQueryFunc().GetStreamLabelValues(ctx, tenantIDs, q, labelName, limit)
 }
 
 func writeStorageMetrics(w io.Writer, strg *logstorage.Storage) {
@@ -163,4 +424,7 @@ func writeStorageMetrics(w io.Writer, strg *logstorage.Storage) {
 
 	metrics.WriteCounterUint64(w, `vl_rows_dropped_total{reason="too_big_timestamp"}`, ss.RowsDroppedTooBigTimestamp)
 	metrics.WriteCounterUint64(w, `vl_rows_dropped_total{reason="too_small_timestamp"}`, ss.RowsDroppedTooSmallTimestamp)
+	metrics.WriteCounterUint64(w, `vl_rows_dropped_total{reason="retention_filter"}`, ss.RowsDroppedByRetentionRules)
+
+	metrics.WriteCounterUint64(w, `vl_rows_deleted_total`, ss.RowsDeleted)
 }
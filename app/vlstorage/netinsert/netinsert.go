@@ -0,0 +1,193 @@
+package netinsert
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/VictoriaMetrics/metrics"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/encoding"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fasttime"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/httpserver"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logstorage"
+)
+
+// InsertPath is the path at storage nodes for accepting rows from netinsert.
+const InsertPath = "/internal/insert"
+
+// brokenNodeRetryInterval is the interval after which the broken storage node is tried again.
+const brokenNodeRetryInterval = 5
+
+// sendTimeout is the timeout for sending rows to a single storage node.
+const sendTimeout = 30 * time.Second
+
+type storageNode struct {
+	// addr is the address of the storage node.
+	addr string
+
+	// insertURL is the url for sending rows to the storage node.
+	insertURL string
+
+	// brokenUntil is the unix timestamp in seconds until which the storage node is considered broken.
+	brokenUntil atomic.Uint64
+
+	rowsSent   *metrics.Counter
+	sendErrors *metrics.Counter
+}
+
+func (sn *storageNode) isBroken() bool {
+	return fasttime.UnixTimestamp() < sn.brokenUntil.Load()
+}
+
+func (sn *storageNode) sendRows(data []byte, rowsCount int) error {
+	req, err := http.NewRequest(http.MethodPost, sn.insertURL, bytes.NewReader(data))
+	if err != nil {
+		logger.Panicf("BUG: unexpected error when creating request to %q: %s", sn.insertURL, err)
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("cannot send rows to -storageNode=%q: %w", sn.addr, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("unexpected response status code %d from -storageNode=%q; response body: %q", resp.StatusCode, sn.addr, body)
+	}
+	sn.rowsSent.Add(rowsCount)
+	return nil
+}
+
+var (
+	storageNodes []*storageNode
+	httpClient   *http.Client
+
+	rowsDropped = metrics.NewCounter(`vl_rpc_rows_dropped_total{type="insert"}`)
+)
+
+// Init initializes netinsert for sending rows to storage nodes with the given addrs.
+//
+// authKey is passed to storage nodes in the authKey query arg if it isn't empty.
+//
+// Stop must be called when netinsert is no longer needed.
+func Init(addrs []string, authKey string) {
+	if len(addrs) == 0 {
+		logger.Panicf("BUG: addrs must be non-empty")
+	}
+	httpClient = &http.Client{
+		Timeout: sendTimeout,
+		Transport: &http.Transport{
+			MaxIdleConnsPerHost: 100,
+		},
+	}
+	storageNodes = make([]*storageNode, len(addrs))
+	for i, addr := range addrs {
+		baseURL := addr
+		if !strings.Contains(baseURL, "://") {
+			baseURL = "http://" + baseURL
+		}
+		insertURL := strings.TrimSuffix(baseURL, "/") + InsertPath
+		if authKey != "" {
+			insertURL += "?authKey=" + url.QueryEscape(authKey)
+		}
+		storageNodes[i] = &storageNode{
+			addr:       addr,
+			insertURL:  insertURL,
+			rowsSent:   metrics.GetOrCreateCounter(fmt.Sprintf(`vl_rpc_rows_sent_total{type="insert",addr=%q}`, addr)),
+			sendErrors: metrics.GetOrCreateCounter(fmt.Sprintf(`vl_rpc_send_errors_total{type="insert",addr=%q}`, addr)),
+		}
+	}
+}
+
+// Stop stops netinsert.
+func Stop() {
+	httpClient.CloseIdleConnections()
+	httpClient = nil
+	storageNodes = nil
+}
+
+// CanWriteData returns non-nil error if there are no healthy storage nodes for writing data.
+func CanWriteData() error {
+	for _, sn := range storageNodes {
+		if !sn.isBroken() {
+			return nil
+		}
+	}
+	return &httpserver.ErrorWithStatusCode{
+		Err:        fmt.Errorf("all the %d -storageNode nodes are unavailable", len(storageNodes)),
+		StatusCode: http.StatusServiceUnavailable,
+	}
+}
+
+// MustAddRows sends lr to storage nodes.
+//
+// Rows are spread among storage nodes by log stream, so all the rows for a single log stream go to the same storage node.
+// Rows are re-routed to the remaining healthy storage nodes if the target storage node is unavailable.
+// Rows are dropped if all the storage nodes are unavailable.
+func MustAddRows(lr *logstorage.LogRows) {
+	sns := storageNodes
+	bufs := make([][]byte, len(sns))
+	rowsCounts := make([]int, len(sns))
+	for i := 0; i < lr.Len(); i++ {
+		idx := lr.GetStreamHash(i) % uint64(len(sns))
+		bufs[idx] = lr.MarshalRow(bufs[idx], i)
+		rowsCounts[idx]++
+	}
+
+	var wg sync.WaitGroup
+	for idx, buf := range bufs {
+		if len(buf) == 0 {
+			continue
+		}
+		wg.Add(1)
+		go func(idx int, buf []byte) {
+			defer wg.Done()
+			data := encoding.CompressZSTDLevel(nil, buf, 1)
+			sendRowsWithRerouting(sns, idx, data, rowsCounts[idx])
+		}(idx, buf)
+	}
+	wg.Wait()
+}
+
+// sendRowsWithRerouting sends data with rowsCount rows to sns[idx].
+//
+// If sns[idx] is unavailable, then the data is sent to the next healthy storage node.
+func sendRowsWithRerouting(sns []*storageNode, idx int, data []byte, rowsCount int) {
+	var lastErr error
+	attempts := 0
+	for i := 0; i < len(sns); i++ {
+		sn := sns[(idx+i)%len(sns)]
+		if sn.isBroken() {
+			continue
+		}
+		attempts++
+		err := sn.sendRows(data, rowsCount)
+		if err == nil {
+			return
+		}
+		sn.sendErrors.Inc()
+		sn.brokenUntil.Store(fasttime.UnixTimestamp() + brokenNodeRetryInterval)
+		logger.Warnf("%s; re-routing %d rows to the remaining -storageNode nodes", err, rowsCount)
+		lastErr = err
+	}
+	if attempts == 0 {
+		// All the storage nodes are broken. Try sending the data to the target node in the hope it is already healthy.
+		sn := sns[idx]
+		err := sn.sendRows(data, rowsCount)
+		if err == nil {
+			sn.brokenUntil.Store(0)
+			return
+		}
+		sn.sendErrors.Inc()
+		lastErr = err
+	}
+	rowsDropped.Add(rowsCount)
+	logger.Errorf("dropping %d rows, since they couldn't be sent to any of %d -storageNode nodes; the last error: %s", rowsCount, len(sns), lastErr)
+}
@@ -0,0 +1,224 @@
+package netselect
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/VictoriaMetrics/metrics"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/encoding"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logstorage"
+)
+
+// QueryPath is the path at storage nodes for executing queries from netselect.
+const QueryPath = "/internal/select/query"
+
+// maxFrameSize is the maximum size of a single frame in the query response from storage nodes.
+const maxFrameSize = 512 * 1024 * 1024
+
+type storageNode struct {
+	// addr is the address of the storage node.
+	addr string
+
+	// queryURL is the url for executing queries at the storage node.
+	queryURL string
+
+	requests      *metrics.Counter
+	requestErrors *metrics.Counter
+}
+
+func (sn *storageNode) runQuery(ctx context.Context, tenantIDs []logstorage.TenantID, q *logstorage.Query, writeBlock func(timestamps []int64, columns []logstorage.BlockColumn)) error {
+	sn.requests.Inc()
+	if err := sn.runQueryInternal(ctx, tenantIDs, q, writeBlock); err != nil {
+		sn.requestErrors.Inc()
+		return fmt.Errorf("cannot execute query at -storageNode=%q: %w", sn.addr, err)
+	}
+	return nil
+}
+
+func (sn *storageNode) runQueryInternal(ctx context.Context, tenantIDs []logstorage.TenantID, q *logstorage.Query, writeBlock func(timestamps []int64, columns []logstorage.BlockColumn)) error {
+	args := make(url.Values)
+	args.Set("query", q.String())
+	args.Set("timestamp", strconv.FormatInt(q.GetTimestamp(), 10))
+	a := make([]string, len(tenantIDs))
+	for i, tenantID := range tenantIDs {
+		a[i] = fmt.Sprintf("%d:%d", tenantID.AccountID, tenantID.ProjectID)
+	}
+	args.Set("tenant_ids", strings.Join(a, ","))
+	if internalAuthKey != "" {
+		args.Set("authKey", internalAuthKey)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sn.queryURL, strings.NewReader(args.Encode()))
+	if err != nil {
+		logger.Panicf("BUG: unexpected error when creating request to %q: %s", sn.queryURL, err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("unexpected response status code %d; response body: %q", resp.StatusCode, body)
+	}
+
+	br := bufio.NewReader(resp.Body)
+	var frame, data []byte
+	var timestamps []int64
+	var columns []logstorage.BlockColumn
+	for {
+		frame, err = readFrame(frame[:0], br)
+		if err != nil {
+			return fmt.Errorf("cannot read data block: %w", err)
+		}
+		if len(frame) == 0 {
+			// The end of data blocks. The next frame contains an optional error message.
+			frame, err = readFrame(frame[:0], br)
+			if err != nil {
+				return fmt.Errorf("cannot read error message: %w", err)
+			}
+			if len(frame) > 0 {
+				return fmt.Errorf("%s", frame)
+			}
+			return nil
+		}
+
+		data, err = encoding.DecompressZSTD(data[:0], frame)
+		if err != nil {
+			return fmt.Errorf("cannot decompress data block: %w", err)
+		}
+		timestamps, columns, err = logstorage.UnmarshalBlockColumns(timestamps[:0], columns[:0], data)
+		if err != nil {
+			return fmt.Errorf("cannot unmarshal data block: %w", err)
+		}
+		writeBlock(timestamps, columns)
+	}
+}
+
+// WriteFrame writes a frame with the given data to bw.
+//
+// The frame can be read via readFrame.
+func WriteFrame(bw *bufio.Writer, data []byte) error {
+	var sizeBuf [8]byte
+	encoding.MarshalUint64(sizeBuf[:0], uint64(len(data)))
+	if _, err := bw.Write(sizeBuf[:]); err != nil {
+		return err
+	}
+	_, err := bw.Write(data)
+	return err
+}
+
+func readFrame(dst []byte, br *bufio.Reader) ([]byte, error) {
+	var sizeBuf [8]byte
+	if _, err := io.ReadFull(br, sizeBuf[:]); err != nil {
+		return dst, fmt.Errorf("cannot read frame size: %w", err)
+	}
+	size := encoding.UnmarshalUint64(sizeBuf[:])
+	if size > maxFrameSize {
+		return dst, fmt.Errorf("too big frame size: %d bytes; it mustn't exceed %d bytes", size, maxFrameSize)
+	}
+	dstLen := len(dst)
+	dst = slices.Grow(dst, int(size))[:dstLen+int(size)]
+	if _, err := io.ReadFull(br, dst[dstLen:]); err != nil {
+		return dst, fmt.Errorf("cannot read frame with size %d bytes: %w", size, err)
+	}
+	return dst, nil
+}
+
+var (
+	storageNodes []*storageNode
+	httpClient   *http.Client
+
+	// internalAuthKey is passed to storage nodes in the authKey query arg.
+	internalAuthKey string
+)
+
+// Init initializes netselect for querying storage nodes with the given addrs.
+//
+// authKey is passed to storage nodes in the authKey query arg if it isn't empty.
+//
+// Stop must be called when netselect is no longer needed.
+func Init(addrs []string, authKey string) {
+	if len(addrs) == 0 {
+		logger.Panicf("BUG: addrs must be non-empty")
+	}
+	httpClient = &http.Client{
+		Transport: &http.Transport{
+			MaxIdleConnsPerHost: 100,
+		},
+	}
+	internalAuthKey = authKey
+	storageNodes = make([]*storageNode, len(addrs))
+	for i, addr := range addrs {
+		baseURL := addr
+		if !strings.Contains(baseURL, "://") {
+			baseURL = "http://" + baseURL
+		}
+		storageNodes[i] = &storageNode{
+			addr:          addr,
+			queryURL:      strings.TrimSuffix(baseURL, "/") + QueryPath,
+			requests:      metrics.GetOrCreateCounter(fmt.Sprintf(`vl_rpc_requests_total{type="select",addr=%q}`, addr)),
+			requestErrors: metrics.GetOrCreateCounter(fmt.Sprintf(`vl_rpc_request_errors_total{type="select",addr=%q}`, addr)),
+		}
+	}
+}
+
+// Stop stops netselect.
+func Stop() {
+	httpClient.CloseIdleConnections()
+	httpClient = nil
+	storageNodes = nil
+	internalAuthKey = ""
+}
+
+// RunQuery runs the given q at all the storage nodes and calls writeBlock for the merged results.
+//
+// The query fails if at least a single storage node returns an error.
+func RunQuery(ctx context.Context, tenantIDs []logstorage.TenantID, q *logstorage.Query, writeBlock func(workerID uint, timestamps []int64, columns []logstorage.BlockColumn)) error {
+	sns := storageNodes
+	runRemote := func(ctx context.Context, qRemote *logstorage.Query, writeBlock func(workerID uint, timestamps []int64, columns []logstorage.BlockColumn)) error {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		errs := make([]error, len(sns))
+		var wg sync.WaitGroup
+		for i, sn := range sns {
+			wg.Add(1)
+			go func(workerID uint, sn *storageNode) {
+				defer wg.Done()
+				err := sn.runQuery(ctx, tenantIDs, qRemote, func(timestamps []int64, columns []logstorage.BlockColumn) {
+					writeBlock(workerID, timestamps, columns)
+				})
+				if err != nil {
+					// Cancel the query at the remaining storage nodes, since the query fails anyway.
+					cancel()
+				}
+				errs[workerID] = err
+			}(uint(i), sn)
+		}
+		wg.Wait()
+
+		// Return the first error, which isn't caused by the query cancelation at the remaining storage nodes.
+		var errFirst error
+		for _, err := range errs {
+			if err != nil && (errFirst == nil || errors.Is(errFirst, context.Canceled)) {
+				errFirst = err
+			}
+		}
+		return errFirst
+	}
+	return logstorage.RunNetQuery(ctx, len(sns), q, runRemote, writeBlock)
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmagent/common"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmagent/remotewrite"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/auth"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/httpserver"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompb"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
 	parserCommon "github.com/VictoriaMetrics/VictoriaMetrics/lib/protoparser/common"
@@ -21,15 +22,33 @@ var (
 )
 
 // InsertHandler processes remote write for prometheus.
-func InsertHandler(at *auth.Token, req *http.Request) error {
+//
+// Both Prometheus remote write 1.0 and 2.0 protocols are supported. The protocol is selected according to Content-Type request header.
+func InsertHandler(at *auth.Token, w http.ResponseWriter, req *http.Request) error {
 	extraLabels, err := parserCommon.GetExtraLabels(req)
 	if err != nil {
 		return err
 	}
+	isRemoteWriteV2, err := stream.IsRemoteWriteV2(req.Header.Get("Content-Type"))
+	if err != nil {
+		return &httpserver.ErrorWithStatusCode{
+			Err:        err,
+			StatusCode: http.StatusUnsupportedMediaType,
+		}
+	}
 	isVMRemoteWrite := req.Header.Get("Content-Encoding") == "zstd"
-	return stream.Parse(req.Body, isVMRemoteWrite, func(tss []prompb.TimeSeries) error {
+	ws, err := stream.Parse(req.Body, isVMRemoteWrite, isRemoteWriteV2, func(tss []prompb.TimeSeries, _ []prompb.MetricMetadata) error {
 		return insertRows(at, tss, extraLabels)
 	})
+	if err != nil {
+		return err
+	}
+	if isRemoteWriteV2 {
+		// vmagent doesn't forward exemplars to remote storage yet.
+		ws.Exemplars = 0
+		ws.SetResponseHeaders(w.Header())
+	}
+	return nil
 }
 
 func insertRows(at *auth.Token, timeseries []prompb.TimeSeries, extraLabels []prompbmarshal.Label) error {
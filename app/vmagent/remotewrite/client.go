@@ -9,14 +9,17 @@ import (
 	"net/url"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/awsapi"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/flagutil"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/persistentqueue"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promauth"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/protoparser/common"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/protoparser/promremotewrite/stream"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/ratelimiter"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/timerpool"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/timeutil"
@@ -28,6 +31,9 @@ var (
 		"to the corresponding -remoteWrite.url . See https://docs.victoriametrics.com/vmagent/#victoriametrics-remote-write-protocol")
 	forceVMProto = flagutil.NewArrayBool("remoteWrite.forceVMProto", "Whether to force VictoriaMetrics remote write protocol for sending data "+
 		"to the corresponding -remoteWrite.url . See https://docs.victoriametrics.com/vmagent/#victoriametrics-remote-write-protocol")
+	usePromProtoV2 = flagutil.NewArrayBool("remoteWrite.usePromProtoV2", "Whether to use Prometheus remote write 2.0 protocol for sending data "+
+		"to the corresponding -remoteWrite.url . vmagent falls back to Prometheus remote write 1.0 protocol if the remote storage doesn't support 2.0. "+
+		"See https://docs.victoriametrics.com/vmagent/#prometheus-remote-write-20")
 
 	rateLimit = flagutil.NewArrayInt("remoteWrite.rateLimit", 0, "Optional rate limit in bytes per second for data sent to the corresponding -remoteWrite.url. "+
 		"By default, the rate limit is disabled. It can be useful for limiting load on remote storage when big amounts of buffered data "+
@@ -85,6 +91,10 @@ type client struct {
 	// Whether to use VictoriaMetrics remote write protocol for sending the data to remoteWriteURL
 	useVMProto bool
 
+	// Whether to use Prometheus remote write 2.0 protocol for sending the data to remoteWriteURL.
+	// It is reset to false if the remote storage doesn't support 2.0 protocol.
+	usePromProtoV2 atomic.Bool
+
 	fq *persistentqueue.FastQueue
 	hc *http.Client
 
@@ -156,10 +166,18 @@ func newHTTPClient(argIdx int, remoteWriteURL, sanitizedURL string, fq *persiste
 	if useVMProto && usePromProto {
 		logger.Fatalf("-remoteWrite.useVMProto and -remoteWrite.usePromProto cannot be set simultaneously for -remoteWrite.url=%s", sanitizedURL)
 	}
+	if usePromProtoV2.GetOptionalArg(argIdx) {
+		if useVMProto {
+			logger.Fatalf("-remoteWrite.forceVMProto and -remoteWrite.usePromProtoV2 cannot be set simultaneously for -remoteWrite.url=%s", sanitizedURL)
+		}
+		// Skip the handshake for VictoriaMetrics remote write protocol, since Prometheus remote write 2.0 is explicitly requested.
+		usePromProto = true
+		c.usePromProtoV2.Store(true)
+	}
 	if !useVMProto && !usePromProto {
 		// Auto-detect whether the remote storage supports VictoriaMetrics remote write protocol.
 		doRequest := func(url string) (*http.Response, error) {
-			return c.doRequest(url, nil)
+			return c.doRequest(url, nil, false)
 		}
 		useVMProto = common.HandleVMProtoClientHandshake(c.remoteWriteURL, doRequest)
 		if !useVMProto {
@@ -330,8 +348,8 @@ func (c *client) runWorker() {
 	}
 }
 
-func (c *client) doRequest(url string, body []byte) (*http.Response, error) {
-	req, err := c.newRequest(url, body)
+func (c *client) doRequest(url string, body []byte, isPromProtoV2 bool) (*http.Response, error) {
+	req, err := c.newRequest(url, body, isPromProtoV2)
 	if err != nil {
 		return nil, err
 	}
@@ -346,7 +364,7 @@ func (c *client) doRequest(url string, body []byte) (*http.Response, error) {
 	// Make another attempt in hope request will succeed.
 	// If not, the error should be handled by the caller as usual.
 	// This should help with https://github.com/VictoriaMetrics/VictoriaMetrics/issues/4139
-	req, err = c.newRequest(url, body)
+	req, err = c.newRequest(url, body, isPromProtoV2)
 	if err != nil {
 		return nil, fmt.Errorf("second attempt: %w", err)
 	}
@@ -357,7 +375,7 @@ func (c *client) doRequest(url string, body []byte) (*http.Response, error) {
 	return resp, nil
 }
 
-func (c *client) newRequest(url string, body []byte) (*http.Request, error) {
+func (c *client) newRequest(url string, body []byte, isPromProtoV2 bool) (*http.Request, error) {
 	reqBody := bytes.NewBuffer(body)
 	req, err := http.NewRequest(http.MethodPost, url, reqBody)
 	if err != nil {
@@ -369,11 +387,17 @@ func (c *client) newRequest(url string, body []byte) (*http.Request, error) {
 	}
 	h := req.Header
 	h.Set("User-Agent", "vmagent")
-	h.Set("Content-Type", "application/x-protobuf")
-	if c.useVMProto {
+	switch {
+	case c.useVMProto:
+		h.Set("Content-Type", "application/x-protobuf")
 		h.Set("Content-Encoding", "zstd")
 		h.Set("X-VictoriaMetrics-Remote-Write-Version", "1")
-	} else {
+	case isPromProtoV2:
+		h.Set("Content-Type", stream.ContentTypeV2)
+		h.Set("Content-Encoding", "snappy")
+		h.Set("X-Prometheus-Remote-Write-Version", "2.0.0")
+	default:
+		h.Set("Content-Type", "application/x-protobuf")
 		h.Set("Content-Encoding", "snappy")
 		h.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
 	}
@@ -396,9 +420,27 @@ func (c *client) sendBlockHTTP(block []byte) bool {
 	retryDuration := timeutil.AddJitterToDuration(time.Second)
 	retriesCount := 0
 
+	// Blocks are stored in Prometheus remote write 1.0 format, so they must be converted to 2.0 format before sending.
+	reqBody := block
+	isPromProtoV2 := c.usePromProtoV2.Load()
+	if isPromProtoV2 {
+		bb := promProtoV2BufPool.Get()
+		defer promProtoV2BufPool.Put(bb)
+		var err error
+		bb.B, err = appendPromProtoV2Block(bb.B[:0], block)
+		if err != nil {
+			// This may be a zstd-compressed block, which has been put into the persistent queue
+			// when VictoriaMetrics remote write protocol was used before vmagent restart.
+			// Send it as is, since VictoriaMetrics accepts such blocks via 1.0 protocol.
+			isPromProtoV2 = false
+		} else {
+			reqBody = bb.B
+		}
+	}
+
 again:
 	startTime := time.Now()
-	resp, err := c.doRequest(c.remoteWriteURL, block)
+	resp, err := c.doRequest(c.remoteWriteURL, reqBody, isPromProtoV2)
 	c.requestDuration.UpdateDuration(startTime)
 	if err != nil {
 		c.errorsCount.Inc()
@@ -422,12 +464,29 @@ again:
 	statusCode := resp.StatusCode
 	if statusCode/100 == 2 {
 		_ = resp.Body.Close()
+		if isPromProtoV2 && resp.Header.Get("X-Prometheus-Remote-Write-Samples-Written") == "" {
+			// The remote storage must return X-Prometheus-Remote-Write-*-Written headers for 2.0 requests.
+			// Their absence means the remote storage supports only 1.0 protocol,
+			// so it most likely ignored the unknown fields of 2.0 request.
+			// See https://prometheus.io/docs/specs/remote_write_spec_2_0/#backward-and-forward-compatibility
+			//
+			// Do not re-send the block with 1.0 protocol, since the remote storage may have already accepted some data from it,
+			// and re-sending would result in duplicate data. Send the subsequent blocks with 1.0 protocol instead.
+			c.disablePromProtoV2("the response doesn't contain X-Prometheus-Remote-Write-Samples-Written header")
+		}
 		c.requestsOKCount.Inc()
-		c.bytesSent.Add(len(block))
+		c.bytesSent.Add(len(reqBody))
 		c.blocksSent.Inc()
 		return true
 	}
 	metrics.GetOrCreateCounter(fmt.Sprintf(`vmagent_remotewrite_requests_total{url=%q, status_code="%d"}`, c.sanitizedURL, statusCode)).Inc()
+	if isPromProtoV2 && statusCode == http.StatusUnsupportedMediaType {
+		_ = resp.Body.Close()
+		c.disablePromProtoV2("the remote storage responded with 415 Unsupported Media Type")
+		reqBody = block
+		isPromProtoV2 = false
+		goto again
+	}
 	if statusCode == 409 || statusCode == 400 {
 		body, err := io.ReadAll(resp.Body)
 		_ = resp.Body.Close()
@@ -473,4 +532,16 @@ again:
 	goto again
 }
 
+// disablePromProtoV2 switches c to Prometheus remote write 1.0 protocol, since the remote storage doesn't support 2.0 protocol.
+//
+// The 2.0 protocol isn't tried again until vmagent restart.
+func (c *client) disablePromProtoV2(reason string) {
+	if c.usePromProtoV2.CompareAndSwap(true, false) {
+		logger.Warnf("the remote storage at %q doesn't support Prometheus remote write 2.0 protocol: %s; switching to Prometheus remote write 1.0 protocol",
+			c.sanitizedURL, reason)
+	}
+}
+
+var promProtoV2BufPool bytesutil.ByteBufferPool
+
 var remoteWriteRejectedLogger = logger.WithThrottler("remoteWriteRejected", 5*time.Second)
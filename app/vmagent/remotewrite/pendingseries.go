@@ -66,6 +66,14 @@ func (ps *pendingSeries) TryPush(tss []prompbmarshal.TimeSeries) bool {
 	return ok
 }
 
+// TryPushMetadata adds mms to the pending data.
+func (ps *pendingSeries) TryPushMetadata(mms []prompbmarshal.MetricMetadata) bool {
+	ps.mu.Lock()
+	ok := ps.wr.tryPushMetadata(mms)
+	ps.mu.Unlock()
+	return ok
+}
+
 func (ps *pendingSeries) periodicFlusher() {
 	flushSeconds := int64(flushInterval.Seconds())
 	if flushSeconds <= 0 {
@@ -113,8 +121,9 @@ type writeRequest struct {
 	labels    []prompbmarshal.Label
 	samples   []prompbmarshal.Sample
 	exemplars []prompbmarshal.Exemplar
+	metadata  []prompbmarshal.MetricMetadata
 
-	// buf holds labels data
+	// buf holds labels and metadata data
 	buf []byte
 }
 
@@ -122,6 +131,7 @@ func (wr *writeRequest) reset() {
 	// Do not reset lastFlushTime, fq, isVMRemoteWrite, significantFigures and roundDigits, since they are re-used.
 
 	wr.wr.Timeseries = nil
+	wr.wr.Metadata = nil
 
 	clear(wr.tss)
 	wr.tss = wr.tss[:0]
@@ -131,6 +141,8 @@ func (wr *writeRequest) reset() {
 
 	wr.samples = wr.samples[:0]
 	wr.exemplars = wr.exemplars[:0]
+	clear(wr.metadata)
+	wr.metadata = wr.metadata[:0]
 	wr.buf = wr.buf[:0]
 }
 
@@ -139,6 +151,7 @@ func (wr *writeRequest) reset() {
 // This is needed in order to properly save in-memory data to persistent queue on graceful shutdown.
 func (wr *writeRequest) mustFlushOnStop() {
 	wr.wr.Timeseries = wr.tss
+	wr.wr.Metadata = wr.metadata
 	if !tryPushWriteRequest(&wr.wr, wr.mustWriteBlock, wr.isVMRemoteWrite) {
 		logger.Panicf("BUG: final flush must always return true")
 	}
@@ -152,6 +165,7 @@ func (wr *writeRequest) mustWriteBlock(block []byte) bool {
 
 func (wr *writeRequest) tryFlush() bool {
 	wr.wr.Timeseries = wr.tss
+	wr.wr.Metadata = wr.metadata
 	wr.lastFlushTime.Store(fasttime.UnixTimestamp())
 	if !tryPushWriteRequest(&wr.wr, wr.fq.TryWriteBlock, wr.isVMRemoteWrite) {
 		return false
@@ -198,6 +212,38 @@ func (wr *writeRequest) tryPush(src []prompbmarshal.TimeSeries) bool {
 	return true
 }
 
+func (wr *writeRequest) tryPushMetadata(src []prompbmarshal.MetricMetadata) bool {
+	maxMetadataPerBlock := *maxRowsPerBlock
+	for i := range src {
+		if len(wr.metadata) >= maxMetadataPerBlock {
+			if !wr.tryFlush() {
+				return false
+			}
+		}
+		wr.copyMetadata(&src[i])
+	}
+	return true
+}
+
+func (wr *writeRequest) copyMetadata(src *prompbmarshal.MetricMetadata) {
+	buf := wr.buf
+
+	buf = append(buf, src.MetricFamilyName...)
+	metricFamilyName := bytesutil.ToUnsafeString(buf[len(buf)-len(src.MetricFamilyName):])
+	buf = append(buf, src.Help...)
+	help := bytesutil.ToUnsafeString(buf[len(buf)-len(src.Help):])
+	buf = append(buf, src.Unit...)
+	unit := bytesutil.ToUnsafeString(buf[len(buf)-len(src.Unit):])
+
+	wr.metadata = append(wr.metadata, prompbmarshal.MetricMetadata{
+		Type:             src.Type,
+		MetricFamilyName: metricFamilyName,
+		Help:             help,
+		Unit:             unit,
+	})
+	wr.buf = buf
+}
+
 func (wr *writeRequest) copyTimeSeries(dst, src *prompbmarshal.TimeSeries) {
 	labelsDst := wr.labels
 	labelsLen := len(wr.labels)
@@ -232,7 +278,7 @@ func (wr *writeRequest) copyTimeSeries(dst, src *prompbmarshal.TimeSeries) {
 var marshalConcurrencyCh = make(chan struct{}, cgroup.AvailableCPUs())
 
 func tryPushWriteRequest(wr *prompbmarshal.WriteRequest, tryPushBlock func(block []byte) bool, isVMRemoteWrite bool) bool {
-	if len(wr.Timeseries) == 0 {
+	if len(wr.Timeseries) == 0 && len(wr.Metadata) == 0 {
 		// Nothing to push
 		return true
 	}
@@ -269,6 +315,9 @@ func tryPushWriteRequest(wr *prompbmarshal.WriteRequest, tryPushBlock func(block
 	}
 
 	// Too big block. Recursively split it into smaller parts if possible.
+	if len(wr.Metadata) > 0 {
+		return tryPushWriteRequestSplitMetadata(wr, tryPushBlock, isVMRemoteWrite)
+	}
 	if len(wr.Timeseries) == 1 {
 		// A single time series left. Recursively split its samples into smaller parts if possible.
 		samples := wr.Timeseries[0].Samples
@@ -313,6 +362,38 @@ func tryPushWriteRequest(wr *prompbmarshal.WriteRequest, tryPushBlock func(block
 	return true
 }
 
+// tryPushWriteRequestSplitMetadata pushes time series and metadata from the too big wr in separate blocks.
+func tryPushWriteRequestSplitMetadata(wr *prompbmarshal.WriteRequest, tryPushBlock func(block []byte) bool, isVMRemoteWrite bool) bool {
+	timeseries := wr.Timeseries
+	metadata := wr.Metadata
+	defer func() {
+		wr.Timeseries = timeseries
+		wr.Metadata = metadata
+	}()
+
+	if len(timeseries) > 0 {
+		wr.Metadata = nil
+		if !tryPushWriteRequest(wr, tryPushBlock, isVMRemoteWrite) {
+			return false
+		}
+		wr.Timeseries = nil
+		wr.Metadata = metadata
+		return tryPushWriteRequest(wr, tryPushBlock, isVMRemoteWrite)
+	}
+
+	if len(metadata) == 1 {
+		logger.Warnf("dropping metadata for metric family %q exceeding -remoteWrite.maxBlockSize=%d bytes", metadata[0].MetricFamilyName, maxUnpackedBlockSize.N)
+		return true
+	}
+	n := len(metadata) / 2
+	wr.Metadata = metadata[:n]
+	if !tryPushWriteRequest(wr, tryPushBlock, isVMRemoteWrite) {
+		return false
+	}
+	wr.Metadata = metadata[n:]
+	return tryPushWriteRequest(wr, tryPushBlock, isVMRemoteWrite)
+}
+
 var (
 	blockSizeBytes = metrics.NewHistogram(`vmagent_remotewrite_block_size_bytes`)
 	blockSizeRows  = metrics.NewHistogram(`vmagent_remotewrite_block_size_rows`)
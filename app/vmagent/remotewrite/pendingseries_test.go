@@ -5,6 +5,9 @@ import (
 	"math"
 	"testing"
 
+	"github.com/golang/snappy"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompb"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
 )
 
@@ -49,6 +52,65 @@ func testPushWriteRequest(t *testing.T, rowsCount, expectedBlockLenProm, expecte
 	f(true, expectedBlockLenVM, 15)
 }
 
+func TestPushWriteRequestMetadata(t *testing.T) {
+	f := func(maxBlockSize int64) {
+		t.Helper()
+
+		origMaxBlockSize := maxUnpackedBlockSize.N
+		maxUnpackedBlockSize.N = maxBlockSize
+		defer func() {
+			maxUnpackedBlockSize.N = origMaxBlockSize
+		}()
+
+		wr := newTestWriteRequest(10, 2)
+		for i := 0; i < 10; i++ {
+			wr.Metadata = append(wr.Metadata, prompbmarshal.MetricMetadata{
+				Type:             uint32(prompb.MetricTypeGauge),
+				MetricFamilyName: fmt.Sprintf("metric_%d", i),
+				Help:             fmt.Sprintf("help for metric_%d", i),
+			})
+		}
+
+		seriesCount := 0
+		metadataNames := make(map[string]int)
+		pushBlock := func(block []byte) bool {
+			data, err := snappy.Decode(nil, block)
+			if err != nil {
+				t.Fatalf("cannot decode block: %s", err)
+			}
+			var wrDecoded prompb.WriteRequest
+			if err := wrDecoded.UnmarshalProtobuf(data); err != nil {
+				t.Fatalf("cannot unmarshal block: %s", err)
+			}
+			seriesCount += len(wrDecoded.Timeseries)
+			for _, mm := range wrDecoded.Metadata {
+				metadataNames[mm.MetricFamilyName]++
+			}
+			return true
+		}
+		if !tryPushWriteRequest(wr, pushBlock, false) {
+			t.Fatalf("cannot push data to to remote storage")
+		}
+		if seriesCount != 10 {
+			t.Fatalf("unexpected number of pushed series; got %d; want 10", seriesCount)
+		}
+		if len(metadataNames) != 10 {
+			t.Fatalf("unexpected number of pushed metadata entries; got %d; want 10", len(metadataNames))
+		}
+		for name, n := range metadataNames {
+			if n != 1 {
+				t.Fatalf("metadata for %q has been pushed %d times; want 1", name, n)
+			}
+		}
+	}
+
+	// Everything fits a single block
+	f(8*1024*1024)
+
+	// Time series and metadata must be split into multiple blocks without duplicates
+	f(300)
+}
+
 func newTestWriteRequest(seriesCount, labelsCount int) *prompbmarshal.WriteRequest {
 	var wr prompbmarshal.WriteRequest
 	for i := 0; i < seriesCount; i++ {
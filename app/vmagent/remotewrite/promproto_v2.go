@@ -0,0 +1,115 @@
+package remotewrite
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/golang/snappy"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompb"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
+)
+
+// appendPromProtoV2Block appends block converted to Prometheus remote write 2.0 format to dst and returns the result.
+//
+// block must contain snappy-compressed Prometheus remote write 1.0 request as created by pendingSeries.
+// Blocks are stored in the persistent queue in 1.0 format, so they could be sent with 1.0 protocol
+// if the remote storage doesn't support 2.0 protocol.
+func appendPromProtoV2Block(dst, block []byte) ([]byte, error) {
+	ctx := getPromProtoV2Ctx()
+	defer putPromProtoV2Ctx(ctx)
+
+	var err error
+	ctx.buf, err = snappy.Decode(ctx.buf[:cap(ctx.buf)], block)
+	if err != nil {
+		return dst, fmt.Errorf("cannot decompress block: %w", err)
+	}
+	if err := ctx.wr.UnmarshalProtobuf(ctx.buf); err != nil {
+		return dst, fmt.Errorf("cannot unmarshal block: %w", err)
+	}
+
+	wrm := &ctx.wrm
+	tss := ctx.wr.Timeseries
+	for i := range tss {
+		ts := &tss[i]
+		labelsLen := len(ctx.labels)
+		for _, label := range ts.Labels {
+			ctx.labels = append(ctx.labels, prompbmarshal.Label(label))
+		}
+		samplesLen := len(ctx.samples)
+		for _, sample := range ts.Samples {
+			ctx.samples = append(ctx.samples, prompbmarshal.Sample(sample))
+		}
+		exemplarsLen := len(ctx.exemplars)
+		for _, exemplar := range ts.Exemplars {
+			exemplarLabelsLen := len(ctx.labels)
+			for _, label := range exemplar.Labels {
+				ctx.labels = append(ctx.labels, prompbmarshal.Label(label))
+			}
+			ctx.exemplars = append(ctx.exemplars, prompbmarshal.Exemplar{
+				Labels:    ctx.labels[exemplarLabelsLen:],
+				Value:     exemplar.Value,
+				Timestamp: exemplar.Timestamp,
+			})
+		}
+		wrm.Timeseries = append(wrm.Timeseries, prompbmarshal.TimeSeries{
+			Labels:    ctx.labels[labelsLen:],
+			Samples:   ctx.samples[samplesLen:],
+			Exemplars: ctx.exemplars[exemplarsLen:],
+		})
+	}
+	for _, mm := range ctx.wr.Metadata {
+		wrm.Metadata = append(wrm.Metadata, prompbmarshal.MetricMetadata{
+			Type:             uint32(mm.Type),
+			MetricFamilyName: mm.MetricFamilyName,
+			Help:             mm.Help,
+			Unit:             mm.Unit,
+		})
+	}
+
+	ctx.data = wrm.MarshalProtobufV2(ctx.data[:0])
+	ctx.compressBuf = snappy.Encode(ctx.compressBuf[:cap(ctx.compressBuf)], ctx.data)
+	return append(dst, ctx.compressBuf...), nil
+}
+
+type promProtoV2Ctx struct {
+	wr  prompb.WriteRequest
+	wrm prompbmarshal.WriteRequest
+
+	labels    []prompbmarshal.Label
+	samples   []prompbmarshal.Sample
+	exemplars []prompbmarshal.Exemplar
+
+	buf         []byte
+	data        []byte
+	compressBuf []byte
+}
+
+func (ctx *promProtoV2Ctx) reset() {
+	ctx.wr.Reset()
+	ctx.wrm.Reset()
+
+	clear(ctx.labels)
+	ctx.labels = ctx.labels[:0]
+	ctx.samples = ctx.samples[:0]
+	clear(ctx.exemplars)
+	ctx.exemplars = ctx.exemplars[:0]
+
+	ctx.buf = ctx.buf[:0]
+	ctx.data = ctx.data[:0]
+}
+
+func getPromProtoV2Ctx() *promProtoV2Ctx {
+	v := promProtoV2CtxPool.Get()
+	if v == nil {
+		return &promProtoV2Ctx{}
+	}
+	return v.(*promProtoV2Ctx)
+}
+
+func putPromProtoV2Ctx(ctx *promProtoV2Ctx) {
+	ctx.reset()
+	promProtoV2CtxPool.Put(ctx)
+}
+
+var promProtoV2CtxPool sync.Pool
@@ -0,0 +1,87 @@
+package remotewrite
+
+import (
+	"testing"
+
+	"github.com/golang/snappy"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompb"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
+)
+
+func TestAppendPromProtoV2Block(t *testing.T) {
+	wrm := &prompbmarshal.WriteRequest{
+		Timeseries: []prompbmarshal.TimeSeries{
+			{
+				Labels: []prompbmarshal.Label{
+					{Name: "__name__", Value: "foo"},
+					{Name: "job", Value: "bar"},
+				},
+				Samples: []prompbmarshal.Sample{
+					{Value: 1, Timestamp: 1000},
+					{Value: 2, Timestamp: 2000},
+				},
+			},
+			{
+				Labels: []prompbmarshal.Label{
+					{Name: "__name__", Value: "foo"},
+					{Name: "job", Value: "baz"},
+				},
+				Samples: []prompbmarshal.Sample{
+					{Value: -3.5, Timestamp: 1000},
+				},
+			},
+		},
+	}
+	dataExpected := wrm.MarshalProtobuf(nil)
+	block := snappy.Encode(nil, dataExpected)
+
+	result, err := appendPromProtoV2Block(nil, block)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	data, err := snappy.Decode(nil, result)
+	if err != nil {
+		t.Fatalf("cannot decompress the result: %s", err)
+	}
+	var wr prompb.WriteRequest
+	if err := wr.UnmarshalProtobufV2(data); err != nil {
+		t.Fatalf("cannot unmarshal the result: %s", err)
+	}
+
+	// Convert the result back to 1.0 format and compare it to the original data
+	var wrmResult prompbmarshal.WriteRequest
+	for _, ts := range wr.Timeseries {
+		var labels []prompbmarshal.Label
+		for _, label := range ts.Labels {
+			labels = append(labels, prompbmarshal.Label(label))
+		}
+		var samples []prompbmarshal.Sample
+		for _, sample := range ts.Samples {
+			samples = append(samples, prompbmarshal.Sample(sample))
+		}
+		wrmResult.Timeseries = append(wrmResult.Timeseries, prompbmarshal.TimeSeries{
+			Labels:  labels,
+			Samples: samples,
+		})
+	}
+	dataResult := wrmResult.MarshalProtobuf(nil)
+	if string(dataResult) != string(dataExpected) {
+		t.Fatalf("unexpected data after the conversion\ngot\n%X\nwant\n%X", dataResult, dataExpected)
+	}
+}
+
+func TestAppendPromProtoV2BlockFailure(t *testing.T) {
+	f := func(block []byte) {
+		t.Helper()
+		if _, err := appendPromProtoV2Block(nil, block); err == nil {
+			t.Fatalf("expecting non-nil error")
+		}
+	}
+
+	// invalid snappy-compressed data
+	f([]byte("foobar"))
+
+	// invalid protobuf message
+	f(snappy.Encode(nil, []byte{0xff, 0xff}))
+}
@@ -497,9 +497,35 @@ func tryPush(at *auth.Token, wr *prompbmarshal.WriteRequest, forceDropSamplesOnF
 			return false
 		}
 	}
+
+	// Metric metadata has no labels, so it cannot be attributed to the tenant. Drop it when the tenant is set.
+	if at == nil {
+		if !tryPushMetadataToRemoteStorages(wr.Metadata, forceDropSamplesOnFailure) {
+			return false
+		}
+	}
 	return true
 }
 
+// tryPushMetadataToRemoteStorages pushes mms to all the configured remote storage systems.
+//
+// Metadata is replicated among all the remote storage systems even if -remoteWrite.shardByURL is set,
+// since it is small and it is needed for every metric family stored in the remote storage.
+// Relabeling and stream aggregation aren't applied to metadata.
+func tryPushMetadataToRemoteStorages(mms []prompbmarshal.MetricMetadata, forceDropSamplesOnFailure bool) bool {
+	if len(mms) == 0 {
+		// Nothing to push
+		return true
+	}
+	anyPushFailed := false
+	for _, rwctx := range rwctxs {
+		if !rwctx.TryPushMetadata(mms, forceDropSamplesOnFailure) {
+			anyPushFailed = true
+		}
+	}
+	return !anyPushFailed
+}
+
 func tryPushBlockToRemoteStorages(tssBlock []prompbmarshal.TimeSeries, forceDropSamplesOnFailure bool) bool {
 	if len(tssBlock) == 0 {
 		// Nothing to push
@@ -930,6 +956,17 @@ func (rwctx *remoteWriteCtx) TryPush(tss []prompbmarshal.TimeSeries, forceDropSa
 	return ok
 }
 
+// TryPushMetadata sends mms to the configured remote write endpoint.
+func (rwctx *remoteWriteCtx) TryPushMetadata(mms []prompbmarshal.MetricMetadata, forceDropSamplesOnFailure bool) bool {
+	pss := rwctx.pss
+	idx := rwctx.pssNextIdx.Add(1) % uint64(len(pss))
+	if pss[idx].TryPushMetadata(mms) {
+		return true
+	}
+	rwctx.pushFailures.Inc()
+	return forceDropSamplesOnFailure || rwctx.dropSamplesOnOverload
+}
+
 var matchIdxsPool bytesutil.ByteBufferPool
 
 func dropAggregatedSeries(src []prompbmarshal.TimeSeries, matchIdxs []byte, dropInput bool) []prompbmarshal.TimeSeries {
@@ -194,7 +194,7 @@ func setUp() {
 			}
 			return true
 		case "/prometheus/api/v1/write", "/api/v1/write":
-			if err := promremotewrite.InsertHandler(r); err != nil {
+			if err := promremotewrite.InsertHandler(w, r); err != nil {
 				httpserver.Errorf(w, r, "%s", err)
 			}
 			return true
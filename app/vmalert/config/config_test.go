@@ -358,6 +358,64 @@ func TestGroup_Validate(t *testing.T) {
 				},
 			},
 		},
+		{
+			group: &Group{
+				Name: "test vlogs",
+				Type: NewVLogsType(),
+				Rules: []Rule{
+					{Alert: "alert", Expr: "_time:5m error | stats by (host) count() errors", Labels: map[string]string{
+						"description": "{{ $labels.host }} has {{ $value }} errors",
+					}},
+					{Record: "record", Expr: "_time:1m | stats rate() logs_per_second"},
+				},
+			},
+			validateExpressions: true,
+			expErr:              "",
+		},
+		{
+			group: &Group{
+				Name: "test vlogs without stats pipe",
+				Type: NewVLogsType(),
+				Rules: []Rule{
+					{Alert: "alert", Expr: "_time:5m error"},
+				},
+			},
+			validateExpressions: true,
+			expErr:              "bad LogsQL expr",
+		},
+		{
+			group: &Group{
+				Name: "test vlogs with pipes after stats pipe",
+				Type: NewVLogsType(),
+				Rules: []Rule{
+					{Alert: "alert", Expr: "_time:5m error | stats by (host) count() errors | filter errors:range(100, inf)"},
+				},
+			},
+			validateExpressions: true,
+			expErr:              "bad LogsQL expr",
+		},
+		{
+			group: &Group{
+				Name: "test vlogs with _time in stats by fields",
+				Type: NewVLogsType(),
+				Rules: []Rule{
+					{Alert: "alert", Expr: "error | stats by (_time:1m, host) count() errors"},
+				},
+			},
+			validateExpressions: true,
+			expErr:              "bad LogsQL expr",
+		},
+		{
+			group: &Group{
+				Name: "test vlogs bad expr",
+				Type: NewVLogsType(),
+				Rules: []Rule{
+					{Alert: "alert", Expr: "sum(up == 0 ) by (host)"},
+				},
+			},
+			validateExpressions: true,
+			expErr:              "bad LogsQL expr",
+		},
 		{
 			group: &Group{
 				Name: "test graphite prometheus bad expr",
@@ -3,8 +3,10 @@ package config
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/graphiteql"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logstorage"
 	"github.com/VictoriaMetrics/metricsql"
 )
 
@@ -27,6 +29,13 @@ func NewGraphiteType() Type {
 	}
 }
 
+// NewVLogsType returns VictoriaLogs datasource type
+func NewVLogsType() Type {
+	return Type{
+		Name: "vlogs",
+	}
+}
+
 // NewRawType returns datasource type from raw string
 // without validation.
 func NewRawType(d string) Type {
@@ -62,6 +71,16 @@ func (t *Type) ValidateExpr(expr string) error {
 		if _, err := metricsql.Parse(expr); err != nil {
 			return fmt.Errorf("bad prometheus expr: %q, err: %w", expr, err)
 		}
+	case "vlogs":
+		q, err := logstorage.ParseQuery(expr)
+		if err != nil {
+			return fmt.Errorf("bad LogsQL expr: %q, err: %w", expr, err)
+		}
+		// Verify the expr against /select/logsql/stats_query_range contract, since it is stricter than /select/logsql/stats_query contract
+		// and it is used for rules backfilling. The query must end with `| stats ...` pipe without `_time` field at `by(...)` clause.
+		if _, err := q.AddStatsByTimeField(int64(time.Minute), 0); err != nil {
+			return fmt.Errorf("bad LogsQL expr: %q, err: %w", expr, err)
+		}
 	default:
 		return fmt.Errorf("unknown datasource type=%q", t.Name)
 	}
@@ -78,9 +97,9 @@ func (t *Type) UnmarshalYAML(unmarshal func(interface{}) error) error {
 		s = "prometheus"
 	}
 	switch s {
-	case "graphite", "prometheus":
+	case "graphite", "prometheus", "vlogs":
 	default:
-		return fmt.Errorf("unknown datasource type=%q, want %q or %q", s, "prometheus", "graphite")
+		return fmt.Errorf("unknown datasource type=%q, want %q, %q or %q", s, "prometheus", "graphite", "vlogs")
 	}
 	t.Name = s
 	return nil
@@ -15,7 +15,7 @@ import (
 )
 
 var (
-	addr = flag.String("datasource.url", "", "Datasource compatible with Prometheus HTTP API. It can be single node VictoriaMetrics or vmselect URL. Required parameter. "+
+	addr = flag.String("datasource.url", "", "Datasource compatible with Prometheus HTTP API. It can be single node VictoriaMetrics or vmselect URL. Required parameter unless -vlogs.url is set. "+
 		"E.g. http://127.0.0.1:8428 . See also -remoteRead.disablePathAppend and -datasource.showURL")
 	appendTypePrefix  = flag.Bool("datasource.appendTypePrefix", false, "Whether to add type prefix to -datasource.url based on the query type. Set to true if sending different query types to the vmselect URL.")
 	showDatasourceURL = flag.Bool("datasource.showURL", false, "Whether to avoid stripping sensitive information such as auth headers or passwords from URLs in log messages or UI and exported metrics. "+
@@ -68,6 +68,9 @@ func InitSecretFlags() {
 	if !*showDatasourceURL {
 		flagutil.RegisterSecretFlag("datasource.url")
 	}
+	if !*showVLogsURL {
+		flagutil.RegisterSecretFlag("vlogs.url")
+	}
 }
 
 // ShowDatasourceURL whether to show -datasource.url with sensitive information
@@ -82,10 +85,19 @@ type Param struct {
 
 // Init creates a Querier from provided flag values.
 // Provided extraParams will be added as GET params for
-// each request.
+// each request to -datasource.url.
+//
+// Rules with `vlogs` type are evaluated against -vlogs.url.
 func Init(extraParams url.Values) (QuerierBuilder, error) {
+	vlogs, err := initVLogs()
+	if err != nil {
+		return nil, fmt.Errorf("failed to init -vlogs.url: %w", err)
+	}
 	if *addr == "" {
-		return nil, fmt.Errorf("datasource.url is empty")
+		if vlogs == nil {
+			return nil, fmt.Errorf("datasource.url is empty")
+		}
+		return &querierBuilder{vlogs: vlogs}, nil
 	}
 	if !*queryTimeAlignment {
 		logger.Warnf("flag `-datasource.queryTimeAlignment` is deprecated and will be removed in next releases. Please use `eval_alignment` in rule group instead.")
@@ -128,7 +140,7 @@ func Init(extraParams url.Values) (QuerierBuilder, error) {
 		return nil, fmt.Errorf("failed to set request auth header to datasource %q: %w", *addr, err)
 	}
 
-	return &VMStorage{
+	vm := &VMStorage{
 		c:                &http.Client{Transport: tr},
 		authCfg:          authCfg,
 		datasourceURL:    strings.TrimSuffix(*addr, "/"),
@@ -136,5 +148,6 @@ func Init(extraParams url.Values) (QuerierBuilder, error) {
 		queryStep:        *queryStep,
 		dataSourceType:   datasourcePrometheus,
 		extraParams:      extraParams,
-	}, nil
+	}
+	return &querierBuilder{vm: vm, vlogs: vlogs}, nil
 }
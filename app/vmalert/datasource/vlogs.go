@@ -0,0 +1,269 @@
+package datasource
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promauth"
+)
+
+const (
+	datasourceVLogs datasourceType = "vlogs"
+
+	vlogsStatsQueryPath      = "/select/logsql/stats_query"
+	vlogsStatsQueryRangePath = "/select/logsql/stats_query_range"
+)
+
+// VLogsStorage represents VictoriaLogs entity with ability to execute LogsQL stats queries
+// and to return their results as time series.
+// WARN: when adding a new field, remember to update Clone() method.
+type VLogsStorage struct {
+	c        *http.Client
+	authCfg  *promauth.Config
+	vlogsURL string
+
+	// evaluationInterval is used as request's `step` param for range queries.
+	evaluationInterval time.Duration
+	// extraParams contains params to be attached to each HTTP request
+	extraParams url.Values
+	// extraHeaders are headers to be attached to each HTTP request
+	extraHeaders []keyValue
+
+	// whether to print additional log messages
+	// for each sent request
+	debug bool
+}
+
+// NewVLogsStorage is a constructor for VLogsStorage
+func NewVLogsStorage(baseURL string, authCfg *promauth.Config, c *http.Client) *VLogsStorage {
+	return &VLogsStorage{
+		c:           c,
+		authCfg:     authCfg,
+		vlogsURL:    strings.TrimSuffix(baseURL, "/"),
+		extraParams: url.Values{},
+	}
+}
+
+// Clone makes clone of VLogsStorage, shares http client.
+func (s *VLogsStorage) Clone() *VLogsStorage {
+	ns := &VLogsStorage{
+		c:        s.c,
+		authCfg:  s.authCfg,
+		vlogsURL: s.vlogsURL,
+
+		evaluationInterval: s.evaluationInterval,
+
+		// init map so it can be populated below
+		extraParams: url.Values{},
+
+		debug: s.debug,
+	}
+	if len(s.extraHeaders) > 0 {
+		ns.extraHeaders = make([]keyValue, len(s.extraHeaders))
+		copy(ns.extraHeaders, s.extraHeaders)
+	}
+	for k, v := range s.extraParams {
+		ns.extraParams[k] = v
+	}
+	return ns
+}
+
+// ApplyParams - changes given querier params.
+func (s *VLogsStorage) ApplyParams(params QuerierParams) *VLogsStorage {
+	s.evaluationInterval = params.EvaluationInterval
+	for k, vl := range params.QueryParams {
+		// custom query params are prior to default ones
+		if s.extraParams.Has(k) {
+			s.extraParams.Del(k)
+		}
+		for _, v := range vl {
+			s.extraParams.Add(k, v)
+		}
+	}
+	for key, value := range params.Headers {
+		kv := keyValue{key: key, value: value}
+		s.extraHeaders = append(s.extraHeaders, kv)
+	}
+	s.debug = params.Debug
+	return s
+}
+
+// BuildWithParams - implements interface.
+func (s *VLogsStorage) BuildWithParams(params QuerierParams) Querier {
+	return s.Clone().ApplyParams(params)
+}
+
+// Query executes the given LogsQL query at VictoriaLogs /select/logsql/stats_query endpoint and returns parsed response.
+//
+// See https://docs.victoriametrics.com/victorialogs/querying/#querying-log-stats
+func (s *VLogsStorage) Query(ctx context.Context, query string, ts time.Time) (Result, *http.Request, error) {
+	newReq := func() (*http.Request, error) {
+		req, err := s.newRequest(ctx, vlogsStatsQueryPath)
+		if err != nil {
+			return nil, err
+		}
+		q := req.URL.Query()
+		q.Set("time", ts.Format(time.RFC3339))
+		s.setReqParams(req, q, query)
+		return req, nil
+	}
+	req, res, err := s.query(newReq)
+	return res, req, err
+}
+
+// QueryRange executes the given LogsQL query at VictoriaLogs /select/logsql/stats_query_range endpoint
+// on the given time range and returns parsed response.
+//
+// See https://docs.victoriametrics.com/victorialogs/querying/#querying-log-range-stats
+func (s *VLogsStorage) QueryRange(ctx context.Context, query string, start, end time.Time) (Result, error) {
+	if start.IsZero() {
+		return Result{}, fmt.Errorf("start param is missing")
+	}
+	if end.IsZero() {
+		return Result{}, fmt.Errorf("end param is missing")
+	}
+	newReq := func() (*http.Request, error) {
+		req, err := s.newRequest(ctx, vlogsStatsQueryRangePath)
+		if err != nil {
+			return nil, err
+		}
+		q := req.URL.Query()
+		q.Set("start", start.Format(time.RFC3339))
+		q.Set("end", end.Format(time.RFC3339))
+		if s.evaluationInterval > 0 { // set step as evaluationInterval by default
+			q.Set("step", fmt.Sprintf("%ds", int(s.evaluationInterval.Seconds())))
+		}
+		s.setReqParams(req, q, query)
+		return req, nil
+	}
+	_, res, err := s.query(newReq)
+	return res, err
+}
+
+func (s *VLogsStorage) query(newReq func() (*http.Request, error)) (*http.Request, Result, error) {
+	req, err := newReq()
+	if err != nil {
+		return nil, Result{}, err
+	}
+	resp, err := s.do(req)
+	if err != nil {
+		if !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+			// Return unexpected error to the caller.
+			return nil, Result{}, err
+		}
+		// Something in the middle between client and datasource might be closing
+		// the connection. So we do a one more attempt in hope request will succeed.
+		req, err = newReq()
+		if err != nil {
+			return nil, Result{}, fmt.Errorf("second attempt: %w", err)
+		}
+		resp, err = s.do(req)
+		if err != nil {
+			return nil, Result{}, fmt.Errorf("second attempt: %w", err)
+		}
+	}
+
+	// VictoriaLogs returns stats results in Prometheus querying API format.
+	res, err := parsePrometheusResponse(req, resp)
+	_ = resp.Body.Close()
+	return req, res, err
+}
+
+func (s *VLogsStorage) do(req *http.Request) (*http.Response, error) {
+	ru := req.URL.Redacted()
+	if *showVLogsURL {
+		ru = req.URL.String()
+	}
+	if s.debug {
+		logger.Infof("DEBUG vlogs request: executing %s request with params %q", req.Method, ru)
+	}
+	resp, err := s.c.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error getting response from %s: %w", ru, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		return nil, fmt.Errorf("unexpected response code %d for %s. Response body %s", resp.StatusCode, ru, body)
+	}
+	return resp, nil
+}
+
+func (s *VLogsStorage) newRequest(ctx context.Context, path string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.vlogsURL+path, nil)
+	if err != nil {
+		logger.Panicf("BUG: unexpected error from http.NewRequest(%q): %s", s.vlogsURL, err)
+	}
+	if s.authCfg != nil {
+		if err := s.authCfg.SetHeaders(req, true); err != nil {
+			return nil, fmt.Errorf("cannot create request to VictoriaLogs %q: %w", s.vlogsURL, err)
+		}
+	}
+	for _, h := range s.extraHeaders {
+		req.Header.Set(h.key, h.value)
+	}
+	return req, nil
+}
+
+func (s *VLogsStorage) setReqParams(r *http.Request, q url.Values, query string) {
+	for k, vs := range s.extraParams {
+		if q.Has(k) { // extraParams are prior to params in URL
+			q.Del(k)
+		}
+		for _, v := range vs {
+			q.Add(k, v)
+		}
+	}
+	q.Set("query", query)
+	r.URL.RawQuery = q.Encode()
+}
+
+// querierBuilder builds Querier for the datasource type from QuerierParams.
+//
+// Rules with `vlogs` type are evaluated against -vlogs.url, while the rest of rules are evaluated against -datasource.url.
+type querierBuilder struct {
+	vm    *VMStorage
+	vlogs *VLogsStorage
+}
+
+// BuildWithParams - implements interface.
+func (qb *querierBuilder) BuildWithParams(params QuerierParams) Querier {
+	if params.DataSourceType == string(datasourceVLogs) {
+		if qb.vlogs == nil {
+			return &errQuerier{
+				err: fmt.Errorf("cannot evaluate %q rule: -vlogs.url command-line flag must be set", datasourceVLogs),
+			}
+		}
+		return qb.vlogs.BuildWithParams(params)
+	}
+	if qb.vm == nil {
+		return &errQuerier{
+			err: fmt.Errorf("cannot evaluate %q rule: -datasource.url command-line flag must be set", toDatasourceType(params.DataSourceType)),
+		}
+	}
+	return qb.vm.BuildWithParams(params)
+}
+
+// errQuerier returns err on every query.
+//
+// It is used for rules with datasource type, which isn't configured.
+type errQuerier struct {
+	err error
+}
+
+// Query - implements interface.
+func (eq *errQuerier) Query(_ context.Context, _ string, _ time.Time) (Result, *http.Request, error) {
+	return Result{}, nil, eq.err
+}
+
+// QueryRange - implements interface.
+func (eq *errQuerier) QueryRange(_ context.Context, _ string, _, _ time.Time) (Result, error) {
+	return Result{}, eq.err
+}
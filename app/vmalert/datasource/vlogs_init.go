@@ -0,0 +1,79 @@
+package datasource
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmalert/utils"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/flagutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/httputils"
+)
+
+var (
+	vlogsAddr = flag.String("vlogs.url", "", "Optional URL to VictoriaLogs. It is used for evaluating rules from groups with 'type: vlogs'. "+
+		"E.g. http://127.0.0.1:9428 . See also -vlogs.showURL and https://docs.victoriametrics.com/vmalert/#victorialogs")
+	showVLogsURL = flag.Bool("vlogs.showURL", false, "Whether to avoid stripping sensitive information such as auth headers or passwords from -vlogs.url in log messages. "+
+		"It is hidden by default, since it can contain sensitive info such as auth key")
+
+	vlogsHeaders = flag.String("vlogs.headers", "", "Optional HTTP headers to send with each request to the corresponding -vlogs.url. "+
+		"For example, -vlogs.headers='AccountID:1' would send 'AccountID: 1' HTTP header with every request to the corresponding -vlogs.url. "+
+		"Multiple headers must be delimited by '^^': -vlogs.headers='header1:value1^^header2:value2'")
+
+	vlogsBasicAuthUsername     = flag.String("vlogs.basicAuth.username", "", "Optional basic auth username for -vlogs.url")
+	vlogsBasicAuthPassword     = flag.String("vlogs.basicAuth.password", "", "Optional basic auth password for -vlogs.url")
+	vlogsBasicAuthPasswordFile = flag.String("vlogs.basicAuth.passwordFile", "", "Optional path to basic auth password to use for -vlogs.url")
+
+	vlogsBearerToken     = flag.String("vlogs.bearerToken", "", "Optional bearer auth token to use for -vlogs.url.")
+	vlogsBearerTokenFile = flag.String("vlogs.bearerTokenFile", "", "Optional path to bearer token file to use for -vlogs.url.")
+
+	vlogsTLSInsecureSkipVerify = flag.Bool("vlogs.tlsInsecureSkipVerify", false, "Whether to skip tls verification when connecting to -vlogs.url")
+	vlogsTLSCertFile           = flag.String("vlogs.tlsCertFile", "", "Optional path to client-side TLS certificate file to use when connecting to -vlogs.url")
+	vlogsTLSKeyFile            = flag.String("vlogs.tlsKeyFile", "", "Optional path to client-side TLS certificate key to use when connecting to -vlogs.url")
+	vlogsTLSCAFile             = flag.String("vlogs.tlsCAFile", "", `Optional path to TLS CA file to use for verifying connections to -vlogs.url. By default, system CA is used`)
+	vlogsTLSServerName         = flag.String("vlogs.tlsServerName", "", `Optional TLS server name to use for connections to -vlogs.url. By default, the server name from -vlogs.url is used`)
+
+	vlogsOAuth2ClientID         = flag.String("vlogs.oauth2.clientID", "", "Optional OAuth2 clientID to use for -vlogs.url")
+	vlogsOAuth2ClientSecret     = flag.String("vlogs.oauth2.clientSecret", "", "Optional OAuth2 clientSecret to use for -vlogs.url")
+	vlogsOAuth2ClientSecretFile = flag.String("vlogs.oauth2.clientSecretFile", "", "Optional OAuth2 clientSecretFile to use for -vlogs.url")
+	vlogsOAuth2EndpointParams   = flag.String("vlogs.oauth2.endpointParams", "", "Optional OAuth2 endpoint parameters to use for -vlogs.url . "+
+		`The endpoint parameters must be set in JSON format: {"param1":"value1",...,"paramN":"valueN"}`)
+	vlogsOAuth2TokenURL = flag.String("vlogs.oauth2.tokenUrl", "", "Optional OAuth2 tokenURL to use for -vlogs.url")
+	vlogsOAuth2Scopes   = flag.String("vlogs.oauth2.scopes", "", "Optional OAuth2 scopes to use for -vlogs.url. Scopes must be delimited by ';'")
+)
+
+// initVLogs creates VLogsStorage from provided flag values.
+// Returns nil if -vlogs.url flag wasn't set.
+func initVLogs() (*VLogsStorage, error) {
+	if *vlogsAddr == "" {
+		return nil, nil
+	}
+	tr, err := httputils.Transport(*vlogsAddr, *vlogsTLSCertFile, *vlogsTLSKeyFile, *vlogsTLSCAFile, *vlogsTLSServerName, *vlogsTLSInsecureSkipVerify)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create transport: %w", err)
+	}
+	tr.DisableKeepAlives = *disableKeepAlive
+	tr.MaxIdleConnsPerHost = *maxIdleConnections
+	if tr.MaxIdleConns != 0 && tr.MaxIdleConns < tr.MaxIdleConnsPerHost {
+		tr.MaxIdleConns = tr.MaxIdleConnsPerHost
+	}
+
+	endpointParams, err := flagutil.ParseJSONMap(*vlogsOAuth2EndpointParams)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse JSON for -vlogs.oauth2.endpointParams=%s: %w", *vlogsOAuth2EndpointParams, err)
+	}
+	authCfg, err := utils.AuthConfig(
+		utils.WithBasicAuth(*vlogsBasicAuthUsername, *vlogsBasicAuthPassword, *vlogsBasicAuthPasswordFile),
+		utils.WithBearer(*vlogsBearerToken, *vlogsBearerTokenFile),
+		utils.WithOAuth(*vlogsOAuth2ClientID, *vlogsOAuth2ClientSecret, *vlogsOAuth2ClientSecretFile, *vlogsOAuth2TokenURL, *vlogsOAuth2Scopes, endpointParams),
+		utils.WithHeaders(*vlogsHeaders))
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure auth: %w", err)
+	}
+	if _, err := authCfg.GetAuthHeader(); err != nil {
+		return nil, fmt.Errorf("failed to set request auth header to VictoriaLogs %q: %w", *vlogsAddr, err)
+	}
+
+	c := &http.Client{Transport: tr}
+	return NewVLogsStorage(*vlogsAddr, authCfg, c), nil
+}
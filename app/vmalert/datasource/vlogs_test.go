@@ -0,0 +1,147 @@
+package datasource
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVLogsQuery(t *testing.T) {
+	logsQuery := "_time:5m error | stats by (host) count() errors"
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(_ http.ResponseWriter, _ *http.Request) {
+		t.Errorf("should not be called")
+	})
+	mux.HandleFunc("/select/logsql/stats_query", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("expected POST method got %s", r.Method)
+		}
+		if r.URL.Query().Get("query") != logsQuery {
+			t.Errorf("expected %s in query param, got %s", logsQuery, r.URL.Query().Get("query"))
+		}
+		if r.URL.Query().Get("extra_filters") != "env:prod" {
+			t.Errorf("expected env:prod in extra_filters param, got %s", r.URL.Query().Get("extra_filters"))
+		}
+		if r.Header.Get("AccountID") != "12" {
+			t.Errorf("expected AccountID: 12 header, got %q", r.Header.Get("AccountID"))
+		}
+		timeParam := r.URL.Query().Get("time")
+		if _, err := time.Parse(time.RFC3339, timeParam); err != nil {
+			t.Errorf("failed to parse 'time' query param %q: %s", timeParam, err)
+		}
+		w.Write([]byte(`{"status":"success","data":{"resultType":"vector","result":[{"metric":{"__name__":"errors","host":"foo"},"value":[1583786142,"13"]},{"metric":{"__name__":"errors","host":"bar"},"value":[1583786142,"2"]}]}}`))
+	})
+	mux.HandleFunc("/select/logsql/stats_query_range", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("query") != logsQuery {
+			t.Errorf("expected %s in query param, got %s", logsQuery, r.URL.Query().Get("query"))
+		}
+		if step := r.URL.Query().Get("step"); step != "60s" {
+			t.Errorf("expected 'step' query param to be 60s; got %q instead", step)
+		}
+		for _, name := range []string{"start", "end"} {
+			v := r.URL.Query().Get(name)
+			if _, err := time.Parse(time.RFC3339, v); err != nil {
+				t.Errorf("failed to parse %q query param %q: %s", name, v, err)
+			}
+		}
+		w.Write([]byte(`{"status":"success","data":{"resultType":"matrix","result":[{"metric":{"__name__":"errors","host":"foo"},"values":[[1583786100,"10"],[1583786160,"13"]]}]}}`))
+	})
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	s := NewVLogsStorage(srv.URL, nil, srv.Client())
+	qb := &querierBuilder{
+		vlogs: s,
+	}
+	vq := qb.BuildWithParams(QuerierParams{
+		DataSourceType:     string(datasourceVLogs),
+		EvaluationInterval: time.Minute,
+		QueryParams:        map[string][]string{"extra_filters": {"env:prod"}},
+		Headers:            map[string]string{"AccountID": "12"},
+	})
+
+	res, req, err := vq.Query(ctx, logsQuery, time.Now())
+	if err != nil {
+		t.Fatalf("unexpected %s", err)
+	}
+	checkEqualString(t, vlogsStatsQueryPath, req.URL.Path)
+	exp := []Metric{
+		{
+			Labels:     []Label{{Value: "errors", Name: "__name__"}, {Value: "foo", Name: "host"}},
+			Timestamps: []int64{1583786142},
+			Values:     []float64{13},
+		},
+		{
+			Labels:     []Label{{Value: "errors", Name: "__name__"}, {Value: "bar", Name: "host"}},
+			Timestamps: []int64{1583786142},
+			Values:     []float64{2},
+		},
+	}
+	metricsEqual(t, res.Data, exp)
+
+	start, end := time.Now().Add(-2*time.Minute), time.Now()
+	res, err = vq.QueryRange(ctx, logsQuery, start, end)
+	if err != nil {
+		t.Fatalf("unexpected %s", err)
+	}
+	exp = []Metric{
+		{
+			Labels:     []Label{{Value: "errors", Name: "__name__"}, {Value: "foo", Name: "host"}},
+			Timestamps: []int64{1583786100, 1583786160},
+			Values:     []float64{10, 13},
+		},
+	}
+	metricsEqual(t, res.Data, exp)
+
+	if _, err := vq.QueryRange(ctx, logsQuery, time.Time{}, end); err == nil {
+		t.Fatalf("expecting non-nil error for missing start param")
+	}
+}
+
+func TestQuerierBuilder(t *testing.T) {
+	vm := NewVMStorage("http://vm", nil, 0, false, nil)
+	vlogs := NewVLogsStorage("http://vlogs", nil, nil)
+
+	f := func(qb *querierBuilder, dataSourceType string, errExpected string) {
+		t.Helper()
+
+		q := qb.BuildWithParams(QuerierParams{
+			DataSourceType: dataSourceType,
+		})
+		if errExpected != "" {
+			_, _, err := q.Query(ctx, "foo", time.Now())
+			if err == nil || !strings.Contains(err.Error(), errExpected) {
+				t.Fatalf("expecting error containing %q; got %v", errExpected, err)
+			}
+			return
+		}
+		switch dataSourceType {
+		case string(datasourceVLogs):
+			if _, ok := q.(*VLogsStorage); !ok {
+				t.Fatalf("unexpected querier type for %q: %T", dataSourceType, q)
+			}
+		default:
+			if _, ok := q.(*VMStorage); !ok {
+				t.Fatalf("unexpected querier type for %q: %T", dataSourceType, q)
+			}
+		}
+	}
+
+	qb := &querierBuilder{vm: vm, vlogs: vlogs}
+	f(qb, "", "")
+	f(qb, "prometheus", "")
+	f(qb, "graphite", "")
+	f(qb, "vlogs", "")
+
+	qb = &querierBuilder{vm: vm}
+	f(qb, "prometheus", "")
+	f(qb, "vlogs", "-vlogs.url")
+
+	qb = &querierBuilder{vlogs: vlogs}
+	f(qb, "vlogs", "")
+	f(qb, "prometheus", "-datasource.url")
+	f(qb, "graphite", "-datasource.url")
+}
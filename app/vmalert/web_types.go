@@ -78,7 +78,7 @@ type apiGroup struct {
 
 	// Additional fields
 
-	// Type shows the datasource type (prometheus or graphite) of the Group
+	// Type shows the datasource type (prometheus, graphite or vlogs) of the Group
 	Type string `json:"type"`
 	// ID is a unique Group ID
 	ID string `json:"id"`
@@ -139,7 +139,7 @@ type apiRule struct {
 
 	// Additional fields
 
-	// DatasourceType of the rule: prometheus or graphite
+	// DatasourceType of the rule: prometheus, graphite or vlogs
 	DatasourceType string `json:"datasourceType"`
 	// LastSamples stores the amount of data samples received on last evaluation
 	LastSamples int `json:"lastSamples"`
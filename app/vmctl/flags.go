@@ -601,6 +601,132 @@ var (
 	}
 )
 
+const (
+	vlogsNativeFilterQuery     = "vlogs-native-filter-query"
+	vlogsNativeFilterTimeStart = "vlogs-native-filter-time-start"
+	vlogsNativeFilterTimeEnd   = "vlogs-native-filter-time-end"
+	vlogsNativeStepInterval    = "vlogs-native-step-interval"
+	vlogsNativeConcurrency     = "vlogs-native-concurrency"
+	vlogsNativeStateFile       = "vlogs-native-state-file"
+
+	vlogsNativeSrcAddr        = "vlogs-native-src-addr"
+	vlogsNativeSrcUser        = "vlogs-native-src-user"
+	vlogsNativeSrcPassword    = "vlogs-native-src-password"
+	vlogsNativeSrcHeaders     = "vlogs-native-src-headers"
+	vlogsNativeSrcBearerToken = "vlogs-native-src-bearer-token"
+	vlogsNativeSrcTenant      = "vlogs-native-src-tenant"
+
+	vlogsNativeDstAddr        = "vlogs-native-dst-addr"
+	vlogsNativeDstUser        = "vlogs-native-dst-user"
+	vlogsNativeDstPassword    = "vlogs-native-dst-password"
+	vlogsNativeDstHeaders     = "vlogs-native-dst-headers"
+	vlogsNativeDstBearerToken = "vlogs-native-dst-bearer-token"
+	vlogsNativeDstTenant      = "vlogs-native-dst-tenant"
+)
+
+var (
+	vlogsNativeFlags = []cli.Flag{
+		&cli.StringFlag{
+			Name: vlogsNativeFilterQuery,
+			Usage: "LogsQL query for selecting logs to migrate. For example, '{app=\"nginx\"} error' migrates logs with the 'error' word from the 'nginx' log streams.\n" +
+				" The query may contain only pipes, which process every log entry independently. " +
+				"See https://docs.victoriametrics.com/victorialogs/querying/#exporting-logs-in-native-format",
+			Value: "*",
+		},
+		&cli.StringFlag{
+			Name:     vlogsNativeFilterTimeStart,
+			Usage:    "The start of the time range to migrate. The time filter may contain different timestamp formats. See more details here https://docs.victoriametrics.com/single-server-victoriametrics/#timestamp-formats",
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name:  vlogsNativeFilterTimeEnd,
+			Usage: "The end of the time range to migrate. By default, the current time is used. The time filter may contain different timestamp formats. See more details here https://docs.victoriametrics.com/single-server-victoriametrics/#timestamp-formats",
+		},
+		&cli.StringFlag{
+			Name: vlogsNativeStepInterval,
+			Usage: fmt.Sprintf("The time interval to split the migration into steps. Every step is migrated via a separate export/import request and is recorded in '--%s' after the successful migration. Valid values are '%s','%s','%s','%s','%s'.",
+				vlogsNativeStateFile, stepper.StepMonth, stepper.StepWeek, stepper.StepDay, stepper.StepHour, stepper.StepMinute),
+			Value: stepper.StepDay,
+		},
+		&cli.IntFlag{
+			Name:  vlogsNativeConcurrency,
+			Usage: "Number of workers concurrently performing export/import requests",
+			Value: 2,
+		},
+		&cli.StringFlag{
+			Name: vlogsNativeStateFile,
+			Usage: "Optional path to the file for storing the migration progress. If set, vmctl records every successfully migrated step to this file " +
+				"and skips the already migrated steps on the next run with the same file, tenants and filters. This allows resuming the interrupted migration",
+		},
+		&cli.StringFlag{
+			Name: vlogsNativeSrcAddr,
+			Usage: "VictoriaLogs address to perform export from. \n" +
+				" For example, http://localhost:9428",
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name:    vlogsNativeSrcUser,
+			Usage:   "VictoriaLogs username for basic auth",
+			EnvVars: []string{"VLOGS_NATIVE_SRC_USERNAME"},
+		},
+		&cli.StringFlag{
+			Name:    vlogsNativeSrcPassword,
+			Usage:   "VictoriaLogs password for basic auth",
+			EnvVars: []string{"VLOGS_NATIVE_SRC_PASSWORD"},
+		},
+		&cli.StringFlag{
+			Name: vlogsNativeSrcHeaders,
+			Usage: "Optional HTTP headers to send with each request to the corresponding source address. \n" +
+				"For example, --vlogs-native-src-headers='My-Auth:foobar' would send 'My-Auth: foobar' HTTP header with every request to the corresponding source address. \n" +
+				"Multiple headers must be delimited by '^^': --vlogs-native-src-headers='header1:value1^^header2:value2'",
+		},
+		&cli.StringFlag{
+			Name:  vlogsNativeSrcBearerToken,
+			Usage: "Optional bearer auth token to use for the corresponding `--vlogs-native-src-addr`",
+		},
+		&cli.StringFlag{
+			Name:  vlogsNativeSrcTenant,
+			Usage: "Tenant to export logs from in the format 'AccountID:ProjectID'. See https://docs.victoriametrics.com/victorialogs/#multitenancy",
+			Value: "0:0",
+		},
+		&cli.StringFlag{
+			Name: vlogsNativeDstAddr,
+			Usage: "VictoriaLogs address to perform import to. \n" +
+				" For example, http://localhost:9428",
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name:    vlogsNativeDstUser,
+			Usage:   "VictoriaLogs username for basic auth",
+			EnvVars: []string{"VLOGS_NATIVE_DST_USERNAME"},
+		},
+		&cli.StringFlag{
+			Name:    vlogsNativeDstPassword,
+			Usage:   "VictoriaLogs password for basic auth",
+			EnvVars: []string{"VLOGS_NATIVE_DST_PASSWORD"},
+		},
+		&cli.StringFlag{
+			Name: vlogsNativeDstHeaders,
+			Usage: "Optional HTTP headers to send with each request to the corresponding destination address. \n" +
+				"For example, --vlogs-native-dst-headers='My-Auth:foobar' would send 'My-Auth: foobar' HTTP header with every request to the corresponding destination address. \n" +
+				"Multiple headers must be delimited by '^^': --vlogs-native-dst-headers='header1:value1^^header2:value2'",
+		},
+		&cli.StringFlag{
+			Name:  vlogsNativeDstBearerToken,
+			Usage: "Optional bearer auth token to use for the corresponding `--vlogs-native-dst-addr`",
+		},
+		&cli.StringFlag{
+			Name:  vlogsNativeDstTenant,
+			Usage: fmt.Sprintf("Tenant to import logs to in the format 'AccountID:ProjectID'. By default, the tenant from '--%s' is used", vlogsNativeSrcTenant),
+		},
+		&cli.Int64Flag{
+			Name: vmRateLimit,
+			Usage: "Optional data transfer rate limit in bytes per second.\n" +
+				"By default, the rate limit is disabled. It can be useful for limiting load on source or destination databases.",
+		},
+	}
+)
+
 const (
 	remoteRead                   = "remote-read"
 	remoteReadUseStream          = "remote-read-use-stream"
@@ -350,6 +350,62 @@ func main() {
 					return p.run(ctx)
 				},
 			},
+			{
+				Name:  "vlogs-native",
+				Usage: "Migrate logs between VictoriaLogs installations via native binary format",
+				Flags: mergeFlags(globalFlags, vlogsNativeFlags),
+				Action: func(c *cli.Context) error {
+					fmt.Println("VictoriaLogs Native import mode")
+
+					srcAddr := strings.Trim(c.String(vlogsNativeSrcAddr), "/")
+					srcAuthConfig, err := auth.Generate(
+						auth.WithBasicAuth(c.String(vlogsNativeSrcUser), c.String(vlogsNativeSrcPassword)),
+						auth.WithBearer(c.String(vlogsNativeSrcBearerToken)),
+						auth.WithHeaders(c.String(vlogsNativeSrcHeaders)))
+					if err != nil {
+						return fmt.Errorf("error initilize auth config for source: %s", srcAddr)
+					}
+
+					dstAddr := strings.Trim(c.String(vlogsNativeDstAddr), "/")
+					dstAuthConfig, err := auth.Generate(
+						auth.WithBasicAuth(c.String(vlogsNativeDstUser), c.String(vlogsNativeDstPassword)),
+						auth.WithBearer(c.String(vlogsNativeDstBearerToken)),
+						auth.WithHeaders(c.String(vlogsNativeDstHeaders)))
+					if err != nil {
+						return fmt.Errorf("error initilize auth config for destination: %s", dstAddr)
+					}
+
+					srcTenant := c.String(vlogsNativeSrcTenant)
+					dstTenant := c.String(vlogsNativeDstTenant)
+					if dstTenant == "" {
+						dstTenant = srcTenant
+					}
+
+					p := vlogsNativeProcessor{
+						src: &vlogsClient{
+							addr:       srcAddr,
+							authCfg:    srcAuthConfig,
+							httpClient: &http.Client{Transport: &http.Transport{}},
+						},
+						dst: &vlogsClient{
+							addr:       dstAddr,
+							authCfg:    dstAuthConfig,
+							httpClient: &http.Client{Transport: &http.Transport{}},
+						},
+						srcTenant: srcTenant,
+						dstTenant: dstTenant,
+						query:     c.String(vlogsNativeFilterQuery),
+						timeStart: c.String(vlogsNativeFilterTimeStart),
+						timeEnd:   c.String(vlogsNativeFilterTimeEnd),
+						step:      c.String(vlogsNativeStepInterval),
+						stateFile: c.String(vlogsNativeStateFile),
+						rateLimit: c.Int64(vmRateLimit),
+						cc:        c.Int(vlogsNativeConcurrency),
+						isSilent:  c.Bool(globalSilent),
+					}
+					return p.run(ctx)
+				},
+			},
 			{
 				Name:  "verify-block",
 				Usage: "Verifies exported block with VictoriaMetrics Native format",
@@ -0,0 +1,427 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cheggaaa/pb/v3"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmctl/auth"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmctl/barpool"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmctl/limiter"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmctl/stepper"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmctl/utils"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logstorage"
+)
+
+const (
+	vlogsNativeExportAddr = "select/logsql/export/native"
+	vlogsNativeImportAddr = "insert/native"
+)
+
+// vlogsNativeProcessor migrates logs between VictoriaLogs instances via native format.
+//
+// See https://docs.victoriametrics.com/victorialogs/querying/#exporting-logs-in-native-format
+type vlogsNativeProcessor struct {
+	src *vlogsClient
+	dst *vlogsClient
+
+	srcTenant string
+	dstTenant string
+
+	query     string
+	timeStart string
+	timeEnd   string
+	step      string
+	stateFile string
+
+	s         *stats
+	rateLimit int64
+	cc        int
+	isSilent  bool
+}
+
+// vlogsClient is an HTTP client for VictoriaLogs.
+type vlogsClient struct {
+	addr       string
+	authCfg    *auth.Config
+	httpClient *http.Client
+}
+
+// vlogsNativeStep is a single step of the migration.
+//
+// Successfully migrated steps are stored in the state file as JSON lines.
+type vlogsNativeStep struct {
+	SrcTenant string `json:"src_tenant"`
+	DstTenant string `json:"dst_tenant"`
+	Query     string `json:"query"`
+
+	// Start and End contain the inclusive time range for the step in RFC3339 format with nanosecond precision.
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+func (p *vlogsNativeProcessor) run(ctx context.Context) error {
+	if p.cc <= 0 {
+		p.cc = 1
+	}
+	p.s = &stats{
+		startTime: time.Now(),
+	}
+
+	q, err := logstorage.ParseQuery(p.query)
+	if err != nil {
+		return fmt.Errorf("cannot parse %s=%q: %w", vlogsNativeFilterQuery, p.query, err)
+	}
+	if !q.CanExportNative() {
+		return fmt.Errorf("%s=%q cannot be used for migration, since it contains pipes, which do not process every log entry independently", vlogsNativeFilterQuery, p.query)
+	}
+	if _, _, err := parseVLogsTenant(p.srcTenant); err != nil {
+		return fmt.Errorf("cannot parse %s: %w", vlogsNativeSrcTenant, err)
+	}
+	if _, _, err := parseVLogsTenant(p.dstTenant); err != nil {
+		return fmt.Errorf("cannot parse %s: %w", vlogsNativeDstTenant, err)
+	}
+
+	start, err := utils.ParseTime(p.timeStart)
+	if err != nil {
+		return fmt.Errorf("failed to parse %s, provided: %s, error: %w", vlogsNativeFilterTimeStart, p.timeStart, err)
+	}
+	end := time.Now().In(start.Location())
+	if p.timeEnd != "" {
+		end, err = utils.ParseTime(p.timeEnd)
+		if err != nil {
+			return fmt.Errorf("failed to parse %s, provided: %s, error: %w", vlogsNativeFilterTimeEnd, p.timeEnd, err)
+		}
+	}
+	ranges, err := stepper.SplitDateRange(start, end, p.step, false)
+	if err != nil {
+		return fmt.Errorf("failed to create date ranges for the given time filters: %w", err)
+	}
+
+	steps := p.getSteps(ranges)
+	migratedSteps, err := readVLogsNativeState(p.stateFile)
+	if err != nil {
+		return err
+	}
+	var pendingSteps []vlogsNativeStep
+	for _, step := range steps {
+		if _, ok := migratedSteps[step]; !ok {
+			pendingSteps = append(pendingSteps, step)
+		}
+	}
+
+	fmt.Println("") // extra line for better output formatting
+	log.Printf("Initing import process from %q (tenant %s) to %q (tenant %s) with query %q", p.src.addr, p.srcTenant, p.dst.addr, p.dstTenant, p.query)
+	msg := fmt.Sprintf("Selected time range is split into %d ranges according to %q step", len(steps), p.step)
+	if skipped := len(steps) - len(pendingSteps); skipped > 0 {
+		msg += fmt.Sprintf("; %d ranges are skipped, since they are already migrated according to %s=%q", skipped, vlogsNativeStateFile, p.stateFile)
+	}
+	if len(pendingSteps) == 0 {
+		log.Printf("%s; nothing to migrate", msg)
+		return nil
+	}
+	log.Print(msg)
+	if !p.isSilent && !prompt("Continue?") {
+		return nil
+	}
+
+	var stateFile *os.File
+	if p.stateFile != "" {
+		stateFile, err = os.OpenFile(p.stateFile, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+		if err != nil {
+			return fmt.Errorf("cannot open %s=%q: %w", vlogsNativeStateFile, p.stateFile, err)
+		}
+		defer func() { _ = stateFile.Close() }()
+	}
+	var stateFileLock sync.Mutex
+
+	var bar *pb.ProgressBar
+	if !p.isSilent {
+		bar = barpool.NewSingleProgress(fmt.Sprintf(nativeWithBackoffTpl, "Requests to make"), len(pendingSteps))
+		bar.Start()
+		defer bar.Finish()
+	}
+
+	stepCh := make(chan vlogsNativeStep)
+	errCh := make(chan error, p.cc)
+
+	var wg sync.WaitGroup
+	for i := 0; i < p.cc; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for step := range stepCh {
+				if err := p.migrateStep(ctx, step); err != nil {
+					errCh <- fmt.Errorf("cannot migrate logs on time range [%s, %s]: %w", step.Start, step.End, err)
+					return
+				}
+				if stateFile != nil {
+					stateFileLock.Lock()
+					err := writeVLogsNativeState(stateFile, step)
+					stateFileLock.Unlock()
+					if err != nil {
+						errCh <- err
+						return
+					}
+				}
+				if bar != nil {
+					bar.Increment()
+				}
+			}
+		}()
+	}
+
+	// any error breaks the migration
+sendLoop:
+	for _, step := range pendingSteps {
+		select {
+		case <-ctx.Done():
+			err = fmt.Errorf("context canceled")
+			break sendLoop
+		case infErr := <-errCh:
+			err = fmt.Errorf("export/import error: %w", infErr)
+			break sendLoop
+		case stepCh <- step:
+		}
+	}
+	close(stepCh)
+	wg.Wait()
+	close(errCh)
+
+	if err == nil {
+		err = <-errCh
+	}
+	if err != nil {
+		if p.stateFile != "" {
+			err = fmt.Errorf("%w; the migration can be resumed by running vmctl with the same %s=%q", err, vlogsNativeStateFile, p.stateFile)
+		}
+		return fmt.Errorf("migration failed: %w", err)
+	}
+
+	log.Println("Import finished!")
+	log.Print(p.s)
+	return nil
+}
+
+// getSteps returns migration steps for the given ranges returned from stepper.SplitDateRange.
+//
+// The steps do not overlap, so every log entry is migrated exactly once.
+func (p *vlogsNativeProcessor) getSteps(ranges [][]time.Time) []vlogsNativeStep {
+	steps := make([]vlogsNativeStep, 0, len(ranges))
+	for i, r := range ranges {
+		start, end := r[0], r[1]
+		if i+1 == len(ranges) || ranges[i+1][0].Equal(end) {
+			// Exclude the end of the range, since it is included either in the next range
+			// or in the next migration started from the end of the current migration.
+			end = end.Add(-time.Nanosecond)
+		}
+		steps = append(steps, vlogsNativeStep{
+			SrcTenant: p.srcTenant,
+			DstTenant: p.dstTenant,
+			Query:     p.query,
+			Start:     start.UTC().Format(time.RFC3339Nano),
+			End:       end.UTC().Format(time.RFC3339Nano),
+		})
+	}
+	return steps
+}
+
+func (p *vlogsNativeProcessor) migrateStep(ctx context.Context, step vlogsNativeStep) error {
+	start, err := time.Parse(time.RFC3339Nano, step.Start)
+	if err != nil {
+		return fmt.Errorf("cannot parse start time: %w", err)
+	}
+	end, err := time.Parse(time.RFC3339Nano, step.End)
+	if err != nil {
+		return fmt.Errorf("cannot parse end time: %w", err)
+	}
+	q, err := logstorage.ParseQuery(step.Query)
+	if err != nil {
+		return fmt.Errorf("cannot parse query: %w", err)
+	}
+	q.AddTimeFilter(start.UnixNano(), end.UnixNano())
+
+	reader, err := p.src.exportNative(ctx, step.SrcTenant, q.String())
+	if err != nil {
+		return fmt.Errorf("failed to init export pipe: %w", err)
+	}
+	defer func() { _ = reader.Close() }()
+
+	pr, pw := io.Pipe()
+	importCh := make(chan error)
+	go func() {
+		importCh <- p.dst.importNative(ctx, step.DstTenant, pr)
+		close(importCh)
+	}()
+
+	w := io.Writer(pw)
+	if p.rateLimit > 0 {
+		rl := limiter.NewLimiter(p.rateLimit)
+		w = limiter.NewWriteLimiter(pw, rl)
+	}
+
+	written, err := io.Copy(w, reader)
+	if err != nil {
+		pw.CloseWithError(err)
+		<-importCh
+		return fmt.Errorf("failed to write into %q: %w", p.dst.addr, err)
+	}
+	if err := pw.Close(); err != nil {
+		return err
+	}
+	if err := <-importCh; err != nil {
+		return err
+	}
+
+	p.s.Lock()
+	p.s.bytes += uint64(written)
+	p.s.requests++
+	p.s.Unlock()
+	return nil
+}
+
+func (c *vlogsClient) exportNative(ctx context.Context, tenant, query string) (io.ReadCloser, error) {
+	u := fmt.Sprintf("%s/%s", c.addr, vlogsNativeExportAddr)
+	params := url.Values{}
+	params.Set("query", query)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, strings.NewReader(params.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("cannot create request to %q: %s", u, err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	// disable compression since the native format is already compressed
+	req.Header.Set("Accept-Encoding", "identity")
+
+	resp, err := c.do(req, tenant, http.StatusOK)
+	if err != nil {
+		return nil, fmt.Errorf("export request failed: %w", err)
+	}
+	return resp.Body, nil
+}
+
+func (c *vlogsClient) importNative(ctx context.Context, tenant string, r io.Reader) error {
+	u := fmt.Sprintf("%s/%s", c.addr, vlogsNativeImportAddr)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, r)
+	if err != nil {
+		return fmt.Errorf("cannot create import request to %q: %s", u, err)
+	}
+
+	resp, err := c.do(req, tenant, http.StatusNoContent)
+	if err != nil {
+		return fmt.Errorf("import request failed: %w", err)
+	}
+	if err := resp.Body.Close(); err != nil {
+		return fmt.Errorf("cannot close import response body: %s", err)
+	}
+	return nil
+}
+
+func (c *vlogsClient) do(req *http.Request, tenant string, expSC int) (*http.Response, error) {
+	if c.authCfg != nil {
+		c.authCfg.SetHeaders(req, true)
+	}
+	accountID, projectID, err := parseVLogsTenant(tenant)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("AccountID", accountID)
+	req.Header.Set("ProjectID", projectID)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("unexpected error when performing request: %w", err)
+	}
+
+	if resp.StatusCode != expSC {
+		body, err := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read response body for status code %d: %s", resp.StatusCode, err)
+		}
+		return nil, fmt.Errorf("unexpected response code %d: %s", resp.StatusCode, string(body))
+	}
+	return resp, nil
+}
+
+// parseVLogsTenant parses tenant in the format 'AccountID:ProjectID'.
+func parseVLogsTenant(s string) (string, string, error) {
+	accountID, projectID, ok := strings.Cut(s, ":")
+	if !ok {
+		return "", "", fmt.Errorf("missing ':' in tenant %q; it must be in the format 'AccountID:ProjectID'", s)
+	}
+	if _, err := strconv.ParseUint(accountID, 10, 32); err != nil {
+		return "", "", fmt.Errorf("cannot parse AccountID in tenant %q: %w", s, err)
+	}
+	if _, err := strconv.ParseUint(projectID, 10, 32); err != nil {
+		return "", "", fmt.Errorf("cannot parse ProjectID in tenant %q: %w", s, err)
+	}
+	return accountID, projectID, nil
+}
+
+// readVLogsNativeState reads successfully migrated steps from the state file at path.
+//
+// An empty map is returned if path is empty or if the file doesn't exist yet.
+func readVLogsNativeState(path string) (map[vlogsNativeStep]struct{}, error) {
+	m := make(map[vlogsNativeStep]struct{})
+	if path == "" {
+		return m, nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return m, nil
+		}
+		return nil, fmt.Errorf("cannot open %s=%q: %w", vlogsNativeStateFile, path, err)
+	}
+	defer func() { _ = f.Close() }()
+
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := sc.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var step vlogsNativeStep
+		if err := json.Unmarshal(line, &step); err != nil {
+			// The line may be incomplete if vmctl was killed while writing it.
+			// The corresponding step will be migrated again.
+			log.Printf("skipping invalid line %q in %s=%q: %s", line, vlogsNativeStateFile, path, err)
+			continue
+		}
+		m[step] = struct{}{}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("cannot read %s=%q: %w", vlogsNativeStateFile, path, err)
+	}
+	return m, nil
+}
+
+// writeVLogsNativeState appends the successfully migrated step to the state file f.
+func writeVLogsNativeState(f *os.File, step vlogsNativeStep) error {
+	data, err := json.Marshal(step)
+	if err != nil {
+		return fmt.Errorf("cannot marshal migration state: %w", err)
+	}
+	data = append(data, '\n')
+	if _, err := f.Write(data); err != nil {
+		return fmt.Errorf("cannot write migration state to %q: %w", f.Name(), err)
+	}
+	if err := f.Sync(); err != nil {
+		return fmt.Errorf("cannot sync %q: %w", f.Name(), err)
+	}
+	return nil
+}
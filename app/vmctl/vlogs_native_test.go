@@ -0,0 +1,150 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmctl/stepper"
+)
+
+func Test_vlogsNativeProcessor_getSteps(t *testing.T) {
+	f := func(start, end, step string, stepsExpected [][2]string) {
+		t.Helper()
+
+		tStart, err := time.Parse(time.RFC3339, start)
+		if err != nil {
+			t.Fatalf("cannot parse start: %s", err)
+		}
+		tEnd, err := time.Parse(time.RFC3339, end)
+		if err != nil {
+			t.Fatalf("cannot parse end: %s", err)
+		}
+		ranges, err := stepper.SplitDateRange(tStart, tEnd, step, false)
+		if err != nil {
+			t.Fatalf("cannot split date range: %s", err)
+		}
+
+		p := &vlogsNativeProcessor{
+			srcTenant: "1:2",
+			dstTenant: "3:4",
+			query:     "error",
+		}
+		var steps [][2]string
+		for _, s := range p.getSteps(ranges) {
+			if s.SrcTenant != "1:2" || s.DstTenant != "3:4" || s.Query != "error" {
+				t.Fatalf("unexpected step: %+v", s)
+			}
+			steps = append(steps, [2]string{s.Start, s.End})
+		}
+		if !reflect.DeepEqual(steps, stepsExpected) {
+			t.Fatalf("unexpected steps\ngot\n%q\nwant\n%q", steps, stepsExpected)
+		}
+	}
+
+	// adjacent ranges must not overlap
+	f("2024-01-01T10:00:00Z", "2024-01-01T12:30:00Z", stepper.StepHour, [][2]string{
+		{"2024-01-01T10:00:00Z", "2024-01-01T10:59:59.999999999Z"},
+		{"2024-01-01T11:00:00Z", "2024-01-01T11:59:59.999999999Z"},
+		{"2024-01-01T12:00:00Z", "2024-01-01T12:29:59.999999999Z"},
+	})
+
+	// month ranges already end with the last nanosecond of the month
+	f("2024-01-15T00:00:00Z", "2024-03-10T00:00:00Z", stepper.StepMonth, [][2]string{
+		{"2024-01-15T00:00:00Z", "2024-01-31T23:59:59.999999999Z"},
+		{"2024-02-01T00:00:00Z", "2024-02-29T23:59:59.999999999Z"},
+		{"2024-03-01T00:00:00Z", "2024-03-09T23:59:59.999999999Z"},
+	})
+}
+
+func Test_parseVLogsTenant(t *testing.T) {
+	f := func(s, accountIDExpected, projectIDExpected string) {
+		t.Helper()
+
+		accountID, projectID, err := parseVLogsTenant(s)
+		if err != nil {
+			t.Fatalf("unexpected error when parsing %q: %s", s, err)
+		}
+		if accountID != accountIDExpected || projectID != projectIDExpected {
+			t.Fatalf("unexpected tenant for %q; got %s:%s; want %s:%s", s, accountID, projectID, accountIDExpected, projectIDExpected)
+		}
+	}
+	f("0:0", "0", "0")
+	f("12:4294967295", "12", "4294967295")
+
+	fError := func(s string) {
+		t.Helper()
+
+		if _, _, err := parseVLogsTenant(s); err == nil {
+			t.Fatalf("expecting non-nil error when parsing %q", s)
+		}
+	}
+	fError("")
+	fError("12")
+	fError("a:1")
+	fError("1:b")
+	fError("1:4294967296")
+	fError("-1:0")
+}
+
+func Test_vlogsNativeState(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.jsonl")
+
+	// missing state file
+	m, err := readVLogsNativeState(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(m) != 0 {
+		t.Fatalf("unexpected steps in missing state file: %v", m)
+	}
+
+	steps := []vlogsNativeStep{
+		{
+			SrcTenant: "0:0",
+			DstTenant: "1:0",
+			Query:     `{app="nginx"} error`,
+			Start:     "2024-01-01T00:00:00Z",
+			End:       "2024-01-01T23:59:59.999999999Z",
+		},
+		{
+			SrcTenant: "0:0",
+			DstTenant: "1:0",
+			Query:     `{app="nginx"} error`,
+			Start:     "2024-01-02T00:00:00Z",
+			End:       "2024-01-02T23:59:59.999999999Z",
+		},
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		t.Fatalf("cannot open state file: %s", err)
+	}
+	for _, step := range steps {
+		if err := writeVLogsNativeState(f, step); err != nil {
+			t.Fatalf("cannot write state: %s", err)
+		}
+	}
+
+	// incomplete line must be skipped
+	if _, err := f.WriteString(`{"src_tenant":"0:0","dst`); err != nil {
+		t.Fatalf("cannot write incomplete line: %s", err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatalf("cannot close state file: %s", err)
+	}
+
+	m, err = readVLogsNativeState(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(m) != len(steps) {
+		t.Fatalf("unexpected number of steps; got %d; want %d", len(m), len(steps))
+	}
+	for _, step := range steps {
+		if _, ok := m[step]; !ok {
+			t.Fatalf("missing step %+v in the state file", step)
+		}
+	}
+}
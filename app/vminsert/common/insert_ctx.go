@@ -20,6 +20,11 @@ type InsertCtx struct {
 	mrs            []storage.MetricRow
 	metricNamesBuf []byte
 
+	ers                []storage.ExemplarRow
+	exemplarLabelsPool []prompb.Label
+
+	mms []prompb.MetricMetadata
+
 	relabelCtx    relabel.Ctx
 	streamAggrCtx streamAggrCtx
 
@@ -42,6 +47,25 @@ func (ctx *InsertCtx) Reset(rowsLen int) {
 	ctx.mrs = mrs[:0]
 
 	ctx.metricNamesBuf = ctx.metricNamesBuf[:0]
+
+	ers := ctx.ers
+	for i := range ers {
+		ers[i] = storage.ExemplarRow{}
+	}
+	ctx.ers = ers[:0]
+
+	exemplarLabelsPool := ctx.exemplarLabelsPool
+	for i := range exemplarLabelsPool {
+		exemplarLabelsPool[i] = prompb.Label{}
+	}
+	ctx.exemplarLabelsPool = exemplarLabelsPool[:0]
+
+	mms := ctx.mms
+	for i := range mms {
+		mms[i] = prompb.MetricMetadata{}
+	}
+	ctx.mms = mms[:0]
+
 	ctx.relabelCtx.Reset()
 	ctx.streamAggrCtx.Reset()
 	ctx.skipStreamAggr = false
@@ -96,6 +120,41 @@ func (ctx *InsertCtx) addRow(metricNameRaw []byte, timestamp int64, value float6
 	return nil
 }
 
+// WriteExemplar writes exemplar with the given exemplarLabels, timestamp and value for the time series
+// with the given metricNameRaw and labels into ctx buffer.
+//
+// exemplarLabels contents must exist until ctx is flushed, while exemplarLabels slice may be re-used by the caller.
+//
+// It returns metricNameRaw for the given labels if len(metricNameRaw) == 0.
+func (ctx *InsertCtx) WriteExemplar(metricNameRaw []byte, labels, exemplarLabels []prompb.Label, timestamp int64, value float64) []byte {
+	if len(metricNameRaw) == 0 {
+		metricNameRaw = ctx.marshalMetricNameRaw(nil, labels)
+	}
+	exemplarLabelsPoolLen := len(ctx.exemplarLabelsPool)
+	ctx.exemplarLabelsPool = append(ctx.exemplarLabelsPool, exemplarLabels...)
+	ctx.ers = append(ctx.ers, storage.ExemplarRow{
+		MetricNameRaw: metricNameRaw,
+		Exemplar: storage.Exemplar{
+			Labels:    ctx.exemplarLabelsPool[exemplarLabelsPoolLen:len(ctx.exemplarLabelsPool):len(ctx.exemplarLabelsPool)],
+			Value:     value,
+			Timestamp: timestamp,
+		},
+	})
+	return metricNameRaw
+}
+
+// WriteMetadata writes metric metadata with the given metricFamilyName, metricType, help and unit into ctx buffer.
+//
+// metricFamilyName, help and unit must exist until ctx is flushed.
+func (ctx *InsertCtx) WriteMetadata(metricFamilyName string, metricType prompb.MetricType, help, unit string) {
+	ctx.mms = append(ctx.mms, prompb.MetricMetadata{
+		Type:             metricType,
+		MetricFamilyName: metricFamilyName,
+		Help:             help,
+		Unit:             unit,
+	})
+}
+
 // AddLabelBytes adds (name, value) label to ctx.Labels.
 //
 // name and value must exist until ctx.Labels is used.
@@ -153,6 +212,12 @@ func (ctx *InsertCtx) FlushBufs() error {
 	// since the number of concurrent FlushBufs() calls should be already limited via writeconcurrencylimiter
 	// used at every stream.Parse() call under lib/protoparser/*
 	err := vmstorage.AddRows(ctx.mrs)
+	if err == nil && len(ctx.ers) > 0 {
+		err = vmstorage.AddExemplars(ctx.ers)
+	}
+	if err == nil && len(ctx.mms) > 0 {
+		err = vmstorage.AddMetricMetadata(ctx.mms)
+	}
 	ctx.Reset(0)
 	if err == nil {
 		return nil
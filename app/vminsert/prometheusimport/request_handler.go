@@ -6,6 +6,7 @@ import (
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vminsert/common"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vminsert/relabel"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/httpserver"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompb"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
 	parserCommon "github.com/VictoriaMetrics/VictoriaMetrics/lib/protoparser/common"
 	parser "github.com/VictoriaMetrics/VictoriaMetrics/lib/protoparser/prometheus"
@@ -29,19 +30,20 @@ func InsertHandler(req *http.Request) error {
 		return err
 	}
 	isGzipped := req.Header.Get("Content-Encoding") == "gzip"
-	return stream.Parse(req.Body, defaultTimestamp, isGzipped, true, func(rows []parser.Row) error {
-		return insertRows(rows, extraLabels)
+	return stream.Parse(req.Body, defaultTimestamp, isGzipped, true, func(rows []parser.Row, mds []parser.Metadata) error {
+		return insertRows(rows, mds, extraLabels)
 	}, func(s string) {
 		httpserver.LogError(req, s)
 	})
 }
 
-func insertRows(rows []parser.Row, extraLabels []prompbmarshal.Label) error {
+func insertRows(rows []parser.Row, mds []parser.Metadata, extraLabels []prompbmarshal.Label) error {
 	ctx := common.GetInsertCtx()
 	defer common.PutInsertCtx(ctx)
 
 	ctx.Reset(len(rows))
 	hasRelabeling := relabel.HasRelabeling()
+	var exemplarLabels []prompb.Label
 	for i := range rows {
 		r := &rows[i]
 		ctx.Labels = ctx.Labels[:0]
@@ -62,9 +64,29 @@ func insertRows(rows []parser.Row, extraLabels []prompbmarshal.Label) error {
 			continue
 		}
 		ctx.SortLabelsIfNeeded()
-		if err := ctx.WriteDataPoint(nil, ctx.Labels, r.Timestamp, r.Value); err != nil {
+		metricNameRaw, err := ctx.WriteDataPointExt(nil, ctx.Labels, r.Timestamp, r.Value)
+		if err != nil {
 			return err
 		}
+		if e := &r.Exemplar; len(e.Tags) > 0 {
+			exemplarLabels = exemplarLabels[:0]
+			for j := range e.Tags {
+				tag := &e.Tags[j]
+				exemplarLabels = append(exemplarLabels, prompb.Label{
+					Name:  tag.Key,
+					Value: tag.Value,
+				})
+			}
+			timestamp := e.Timestamp
+			if timestamp == 0 {
+				timestamp = r.Timestamp
+			}
+			ctx.WriteExemplar(metricNameRaw, ctx.Labels, exemplarLabels, timestamp, e.Value)
+		}
+	}
+	for i := range mds {
+		md := &mds[i]
+		ctx.WriteMetadata(md.Metric, prompb.GetMetricType(md.Type), md.Help, md.Unit)
 	}
 	rowsInserted.Add(len(rows))
 	rowsPerInsert.Update(float64(len(rows)))
@@ -3,6 +3,7 @@ package prompush
 import (
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vminsert/common"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompb"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
 	"github.com/VictoriaMetrics/metrics"
 )
@@ -40,6 +41,20 @@ func Push(wr *prompbmarshal.WriteRequest) {
 		}
 		push(ctx, tssBlock)
 	}
+	if len(wr.Metadata) > 0 {
+		pushMetadata(ctx, wr.Metadata)
+	}
+}
+
+func pushMetadata(ctx *common.InsertCtx, mms []prompbmarshal.MetricMetadata) {
+	ctx.Reset(0)
+	for i := range mms {
+		mm := &mms[i]
+		ctx.WriteMetadata(mm.MetricFamilyName, prompb.MetricType(mm.Type), mm.Help, mm.Unit)
+	}
+	if err := ctx.FlushBufs(); err != nil {
+		logger.Errorf("cannot flush promscrape metadata to storage: %s", err)
+	}
 }
 
 func push(ctx *common.InsertCtx, tss []prompbmarshal.TimeSeries) {
@@ -49,6 +64,7 @@ func push(ctx *common.InsertCtx, tss []prompbmarshal.TimeSeries) {
 	}
 	ctx.Reset(rowsLen)
 	rowsTotal := 0
+	var exemplarLabels []prompb.Label
 	for i := range tss {
 		ts := &tss[i]
 		rowsTotal += len(ts.Samples)
@@ -73,6 +89,22 @@ func push(ctx *common.InsertCtx, tss []prompbmarshal.TimeSeries) {
 				return
 			}
 		}
+		for i := range ts.Exemplars {
+			e := &ts.Exemplars[i]
+			exemplarLabels = exemplarLabels[:0]
+			for j := range e.Labels {
+				label := &e.Labels[j]
+				exemplarLabels = append(exemplarLabels, prompb.Label{
+					Name:  label.Name,
+					Value: label.Value,
+				})
+			}
+			timestamp := e.Timestamp
+			if timestamp == 0 && len(ts.Samples) > 0 {
+				timestamp = ts.Samples[len(ts.Samples)-1].Timestamp
+			}
+			metricNameRaw = ctx.WriteExemplar(metricNameRaw, ctx.Labels, exemplarLabels, timestamp, e.Value)
+		}
 	}
 	rowsInserted.Add(rowsTotal)
 	rowsPerInsert.Update(float64(rowsTotal))
@@ -5,6 +5,7 @@ import (
 
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vminsert/common"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vminsert/relabel"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/httpserver"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompb"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
 	parserCommon "github.com/VictoriaMetrics/VictoriaMetrics/lib/protoparser/common"
@@ -18,18 +19,34 @@ var (
 )
 
 // InsertHandler processes remote write for prometheus.
-func InsertHandler(req *http.Request) error {
+//
+// Both Prometheus remote write 1.0 and 2.0 protocols are supported. The protocol is selected according to Content-Type request header.
+func InsertHandler(w http.ResponseWriter, req *http.Request) error {
 	extraLabels, err := parserCommon.GetExtraLabels(req)
 	if err != nil {
 		return err
 	}
+	isRemoteWriteV2, err := stream.IsRemoteWriteV2(req.Header.Get("Content-Type"))
+	if err != nil {
+		return &httpserver.ErrorWithStatusCode{
+			Err:        err,
+			StatusCode: http.StatusUnsupportedMediaType,
+		}
+	}
 	isVMRemoteWrite := req.Header.Get("Content-Encoding") == "zstd"
-	return stream.Parse(req.Body, isVMRemoteWrite, func(tss []prompb.TimeSeries) error {
-		return insertRows(tss, extraLabels)
+	ws, err := stream.Parse(req.Body, isVMRemoteWrite, isRemoteWriteV2, func(tss []prompb.TimeSeries, mms []prompb.MetricMetadata) error {
+		return insertRows(tss, mms, extraLabels)
 	})
+	if err != nil {
+		return err
+	}
+	if isRemoteWriteV2 {
+		ws.SetResponseHeaders(w.Header())
+	}
+	return nil
 }
 
-func insertRows(timeseries []prompb.TimeSeries, extraLabels []prompbmarshal.Label) error {
+func insertRows(timeseries []prompb.TimeSeries, mms []prompb.MetricMetadata, extraLabels []prompbmarshal.Label) error {
 	ctx := common.GetInsertCtx()
 	defer common.PutInsertCtx(ctx)
 
@@ -70,6 +87,19 @@ func insertRows(timeseries []prompb.TimeSeries, extraLabels []prompbmarshal.Labe
 				return err
 			}
 		}
+		exemplars := ts.Exemplars
+		for i := range exemplars {
+			e := &exemplars[i]
+			timestamp := e.Timestamp
+			if timestamp == 0 && len(samples) > 0 {
+				timestamp = samples[len(samples)-1].Timestamp
+			}
+			metricNameRaw = ctx.WriteExemplar(metricNameRaw, ctx.Labels, e.Labels, timestamp, e.Value)
+		}
+	}
+	for i := range mms {
+		mm := &mms[i]
+		ctx.WriteMetadata(mm.MetricFamilyName, mm.Type, mm.Help, mm.Unit)
 	}
 	rowsInserted.Add(rowsTotal)
 	rowsPerInsert.Update(float64(rowsTotal))
@@ -232,6 +232,24 @@ func RequestHandler(w http.ResponseWriter, r *http.Request) bool {
 			return true
 		}
 		return true
+	case "/api/v1/query_exemplars":
+		queryExemplarsRequests.Inc()
+		httpserver.EnableCORS(w, r)
+		if err := prometheus.QueryExemplarsHandler(qt, startTime, w, r); err != nil {
+			queryExemplarsErrors.Inc()
+			sendPrometheusError(w, r, err)
+			return true
+		}
+		return true
+	case "/api/v1/metadata":
+		metadataRequests.Inc()
+		httpserver.EnableCORS(w, r)
+		if err := prometheus.MetricMetadataHandler(qt, startTime, w, r); err != nil {
+			metadataErrors.Inc()
+			sendPrometheusError(w, r, err)
+			return true
+		}
+		return true
 	case "/api/v1/series/count":
 		seriesCountRequests.Inc()
 		httpserver.EnableCORS(w, r)
@@ -267,6 +285,14 @@ func RequestHandler(w http.ResponseWriter, r *http.Request) bool {
 			return true
 		}
 		return true
+	case "/api/v1/read":
+		remoteReadRequests.Inc()
+		if err := prometheus.RemoteReadHandler(qt, startTime, w, r); err != nil {
+			remoteReadErrors.Inc()
+			httpserver.Errorf(w, r, "%s", err)
+			return true
+		}
+		return true
 	case "/api/v1/export/csv":
 		exportCSVRequests.Inc()
 		if err := prometheus.ExportCSVHandler(startTime, w, r); err != nil {
@@ -538,12 +564,6 @@ func handleStaticAndSimpleRequests(w http.ResponseWriter, r *http.Request, path
 		w.Header().Set("Content-Type", "application/json")
 		fmt.Fprint(w, `{"status":"success","data":{"alerts":[]}}`)
 		return true
-	case "/api/v1/metadata":
-		// Return dumb placeholder for https://prometheus.io/docs/prometheus/latest/querying/api/#querying-metric-metadata
-		metadataRequests.Inc()
-		w.Header().Set("Content-Type", "application/json")
-		fmt.Fprintf(w, "%s", `{"status":"success","data":{}}`)
-		return true
 	case "/api/v1/status/buildinfo":
 		buildInfoRequests.Inc()
 		w.Header().Set("Content-Type", "application/json")
@@ -552,12 +572,6 @@ func handleStaticAndSimpleRequests(w http.ResponseWriter, r *http.Request, path
 		// see this issue for more info: https://github.com/VictoriaMetrics/VictoriaMetrics/issues/5370
 		fmt.Fprintf(w, "%s", `{"status":"success","data":{"version":"2.24.0"}}`)
 		return true
-	case "/api/v1/query_exemplars":
-		// Return dumb placeholder for https://prometheus.io/docs/prometheus/latest/querying/api/#querying-exemplars
-		queryExemplarsRequests.Inc()
-		w.Header().Set("Content-Type", "application/json")
-		fmt.Fprintf(w, "%s", `{"status":"success","data":[]}`)
-		return true
 	default:
 		return false
 	}
@@ -628,6 +642,9 @@ var (
 	exportRequests = metrics.NewCounter(`vm_http_requests_total{path="/api/v1/export"}`)
 	exportErrors   = metrics.NewCounter(`vm_http_request_errors_total{path="/api/v1/export"}`)
 
+	remoteReadRequests = metrics.NewCounter(`vm_http_requests_total{path="/api/v1/read"}`)
+	remoteReadErrors   = metrics.NewCounter(`vm_http_request_errors_total{path="/api/v1/read"}`)
+
 	exportCSVRequests = metrics.NewCounter(`vm_http_requests_total{path="/api/v1/export/csv"}`)
 	exportCSVErrors   = metrics.NewCounter(`vm_http_request_errors_total{path="/api/v1/export/csv"}`)
 
@@ -690,8 +707,10 @@ var (
 	alertsRequests  = metrics.NewCounter(`vm_http_requests_total{path="/api/v1/alerts"}`)
 
 	metadataRequests       = metrics.NewCounter(`vm_http_requests_total{path="/api/v1/metadata"}`)
+	metadataErrors         = metrics.NewCounter(`vm_http_request_errors_total{path="/api/v1/metadata"}`)
 	buildInfoRequests      = metrics.NewCounter(`vm_http_requests_total{path="/api/v1/buildinfo"}`)
 	queryExemplarsRequests = metrics.NewCounter(`vm_http_requests_total{path="/api/v1/query_exemplars"}`)
+	queryExemplarsErrors   = metrics.NewCounter(`vm_http_request_errors_total{path="/api/v1/query_exemplars"}`)
 )
 
 func proxyVMAlertRequests(w http.ResponseWriter, r *http.Request) {
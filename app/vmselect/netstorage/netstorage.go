@@ -199,6 +199,13 @@ func MaxWorkers() int {
 
 var gomaxprocs = cgroup.AvailableCPUs()
 
+// MaxSamplesPerQuery returns the value of -search.maxSamplesPerQuery command-line flag.
+//
+// Zero or negative value means there is no limit on the number of samples.
+func MaxSamplesPerQuery() int {
+	return *maxSamplesPerQuery
+}
+
 var defaultMaxWorkersPerQuery = func() int {
 	// maxWorkersLimit is the maximum number of CPU cores, which can be used in parallel
 	// for processing an average query, without significant impact on inter-CPU communications.
@@ -1118,6 +1125,48 @@ func SearchMetricNames(qt *querytracer.Tracer, sq *storage.SearchQuery, deadline
 	return metricNames, nil
 }
 
+// SearchExemplars returns exemplars for time series matching the given sq until the given deadline.
+func SearchExemplars(qt *querytracer.Tracer, sq *storage.SearchQuery, deadline searchutils.Deadline) ([]storage.ExemplarSeries, error) {
+	qt = qt.NewChild("fetch exemplars: %s", sq)
+	defer qt.Done()
+	if deadline.Exceeded() {
+		return nil, fmt.Errorf("timeout exceeded before starting to search exemplars: %s", deadline.String())
+	}
+
+	// Setup search.
+	tr := sq.GetTimeRange()
+	if err := vmstorage.CheckTimeRange(tr); err != nil {
+		return nil, err
+	}
+	tfss, err := setupTfss(qt, tr, sq.TagFilterss, sq.MaxMetrics, deadline)
+	if err != nil {
+		return nil, err
+	}
+
+	ess, err := vmstorage.SearchExemplars(qt, tfss, tr, sq.MaxMetrics, deadline.Deadline())
+	if err != nil {
+		return nil, fmt.Errorf("cannot find exemplars: %w", err)
+	}
+	sort.Slice(ess, func(i, j int) bool {
+		return ess[i].MetricName < ess[j].MetricName
+	})
+	qt.Printf("sort exemplars for %d series", len(ess))
+	return ess, nil
+}
+
+// SearchMetricMetadata returns metadata for the given metricFamilyName.
+//
+// Metadata for all the metric families is returned if metricFamilyName is empty.
+// limit limits the number of returned metric families, while limitPerMetric limits the number of returned entries per metric family.
+func SearchMetricMetadata(qt *querytracer.Tracer, metricFamilyName string, limit, limitPerMetric int) []storage.MetricMetadata {
+	qt = qt.NewChild("search metric metadata: metric=%q, limit=%d, limitPerMetric=%d", metricFamilyName, limit, limitPerMetric)
+	defer qt.Done()
+
+	mms := vmstorage.SearchMetricMetadata(metricFamilyName, limit, limitPerMetric)
+	qt.Printf("found %d metadata entries", len(mms))
+	return mms
+}
+
 // ProcessSearchQuery performs sq until the given deadline.
 //
 // Results.RunParallel or Results.Cancel must be called on the returned Results.
@@ -51,7 +51,7 @@ var (
 	maxFederateSeries   = flag.Int("search.maxFederateSeries", 1e6, "The maximum number of time series, which can be returned from /federate. This option allows limiting memory usage")
 	maxExportSeries     = flag.Int("search.maxExportSeries", 10e6, "The maximum number of time series, which can be returned from /api/v1/export* APIs. This option allows limiting memory usage")
 	maxTSDBStatusSeries = flag.Int("search.maxTSDBStatusSeries", 10e6, "The maximum number of time series, which can be processed during the call to /api/v1/status/tsdb. This option allows limiting memory usage")
-	maxSeriesLimit      = flag.Int("search.maxSeries", 30e3, "The maximum number of time series, which can be returned from /api/v1/series. This option allows limiting memory usage")
+	maxSeriesLimit      = flag.Int("search.maxSeries", 30e3, "The maximum number of time series, which can be returned from /api/v1/series and per each query at /api/v1/read. This option allows limiting memory usage")
 	maxLabelsAPISeries  = flag.Int("search.maxLabelsAPISeries", 1e6, "The maximum number of time series, which could be scanned when searching for the the matching time series "+
 		"at /api/v1/labels and /api/v1/label/.../values. This option allows limiting memory usage and CPU usage. See also -search.maxLabelsAPIDuration, "+
 		"-search.maxTagKeys, -search.maxTagValues and -search.ignoreExtraFiltersAtLabelsAPI")
@@ -682,6 +682,111 @@ func SeriesHandler(qt *querytracer.Tracer, startTime time.Time, w http.ResponseW
 
 var seriesDuration = metrics.NewSummary(`vm_request_duration_seconds{path="/api/v1/series"}`)
 
+// QueryExemplarsHandler processes /api/v1/query_exemplars request.
+//
+// See https://prometheus.io/docs/prometheus/latest/querying/api/#querying-exemplars
+func QueryExemplarsHandler(qt *querytracer.Tracer, startTime time.Time, w http.ResponseWriter, r *http.Request) error {
+	defer queryExemplarsDuration.UpdateDuration(startTime)
+
+	deadline := searchutils.GetDeadlineForQuery(r, startTime)
+	query := r.FormValue("query")
+	if len(query) == 0 {
+		return fmt.Errorf("missing `query` arg")
+	}
+	if len(query) > maxQueryLen.IntN() {
+		return fmt.Errorf("too long query; got %d bytes; mustn't exceed `-search.maxQueryLen=%d` bytes", len(query), maxQueryLen.N)
+	}
+	ct := startTime.UnixNano() / 1e6
+	end, err := httputils.GetTime(r, "end", ct)
+	if err != nil {
+		return err
+	}
+	// Do not set start to httputils.minTimeMsecs by default as Prometheus does,
+	// since this leads to fetching and scanning all the data from the storage.
+	start, err := httputils.GetTime(r, "start", end-defaultStep)
+	if err != nil {
+		return err
+	}
+	if end < start {
+		end = start
+	}
+	etfs, err := searchutils.GetExtraTagFilters(r)
+	if err != nil {
+		return err
+	}
+	tagFilterss, err := getTagFilterssFromQuery(query)
+	if err != nil {
+		return err
+	}
+
+	var ess []storage.ExemplarSeries
+	if len(tagFilterss) > 0 {
+		filterss := searchutils.JoinTagFilterss(tagFilterss, etfs)
+		sq := storage.NewSearchQuery(start, end, filterss, *maxUniqueTimeseries)
+		ess, err = netstorage.SearchExemplars(qt, sq, deadline)
+		if err != nil {
+			return fmt.Errorf("cannot fetch exemplars for %q: %w", sq, err)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	bw := bufferedwriter.Get(w)
+	defer bufferedwriter.Put(bw)
+	qtDone := func() {
+		qt.Donef("query=%s, start=%d, end=%d", query, start, end)
+	}
+	WriteQueryExemplarsResponse(bw, ess, qt, qtDone)
+	return bw.Flush()
+}
+
+var queryExemplarsDuration = metrics.NewSummary(`vm_request_duration_seconds{path="/api/v1/query_exemplars"}`)
+
+// MetricMetadataHandler processes /api/v1/metadata request.
+//
+// See https://prometheus.io/docs/prometheus/latest/querying/api/#querying-metric-metadata
+func MetricMetadataHandler(qt *querytracer.Tracer, startTime time.Time, w http.ResponseWriter, r *http.Request) error {
+	defer metricMetadataDuration.UpdateDuration(startTime)
+
+	metric := r.FormValue("metric")
+	limit, err := httputils.GetInt(r, "limit")
+	if err != nil {
+		return err
+	}
+	limitPerMetric, err := httputils.GetInt(r, "limit_per_metric")
+	if err != nil {
+		return err
+	}
+	mms := netstorage.SearchMetricMetadata(qt, metric, limit, limitPerMetric)
+
+	w.Header().Set("Content-Type", "application/json")
+	bw := bufferedwriter.Get(w)
+	defer bufferedwriter.Put(bw)
+	qtDone := func() {
+		qt.Donef("metric=%q, limit=%d, limit_per_metric=%d", metric, limit, limitPerMetric)
+	}
+	WriteMetricMetadataResponse(bw, mms, qt, qtDone)
+	return bw.Flush()
+}
+
+var metricMetadataDuration = metrics.NewSummary(`vm_request_duration_seconds{path="/api/v1/metadata"}`)
+
+// getTagFilterssFromQuery returns tag filters for all the series selectors in the given query.
+func getTagFilterssFromQuery(query string) ([][]storage.TagFilter, error) {
+	e, err := metricsql.Parse(query)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse query=%q: %w", query, err)
+	}
+	var tfss [][]storage.TagFilter
+	metricsql.VisitAll(e, func(expr metricsql.Expr) {
+		me, ok := expr.(*metricsql.MetricExpr)
+		if !ok || me.IsEmpty() {
+			return
+		}
+		tfss = append(tfss, searchutils.ToTagFilterss(me.LabelFilterss)...)
+	})
+	return tfss, nil
+}
+
 // QueryHandler processes /api/v1/query request.
 //
 // See https://prometheus.io/docs/prometheus/latest/querying/api/#instant-queries
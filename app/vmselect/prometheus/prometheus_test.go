@@ -4,9 +4,12 @@ import (
 	"math"
 	"net/http"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/netstorage"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompb"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/storage"
 )
 
 func TestRemoveEmptyValuesAndTimeseries(t *testing.T) {
@@ -229,3 +232,64 @@ func TestGetLatencyOffsetMillisecondsFailure(t *testing.T) {
 	}
 	f("http://localhost?latency_offset=foobar")
 }
+
+func TestGetTagFilterssFromQuery(t *testing.T) {
+	f := func(query string, resultExpected []string) {
+		t.Helper()
+		tfss, err := getTagFilterssFromQuery(query)
+		if err != nil {
+			t.Fatalf("unexpected error when parsing %q: %s", query, err)
+		}
+		var result []string
+		for _, tfs := range tfss {
+			var a []string
+			for _, tf := range tfs {
+				a = append(a, tf.String())
+			}
+			result = append(result, strings.Join(a, ","))
+		}
+		if !reflect.DeepEqual(result, resultExpected) {
+			t.Fatalf("unexpected tag filters for %q; got %q; want %q", query, result, resultExpected)
+		}
+	}
+
+	f(`1+2`, nil)
+	f(`foo`, []string{`__name__="foo"`})
+	f(`histogram_quantile(0.99, sum(rate(foo_bucket{job="bar"}[5m])) by (le))`, []string{`__name__="foo_bucket",job="bar"`})
+	f(`foo{a="b" or c=~"d.+"} / bar`, []string{`__name__="foo",a="b"`, `__name__="foo",c=~"d.+"`, `__name__="bar"`})
+
+	// invalid query
+	if _, err := getTagFilterssFromQuery(`foo{`); err == nil {
+		t.Fatalf("expecting non-nil error for invalid query")
+	}
+}
+
+func TestMetricMetadataResponse(t *testing.T) {
+	f := func(mms []storage.MetricMetadata, resultExpected string) {
+		t.Helper()
+		result := MetricMetadataResponse(mms, nil, func() {})
+		if result != resultExpected {
+			t.Fatalf("unexpected response\ngot\n%s\nwant\n%s", result, resultExpected)
+		}
+	}
+
+	f(nil, `{"status":"success","data":{}}`)
+	f([]storage.MetricMetadata{
+		{
+			MetricFamilyName: "bar",
+			Type:             prompb.MetricTypeGauge,
+			Help:             `bar "help"`,
+			Unit:             "seconds",
+		},
+		{
+			MetricFamilyName: "foo",
+			Type:             prompb.MetricTypeCounter,
+			Help:             "foo help",
+		},
+		{
+			MetricFamilyName: "foo",
+			Help:             "another foo help",
+		},
+	}, `{"status":"success","data":{"bar":[{"type":"gauge","help":"bar \"help\"","unit":"seconds"}],`+
+		`"foo":[{"type":"counter","help":"foo help","unit":""},{"type":"unknown","help":"another foo help","unit":""}]}}`)
+}
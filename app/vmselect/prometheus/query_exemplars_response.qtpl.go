@@ -0,0 +1,176 @@
+// Code generated by qtc from "query_exemplars_response.qtpl". DO NOT EDIT.
+// See https://github.com/valyala/quicktemplate for details.
+
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:1
+package prometheus
+
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:1
+import (
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/querytracer"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/storage"
+)
+
+// QueryExemplarsResponse generates response for /api/v1/query_exemplars.See https://prometheus.io/docs/prometheus/latest/querying/api/#querying-exemplars
+
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:9
+import (
+	qtio422016 "io"
+
+	qt422016 "github.com/valyala/quicktemplate"
+)
+
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:9
+var (
+	_ = qtio422016.Copy
+	_ = qt422016.AcquireByteBuffer
+)
+
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:9
+func StreamQueryExemplarsResponse(qw422016 *qt422016.Writer, ess []storage.ExemplarSeries, qt *querytracer.Tracer, qtDone func()) {
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:9
+	qw422016.N().S(`{"status":"success","data":[`)
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:13
+	var mn storage.MetricName
+
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:14
+	for i := range ess {
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:16
+		es := &ess[i]
+		err := mn.UnmarshalString(es.MetricName)
+
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:18
+		qw422016.N().S(`{"seriesLabels":`)
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:21
+		if err != nil {
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:22
+			qw422016.N().Q(err.Error())
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:23
+		} else {
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:24
+			streammetricNameObject(qw422016, &mn)
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:25
+		}
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:25
+		qw422016.N().S(`,"exemplars":[`)
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:27
+		for j := range es.Exemplars {
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:28
+			streamexemplarObject(qw422016, &es.Exemplars[j])
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:29
+			if j+1 < len(es.Exemplars) {
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:29
+				qw422016.N().S(`,`)
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:29
+			}
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:30
+		}
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:30
+		qw422016.N().S(`]}`)
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:33
+		if i+1 < len(ess) {
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:33
+			qw422016.N().S(`,`)
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:33
+		}
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:34
+	}
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:34
+	qw422016.N().S(`]`)
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:37
+	qt.Printf("generate response: series=%d", len(ess))
+	qtDone()
+
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:40
+	streamdumpQueryTrace(qw422016, qt)
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:40
+	qw422016.N().S(`}`)
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:42
+}
+
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:42
+func WriteQueryExemplarsResponse(qq422016 qtio422016.Writer, ess []storage.ExemplarSeries, qt *querytracer.Tracer, qtDone func()) {
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:42
+	qw422016 := qt422016.AcquireWriter(qq422016)
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:42
+	StreamQueryExemplarsResponse(qw422016, ess, qt, qtDone)
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:42
+	qt422016.ReleaseWriter(qw422016)
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:42
+}
+
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:42
+func QueryExemplarsResponse(ess []storage.ExemplarSeries, qt *querytracer.Tracer, qtDone func()) string {
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:42
+	qb422016 := qt422016.AcquireByteBuffer()
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:42
+	WriteQueryExemplarsResponse(qb422016, ess, qt, qtDone)
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:42
+	qs422016 := string(qb422016.B)
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:42
+	qt422016.ReleaseByteBuffer(qb422016)
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:42
+	return qs422016
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:42
+}
+
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:44
+func streamexemplarObject(qw422016 *qt422016.Writer, e *storage.Exemplar) {
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:44
+	qw422016.N().S(`{"labels":{`)
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:47
+	for i := range e.Labels {
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:48
+		label := &e.Labels[i]
+
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:49
+		qw422016.N().Q(label.Name)
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:49
+		qw422016.N().S(`:`)
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:49
+		qw422016.N().Q(label.Value)
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:49
+		if i+1 < len(e.Labels) {
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:49
+			qw422016.N().S(`,`)
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:49
+		}
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:50
+	}
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:50
+	qw422016.N().S(`},"value":"`)
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:52
+	qw422016.N().F(e.Value)
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:52
+	qw422016.N().S(`","timestamp":`)
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:53
+	qw422016.N().F(float64(e.Timestamp) / 1e3)
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:53
+	qw422016.N().S(`}`)
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:55
+}
+
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:55
+func writeexemplarObject(qq422016 qtio422016.Writer, e *storage.Exemplar) {
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:55
+	qw422016 := qt422016.AcquireWriter(qq422016)
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:55
+	streamexemplarObject(qw422016, e)
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:55
+	qt422016.ReleaseWriter(qw422016)
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:55
+}
+
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:55
+func exemplarObject(e *storage.Exemplar) string {
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:55
+	qb422016 := qt422016.AcquireByteBuffer()
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:55
+	writeexemplarObject(qb422016, e)
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:55
+	qs422016 := string(qb422016.B)
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:55
+	qt422016.ReleaseByteBuffer(qb422016)
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:55
+	return qs422016
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:55
+}
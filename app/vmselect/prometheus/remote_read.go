@@ -0,0 +1,642 @@
+package prometheus
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+	"net/http"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/VictoriaMetrics/easyproto"
+	"github.com/VictoriaMetrics/metrics"
+	"github.com/golang/snappy"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/netstorage"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/searchutils"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bufferedwriter"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/querytracer"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/storage"
+)
+
+// maxRemoteReadRequestSize is the maximum size in bytes of the packed and the unpacked remote read request.
+//
+// Remote read requests contain only label matchers, so they are usually small.
+const maxRemoteReadRequestSize = 32 * 1024 * 1024
+
+// maxRemoteReadFrameSize is the maximum size in bytes of chunks in a single frame for STREAMED_XOR_CHUNKS response.
+//
+// Prometheus uses the same limit.
+const maxRemoteReadFrameSize = 1024 * 1024
+
+// Response types for remote read requests.
+//
+// See https://github.com/prometheus/prometheus/blob/main/prompb/remote.proto
+const (
+	remoteReadResponseTypeSamples           = 0
+	remoteReadResponseTypeStreamedXORChunks = 1
+)
+
+// RemoteReadHandler processes /api/v1/read request.
+//
+// Both SAMPLES and STREAMED_XOR_CHUNKS response types are supported.
+// See https://prometheus.io/docs/prometheus/latest/querying/remote_read_api/
+func RemoteReadHandler(qt *querytracer.Tracer, startTime time.Time, w http.ResponseWriter, r *http.Request) error {
+	defer remoteReadDuration.UpdateDuration(startTime)
+
+	deadline := searchutils.GetDeadlineForExport(r, startTime)
+	bb := bbPool.Get()
+	defer bbPool.Put(bb)
+	reqBuf := bbPool.Get()
+	defer bbPool.Put(reqBuf)
+	if _, err := reqBuf.ReadFrom(io.LimitReader(r.Body, maxRemoteReadRequestSize+1)); err != nil {
+		return fmt.Errorf("cannot read remote read request: %w", err)
+	}
+	if len(reqBuf.B) > maxRemoteReadRequestSize {
+		return fmt.Errorf("too big packed remote read request; mustn't exceed %d bytes", maxRemoteReadRequestSize)
+	}
+	n, err := snappy.DecodedLen(reqBuf.B)
+	if err != nil {
+		return fmt.Errorf("cannot decompress snappy-encoded remote read request: %w", err)
+	}
+	if n > maxRemoteReadRequestSize {
+		return fmt.Errorf("too big unpacked remote read request; mustn't exceed %d bytes; got %d bytes", maxRemoteReadRequestSize, n)
+	}
+	bb.B, err = snappy.Decode(bb.B[:cap(bb.B)], reqBuf.B)
+	if err != nil {
+		return fmt.Errorf("cannot decompress snappy-encoded remote read request: %w", err)
+	}
+	var rr remoteReadRequest
+	if err := rr.unmarshalProtobuf(bb.B); err != nil {
+		return fmt.Errorf("cannot unmarshal remote read request: %w", err)
+	}
+
+	if rr.getResponseType() == remoteReadResponseTypeStreamedXORChunks {
+		return remoteReadStreamedXORChunks(qt, w, &rr, deadline)
+	}
+	return remoteReadSamples(qt, w, &rr, deadline)
+}
+
+var remoteReadDuration = metrics.NewSummary(`vm_request_duration_seconds{path="/api/v1/read"}`)
+
+// remoteReadSamples sends SAMPLES response for rr to w.
+//
+// The response must be snappy-compressed as a single block, so it is collected in memory before sending.
+// Query results are compressed one by one in order to avoid holding the whole uncompressed response in memory.
+func remoteReadSamples(qt *querytracer.Tracer, w http.ResponseWriter, rr *remoteReadRequest, deadline searchutils.Deadline) error {
+	// message ReadResponse {
+	//   repeated QueryResult results = 1;
+	// }
+	//
+	// message QueryResult {
+	//   repeated TimeSeries timeseries = 1;
+	// }
+	var sbw snappyBlockWriter
+	qr := bbPool.Get()
+	defer bbPool.Put(qr)
+	workers := make([]remoteReadWorker, netstorage.MaxWorkers())
+	sl := newRemoteReadSamplesLimiter()
+	var series []remoteReadSeries
+	for i := range rr.queries {
+		var err error
+		series, err = runRemoteReadQuery(qt, &rr.queries[i], deadline, workers, sl, series[:0], func(rw *remoteReadWorker, rs *netstorage.Result) {
+			rw.buf = rw.marshalTimeSeries(rw.buf, rs)
+		})
+		if err != nil {
+			return fmt.Errorf("error when fetching data for the query #%d: %w", i, err)
+		}
+		qrLen := 0
+		for j := range series {
+			qrLen += len(series[j].data())
+		}
+		// Marshal QueryResult as results field with the tag for field number 1 and length-delimited wire type.
+		qr.B = append(qr.B[:0], 0x0a)
+		qr.B = binary.AppendUvarint(qr.B, uint64(qrLen))
+		for j := range series {
+			qr.B = append(qr.B, series[j].data()...)
+			if len(qr.B) >= snappyBlockPartSize {
+				sbw.write(qr.B)
+				qr.B = qr.B[:0]
+			}
+		}
+		sbw.write(qr.B)
+	}
+	if sbw.decodedLen > math.MaxUint32 {
+		return fmt.Errorf("too big SAMPLES response with %d bytes; it mustn't exceed %d bytes; use STREAMED_XOR_CHUNKS response type "+
+			"or reduce the number of samples in the request", sbw.decodedLen, uint64(math.MaxUint32))
+	}
+
+	w.Header().Set("Content-Type", "application/x-protobuf")
+	w.Header().Set("Content-Encoding", "snappy")
+	bw := bufferedwriter.Get(w)
+	defer bufferedwriter.Put(bw)
+	bb := bbPool.Get()
+	bb.B = sbw.appendBlock(bb.B[:0])
+	_, err := bw.Write(bb.B)
+	bbPool.Put(bb)
+	if err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// remoteReadStreamedXORChunks sends STREAMED_XOR_CHUNKS response for rr to w.
+//
+// The response consists of frames with ChunkedReadResponse messages. Every frame is prefixed with uvarint length
+// and big-endian CRC32 Castagnoli checksum of the message.
+//
+// Frames for every query are collected in memory before sending, since they must be sorted by series labels.
+func remoteReadStreamedXORChunks(qt *querytracer.Tracer, w http.ResponseWriter, rr *remoteReadRequest, deadline searchutils.Deadline) error {
+	w.Header().Set("Content-Type", "application/x-streamed-protobuf; proto=prometheus.ChunkedReadResponse")
+	bw := bufferedwriter.Get(w)
+	defer bufferedwriter.Put(bw)
+	workers := make([]remoteReadWorker, netstorage.MaxWorkers())
+	sl := newRemoteReadSamplesLimiter()
+	var series []remoteReadSeries
+	for i := range rr.queries {
+		queryIndex := int64(i)
+		var err error
+		series, err = runRemoteReadQuery(qt, &rr.queries[i], deadline, workers, sl, series[:0], func(rw *remoteReadWorker, rs *netstorage.Result) {
+			rw.buf = rw.appendChunkedSeriesFrames(rw.buf, rs, queryIndex)
+		})
+		if err != nil {
+			return fmt.Errorf("error when fetching data for the query #%d: %w", i, err)
+		}
+		for j := range series {
+			if _, err := bw.Write(series[j].data()); err != nil {
+				return err
+			}
+		}
+	}
+	return bw.Flush()
+}
+
+// runRemoteReadQuery executes q and returns the matching series appended to dst and sorted by labels.
+//
+// marshalSeries must append the marshaled rs to rw.buf. The returned series refer to workers buffers,
+// so they are valid until the next call to runRemoteReadQuery with the same workers.
+func runRemoteReadQuery(qt *querytracer.Tracer, q *remoteReadQuery, deadline searchutils.Deadline, workers []remoteReadWorker, sl *remoteReadSamplesLimiter,
+	dst []remoteReadSeries, marshalSeries func(rw *remoteReadWorker, rs *netstorage.Result)) ([]remoteReadSeries, error) {
+	for i := range workers {
+		workers[i].reset()
+	}
+	sq := storage.NewSearchQuery(q.start, q.end, [][]storage.TagFilter{q.filters}, *maxSeriesLimit)
+	rss, err := netstorage.ProcessSearchQuery(qt, sq, deadline)
+	if err != nil {
+		return dst, fmt.Errorf("cannot fetch data for %q: %w", sq, err)
+	}
+	err = rss.RunParallel(qt, func(rs *netstorage.Result, workerID uint) error {
+		if err := sl.add(len(rs.Timestamps)); err != nil {
+			return err
+		}
+		rw := &workers[workerID]
+		start := len(rw.buf)
+		marshalSeries(rw, rs)
+		rw.addSeries(start)
+		return nil
+	})
+	if err != nil {
+		return dst, err
+	}
+
+	return appendSortedRemoteReadSeries(dst, workers), nil
+}
+
+// appendSortedRemoteReadSeries appends series from workers sorted by labels to dst and returns the result.
+//
+// Series must be sorted, since remote read clients such as Thanos and Prometheus rely on this.
+func appendSortedRemoteReadSeries(dst []remoteReadSeries, workers []remoteReadWorker) []remoteReadSeries {
+	dstLen := len(dst)
+	for i := range workers {
+		dst = append(dst, workers[i].series...)
+	}
+	slices.SortFunc(dst[dstLen:], func(a, b remoteReadSeries) int {
+		return compareRemoteReadLabels(a.labels, b.labels)
+	})
+	return dst
+}
+
+// remoteReadSamplesLimiter limits the number of samples returned across all the queries in a single remote read request.
+type remoteReadSamplesLimiter struct {
+	maxSamples int
+	remaining  atomic.Int64
+}
+
+func newRemoteReadSamplesLimiter() *remoteReadSamplesLimiter {
+	var sl remoteReadSamplesLimiter
+	sl.maxSamples = netstorage.MaxSamplesPerQuery()
+	sl.remaining.Store(int64(sl.maxSamples))
+	return &sl
+}
+
+// add registers the given number of samples at sl and returns an error if the limit is exceeded.
+func (sl *remoteReadSamplesLimiter) add(samples int) error {
+	if sl.maxSamples <= 0 {
+		return nil
+	}
+	if sl.remaining.Add(-int64(samples)) < 0 {
+		return fmt.Errorf("cannot select more than -search.maxSamplesPerQuery=%d samples across all the queries in the remote read request; "+
+			"possible solutions: increase the -search.maxSamplesPerQuery; reduce time range for the queries; use more specific label filters in order to select fewer series", sl.maxSamples)
+	}
+	return nil
+}
+
+// snappyBlockPartSize is the size of uncompressed parts of SAMPLES response, which are compressed independently.
+//
+// Snappy compresses data in 64KiB blocks, so bigger parts do not improve the compression ratio.
+const snappyBlockPartSize = 64 * 1024
+
+// snappyBlockWriter builds a single snappy block from independently compressed parts.
+//
+// Snappy block consists of uvarint-encoded length of the decoded data followed by literal and copy elements.
+// Copy elements refer to the previously decoded data via relative offsets, so elements of independently
+// compressed parts can be concatenated into a valid block for the concatenated parts.
+type snappyBlockWriter struct {
+	decodedLen uint64
+	elements   []byte
+	buf        []byte
+}
+
+// write compresses data and appends it to sbw.
+func (sbw *snappyBlockWriter) write(data []byte) {
+	if len(data) == 0 {
+		return
+	}
+	sbw.buf = snappy.Encode(sbw.buf[:cap(sbw.buf)], data)
+	_, n := binary.Uvarint(sbw.buf)
+	sbw.elements = append(sbw.elements, sbw.buf[n:]...)
+	sbw.decodedLen += uint64(len(data))
+}
+
+// appendBlock appends snappy block with all the data written to sbw to dst and returns the result.
+func (sbw *snappyBlockWriter) appendBlock(dst []byte) []byte {
+	dst = binary.AppendUvarint(dst, sbw.decodedLen)
+	return append(dst, sbw.elements...)
+}
+
+// remoteReadWorker holds per-worker state for building remote read responses.
+type remoteReadWorker struct {
+	buf    []byte
+	data   []byte
+	labels []prompbmarshal.Label
+	chunk  xorChunk
+
+	// series contains series marshaled into buf.
+	series []remoteReadSeries
+
+	// labelsPool holds labels for series.
+	labelsPool []prompbmarshal.Label
+	keyBuf     []byte
+}
+
+// remoteReadSeries refers to a series marshaled by remoteReadWorker.
+type remoteReadSeries struct {
+	// labels are sorted by name.
+	labels []prompbmarshal.Label
+
+	rw    *remoteReadWorker
+	start int
+	end   int
+}
+
+// data returns the marshaled series.
+func (s *remoteReadSeries) data() []byte {
+	return s.rw.buf[s.start:s.end]
+}
+
+func (rw *remoteReadWorker) reset() {
+	rw.buf = rw.buf[:0]
+	clear(rw.series)
+	rw.series = rw.series[:0]
+	clear(rw.labelsPool)
+	rw.labelsPool = rw.labelsPool[:0]
+}
+
+// addSeries registers the series marshaled into rw.buf starting from the given start offset.
+//
+// The series labels are taken from rw.labels, so initLabels must be called for the series before addSeries.
+func (rw *remoteReadWorker) addSeries(start int) {
+	// Copy labels into a single string, since rw.labels refer to the memory, which may be changed after returning from the function.
+	keyBuf := rw.keyBuf[:0]
+	for _, label := range rw.labels {
+		keyBuf = append(keyBuf, label.Name...)
+		keyBuf = append(keyBuf, label.Value...)
+	}
+	rw.keyBuf = keyBuf
+	key := string(keyBuf)
+
+	labelsPoolLen := len(rw.labelsPool)
+	for _, label := range rw.labels {
+		nameLen := len(label.Name)
+		valueLen := len(label.Value)
+		rw.labelsPool = append(rw.labelsPool, prompbmarshal.Label{
+			Name:  key[:nameLen],
+			Value: key[nameLen : nameLen+valueLen],
+		})
+		key = key[nameLen+valueLen:]
+	}
+	rw.series = append(rw.series, remoteReadSeries{
+		labels: rw.labelsPool[labelsPoolLen:len(rw.labelsPool):len(rw.labelsPool)],
+		rw:     rw,
+		start:  start,
+		end:    len(rw.buf),
+	})
+}
+
+// compareRemoteReadLabels compares labels sorted by name in the same way as Prometheus does.
+func compareRemoteReadLabels(a, b []prompbmarshal.Label) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if n := strings.Compare(a[i].Name, b[i].Name); n != 0 {
+			return n
+		}
+		if n := strings.Compare(a[i].Value, b[i].Value); n != 0 {
+			return n
+		}
+	}
+	return len(a) - len(b)
+}
+
+// initLabels initializes rw.labels from mn.
+//
+// Labels are sorted by name, since remote read clients such as Thanos rely on this.
+func (rw *remoteReadWorker) initLabels(mn *storage.MetricName) {
+	labels := rw.labels[:0]
+	if len(mn.MetricGroup) > 0 {
+		labels = append(labels, prompbmarshal.Label{
+			Name:  "__name__",
+			Value: bytesutil.ToUnsafeString(mn.MetricGroup),
+		})
+	}
+	for i := range mn.Tags {
+		tag := &mn.Tags[i]
+		labels = append(labels, prompbmarshal.Label{
+			Name:  bytesutil.ToUnsafeString(tag.Key),
+			Value: bytesutil.ToUnsafeString(tag.Value),
+		})
+	}
+	slices.SortFunc(labels, func(a, b prompbmarshal.Label) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	rw.labels = labels
+}
+
+// marshalTimeSeries appends rs marshaled as QueryResult.timeseries field to dst and returns the result.
+func (rw *remoteReadWorker) marshalTimeSeries(dst []byte, rs *netstorage.Result) []byte {
+	// message TimeSeries {
+	//   repeated Label labels = 1;
+	//   repeated Sample samples = 2;
+	// }
+	rw.initLabels(&rs.MetricName)
+	m := mp.Get()
+	tsm := m.MessageMarshaler().AppendMessage(1)
+	marshalRemoteReadLabels(tsm, rw.labels)
+	for i, v := range rs.Values {
+		sm := tsm.AppendMessage(2)
+		sm.AppendDouble(1, v)
+		sm.AppendInt64(2, rs.Timestamps[i])
+	}
+	dst = m.Marshal(dst)
+	mp.Put(m)
+	return dst
+}
+
+// appendChunkedSeriesFrames appends frames with rs samples encoded into XOR chunks to dst and returns the result.
+//
+// Series with many chunks are split into multiple frames with the same labels.
+func (rw *remoteReadWorker) appendChunkedSeriesFrames(dst []byte, rs *netstorage.Result, queryIndex int64) []byte {
+	// message ChunkedReadResponse {
+	//   repeated ChunkedSeries chunked_series = 1;
+	//   int64 query_index = 2;
+	// }
+	//
+	// message ChunkedSeries {
+	//   repeated Label labels = 1;
+	//   repeated Chunk chunks = 2;
+	// }
+	//
+	// message Chunk {
+	//   int64 min_time_ms = 1;
+	//   int64 max_time_ms = 2;
+	//   Encoding type = 3;
+	//   bytes data = 4;
+	// }
+	rw.initLabels(&rs.MetricName)
+	values := rs.Values
+	timestamps := rs.Timestamps
+	m := mp.Get()
+	defer mp.Put(m)
+	for len(timestamps) > 0 {
+		m.Reset()
+		mm := m.MessageMarshaler()
+		csm := mm.AppendMessage(1)
+		marshalRemoteReadLabels(csm, rw.labels)
+		frameSize := 0
+		for len(timestamps) > 0 && frameSize < maxRemoteReadFrameSize {
+			n := min(len(timestamps), maxSamplesPerXORChunk)
+			c := &rw.chunk
+			c.reset()
+			for i := 0; i < n; i++ {
+				c.appendSample(timestamps[i], values[i])
+			}
+			cm := csm.AppendMessage(2)
+			cm.AppendInt64(1, timestamps[0])
+			cm.AppendInt64(2, timestamps[n-1])
+			// Encoding XOR = 1
+			cm.AppendInt32(3, 1)
+			cm.AppendBytes(4, c.b)
+			frameSize += len(c.b)
+			timestamps = timestamps[n:]
+			values = values[n:]
+		}
+		mm.AppendInt64(2, queryIndex)
+
+		rw.data = m.Marshal(rw.data[:0])
+		dst = binary.AppendUvarint(dst, uint64(len(rw.data)))
+		dst = binary.BigEndian.AppendUint32(dst, crc32.Checksum(rw.data, castagnoliTable))
+		dst = append(dst, rw.data...)
+	}
+	return dst
+}
+
+var castagnoliTable = crc32.MakeTable(crc32.Castagnoli)
+
+var mp easyproto.MarshalerPool
+
+func marshalRemoteReadLabels(mm *easyproto.MessageMarshaler, labels []prompbmarshal.Label) {
+	// message Label {
+	//   string name = 1;
+	//   string value = 2;
+	// }
+	for _, label := range labels {
+		lm := mm.AppendMessage(1)
+		lm.AppendString(1, label.Name)
+		lm.AppendString(2, label.Value)
+	}
+}
+
+// remoteReadRequest represents Prometheus remote read request.
+type remoteReadRequest struct {
+	queries               []remoteReadQuery
+	acceptedResponseTypes []int32
+}
+
+// remoteReadQuery represents a single query in Prometheus remote read request.
+type remoteReadQuery struct {
+	// start and end are inclusive timestamps in milliseconds.
+	start int64
+	end   int64
+
+	filters []storage.TagFilter
+}
+
+// getResponseType returns the first response type from rr.acceptedResponseTypes supported by VictoriaMetrics.
+//
+// SAMPLES response type is returned if the client didn't specify the accepted response types.
+func (rr *remoteReadRequest) getResponseType() int32 {
+	for _, rt := range rr.acceptedResponseTypes {
+		if rt == remoteReadResponseTypeSamples || rt == remoteReadResponseTypeStreamedXORChunks {
+			return rt
+		}
+	}
+	return remoteReadResponseTypeSamples
+}
+
+func (rr *remoteReadRequest) unmarshalProtobuf(src []byte) (err error) {
+	// message ReadRequest {
+	//   repeated Query queries = 1;
+	//   repeated ResponseType accepted_response_types = 2;
+	// }
+	var fc easyproto.FieldContext
+	for len(src) > 0 {
+		src, err = fc.NextField(src)
+		if err != nil {
+			return fmt.Errorf("cannot read the next field: %w", err)
+		}
+		switch fc.FieldNum {
+		case 1:
+			data, ok := fc.MessageData()
+			if !ok {
+				return fmt.Errorf("cannot read query data")
+			}
+			rr.queries = append(rr.queries, remoteReadQuery{})
+			q := &rr.queries[len(rr.queries)-1]
+			if err := q.unmarshalProtobuf(data); err != nil {
+				return fmt.Errorf("cannot unmarshal query: %w", err)
+			}
+		case 2:
+			var ok bool
+			rr.acceptedResponseTypes, ok = fc.UnpackInt32s(rr.acceptedResponseTypes)
+			if !ok {
+				return fmt.Errorf("cannot read accepted_response_types")
+			}
+		}
+	}
+	return nil
+}
+
+func (q *remoteReadQuery) unmarshalProtobuf(src []byte) (err error) {
+	// message Query {
+	//   int64 start_timestamp_ms = 1;
+	//   int64 end_timestamp_ms = 2;
+	//   repeated LabelMatcher matchers = 3;
+	//   ReadHints hints = 4;
+	// }
+	var fc easyproto.FieldContext
+	for len(src) > 0 {
+		src, err = fc.NextField(src)
+		if err != nil {
+			return fmt.Errorf("cannot read the next field: %w", err)
+		}
+		switch fc.FieldNum {
+		case 1:
+			start, ok := fc.Int64()
+			if !ok {
+				return fmt.Errorf("cannot read start_timestamp_ms")
+			}
+			q.start = start
+		case 2:
+			end, ok := fc.Int64()
+			if !ok {
+				return fmt.Errorf("cannot read end_timestamp_ms")
+			}
+			q.end = end
+		case 3:
+			data, ok := fc.MessageData()
+			if !ok {
+				return fmt.Errorf("cannot read matcher data")
+			}
+			q.filters = append(q.filters, storage.TagFilter{})
+			tf := &q.filters[len(q.filters)-1]
+			if err := unmarshalRemoteReadMatcher(tf, data); err != nil {
+				return fmt.Errorf("cannot unmarshal matcher: %w", err)
+			}
+		}
+	}
+	if len(q.filters) == 0 {
+		return fmt.Errorf("query must contain at least a single matcher")
+	}
+	return nil
+}
+
+// unmarshalRemoteReadMatcher unmarshals LabelMatcher from src into tf.
+func unmarshalRemoteReadMatcher(tf *storage.TagFilter, src []byte) (err error) {
+	// message LabelMatcher {
+	//   enum Type {
+	//     EQ  = 0;
+	//     NEQ = 1;
+	//     RE  = 2;
+	//     NRE = 3;
+	//   }
+	//   Type type = 1;
+	//   string name = 2;
+	//   string value = 3;
+	// }
+	var fc easyproto.FieldContext
+	for len(src) > 0 {
+		src, err = fc.NextField(src)
+		if err != nil {
+			return fmt.Errorf("cannot read the next field: %w", err)
+		}
+		switch fc.FieldNum {
+		case 1:
+			typ, ok := fc.Int32()
+			if !ok {
+				return fmt.Errorf("cannot read matcher type")
+			}
+			switch typ {
+			case 0:
+			case 1:
+				tf.IsNegative = true
+			case 2:
+				tf.IsRegexp = true
+			case 3:
+				tf.IsNegative = true
+				tf.IsRegexp = true
+			default:
+				return fmt.Errorf("unsupported matcher type: %d", typ)
+			}
+		case 2:
+			name, ok := fc.String()
+			if !ok {
+				return fmt.Errorf("cannot read matcher name")
+			}
+			// storage.Search expects empty key for metric name.
+			if name != "__name__" {
+				tf.Key = []byte(name)
+			}
+		case 3:
+			value, ok := fc.String()
+			if !ok {
+				return fmt.Errorf("cannot read matcher value")
+			}
+			tf.Value = []byte(value)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,449 @@
+package prometheus
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/golang/snappy"
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/prometheus/prometheus/storage/remote"
+	"github.com/prometheus/prometheus/tsdb/chunkenc"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/netstorage"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/storage"
+)
+
+func TestXORChunkAppendSample(t *testing.T) {
+	f := func(timestamps []int64, values []float64) {
+		t.Helper()
+		var c xorChunk
+		c.reset()
+		for i := range timestamps {
+			c.appendSample(timestamps[i], values[i])
+		}
+		timestampsResult, valuesResult := decodeXORChunk(t, c.b)
+		if !reflect.DeepEqual(timestampsResult, timestamps) {
+			t.Fatalf("unexpected timestamps;\ngot\n%v\nwant\n%v", timestampsResult, timestamps)
+		}
+		if !equalFloats(valuesResult, values) {
+			t.Fatalf("unexpected values;\ngot\n%v\nwant\n%v", valuesResult, values)
+		}
+	}
+
+	// single sample
+	f([]int64{1000}, []float64{1.5})
+	f([]int64{-1000}, []float64{0})
+
+	// two samples
+	f([]int64{1000, 2000}, []float64{1.5, 1.5})
+	f([]int64{1000, 1001}, []float64{-1, 1e300})
+
+	// regular intervals and equal values
+	f([]int64{0, 15000, 30000, 45000, 60000}, []float64{1, 1, 1, 1, 1})
+
+	// various timestamp delta-of-deltas
+	f([]int64{0, 10, 20, 8200, 8201, 74000, 74001, 600000, 600001, 1e12, 1e12 + 1}, []float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11})
+
+	// values with various leading and trailing zeros in xor
+	f([]int64{1, 2, 3, 4, 5, 6, 7, 8}, []float64{0, 1, 1.0000001, -1, math.MaxFloat64, math.SmallestNonzeroFloat64, math.Inf(1), math.NaN()})
+	f([]int64{1, 2, 3, 4, 5}, []float64{math.Float64frombits(1), math.Float64frombits(1 << 63), math.Float64frombits(1), 0, math.Float64frombits(math.MaxUint64)})
+
+	// the maximum number of samples per chunk
+	timestamps := make([]int64, maxSamplesPerXORChunk)
+	values := make([]float64, maxSamplesPerXORChunk)
+	for i := range timestamps {
+		timestamps[i] = 1700000000000 + int64(i)*15000 + int64(i%7)*13
+		values[i] = float64(i*i) / 3
+	}
+	f(timestamps, values)
+}
+
+func TestRemoteReadRequestUnmarshalProtobuf(t *testing.T) {
+	f := func(req *prompb.ReadRequest, responseTypeExpected int32, queriesExpected []remoteReadQuery) {
+		t.Helper()
+		data, err := req.Marshal()
+		if err != nil {
+			t.Fatalf("cannot marshal request: %s", err)
+		}
+		var rr remoteReadRequest
+		if err := rr.unmarshalProtobuf(data); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if responseType := rr.getResponseType(); responseType != responseTypeExpected {
+			t.Fatalf("unexpected response type; got %d; want %d", responseType, responseTypeExpected)
+		}
+		if !reflect.DeepEqual(rr.queries, queriesExpected) {
+			t.Fatalf("unexpected queries;\ngot\n%+v\nwant\n%+v", rr.queries, queriesExpected)
+		}
+	}
+
+	f(&prompb.ReadRequest{
+		Queries: []*prompb.Query{
+			{
+				StartTimestampMs: 1000,
+				EndTimestampMs:   2000,
+				Matchers: []*prompb.LabelMatcher{
+					{Type: prompb.LabelMatcher_EQ, Name: "__name__", Value: "foo"},
+				},
+			},
+		},
+	}, remoteReadResponseTypeSamples, []remoteReadQuery{
+		{
+			start: 1000,
+			end:   2000,
+			filters: []storage.TagFilter{
+				{Value: []byte("foo")},
+			},
+		},
+	})
+
+	f(&prompb.ReadRequest{
+		Queries: []*prompb.Query{
+			{
+				StartTimestampMs: 1000,
+				EndTimestampMs:   2000,
+				Matchers: []*prompb.LabelMatcher{
+					{Type: prompb.LabelMatcher_NEQ, Name: "job", Value: "a"},
+					{Type: prompb.LabelMatcher_RE, Name: "instance", Value: "b.+"},
+					{Type: prompb.LabelMatcher_NRE, Name: "__name__", Value: "c|d"},
+				},
+				Hints: &prompb.ReadHints{
+					StepMs: 15000,
+					Func:   "rate",
+				},
+			},
+			{
+				StartTimestampMs: -10,
+				EndTimestampMs:   3000,
+				Matchers: []*prompb.LabelMatcher{
+					{Type: prompb.LabelMatcher_EQ, Name: "foo", Value: ""},
+				},
+			},
+		},
+		AcceptedResponseTypes: []prompb.ReadRequest_ResponseType{
+			prompb.ReadRequest_STREAMED_XOR_CHUNKS,
+			prompb.ReadRequest_SAMPLES,
+		},
+	}, remoteReadResponseTypeStreamedXORChunks, []remoteReadQuery{
+		{
+			start: 1000,
+			end:   2000,
+			filters: []storage.TagFilter{
+				{Key: []byte("job"), Value: []byte("a"), IsNegative: true},
+				{Key: []byte("instance"), Value: []byte("b.+"), IsRegexp: true},
+				{Value: []byte("c|d"), IsNegative: true, IsRegexp: true},
+			},
+		},
+		{
+			start: -10,
+			end:   3000,
+			filters: []storage.TagFilter{
+				{Key: []byte("foo")},
+			},
+		},
+	})
+}
+
+func TestRemoteReadRequestUnmarshalProtobufFailure(t *testing.T) {
+	f := func(req *prompb.ReadRequest) {
+		t.Helper()
+		data, err := req.Marshal()
+		if err != nil {
+			t.Fatalf("cannot marshal request: %s", err)
+		}
+		var rr remoteReadRequest
+		if err := rr.unmarshalProtobuf(data); err == nil {
+			t.Fatalf("expecting non-nil error")
+		}
+	}
+
+	// query without matchers
+	f(&prompb.ReadRequest{
+		Queries: []*prompb.Query{
+			{
+				StartTimestampMs: 1000,
+				EndTimestampMs:   2000,
+			},
+		},
+	})
+
+	// unsupported matcher type
+	f(&prompb.ReadRequest{
+		Queries: []*prompb.Query{
+			{
+				Matchers: []*prompb.LabelMatcher{
+					{Type: 10, Name: "foo", Value: "bar"},
+				},
+			},
+		},
+	})
+}
+
+func TestRemoteReadWorkerMarshalTimeSeries(t *testing.T) {
+	var rw remoteReadWorker
+	rs := newTestRemoteReadResult(3)
+	data := rw.marshalTimeSeries(nil, rs)
+
+	var qr prompb.QueryResult
+	if err := qr.Unmarshal(data); err != nil {
+		t.Fatalf("cannot unmarshal QueryResult: %s", err)
+	}
+	if len(qr.Timeseries) != 1 {
+		t.Fatalf("unexpected number of time series; got %d; want 1", len(qr.Timeseries))
+	}
+	ts := qr.Timeseries[0]
+	labelsExpected := []prompb.Label{
+		{Name: "__name__", Value: "foo"},
+		{Name: "instance", Value: "host1"},
+		{Name: "job", Value: "bar"},
+	}
+	if !reflect.DeepEqual(ts.Labels, labelsExpected) {
+		t.Fatalf("unexpected labels;\ngot\n%v\nwant\n%v", ts.Labels, labelsExpected)
+	}
+	samplesExpected := []prompb.Sample{
+		{Value: 0, Timestamp: 1000},
+		{Value: 0.5, Timestamp: 16000},
+		{Value: 1, Timestamp: 31000},
+	}
+	if !reflect.DeepEqual(ts.Samples, samplesExpected) {
+		t.Fatalf("unexpected samples;\ngot\n%v\nwant\n%v", ts.Samples, samplesExpected)
+	}
+}
+
+func TestRemoteReadWorkerAppendChunkedSeriesFrames(t *testing.T) {
+	f := func(samplesCount, framesExpected int) {
+		t.Helper()
+		var rw remoteReadWorker
+		rs := newTestRemoteReadResult(samplesCount)
+		data := rw.appendChunkedSeriesFrames(nil, rs, 42)
+
+		var timestamps []int64
+		var values []float64
+		frames := 0
+		cr := remote.NewChunkedReader(bytes.NewReader(data), 2*maxRemoteReadFrameSize, nil)
+		for {
+			var resp prompb.ChunkedReadResponse
+			err := cr.NextProto(&resp)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("cannot read frame: %s", err)
+			}
+			frames++
+			if resp.QueryIndex != 42 {
+				t.Fatalf("unexpected query index; got %d; want 42", resp.QueryIndex)
+			}
+			if len(resp.ChunkedSeries) != 1 {
+				t.Fatalf("unexpected number of series in the frame; got %d; want 1", len(resp.ChunkedSeries))
+			}
+			cs := resp.ChunkedSeries[0]
+			if len(cs.Labels) != 3 || cs.Labels[0].Name != "__name__" {
+				t.Fatalf("unexpected labels: %v", cs.Labels)
+			}
+			for _, chunk := range cs.Chunks {
+				if chunk.Type != prompb.Chunk_XOR {
+					t.Fatalf("unexpected chunk type: %s", chunk.Type)
+				}
+				chunkTimestamps, chunkValues := decodeXORChunk(t, chunk.Data)
+				if len(chunkTimestamps) > maxSamplesPerXORChunk {
+					t.Fatalf("too many samples in the chunk: %d", len(chunkTimestamps))
+				}
+				if chunk.MinTimeMs != chunkTimestamps[0] || chunk.MaxTimeMs != chunkTimestamps[len(chunkTimestamps)-1] {
+					t.Fatalf("unexpected chunk time range [%d..%d]; want [%d..%d]", chunk.MinTimeMs, chunk.MaxTimeMs, chunkTimestamps[0], chunkTimestamps[len(chunkTimestamps)-1])
+				}
+				timestamps = append(timestamps, chunkTimestamps...)
+				values = append(values, chunkValues...)
+			}
+		}
+		if frames != framesExpected {
+			t.Fatalf("unexpected number of frames; got %d; want %d", frames, framesExpected)
+		}
+		if !reflect.DeepEqual(timestamps, rs.Timestamps) {
+			t.Fatalf("unexpected timestamps;\ngot\n%v\nwant\n%v", timestamps, rs.Timestamps)
+		}
+		if !reflect.DeepEqual(values, rs.Values) {
+			t.Fatalf("unexpected values;\ngot\n%v\nwant\n%v", values, rs.Values)
+		}
+	}
+
+	f(1, 1)
+	f(maxSamplesPerXORChunk, 1)
+	f(maxSamplesPerXORChunk+1, 1)
+	f(1000, 1)
+
+	// big series must be split into multiple frames
+	f(300000, 3)
+}
+
+func newTestRemoteReadResult(samplesCount int) *netstorage.Result {
+	rs := &netstorage.Result{
+		MetricName: storage.MetricName{
+			MetricGroup: []byte("foo"),
+			Tags: []storage.Tag{
+				{Key: []byte("job"), Value: []byte("bar")},
+				{Key: []byte("instance"), Value: []byte("host1")},
+			},
+		},
+	}
+	// Use poorly compressible values for big series in order to verify splitting them into multiple frames.
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < samplesCount; i++ {
+		rs.Timestamps = append(rs.Timestamps, 1000+int64(i)*15000)
+		v := float64(i) / 2
+		if samplesCount > 1000 {
+			v = r.Float64()
+		}
+		rs.Values = append(rs.Values, v)
+	}
+	return rs
+}
+
+func decodeXORChunk(t *testing.T, data []byte) ([]int64, []float64) {
+	t.Helper()
+	c, err := chunkenc.FromData(chunkenc.EncXOR, data)
+	if err != nil {
+		t.Fatalf("cannot decode chunk: %s", err)
+	}
+	var timestamps []int64
+	var values []float64
+	it := c.Iterator(nil)
+	for it.Next() == chunkenc.ValFloat {
+		ts, v := it.At()
+		timestamps = append(timestamps, ts)
+		values = append(values, v)
+	}
+	if err := it.Err(); err != nil {
+		t.Fatalf("cannot read chunk: %s", err)
+	}
+	if n := c.NumSamples(); n != len(timestamps) {
+		t.Fatalf("unexpected number of samples in the chunk header; got %d; want %d", n, len(timestamps))
+	}
+	return timestamps, values
+}
+
+func equalFloats(a, b []float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if math.Float64bits(a[i]) != math.Float64bits(b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func TestAppendSortedRemoteReadSeries(t *testing.T) {
+	newResult := func(metricGroup string, tags ...string) *netstorage.Result {
+		rs := &netstorage.Result{
+			MetricName: storage.MetricName{
+				MetricGroup: []byte(metricGroup),
+			},
+			Timestamps: []int64{1000},
+			Values:     []float64{1},
+		}
+		for i := 0; i < len(tags); i += 2 {
+			rs.MetricName.Tags = append(rs.MetricName.Tags, storage.Tag{
+				Key:   []byte(tags[i]),
+				Value: []byte(tags[i+1]),
+			})
+		}
+		return rs
+	}
+	rss := []*netstorage.Result{
+		newResult("foo", "job", "b"),
+		newResult("bar", "job", "a"),
+		newResult("foo", "job", "a", "instance", "x"),
+		newResult("foo"),
+		newResult("foo", "job", "a"),
+		newResult("", "job", "c"),
+	}
+
+	// Spread series among workers in the same way as RunParallel does.
+	workers := make([]remoteReadWorker, 3)
+	for i, rs := range rss {
+		rw := &workers[i%len(workers)]
+		start := len(rw.buf)
+		rw.buf = rw.marshalTimeSeries(rw.buf, rs)
+
+		rw.addSeries(start)
+
+		// Modify the original tags in order to verify that the registered labels do not refer to them.
+		for i := range rs.MetricName.Tags {
+			rs.MetricName.Tags[i].Value[0] = 'X'
+		}
+	}
+
+	var labelsResult [][]prompb.Label
+	for _, s := range appendSortedRemoteReadSeries(nil, workers) {
+		var qr prompb.QueryResult
+		if err := qr.Unmarshal(s.data()); err != nil {
+			t.Fatalf("cannot unmarshal QueryResult: %s", err)
+		}
+		if len(qr.Timeseries) != 1 {
+			t.Fatalf("unexpected number of time series; got %d; want 1", len(qr.Timeseries))
+		}
+		labelsResult = append(labelsResult, qr.Timeseries[0].Labels)
+	}
+	labelsExpected := [][]prompb.Label{
+		{{Name: "__name__", Value: "bar"}, {Name: "job", Value: "a"}},
+		{{Name: "__name__", Value: "foo"}},
+		{{Name: "__name__", Value: "foo"}, {Name: "instance", Value: "x"}, {Name: "job", Value: "a"}},
+		{{Name: "__name__", Value: "foo"}, {Name: "job", Value: "a"}},
+		{{Name: "__name__", Value: "foo"}, {Name: "job", Value: "b"}},
+		{{Name: "job", Value: "c"}},
+	}
+	if !reflect.DeepEqual(labelsResult, labelsExpected) {
+		t.Fatalf("unexpected order of series;\ngot\n%v\nwant\n%v", labelsResult, labelsExpected)
+	}
+}
+
+func TestSnappyBlockWriter(t *testing.T) {
+	f := func(parts [][]byte) {
+		t.Helper()
+		var sbw snappyBlockWriter
+		var dataExpected []byte
+		for _, part := range parts {
+			sbw.write(part)
+			dataExpected = append(dataExpected, part...)
+		}
+		block := sbw.appendBlock(nil)
+		data, err := snappy.Decode(nil, block)
+		if err != nil {
+			t.Fatalf("cannot decode snappy block: %s", err)
+		}
+		if !bytes.Equal(data, dataExpected) {
+			t.Fatalf("unexpected data;\ngot\n%q\nwant\n%q", data, dataExpected)
+		}
+	}
+
+	// empty block
+	f(nil)
+	f([][]byte{nil, {}})
+
+	// single part
+	f([][]byte{[]byte("foobar")})
+
+	// multiple parts with repeated data, which is compressed with copy elements
+	f([][]byte{
+		[]byte("foo"),
+		bytes.Repeat([]byte("foobarbaz"), 1000),
+		[]byte("bar"),
+		bytes.Repeat([]byte("foobarbaz"), 100000),
+	})
+
+	// poorly compressible parts
+	r := rand.New(rand.NewSource(1))
+	var parts [][]byte
+	for i := 0; i < 10; i++ {
+		part := make([]byte, r.Intn(2*snappyBlockPartSize))
+		r.Read(part)
+		parts = append(parts, part)
+	}
+	f(parts)
+}
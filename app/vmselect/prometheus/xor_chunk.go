@@ -0,0 +1,143 @@
+package prometheus
+
+import (
+	"encoding/binary"
+	"math"
+	"math/bits"
+)
+
+// maxSamplesPerXORChunk is the maximum number of samples per XOR chunk.
+//
+// Prometheus uses the same limit for chunks in its TSDB.
+const maxSamplesPerXORChunk = 120
+
+// xorChunk builds chunks in Prometheus XOR format (Gorilla compression).
+//
+// The format must be compatible with XOR chunks from Prometheus tsdb/chunkenc package,
+// since remote read clients decode chunks with it.
+type xorChunk struct {
+	// b contains the chunk data. The first two bytes contain big-endian number of samples in the chunk.
+	b []byte
+
+	// count is the number of bits in the last byte of b, which are already written.
+	count uint8
+
+	samples  uint16
+	t        int64
+	v        float64
+	tDelta   uint64
+	leading  uint8
+	trailing uint8
+}
+
+func (c *xorChunk) reset() {
+	c.b = append(c.b[:0], 0, 0)
+	c.count = 0
+	c.samples = 0
+	c.t = 0
+	c.v = 0
+	c.tDelta = 0
+	c.leading = 0xff
+	c.trailing = 0
+}
+
+// appendSample appends the sample with the given timestamp and value to c.
+//
+// Samples must be appended in the order of increasing timestamps.
+func (c *xorChunk) appendSample(timestamp int64, value float64) {
+	var tDelta uint64
+	switch c.samples {
+	case 0:
+		var buf [binary.MaxVarintLen64]byte
+		for _, b := range buf[:binary.PutVarint(buf[:], timestamp)] {
+			c.writeBits(uint64(b), 8)
+		}
+		c.writeBits(math.Float64bits(value), 64)
+	case 1:
+		tDelta = uint64(timestamp - c.t)
+		var buf [binary.MaxVarintLen64]byte
+		for _, b := range buf[:binary.PutUvarint(buf[:], tDelta)] {
+			c.writeBits(uint64(b), 8)
+		}
+		c.writeValue(value)
+	default:
+		tDelta = uint64(timestamp - c.t)
+		dod := int64(tDelta - c.tDelta)
+		switch {
+		case dod == 0:
+			c.writeBits(0, 1)
+		case fitsBits(dod, 14):
+			c.writeBits(0b10, 2)
+			c.writeBits(uint64(dod), 14)
+		case fitsBits(dod, 17):
+			c.writeBits(0b110, 3)
+			c.writeBits(uint64(dod), 17)
+		case fitsBits(dod, 20):
+			c.writeBits(0b1110, 4)
+			c.writeBits(uint64(dod), 20)
+		default:
+			c.writeBits(0b1111, 4)
+			c.writeBits(uint64(dod), 64)
+		}
+		c.writeValue(value)
+	}
+	c.t = timestamp
+	c.v = value
+	c.tDelta = tDelta
+	c.samples++
+	binary.BigEndian.PutUint16(c.b, c.samples)
+}
+
+func (c *xorChunk) writeValue(value float64) {
+	delta := math.Float64bits(value) ^ math.Float64bits(c.v)
+	if delta == 0 {
+		c.writeBits(0, 1)
+		return
+	}
+	c.writeBits(1, 1)
+
+	leading := uint8(bits.LeadingZeros64(delta))
+	trailing := uint8(bits.TrailingZeros64(delta))
+	// The number of leading zeros is stored in 5 bits, so it cannot exceed 31.
+	if leading >= 32 {
+		leading = 31
+	}
+	if c.leading != 0xff && leading >= c.leading && trailing >= c.trailing {
+		// The meaningful bits fit the previous window.
+		c.writeBits(0, 1)
+		c.writeBits(delta>>c.trailing, 64-int(c.leading)-int(c.trailing))
+		return
+	}
+	c.leading = leading
+	c.trailing = trailing
+	c.writeBits(1, 1)
+	c.writeBits(uint64(leading), 5)
+	// The number of significant bits is stored in 6 bits, so 64 is stored as 0.
+	// This is unambiguous, since the delta isn't zero.
+	sigbits := 64 - leading - trailing
+	c.writeBits(uint64(sigbits), 6)
+	c.writeBits(delta>>trailing, int(sigbits))
+}
+
+// writeBits writes the lowest nbits bits from u to c.b starting from the most significant bit.
+func (c *xorChunk) writeBits(u uint64, nbits int) {
+	u <<= 64 - uint(nbits)
+	for nbits > 0 {
+		if c.count == 0 {
+			c.b = append(c.b, 0)
+			c.count = 8
+		}
+		n := min(nbits, int(c.count))
+		c.b[len(c.b)-1] |= byte(u >> (64 - uint(c.count)))
+		u <<= uint(n)
+		c.count -= uint8(n)
+		nbits -= n
+	}
+}
+
+// fitsBits returns true if x fits nbits bits with the sign bit.
+//
+// The range matches Prometheus XOR chunk encoding, which reserves -(1<<(nbits-1)) value.
+func fitsBits(x int64, nbits uint8) bool {
+	return -((1<<(nbits-1))-1) <= x && x <= 1<<(nbits-1)
+}
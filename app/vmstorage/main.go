@@ -19,6 +19,7 @@ import (
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/httpserver"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/mergeset"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompb"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/querytracer"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/storage"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/syncwg"
@@ -58,6 +59,13 @@ var (
 		"Excess series are logged and dropped. This can be useful for limiting series churn rate. See https://docs.victoriametrics.com/#cardinality-limiter . "+
 		"See also -storage.maxHourlySeries")
 
+	maxExemplars = flag.Int("storage.maxExemplars", 100e3, "The maximum number of exemplars, which can be stored. When the limit is reached, "+
+		"the oldest exemplars are dropped in order to free space for new exemplars. Exemplars aren't stored if the limit is set to 0. "+
+		"See https://docs.victoriametrics.com/#exemplars")
+	maxMetricMetadataFamilies = flag.Int("storage.maxMetricMetadataFamilies", 100e3, "The maximum number of metric families, which can have metadata stored. "+
+		"When the limit is reached, metadata for new metric families is dropped. Metric metadata isn't stored if the limit is set to 0. "+
+		"See https://docs.victoriametrics.com/#metric-metadata")
+
 	minFreeDiskSpaceBytes = flagutil.NewBytes("storage.minFreeDiskSpaceBytes", 10e6, "The minimum free disk space at -storageDataPath after which the storage stops accepting new data")
 
 	cacheSizeStorageTSID = flagutil.NewBytes("storage.cacheSizeStorageTSID", 0, "Overrides max size for storage/tsid cache. "+
@@ -96,6 +104,8 @@ func Init(resetCacheIfNeeded func(mrs []storage.MetricRow)) {
 	storage.SetRetentionTimezoneOffset(*retentionTimezoneOffset)
 	storage.SetFreeDiskSpaceLimit(minFreeDiskSpaceBytes.N)
 	storage.SetTSIDCacheSize(cacheSizeStorageTSID.IntN())
+	storage.SetMaxExemplars(*maxExemplars)
+	storage.SetMaxMetricMetadataFamilies(*maxMetricMetadataFamilies)
 	storage.SetTagFiltersCacheSize(cacheSizeIndexDBTagFilters.IntN())
 	mergeset.SetIndexBlocksCacheSize(cacheSizeIndexDBIndexBlocks.IntN())
 	mergeset.SetDataBlocksCacheSize(cacheSizeIndexDBDataBlocks.IntN())
@@ -158,6 +168,28 @@ func AddRows(mrs []storage.MetricRow) error {
 	return err
 }
 
+// AddExemplars adds ers to the storage.
+func AddExemplars(ers []storage.ExemplarRow) error {
+	if Storage.IsReadOnly() {
+		return errReadOnly
+	}
+	WG.Add(1)
+	Storage.AddExemplars(ers)
+	WG.Done()
+	return nil
+}
+
+// AddMetricMetadata adds mms to the storage.
+func AddMetricMetadata(mms []prompb.MetricMetadata) error {
+	if Storage.IsReadOnly() {
+		return errReadOnly
+	}
+	WG.Add(1)
+	Storage.AddMetricMetadata(mms)
+	WG.Done()
+	return nil
+}
+
 var errReadOnly = errors.New("the storage is in read-only mode; check -storage.minFreeDiskSpaceBytes command-line flag value")
 
 // RegisterMetricNames registers all the metrics from mrs in the storage.
@@ -185,6 +217,24 @@ func SearchMetricNames(qt *querytracer.Tracer, tfss []*storage.TagFilters, tr st
 	return metricNames, err
 }
 
+// SearchExemplars returns exemplars for time series matching the given tfss on the given tr.
+func SearchExemplars(qt *querytracer.Tracer, tfss []*storage.TagFilters, tr storage.TimeRange, maxMetrics int, deadline uint64) ([]storage.ExemplarSeries, error) {
+	WG.Add(1)
+	ess, err := Storage.SearchExemplars(qt, tfss, tr, maxMetrics, deadline)
+	WG.Done()
+	return ess, err
+}
+
+// SearchMetricMetadata returns metadata for the given metricFamilyName.
+//
+// Metadata for all the metric families is returned if metricFamilyName is empty.
+func SearchMetricMetadata(metricFamilyName string, limit, limitPerMetric int) []storage.MetricMetadata {
+	WG.Add(1)
+	mms := Storage.SearchMetricMetadata(metricFamilyName, limit, limitPerMetric)
+	WG.Done()
+	return mms
+}
+
 // SearchLabelNamesWithFiltersOnTimeRange searches for tag keys matching the given tfss on tr.
 func SearchLabelNamesWithFiltersOnTimeRange(qt *querytracer.Tracer, tfss []*storage.TagFilters, tr storage.TimeRange, maxTagKeys, maxMetrics int, deadline uint64) ([]string, error) {
 	WG.Add(1)
@@ -627,6 +677,16 @@ func writeStorageMetrics(w io.Writer, strg *storage.Storage) {
 
 	metrics.WriteGaugeUint64(w, `vm_next_retention_seconds`, m.NextRetentionSeconds)
 
+	metrics.WriteGaugeUint64(w, `vm_exemplars`, m.ExemplarsCount)
+	metrics.WriteGaugeUint64(w, `vm_exemplars_size_bytes`, m.ExemplarsSizeBytes)
+	metrics.WriteGaugeUint64(w, `vm_exemplars_partitions`, m.ExemplarsPartitions)
+	metrics.WriteCounterUint64(w, `vm_exemplars_added_total`, m.ExemplarsAddedTotal)
+	metrics.WriteCounterUint64(w, `vm_exemplars_dropped_total`, m.ExemplarsDroppedTotal)
+
+	metrics.WriteGaugeUint64(w, `vm_metric_metadata_entries`, m.MetricMetadataEntries)
+	metrics.WriteGaugeUint64(w, `vm_metric_metadata_size_bytes`, m.MetricMetadataSizeBytes)
+	metrics.WriteCounterUint64(w, `vm_metric_metadata_dropped_total`, m.MetricMetadataDroppedTotal)
+
 	metrics.WriteGaugeUint64(w, `vm_downsampling_partitions_scheduled`, tm.ScheduledDownsamplingPartitions)
 	metrics.WriteGaugeUint64(w, `vm_downsampling_partitions_scheduled_size_bytes`, tm.ScheduledDownsamplingPartitionsSize)
 }
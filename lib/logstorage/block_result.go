@@ -1176,8 +1176,9 @@ func (br *blockResult) copyColumns(srcColumnNames, dstColumnNames []string) {
 	csBufOffset := len(csBuf)
 	for _, c := range br.getColumns() {
 		if idx := slices.Index(srcColumnNames, c.name); idx >= 0 {
-			c.name = dstColumnNames[idx]
-			csBuf = append(csBuf, *c)
+			cCopy := *c
+			cCopy.name = dstColumnNames[idx]
+			csBuf = append(csBuf, cCopy)
 			// continue is skipped intentionally in order to leave the original column in the columns list.
 		}
 		if !slices.Contains(dstColumnNames, c.name) {
@@ -1220,6 +1221,32 @@ func (br *blockResult) renameColumns(srcColumnNames, dstColumnNames []string) {
 	}
 }
 
+// addResultColumn adds rc to br, while replacing the existing column with the same name.
+//
+// The br is valid only until rc is modified.
+func (br *blockResult) addResultColumn(rc *resultColumn) {
+	if len(rc.values) != len(br.timestamps) {
+		logger.Panicf("BUG: the number of values in the column %q must match the number of rows; got %d values; want %d values", rc.name, len(rc.values), len(br.timestamps))
+	}
+
+	if br.getColumnByNameIfExists(rc.name) != nil {
+		br.deleteColumns([]string{rc.name})
+	}
+
+	if areConstValues(rc.values) {
+		// This optimization allows reducing memory usage after br cloning
+		br.addConstColumn(rc.name, rc.values[0])
+		return
+	}
+
+	br.csBuf = append(br.csBuf, blockResultColumn{
+		name:          rc.name,
+		valueType:     valueTypeString,
+		encodedValues: rc.values,
+	})
+	br.csInitialized = false
+}
+
 // deleteColumns deletes columns with the given columnNames.
 func (br *blockResult) deleteColumns(columnNames []string) {
 	if len(columnNames) == 0 {
@@ -1270,12 +1297,20 @@ func (br *blockResult) areSameColumns(columnNames []string) bool {
 	return true
 }
 
-func (br *blockResult) getColumnByName(columnName string) *blockResultColumn {
+// getColumnByNameIfExists returns the column with the given columnName, or nil if it is missing in br.
+func (br *blockResult) getColumnByNameIfExists(columnName string) *blockResultColumn {
 	for _, c := range br.getColumns() {
 		if c.name == columnName {
 			return c
 		}
 	}
+	return nil
+}
+
+func (br *blockResult) getColumnByName(columnName string) *blockResultColumn {
+	if c := br.getColumnByNameIfExists(columnName); c != nil {
+		return c
+	}
 
 	br.addConstColumn(columnName, "")
 	return &br.csBuf[len(br.csBuf)-1]
@@ -1323,6 +1358,49 @@ func (br *blockResult) skipRows(skipRows int) {
 	}
 }
 
+// filter leaves only rows with the set bits in bm.
+//
+// bm.bitsLen must match the number of rows in br.
+func (br *blockResult) filter(bm *bitmap) {
+	if bm.areAllBitsSet() {
+		// Fast path - nothing to filter.
+		return
+	}
+
+	for _, c := range br.getColumns() {
+		// Do not update c.encodedValues and c.values in place, since they may be shared
+		// with other columns after copyColumns() call.
+		//
+		// Const and time columns may have c.values cached by the filters, so they must be filtered too.
+		// Const columns store the value in c.encodedValues[0], while time columns store values in br.timestamps,
+		// so c.encodedValues mustn't be filtered for them.
+		if c.values != nil {
+			c.values = br.filterValues(c.values, bm)
+		}
+		if !c.isConst && !c.isTime && c.encodedValues != nil {
+			c.encodedValues = br.filterValues(c.encodedValues, bm)
+		}
+		c.bucketedValues = nil
+	}
+
+	timestamps := br.timestamps
+	dstTimestamps := timestamps[:0]
+	bm.forEachSetBitReadonly(func(idx int) {
+		dstTimestamps = append(dstTimestamps, timestamps[idx])
+	})
+	br.timestamps = dstTimestamps
+}
+
+func (br *blockResult) filterValues(values []string, bm *bitmap) []string {
+	valuesBuf := br.valuesBuf
+	valuesBufLen := len(valuesBuf)
+	bm.forEachSetBitReadonly(func(idx int) {
+		valuesBuf = append(valuesBuf, values[idx])
+	})
+	br.valuesBuf = valuesBuf
+	return valuesBuf[valuesBufLen:]
+}
+
 func (br *blockResult) truncateRows(keepRows int) {
 	br.timestamps = br.timestamps[:keepRows]
 	for _, c := range br.getColumns() {
@@ -1738,6 +1816,45 @@ func (c *blockResultColumn) getMinValue() float64 {
 	}
 }
 
+// getNonEmptyValuesCount returns the number of non-empty values in c.
+//
+// It doesn't decode column values, so it is cheap to call.
+func (c *blockResultColumn) getNonEmptyValuesCount(br *blockResult) uint64 {
+	if c.isConst {
+		if c.encodedValues[0] == "" {
+			return 0
+		}
+		return uint64(len(br.timestamps))
+	}
+	if c.isTime {
+		return uint64(len(br.timestamps))
+	}
+
+	switch c.valueType {
+	case valueTypeString:
+		n := uint64(0)
+		for _, v := range c.encodedValues {
+			if v != "" {
+				n++
+			}
+		}
+		return n
+	case valueTypeDict:
+		n := uint64(0)
+		dictValues := c.dictValues
+		for _, v := range c.encodedValues {
+			idx := v[0]
+			if dictValues[idx] != "" {
+				n++
+			}
+		}
+		return n
+	default:
+		// Numeric, ipv4 and timestamp values cannot be empty.
+		return uint64(len(br.timestamps))
+	}
+}
+
 func (c *blockResultColumn) sumLenValues(br *blockResult) uint64 {
 	if c.isConst {
 		v := c.encodedValues[0]
@@ -11,8 +11,11 @@ import (
 
 // mustMergeBlockStreams merges bsrs to bsw and updates ph accordingly.
 //
+// Log entries outside the retention rules are dropped if rm isn't nil.
+// Log entries deleted via Storage.DeleteRows are dropped if dm isn't nil.
+//
 // Finalize() is guaranteed to be called on bsrs and bsw before returning from the func.
-func mustMergeBlockStreams(ph *partHeader, bsw *blockStreamWriter, bsrs []*blockStreamReader, stopCh <-chan struct{}) {
+func mustMergeBlockStreams(ph *partHeader, bsw *blockStreamWriter, bsrs []*blockStreamReader, rm *retentionMatcher, dm *deleteMatcher, stopCh <-chan struct{}) {
 	bsm := getBlockStreamMerger()
 	bsm.mustInit(bsw, bsrs)
 	for len(bsm.readersHeap) > 0 {
@@ -20,7 +23,19 @@ func mustMergeBlockStreams(ph *partHeader, bsw *blockStreamWriter, bsrs []*block
 			break
 		}
 		bsr := bsm.readersHeap[0]
-		bsm.mustWriteBlock(&bsr.blockData, bsw)
+		bd := &bsr.blockData
+		deadline := rm.getDeadline(&bd.streamID)
+		deleteTasksSeq := bsr.ph.DeleteTasksSeq
+		switch {
+		case bd.timestampsData.maxTimestamp < deadline:
+			// Fast path - drop the block, since all its log entries are outside the retention rules.
+			rm.addRowsDropped(bd.rowsCount)
+		case bd.timestampsData.minTimestamp < deadline || dm.needDeleteRows(bd, deleteTasksSeq):
+			// Slow path - drop log entries outside the retention rules and deleted log entries from the block.
+			bsm.mustWriteBlockWithDroppedRows(bd, rm, deadline, dm, deleteTasksSeq)
+		default:
+			bsm.mustWriteBlock(bd, bsw)
+		}
 		if bsr.NextBlock() {
 			heap.Fix(&bsm.readersHeap, 0)
 		} else {
@@ -77,6 +92,12 @@ type blockStreamMerger struct {
 	//
 	// It is used for limiting the number of columns written per block
 	uniqueFields int
+
+	// hasDeletedRows is set if some log entries were deleted or dropped according to the retention rules from rows.
+	//
+	// In this case rows may start with bigger timestamp than the minTimestamp for the next block with the same streamID,
+	// so the next block must be merged with rows in order to preserve the order of blocks by minTimestamp.
+	hasDeletedRows bool
 }
 
 func (bsm *blockStreamMerger) reset() {
@@ -109,6 +130,7 @@ func (bsm *blockStreamMerger) resetRows() {
 
 	bsm.uncompressedRowsSizeBytes = 0
 	bsm.uniqueFields = 0
+	bsm.hasDeletedRows = false
 }
 
 func (bsm *blockStreamMerger) mustInit(bsw *blockStreamWriter, bsrs []*blockStreamReader) {
@@ -132,6 +154,11 @@ func (bsm *blockStreamMerger) mustWriteBlock(bd *blockData, bsw *blockStreamWrit
 	bsm.checkNextBlock(bd)
 	uniqueFields := len(bd.columnsData) + len(bd.constColumns)
 	switch {
+	case bsm.hasDeletedRows && bd.streamID.equal(&bsm.streamID) && len(bsm.rows.timestamps) > 0 && bd.timestampsData.minTimestamp < bsm.rows.timestamps[0]:
+		// The current log entries start after the bd, since some of them were deleted.
+		// They must be merged with the bd in order to preserve the order of blocks by minTimestamp.
+		bsm.mustMergeRows(bd)
+		bsm.uniqueFields += uniqueFields
 	case !bd.streamID.equal(&bsm.streamID):
 		// The bd contains another streamID.
 		// Write the current log entries under the current streamID, then process the bd.
@@ -174,6 +201,49 @@ func (bsm *blockStreamMerger) mustWriteBlock(bd *blockData, bsw *blockStreamWrit
 	}
 }
 
+// mustWriteBlockWithDroppedRows writes bd to bsm after dropping log entries with timestamps smaller than the deadline
+// and deleting log entries matching dm delete tasks with sequence numbers bigger than deleteTasksSeq.
+func (bsm *blockStreamMerger) mustWriteBlockWithDroppedRows(bd *blockData, rm *retentionMatcher, deadline int64, dm *deleteMatcher, deleteTasksSeq uint64) {
+	bsm.checkNextBlock(bd)
+	switch {
+	case !bd.streamID.equal(&bsm.streamID):
+		bsm.mustFlushRows()
+		bsm.streamID = bd.streamID
+	case bsm.uncompressedRowsSizeBytes >= maxUncompressedBlockSize && (len(bsm.rows.timestamps) == 0 || bd.timestampsData.minTimestamp >= bsm.rows.timestamps[0]):
+		// The current log entries are too big and they can be written before the bd.
+		bsm.mustFlushRows()
+	}
+	if bsm.bd.rowsCount > 0 {
+		bsm.mustUnmarshalRows(&bsm.bd)
+		bsm.bd.reset()
+		bsm.a.reset()
+	}
+
+	rowsLen := len(bsm.rows.timestamps)
+	bsm.mustUnmarshalRows(bd)
+	rm.dropRows(&bsm.rows, rowsLen, deadline)
+	dm.deleteRows(&bsm.rows, rowsLen, &bd.streamID, deleteTasksSeq)
+
+	timestamps := bsm.rows.timestamps
+	rows := bsm.rows.rows
+	bsm.rowsTmp.mergeRows(timestamps[:rowsLen], timestamps[rowsLen:], rows[:rowsLen], rows[rowsLen:])
+	bsm.rows, bsm.rowsTmp = bsm.rowsTmp, bsm.rows
+	bsm.rowsTmp.reset()
+
+	bsm.uncompressedRowsSizeBytes = uncompressedRowsSizeBytes(bsm.rows.rows)
+	bsm.uniqueFields += len(bd.columnsData) + len(bd.constColumns)
+
+	if len(bsm.rows.timestamps) > 0 && bsm.rows.timestamps[0] > bd.timestampsData.minTimestamp {
+		// Do not flush rows even if they are too big, since the next block for the same streamID
+		// may have smaller minTimestamp than the remaining log entries. Such a block must be merged with rows.
+		bsm.hasDeletedRows = true
+		return
+	}
+	if bsm.uncompressedRowsSizeBytes >= maxUncompressedBlockSize {
+		bsm.mustFlushRows()
+	}
+}
+
 // checkNextBlock checks whether the bd can be written next after the current data.
 func (bsm *blockStreamMerger) checkNextBlock(bd *blockData) {
 	if len(bsm.rows.timestamps) > 0 && bsm.bd.rowsCount > 0 {
@@ -202,6 +272,10 @@ func (bsm *blockStreamMerger) checkNextBlock(bd *blockData) {
 		}
 		return
 	}
+	if bsm.hasDeletedRows {
+		// The minimum timestamp for rows may be bigger than the original minTimestamp because of deleted log entries.
+		return
+	}
 	minTimestamp := bsm.rows.timestamps[0]
 	if nextMinTimestamp < minTimestamp {
 		logger.Panicf("FATAL: cannot merge %s: the next block's minTimestamp=%d is smaller than the minTimestamp=%d for log entries for the current block",
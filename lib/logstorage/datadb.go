@@ -15,6 +15,7 @@ import (
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fs"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/memory"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/timerpool"
 )
 
 // The maximum size of big part.
@@ -82,6 +83,11 @@ type datadb struct {
 	// partsLock protects parts from concurrent access
 	partsLock sync.Mutex
 
+	// isRetentionRulesMergeInProgress is set to true when the merge for applying retention rules is in progress.
+	//
+	// It must be accessed under partsLock.
+	isRetentionRulesMergeInProgress bool
+
 	// wg is used for determining when background workers stop
 	//
 	// wg.Add() must be called under partsLock after checking whether stopCh isn't closed.
@@ -425,6 +431,155 @@ func (ddb *datadb) bigPartsMerger() {
 	}
 }
 
+// mustApplyRetentionRules starts background merge for dropping log entries outside the given retention rules from ddb.
+//
+// The merge isn't started if all the rules were already applied to ddb.
+func (ddb *datadb) mustApplyRetentionRules(rules []string) {
+	appliedRules := mustReadAppliedRetentionRules(ddb.pt.path)
+	if containsAllStrings(appliedRules, rules) {
+		return
+	}
+
+	ddb.partsLock.Lock()
+	ddb.startRetentionRulesMergerLocked(rules)
+	ddb.partsLock.Unlock()
+}
+
+func (ddb *datadb) startRetentionRulesMergerLocked(rules []string) {
+	if needStop(ddb.stopCh) {
+		return
+	}
+	if ddb.isRetentionRulesMergeInProgress {
+		return
+	}
+	ddb.isRetentionRulesMergeInProgress = true
+	ddb.wg.Add(1)
+	go func() {
+		ddb.retentionRulesMerger(rules)
+		ddb.partsLock.Lock()
+		ddb.isRetentionRulesMergeInProgress = false
+		ddb.partsLock.Unlock()
+		ddb.wg.Done()
+	}()
+}
+
+// retentionRulesMerger merges every file part at ddb individually in order to drop log entries outside the retention rules.
+//
+// The rules are stored as applied after all the parts are successfully merged.
+func (ddb *datadb) retentionRulesMerger(rules []string) {
+	rm := newRetentionMatcher(ddb.pt, math.MinInt64)
+	if rm == nil {
+		return
+	}
+	needRewrite := func(pw *partWrapper) bool {
+		// There is no need in merging the part if all its log entries are inside the retention rules.
+		return pw.p.ph.MinTimestamp < rm.maxDeadline
+	}
+	isComplete := ddb.mustRewriteParts(needRewrite, rm.needMergePart)
+	if isComplete {
+		mustWriteAppliedRetentionRules(ddb.pt.path, rules)
+	}
+}
+
+func (ddb *datadb) startDeleteMerger(dt *deleteTask) {
+	ddb.partsLock.Lock()
+	defer ddb.partsLock.Unlock()
+
+	if needStop(ddb.stopCh) {
+		return
+	}
+	ddb.wg.Add(1)
+	go func() {
+		if ddb.deleteMerger(dt) {
+			ddb.pt.s.markDeleteTaskPartitionDone(dt)
+		}
+		ddb.wg.Done()
+	}()
+}
+
+// deleteMerger merges every part at ddb, which may contain log entries deleted by dt, individually
+// in order to drop these log entries.
+//
+// It returns true if all these parts are re-written. It returns false if ddb is stopped.
+func (ddb *datadb) deleteMerger(dt *deleteTask) bool {
+	needRewrite := func(pw *partWrapper) bool {
+		if pw.p.ph.DeleteTasksSeq >= dt.Seq {
+			// The part has been created after dt registration, so dt is already applied to it.
+			return false
+		}
+		ph := &pw.p.ph
+		return ph.MinTimestamp <= dt.maxTimestamp && ph.MaxTimestamp >= dt.minTimestamp
+	}
+	for !ddb.mustRewriteParts(needRewrite, nil) {
+		// Some of the parts are in merge or in memory. Wait until they are merged into new parts
+		// and then re-write the remaining parts.
+		t := timerpool.Get(time.Second)
+		select {
+		case <-ddb.stopCh:
+			timerpool.Put(t)
+			return false
+		case <-t.C:
+			timerpool.Put(t)
+		}
+	}
+	return true
+}
+
+// mustRewriteParts merges every file part at ddb individually if needRewrite returns true for it.
+//
+// needRewrite is called under ddb.partsLock, so it must be fast. If needRewritePart isn't nil,
+// then it is called without the lock before merging every part selected by needRewrite. The part isn't merged
+// if needRewritePart returns false for it. This allows performing slower checks, which need reading the part data.
+//
+// It returns true if all the parts were successfully re-written.
+func (ddb *datadb) mustRewriteParts(needRewrite func(pw *partWrapper) bool, needRewritePart func(p *part) bool) bool {
+	var pws []*partWrapper
+	isComplete := true
+
+	ddb.partsLock.Lock()
+	for _, pw := range ddb.inmemoryParts {
+		if needRewrite(pw) {
+			// In-memory parts are merged by background mergers.
+			isComplete = false
+			break
+		}
+	}
+	pwsAll := append([]*partWrapper{}, ddb.smallParts...)
+	pwsAll = append(pwsAll, ddb.bigParts...)
+	for _, pw := range pwsAll {
+		if !needRewrite(pw) {
+			continue
+		}
+		if pw.isInMerge {
+			isComplete = false
+			continue
+		}
+		pw.isInMerge = true
+		pws = append(pws, pw)
+	}
+	ddb.partsLock.Unlock()
+
+	for i, pw := range pws {
+		if needStop(ddb.stopCh) {
+			ddb.releasePartsToMerge(pws[i:])
+			return false
+		}
+		if needRewritePart != nil && !needRewritePart(pw.p) {
+			ddb.releasePartsToMerge([]*partWrapper{pw})
+			continue
+		}
+		bigPartsConcurrencyCh <- struct{}{}
+		ddb.mustMergeParts([]*partWrapper{pw}, false)
+		<-bigPartsConcurrencyCh
+
+		if !pw.mustDrop.Load() {
+			// The part wasn't merged because of lack of free disk space or because ddb is stopped.
+			isComplete = false
+		}
+	}
+	return isComplete
+}
+
 // getPartsToMergeLocked returns optimal parts to merge from pws.
 //
 // The summary size of the returned parts must be smaller than maxOutBytes.
@@ -513,9 +668,18 @@ func (ddb *datadb) mustMergeParts(pws []*partWrapper, isFinal bool) {
 	mergeIdx := ddb.nextMergeIdx()
 	dstPartPath := ddb.getDstPartPath(dstPartType, mergeIdx)
 
-	if isFinal && len(pws) == 1 && pws[0].mp != nil {
+	// Prepare deleteMatcher for dropping log entries deleted via Storage.DeleteRows.
+	// All the delete tasks up to deleteTasksSeq are applied to the resulting part.
+	deleteTasks, deleteTasksSeq := ddb.pt.s.getDeleteTasksWithSeq()
+	dm := newDeleteMatcher(ddb.pt, deleteTasks, getMinDeleteTasksSeq(pws), getMinTimestamp(pws), getMaxTimestamp(pws))
+
+	// Prepare retentionMatcher for dropping log entries outside the retention rules.
+	rm := newRetentionMatcher(ddb.pt, getMinTimestamp(pws))
+
+	if isFinal && len(pws) == 1 && pws[0].mp != nil && dm == nil && rm == nil {
 		// Fast path: flush a single in-memory part to disk.
 		mp := pws[0].mp
+		mp.ph.DeleteTasksSeq = deleteTasksSeq
 		mp.MustStoreToDisk(dstPartPath)
 		pwNew := ddb.openCreatedPart(&mp.ph, pws, nil, dstPartPath)
 		ddb.swapSrcWithDstParts(pws, pwNew, dstPartType)
@@ -551,11 +715,12 @@ func (ddb *datadb) mustMergeParts(pws []*partWrapper, isFinal bool) {
 		// The final merge shouldn't be stopped even if ddb.stopCh is closed.
 		stopCh = nil
 	}
-	mustMergeBlockStreams(&ph, bsw, bsrs, stopCh)
+	mustMergeBlockStreams(&ph, bsw, bsrs, rm, dm, stopCh)
 	putBlockStreamWriter(bsw)
 	for _, bsr := range bsrs {
 		putBlockStreamReader(bsr)
 	}
+	ph.DeleteTasksSeq = deleteTasksSeq
 
 	// Persist partHeader for destination part after the merge.
 	if mpNew != nil {
@@ -573,6 +738,11 @@ func (ddb *datadb) mustMergeParts(pws []*partWrapper, isFinal bool) {
 		return
 	}
 
+	if rm != nil {
+		ddb.pt.s.rowsDroppedByRetentionRules.Add(rm.rowsDropped)
+	}
+	dm.addRowsDeleted()
+
 	// Atomically swap the source parts with the newly created part.
 	pwNew := ddb.openCreatedPart(&ph, pws, mpNew, dstPartPath)
 
@@ -664,9 +834,13 @@ func (ddb *datadb) mustAddRows(lr *LogRows) {
 		return
 	}
 
+	// Rows added after the registration of delete tasks mustn't be deleted by these tasks.
+	_, deleteTasksSeq := ddb.pt.s.getDeleteTasksWithSeq()
+
 	inmemoryPartsConcurrencyCh <- struct{}{}
 	mp := getInmemoryPart()
 	mp.mustInitFromRows(lr)
+	mp.ph.DeleteTasksSeq = deleteTasksSeq
 	p := mustOpenInmemoryPart(ddb.pt, mp)
 	<-inmemoryPartsConcurrencyCh
 
@@ -793,6 +967,39 @@ func (ddb *datadb) debugFlush() {
 	// Nothing to do, since all the ingested data is available for search via ddb.inmemoryParts.
 }
 
+// mustCreateSnapshotAt creates a snapshot for ddb at dstDir.
+//
+// The snapshot contains hard links to file parts, so it doesn't occupy additional disk space
+// until the original parts are removed by background merges.
+func (ddb *datadb) mustCreateSnapshotAt(dstDir string) {
+	// Flush in-memory parts to disk, so they are included in the snapshot.
+	ddb.mustFlushInmemoryPartsToFiles(true)
+
+	ddb.partsLock.Lock()
+	pws := append([]*partWrapper{}, ddb.smallParts...)
+	pws = append(pws, ddb.bigParts...)
+	for _, pw := range pws {
+		pw.incRef()
+	}
+	ddb.partsLock.Unlock()
+
+	defer func() {
+		for _, pw := range pws {
+			pw.decRef()
+		}
+	}()
+
+	fs.MustMkdirFailIfExist(dstDir)
+	for _, pw := range pws {
+		srcPartPath := pw.p.path
+		dstPartPath := filepath.Join(dstDir, filepath.Base(srcPartPath))
+		fs.MustHardLinkFiles(srcPartPath, dstPartPath)
+	}
+	mustWritePartNames(dstDir, getPartNames(pws), nil)
+
+	fs.MustSyncPath(dstDir)
+}
+
 func (ddb *datadb) swapSrcWithDstParts(pws []*partWrapper, pwNew *partWrapper, dstPartType partType) {
 	// Atomically unregister old parts and add new part to pt.
 	partsToRemove := partsToMap(pws)
@@ -1226,3 +1433,34 @@ func getBlocksCount(pws []*partWrapper) uint64 {
 	}
 	return n
 }
+
+func getMinTimestamp(pws []*partWrapper) int64 {
+	minTimestamp := int64(math.MaxInt64)
+	for _, pw := range pws {
+		if pw.p.ph.MinTimestamp < minTimestamp {
+			minTimestamp = pw.p.ph.MinTimestamp
+		}
+	}
+	return minTimestamp
+}
+
+// getMinDeleteTasksSeq returns the minimum sequence number of the applied delete tasks across pws.
+func getMinDeleteTasksSeq(pws []*partWrapper) uint64 {
+	minSeq := uint64(math.MaxUint64)
+	for _, pw := range pws {
+		if pw.p.ph.DeleteTasksSeq < minSeq {
+			minSeq = pw.p.ph.DeleteTasksSeq
+		}
+	}
+	return minSeq
+}
+
+func getMaxTimestamp(pws []*partWrapper) int64 {
+	maxTimestamp := int64(math.MinInt64)
+	for _, pw := range pws {
+		if pw.p.ph.MaxTimestamp > maxTimestamp {
+			maxTimestamp = pw.p.ph.MaxTimestamp
+		}
+	}
+	return maxTimestamp
+}
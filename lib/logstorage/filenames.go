@@ -13,10 +13,14 @@ const (
 	metadataFilename = "metadata.json"
 	partsFilename    = "parts.json"
 
+	appliedRetentionRulesFilename = "applied_retention_rules.json"
+	deleteTasksFilename           = "delete_tasks.json"
+
 	streamIDCacheFilename = "stream_id.bin"
 
 	indexdbDirname    = "indexdb"
 	datadbDirname     = "datadb"
 	cacheDirname      = "cache"
 	partitionsDirname = "partitions"
+	snapshotsDirname  = "snapshots"
 )
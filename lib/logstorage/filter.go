@@ -5,6 +5,54 @@ type filter interface {
 	// String returns string representation of the filter
 	String() string
 
+	// updateNeededFields must update neededFields with fields needed for the filter
+	updateNeededFields(neededFields fieldsSet)
+
 	// apply must update bm according to the filter applied to the given bs block
 	apply(bs *blockSearch, bm *bitmap)
+
+	// applyToBlockResult must update bm according to the filter applied to the given br block
+	applyToBlockResult(br *blockResult, bm *bitmap)
+}
+
+// applyToBlockResultGeneric updates bm according to matchFunc applied to fieldName values in br.
+//
+// Missing fieldName is treated as a column with empty values.
+func applyToBlockResultGeneric(br *blockResult, bm *bitmap, fieldName string, matchFunc func(v string) bool) {
+	c := br.getColumnByNameIfExists(getCanonicalColumnName(fieldName))
+	if c == nil {
+		if !matchFunc("") {
+			bm.resetBits()
+		}
+		return
+	}
+	if c.isConst {
+		if !matchFunc(c.encodedValues[0]) {
+			bm.resetBits()
+		}
+		return
+	}
+	if c.valueType == valueTypeDict {
+		// Verify dict values only once.
+		bb := bbPool.Get()
+		for _, v := range c.dictValues {
+			ok := byte(0)
+			if matchFunc(v) {
+				ok = 1
+			}
+			bb.B = append(bb.B, ok)
+		}
+		encodedValues := c.encodedValues
+		bm.forEachSetBit(func(idx int) bool {
+			n := encodedValues[idx][0]
+			return bb.B[n] == 1
+		})
+		bbPool.Put(bb)
+		return
+	}
+
+	values := c.getValues(br)
+	bm.forEachSetBit(func(idx int) bool {
+		return matchFunc(values[idx])
+	})
 }
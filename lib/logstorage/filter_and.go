@@ -31,6 +31,23 @@ func (fa *filterAnd) String() string {
 	return strings.Join(a, " ")
 }
 
+func (fa *filterAnd) updateNeededFields(neededFields fieldsSet) {
+	for _, f := range fa.filters {
+		f.updateNeededFields(neededFields)
+	}
+}
+
+func (fa *filterAnd) applyToBlockResult(br *blockResult, bm *bitmap) {
+	for _, f := range fa.filters {
+		f.applyToBlockResult(br, bm)
+		if bm.isZero() {
+			// Shortcut - there is no need in applying the remaining filters,
+			// since the result will be zero anyway.
+			return
+		}
+	}
+}
+
 func (fa *filterAnd) apply(bs *blockSearch, bm *bitmap) {
 	if !fa.matchMessageBloomFilter(bs) {
 		// Fast path - fa doesn't match _msg bloom filter.
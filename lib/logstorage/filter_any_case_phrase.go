@@ -148,3 +148,14 @@ func isASCIILowercase(s string) bool {
 	}
 	return true
 }
+
+func (fp *filterAnyCasePhrase) updateNeededFields(neededFields fieldsSet) {
+	neededFields.add(getCanonicalColumnName(fp.fieldName))
+}
+
+func (fp *filterAnyCasePhrase) applyToBlockResult(br *blockResult, bm *bitmap) {
+	phraseLowercase := fp.getPhraseLowercase()
+	applyToBlockResultGeneric(br, bm, fp.fieldName, func(v string) bool {
+		return matchAnyCasePhrase(v, phraseLowercase)
+	})
+}
@@ -139,3 +139,14 @@ func matchAnyCasePrefix(s, prefixLowercase string) bool {
 
 	return ok
 }
+
+func (fp *filterAnyCasePrefix) updateNeededFields(neededFields fieldsSet) {
+	neededFields.add(getCanonicalColumnName(fp.fieldName))
+}
+
+func (fp *filterAnyCasePrefix) applyToBlockResult(br *blockResult, bm *bitmap) {
+	prefixLowercase := fp.getPrefixLowercase()
+	applyToBlockResultGeneric(br, bm, fp.fieldName, func(v string) bool {
+		return matchAnyCasePrefix(v, prefixLowercase)
+	})
+}
@@ -197,3 +197,14 @@ func matchBinaryValue(bs *blockSearch, ch *columnHeader, bm *bitmap, binValue []
 		return v == string(binValue)
 	})
 }
+
+func (fe *filterExact) updateNeededFields(neededFields fieldsSet) {
+	neededFields.add(getCanonicalColumnName(fe.fieldName))
+}
+
+func (fe *filterExact) applyToBlockResult(br *blockResult, bm *bitmap) {
+	value := fe.value
+	applyToBlockResultGeneric(br, bm, fe.fieldName, func(v string) bool {
+		return v == value
+	})
+}
@@ -227,3 +227,14 @@ func matchMinMaxExactPrefix(ch *columnHeader, bm *bitmap, prefix string, tokens
 func matchExactPrefix(s, prefix string) bool {
 	return strings.HasPrefix(s, prefix)
 }
+
+func (fep *filterExactPrefix) updateNeededFields(neededFields fieldsSet) {
+	neededFields.add(getCanonicalColumnName(fep.fieldName))
+}
+
+func (fep *filterExactPrefix) applyToBlockResult(br *blockResult, bm *bitmap) {
+	prefix := fep.prefix
+	applyToBlockResultGeneric(br, bm, fep.fieldName, func(v string) bool {
+		return matchExactPrefix(v, prefix)
+	})
+}
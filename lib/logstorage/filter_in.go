@@ -352,3 +352,20 @@ func matchValuesDictByAnyValue(bs *blockSearch, ch *columnHeader, bm *bitmap, va
 	matchEncodedValuesDict(bs, ch, bm, bb.B)
 	bbPool.Put(bb)
 }
+
+func (fi *filterIn) updateNeededFields(neededFields fieldsSet) {
+	neededFields.add(getCanonicalColumnName(fi.fieldName))
+}
+
+func (fi *filterIn) applyToBlockResult(br *blockResult, bm *bitmap) {
+	if len(fi.values) == 0 {
+		bm.resetBits()
+		return
+	}
+
+	stringValues := fi.getStringValues()
+	applyToBlockResultGeneric(br, bm, fi.fieldName, func(v string) bool {
+		_, ok := stringValues[v]
+		return ok
+	})
+}
@@ -113,3 +113,21 @@ func matchIPv4ByRange(bs *blockSearch, ch *columnHeader, bm *bitmap, minValue, m
 		return n >= minValue && n <= maxValue
 	})
 }
+
+func (fr *filterIPv4Range) updateNeededFields(neededFields fieldsSet) {
+	neededFields.add(getCanonicalColumnName(fr.fieldName))
+}
+
+func (fr *filterIPv4Range) applyToBlockResult(br *blockResult, bm *bitmap) {
+	minValue := fr.minValue
+	maxValue := fr.maxValue
+
+	if minValue > maxValue {
+		bm.resetBits()
+		return
+	}
+
+	applyToBlockResultGeneric(br, bm, fr.fieldName, func(v string) bool {
+		return matchIPv4Range(v, minValue, maxValue)
+	})
+}
@@ -200,3 +200,21 @@ func matchMinMaxValueLen(ch *columnHeader, minLen, maxLen uint64) bool {
 	s = bytesutil.ToUnsafeString(bb.B)
 	return minLen <= uint64(len(s))
 }
+
+func (fr *filterLenRange) updateNeededFields(neededFields fieldsSet) {
+	neededFields.add(getCanonicalColumnName(fr.fieldName))
+}
+
+func (fr *filterLenRange) applyToBlockResult(br *blockResult, bm *bitmap) {
+	minLen := fr.minLen
+	maxLen := fr.maxLen
+
+	if minLen > maxLen {
+		bm.resetBits()
+		return
+	}
+
+	applyToBlockResultGeneric(br, bm, fr.fieldName, func(v string) bool {
+		return matchLenRange(v, minLen, maxLen)
+	})
+}
@@ -8,6 +8,14 @@ func (fn *filterNoop) String() string {
 	return ""
 }
 
+func (fn *filterNoop) updateNeededFields(_ fieldsSet) {
+	// nothing to do
+}
+
+func (fn *filterNoop) applyToBlockResult(_ *blockResult, _ *bitmap) {
+	// nothing to do
+}
+
 func (fn *filterNoop) apply(_ *blockSearch, _ *bitmap) {
 	// nothing to do
 }
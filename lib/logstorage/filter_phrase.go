@@ -317,3 +317,14 @@ func toTimestampISO8601StringExt(bs *blockSearch, bb *bytesutil.ByteBuffer, v st
 	bb.B = toTimestampISO8601String(bb.B[:0], v)
 	return bytesutil.ToUnsafeString(bb.B)
 }
+
+func (fp *filterPhrase) updateNeededFields(neededFields fieldsSet) {
+	neededFields.add(getCanonicalColumnName(fp.fieldName))
+}
+
+func (fp *filterPhrase) applyToBlockResult(br *blockResult, bm *bitmap) {
+	phrase := fp.phrase
+	applyToBlockResultGeneric(br, bm, fp.fieldName, func(v string) bool {
+		return matchPhrase(v, phrase)
+	})
+}
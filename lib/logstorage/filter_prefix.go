@@ -355,3 +355,14 @@ func toUint64String(bs *blockSearch, bb *bytesutil.ByteBuffer, v string) string
 	bb.B = marshalUint64(bb.B[:0], n)
 	return bytesutil.ToUnsafeString(bb.B)
 }
+
+func (fp *filterPrefix) updateNeededFields(neededFields fieldsSet) {
+	neededFields.add(getCanonicalColumnName(fp.fieldName))
+}
+
+func (fp *filterPrefix) applyToBlockResult(br *blockResult, bm *bitmap) {
+	prefix := fp.prefix
+	applyToBlockResultGeneric(br, bm, fp.fieldName, func(v string) bool {
+		return matchPrefix(v, prefix)
+	})
+}
@@ -201,3 +201,21 @@ func toUint64Clamp(f float64) uint64 {
 	}
 	return uint64(f)
 }
+
+func (fr *filterRange) updateNeededFields(neededFields fieldsSet) {
+	neededFields.add(getCanonicalColumnName(fr.fieldName))
+}
+
+func (fr *filterRange) applyToBlockResult(br *blockResult, bm *bitmap) {
+	minValue := fr.minValue
+	maxValue := fr.maxValue
+
+	if minValue > maxValue {
+		bm.resetBits()
+		return
+	}
+
+	applyToBlockResultGeneric(br, bm, fr.fieldName, func(v string) bool {
+		return matchRange(v, minValue, maxValue)
+	})
+}
@@ -145,3 +145,14 @@ func matchUint64ByRegexp(bs *blockSearch, ch *columnHeader, bm *bitmap, re *rege
 	})
 	bbPool.Put(bb)
 }
+
+func (fr *filterRegexp) updateNeededFields(neededFields fieldsSet) {
+	neededFields.add(getCanonicalColumnName(fr.fieldName))
+}
+
+func (fr *filterRegexp) applyToBlockResult(br *blockResult, bm *bitmap) {
+	re := fr.re
+	applyToBlockResultGeneric(br, bm, fr.fieldName, func(v string) bool {
+		return re.MatchString(v)
+	})
+}
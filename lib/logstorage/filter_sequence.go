@@ -232,3 +232,18 @@ func matchSequence(s string, phrases []string) bool {
 	}
 	return true
 }
+
+func (fs *filterSequence) updateNeededFields(neededFields fieldsSet) {
+	neededFields.add(getCanonicalColumnName(fs.fieldName))
+}
+
+func (fs *filterSequence) applyToBlockResult(br *blockResult, bm *bitmap) {
+	phrases := fs.getNonEmptyPhrases()
+	if len(phrases) == 0 {
+		return
+	}
+
+	applyToBlockResultGeneric(br, bm, fs.fieldName, func(v string) bool {
+		return matchSequence(v, phrases)
+	})
+}
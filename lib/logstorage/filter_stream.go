@@ -41,6 +41,26 @@ func (fs *filterStream) initStreamIDs() {
 	fs.streamIDs = m
 }
 
+func (fs *filterStream) updateNeededFields(neededFields fieldsSet) {
+	neededFields.add("_stream")
+}
+
+func (fs *filterStream) applyToBlockResult(br *blockResult, bm *bitmap) {
+	if fs.f.isEmpty() {
+		return
+	}
+
+	var fields []Field
+	applyToBlockResultGeneric(br, bm, "_stream", func(v string) bool {
+		var err error
+		fields, err = parseStreamFields(fields[:0], v)
+		if err != nil {
+			return false
+		}
+		return fs.f.matchStreamFields(fields)
+	})
+}
+
 func (fs *filterStream) apply(bs *blockSearch, bm *bitmap) {
 	if fs.f.isEmpty() {
 		return
@@ -187,3 +187,21 @@ func matchUint64ByStringRange(bs *blockSearch, ch *columnHeader, bm *bitmap, min
 func matchStringRange(s, minValue, maxValue string) bool {
 	return s >= minValue && s < maxValue
 }
+
+func (fr *filterStringRange) updateNeededFields(neededFields fieldsSet) {
+	neededFields.add(getCanonicalColumnName(fr.fieldName))
+}
+
+func (fr *filterStringRange) applyToBlockResult(br *blockResult, bm *bitmap) {
+	minValue := fr.minValue
+	maxValue := fr.maxValue
+
+	if minValue > maxValue {
+		bm.resetBits()
+		return
+	}
+
+	applyToBlockResultGeneric(br, bm, fr.fieldName, func(v string) bool {
+		return matchStringRange(v, minValue, maxValue)
+	})
+}
@@ -18,6 +18,50 @@ func (ft *filterTime) String() string {
 	return "_time:" + ft.stringRepr
 }
 
+func (ft *filterTime) updateNeededFields(neededFields fieldsSet) {
+	neededFields.add("_time")
+}
+
+func (ft *filterTime) applyToBlockResult(br *blockResult, bm *bitmap) {
+	minTimestamp := ft.minTimestamp
+	maxTimestamp := ft.maxTimestamp
+
+	if minTimestamp > maxTimestamp {
+		bm.resetBits()
+		return
+	}
+
+	c := br.getColumnByNameIfExists("_time")
+	if c == nil {
+		bm.resetBits()
+		return
+	}
+	if c.isTime {
+		timestamps := br.timestamps
+		bm.forEachSetBit(func(idx int) bool {
+			ts := timestamps[idx]
+			return ts >= minTimestamp && ts <= maxTimestamp
+		})
+		return
+	}
+
+	applyToBlockResultGeneric(br, bm, "_time", func(v string) bool {
+		return ft.matchTimestampString(v)
+	})
+}
+
+// matchTimestampString returns true if v contains a timestamp in RFC3339 or ISO8601 format, which matches ft.
+func (ft *filterTime) matchTimestampString(v string) bool {
+	ts, ok := tryParseTimestampRFC3339Nano(v)
+	if !ok {
+		ts, ok = tryParseTimestampISO8601(v)
+		if !ok {
+			return false
+		}
+	}
+	return ts >= ft.minTimestamp && ts <= ft.maxTimestamp
+}
+
 func (ft *filterTime) apply(bs *blockSearch, bm *bitmap) {
 	minTimestamp := ft.minTimestamp
 	maxTimestamp := ft.maxTimestamp
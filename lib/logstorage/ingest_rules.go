@@ -0,0 +1,345 @@
+package logstorage
+
+import (
+	"fmt"
+	"regexp"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+)
+
+// IngestRuleConfig is a rule for transforming log entries at data ingestion stage.
+//
+// See https://docs.victoriametrics.com/VictoriaLogs/data-ingestion/#ingestion-rules
+type IngestRuleConfig struct {
+	// If is an optional LogsQL filter. The rule is applied only to log entries matching the filter.
+	//
+	// The filter is mandatory for `drop` and `keep` actions.
+	If string `yaml:"if,omitempty"`
+
+	// Action is the action to perform for the matching log entries.
+	//
+	// Supported actions: drop, keep, rename, set, unpack_json, unpack_logfmt, mask and set_tenant.
+	Action string `yaml:"action"`
+
+	// Field is the field to process by rename, set, unpack_json, unpack_logfmt and mask actions.
+	//
+	// unpack_json, unpack_logfmt and mask actions process _msg field if Field is empty.
+	Field string `yaml:"field,omitempty"`
+
+	// TargetField is the new field name for rename action.
+	TargetField string `yaml:"target_field,omitempty"`
+
+	// Value is the field value for set action.
+	Value string `yaml:"value,omitempty"`
+
+	// Regex is the regular expression for mask action.
+	Regex string `yaml:"regex,omitempty"`
+
+	// Replacement is the replacement for Regex matches at mask action.
+	//
+	// By default the matches are replaced with `***`.
+	Replacement *string `yaml:"replacement,omitempty"`
+
+	// Tenant is the tenant in the form AccountID:ProjectID for set_tenant action.
+	Tenant string `yaml:"tenant,omitempty"`
+}
+
+// IngestRules contains parsed rules for transforming log entries at data ingestion stage.
+//
+// IngestRules can be obtained via ParseIngestRules.
+type IngestRules struct {
+	rules []*ingestRule
+}
+
+type ingestRule struct {
+	// f is an optional filter for log entries the rule applies to.
+	f filter
+
+	// needStream is set to true if f needs the _stream field.
+	needStream bool
+
+	action      string
+	field       string
+	targetField string
+	value       string
+	re          *regexp.Regexp
+	replacement string
+	tenantID    TenantID
+}
+
+// ParseIngestRules parses ingestion rules from YAML data.
+//
+// The data must contain a list of IngestRuleConfig items.
+func ParseIngestRules(data []byte) (*IngestRules, error) {
+	var rcs []IngestRuleConfig
+	if err := yaml.UnmarshalStrict(data, &rcs); err != nil {
+		return nil, err
+	}
+	rules := make([]*ingestRule, len(rcs))
+	for i := range rcs {
+		rule, err := newIngestRule(&rcs[i])
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse rule #%d: %w", i+1, err)
+		}
+		rules[i] = rule
+	}
+	return &IngestRules{
+		rules: rules,
+	}, nil
+}
+
+func newIngestRule(rc *IngestRuleConfig) (*ingestRule, error) {
+	rule := &ingestRule{
+		action:      rc.Action,
+		field:       getCanonicalColumnName(rc.Field),
+		targetField: getCanonicalColumnName(rc.TargetField),
+		value:       rc.Value,
+	}
+
+	if rc.If != "" {
+		q, err := ParseQuery(rc.If)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse `if` filter: %w", err)
+		}
+		if len(q.pipes) > 0 {
+			return nil, fmt.Errorf("`if` filter cannot contain pipes; got [%s]", rc.If)
+		}
+		rule.f = q.f
+
+		neededFields := newFieldsSet()
+		rule.f.updateNeededFields(neededFields)
+		rule.needStream = neededFields.contains("_stream")
+	}
+
+	switch rc.Action {
+	case "drop", "keep":
+		if rule.f == nil {
+			return nil, fmt.Errorf("missing `if` filter for `action: %s`", rc.Action)
+		}
+	case "rename":
+		if rc.Field == "" || rc.TargetField == "" {
+			return nil, fmt.Errorf("`action: rename` requires non-empty `field` and `target_field`")
+		}
+	case "set":
+		if rc.Field == "" {
+			return nil, fmt.Errorf("`action: set` requires non-empty `field`")
+		}
+	case "unpack_json", "unpack_logfmt":
+	case "mask":
+		if rc.Regex == "" {
+			return nil, fmt.Errorf("`action: mask` requires non-empty `regex`")
+		}
+		re, err := regexp.Compile(rc.Regex)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse `regex` for `action: mask`: %w", err)
+		}
+		rule.re = re
+		rule.replacement = "***"
+		if rc.Replacement != nil {
+			rule.replacement = *rc.Replacement
+		}
+	case "set_tenant":
+		if rc.Tenant == "" {
+			return nil, fmt.Errorf("`action: set_tenant` requires non-empty `tenant`")
+		}
+		tenantID, err := GetTenantIDFromString(rc.Tenant)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse `tenant` for `action: set_tenant`: %w", err)
+		}
+		rule.tenantID = tenantID
+	case "":
+		return nil, fmt.Errorf("missing `action`")
+	default:
+		return nil, fmt.Errorf("unsupported `action: %s`; supported actions: drop, keep, rename, set, unpack_json, unpack_logfmt, mask, set_tenant", rc.Action)
+	}
+
+	return rule, nil
+}
+
+// IngestRulesContext holds the state for applying IngestRules to log entries.
+//
+// It cannot be used from concurrently running goroutines.
+type IngestRulesContext struct {
+	// TenantID is the tenant for the log entry processed by the last IngestRules.Apply call.
+	TenantID TenantID
+
+	// Fields contains fields for the log entry processed by the last IngestRules.Apply call.
+	//
+	// Fields are valid until the next IngestRules.Apply call.
+	Fields []Field
+
+	// buf holds the backing data for the modified Fields.
+	buf []byte
+
+	// uctx is used for unpack_logfmt action.
+	uctx fieldsUnpackerContext
+
+	// jp is used for unpack_json action.
+	jp JSONParser
+
+	// rcs and br are used for matching log entries against rule filters.
+	rcs []resultColumn
+	br  blockResult
+}
+
+// Apply applies irs to the log entry with the given tenantID, timestamp and fields.
+//
+// streamFields must contain the names of log stream fields. They are used for matching `_stream:{...}` filters.
+//
+// false is returned if the log entry must be dropped. Otherwise the transformed log entry is stored in ctx.TenantID and ctx.Fields.
+func (irs *IngestRules) Apply(ctx *IngestRulesContext, tenantID TenantID, timestamp int64, fields []Field, streamFields []string) bool {
+	ctx.buf = ctx.buf[:0]
+	ctx.TenantID = tenantID
+	ctx.Fields = append(ctx.Fields[:0], fields...)
+
+	for _, rule := range irs.rules {
+		if rule.f != nil && !ctx.matchFilter(rule, timestamp, streamFields) {
+			if rule.action == "keep" {
+				return false
+			}
+			continue
+		}
+
+		switch rule.action {
+		case "drop":
+			return false
+		case "keep":
+			// Nothing to do - the log entry matches the filter.
+		case "rename":
+			if v, ok := ctx.getFieldValue(rule.field); ok {
+				ctx.deleteField(rule.field)
+				ctx.setField(rule.targetField, v)
+			}
+		case "set":
+			ctx.setField(rule.field, rule.value)
+		case "unpack_json":
+			v, _ := ctx.getFieldValue(rule.field)
+			if err := ctx.jp.ParseLogMessage(bytesutil.ToUnsafeBytes(v)); err != nil {
+				// Leave the log entry as is if the field doesn't contain JSON object.
+				continue
+			}
+			for _, f := range ctx.jp.Fields {
+				ctx.setField(f.Name, f.Value)
+			}
+		case "unpack_logfmt":
+			v, _ := ctx.getFieldValue(rule.field)
+			ctx.uctx.reset()
+			unpackLogfmt(&ctx.uctx, v)
+			for _, f := range ctx.uctx.fields {
+				ctx.setField(f.Name, f.Value)
+			}
+		case "mask":
+			if v, ok := ctx.getFieldValue(rule.field); ok {
+				ctx.setField(rule.field, rule.re.ReplaceAllString(v, rule.replacement))
+			}
+		case "set_tenant":
+			ctx.TenantID = rule.tenantID
+		default:
+			logger.Panicf("BUG: unexpected action: %q", rule.action)
+		}
+	}
+	return true
+}
+
+func (ctx *IngestRulesContext) getFieldValue(name string) (string, bool) {
+	for _, f := range ctx.Fields {
+		if getCanonicalColumnName(f.Name) == name {
+			return f.Value, true
+		}
+	}
+	return "", false
+}
+
+// setField sets the field with the given name to the given value.
+//
+// name and value are copied to ctx.buf, so they can be changed after returning from the function.
+func (ctx *IngestRulesContext) setField(name, value string) {
+	buf := ctx.buf
+	bufLen := len(buf)
+	buf = append(buf, value...)
+	value = bytesutil.ToUnsafeString(buf[bufLen:])
+
+	fields := ctx.Fields
+	for i := range fields {
+		f := &fields[i]
+		if getCanonicalColumnName(f.Name) == name {
+			ctx.buf = buf
+			f.Value = value
+			return
+		}
+	}
+
+	bufLen = len(buf)
+	buf = append(buf, name...)
+	name = bytesutil.ToUnsafeString(buf[bufLen:])
+	ctx.buf = buf
+
+	ctx.Fields = append(fields, Field{
+		Name:  name,
+		Value: value,
+	})
+}
+
+func (ctx *IngestRulesContext) deleteField(name string) {
+	fields := ctx.Fields[:0]
+	for _, f := range ctx.Fields {
+		if getCanonicalColumnName(f.Name) != name {
+			fields = append(fields, f)
+		}
+	}
+	clear(ctx.Fields[len(fields):])
+	ctx.Fields = fields
+}
+
+// matchFilter returns true if the current log entry at ctx matches the filter for the given rule.
+func (ctx *IngestRulesContext) matchFilter(rule *ingestRule, timestamp int64, streamFields []string) bool {
+	rcs := ctx.rcs[:0]
+	for _, f := range ctx.Fields {
+		name := getCanonicalColumnName(f.Name)
+		if name == "_time" || name == "_stream" {
+			continue
+		}
+		if len(rcs) < cap(rcs) {
+			rcs = rcs[:len(rcs)+1]
+		} else {
+			rcs = append(rcs, resultColumn{})
+		}
+		rc := &rcs[len(rcs)-1]
+		rc.name = name
+		rc.values = append(rc.values[:0], f.Value)
+	}
+	ctx.rcs = rcs
+
+	br := &ctx.br
+	br.setResultColumns(rcs)
+	br.timestamps = append(br.timestamps[:0], timestamp)
+	br.addTimeColumn()
+
+	if rule.needStream {
+		st := GetStreamTags()
+		for _, f := range ctx.Fields {
+			for _, streamField := range streamFields {
+				if f.Name == streamField {
+					st.Add(f.Name, f.Value)
+					break
+				}
+			}
+		}
+		bb := bbPool.Get()
+		bb.B = st.marshalString(bb.B[:0])
+		PutStreamTags(st)
+		br.addConstColumn("_stream", bytesutil.ToUnsafeString(bb.B))
+		bbPool.Put(bb)
+	}
+
+	bm := getBitmap(1)
+	bm.setBits()
+	rule.f.applyToBlockResult(br, bm)
+	ok := !bm.isZero()
+	putBitmap(bm)
+
+	return ok
+}
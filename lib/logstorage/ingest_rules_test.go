@@ -0,0 +1,207 @@
+package logstorage
+
+import (
+	"testing"
+)
+
+func TestParseIngestRulesFailure(t *testing.T) {
+	f := func(data string) {
+		t.Helper()
+
+		irs, err := ParseIngestRules([]byte(data))
+		if err == nil {
+			t.Fatalf("expecting non-nil error")
+		}
+		if irs != nil {
+			t.Fatalf("expecting nil result")
+		}
+	}
+
+	// invalid yaml
+	f(`foo`)
+	f(`- action: drop
+  unknown_field: bar`)
+
+	// missing action
+	f(`- if: foo`)
+
+	// unsupported action
+	f(`- action: foobar`)
+
+	// invalid filter
+	f(`- if: 'foo:('
+  action: drop`)
+
+	// pipes in filter
+	f(`- if: 'foo | limit 10'
+  action: drop`)
+
+	// missing filter for drop and keep actions
+	f(`- action: drop`)
+	f(`- action: keep`)
+
+	// missing fields for rename action
+	f(`- action: rename
+  field: foo`)
+	f(`- action: rename
+  target_field: foo`)
+
+	// missing field for set action
+	f(`- action: set
+  value: foo`)
+
+	// missing or invalid regex for mask action
+	f(`- action: mask`)
+	f(`- action: mask
+  regex: '('`)
+
+	// invalid tenant for set_tenant action
+	f(`- action: set_tenant`)
+	f(`- action: set_tenant
+  tenant: foo:bar`)
+}
+
+func TestIngestRulesApply(t *testing.T) {
+	f := func(data string, fields []Field, resultExpected string, tenantIDExpected TenantID) {
+		t.Helper()
+
+		irs, err := ParseIngestRules([]byte(data))
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		var ctx IngestRulesContext
+		tenantID := TenantID{
+			AccountID: 1,
+			ProjectID: 2,
+		}
+		if !irs.Apply(&ctx, tenantID, 123, fields, []string{"app"}) {
+			if resultExpected != "" {
+				t.Fatalf("unexpected drop of the log entry; want %s", resultExpected)
+			}
+			return
+		}
+		rf := RowFormatter(ctx.Fields)
+		result := rf.String()
+		if result != resultExpected {
+			t.Fatalf("unexpected result\ngot\n%s\nwant\n%s", result, resultExpected)
+		}
+		if ctx.TenantID != tenantIDExpected {
+			t.Fatalf("unexpected tenantID; got %s; want %s", ctx.TenantID.String(), tenantIDExpected.String())
+		}
+	}
+
+	defaultTenantID := TenantID{
+		AccountID: 1,
+		ProjectID: 2,
+	}
+
+	// no rules
+	f(``, []Field{
+		{Name: "_msg", Value: "foo bar"},
+	}, `{"_msg":"foo bar"}`, defaultTenantID)
+
+	// drop matching entries
+	f(`- if: 'error'
+  action: drop`, []Field{
+		{Name: "_msg", Value: "some error"},
+	}, ``, defaultTenantID)
+	f(`- if: 'error'
+  action: drop`, []Field{
+		{Name: "_msg", Value: "some warning"},
+	}, `{"_msg":"some warning"}`, defaultTenantID)
+
+	// drop by stream filter
+	f(`- if: '_stream:{app="nginx"}'
+  action: drop`, []Field{
+		{Name: "app", Value: "nginx"},
+		{Name: "_msg", Value: "foo"},
+	}, ``, defaultTenantID)
+	f(`- if: '_stream:{app="nginx"}'
+  action: drop`, []Field{
+		{Name: "app", Value: "apache"},
+		{Name: "_msg", Value: "foo"},
+	}, `{"app":"apache","_msg":"foo"}`, defaultTenantID)
+
+	// keep matching entries
+	f(`- if: 'level:error'
+  action: keep`, []Field{
+		{Name: "level", Value: "info"},
+		{Name: "_msg", Value: "foo"},
+	}, ``, defaultTenantID)
+	f(`- if: 'level:error'
+  action: keep`, []Field{
+		{Name: "level", Value: "error"},
+		{Name: "_msg", Value: "foo"},
+	}, `{"level":"error","_msg":"foo"}`, defaultTenantID)
+
+	// rename field
+	f(`- action: rename
+  field: message
+  target_field: _msg`, []Field{
+		{Name: "message", Value: "foo"},
+		{Name: "_msg", Value: "bar"},
+		{Name: "x", Value: "y"},
+	}, `{"_msg":"foo","x":"y"}`, defaultTenantID)
+
+	// set constant field only for matching entries
+	f(`- if: 'app:exact(nginx)'
+  action: set
+  field: env
+  value: prod`, []Field{
+		{Name: "app", Value: "nginx"},
+	}, `{"app":"nginx","env":"prod"}`, defaultTenantID)
+	f(`- if: 'app:exact(nginx)'
+  action: set
+  field: env
+  value: prod`, []Field{
+		{Name: "app", Value: "apache"},
+	}, `{"app":"apache"}`, defaultTenantID)
+
+	// unpack json from _msg
+	f(`- action: unpack_json`, []Field{
+		{Name: "_msg", Value: `{"foo":"bar","_msg":"baz"}`},
+		{Name: "x", Value: "y"},
+	}, `{"_msg":"baz","x":"y","foo":"bar"}`, defaultTenantID)
+
+	// unpack json from invalid json
+	f(`- action: unpack_json`, []Field{
+		{Name: "_msg", Value: `foo bar`},
+	}, `{"_msg":"foo bar"}`, defaultTenantID)
+
+	// unpack logfmt from the given field
+	f(`- action: unpack_logfmt
+  field: data
+- if: 'level:error'
+  action: drop`, []Field{
+		{Name: "data", Value: `level=error msg="foo bar"`},
+	}, ``, defaultTenantID)
+	f(`- action: unpack_logfmt
+  field: data`, []Field{
+		{Name: "data", Value: `level=info msg="foo bar"`},
+	}, `{"data":"level=info msg=\"foo bar\"","level":"info","msg":"foo bar"}`, defaultTenantID)
+
+	// mask sensitive data
+	f(`- action: mask
+  regex: '\d{4}-\d{4}-\d{4}-\d{4}'`, []Field{
+		{Name: "_msg", Value: "card 1234-5678-1234-5678 is used"},
+	}, `{"_msg":"card *** is used"}`, defaultTenantID)
+	f(`- action: mask
+  field: email
+  regex: '^[^@]+'
+  replacement: 'user'`, []Field{
+		{Name: "email", Value: "john@example.com"},
+	}, `{"email":"user@example.com"}`, defaultTenantID)
+
+	// route to tenant
+	f(`- if: '_stream:{app="billing"}'
+  action: set_tenant
+  tenant: '10:20'`, []Field{
+		{Name: "app", Value: "billing"},
+	}, `{"app":"billing"}`, TenantID{AccountID: 10, ProjectID: 20})
+	f(`- if: '_stream:{app="billing"}'
+  action: set_tenant
+  tenant: '10:20'`, []Field{
+		{Name: "app", Value: "auth"},
+	}, `{"app":"auth"}`, defaultTenantID)
+}
@@ -148,7 +148,7 @@ func TestInmemoryPartInitFromBlockStreamReaders(t *testing.T) {
 		mpDst := getInmemoryPart()
 		bsw := getBlockStreamWriter()
 		bsw.MustInitForInmemoryPart(mpDst)
-		mustMergeBlockStreams(&mpDst.ph, bsw, bsrs, nil)
+		mustMergeBlockStreams(&mpDst.ph, bsw, bsrs, nil, nil, nil)
 		putBlockStreamWriter(bsw)
 
 		// Check mpDst.ph stats
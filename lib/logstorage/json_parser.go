@@ -1,4 +1,4 @@
-package logjson
+package logstorage
 
 import (
 	"fmt"
@@ -6,21 +6,20 @@ import (
 
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
-	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logstorage"
 	"github.com/valyala/fastjson"
 )
 
-// Parser parses a single JSON log message into Fields.
+// JSONParser parses a single JSON log message into Fields.
 //
 // See https://docs.victoriametrics.com/VictoriaLogs/keyConcepts.html#data-model
 //
-// Use GetParser() for obtaining the parser.
-type Parser struct {
+// Use GetJSONParser() for obtaining the parser.
+type JSONParser struct {
 	// Fields contains the parsed JSON line after Parse() call
 	//
 	// The Fields are valid until the next call to ParseLogMessage()
-	// or until the parser is returned to the pool with PutParser() call.
-	Fields []logstorage.Field
+	// or until the parser is returned to the pool with PutJSONParser() call.
+	Fields []Field
 
 	// p is used for fast JSON parsing
 	p fastjson.Parser
@@ -33,7 +32,7 @@ type Parser struct {
 	prefixBuf []byte
 }
 
-func (p *Parser) reset() {
+func (p *JSONParser) reset() {
 	fields := p.Fields
 	for i := range fields {
 		lf := &fields[i]
@@ -46,31 +45,31 @@ func (p *Parser) reset() {
 	p.prefixBuf = p.prefixBuf[:0]
 }
 
-// GetParser returns Parser ready to parse JSON lines.
+// GetJSONParser returns JSONParser ready to parse JSON lines.
 //
-// Return the parser to the pool when it is no longer needed by calling PutParser().
-func GetParser() *Parser {
-	v := parserPool.Get()
+// Return the parser to the pool when it is no longer needed by calling PutJSONParser().
+func GetJSONParser() *JSONParser {
+	v := jsonParserPool.Get()
 	if v == nil {
-		return &Parser{}
+		return &JSONParser{}
 	}
-	return v.(*Parser)
+	return v.(*JSONParser)
 }
 
-// PutParser returns the parser to the pool.
+// PutJSONParser returns the parser to the pool.
 //
 // The parser cannot be used after returning to the pool.
-func PutParser(p *Parser) {
+func PutJSONParser(p *JSONParser) {
 	p.reset()
-	parserPool.Put(p)
+	jsonParserPool.Put(p)
 }
 
-var parserPool sync.Pool
+var jsonParserPool sync.Pool
 
 // ParseLogMessage parses the given JSON log message msg into p.Fields.
 //
-// The p.Fields remains valid until the next call to ParseLogMessage() or PutParser().
-func (p *Parser) ParseLogMessage(msg []byte) error {
+// The p.Fields remains valid until the next call to ParseLogMessage() or PutJSONParser().
+func (p *JSONParser) ParseLogMessage(msg []byte) error {
 	s := bytesutil.ToUnsafeString(msg)
 	v, err := p.p.Parse(s)
 	if err != nil {
@@ -85,7 +84,7 @@ func (p *Parser) ParseLogMessage(msg []byte) error {
 }
 
 // RenameField renames field with the oldName to newName in p.Fields
-func (p *Parser) RenameField(oldName, newName string) {
+func (p *JSONParser) RenameField(oldName, newName string) {
 	if oldName == "" {
 		return
 	}
@@ -99,7 +98,7 @@ func (p *Parser) RenameField(oldName, newName string) {
 	}
 }
 
-func appendLogFields(dst []logstorage.Field, dstBuf, prefixBuf []byte, v *fastjson.Value) ([]logstorage.Field, []byte, []byte) {
+func appendLogFields(dst []Field, dstBuf, prefixBuf []byte, v *fastjson.Value) ([]Field, []byte, []byte) {
 	o := v.GetObject()
 	o.Visit(func(k []byte, v *fastjson.Value) {
 		t := v.Type()
@@ -133,13 +132,13 @@ func appendLogFields(dst []logstorage.Field, dstBuf, prefixBuf []byte, v *fastjs
 	return dst, dstBuf, prefixBuf
 }
 
-func appendLogField(dst []logstorage.Field, dstBuf, prefixBuf, k, value []byte) ([]logstorage.Field, []byte) {
+func appendLogField(dst []Field, dstBuf, prefixBuf, k, value []byte) ([]Field, []byte) {
 	dstBufLen := len(dstBuf)
 	dstBuf = append(dstBuf, prefixBuf...)
 	dstBuf = append(dstBuf, k...)
 	name := dstBuf[dstBufLen:]
 
-	dst = append(dst, logstorage.Field{
+	dst = append(dst, Field{
 		Name:  bytesutil.ToUnsafeString(name),
 		Value: bytesutil.ToUnsafeString(value),
 	})
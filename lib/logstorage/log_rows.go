@@ -1,10 +1,12 @@
 package logstorage
 
 import (
+	"fmt"
 	"sort"
 	"sync"
 
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/encoding"
 )
 
 // LogRows holds a set of rows needed for Storage.MustAddRows
@@ -233,6 +235,84 @@ func (lr *LogRows) GetRowString(idx int) string {
 	return rf.String()
 }
 
+// GetStreamHash returns the hash for the log stream of the row with the given idx.
+//
+// The hash can be used for spreading log streams among storage nodes.
+func (lr *LogRows) GetStreamHash(idx int) uint64 {
+	return lr.streamIDs[idx].id.lo
+}
+
+// MarshalRow appends the marshaled row with the given idx to dst and returns the result.
+//
+// The marshaled rows can be added to LogRows via AddMarshaledRows().
+func (lr *LogRows) MarshalRow(dst []byte, idx int) []byte {
+	dst = lr.streamIDs[idx].marshal(dst)
+	dst = encoding.MarshalBytes(dst, lr.streamTagsCanonicals[idx])
+	dst = encoding.MarshalVarInt64(dst, lr.timestamps[idx])
+
+	fields := lr.rows[idx]
+	dst = encoding.MarshalVarUint64(dst, uint64(len(fields)))
+	for _, f := range fields {
+		dst = encoding.MarshalBytes(dst, bytesutil.ToUnsafeBytes(f.Name))
+		dst = encoding.MarshalBytes(dst, bytesutil.ToUnsafeBytes(f.Value))
+	}
+	return dst
+}
+
+// AddMarshaledRows adds rows marshaled via MarshalRow() from src to lr.
+func (lr *LogRows) AddMarshaledRows(src []byte) error {
+	var sid streamID
+	var fields []Field
+	for len(src) > 0 {
+		tail, err := sid.unmarshal(src)
+		if err != nil {
+			return fmt.Errorf("cannot unmarshal streamID: %w", err)
+		}
+		src = tail
+
+		streamTagsCanonical, n := encoding.UnmarshalBytes(src)
+		if n <= 0 {
+			return fmt.Errorf("cannot unmarshal stream tags")
+		}
+		src = src[n:]
+
+		timestamp, n := encoding.UnmarshalVarInt64(src)
+		if n <= 0 {
+			return fmt.Errorf("cannot unmarshal timestamp")
+		}
+		src = src[n:]
+
+		fieldsLen, n := encoding.UnmarshalVarUint64(src)
+		if n <= 0 {
+			return fmt.Errorf("cannot unmarshal the number of fields")
+		}
+		src = src[n:]
+
+		fields = fields[:0]
+		for i := uint64(0); i < fieldsLen; i++ {
+			name, n := encoding.UnmarshalBytes(src)
+			if n <= 0 {
+				return fmt.Errorf("cannot unmarshal field name")
+			}
+			src = src[n:]
+
+			value, n := encoding.UnmarshalBytes(src)
+			if n <= 0 {
+				return fmt.Errorf("cannot unmarshal value for field %q", name)
+			}
+			src = src[n:]
+
+			fields = append(fields, Field{
+				Name:  bytesutil.ToUnsafeString(name),
+				Value: bytesutil.ToUnsafeString(value),
+			})
+		}
+
+		lr.mustAddInternal(sid, timestamp, fields, streamTagsCanonical)
+	}
+	return nil
+}
+
 // GetLogRows returns LogRows from the pool for the given streamFields.
 //
 // streamFields is a set of field names, which must be associated with the stream.
@@ -0,0 +1,176 @@
+package logstorage
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/encoding"
+)
+
+// CanExportNative returns true if q can be used for exporting logs in native format via MarshalNativeBlock.
+//
+// Native export is possible only for queries with pipes, which process every log entry independently of other log entries.
+func (q *Query) CanExportNative() bool {
+	for _, p := range q.pipes {
+		if !isRowLocalPipe(p) {
+			return false
+		}
+	}
+	return true
+}
+
+// MarshalNativeBlock appends native representation of the block with the given columns to dst and returns the result.
+//
+// columns must contain `_time` and `_stream` columns, since they are needed for restoring the original log entries.
+// The marshaled block can be added to LogRows via AddNativeBlock.
+func MarshalNativeBlock(dst []byte, columns []BlockColumn) ([]byte, error) {
+	timeIdx := -1
+	streamIdx := -1
+	for i, c := range columns {
+		switch c.Name {
+		case "_time":
+			timeIdx = i
+		case "_stream":
+			streamIdx = i
+		}
+	}
+	if timeIdx < 0 {
+		return dst, fmt.Errorf("missing _time field")
+	}
+	if streamIdx < 0 {
+		return dst, fmt.Errorf("missing _stream field")
+	}
+
+	timeValues := columns[timeIdx].Values
+	rowTimestamps := make([]int64, len(timeValues))
+	for i, v := range timeValues {
+		timestamp, ok := tryParseTimestampRFC3339Nano(v)
+		if !ok {
+			return dst, fmt.Errorf("cannot parse _time field value %q", v)
+		}
+		rowTimestamps[i] = timestamp
+	}
+
+	st := GetStreamTags()
+	defer PutStreamTags(st)
+
+	var streamTagsCanonical []byte
+	var data []byte
+	var cs []BlockColumn
+	streamValues := columns[streamIdx].Values
+	for start := 0; start < len(streamValues); {
+		// Marshal the run of rows with the same log stream.
+		streamValue := streamValues[start]
+		end := start + 1
+		for end < len(streamValues) && streamValues[end] == streamValue {
+			end++
+		}
+
+		if err := parseStreamTagsString(st, streamValue); err != nil {
+			return dst, fmt.Errorf("cannot parse _stream field value %q: %w", streamValue, err)
+		}
+		streamTagsCanonical = st.MarshalCanonical(streamTagsCanonical[:0])
+
+		cs = cs[:0]
+		for i, c := range columns {
+			if i == timeIdx || i == streamIdx {
+				continue
+			}
+			cs = append(cs, BlockColumn{
+				Name:   c.Name,
+				Values: c.Values[start:end],
+			})
+		}
+		data = MarshalBlockColumns(data[:0], rowTimestamps[start:end], cs)
+
+		dst = encoding.MarshalBytes(dst, streamTagsCanonical)
+		dst = encoding.MarshalBytes(dst, data)
+
+		start = end
+	}
+	return dst, nil
+}
+
+// AddNativeBlock adds log entries from src marshaled with MarshalNativeBlock to lr for the given tenantID.
+func (lr *LogRows) AddNativeBlock(tenantID TenantID, src []byte) error {
+	var timestamps []int64
+	var columns []BlockColumn
+	var fields []Field
+	for len(src) > 0 {
+		streamTagsCanonical, n := encoding.UnmarshalBytes(src)
+		if n <= 0 {
+			return fmt.Errorf("cannot unmarshal stream tags")
+		}
+		src = src[n:]
+
+		data, n := encoding.UnmarshalBytes(src)
+		if n <= 0 {
+			return fmt.Errorf("cannot unmarshal block columns")
+		}
+		src = src[n:]
+
+		var err error
+		timestamps, columns, err = UnmarshalBlockColumns(timestamps[:0], columns[:0], data)
+		if err != nil {
+			return fmt.Errorf("cannot unmarshal block columns: %w", err)
+		}
+
+		var sid streamID
+		sid.tenantID = tenantID
+		sid.id = hash128(streamTagsCanonical)
+
+		for i, timestamp := range timestamps {
+			fields = fields[:0]
+			for _, c := range columns {
+				fields = append(fields, Field{
+					Name:  c.Name,
+					Value: c.Values[i],
+				})
+			}
+			lr.mustAddInternal(sid, timestamp, fields, streamTagsCanonical)
+		}
+	}
+	return nil
+}
+
+// parseStreamTagsString parses s in the format returned by StreamTags.String() into dst.
+func parseStreamTagsString(dst *StreamTags, s string) error {
+	dst.Reset()
+
+	if !strings.HasPrefix(s, "{") || !strings.HasSuffix(s, "}") {
+		return fmt.Errorf("missing surrounding curly braces")
+	}
+	s = s[1 : len(s)-1]
+	for len(s) > 0 {
+		n := strings.IndexByte(s, '=')
+		if n < 0 {
+			return fmt.Errorf("missing '=' after tag name %q", s)
+		}
+		name := s[:n]
+		s = s[n+1:]
+
+		qValue, err := strconv.QuotedPrefix(s)
+		if err != nil {
+			return fmt.Errorf("cannot parse value for tag %q: %w", name, err)
+		}
+		value, err := strconv.Unquote(qValue)
+		if err != nil {
+			return fmt.Errorf("cannot unquote value for tag %q: %w", name, err)
+		}
+		s = s[len(qValue):]
+		dst.Add(name, value)
+
+		if len(s) == 0 {
+			break
+		}
+		if s[0] != ',' {
+			return fmt.Errorf("unexpected tail after the value for tag %q: %q; want ','", name, s)
+		}
+		s = s[1:]
+		if len(s) == 0 {
+			return fmt.Errorf("missing tag after ','")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,183 @@
+package logstorage
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fs"
+)
+
+func TestParseStreamTagsString(t *testing.T) {
+	f := func(s string) {
+		t.Helper()
+
+		st := GetStreamTags()
+		defer PutStreamTags(st)
+
+		if err := parseStreamTagsString(st, s); err != nil {
+			t.Fatalf("unexpected error when parsing %q: %s", s, err)
+		}
+		result := st.String()
+		if result != s {
+			t.Fatalf("unexpected result\ngot\n%s\nwant\n%s", result, s)
+		}
+	}
+
+	f(`{}`)
+	f(`{app="foo"}`)
+	f(`{app="foo",instance="host-1:234",job="x\"y,z=\n"}`)
+}
+
+func TestParseStreamTagsStringFailure(t *testing.T) {
+	f := func(s string) {
+		t.Helper()
+
+		st := GetStreamTags()
+		defer PutStreamTags(st)
+
+		if err := parseStreamTagsString(st, s); err == nil {
+			t.Fatalf("expecting non-nil error when parsing %q", s)
+		}
+	}
+
+	f(``)
+	f(`{`)
+	f(`app="foo"`)
+	f(`{app}`)
+	f(`{app=foo}`)
+	f(`{app="foo"instance="bar"}`)
+	f(`{app="foo",}`)
+}
+
+func TestNativeExportImport(t *testing.T) {
+	const path = "TestNativeExportImport"
+
+	sc := &StorageConfig{
+		Retention: 24 * time.Hour,
+	}
+	sSrc := MustOpenStorage(path+"/src", sc)
+	sDst := MustOpenStorage(path+"/dst", sc)
+
+	tenantID := TenantID{AccountID: 1, ProjectID: 2}
+	tenantIDs := []TenantID{tenantID}
+
+	baseTimestamp := time.Now().UnixNano() - 3600*1e9
+	lr := GetLogRows([]string{"app", "host"}, nil)
+	for i := 0; i < 1000; i++ {
+		fields := []Field{
+			{
+				Name:  "app",
+				Value: fmt.Sprintf("app_%d", i%7),
+			},
+			{
+				Name:  "host",
+				Value: fmt.Sprintf("host_%d", i%3),
+			},
+			{
+				Name:  "n",
+				Value: fmt.Sprintf("%d", i),
+			},
+			{
+				Name:  "_msg",
+				Value: fmt.Sprintf("message %d", i),
+			},
+		}
+		lr.MustAdd(tenantID, baseTimestamp+int64(i)*1e9+int64(i), fields)
+	}
+	sSrc.MustAddRows(lr)
+	PutLogRows(lr)
+	sSrc.debugFlush()
+
+	// Export all the logs from sSrc and import them into sDst.
+	var data []byte
+	var dataLock sync.Mutex
+	var errExport error
+	q := mustParseQuery(`*`)
+	if !q.CanExportNative() {
+		t.Fatalf("expecting the ability to export [%s]", q)
+	}
+	checkErr(t, sSrc.RunQuery(context.Background(), tenantIDs, q, func(_ uint, _ []int64, columns []BlockColumn) {
+		dataLock.Lock()
+		defer dataLock.Unlock()
+
+		var err error
+		data, err = MarshalNativeBlock(data, columns)
+		if err != nil && errExport == nil {
+			errExport = err
+		}
+	}))
+	checkErr(t, errExport)
+
+	lr = GetLogRows(nil, nil)
+	checkErr(t, lr.AddNativeBlock(tenantID, data))
+	sDst.MustAddRows(lr)
+	PutLogRows(lr)
+	sDst.debugFlush()
+
+	getRows := func(s *Storage) []string {
+		t.Helper()
+
+		var rows []string
+		var rowsLock sync.Mutex
+		checkErr(t, s.RunQuery(context.Background(), tenantIDs, mustParseQuery(`*`), func(_ uint, timestamps []int64, columns []BlockColumn) {
+			rowsLock.Lock()
+			defer rowsLock.Unlock()
+
+			for i := range timestamps {
+				var rf RowFormatter
+				for _, c := range columns {
+					rf = append(rf, Field{
+						Name:  c.Name,
+						Value: c.Values[i],
+					})
+				}
+				sort.Slice(rf, func(i, j int) bool {
+					return rf[i].Name < rf[j].Name
+				})
+				rows = append(rows, rf.String())
+			}
+		}))
+		sort.Strings(rows)
+		return rows
+	}
+	rowsExpected := getRows(sSrc)
+	rows := getRows(sDst)
+	if len(rows) != 1000 {
+		t.Fatalf("unexpected number of imported rows; got %d; want %d", len(rows), 1000)
+	}
+	if !reflect.DeepEqual(rows, rowsExpected) {
+		t.Fatalf("unexpected rows after import\ngot\n%s\nwant\n%s", rows, rowsExpected)
+	}
+
+	getStreams := func(s *Storage) []ValueWithHits {
+		t.Helper()
+
+		streams, err := s.GetStreams(context.Background(), tenantIDs, mustParseQuery(`*`), 100)
+		checkErr(t, err)
+		return streams
+	}
+	streamsExpected := getStreams(sSrc)
+	streams := getStreams(sDst)
+	if !reflect.DeepEqual(streams, streamsExpected) {
+		t.Fatalf("unexpected streams after import\ngot\n%v\nwant\n%v", streams, streamsExpected)
+	}
+
+	// Queries with pipes, which aren't row-local, cannot be exported
+	if q := mustParseQuery(`* | stats count() rows`); q.CanExportNative() {
+		t.Fatalf("unexpected ability to export [%s]", q)
+	}
+
+	// Export must fail if _stream field is missing
+	if _, err := MarshalNativeBlock(nil, []BlockColumn{{Name: "_time", Values: []string{"2024-01-02T03:04:05Z"}}}); err == nil {
+		t.Fatalf("expecting non-nil error for missing _stream field")
+	}
+
+	sSrc.MustClose()
+	sDst.MustClose()
+	fs.MustRemoveAll(path)
+}
@@ -0,0 +1,335 @@
+package logstorage
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/encoding"
+)
+
+// RunRemoteQueryFunc must run qRemote at every remote storage node and call writeBlock for the returned data blocks.
+//
+// writeBlock may be called concurrently from multiple goroutines with distinct workerID values in the range [0 ... workersCount-1],
+// where workersCount is the value passed to RunNetQuery.
+type RunRemoteQueryFunc func(ctx context.Context, qRemote *Query, writeBlock func(workerID uint, timestamps []int64, columns []BlockColumn)) error
+
+// RunNetQuery runs q over remote storage nodes and calls writeBlock for the results.
+//
+// q is split into the remote part, which is executed at every storage node via runRemote,
+// and the local part, which is executed over the merged results returned from storage nodes.
+// For example, `stats` results from storage nodes are merged into the final stats,
+// `sort ... limit N`, `limit N` and `uniq` results are limited at storage nodes before sending them over the network.
+//
+// workersCount is the number of concurrent workers, which may call writeBlock passed to runRemote.
+func RunNetQuery(ctx context.Context, workersCount int, q *Query, runRemote RunRemoteQueryFunc, writeBlock func(workerID uint, timestamps []int64, columns []BlockColumn)) error {
+	qRemote, pipesLocal := q.splitToRemoteAndLocal()
+
+	search := func(stopCh <-chan struct{}, writeBlockResult func(workerID uint, br *blockResult)) error {
+		ctxSearch, cancel := contextWithStopCh(ctx, stopCh)
+		defer cancel()
+
+		rbcs := make([]remoteBlockContext, workersCount)
+		err := runRemote(ctxSearch, qRemote, func(workerID uint, timestamps []int64, columns []BlockColumn) {
+			rbc := &rbcs[workerID]
+			br := rbc.initBlockResult(timestamps, columns)
+			writeBlockResult(workerID, br)
+		})
+		if err != nil && ctxSearch.Err() != nil {
+			// The search has been canceled either by the caller or by the local pipes, which do not need more data.
+			// Ignore the error in this case, since it is expected.
+			return nil
+		}
+		return err
+	}
+	return runPipes(ctx, workersCount, pipesLocal, search, writeBlock)
+}
+
+// contextWithStopCh returns a child context for ctx, which is canceled when stopCh is closed.
+//
+// The returned cancel func must be called when the context is no longer needed.
+func contextWithStopCh(ctx context.Context, stopCh <-chan struct{}) (context.Context, func()) {
+	ctxChild, cancel := context.WithCancel(ctx)
+	doneCh := make(chan struct{})
+	go func() {
+		select {
+		case <-stopCh:
+			cancel()
+		case <-doneCh:
+		}
+	}()
+	return ctxChild, func() {
+		close(doneCh)
+		cancel()
+	}
+}
+
+// remoteBlockContext converts data blocks received from remote storage nodes to blockResult.
+type remoteBlockContext struct {
+	rcs        []resultColumn
+	timestamps []int64
+	br         blockResult
+}
+
+func (rbc *remoteBlockContext) initBlockResult(timestamps []int64, columns []BlockColumn) *blockResult {
+	rcs := rbc.rcs[:0]
+	timeColumnIdx := -1
+	for i, c := range columns {
+		rcs = append(rcs, resultColumn{
+			name:   c.Name,
+			values: c.Values,
+		})
+		if c.Name == "_time" {
+			timeColumnIdx = i
+		}
+	}
+	rbc.rcs = rcs
+
+	br := &rbc.br
+	br.setResultColumns(rcs)
+	br.timestamps = append(br.timestamps[:0], timestamps...)
+
+	if timeColumnIdx >= 0 {
+		// Convert _time column back to timestamps, so the local pipes could process it as a regular _time field.
+		values := columns[timeColumnIdx].Values
+		rbc.timestamps = rbc.timestamps[:0]
+		for _, v := range values {
+			timestamp, ok := tryParseTimestampRFC3339Nano(v)
+			if !ok {
+				break
+			}
+			rbc.timestamps = append(rbc.timestamps, timestamp)
+		}
+		if len(rbc.timestamps) == len(values) {
+			br.timestamps = append(br.timestamps[:0], rbc.timestamps...)
+			br.csBuf[timeColumnIdx] = blockResultColumn{
+				name:   "_time",
+				isTime: true,
+			}
+			br.csInitialized = false
+		}
+	}
+	return br
+}
+
+// splitToRemoteAndLocal splits q into the query, which must be executed at every remote storage node,
+// and the pipes, which must be executed locally over the merged results received from storage nodes.
+func (q *Query) splitToRemoteAndLocal() (*Query, []pipe) {
+	var pipesRemote, pipesLocal []pipe
+	for i, p := range q.pipes {
+		if isRowLocalPipe(p) {
+			pipesRemote = append(pipesRemote, p)
+			continue
+		}
+
+		pRemote, pipesLocalHead := splitPipeToRemoteAndLocal(p)
+		pipesLocal = append(pipesLocalHead, q.pipes[i+1:]...)
+		if pRemote == nil {
+			// The pipe cannot be executed at storage nodes. Fetch only the needed fields from storage nodes then.
+			pRemote = getPipeForNeededFields(pipesLocal)
+		}
+		if pRemote != nil {
+			pipesRemote = append(pipesRemote, pRemote)
+		}
+		break
+	}
+
+	qRemote := &Query{
+		f:         q.f,
+		pipes:     pipesRemote,
+		timestamp: q.timestamp,
+	}
+	return qRemote, pipesLocal
+}
+
+// splitPipeToRemoteAndLocal splits p into the pipe, which must be executed at every remote storage node,
+// and the pipes, which must be executed locally over the merged results received from storage nodes.
+//
+// nil remote pipe is returned if p cannot be executed at remote storage nodes.
+func splitPipeToRemoteAndLocal(p pipe) (pipe, []pipe) {
+	switch t := p.(type) {
+	case *pipeLimit:
+		return t, []pipe{t}
+	case *pipeSort:
+		if t.limit == 0 {
+			return nil, []pipe{t}
+		}
+		psRemote := &pipeSort{
+			byFields: t.byFields,
+			isDesc:   t.isDesc,
+			limit:    t.offset + t.limit,
+		}
+		return psRemote, []pipe{t}
+	case *pipeUniq:
+		puRemote := &pipeUniq{
+			byFields: t.byFields,
+			limit:    t.limit,
+		}
+		if t.hitsFieldName == "" {
+			return puRemote, []pipe{t}
+		}
+		if len(t.byFields) == 0 || slices.Contains(t.byFields, "hits") {
+			return nil, []pipe{t}
+		}
+
+		// Storage nodes return hits in the `hits` field - see pipeUniq.String()
+		puRemote.hitsFieldName = "hits"
+		pipesLocal := []pipe{newPipeStatsSumHits(t.byFields, t.hitsFieldName)}
+		if t.limit > 0 {
+			pipesLocal = append(pipesLocal, &pipeLimit{
+				n: t.limit,
+			})
+		}
+		return puRemote, pipesLocal
+	case *pipeFieldNames:
+		if t.resultName == "hits" {
+			return nil, []pipe{t}
+		}
+		return t, []pipe{newPipeStatsSumHits([]string{t.resultName}, "hits")}
+	case *pipeStreamLabels:
+		return t, []pipe{newPipeStatsSumHits([]string{t.resultName()}, "hits")}
+	case *pipeStats:
+		// Storage nodes return states for stats functions, which are merged locally.
+		psRemote := &pipeStats{
+			byFields:    t.byFields,
+			resultNames: t.resultNames,
+			funcs:       t.funcs,
+			mode:        statsModeExportState,
+		}
+
+		// The results from storage nodes are already split into buckets, so they must be grouped by field names without buckets.
+		byFields := make([]*byStatsField, len(t.byFields))
+		for i, bf := range t.byFields {
+			byFields[i] = &byStatsField{
+				name: bf.name,
+			}
+		}
+		psLocal := &pipeStats{
+			byFields:    byFields,
+			resultNames: t.resultNames,
+			funcs:       t.funcs,
+			mode:        statsModeImportState,
+		}
+		return psRemote, []pipe{psLocal}
+	default:
+		return nil, []pipe{p}
+	}
+}
+
+// newPipeStatsSumHits returns `stats by (byFields) sum(hits) as resultName` pipe.
+func newPipeStatsSumHits(byFields []string, resultName string) *pipeStats {
+	bfs := make([]*byStatsField, len(byFields))
+	for i, f := range byFields {
+		bfs[i] = &byStatsField{
+			name: f,
+		}
+	}
+	return &pipeStats{
+		byFields:    bfs,
+		resultNames: []string{resultName},
+		funcs: []statsFunc{
+			&statsSum{
+				fields: []string{"hits"},
+			},
+		},
+	}
+}
+
+// getPipeForNeededFields returns the pipe, which leaves only the fields needed by the given pipes.
+//
+// nil is returned if all the fields are needed.
+func getPipeForNeededFields(pipes []pipe) pipe {
+	q := &Query{
+		pipes: pipes,
+	}
+	neededFields, unneededFields := q.getNeededColumns()
+	if slices.Contains(neededFields, "*") {
+		if len(unneededFields) == 0 {
+			return nil
+		}
+		return &pipeDelete{
+			fields: unneededFields,
+		}
+	}
+	if len(neededFields) == 0 {
+		// The pipes need only the number of rows. Fetch the smallest field then.
+		neededFields = []string{"_time"}
+	}
+	return &pipeFields{
+		fields: neededFields,
+	}
+}
+
+// MarshalBlockColumns appends marshaled timestamps and columns to dst and returns the result.
+//
+// The marshaled data can be unmarshaled via UnmarshalBlockColumns.
+func MarshalBlockColumns(dst []byte, timestamps []int64, columns []BlockColumn) []byte {
+	dst = encoding.MarshalVarUint64(dst, uint64(len(timestamps)))
+	dst = encoding.MarshalVarInt64s(dst, timestamps)
+
+	dst = encoding.MarshalVarUint64(dst, uint64(len(columns)))
+	for _, c := range columns {
+		dst = encoding.MarshalBytes(dst, bytesutil.ToUnsafeBytes(c.Name))
+		for _, v := range c.Values {
+			dst = encoding.MarshalBytes(dst, bytesutil.ToUnsafeBytes(v))
+		}
+	}
+	return dst
+}
+
+// UnmarshalBlockColumns unmarshals timestamps and columns marshaled via MarshalBlockColumns from src.
+//
+// The unmarshaled timestamps and columns are appended to the given timestamps and columns.
+// The returned columns refer to src, so src mustn't be changed while the columns are in use.
+func UnmarshalBlockColumns(timestamps []int64, columns []BlockColumn, src []byte) ([]int64, []BlockColumn, error) {
+	rowsCount, n := encoding.UnmarshalVarUint64(src)
+	if n <= 0 {
+		return timestamps, columns, fmt.Errorf("cannot unmarshal the number of rows")
+	}
+	src = src[n:]
+	if rowsCount > uint64(len(src)) {
+		return timestamps, columns, fmt.Errorf("too big number of rows: %d; it cannot exceed %d", rowsCount, len(src))
+	}
+
+	timestampsLen := len(timestamps)
+	timestamps = slices.Grow(timestamps, int(rowsCount))[:timestampsLen+int(rowsCount)]
+	tail, err := encoding.UnmarshalVarInt64s(timestamps[timestampsLen:], src)
+	if err != nil {
+		return timestamps, columns, fmt.Errorf("cannot unmarshal timestamps: %w", err)
+	}
+	src = tail
+
+	columnsCount, n := encoding.UnmarshalVarUint64(src)
+	if n <= 0 {
+		return timestamps, columns, fmt.Errorf("cannot unmarshal the number of columns")
+	}
+	src = src[n:]
+
+	for i := uint64(0); i < columnsCount; i++ {
+		name, n := encoding.UnmarshalBytes(src)
+		if n <= 0 {
+			return timestamps, columns, fmt.Errorf("cannot unmarshal column name")
+		}
+		src = src[n:]
+
+		values := make([]string, rowsCount)
+		for j := range values {
+			v, n := encoding.UnmarshalBytes(src)
+			if n <= 0 {
+				return timestamps, columns, fmt.Errorf("cannot unmarshal value #%d for column %q", j, name)
+			}
+			src = src[n:]
+			values[j] = bytesutil.ToUnsafeString(v)
+		}
+
+		columns = append(columns, BlockColumn{
+			Name:   bytesutil.ToUnsafeString(name),
+			Values: values,
+		})
+	}
+	if len(src) > 0 {
+		return timestamps, columns, fmt.Errorf("unexpected non-empty tail left after unmarshaling block columns; len(tail)=%d", len(src))
+	}
+	return timestamps, columns, nil
+}
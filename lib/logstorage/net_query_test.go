@@ -0,0 +1,315 @@
+package logstorage
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fs"
+)
+
+func TestQuerySplitToRemoteAndLocal(t *testing.T) {
+	f := func(qStr, remoteExpected, localExpected string) {
+		t.Helper()
+
+		q, err := ParseQuery(qStr)
+		if err != nil {
+			t.Fatalf("cannot parse [%s]: %s", qStr, err)
+		}
+		qRemote, pipesLocal := q.splitToRemoteAndLocal()
+		remote := qRemote.String()
+		if remote != remoteExpected {
+			t.Fatalf("unexpected remote query\ngot\n%s\nwant\n%s", remote, remoteExpected)
+		}
+		a := make([]string, len(pipesLocal))
+		for i, p := range pipesLocal {
+			a[i] = p.String()
+		}
+		local := strings.Join(a, " | ")
+		if local != localExpected {
+			t.Fatalf("unexpected local pipes\ngot\n%s\nwant\n%s", local, localExpected)
+		}
+	}
+
+	// row-local pipes are executed at storage nodes
+	f(`foo`, `foo`, ``)
+	f(`foo | fields a, b | rename a as c`, `foo | fields a, b | rename a as c`, ``)
+
+	// limit
+	f(`foo | limit 10 | fields a`, `foo | limit 10`, `limit 10 | fields a`)
+
+	// sort
+	f(`foo | sort by (a)`, `foo`, `sort by (a)`)
+	f(`foo | sort by (a desc) offset 5 limit 10`, `foo | sort by (a desc) limit 15`, `sort by (a desc) offset 5 limit 10`)
+
+	// uniq
+	f(`foo | uniq by (a, b) limit 10`, `foo | uniq by (a, b) limit 10`, `uniq by (a, b) limit 10`)
+	f(`foo | uniq by (a) with hits limit 10`, `foo | uniq by (a) with hits limit 10`, `stats by (a) sum(hits) as hits | limit 10`)
+	f(`foo | uniq by (hits) with hits`, `foo | fields hits`, `uniq by (hits) with hits`)
+
+	// field_names
+	f(`foo | field_names as x`, `foo | field_names as x`, `stats by (x) sum(hits) as hits`)
+
+	// stream_label_names and stream_label_values
+	f(`foo | stream_label_names`, `foo | stream_label_names`, `stats by (name) sum(hits) as hits`)
+	f(`foo | stream_label_values host`, `foo | stream_label_values host`, `stats by (value) sum(hits) as hits`)
+
+	// stats
+	f(`foo | stats by (_time:1h, a) count() x, sum(b) y | sort by (x)`,
+		`foo | stats_remote by (_time:1h, a) count(*) as x, sum(b) as y`,
+		`stats_local by (_time, a) count(*) as x, sum(b) as y | sort by (x)`)
+	f(`foo | stats count_uniq(a) x, avg(b) y`, `foo | stats_remote count_uniq(a) as x, avg(b) as y`, `stats_local count_uniq(a) as x, avg(b) as y`)
+
+	// offset
+	f(`foo | offset 10 | stats count() x`, `foo | fields _time`, `offset 10 | stats count(*) as x`)
+	f(`foo | offset 10 | stats count() x | stats count() y`, `foo | fields _time`, `offset 10 | stats count(*) as x | stats count(*) as y`)
+}
+
+func TestParseRemoteQuery(t *testing.T) {
+	f := func(s, resultExpected string) {
+		t.Helper()
+
+		q, err := ParseRemoteQueryAtTimestamp(s, 0)
+		if err != nil {
+			t.Fatalf("cannot parse [%s]: %s", s, err)
+		}
+		result := q.String()
+		if result != resultExpected {
+			t.Fatalf("unexpected result\ngot\n%s\nwant\n%s", result, resultExpected)
+		}
+
+		// The query with remote pipes mustn't be parsed by ParseQuery
+		if _, err := ParseQuery(s); err == nil {
+			t.Fatalf("expecting non-nil error when parsing [%s] via ParseQuery", s)
+		}
+	}
+
+	f(`* | stats_remote by (_time:1h, x) count() z, avg(y) w`, `* | stats_remote by (_time:1h, x) count(*) as z, avg(y) as w`)
+	f(`foo | fields a | stats_remote count_uniq(a) x`, `foo | fields a | stats_remote count_uniq(a) as x`)
+}
+
+func TestRunNetQuery(t *testing.T) {
+	const path = "TestRunNetQuery"
+
+	sc := &StorageConfig{
+		Retention: 24 * time.Hour,
+	}
+	paths := []string{
+		path + "/all",
+		path + "/node1",
+		path + "/node2",
+	}
+	sAll := MustOpenStorage(paths[0], sc)
+	sNodes := []*Storage{
+		MustOpenStorage(paths[1], sc),
+		MustOpenStorage(paths[2], sc),
+	}
+
+	tenantID := TenantID{AccountID: 1, ProjectID: 2}
+	tenantIDs := []TenantID{tenantID}
+
+	// Ingest the same rows into sAll and spread them among sNodes.
+	baseTimestamp := time.Now().UnixNano() - 3600*1e9
+	lr := GetLogRows([]string{"app"}, nil)
+	for i := 0; i < 1000; i++ {
+		fields := []Field{
+			{
+				Name:  "app",
+				Value: fmt.Sprintf("app_%d", i%7),
+			},
+			{
+				Name:  "n",
+				Value: fmt.Sprintf("%d", i),
+			},
+			{
+				Name:  "_msg",
+				Value: fmt.Sprintf("message %d", i),
+			},
+		}
+		lr.MustAdd(tenantID, baseTimestamp+int64(i)*1e9, fields)
+	}
+	sAll.MustAddRows(lr)
+
+	var bufs [2][]byte
+	for i := 0; i < lr.Len(); i++ {
+		idx := lr.GetStreamHash(i) % uint64(len(bufs))
+		bufs[idx] = lr.MarshalRow(bufs[idx], i)
+	}
+	PutLogRows(lr)
+	for i, buf := range bufs {
+		lrNode := GetLogRows(nil, nil)
+		if err := lrNode.AddMarshaledRows(buf); err != nil {
+			t.Fatalf("cannot unmarshal rows: %s", err)
+		}
+		sNodes[i].MustAddRows(lrNode)
+		PutLogRows(lrNode)
+	}
+
+	sAll.debugFlush()
+	for _, s := range sNodes {
+		s.debugFlush()
+	}
+
+	runRemote := func(ctx context.Context, qRemote *Query, writeBlock func(workerID uint, timestamps []int64, columns []BlockColumn)) error {
+		// Marshal and unmarshal qRemote in the same way as it is sent over the network to storage nodes.
+		q, err := ParseRemoteQueryAtTimestamp(qRemote.String(), qRemote.GetTimestamp())
+		if err != nil {
+			return fmt.Errorf("cannot parse remote query [%s]: %w", qRemote, err)
+		}
+
+		var wg sync.WaitGroup
+		errs := make([]error, len(sNodes))
+		for i, s := range sNodes {
+			wg.Add(1)
+			go func(workerID uint, s *Storage) {
+				defer wg.Done()
+
+				// Storage.RunQuery calls writeBlock from concurrently running goroutines,
+				// while every storage node must use a single workerID.
+				var mu sync.Mutex
+				errs[workerID] = s.RunQuery(ctx, tenantIDs, q, func(_ uint, timestamps []int64, columns []BlockColumn) {
+					mu.Lock()
+					writeBlock(workerID, timestamps, columns)
+					mu.Unlock()
+				})
+			}(uint(i), s)
+		}
+		wg.Wait()
+
+		for _, err := range errs {
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	getRows := func(runQuery func(writeBlock func(workerID uint, timestamps []int64, columns []BlockColumn)) error) []string {
+		t.Helper()
+
+		var rows []string
+		var rowsLock sync.Mutex
+		writeBlock := func(_ uint, timestamps []int64, columns []BlockColumn) {
+			rowsLock.Lock()
+			defer rowsLock.Unlock()
+
+			for i := range timestamps {
+				var rf RowFormatter
+				for _, c := range columns {
+					rf = append(rf, Field{
+						Name:  c.Name,
+						Value: c.Values[i],
+					})
+				}
+				sort.Slice(rf, func(i, j int) bool {
+					return rf[i].Name < rf[j].Name
+				})
+				rows = append(rows, rf.String())
+			}
+		}
+		checkErr(t, runQuery(writeBlock))
+		sort.Strings(rows)
+		return rows
+	}
+
+	f := func(qStr string) {
+		t.Helper()
+
+		q := mustParseQuery(qStr)
+		rowsExpected := getRows(func(writeBlock func(workerID uint, timestamps []int64, columns []BlockColumn)) error {
+			return sAll.RunQuery(context.Background(), tenantIDs, q, writeBlock)
+		})
+		rows := getRows(func(writeBlock func(workerID uint, timestamps []int64, columns []BlockColumn)) error {
+			return RunNetQuery(context.Background(), len(sNodes), q, runRemote, writeBlock)
+		})
+		if !reflect.DeepEqual(rows, rowsExpected) {
+			t.Fatalf("unexpected rows for [%s]\ngot\n%s\nwant\n%s", qStr, rows, rowsExpected)
+		}
+	}
+
+	f(`*`)
+	f(`app_1 | fields n, _time`)
+	f(`* | stats count() rows`)
+	f(`* | stats by (app) count() rows, sum(n) n_sum, min(n) n_min, max(n) n_max, sum_len(_msg) msg_len`)
+	f(`* | stats by (_time:10m) count() rows`)
+	f(`* | stats count_uniq(app) apps, avg(n) n_avg`)
+	f(`* | stats by (app) count_uniq(n) n_uniq, avg(n) n_avg, quantile(0.5, n) n_median, median(n) n_median2, count_empty(x) empty`)
+	f(`* | stats by (app) uniq_values(n) n_values | unpack_json from n_values | fields app`)
+	f(`* | stats by (_time:10m) values(app) apps | unpack_json from apps | fields _time`)
+	f(`* | stats count_uniq(app) limit 3 apps`)
+	f(`_time:1h | stats by (_time:10m) rate() r, rate_sum(n) rs`)
+	f(`* | stats min(x) x_min, max(x) x_max, sum(x) x_sum, avg(x) x_avg`)
+	f(`* | sort by (n) desc offset 3 limit 5`)
+	f(`* | sort by (_time) limit 3`)
+	f(`* | uniq by (app)`)
+	f(`* | uniq by (app) with hits`)
+	f(`* | field_names`)
+	f(`* | stream_label_names | sort by (name)`)
+	f(`* | stream_label_values app | sort by (value)`)
+	f(`* | offset 990 | stats count() rows`)
+	f(`* | limit 0`)
+
+	getFieldNames := func(runQuery RunQueryFunc) []ValueWithHits {
+		t.Helper()
+
+		results, err := runQuery.GetFieldNames(context.Background(), tenantIDs, mustParseQuery(`*`))
+		checkErr(t, err)
+		return results
+	}
+	resultsExpected := getFieldNames(sAll.RunQuery)
+	results := getFieldNames(func(ctx context.Context, _ []TenantID, q *Query, writeBlock func(workerID uint, timestamps []int64, columns []BlockColumn)) error {
+		return RunNetQuery(ctx, len(sNodes), q, runRemote, writeBlock)
+	})
+	if !reflect.DeepEqual(results, resultsExpected) {
+		t.Fatalf("unexpected field names\ngot\n%v\nwant\n%v", results, resultsExpected)
+	}
+
+	sAll.MustClose()
+	for _, s := range sNodes {
+		s.MustClose()
+	}
+	fs.MustRemoveAll(path)
+}
+
+func TestMarshalUnmarshalBlockColumns(t *testing.T) {
+	f := func(timestamps []int64, columns []BlockColumn) {
+		t.Helper()
+
+		data := MarshalBlockColumns(nil, timestamps, columns)
+		timestampsResult, columnsResult, err := UnmarshalBlockColumns(nil, nil, data)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if len(timestampsResult) != len(timestamps) || (len(timestamps) > 0 && !reflect.DeepEqual(timestampsResult, timestamps)) {
+			t.Fatalf("unexpected timestamps\ngot\n%v\nwant\n%v", timestampsResult, timestamps)
+		}
+		if len(columnsResult) != len(columns) || (len(columns) > 0 && !reflect.DeepEqual(columnsResult, columns)) {
+			t.Fatalf("unexpected columns\ngot\n%v\nwant\n%v", columnsResult, columns)
+		}
+
+		// Truncated data must result in error
+		for i := 0; i < len(data); i++ {
+			if _, _, err := UnmarshalBlockColumns(nil, nil, data[:i]); err == nil {
+				t.Fatalf("expecting non-nil error when unmarshaling truncated data with len=%d", i)
+			}
+		}
+	}
+
+	f(nil, nil)
+	f([]int64{123}, nil)
+	f([]int64{1, -2, 3}, []BlockColumn{
+		{
+			Name:   "_msg",
+			Values: []string{"foo", "", "bar baz"},
+		},
+		{
+			Name:   "x",
+			Values: []string{"1", "2", "3"},
+		},
+	})
+}
@@ -10,6 +10,7 @@ import (
 	"unicode"
 	"unicode/utf8"
 
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promutils"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/regexutil"
 )
@@ -37,16 +38,28 @@ type lexer struct {
 
 	// currentTimestamp is the current timestamp in nanoseconds
 	currentTimestamp int64
+
+	// allowRemotePipes is set to true if the pipes, which are sent to storage nodes in cluster setup, can be parsed.
+	//
+	// Such pipes mustn't be parsed in user queries, since they may return partial states.
+	allowRemotePipes bool
 }
 
 // newLexer returns new lexer for the given s.
 //
 // The lex.token points to the first token in s.
 func newLexer(s string) *lexer {
+	return newLexerAtTimestamp(s, time.Now().UnixNano())
+}
+
+// newLexerAtTimestamp returns new lexer for the given s, which evaluates relative time filters at the given timestamp in nanoseconds.
+//
+// The lex.token points to the first token in s.
+func newLexerAtTimestamp(s string, timestamp int64) *lexer {
 	lex := &lexer{
 		s:                s,
 		sOrig:            s,
-		currentTimestamp: time.Now().UnixNano(),
+		currentTimestamp: timestamp,
 	}
 	lex.nextToken()
 	return lex
@@ -193,6 +206,9 @@ type Query struct {
 	f filter
 
 	pipes []pipe
+
+	// timestamp is the timestamp in nanoseconds, which is used for evaluating relative time filters such as `_time:5m`.
+	timestamp int64
 }
 
 // String returns string representation for q.
@@ -206,6 +222,21 @@ func (q *Query) String() string {
 	return s
 }
 
+// GetTimestamp returns the timestamp in nanoseconds, which is used for evaluating relative time filters such as `_time:5m` in q.
+func (q *Query) GetTimestamp() int64 {
+	return q.timestamp
+}
+
+// Clone returns a copy of q.
+func (q *Query) Clone() *Query {
+	qStr := q.String()
+	qCopy, err := ParseQueryAtTimestamp(qStr, q.timestamp)
+	if err != nil {
+		logger.Panicf("BUG: cannot parse %q: %s", qStr, err)
+	}
+	return qCopy
+}
+
 // AddTimeFilter adds global filter _time:[start ... end] to q.
 func (q *Query) AddTimeFilter(start, end int64) {
 	startStr := marshalTimestampRFC3339Nano(nil, start)
@@ -227,6 +258,8 @@ func (q *Query) AddTimeFilter(start, end int64) {
 			filters: []filter{ft, q.f},
 		}
 	}
+
+	q.initStatsRateFuncs()
 }
 
 // AddPipeLimit adds `| limit n` pipe to q.
@@ -238,12 +271,187 @@ func (q *Query) AddPipeLimit(n uint64) {
 	})
 }
 
+// AddCountByTimePipe adds '| stats by (_time:step offset off, field1, ..., fieldN) count() hits | sort by (_time, field1, ..., fieldN)' to the end of q.
+//
+// This is used for building log volume histograms via /select/logsql/hits endpoint.
+func (q *Query) AddCountByTimePipe(step, off int64, fields []string) {
+	// add 'stats by (_time:step offset off, fields) count() hits'
+	bfTime := &byStatsField{
+		name:          "_time",
+		bucketSizeStr: string(marshalDuration(nil, step)),
+		bucketSize:    float64(step),
+	}
+	if off != 0 {
+		bfTime.bucketOffsetStr = string(marshalDuration(nil, off))
+		bfTime.bucketOffset = float64(off)
+	}
+	byFields := []*byStatsField{bfTime}
+	for _, f := range fields {
+		byFields = append(byFields, &byStatsField{
+			name: f,
+		})
+	}
+	ps := &pipeStats{
+		byFields:    byFields,
+		resultNames: []string{"hits"},
+		funcs: []statsFunc{
+			&statsCount{
+				fields:       []string{"*"},
+				containsStar: true,
+			},
+		},
+	}
+	q.pipes = append(q.pipes, ps)
+
+	// add 'sort by (_time, fields)' in order to get consistent order of the results.
+	sortFields := []*bySortField{
+		{
+			name: "_time",
+		},
+	}
+	for _, f := range fields {
+		sortFields = append(sortFields, &bySortField{
+			name: f,
+		})
+	}
+	q.pipes = append(q.pipes, &pipeSort{
+		byFields: sortFields,
+	})
+}
+
+// AddStatsByTimeField adds `_time:step offset off` field to `by(...)` clause of the last `| stats ...` pipe at q.
+//
+// It returns the names of the remaining `by(...)` fields for the last `| stats ...` pipe.
+//
+// This is used for returning time series from stats results via /select/logsql/stats_query_range endpoint.
+func (q *Query) AddStatsByTimeField(step, off int64) ([]string, error) {
+	ps, err := q.getLastPipeStats()
+	if err != nil {
+		return nil, err
+	}
+
+	var fields []string
+	for _, bf := range ps.byFields {
+		if bf.name == "_time" {
+			return nil, fmt.Errorf("the last '| stats ...' pipe mustn't contain '_time' field in 'by(...)' clause, since it is added automatically")
+		}
+		fields = append(fields, bf.name)
+	}
+
+	bfTime := &byStatsField{
+		name:          "_time",
+		bucketSizeStr: string(marshalDuration(nil, step)),
+		bucketSize:    float64(step),
+	}
+	if off != 0 {
+		bfTime.bucketOffsetStr = string(marshalDuration(nil, off))
+		bfTime.bucketOffset = float64(off)
+	}
+	byFields := make([]*byStatsField, 0, len(ps.byFields)+1)
+	byFields = append(byFields, bfTime)
+	byFields = append(byFields, ps.byFields...)
+	ps.byFields = byFields
+
+	q.initStatsRateFuncs()
+
+	return fields, nil
+}
+
+// GetStatsByFields returns the names of `by(...)` fields for the last `| stats ...` pipe at q.
+//
+// An error is returned if q doesn't contain `| stats ...` pipe or if the `| stats ...` pipe is followed by pipes,
+// which may change the set of the returned fields. Only `filter`, `sort`, `limit` and `offset` pipes are allowed after the `| stats ...` pipe.
+//
+// This is used for returning stats results as time series via /select/logsql/stats_query endpoint.
+func (q *Query) GetStatsByFields() ([]string, error) {
+	pipes := q.pipes
+	idx := len(pipes) - 1
+	for idx >= 0 {
+		if _, ok := pipes[idx].(*pipeStats); ok {
+			break
+		}
+		switch pipes[idx].(type) {
+		case *pipeFilter, *pipeSort, *pipeLimit, *pipeOffset:
+		default:
+			return nil, fmt.Errorf("the '| stats ...' pipe cannot be followed by %q pipe; only 'filter', 'sort', 'limit' and 'offset' pipes are allowed after it", pipes[idx])
+		}
+		idx--
+	}
+	if idx < 0 {
+		return nil, fmt.Errorf("the query must contain '| stats ...' pipe")
+	}
+	ps := pipes[idx].(*pipeStats)
+
+	var fields []string
+	for _, bf := range ps.byFields {
+		fields = append(fields, bf.name)
+	}
+	return fields, nil
+}
+
+func (q *Query) getLastPipeStats() (*pipeStats, error) {
+	if len(q.pipes) == 0 {
+		return nil, fmt.Errorf("the query must end with '| stats ...' pipe")
+	}
+	ps, ok := q.pipes[len(q.pipes)-1].(*pipeStats)
+	if !ok {
+		return nil, fmt.Errorf("the last pipe must be '| stats ...'; got %q", q.pipes[len(q.pipes)-1])
+	}
+	return ps, nil
+}
+
+// initStatsRateFuncs initializes rate() and rate_sum() funcs at `| stats ...` pipes in q.
+//
+// The step for these funcs is taken from `_time:step` field at `by(...)` clause of the `| stats ...` pipe.
+// Otherwise the duration of the time range for the query is used.
+func (q *Query) initStatsRateFuncs() {
+	step := int64(0)
+	ft, _ := getCommonFilterTime(q.f)
+	if ft.minTimestamp != math.MinInt64 && ft.maxTimestamp != math.MaxInt64 && ft.maxTimestamp >= ft.minTimestamp {
+		step = ft.maxTimestamp - ft.minTimestamp + 1
+	}
+
+	for _, p := range q.pipes {
+		ps, ok := p.(*pipeStats)
+		if !ok {
+			continue
+		}
+		if bucketStep, ok := ps.getTimeBucketStep(); ok {
+			ps.initRateFuncs(bucketStep)
+		} else {
+			ps.initRateFuncs(step)
+		}
+	}
+}
+
 // Optimize tries optimizing the query.
 func (q *Query) Optimize() {
 	q.pipes = optimizeSortOffsetPipes(q.pipes)
 	q.pipes = optimizeSortLimitPipes(q.pipes)
 }
 
+// CanLiveTail returns true if q can be used in live tailing.
+//
+// Live tailing is possible only for queries with pipes, which process every log entry independently of other log entries.
+func (q *Query) CanLiveTail() bool {
+	for _, p := range q.pipes {
+		if !isRowLocalPipe(p) {
+			return false
+		}
+	}
+	return true
+}
+
+// isRowLocalPipe returns true if p processes every row independently of other rows.
+func isRowLocalPipe(p pipe) bool {
+	switch p.(type) {
+	case *pipeCopy, *pipeDelete, *pipeExtract, *pipeFields, *pipeFilter, *pipeFormat, *pipeMath, *pipeRename, *pipeUnpackJSON, *pipeUnpackLogfmt:
+		return true
+	default:
+		return false
+	}
+}
+
 func optimizeSortOffsetPipes(pipes []pipe) []pipe {
 	// Merge 'sort ... | offset ...' into 'sort ... offset ...'
 	i := 1
@@ -303,14 +511,34 @@ func (q *Query) getNeededColumns() ([]string, []string) {
 
 // ParseQuery parses s.
 func ParseQuery(s string) (*Query, error) {
-	lex := newLexer(s)
+	return ParseQueryAtTimestamp(s, time.Now().UnixNano())
+}
+
+// ParseQueryAtTimestamp parses s in the context of the given timestamp in nanoseconds.
+//
+// Relative time filters such as `_time:5m` are evaluated relative to the given timestamp.
+func ParseQueryAtTimestamp(s string, timestamp int64) (*Query, error) {
+	lex := newLexerAtTimestamp(s, timestamp)
+	return parseQuery(lex, timestamp)
+}
+
+// ParseRemoteQueryAtTimestamp parses s sent by RunNetQuery to storage nodes in the context of the given timestamp in nanoseconds.
+//
+// Unlike ParseQueryAtTimestamp, it accepts the pipes, which are sent only to storage nodes such as `stats_remote`.
+func ParseRemoteQueryAtTimestamp(s string, timestamp int64) (*Query, error) {
+	lex := newLexerAtTimestamp(s, timestamp)
+	lex.allowRemotePipes = true
+	return parseQuery(lex, timestamp)
+}
 
+func parseQuery(lex *lexer, timestamp int64) (*Query, error) {
 	f, err := parseFilter(lex)
 	if err != nil {
 		return nil, fmt.Errorf("%w; context: [%s]", err, lex.context())
 	}
 	q := &Query{
-		f: f,
+		f:         f,
+		timestamp: timestamp,
 	}
 
 	pipes, err := parsePipes(lex)
@@ -323,6 +551,8 @@ func ParseQuery(s string) (*Query, error) {
 		return nil, fmt.Errorf("unexpected unparsed tail; context: [%s]; tail: [%s]", lex.context(), lex.s)
 	}
 
+	q.initStatsRateFuncs()
+
 	return q, nil
 }
 
@@ -1183,6 +1413,11 @@ func parseStreamTagFilter(lex *lexer) (*streamTagFilter, error) {
 
 func parseTime(lex *lexer) (int64, string, error) {
 	s := getCompoundToken(lex)
+	if nsecs, ok := tryParseTimestampRFC3339Nano(s); ok {
+		// Fast path for RFC3339 timestamps. It preserves nanosecond precision,
+		// which is lost by promutils.ParseTimeAt because of float64 seconds.
+		return nsecs, s, nil
+	}
 	t, err := promutils.ParseTimeAt(s, float64(lex.currentTimestamp)/1e9)
 	if err != nil {
 		return 0, "", err
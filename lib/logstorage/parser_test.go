@@ -272,6 +272,16 @@ func TestParseTimeRange(t *testing.T) {
 	minTimestamp = time.Date(2023, time.February, 28, 21, 40, 0, 0, time.UTC).UnixNano() - offset
 	maxTimestamp = time.Date(2023, time.April, 7, 0, 0, 0, 0, time.UTC).UnixNano() - 1 - offset
 	f(`[2023-03-01+02:20,2023-04-06T23] offset 30m5s`, minTimestamp, maxTimestamp)
+
+	// _time:[start, end) with nanosecond precision
+	minTimestamp = time.Date(2024, time.October, 15, 10, 20, 30, 123456789, time.UTC).UnixNano()
+	maxTimestamp = time.Date(2024, time.October, 16, 10, 20, 30, 987654321, time.UTC).UnixNano() - 1
+	f(`[2024-10-15T10:20:30.123456789Z,2024-10-16T10:20:30.987654321Z)`, minTimestamp, maxTimestamp)
+
+	// _time:(start, end] with nanosecond precision
+	minTimestamp = time.Date(2024, time.October, 15, 10, 20, 30, 123456789, time.UTC).UnixNano() + 1
+	maxTimestamp = time.Date(2024, time.October, 16, 10, 20, 30, 987654321, time.UTC).UnixNano()
+	f(`(2024-10-15T10:20:30.123456789Z,2024-10-16T10:20:30.987654321Z]`, minTimestamp, maxTimestamp)
 }
 
 func TestParseFilterSequence(t *testing.T) {
@@ -882,6 +892,16 @@ func TestParseQuerySuccess(t *testing.T) {
 	f(`* | stats sum(*) x`, `* | stats sum(*) as x`)
 	f(`* | stats sum(foo,*,bar) x`, `* | stats sum(*) as x`)
 
+	// stats pipe rate
+	f(`* | stats Rate() bar`, `* | stats rate() as bar`)
+	f(`* | stats by (_time:5m) rate(*) x`, `* | stats by (_time:5m) rate() as x`)
+
+	// stats pipe rate_sum
+	f(`* | stats Rate_Sum(foo) bar`, `* | stats rate_sum(foo) as bar`)
+	f(`* | stats BY(x, y, ) RATE_SUM(foo,bar,) bar`, `* | stats by (x, y) rate_sum(foo, bar) as bar`)
+	f(`* | stats rate_sum() x`, `* | stats rate_sum(*) as x`)
+	f(`* | stats rate_sum(foo,*,bar) x`, `* | stats rate_sum(*) as x`)
+
 	// stats pipe max
 	f(`* | stats Max(foo) bar`, `* | stats max(foo) as bar`)
 	f(`* | stats BY(x, y, ) MAX(foo,bar,) bar`, `* | stats by (x, y) max(foo, bar) as bar`)
@@ -992,6 +1012,63 @@ func TestParseQuerySuccess(t *testing.T) {
 	f(`* | uniq by(f1,f2)`, `* | uniq by (f1, f2)`)
 	f(`* | uniq by(f1,f2) limit 10`, `* | uniq by (f1, f2) limit 10`)
 	f(`* | uniq limit 10`, `* | uniq limit 10`)
+	f(`* | uniq by(f1) with hits`, `* | uniq by (f1) with hits`)
+	f(`* | uniq by(f1) with hits limit 10`, `* | uniq by (f1) with hits limit 10`)
+	f(`* | uniq with hits`, `* | uniq with hits`)
+
+	// field_names pipe
+	f(`* | field_names`, `* | field_names`)
+	f(`* | field_names name`, `* | field_names`)
+	f(`* | field_names as foo`, `* | field_names as foo`)
+	f(`* | field_names bar | limit 10`, `* | field_names as bar | limit 10`)
+
+	// stream_label_names and stream_label_values pipes
+	f(`* | stream_label_names`, `* | stream_label_names`)
+	f(`* | stream_label_values host`, `* | stream_label_values host`)
+	f(`* | stream_label_values "a b" | limit 10`, `* | stream_label_values "a b" | limit 10`)
+
+	// extract pipe
+	f(`* | extract "foo<bar>baz"`, `* | extract "foo<bar>baz"`)
+	f(`* | extract "foo<bar>baz" from _msg`, `* | extract "foo<bar>baz"`)
+	f(`* | extract "foo<bar>baz" from ''`, `* | extract "foo<bar>baz"`)
+	f(`* | extract "ip=<ip> <_>" from x`, `* | extract "ip=<ip> <_>" from x`)
+	f(`* | EXTRACT "<a> <b>" from "a b" | extract "x<y>"`, `* | extract "<a> <b>" from "a b" | extract "x<y>"`)
+
+	// unpack_json pipe
+	f(`* | unpack_json`, `* | unpack_json`)
+	f(`* | unpack_json from _msg`, `* | unpack_json`)
+	f(`* | unpack_json from x`, `* | unpack_json from x`)
+	f(`* | unpack_json fields (a, b) result_prefix "foo."`, `* | unpack_json fields (a, b) result_prefix foo.`)
+	f(`* | unpack_json from x fields (*) result_prefix "a b"`, `* | unpack_json from x result_prefix "a b"`)
+
+	// unpack_logfmt pipe
+	f(`* | unpack_logfmt`, `* | unpack_logfmt`)
+	f(`* | unpack_logfmt from _msg`, `* | unpack_logfmt`)
+	f(`* | unpack_logfmt from x`, `* | unpack_logfmt from x`)
+	f(`* | unpack_logfmt from x fields (a) result_prefix y_`, `* | unpack_logfmt from x fields (a) result_prefix y_`)
+
+	// format pipe
+	f(`* | format "<a>:<b>"`, `* | format "<a>:<b>"`)
+	f(`* | format "<a>:<q:b>" as c`, `* | format "<a>:<q:b>" as c`)
+	f(`* | format "&lt;<host>&gt;" as _msg`, `* | format "&lt;<host>&gt;"`)
+	f(`* | format foo as bar`, `* | format foo as bar`)
+
+	// math pipe
+	f(`* | math duration_us / 1000 as duration_ms`, `* | math duration_us / 1000 as duration_ms`)
+	f(`* | math a+b*c as x, (a+b)*c as y`, `* | math a + b * c as x, (a + b) * c as y`)
+	f(`* | math a-(b-c) as x, (a-b)-c y`, `* | math a - (b - c) as x, a - b - c as y`)
+	f(`* | math 2^3^2 as x, (2^3)^2 as y, -2^2 as z, (-2)^2 as w`, `* | math 2 ^ 3 ^ 2 as x, (2 ^ 3) ^ 2 as y, -(2 ^ 2) as z, (-2) ^ 2 as w`)
+	f(`* | math -a as x, -(a+b) as y, a % 10 as z`, `* | math -a as x, -(a + b) as y, a % 10 as z`)
+	f(`* | math abs(a) as x, round(b, 0.1) as y, max(a, b, 1KB) as z, min(exp(a), ln(b)) as w`, `* | math abs(a) as x, round(b, 0.1) as y, max(a, b, 1KB) as z, min(exp(a), ln(b)) as w`)
+	f(`* | math "foo-bar" * 2 as "x y", "123" + 0x10 as z`, `* | math "foo-bar" * 2 as "x y", "123" + 0x10 as z`)
+	f("* | math\n  (errors / requests) as error_rate,\n  (requests - errors - warnings) as success_requests", `* | math errors / requests as error_rate, requests - errors - warnings as success_requests`)
+
+	// filter pipe
+	f(`* | filter foo:bar`, `* | filter foo:bar`)
+	f(`* | where foo:bar`, `* | filter foo:bar`)
+	f(`* | filter x:range[1, 10] or y:re("a.+b")`, `* | filter x:range[1, 10] or y:re("a.+b")`)
+	f(`* | stats by (host) count() hits | filter hits:range(10, inf) host:in(a, b) | sort by (hits desc)`, `* | stats by (host) count(*) as hits | filter hits:range(10, inf) host:in(a,b) | sort by (hits desc)`)
+	f(`* | filter _time:5m _stream:{a="b"} !len_range(0, 3)`, `* | filter _time:5m _stream:{a="b"} !len_range(0, 3)`)
 
 	// multiple different pipes
 	f(`* | fields foo, bar | limit 100 | stats by(foo,bar) count(baz) as qwert`, `* | fields foo, bar | limit 100 | stats by (foo, bar) count(baz) as qwert`)
@@ -1269,6 +1346,15 @@ func TestParseQueryFailure(t *testing.T) {
 	f(`foo | stats sum`)
 	f(`foo | stats sum()`)
 
+	// invalid stats rate
+	f(`foo | stats rate`)
+	f(`foo | stats rate()`)
+	f(`foo | stats rate(foo) x`)
+
+	// invalid stats rate_sum
+	f(`foo | stats rate_sum`)
+	f(`foo | stats rate_sum()`)
+
 	// invalid stats max
 	f(`foo | stats max`)
 	f(`foo | stats max()`)
@@ -1359,6 +1445,74 @@ func TestParseQueryFailure(t *testing.T) {
 	f(`foo | uniq by(a) bar`)
 	f(`foo | uniq by(a) limit -10`)
 	f(`foo | uniq by(a) limit foo`)
+	f(`foo | uniq by(a) with`)
+	f(`foo | uniq by(a) with foo`)
+	f(`foo | uniq by(a) limit 10 with hits`)
+
+	// invalid field_names pipe
+	f(`foo | field_names as`)
+	f(`foo | field_names (`)
+	f(`foo | field_names hits`)
+	f(`foo | field_names foo bar`)
+
+	// invalid stream_label_names and stream_label_values pipes
+	f(`foo | stream_label_names foo`)
+	f(`foo | stream_label_values`)
+	f(`foo | stream_label_values (`)
+
+	// invalid extract pipe
+	f(`foo | extract`)
+	f(`foo | extract bar`)
+	f(`foo | extract "xy"`)
+	f(`foo | extract "<_>"`)
+	f(`foo | extract "<foo"`)
+	f(`foo | extract "<foo><bar>"`)
+	f(`foo | extract "<foo>" from`)
+	f(`foo | extract "<foo>" from x y`)
+
+	// invalid unpack_json pipe
+	f(`foo | unpack_json bar`)
+	f(`foo | unpack_json from`)
+	f(`foo | unpack_json from x y`)
+	f(`foo | unpack_json fields`)
+	f(`foo | unpack_json fields ()`)
+	f(`foo | unpack_json fields (a`)
+	f(`foo | unpack_json result_prefix`)
+	f(`foo | unpack_json result_prefix x fields (a)`)
+
+	// invalid unpack_logfmt pipe
+	f(`foo | unpack_logfmt bar`)
+	f(`foo | unpack_logfmt from`)
+	f(`foo | unpack_logfmt fields ()`)
+	f(`foo | unpack_logfmt result_prefix`)
+
+	// invalid format pipe
+	f(`foo | format`)
+	f(`foo | format "<a"`)
+	f(`foo | format "<a>" as`)
+	f(`foo | format "<a>" bar`)
+
+	// invalid math pipe
+	f(`foo | math`)
+	f(`foo | math a`)
+	f(`foo | math a +`)
+	f(`foo | math a + b as`)
+	f(`foo | math (a + b as c`)
+	f(`foo | math a + b) as c`)
+	f(`foo | math abs() as c`)
+	f(`foo | math abs(a, b) as c`)
+	f(`foo | math max(a) as c`)
+	f(`foo | math round(a, b, c) as c`)
+	f(`foo | math a * * b as c`)
+	f(`foo | math a b c`)
+	f(`foo | math a as b,`)
+
+	// invalid filter pipe
+	f(`foo | filter`)
+	f(`foo | where`)
+	f(`foo | filter foo:(`)
+	f(`foo | filter | sort by (x)`)
+	f(`foo | filter bar)`)
 }
 
 func TestQueryGetNeededColumns(t *testing.T) {
@@ -1475,6 +1629,41 @@ func TestQueryGetNeededColumns(t *testing.T) {
 	f(`* | uniq by (f1,f2) | fields f1,f3`, `f1,f2`, ``)
 	f(`* | uniq by (f1,f2) | rm f1,f3`, `f1,f2`, ``)
 	f(`* | uniq by (f1,f2) | fields f3`, `f1,f2`, ``)
+	f(`* | uniq by (f1,f2) with hits | fields f1`, `f1,f2`, ``)
+
+	f(`* | field_names`, `*`, ``)
+	f(`* | fields f1, f2 | field_names`, `f1,f2`, ``)
+	f(`* | rm f1, f2 | field_names`, `*`, `f1,f2`)
+	f(`* | field_names | fields name`, `*`, ``)
+
+	f(`* | extract "<f1> <f2>"`, `*`, `f1,f2`)
+	f(`* | extract "<f1> <f2>" from x`, `*`, `f1,f2`)
+	f(`* | extract "<f1> <f2>" from x | fields f1`, `x`, ``)
+	f(`* | extract "<f1> <f2>" from x | fields f3`, `f3`, ``)
+	f(`* | extract "<f1> <f2>" from x | rm f1, f2`, `*`, `f1,f2`)
+	f(`* | extract "<f1> <f2>" from x | rm f1, x`, `*`, `f1,f2`)
+
+	f(`* | unpack_json`, `*`, ``)
+	f(`* | unpack_json from x | fields f1`, `f1,x`, ``)
+	f(`* | unpack_json from x fields (f1, f2) | fields f1`, `x`, ``)
+	f(`* | unpack_json from x fields (f1, f2) result_prefix p_ | fields f1`, `f1`, ``)
+	f(`* | rm x | unpack_json from x`, `*`, `x`)
+	f(`* | unpack_logfmt from x | rm x`, `*`, ``)
+	f(`* | unpack_logfmt from x fields (f1) | rm f1`, `*`, `f1`)
+	f(`* | filter foo:bar`, `*`, ``)
+	f(`* | rm foo | filter foo:bar`, `*`, `foo`)
+	f(`* | rm x | filter foo:bar or baz:qux`, `*`, `x`)
+	f(`* | filter foo:bar | fields x`, `foo,x`, ``)
+	f(`* | filter (foo:bar or _stream:{a="b"}) _time:5m | fields x`, `_stream,_time,foo,x`, ``)
+	f(`* | filter foo:bar !baz | fields x`, `_msg,foo,x`, ``)
+	f(`* | format "<a>:<b>" as c`, `*`, `c`)
+	f(`* | format "<a>:<b>" as c | rm c`, `*`, `c`)
+	f(`* | format "<a>:<q:b>" as c | fields c, d`, `a,b,d`, ``)
+	f(`* | format "<_msg> <a>" | fields x`, `x`, ``)
+	f(`* | math a * 2 as b`, `*`, `b`)
+	f(`* | math a * 2 as b | rm b`, `*`, `b`)
+	f(`* | math a * 2 as b, b + c as d | fields d`, `a,c`, ``)
+	f(`* | math abs(x) as y, max(y, z) as w | fields y, w`, `x,z`, ``)
 
 	f(`* | rm f1, f2`, `*`, `f1,f2`)
 	f(`* | rm f1, f2 | mv f2 f3`, `*`, `f1,f2,f3`)
@@ -1491,3 +1680,242 @@ func TestQueryGetNeededColumns(t *testing.T) {
 	f(`* | rm f1, f2 | stats by(f3) count(f2) r1`, `f3`, ``)
 	f(`* | rm f1, f2 | stats by(f3) count(f4) r1`, `f3,f4`, ``)
 }
+
+func TestQueryAddCountByTimePipe(t *testing.T) {
+	f := func(qStr string, step, off int64, fields []string, resultExpected string) {
+		t.Helper()
+
+		q, err := ParseQuery(qStr)
+		if err != nil {
+			t.Fatalf("cannot parse [%s]: %s", qStr, err)
+		}
+		q.AddCountByTimePipe(step, off, fields)
+		result := q.String()
+		if result != resultExpected {
+			t.Fatalf("unexpected result;\ngot\n%s\nwant\n%s", result, resultExpected)
+		}
+	}
+
+	f(`*`, nsecsPerHour, 0, nil, `* | stats by (_time:1h) count(*) as hits | sort by (_time)`)
+	f(`error`, 5*nsecsPerMinute, nsecsPerMinute, nil, `error | stats by (_time:5m offset 1m) count(*) as hits | sort by (_time)`)
+	f(`error | fields host, level`, nsecsPerDay, 0, []string{"host", "level"}, `error | fields host, level | stats by (_time:1d, host, level) count(*) as hits | sort by (_time, host, level)`)
+	f(`*`, nsecsPerHour, 0, []string{"foo:bar"}, `* | stats by (_time:1h, "foo:bar") count(*) as hits | sort by (_time, "foo:bar")`)
+}
+
+func TestQueryAddStatsByTimeField(t *testing.T) {
+	f := func(qStr string, step, off int64, resultExpected string, fieldsExpected []string, stepSecondsExpected float64) {
+		t.Helper()
+
+		q, err := ParseQuery(qStr)
+		if err != nil {
+			t.Fatalf("cannot parse [%s]: %s", qStr, err)
+		}
+		fields, err := q.AddStatsByTimeField(step, off)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		result := q.String()
+		if result != resultExpected {
+			t.Fatalf("unexpected result;\ngot\n%s\nwant\n%s", result, resultExpected)
+		}
+		if !reflect.DeepEqual(fields, fieldsExpected) {
+			t.Fatalf("unexpected fields;\ngot\n%q\nwant\n%q", fields, fieldsExpected)
+		}
+		ps := q.pipes[len(q.pipes)-1].(*pipeStats)
+		for _, f := range ps.funcs {
+			if sr, ok := f.(*statsRate); ok && sr.stepSeconds != stepSecondsExpected {
+				t.Fatalf("unexpected stepSeconds for %s; got %v; want %v", sr, sr.stepSeconds, stepSecondsExpected)
+			}
+		}
+	}
+
+	f(`* | stats count() hits`, nsecsPerHour, 0, `* | stats by (_time:1h) count(*) as hits`, nil, 0)
+	f(`error | stats by (host) rate() r`, 5*nsecsPerMinute, nsecsPerMinute, `error | stats by (_time:5m offset 1m, host) rate() as r`, []string{"host"}, 300)
+	f(`* | stats by (x, "foo:bar") rate() r, count() c`, nsecsPerDay, 0, `* | stats by (_time:1d, x, "foo:bar") rate() as r, count(*) as c`, []string{"x", "foo:bar"}, 86400)
+}
+
+func TestQueryAddStatsByTimeFieldFailure(t *testing.T) {
+	f := func(qStr string) {
+		t.Helper()
+
+		q, err := ParseQuery(qStr)
+		if err != nil {
+			t.Fatalf("cannot parse [%s]: %s", qStr, err)
+		}
+		if _, err := q.AddStatsByTimeField(nsecsPerHour, 0); err == nil {
+			t.Fatalf("expecting non-nil error")
+		}
+	}
+
+	f(`*`)
+	f(`* | stats count() x | fields x`)
+	f(`* | stats by (_time:1h) count() x`)
+	f(`* | stats by (_time) count() x`)
+}
+
+func TestQueryGetStatsByFields(t *testing.T) {
+	f := func(qStr string, fieldsExpected []string) {
+		t.Helper()
+
+		q, err := ParseQuery(qStr)
+		if err != nil {
+			t.Fatalf("cannot parse [%s]: %s", qStr, err)
+		}
+		fields, err := q.GetStatsByFields()
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if !reflect.DeepEqual(fields, fieldsExpected) {
+			t.Fatalf("unexpected fields;\ngot\n%q\nwant\n%q", fields, fieldsExpected)
+		}
+	}
+
+	f(`* | stats count() x`, nil)
+	f(`error | stats by (host, level) count() x, rate() r`, []string{"host", "level"})
+	f(`* | fields a, b | stats by (_time:5m, a) sum(b) s`, []string{"_time", "a"})
+	f(`error | stats by (host) count() x | filter x:range(10, inf) | sort by (x) desc | offset 1 | limit 5`, []string{"host"})
+}
+
+func TestQueryGetStatsByFieldsFailure(t *testing.T) {
+	f := func(qStr string) {
+		t.Helper()
+
+		q, err := ParseQuery(qStr)
+		if err != nil {
+			t.Fatalf("cannot parse [%s]: %s", qStr, err)
+		}
+		if _, err := q.GetStatsByFields(); err == nil {
+			t.Fatalf("expecting non-nil error")
+		}
+	}
+
+	f(`*`)
+	f(`error | fields host`)
+	f(`* | stats count() x | fields x`)
+	f(`* | stats by (host) count() x | stats count() y | rename y z`)
+}
+
+func TestParseQueryAtTimestamp(t *testing.T) {
+	f := func(qStr string, timestamp, minTimestampExpected, maxTimestampExpected int64) {
+		t.Helper()
+
+		q, err := ParseQueryAtTimestamp(qStr, timestamp)
+		if err != nil {
+			t.Fatalf("cannot parse [%s]: %s", qStr, err)
+		}
+		ft, _ := getCommonFilterTime(q.f)
+		if ft.minTimestamp != minTimestampExpected || ft.maxTimestamp != maxTimestampExpected {
+			t.Fatalf("unexpected time range; got [%d, %d]; want [%d, %d]", ft.minTimestamp, ft.maxTimestamp, minTimestampExpected, maxTimestampExpected)
+		}
+
+		// Verify that the cloned query has the same time range
+		qCopy := q.Clone()
+		ft, _ = getCommonFilterTime(qCopy.f)
+		if ft.minTimestamp != minTimestampExpected || ft.maxTimestamp != maxTimestampExpected {
+			t.Fatalf("unexpected time range for the cloned query; got [%d, %d]; want [%d, %d]", ft.minTimestamp, ft.maxTimestamp, minTimestampExpected, maxTimestampExpected)
+		}
+	}
+
+	f(`_time:5m error`, 10*nsecsPerHour, 10*nsecsPerHour-5*nsecsPerMinute, 10*nsecsPerHour)
+	f(`_time:1h | stats count() x`, 5*nsecsPerHour, 4*nsecsPerHour, 5*nsecsPerHour)
+}
+
+func TestQueryInitStatsRateFuncs(t *testing.T) {
+	f := func(qStr string, stepSecondsExpected float64) {
+		t.Helper()
+
+		q, err := ParseQuery(qStr)
+		if err != nil {
+			t.Fatalf("cannot parse [%s]: %s", qStr, err)
+		}
+		ps := q.pipes[len(q.pipes)-1].(*pipeStats)
+		for _, f := range ps.funcs {
+			var stepSeconds float64
+			switch t := f.(type) {
+			case *statsRate:
+				stepSeconds = t.stepSeconds
+			case *statsRateSum:
+				stepSeconds = t.stepSeconds
+			default:
+				continue
+			}
+			if stepSeconds != stepSecondsExpected {
+				t.Fatalf("unexpected stepSeconds for %s; got %v; want %v", f, stepSeconds, stepSecondsExpected)
+			}
+		}
+	}
+
+	// missing time range
+	f(`* | stats rate() r`, 0)
+
+	// step is taken from _time bucket
+	f(`* | stats by (_time:1m) rate() r, rate_sum(x) rs`, 60)
+	f(`_time:[2024-01-01T00:00:00Z, 2024-01-02T00:00:00Z) | stats by (_time:10s, host) rate_sum(x) rs`, 10)
+
+	// step is taken from the time range
+	f(`_time:[2024-01-01T00:00:00Z, 2024-01-01T00:01:00Z) | stats rate() r, rate_sum(x) rs`, 60)
+	f(`_time:[2024-01-01T00:00:00Z, 2024-01-01T00:01:00Z) error | stats by (host) rate() r`, 60)
+}
+
+func TestQueryClone(t *testing.T) {
+	f := func(qStr string) {
+		t.Helper()
+
+		q, err := ParseQuery(qStr)
+		if err != nil {
+			t.Fatalf("cannot parse [%s]: %s", qStr, err)
+		}
+		qCopy := q.Clone()
+		if s := qCopy.String(); s != qStr {
+			t.Fatalf("unexpected clone result;\ngot\n%s\nwant\n%s", s, qStr)
+		}
+
+		// Verify that modifications of the clone do not affect the original query
+		qCopy.AddTimeFilter(1e9, 2e9)
+		if s := q.String(); s != qStr {
+			t.Fatalf("unexpected modification of the original query;\ngot\n%s\nwant\n%s", s, qStr)
+		}
+	}
+
+	f(`*`)
+	f(`error`)
+	f(`foo or bar`)
+	f(`_stream:{app="nginx"} error | fields _time, _msg`)
+	f(`error | stats by (host) count(*) as hits | sort by (hits desc) limit 10`)
+}
+
+func TestQueryCanLiveTail(t *testing.T) {
+	f := func(qStr string, resultExpected bool) {
+		t.Helper()
+
+		q, err := ParseQuery(qStr)
+		if err != nil {
+			t.Fatalf("cannot parse [%s]: %s", qStr, err)
+		}
+		result := q.CanLiveTail()
+		if result != resultExpected {
+			t.Fatalf("unexpected result for CanLiveTail(%q); got %v; want %v", qStr, result, resultExpected)
+		}
+	}
+
+	f("foo", true)
+	f("* | copy a b", true)
+	f("* | rm a, b", true)
+	f("* | fields a, b", true)
+	f("* | rename a b", true)
+	f("* | fields a | rm b | cp c d | mv e f", true)
+	f("* | extract 'foo<bar>baz'", true)
+	f("* | unpack_json", true)
+	f("* | unpack_logfmt from x fields (a, b)", true)
+	f("* | filter foo:bar", true)
+	f("* | where _time:5m", true)
+	f("* | format \"<a>:<b>\" as c", true)
+	f("* | math a / 1000 as b", true)
+	f("* | field_names", false)
+	f("* | limit 10", false)
+	f("* | offset 10", false)
+	f("* | sort by (a)", false)
+	f("* | stats count() rows", false)
+	f("* | uniq by (a)", false)
+	f("* | fields a | uniq by (a)", false)
+}
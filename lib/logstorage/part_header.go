@@ -31,6 +31,12 @@ type partHeader struct {
 
 	// MaxTimestamp is the maximum timestamp seen in the part
 	MaxTimestamp int64
+
+	// DeleteTasksSeq is the sequence number of the last delete task applied to the part.
+	//
+	// Delete tasks with bigger sequence numbers may match log entries in the part,
+	// while the rest of delete tasks have been already applied to the part.
+	DeleteTasksSeq uint64 `json:",omitempty"`
 }
 
 // reset resets ph for subsequent re-use
@@ -41,6 +47,7 @@ func (ph *partHeader) reset() {
 	ph.BlocksCount = 0
 	ph.MinTimestamp = 0
 	ph.MaxTimestamp = 0
+	ph.DeleteTasksSeq = 0
 }
 
 // String returns string represenation for ph.
@@ -88,14 +88,15 @@ func mustOpenPartition(s *Storage, path string) *partition {
 //
 // The partition can be deleted if needed after it is closed via mustDeletePartition() call.
 func mustClosePartition(pt *partition) {
+	// Close datadb before indexdb, since background merges at datadb may access indexdb
+	// for applying retention rules.
+	mustCloseDatadb(pt.ddb)
+	pt.ddb = nil
+
 	// Close indexdb
 	mustCloseIndexdb(pt.idb)
 	pt.idb = nil
 
-	// Close datadb
-	mustCloseDatadb(pt.ddb)
-	pt.ddb = nil
-
 	pt.name = ""
 	pt.path = ""
 	pt.s = nil
@@ -131,6 +132,11 @@ func (pt *partition) mustAddRows(lr *LogRows) {
 			if !pt.idb.hasStreamID(streamID) {
 				streamTagsCanonical := streamTagsCanonicals[rowIdx]
 				pt.idb.mustRegisterStream(streamID, streamTagsCanonical)
+
+				// Put the stream tags into the cache, so they become visible to search immediately.
+				// Otherwise they become visible to search only after the registered stream is flushed to searchable parts in indexdb.
+				// This prevents from missing the first logs for new streams in live tailing.
+				pt.putStreamTagsToCache(streamID, streamTagsCanonical)
 				if logNewStreams {
 					pt.logNewStream(streamTagsCanonical, lr.rows[rowIdx])
 				}
@@ -185,6 +191,13 @@ func (pt *partition) appendStreamTagsByStreamID(dst []byte, sid *streamID) []byt
 	return dst
 }
 
+func (pt *partition) putStreamTagsToCache(sid *streamID, streamTagsCanonical []byte) {
+	key := bbPool.Get()
+	key.B = sid.marshal(key.B)
+	pt.s.streamTagsCache.SetBig(key.B, streamTagsCanonical)
+	bbPool.Put(key)
+}
+
 func (pt *partition) hasStreamIDInCache(sid *streamID) bool {
 	var result [1]byte
 
@@ -217,6 +230,27 @@ func (pt *partition) debugFlush() {
 	pt.idb.debugFlush()
 }
 
+// mustCreateSnapshotAt creates a snapshot for pt at dstDir.
+func (pt *partition) mustCreateSnapshotAt(dstDir string) {
+	fs.MustMkdirFailIfExist(dstDir)
+
+	// Snapshot datadb before indexdb, so all the streams referred by the snapshotted data
+	// are registered in the snapshotted indexdb.
+	datadbPath := filepath.Join(dstDir, datadbDirname)
+	pt.ddb.mustCreateSnapshotAt(datadbPath)
+
+	indexdbPath := filepath.Join(dstDir, indexdbDirname)
+	if err := pt.idb.tb.CreateSnapshotAt(indexdbPath); err != nil {
+		logger.Panicf("FATAL: cannot create indexdb snapshot: %s", err)
+	}
+
+	if rules := mustReadAppliedRetentionRules(pt.path); len(rules) > 0 {
+		mustWriteAppliedRetentionRules(dstDir, rules)
+	}
+
+	fs.MustSyncPath(dstDir)
+}
+
 func (pt *partition) updateStats(ps *PartitionStats) {
 	pt.ddb.updateStats(&ps.DatadbStats)
 	pt.idb.updateStats(&ps.IndexdbStats)
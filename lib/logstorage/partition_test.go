@@ -178,10 +178,12 @@ func TestPartitionMustAddRowsConcurrent(t *testing.T) {
 // When the storage is no longer needed, closeTestStorage() must be called.
 func newTestStorage() *Storage {
 	streamIDCache := workingsetcache.New(1024 * 1024)
+	streamTagsCache := workingsetcache.New(1024 * 1024)
 	filterStreamCache := workingsetcache.New(1024 * 1024)
 	return &Storage{
 		flushInterval:     time.Second,
 		streamIDCache:     streamIDCache,
+		streamTagsCache:   streamTagsCache,
 		filterStreamCache: filterStreamCache,
 	}
 }
@@ -189,5 +191,6 @@ func newTestStorage() *Storage {
 // closeTestStorage closes storage created via newTestStorage().
 func closeTestStorage(s *Storage) {
 	s.streamIDCache.Stop()
+	s.streamTagsCache.Stop()
 	s.filterStreamCache.Stop()
 }
@@ -0,0 +1,161 @@
+package logstorage
+
+import (
+	"fmt"
+	"html"
+	"strings"
+)
+
+// pattern represents text pattern in the form 'some_text<some_field>other_text...'
+//
+// See https://docs.victoriametrics.com/victorialogs/logsql/#extract-pipe
+type pattern struct {
+	// steps contains steps for extracting fields from string
+	steps []patternStep
+
+	// matches contains matches for every step in steps
+	matches []string
+
+	// fields contains matches for non-empty fields
+	fields []patternField
+}
+
+type patternField struct {
+	name  string
+	value *string
+}
+
+type patternStep struct {
+	// prefix is the text, which must precede the field value
+	prefix string
+
+	// field is the name of the field to extract; an empty field means the value must be skipped
+	field string
+}
+
+func (ptn *pattern) clone() *pattern {
+	steps := ptn.steps
+	fields, matches := newFieldsAndMatchesFromPatternSteps(steps)
+	return &pattern{
+		steps:   steps,
+		matches: matches,
+		fields:  fields,
+	}
+}
+
+func parsePattern(s string) (*pattern, error) {
+	steps, err := parsePatternSteps(s)
+	if err != nil {
+		return nil, err
+	}
+
+	// Verify that prefixes are non-empty between fields. The first prefix may be empty.
+	for i := 1; i < len(steps); i++ {
+		if steps[i].prefix == "" {
+			return nil, fmt.Errorf("missing delimiter between <%s> and <%s>", steps[i-1].field, steps[i].field)
+		}
+	}
+
+	// Build pattern struct
+	fields, matches := newFieldsAndMatchesFromPatternSteps(steps)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("pattern %q must contain at least a single named field in the form <field_name>", s)
+	}
+
+	ptn := &pattern{
+		steps:   steps,
+		matches: matches,
+		fields:  fields,
+	}
+	return ptn, nil
+}
+
+func newFieldsAndMatchesFromPatternSteps(steps []patternStep) ([]patternField, []string) {
+	matches := make([]string, len(steps))
+
+	var fields []patternField
+	for i, step := range steps {
+		if step.field != "" {
+			fields = append(fields, patternField{
+				name:  step.field,
+				value: &matches[i],
+			})
+		}
+	}
+
+	return fields, matches
+}
+
+// apply extracts fields from s according to ptn and stores them in ptn.fields.
+//
+// All the ptn.fields are set to empty values if s doesn't match ptn.
+func (ptn *pattern) apply(s string) {
+	clear(ptn.matches)
+
+	steps := ptn.steps
+
+	if prefix := steps[0].prefix; prefix != "" {
+		n := strings.Index(s, prefix)
+		if n < 0 {
+			// Mismatch
+			return
+		}
+		s = s[n+len(prefix):]
+	}
+
+	matches := ptn.matches
+	for i := range steps {
+		nextPrefix := ""
+		if i+1 < len(steps) {
+			nextPrefix = steps[i+1].prefix
+		}
+
+		if nextPrefix == "" {
+			// The last step - the rest of s belongs to the field.
+			matches[i] = s
+			return
+		}
+
+		n := strings.Index(s, nextPrefix)
+		if n < 0 {
+			// Mismatch
+			clear(matches)
+			return
+		}
+		matches[i] = s[:n]
+		s = s[n+len(nextPrefix):]
+	}
+}
+
+func parsePatternSteps(s string) ([]patternStep, error) {
+	var steps []patternStep
+	for {
+		n := strings.IndexByte(s, '<')
+		if n < 0 {
+			if s != "" {
+				// Add the trailing text, which must follow the last field
+				steps = append(steps, patternStep{
+					prefix: html.UnescapeString(s),
+				})
+			}
+			return steps, nil
+		}
+		prefix := s[:n]
+		s = s[n+1:]
+
+		n = strings.IndexByte(s, '>')
+		if n < 0 {
+			return nil, fmt.Errorf("missing '>' for <%s", s)
+		}
+		field := s[:n]
+		s = s[n+1:]
+
+		if field == "_" || field == "*" {
+			field = ""
+		}
+		steps = append(steps, patternStep{
+			prefix: html.UnescapeString(prefix),
+			field:  field,
+		})
+	}
+}
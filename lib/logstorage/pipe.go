@@ -2,6 +2,9 @@ package logstorage
 
 import (
 	"fmt"
+	"sync/atomic"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/memory"
 )
 
 type pipe interface {
@@ -19,7 +22,9 @@ type pipe interface {
 	// It is OK to continue processing pipeProcessor calls if they take less than a few milliseconds.
 	//
 	// The returned pipeProcessor may call cancel() at any time in order to notify worker goroutines to stop sending new data to pipeProcessor.
-	newPipeProcessor(workersCount int, stopCh <-chan struct{}, cancel func(), ppBase pipeProcessor) pipeProcessor
+	//
+	// Pipe processors, which keep state in memory, must take the memory for the state from sb, which is shared among all the pipes in the query.
+	newPipeProcessor(workersCount int, stopCh <-chan struct{}, cancel func(), sb *stateSizeBudget, ppBase pipeProcessor) pipeProcessor
 }
 
 // pipeProcessor must process a single pipe.
@@ -61,6 +66,56 @@ func (dpp defaultPipeProcessor) flush() error {
 	return nil
 }
 
+var maxMemoryPerQuery atomic.Int64
+
+// SetMaxMemoryPerQuery sets the maximum memory in bytes, which can be used for the state of all the stats, sort, uniq and stream_label_* pipes in a single query.
+//
+// If maxMemory <= 0, then the limit is calculated from the allowed memory.
+func SetMaxMemoryPerQuery(maxMemory int64) {
+	maxMemoryPerQuery.Store(maxMemory)
+}
+
+// stateSizeBudget is the memory budget shared among the states of all the pipes in a single query.
+type stateSizeBudget struct {
+	// maxSize is the maximum state size in bytes for all the pipes in the query.
+	maxSize int64
+
+	// remaining is the remaining budget in bytes. It is decreased by pipe processors in chunks.
+	remaining atomic.Int64
+}
+
+// newStateSizeBudget returns new state size budget for a single query.
+func newStateSizeBudget() *stateSizeBudget {
+	maxSize := maxMemoryPerQuery.Load()
+	if maxSize <= 0 {
+		maxSize = int64(float64(memory.Allowed()) * 0.3)
+	}
+	sb := &stateSizeBudget{
+		maxSize: maxSize,
+	}
+	sb.remaining.Store(maxSize)
+	return sb
+}
+
+// getStateSizeBudgetChunk returns the size of chunks, which are passed from the maxStateSize budget to per-worker budgets.
+//
+// Smaller chunks are used for small maxStateSize, so every worker could obtain its share of the budget.
+func getStateSizeBudgetChunk(maxStateSize int64, workersCount int) int64 {
+	n := maxStateSize / int64(4*workersCount)
+	if n > stateSizeBudgetChunk {
+		n = stateSizeBudgetChunk
+	}
+	if n < 1 {
+		n = 1
+	}
+	return n
+}
+
+func newStateSizeLimitError(p pipe, maxStateSize int64) error {
+	return fmt.Errorf("cannot calculate [%s], since it requires more than %d bytes of memory; possible solutions: to select lower number of logs "+
+		"via more specific filters or smaller time range; to increase -search.maxMemoryPerQuery command-line flag value", p, maxStateSize)
+}
+
 func parsePipes(lex *lexer) ([]pipe, error) {
 	var pipes []pipe
 	for !lex.isKeyword(")", "") {
@@ -71,7 +126,7 @@ func parsePipes(lex *lexer) ([]pipe, error) {
 			return nil, fmt.Errorf("missing token after '|'")
 		}
 		switch {
-		case lex.isKeyword("stats"):
+		case lex.isKeyword("stats"), lex.allowRemotePipes && lex.isKeyword("stats_remote"):
 			ps, err := parsePipeStats(lex)
 			if err != nil {
 				return nil, fmt.Errorf("cannot parse 'stats' pipe: %w", err)
@@ -101,6 +156,18 @@ func parsePipes(lex *lexer) ([]pipe, error) {
 				return nil, fmt.Errorf("cannot parse 'offset' pipe: %w", err)
 			}
 			pipes = append(pipes, ps)
+		case lex.isKeyword("field_names"):
+			pf, err := parsePipeFieldNames(lex)
+			if err != nil {
+				return nil, fmt.Errorf("cannot parse 'field_names' pipe: %w", err)
+			}
+			pipes = append(pipes, pf)
+		case lex.isKeyword("stream_label_names", "stream_label_values"):
+			pl, err := parsePipeStreamLabels(lex)
+			if err != nil {
+				return nil, fmt.Errorf("cannot parse 'stream_label_names' or 'stream_label_values' pipe: %w", err)
+			}
+			pipes = append(pipes, pl)
 		case lex.isKeyword("fields"):
 			pf, err := parsePipeFields(lex)
 			if err != nil {
@@ -119,6 +186,42 @@ func parsePipes(lex *lexer) ([]pipe, error) {
 				return nil, fmt.Errorf("cannot parse 'rename' pipe: %w", err)
 			}
 			pipes = append(pipes, pr)
+		case lex.isKeyword("filter", "where"):
+			pf, err := parsePipeFilter(lex)
+			if err != nil {
+				return nil, fmt.Errorf("cannot parse 'filter' pipe: %w", err)
+			}
+			pipes = append(pipes, pf)
+		case lex.isKeyword("extract"):
+			pe, err := parsePipeExtract(lex)
+			if err != nil {
+				return nil, fmt.Errorf("cannot parse 'extract' pipe: %w", err)
+			}
+			pipes = append(pipes, pe)
+		case lex.isKeyword("format"):
+			pf, err := parsePipeFormat(lex)
+			if err != nil {
+				return nil, fmt.Errorf("cannot parse 'format' pipe: %w", err)
+			}
+			pipes = append(pipes, pf)
+		case lex.isKeyword("math"):
+			pm, err := parsePipeMath(lex)
+			if err != nil {
+				return nil, fmt.Errorf("cannot parse 'math' pipe: %w", err)
+			}
+			pipes = append(pipes, pm)
+		case lex.isKeyword("unpack_json"):
+			pu, err := parsePipeUnpackJSON(lex)
+			if err != nil {
+				return nil, fmt.Errorf("cannot parse 'unpack_json' pipe: %w", err)
+			}
+			pipes = append(pipes, pu)
+		case lex.isKeyword("unpack_logfmt"):
+			pu, err := parsePipeUnpackLogfmt(lex)
+			if err != nil {
+				return nil, fmt.Errorf("cannot parse 'unpack_logfmt' pipe: %w", err)
+			}
+			pipes = append(pipes, pu)
 		case lex.isKeyword("delete", "del", "rm"):
 			pd, err := parsePipeDelete(lex)
 			if err != nil {
@@ -58,7 +58,7 @@ func (pc *pipeCopy) updateNeededFields(neededFields, unneededFields fieldsSet) {
 	}
 }
 
-func (pc *pipeCopy) newPipeProcessor(_ int, _ <-chan struct{}, _ func(), ppBase pipeProcessor) pipeProcessor {
+func (pc *pipeCopy) newPipeProcessor(_ int, _ <-chan struct{}, _ func(), _ *stateSizeBudget, ppBase pipeProcessor) pipeProcessor {
 	return &pipeCopyProcessor{
 		pc:     pc,
 		ppBase: ppBase,
@@ -32,7 +32,7 @@ func (pd *pipeDelete) updateNeededFields(neededFields, unneededFields fieldsSet)
 	}
 }
 
-func (pd *pipeDelete) newPipeProcessor(_ int, _ <-chan struct{}, _ func(), ppBase pipeProcessor) pipeProcessor {
+func (pd *pipeDelete) newPipeProcessor(_ int, _ <-chan struct{}, _ func(), _ *stateSizeBudget, ppBase pipeProcessor) pipeProcessor {
 	return &pipeDeleteProcessor{
 		pd:     pd,
 		ppBase: ppBase,
@@ -0,0 +1,182 @@
+package logstorage
+
+import (
+	"fmt"
+	"unsafe"
+)
+
+// pipeExtract processes '| extract "pattern" from ...' pipe.
+//
+// See https://docs.victoriametrics.com/victorialogs/logsql/#extract-pipe
+type pipeExtract struct {
+	fromField string
+	ptn       *pattern
+
+	patternStr string
+}
+
+func (pe *pipeExtract) String() string {
+	s := "extract " + quoteTokenIfNeeded(pe.patternStr)
+	if !isMsgFieldName(pe.fromField) {
+		s += " from " + quoteTokenIfNeeded(pe.fromField)
+	}
+	return s
+}
+
+func (pe *pipeExtract) updateNeededFields(neededFields, unneededFields fieldsSet) {
+	if neededFields.contains("*") {
+		needFromField := false
+		for _, f := range pe.ptn.fields {
+			if !unneededFields.contains(f.name) {
+				needFromField = true
+			}
+		}
+		for _, f := range pe.ptn.fields {
+			unneededFields.add(f.name)
+		}
+		if needFromField {
+			unneededFields.remove(pe.fromField)
+		}
+	} else {
+		needFromField := false
+		for _, f := range pe.ptn.fields {
+			if neededFields.contains(f.name) {
+				needFromField = true
+				neededFields.remove(f.name)
+			}
+		}
+		if needFromField {
+			neededFields.add(pe.fromField)
+		}
+	}
+}
+
+func (pe *pipeExtract) newPipeProcessor(workersCount int, _ <-chan struct{}, _ func(), _ *stateSizeBudget, ppBase pipeProcessor) pipeProcessor {
+	shards := make([]pipeExtractProcessorShard, workersCount)
+	for i := range shards {
+		ptn := pe.ptn.clone()
+		rcs := make([]resultColumn, len(ptn.fields))
+		for j := range rcs {
+			rcs[j].name = ptn.fields[j].name
+		}
+		shards[i] = pipeExtractProcessorShard{
+			pipeExtractProcessorShardNopad: pipeExtractProcessorShardNopad{
+				ptn: ptn,
+				rcs: rcs,
+			},
+		}
+	}
+
+	pep := &pipeExtractProcessor{
+		pe:     pe,
+		ppBase: ppBase,
+
+		shards: shards,
+	}
+	return pep
+}
+
+type pipeExtractProcessor struct {
+	pe     *pipeExtract
+	ppBase pipeProcessor
+
+	shards []pipeExtractProcessorShard
+}
+
+type pipeExtractProcessorShard struct {
+	pipeExtractProcessorShardNopad
+
+	// The padding prevents false sharing on widespread platforms with 128 mod (cache line size) = 0 .
+	_ [128 - unsafe.Sizeof(pipeExtractProcessorShardNopad{})%128]byte
+}
+
+type pipeExtractProcessorShardNopad struct {
+	// ptn is the pattern for extracting fields; it is cloned per shard, since it holds matches state.
+	ptn *pattern
+
+	// rcs holds the extracted values for the fields in ptn.
+	rcs []resultColumn
+}
+
+func (pep *pipeExtractProcessor) writeBlock(workerID uint, br *blockResult) {
+	if len(br.timestamps) == 0 {
+		return
+	}
+
+	shard := &pep.shards[workerID]
+	ptn := shard.ptn
+	rcs := shard.rcs
+
+	c := br.getColumnByNameIfExists(pep.pe.fromField)
+	if c == nil || c.isConst {
+		v := ""
+		if c != nil {
+			v = c.encodedValues[0]
+		}
+		ptn.apply(v)
+		for i, f := range ptn.fields {
+			for range br.timestamps {
+				rcs[i].addValue(*f.value)
+			}
+		}
+	} else {
+		values := c.getValues(br)
+		for i, v := range values {
+			if i == 0 || values[i-1] != v {
+				ptn.apply(v)
+			}
+			for j, f := range ptn.fields {
+				rcs[j].addValue(*f.value)
+			}
+		}
+	}
+
+	for i := range rcs {
+		br.addResultColumn(&rcs[i])
+	}
+	pep.ppBase.writeBlock(workerID, br)
+
+	for i := range rcs {
+		rcs[i].resetKeepName()
+	}
+}
+
+func (pep *pipeExtractProcessor) flush() error {
+	return nil
+}
+
+func parsePipeExtract(lex *lexer) (*pipeExtract, error) {
+	if !lex.isKeyword("extract") {
+		return nil, fmt.Errorf("unexpected token: %q; want %q", lex.token, "extract")
+	}
+	lex.nextToken()
+
+	// parse pattern
+	if lex.isKeyword("|", ")", "") {
+		return nil, fmt.Errorf("missing pattern")
+	}
+	patternStr := lex.token
+	ptn, err := parsePattern(patternStr)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse 'pattern' %q: %w", patternStr, err)
+	}
+	lex.nextToken()
+
+	// parse optional 'from ...' part
+	fromField := "_msg"
+	if lex.isKeyword("from") {
+		lex.nextToken()
+		f, err := parseFieldName(lex)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse 'from' field name: %w", err)
+		}
+		fromField = f
+	}
+
+	pe := &pipeExtract{
+		fromField:  fromField,
+		ptn:        ptn,
+		patternStr: patternStr,
+	}
+	return pe, nil
+}
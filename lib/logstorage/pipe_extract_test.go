@@ -0,0 +1,55 @@
+package logstorage
+
+import (
+	"testing"
+)
+
+func TestPipeExtractUpdateNeededFields(t *testing.T) {
+	f := func(s string, neededFields, unneededFields, neededFieldsExpected, unneededFieldsExpected string) {
+		t.Helper()
+
+		nfs := newTestFieldsSet(neededFields)
+		unfs := newTestFieldsSet(unneededFields)
+
+		lex := newLexer(s)
+		p, err := parsePipeExtract(lex)
+		if err != nil {
+			t.Fatalf("cannot parse %s: %s", s, err)
+		}
+		p.updateNeededFields(nfs, unfs)
+
+		assertNeededFields(t, nfs, unfs, neededFieldsExpected, unneededFieldsExpected)
+	}
+
+	// all the needed fields
+	f(`extract "<foo>bar<baz>" from x`, "*", "", "*", "baz,foo")
+
+	// all the needed fields, unneeded fields do not intersect with fromField and output fields
+	f(`extract "<foo>bar<baz>" from x`, "*", "f1,f2", "*", "baz,f1,f2,foo")
+
+	// all the needed fields, unneeded fields intersect with fromField
+	f(`extract "<foo>bar<baz>" from x`, "*", "f2,x", "*", "baz,f2,foo")
+
+	// all the needed fields, unneeded fields intersect with output fields
+	f(`extract "<foo>bar<baz>" from x`, "*", "f2,foo", "*", "baz,f2,foo")
+
+	// all the needed fields, unneeded fields intersect with all the output fields
+	f(`extract "<foo>bar<baz>" from x`, "*", "f2,foo,baz", "*", "baz,f2,foo")
+	f(`extract "<foo>bar<baz>" from x`, "*", "f2,foo,baz,x", "*", "baz,f2,foo,x")
+
+	// needed fields do not intersect with fromField and output fields
+	f(`extract "<foo>bar<baz>" from x`, "f1,f2", "", "f1,f2", "")
+
+	// needed fields intersect with fromField
+	f(`extract "<foo>bar<baz>" from x`, "f2,x", "", "f2,x", "")
+
+	// needed fields intersect with output fields
+	f(`extract "<foo>bar<baz>" from x`, "f2,foo", "", "f2,x", "")
+
+	// needed fields intersect with fromField and output fields
+	f(`extract "<foo>bar<baz>" from x`, "f2,foo,x,y", "", "f2,x,y", "")
+
+	// the output field matches fromField
+	f(`extract "<x>bar<baz>" from x`, "*", "", "*", "baz")
+	f(`extract "<x>bar<baz>" from x`, "x", "", "x", "")
+}
@@ -0,0 +1,193 @@
+package logstorage
+
+import (
+	"fmt"
+	"strings"
+	"unsafe"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
+)
+
+// pipeFieldNames processes '| field_names' pipe.
+//
+// See https://docs.victoriametrics.com/victorialogs/logsql/#field_names-pipe
+type pipeFieldNames struct {
+	// resultName is the name of the column to write results to.
+	resultName string
+}
+
+func (pf *pipeFieldNames) String() string {
+	s := "field_names"
+	if pf.resultName != "name" {
+		s += " as " + quoteTokenIfNeeded(pf.resultName)
+	}
+	return s
+}
+
+func (pf *pipeFieldNames) updateNeededFields(neededFields, unneededFields fieldsSet) {
+	neededFields.reset()
+	neededFields.add("*")
+	unneededFields.reset()
+}
+
+func (pf *pipeFieldNames) newPipeProcessor(workersCount int, stopCh <-chan struct{}, _ func(), _ *stateSizeBudget, ppBase pipeProcessor) pipeProcessor {
+	shards := make([]pipeFieldNamesProcessorShard, workersCount)
+	for i := range shards {
+		shard := &shards[i]
+		shard.m = make(map[string]*uint64)
+	}
+
+	pfp := &pipeFieldNamesProcessor{
+		pf:     pf,
+		stopCh: stopCh,
+		ppBase: ppBase,
+
+		shards: shards,
+	}
+	return pfp
+}
+
+type pipeFieldNamesProcessor struct {
+	pf     *pipeFieldNames
+	stopCh <-chan struct{}
+	ppBase pipeProcessor
+
+	shards []pipeFieldNamesProcessorShard
+}
+
+type pipeFieldNamesProcessorShard struct {
+	pipeFieldNamesProcessorShardNopad
+
+	// The padding prevents false sharing on widespread platforms with 128 mod (cache line size) = 0 .
+	_ [128 - unsafe.Sizeof(pipeFieldNamesProcessorShardNopad{})%128]byte
+}
+
+type pipeFieldNamesProcessorShardNopad struct {
+	// m holds the number of hits per each field name.
+	m map[string]*uint64
+}
+
+func (shard *pipeFieldNamesProcessorShard) updateState(name string, hits uint64) {
+	pHits, ok := shard.m[name]
+	if !ok {
+		nameCopy := strings.Clone(name)
+		pHits = new(uint64)
+		shard.m[nameCopy] = pHits
+	}
+	*pHits += hits
+}
+
+func (pfp *pipeFieldNamesProcessor) writeBlock(workerID uint, br *blockResult) {
+	if len(br.timestamps) == 0 {
+		return
+	}
+
+	shard := &pfp.shards[workerID]
+	for _, c := range br.getColumns() {
+		if hits := c.getNonEmptyValuesCount(br); hits > 0 {
+			shard.updateState(c.name, hits)
+		}
+	}
+}
+
+func (pfp *pipeFieldNamesProcessor) flush() error {
+	if needStop(pfp.stopCh) {
+		return nil
+	}
+
+	// merge state across shards
+	shards := pfp.shards
+	m := shards[0].m
+	shards = shards[1:]
+	for i := range shards {
+		for name, pHitsSrc := range shards[i].m {
+			pHits, ok := m[name]
+			if !ok {
+				m[name] = pHitsSrc
+			} else {
+				*pHits += *pHitsSrc
+			}
+		}
+	}
+
+	// write result
+	wctx := &pipeFieldNamesWriteContext{
+		pfp: pfp,
+	}
+	wctx.rcs[0].name = pfp.pf.resultName
+	wctx.rcs[1].name = "hits"
+
+	var hitsBuf []byte
+	for name, pHits := range m {
+		hitsBuf = marshalUint64(hitsBuf[:0], *pHits)
+		wctx.writeRow(name, bytesutil.ToUnsafeString(hitsBuf))
+	}
+	wctx.flush()
+
+	return nil
+}
+
+type pipeFieldNamesWriteContext struct {
+	pfp *pipeFieldNamesProcessor
+	rcs [2]resultColumn
+	br  blockResult
+
+	valuesLen int
+}
+
+func (wctx *pipeFieldNamesWriteContext) writeRow(name, hits string) {
+	wctx.rcs[0].addValue(name)
+	wctx.rcs[1].addValue(hits)
+	wctx.valuesLen += len(name) + len(hits)
+	if wctx.valuesLen >= 1_000_000 {
+		wctx.flush()
+	}
+}
+
+func (wctx *pipeFieldNamesWriteContext) flush() {
+	br := &wctx.br
+
+	wctx.valuesLen = 0
+
+	if len(wctx.rcs[0].values) == 0 {
+		return
+	}
+
+	// Flush rcs to ppBase
+	br.setResultColumns(wctx.rcs[:])
+	wctx.pfp.ppBase.writeBlock(0, br)
+	br.reset()
+	for i := range wctx.rcs {
+		wctx.rcs[i].resetKeepName()
+	}
+}
+
+func parsePipeFieldNames(lex *lexer) (*pipeFieldNames, error) {
+	if !lex.isKeyword("field_names") {
+		return nil, fmt.Errorf("expecting 'field_names'; got %q", lex.token)
+	}
+	lex.nextToken()
+
+	resultName := "name"
+	if lex.isKeyword("as") {
+		lex.nextToken()
+		if lex.isKeyword("|", ")", "") {
+			return nil, fmt.Errorf("missing result name after 'as'")
+		}
+	}
+	if !lex.isKeyword("|", ")", "") {
+		name, err := parseFieldName(lex)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse result name: %w", err)
+		}
+		resultName = name
+	}
+	if resultName == "hits" {
+		return nil, fmt.Errorf("result name cannot be 'hits', since it is reserved for the number of hits per each field name")
+	}
+
+	pf := &pipeFieldNames{
+		resultName: resultName,
+	}
+	return pf, nil
+}
@@ -0,0 +1,35 @@
+package logstorage
+
+import (
+	"testing"
+)
+
+func TestPipeFieldNamesUpdateNeededFields(t *testing.T) {
+	f := func(s, neededFields, unneededFields, neededFieldsExpected, unneededFieldsExpected string) {
+		t.Helper()
+
+		nfs := newTestFieldsSet(neededFields)
+		unfs := newTestFieldsSet(unneededFields)
+
+		lex := newLexer(s)
+		p, err := parsePipeFieldNames(lex)
+		if err != nil {
+			t.Fatalf("cannot parse %s: %s", s, err)
+		}
+		p.updateNeededFields(nfs, unfs)
+
+		assertNeededFields(t, nfs, unfs, neededFieldsExpected, unneededFieldsExpected)
+	}
+
+	// all the needed fields
+	f("field_names", "*", "", "*", "")
+	f("field_names as foo", "*", "", "*", "")
+
+	// all the needed fields, plus unneeded fields
+	f("field_names", "*", "f1,f2", "*", "")
+	f("field_names as name", "*", "name,hits", "*", "")
+
+	// needed fields
+	f("field_names", "f1,f2", "", "*", "")
+	f("field_names as foo", "foo,hits", "", "*", "")
+}
@@ -49,7 +49,7 @@ func (pf *pipeFields) updateNeededFields(neededFields, unneededFields fieldsSet)
 	unneededFields.reset()
 }
 
-func (pf *pipeFields) newPipeProcessor(_ int, _ <-chan struct{}, _ func(), ppBase pipeProcessor) pipeProcessor {
+func (pf *pipeFields) newPipeProcessor(_ int, _ <-chan struct{}, _ func(), _ *stateSizeBudget, ppBase pipeProcessor) pipeProcessor {
 	return &pipeFieldsProcessor{
 		pf:     pf,
 		ppBase: ppBase,
@@ -0,0 +1,126 @@
+package logstorage
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPipeFilter(t *testing.T) {
+	f := func(pipeStr string, rows, rowsExpected [][]Field) {
+		t.Helper()
+
+		lex := newLexer(pipeStr)
+		pf, err := parsePipeFilter(lex)
+		if err != nil {
+			t.Fatalf("cannot parse %q: %s", pipeStr, err)
+		}
+
+		var rcs []resultColumn
+		for _, field := range rows[0] {
+			rcs = append(rcs, resultColumn{
+				name: field.Name,
+			})
+		}
+		for _, row := range rows {
+			for i, field := range row {
+				rcs[i].addValue(field.Value)
+			}
+		}
+
+		var br blockResult
+		br.setResultColumns(rcs)
+
+		var result [][]Field
+		pp := newDefaultPipeProcessor(func(_ uint, br *blockResult) {
+			cs := br.getColumns()
+			for i := range br.timestamps {
+				var row []Field
+				for _, c := range cs {
+					row = append(row, Field{
+						Name:  c.name,
+						Value: c.getValueAtRow(br, i),
+					})
+				}
+				result = append(result, row)
+			}
+		})
+
+		pfp := pf.newPipeProcessor(1, nil, nil, nil, pp)
+		pfp.writeBlock(0, &br)
+		if err := pfp.flush(); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		if !reflect.DeepEqual(result, rowsExpected) {
+			t.Fatalf("unexpected result for %q;\ngot\n%v\nwant\n%v", pipeStr, result, rowsExpected)
+		}
+	}
+
+	rows := [][]Field{
+		{{"_msg", "foo bar"}, {"host", "a"}, {"n", "1"}},
+		{{"_msg", "baz"}, {"host", "b"}, {"n", "12"}},
+		{{"_msg", "foo baz"}, {"host", "a"}, {"n", "123"}},
+	}
+
+	// all the rows match
+	f("filter *", rows, rows)
+	f("filter host:in(a, b)", rows, rows)
+
+	// no rows match
+	f("filter missing:foo", rows, nil)
+	f("filter n:range(1000, inf)", rows, nil)
+
+	// some rows match
+	f("filter foo", rows, [][]Field{
+		{{"_msg", "foo bar"}, {"host", "a"}, {"n", "1"}},
+		{{"_msg", "foo baz"}, {"host", "a"}, {"n", "123"}},
+	})
+	f("where n:range[10, 100] or bar", rows, [][]Field{
+		{{"_msg", "foo bar"}, {"host", "a"}, {"n", "1"}},
+		{{"_msg", "baz"}, {"host", "b"}, {"n", "12"}},
+	})
+	f("filter !host:a", rows, [][]Field{
+		{{"_msg", "baz"}, {"host", "b"}, {"n", "12"}},
+	})
+	f(`filter host:a n:re("^1.$") or len_range(0, 3)`, rows, [][]Field{
+		{{"_msg", "baz"}, {"host", "b"}, {"n", "12"}},
+	})
+	f(`filter missing:""`, rows, rows)
+}
+
+func TestPipeFilterUpdateNeededFields(t *testing.T) {
+	f := func(s, neededFields, unneededFields, neededFieldsExpected, unneededFieldsExpected string) {
+		t.Helper()
+
+		nfs := newTestFieldsSet(neededFields)
+		unfs := newTestFieldsSet(unneededFields)
+
+		lex := newLexer(s)
+		p, err := parsePipeFilter(lex)
+		if err != nil {
+			t.Fatalf("cannot parse %s: %s", s, err)
+		}
+		p.updateNeededFields(nfs, unfs)
+
+		assertNeededFields(t, nfs, unfs, neededFieldsExpected, unneededFieldsExpected)
+	}
+
+	// all the needed fields
+	f("filter foo:bar", "*", "", "*", "")
+	f("filter foo:bar baz", "*", "", "*", "")
+
+	// all the needed fields, unneeded fields do not intersect with filter fields
+	f("filter foo:bar", "*", "f1,f2", "*", "f1,f2")
+
+	// all the needed fields, unneeded fields intersect with filter fields
+	f("filter foo:bar or x:y", "*", "f1,foo,x", "*", "f1")
+	f("filter _time:5m", "*", "_time,f1", "*", "f1")
+
+	// needed fields do not intersect with filter fields
+	f("filter foo:bar", "f1,f2", "", "f1,f2,foo", "")
+	f("filter foo:bar baz", "f1,f2", "", "_msg,f1,f2,foo", "")
+	f(`filter _stream:{a="b"}`, "f1", "", "_stream,f1", "")
+
+	// needed fields intersect with filter fields
+	f("filter foo:bar !x:y", "f1,foo", "", "f1,foo,x", "")
+}
@@ -0,0 +1,200 @@
+package logstorage
+
+import (
+	"fmt"
+	"strconv"
+	"unsafe"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
+)
+
+// pipeFormat processes '| format ...' pipe.
+//
+// See https://docs.victoriametrics.com/victorialogs/logsql/#format-pipe
+type pipeFormat struct {
+	formatStr string
+	steps     []patternStep
+
+	resultField string
+}
+
+func (pf *pipeFormat) String() string {
+	s := "format " + quoteTokenIfNeeded(pf.formatStr)
+	if !isMsgFieldName(pf.resultField) {
+		s += " as " + quoteTokenIfNeeded(pf.resultField)
+	}
+	return s
+}
+
+func (pf *pipeFormat) updateNeededFields(neededFields, unneededFields fieldsSet) {
+	if neededFields.contains("*") {
+		if !unneededFields.contains(pf.resultField) {
+			unneededFields.add(pf.resultField)
+			for _, step := range pf.steps {
+				if step.field != "" {
+					unneededFields.remove(getFormatFieldName(step.field))
+				}
+			}
+		}
+	} else {
+		if neededFields.contains(pf.resultField) {
+			neededFields.remove(pf.resultField)
+			for _, step := range pf.steps {
+				if step.field != "" {
+					neededFields.add(getFormatFieldName(step.field))
+				}
+			}
+		}
+	}
+}
+
+func (pf *pipeFormat) newPipeProcessor(workersCount int, _ <-chan struct{}, _ func(), _ *stateSizeBudget, ppBase pipeProcessor) pipeProcessor {
+	shards := make([]pipeFormatProcessorShard, workersCount)
+	for i := range shards {
+		shards[i].rc.name = pf.resultField
+	}
+
+	pfp := &pipeFormatProcessor{
+		pf:     pf,
+		ppBase: ppBase,
+
+		shards: shards,
+	}
+	return pfp
+}
+
+type pipeFormatProcessor struct {
+	pf     *pipeFormat
+	ppBase pipeProcessor
+
+	shards []pipeFormatProcessorShard
+}
+
+type pipeFormatProcessorShard struct {
+	pipeFormatProcessorShardNopad
+
+	// The padding prevents false sharing on widespread platforms with 128 mod (cache line size) = 0 .
+	_ [128 - unsafe.Sizeof(pipeFormatProcessorShardNopad{})%128]byte
+}
+
+type pipeFormatProcessorShardNopad struct {
+	// columnValues holds the values for every placeholder in pf.steps.
+	columnValues [][]string
+
+	// rc holds the formatted results.
+	rc resultColumn
+
+	// buf is a temporary buffer for the formatted result.
+	buf []byte
+}
+
+func (pfp *pipeFormatProcessor) writeBlock(workerID uint, br *blockResult) {
+	if len(br.timestamps) == 0 {
+		return
+	}
+
+	shard := &pfp.shards[workerID]
+	steps := pfp.pf.steps
+
+	columnValues := shard.columnValues[:0]
+	for _, step := range steps {
+		var values []string
+		if step.field != "" {
+			c := br.getColumnByNameIfExists(getFormatFieldName(step.field))
+			if c != nil {
+				values = c.getValues(br)
+			}
+		}
+		columnValues = append(columnValues, values)
+	}
+	shard.columnValues = columnValues
+
+	rc := &shard.rc
+	for rowIdx := range br.timestamps {
+		v := shard.formatRow(steps, rowIdx)
+		rc.addValue(v)
+	}
+
+	br.addResultColumn(rc)
+	pfp.ppBase.writeBlock(workerID, br)
+
+	clear(shard.columnValues)
+	rc.resetKeepName()
+}
+
+func (shard *pipeFormatProcessorShard) formatRow(steps []patternStep, rowIdx int) string {
+	b := shard.buf[:0]
+	for i, step := range steps {
+		b = append(b, step.prefix...)
+		if step.field == "" {
+			continue
+		}
+		v := ""
+		if values := shard.columnValues[i]; values != nil {
+			v = values[rowIdx]
+		}
+		if isFormatQuotedField(step.field) {
+			b = strconv.AppendQuote(b, v)
+		} else {
+			b = append(b, v...)
+		}
+	}
+	shard.buf = b
+
+	return bytesutil.ToUnsafeString(b)
+}
+
+func (pfp *pipeFormatProcessor) flush() error {
+	return nil
+}
+
+// isFormatQuotedField returns true if the field value must be quoted in the format pipe output.
+//
+// Such fields are set via '<q:field_name>' placeholder.
+func isFormatQuotedField(s string) bool {
+	return len(s) > len("q:") && s[:len("q:")] == "q:"
+}
+
+// getFormatFieldName returns the field name for the '<field_name>' or '<q:field_name>' placeholder.
+func getFormatFieldName(s string) string {
+	if isFormatQuotedField(s) {
+		s = s[len("q:"):]
+	}
+	return getCanonicalColumnName(s)
+}
+
+func parsePipeFormat(lex *lexer) (*pipeFormat, error) {
+	if !lex.isKeyword("format") {
+		return nil, fmt.Errorf("unexpected token: %q; want %q", lex.token, "format")
+	}
+	lex.nextToken()
+
+	// parse format
+	if lex.isKeyword("|", ")", "") {
+		return nil, fmt.Errorf("missing format")
+	}
+	formatStr := lex.token
+	steps, err := parsePatternSteps(formatStr)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse 'format' %q: %w", formatStr, err)
+	}
+	lex.nextToken()
+
+	// parse optional 'as ...` part
+	resultField := "_msg"
+	if lex.isKeyword("as") {
+		lex.nextToken()
+		field, err := parseFieldName(lex)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse result field after 'format %q as': %w", formatStr, err)
+		}
+		resultField = field
+	}
+
+	pf := &pipeFormat{
+		formatStr:   formatStr,
+		steps:       steps,
+		resultField: resultField,
+	}
+	return pf, nil
+}
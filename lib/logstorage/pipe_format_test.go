@@ -0,0 +1,72 @@
+package logstorage
+
+import (
+	"testing"
+)
+
+func TestPipeFormat(t *testing.T) {
+	f := func(pipeStr string, rows, rowsExpected [][]Field) {
+		t.Helper()
+		expectPipeResults(t, pipeStr, rows, rowsExpected)
+	}
+
+	rows := [][]Field{
+		{{"_msg", "foo"}, {"host", "a"}, {"port", "80"}},
+		{{"_msg", "bar"}, {"host", "b"}, {"port", "443"}},
+	}
+
+	f(`format "<host>:<port>" as addr`, rows, [][]Field{
+		{{"_msg", "foo"}, {"host", "a"}, {"port", "80"}, {"addr", "a:80"}},
+		{{"_msg", "bar"}, {"host", "b"}, {"port", "443"}, {"addr", "b:443"}},
+	})
+	f(`format "host=<q:host> msg=<_msg> missing=<q:missing>"`, rows, [][]Field{
+		{{"host", "a"}, {"port", "80"}, {"_msg", `host="a" msg=foo missing=""`}},
+		{{"host", "b"}, {"port", "443"}, {"_msg", `host="b" msg=bar missing=""`}},
+	})
+	f(`format "&lt;<port>&gt;" as port`, rows, [][]Field{
+		{{"_msg", "foo"}, {"host", "a"}, {"port", "<80>"}},
+		{{"_msg", "bar"}, {"host", "b"}, {"port", "<443>"}},
+	})
+	f(`format "const" as x`, rows, [][]Field{
+		{{"_msg", "foo"}, {"host", "a"}, {"port", "80"}, {"x", "const"}},
+		{{"_msg", "bar"}, {"host", "b"}, {"port", "443"}, {"x", "const"}},
+	})
+}
+
+func TestPipeFormatUpdateNeededFields(t *testing.T) {
+	f := func(s, neededFields, unneededFields, neededFieldsExpected, unneededFieldsExpected string) {
+		t.Helper()
+
+		nfs := newTestFieldsSet(neededFields)
+		unfs := newTestFieldsSet(unneededFields)
+
+		lex := newLexer(s)
+		p, err := parsePipeFormat(lex)
+		if err != nil {
+			t.Fatalf("cannot parse %s: %s", s, err)
+		}
+		p.updateNeededFields(nfs, unfs)
+
+		assertNeededFields(t, nfs, unfs, neededFieldsExpected, unneededFieldsExpected)
+	}
+
+	// all the needed fields
+	f(`format "<a><b>" as x`, "*", "", "*", "x")
+	f(`format "<a>:<q:b>" as a`, "*", "", "*", "")
+
+	// all the needed fields, unneeded fields do not intersect with format fields
+	f(`format "<a>:<b>" as x`, "*", "f1,f2", "*", "f1,f2,x")
+
+	// all the needed fields, unneeded fields intersect with the result field
+	f(`format "<a>:<b>" as x`, "*", "a,x", "*", "a,x")
+
+	// all the needed fields, unneeded fields intersect with the source fields
+	f(`format "<a>:<b>" as x`, "*", "a,f1", "*", "f1,x")
+
+	// needed fields do not intersect with the result field
+	f(`format "<a>:<b>" as x`, "f1,f2", "", "f1,f2", "")
+
+	// needed fields intersect with the result field
+	f(`format "<a>:<q:b>" as x`, "f1,x", "", "a,b,f1", "")
+	f(`format "<_msg> <a>"`, "_msg", "", "_msg,a", "")
+}
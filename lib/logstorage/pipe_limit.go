@@ -19,7 +19,7 @@ func (pl *pipeLimit) String() string {
 func (pl *pipeLimit) updateNeededFields(_, _ fieldsSet) {
 }
 
-func (pl *pipeLimit) newPipeProcessor(_ int, _ <-chan struct{}, cancel func(), ppBase pipeProcessor) pipeProcessor {
+func (pl *pipeLimit) newPipeProcessor(_ int, _ <-chan struct{}, cancel func(), _ *stateSizeBudget, ppBase pipeProcessor) pipeProcessor {
 	if pl.n == 0 {
 		// Special case - notify the caller to stop writing data to the returned pipeLimitProcessor
 		cancel()
@@ -0,0 +1,721 @@
+package logstorage
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unsafe"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/slicesutil"
+)
+
+// pipeMath processes '| math ...' pipe.
+//
+// See https://docs.victoriametrics.com/victorialogs/logsql/#math-pipe
+type pipeMath struct {
+	entries []*mathEntry
+}
+
+type mathEntry struct {
+	// The calculated expr result is stored in resultField.
+	resultField string
+
+	// expr is the expression to calculate.
+	expr *mathExpr
+}
+
+type mathExpr struct {
+	// if isConst is set, then the given mathExpr returns the given constValue.
+	isConst    bool
+	constValue float64
+
+	// constValueStr is the original string representation of constValue.
+	//
+	// It is used in String() method for returning the original representation of the given constValue.
+	constValueStr string
+
+	// if fieldName isn't empty, then the given mathExpr fetches numeric values from the given fieldName.
+	fieldName string
+
+	// args are args for the given mathExpr.
+	args []*mathExpr
+
+	// op is the operation name (aka function name) for the given mathExpr.
+	op string
+
+	// f is the function for calculating results for the given mathExpr.
+	f mathFunc
+}
+
+// mathFunc must fill result with calculated results based on the given args.
+type mathFunc func(result []float64, args [][]float64)
+
+func (pm *pipeMath) String() string {
+	s := "math"
+	a := make([]string, len(pm.entries))
+	for i, e := range pm.entries {
+		a[i] = e.String()
+	}
+	s += " " + strings.Join(a, ", ")
+	return s
+}
+
+func (me *mathEntry) String() string {
+	return me.expr.String() + " as " + quoteTokenIfNeeded(me.resultField)
+}
+
+func (me *mathExpr) String() string {
+	if me.isConst {
+		return me.constValueStr
+	}
+	if me.fieldName != "" {
+		return quoteMathFieldNameIfNeeded(me.fieldName)
+	}
+
+	args := me.args
+
+	if isMathBinaryOp(me.op) {
+		opPriority := getMathBinaryOpPriority(me.op)
+		left := args[0]
+		right := args[1]
+		leftStr := left.String()
+		rightStr := right.String()
+		if isMathBinaryOp(left.op) {
+			leftPriority := getMathBinaryOpPriority(left.op)
+			if leftPriority < opPriority || leftPriority == opPriority && me.op == "^" {
+				leftStr = "(" + leftStr + ")"
+			}
+		} else if me.op == "^" && strings.HasPrefix(leftStr, "-") {
+			// '^' has higher priority than unary minus
+			leftStr = "(" + leftStr + ")"
+		}
+		if isMathBinaryOp(right.op) {
+			rightPriority := getMathBinaryOpPriority(right.op)
+			if rightPriority < opPriority || rightPriority == opPriority && me.op != "^" {
+				rightStr = "(" + rightStr + ")"
+			}
+		}
+		return leftStr + " " + me.op + " " + rightStr
+	}
+
+	if me.op == "unary_minus" {
+		argStr := args[0].String()
+		if isMathBinaryOp(args[0].op) {
+			argStr = "(" + argStr + ")"
+		}
+		return "-" + argStr
+	}
+
+	a := make([]string, len(args))
+	for i, arg := range args {
+		a[i] = arg.String()
+	}
+	argsStr := strings.Join(a, ", ")
+	return me.op + "(" + argsStr + ")"
+}
+
+func quoteMathFieldNameIfNeeded(s string) string {
+	if strings.Contains(s, "-") || isMathBinaryOp(s) || isMathConst(s) {
+		return strconv.Quote(s)
+	}
+	return quoteTokenIfNeeded(s)
+}
+
+func isMathBinaryOp(op string) bool {
+	_, ok := mathBinaryOps[op]
+	return ok
+}
+
+func getMathBinaryOpPriority(op string) int {
+	bo, ok := mathBinaryOps[op]
+	if !ok {
+		return -1
+	}
+	return bo.priority
+}
+
+func getMathFuncForBinaryOp(op string) mathFunc {
+	bo, ok := mathBinaryOps[op]
+	if !ok {
+		return nil
+	}
+	return bo.f
+}
+
+var mathBinaryOps = map[string]mathBinaryOp{
+	"^": {
+		priority: 3,
+		f:        mathFuncPow,
+	},
+	"*": {
+		priority: 2,
+		f:        mathFuncMul,
+	},
+	"/": {
+		priority: 2,
+		f:        mathFuncDiv,
+	},
+	"%": {
+		priority: 2,
+		f:        mathFuncMod,
+	},
+	"+": {
+		priority: 1,
+		f:        mathFuncPlus,
+	},
+	"-": {
+		priority: 1,
+		f:        mathFuncMinus,
+	},
+}
+
+type mathBinaryOp struct {
+	priority int
+	f        mathFunc
+}
+
+func (pm *pipeMath) updateNeededFields(neededFields, unneededFields fieldsSet) {
+	for i := len(pm.entries) - 1; i >= 0; i-- {
+		e := pm.entries[i]
+		if neededFields.contains("*") {
+			if !unneededFields.contains(e.resultField) {
+				unneededFields.add(e.resultField)
+
+				fs := newFieldsSet()
+				e.expr.updateNeededFields(fs)
+				for f := range fs {
+					unneededFields.remove(f)
+				}
+			}
+		} else {
+			if neededFields.contains(e.resultField) {
+				neededFields.remove(e.resultField)
+				e.expr.updateNeededFields(neededFields)
+			}
+		}
+	}
+}
+
+func (me *mathExpr) updateNeededFields(neededFields fieldsSet) {
+	if me.isConst {
+		return
+	}
+	if me.fieldName != "" {
+		neededFields.add(me.fieldName)
+		return
+	}
+	for _, arg := range me.args {
+		arg.updateNeededFields(neededFields)
+	}
+}
+
+func (pm *pipeMath) newPipeProcessor(workersCount int, _ <-chan struct{}, _ func(), _ *stateSizeBudget, ppBase pipeProcessor) pipeProcessor {
+	shards := make([]pipeMathProcessorShard, workersCount)
+	for i := range shards {
+		rcs := make([]resultColumn, len(pm.entries))
+		for j := range rcs {
+			rcs[j].name = pm.entries[j].resultField
+		}
+		shards[i].rcs = rcs
+	}
+
+	pmp := &pipeMathProcessor{
+		pm:     pm,
+		ppBase: ppBase,
+
+		shards: shards,
+	}
+	return pmp
+}
+
+type pipeMathProcessor struct {
+	pm     *pipeMath
+	ppBase pipeProcessor
+
+	shards []pipeMathProcessorShard
+}
+
+type pipeMathProcessorShard struct {
+	pipeMathProcessorShardNopad
+
+	// The padding prevents false sharing on widespread platforms with 128 mod (cache line size) = 0 .
+	_ [128 - unsafe.Sizeof(pipeMathProcessorShardNopad{})%128]byte
+}
+
+type pipeMathProcessorShardNopad struct {
+	// buf holds the string representation of the calculated results.
+	buf []byte
+
+	// rs holds temporary results calculated for mathExpr args.
+	rs [][]float64
+
+	// rcs holds the calculated results per every math entry.
+	rcs []resultColumn
+}
+
+func (shard *pipeMathProcessorShard) executeMathEntry(e *mathEntry, rc *resultColumn, br *blockResult) {
+	shard.rs = shard.rs[:0]
+	shard.executeExpr(e.expr, br)
+	r := shard.rs[0]
+
+	buf := shard.buf
+	for _, f := range r {
+		bufLen := len(buf)
+		buf = marshalFloat64(buf, f)
+		v := bytesutil.ToUnsafeString(buf[bufLen:])
+		rc.addValue(v)
+	}
+	shard.buf = buf[:0]
+}
+
+func (shard *pipeMathProcessorShard) executeExpr(me *mathExpr, br *blockResult) {
+	rIdx := len(shard.rs)
+	shard.rs = slicesutil.SetLength(shard.rs, len(shard.rs)+1)
+	shard.rs[rIdx] = slicesutil.SetLength(shard.rs[rIdx], len(br.timestamps))
+	r := shard.rs[rIdx]
+
+	if me.isConst {
+		for i := range r {
+			r[i] = me.constValue
+		}
+		return
+	}
+	if me.fieldName != "" {
+		c := br.getColumnByNameIfExists(me.fieldName)
+		if c == nil {
+			for i := range r {
+				r[i] = nan
+			}
+			return
+		}
+		values := c.getValues(br)
+		var f float64
+		for i, v := range values {
+			if i == 0 || v != values[i-1] {
+				f = parseMathNumber(v)
+			}
+			r[i] = f
+		}
+		return
+	}
+
+	rsLen := len(shard.rs)
+	for _, arg := range me.args {
+		shard.executeExpr(arg, br)
+	}
+	me.f(r, shard.rs[rsLen:])
+	shard.rs = shard.rs[:rsLen]
+}
+
+func (pmp *pipeMathProcessor) writeBlock(workerID uint, br *blockResult) {
+	if len(br.timestamps) == 0 {
+		return
+	}
+
+	shard := &pmp.shards[workerID]
+	for i, e := range pmp.pm.entries {
+		rc := &shard.rcs[i]
+		shard.executeMathEntry(e, rc, br)
+		// Add the result column to br immediately, so the next entries could refer to it.
+		br.addResultColumn(rc)
+	}
+
+	pmp.ppBase.writeBlock(workerID, br)
+
+	for i := range shard.rcs {
+		shard.rcs[i].resetKeepName()
+	}
+}
+
+func (pmp *pipeMathProcessor) flush() error {
+	return nil
+}
+
+func parsePipeMath(lex *lexer) (*pipeMath, error) {
+	if !lex.isKeyword("math") {
+		return nil, fmt.Errorf("unexpected token: %q; want %q", lex.token, "math")
+	}
+	lex.nextToken()
+
+	var mes []*mathEntry
+	for {
+		me, err := parseMathEntry(lex)
+		if err != nil {
+			return nil, err
+		}
+		mes = append(mes, me)
+
+		switch {
+		case lex.isKeyword(","):
+			lex.nextToken()
+		case lex.isKeyword("|", ")", ""):
+			pm := &pipeMath{
+				entries: mes,
+			}
+			return pm, nil
+		default:
+			return nil, fmt.Errorf("unexpected token after 'math' expression [%s]: %q; expecting ',', '|' or ')'", mes[len(mes)-1], lex.token)
+		}
+	}
+}
+
+func parseMathEntry(lex *lexer) (*mathEntry, error) {
+	me, err := parseMathExpr(lex)
+	if err != nil {
+		return nil, err
+	}
+
+	resultField, err := parseResultName(lex)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse result name for [%s]: %w", me, err)
+	}
+
+	e := &mathEntry{
+		resultField: resultField,
+		expr:        me,
+	}
+	return e, nil
+}
+
+// parseMathExpr parses math expression with binary operators '+', '-', '*', '/', '%' and '^', while respecting their priorities.
+func parseMathExpr(lex *lexer) (*mathExpr, error) {
+	return parseMathExprWithPriority(lex, 1)
+}
+
+func parseMathExprWithPriority(lex *lexer, minPriority int) (*mathExpr, error) {
+	if minPriority >= getMathBinaryOpPriority("^") {
+		// '^' is parsed separately, since it is right-associative and has higher priority than unary minus.
+		return parseMathExprUnary(lex)
+	}
+
+	left, err := parseMathExprWithPriority(lex, minPriority+1)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := lex.token
+		if lex.isQuotedToken() || getMathBinaryOpPriority(op) != minPriority {
+			return left, nil
+		}
+		lex.nextToken()
+
+		right, err := parseMathExprWithPriority(lex, minPriority+1)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse right operand after [%s %s]: %w", left, op, err)
+		}
+
+		left = &mathExpr{
+			args: []*mathExpr{left, right},
+			op:   op,
+			f:    getMathFuncForBinaryOp(op),
+		}
+	}
+}
+
+func parseMathExprUnary(lex *lexer) (*mathExpr, error) {
+	if !lex.isKeyword("-") {
+		return parseMathExprPow(lex)
+	}
+	lex.nextToken()
+
+	arg, err := parseMathExprUnary(lex)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse operand for unary minus: %w", err)
+	}
+	if arg.isConst {
+		// Fold the constant.
+		arg.constValue = -arg.constValue
+		arg.constValueStr = "-" + arg.constValueStr
+		return arg, nil
+	}
+	me := &mathExpr{
+		args: []*mathExpr{arg},
+		op:   "unary_minus",
+		f:    mathFuncUnaryMinus,
+	}
+	return me, nil
+}
+
+func parseMathExprPow(lex *lexer) (*mathExpr, error) {
+	base, err := parseMathExprOperand(lex)
+	if err != nil {
+		return nil, err
+	}
+	if !lex.isKeyword("^") {
+		return base, nil
+	}
+	lex.nextToken()
+
+	exponent, err := parseMathExprUnary(lex)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse exponent after [%s ^]: %w", base, err)
+	}
+	me := &mathExpr{
+		args: []*mathExpr{base, exponent},
+		op:   "^",
+		f:    mathFuncPow,
+	}
+	return me, nil
+}
+
+func parseMathExprOperand(lex *lexer) (*mathExpr, error) {
+	if lex.isKeyword("(") {
+		return parseMathExprInParens(lex)
+	}
+	if lex.isKeyword(",", ")", "|", "", "as") || !lex.isQuotedToken() && isMathBinaryOp(lex.token) {
+		return nil, fmt.Errorf("missing operand; got %q", lex.token)
+	}
+
+	if !lex.isQuotedToken() {
+		switch {
+		case lex.isKeyword("abs"):
+			return parseMathExprFunc(lex, "abs", 1, 1, mathFuncAbs)
+		case lex.isKeyword("exp"):
+			return parseMathExprFunc(lex, "exp", 1, 1, mathFuncExp)
+		case lex.isKeyword("ln"):
+			return parseMathExprFunc(lex, "ln", 1, 1, mathFuncLn)
+		case lex.isKeyword("max"):
+			return parseMathExprFunc(lex, "max", 2, -1, mathFuncMax)
+		case lex.isKeyword("min"):
+			return parseMathExprFunc(lex, "min", 2, -1, mathFuncMin)
+		case lex.isKeyword("round"):
+			return parseMathExprFunc(lex, "round", 1, 2, mathFuncRound)
+		}
+
+		if f, ok := tryParseMathConst(lex.token); ok {
+			me := &mathExpr{
+				isConst:       true,
+				constValue:    f,
+				constValueStr: lex.token,
+			}
+			lex.nextToken()
+			return me, nil
+		}
+	}
+
+	fieldName := getCanonicalColumnName(lex.token)
+	lex.nextToken()
+	me := &mathExpr{
+		fieldName: fieldName,
+	}
+	return me, nil
+}
+
+func parseMathExprInParens(lex *lexer) (*mathExpr, error) {
+	if !lex.isKeyword("(") {
+		return nil, fmt.Errorf("missing '('")
+	}
+	lex.nextToken()
+
+	me, err := parseMathExpr(lex)
+	if err != nil {
+		return nil, err
+	}
+	if !lex.isKeyword(")") {
+		return nil, fmt.Errorf("missing ')'; got %q instead", lex.token)
+	}
+	lex.nextToken()
+	return me, nil
+}
+
+func parseMathExprFunc(lex *lexer, funcName string, minArgs, maxArgs int, f mathFunc) (*mathExpr, error) {
+	if !lex.isKeyword(funcName) {
+		return nil, fmt.Errorf("missing %q keyword", funcName)
+	}
+	lex.nextToken()
+	if !lex.isKeyword("(") {
+		return nil, fmt.Errorf("missing '(' after %s", funcName)
+	}
+
+	var args []*mathExpr
+	for {
+		lex.nextToken()
+		if lex.isKeyword(")") {
+			lex.nextToken()
+			break
+		}
+		arg, err := parseMathExpr(lex)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse arg #%d for %s(): %w", len(args)+1, funcName, err)
+		}
+		args = append(args, arg)
+		if lex.isKeyword(")") {
+			lex.nextToken()
+			break
+		}
+		if !lex.isKeyword(",") {
+			return nil, fmt.Errorf("unexpected token after [%s] in %s(); got %q; want ',' or ')'", arg, funcName, lex.token)
+		}
+	}
+
+	if len(args) < minArgs {
+		return nil, fmt.Errorf("%s() must contain at least %d args; got %d args", funcName, minArgs, len(args))
+	}
+	if maxArgs > 0 && len(args) > maxArgs {
+		return nil, fmt.Errorf("%s() cannot contain more than %d args; got %d args", funcName, maxArgs, len(args))
+	}
+
+	me := &mathExpr{
+		args: args,
+		op:   funcName,
+		f:    f,
+	}
+	return me, nil
+}
+
+func isMathConst(s string) bool {
+	_, ok := tryParseMathConst(s)
+	return ok
+}
+
+// tryParseMathConst parses numeric constant s for math expression.
+//
+// It supports integers and floating-point numbers alongside duration and byte size suffixes.
+func tryParseMathConst(s string) (float64, bool) {
+	f, err := strconv.ParseFloat(s, 64)
+	if err == nil {
+		return f, true
+	}
+
+	// Try parsing s as integer.
+	// This handles 0x..., 0b... and 0... prefixes, alongside '_' delimiters, durations and byte sizes.
+	n, err := parseInt(s)
+	if err == nil {
+		return float64(n), true
+	}
+	return 0, false
+}
+
+// parseMathNumber parses s as a number for math calculations.
+//
+// NaN is returned if s cannot be parsed as a number.
+func parseMathNumber(s string) float64 {
+	f, ok := tryParseFloat64(s)
+	if ok {
+		return f
+	}
+	f, ok = tryParseMathConst(s)
+	if ok {
+		return f
+	}
+	return nan
+}
+
+func mathFuncPlus(result []float64, args [][]float64) {
+	a := args[0]
+	b := args[1]
+	for i := range result {
+		result[i] = a[i] + b[i]
+	}
+}
+
+func mathFuncMinus(result []float64, args [][]float64) {
+	a := args[0]
+	b := args[1]
+	for i := range result {
+		result[i] = a[i] - b[i]
+	}
+}
+
+func mathFuncMul(result []float64, args [][]float64) {
+	a := args[0]
+	b := args[1]
+	for i := range result {
+		result[i] = a[i] * b[i]
+	}
+}
+
+func mathFuncDiv(result []float64, args [][]float64) {
+	a := args[0]
+	b := args[1]
+	for i := range result {
+		result[i] = a[i] / b[i]
+	}
+}
+
+func mathFuncMod(result []float64, args [][]float64) {
+	a := args[0]
+	b := args[1]
+	for i := range result {
+		result[i] = math.Mod(a[i], b[i])
+	}
+}
+
+func mathFuncPow(result []float64, args [][]float64) {
+	a := args[0]
+	b := args[1]
+	for i := range result {
+		result[i] = math.Pow(a[i], b[i])
+	}
+}
+
+func mathFuncUnaryMinus(result []float64, args [][]float64) {
+	a := args[0]
+	for i := range result {
+		result[i] = -a[i]
+	}
+}
+
+func mathFuncAbs(result []float64, args [][]float64) {
+	a := args[0]
+	for i := range result {
+		result[i] = math.Abs(a[i])
+	}
+}
+
+func mathFuncExp(result []float64, args [][]float64) {
+	a := args[0]
+	for i := range result {
+		result[i] = math.Exp(a[i])
+	}
+}
+
+func mathFuncLn(result []float64, args [][]float64) {
+	a := args[0]
+	for i := range result {
+		result[i] = math.Log(a[i])
+	}
+}
+
+func mathFuncMax(result []float64, args [][]float64) {
+	for i := range result {
+		f := nan
+		for _, arg := range args {
+			if math.IsNaN(f) || arg[i] > f {
+				f = arg[i]
+			}
+		}
+		result[i] = f
+	}
+}
+
+func mathFuncMin(result []float64, args [][]float64) {
+	for i := range result {
+		f := nan
+		for _, arg := range args {
+			if math.IsNaN(f) || arg[i] < f {
+				f = arg[i]
+			}
+		}
+		result[i] = f
+	}
+}
+
+func mathFuncRound(result []float64, args [][]float64) {
+	arg := args[0]
+	if len(args) == 1 {
+		// Round to integer
+		for i := range result {
+			result[i] = math.Round(arg[i])
+		}
+		return
+	}
+
+	// Round to the nearest multiple of the second arg
+	nearest := args[1]
+	for i := range result {
+		result[i] = math.Round(arg[i]/nearest[i]) * nearest[i]
+	}
+}
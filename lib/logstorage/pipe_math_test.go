@@ -0,0 +1,116 @@
+package logstorage
+
+import (
+	"math"
+	"testing"
+)
+
+func TestPipeMath(t *testing.T) {
+	f := func(pipeStr string, rows, rowsExpected [][]Field) {
+		t.Helper()
+		expectPipeResults(t, pipeStr, rows, rowsExpected)
+	}
+
+	rows := [][]Field{
+		{{"a", "10"}, {"b", "3"}},
+		{{"a", "-2.5"}, {"b", "foo"}},
+		{{"a", "1KB"}, {"b", "0"}},
+	}
+
+	f("math a + b as c", rows, [][]Field{
+		{{"a", "10"}, {"b", "3"}, {"c", "13"}},
+		{{"a", "-2.5"}, {"b", "foo"}, {"c", "NaN"}},
+		{{"a", "1KB"}, {"b", "0"}, {"c", "1000"}},
+	})
+	f("math a / b as a", rows, [][]Field{
+		{{"b", "3"}, {"a", "3.3333333333333335"}},
+		{{"b", "foo"}, {"a", "NaN"}},
+		{{"b", "0"}, {"a", "+Inf"}},
+	})
+	f("math -a * 2 + 1 as x, x % 3 as y, abs(x) as z", rows, [][]Field{
+		{{"a", "10"}, {"b", "3"}, {"x", "-19"}, {"y", "-1"}, {"z", "19"}},
+		{{"a", "-2.5"}, {"b", "foo"}, {"x", "6"}, {"y", "0"}, {"z", "6"}},
+		{{"a", "1KB"}, {"b", "0"}, {"x", "-1999"}, {"y", "-1"}, {"z", "1999"}},
+	})
+	f("math 2 ^ 3 ^ 2 as x, max(a, b, 5) as y, min(a, b) as z", rows, [][]Field{
+		{{"a", "10"}, {"b", "3"}, {"x", "512"}, {"y", "10"}, {"z", "3"}},
+		{{"a", "-2.5"}, {"b", "foo"}, {"x", "512"}, {"y", "5"}, {"z", "-2.5"}},
+		{{"a", "1KB"}, {"b", "0"}, {"x", "512"}, {"y", "1000"}, {"z", "0"}},
+	})
+	f("math round(a / 3, 0.5) as x, round(missing) as y", rows, [][]Field{
+		{{"a", "10"}, {"b", "3"}, {"x", "3.5"}, {"y", "NaN"}},
+		{{"a", "-2.5"}, {"b", "foo"}, {"x", "-1"}, {"y", "NaN"}},
+		{{"a", "1KB"}, {"b", "0"}, {"x", "333.5"}, {"y", "NaN"}},
+	})
+}
+
+func TestPipeMathUpdateNeededFields(t *testing.T) {
+	f := func(s, neededFields, unneededFields, neededFieldsExpected, unneededFieldsExpected string) {
+		t.Helper()
+
+		nfs := newTestFieldsSet(neededFields)
+		unfs := newTestFieldsSet(unneededFields)
+
+		lex := newLexer(s)
+		p, err := parsePipeMath(lex)
+		if err != nil {
+			t.Fatalf("cannot parse %s: %s", s, err)
+		}
+		p.updateNeededFields(nfs, unfs)
+
+		assertNeededFields(t, nfs, unfs, neededFieldsExpected, unneededFieldsExpected)
+	}
+
+	// all the needed fields
+	f("math a + b as c", "*", "", "*", "c")
+	f("math a + 1 as a", "*", "", "*", "")
+
+	// all the needed fields, unneeded fields do not intersect with the math fields
+	f("math a + b as c", "*", "f1,f2", "*", "c,f1,f2")
+
+	// all the needed fields, unneeded fields intersect with the result field
+	f("math a + b as c", "*", "a,c", "*", "a,c")
+
+	// all the needed fields, unneeded fields intersect with the source fields
+	f("math a + b as c", "*", "a,b", "*", "c")
+
+	// needed fields do not intersect with the result field
+	f("math a + b as c", "f1,f2", "", "f1,f2", "")
+
+	// needed fields intersect with the result field
+	f("math a + b as c", "c,f1", "", "a,b,f1", "")
+	f("math a + 1 as a", "a", "", "a", "")
+
+	// the result field is used by the next entry
+	f("math a * 2 as b, b + c as d", "d", "", "a,c", "")
+	f("math a * 2 as b, b + c as d", "*", "", "*", "b,d")
+}
+
+func TestParseMathNumber(t *testing.T) {
+	f := func(s string, resultExpected float64) {
+		t.Helper()
+
+		result := parseMathNumber(s)
+		if math.IsNaN(resultExpected) {
+			if !math.IsNaN(result) {
+				t.Fatalf("unexpected result for parseMathNumber(%q); got %v; want NaN", s, result)
+			}
+			return
+		}
+		if result != resultExpected {
+			t.Fatalf("unexpected result for parseMathNumber(%q); got %v; want %v", s, result, resultExpected)
+		}
+	}
+
+	f("0", 0)
+	f("-123", -123)
+	f("1.5", 1.5)
+	f("1e3", 1000)
+	f("0x10", 16)
+	f("1_000", 1000)
+	f("2KiB", 2048)
+	f("1.5s", 1.5e9)
+	f("", nan)
+	f("foo", nan)
+	f("1.2.3.4", nan)
+}
@@ -8,11 +8,9 @@ import (
 	"strconv"
 	"strings"
 	"sync"
-	"sync/atomic"
 	"unsafe"
 
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
-	"github.com/VictoriaMetrics/VictoriaMetrics/lib/memory"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/stringsutil"
 )
 
@@ -68,22 +66,18 @@ func (ps *pipeSort) updateNeededFields(neededFields, unneededFields fieldsSet) {
 	}
 }
 
-func (ps *pipeSort) newPipeProcessor(workersCount int, stopCh <-chan struct{}, cancel func(), ppBase pipeProcessor) pipeProcessor {
+func (ps *pipeSort) newPipeProcessor(workersCount int, stopCh <-chan struct{}, cancel func(), sb *stateSizeBudget, ppBase pipeProcessor) pipeProcessor {
 	if ps.limit > 0 {
-		return newPipeTopkProcessor(ps, workersCount, stopCh, cancel, ppBase)
+		return newPipeTopkProcessor(ps, workersCount, stopCh, cancel, sb, ppBase)
 	}
-	return newPipeSortProcessor(ps, workersCount, stopCh, cancel, ppBase)
+	return newPipeSortProcessor(ps, workersCount, stopCh, cancel, sb, ppBase)
 }
 
-func newPipeSortProcessor(ps *pipeSort, workersCount int, stopCh <-chan struct{}, cancel func(), ppBase pipeProcessor) pipeProcessor {
-	maxStateSize := int64(float64(memory.Allowed()) * 0.2)
-
+func newPipeSortProcessor(ps *pipeSort, workersCount int, stopCh <-chan struct{}, cancel func(), sb *stateSizeBudget, ppBase pipeProcessor) pipeProcessor {
 	shards := make([]pipeSortProcessorShard, workersCount)
 	for i := range shards {
 		shard := &shards[i]
 		shard.ps = ps
-		shard.stateSizeBudget = stateSizeBudgetChunk
-		maxStateSize -= stateSizeBudgetChunk
 	}
 
 	psp := &pipeSortProcessor{
@@ -94,9 +88,9 @@ func newPipeSortProcessor(ps *pipeSort, workersCount int, stopCh <-chan struct{}
 
 		shards: shards,
 
-		maxStateSize: maxStateSize,
+		sb:          sb,
+		budgetChunk: getStateSizeBudgetChunk(sb.maxSize, workersCount),
 	}
-	psp.stateSizeBudget.Store(maxStateSize)
 
 	return psp
 }
@@ -109,8 +103,8 @@ type pipeSortProcessor struct {
 
 	shards []pipeSortProcessorShard
 
-	maxStateSize    int64
-	stateSizeBudget atomic.Int64
+	sb          *stateSizeBudget
+	budgetChunk int64
 }
 
 type pipeSortProcessorShard struct {
@@ -369,24 +363,24 @@ func (psp *pipeSortProcessor) writeBlock(workerID uint, br *blockResult) {
 
 	for shard.stateSizeBudget < 0 {
 		// steal some budget for the state size from the global budget.
-		remaining := psp.stateSizeBudget.Add(-stateSizeBudgetChunk)
+		remaining := psp.sb.remaining.Add(-psp.budgetChunk)
 		if remaining < 0 {
 			// The state size is too big. Stop processing data in order to avoid OOM crash.
-			if remaining+stateSizeBudgetChunk >= 0 {
+			if remaining+psp.budgetChunk >= 0 {
 				// Notify worker goroutines to stop calling writeBlock() in order to save CPU time.
 				psp.cancel()
 			}
 			return
 		}
-		shard.stateSizeBudget += stateSizeBudgetChunk
+		shard.stateSizeBudget += int(psp.budgetChunk)
 	}
 
 	shard.writeBlock(br)
 }
 
 func (psp *pipeSortProcessor) flush() error {
-	if n := psp.stateSizeBudget.Load(); n <= 0 {
-		return fmt.Errorf("cannot calculate [%s], since it requires more than %dMB of memory", psp.ps.String(), psp.maxStateSize/(1<<20))
+	if n := psp.sb.remaining.Load(); n <= 0 {
+		return newStateSizeLimitError(psp.ps, psp.sb.maxSize)
 	}
 
 	if needStop(psp.stopCh) {
@@ -4,13 +4,11 @@ import (
 	"fmt"
 	"slices"
 	"strings"
-	"sync/atomic"
 	"unsafe"
 
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/encoding"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
-	"github.com/VictoriaMetrics/VictoriaMetrics/lib/memory"
 )
 
 // pipeStats processes '| stats ...' queries.
@@ -25,8 +23,27 @@ type pipeStats struct {
 
 	// funcs contains stats functions to execute.
 	funcs []statsFunc
+
+	// mode is the mode for executing the pipe at cluster setup.
+	mode statsMode
 }
 
+// statsMode is the mode for executing pipeStats.
+type statsMode int
+
+const (
+	// statsModeDefault calculates stats over the input rows.
+	statsModeDefault = statsMode(0)
+
+	// statsModeExportState calculates stats over the input rows and returns marshaled states of stats functions instead of the final results.
+	//
+	// It is used at storage nodes, so the returned states could be merged via statsModeImportState.
+	statsModeExportState = statsMode(1)
+
+	// statsModeImportState merges the states returned by storage nodes in statsModeExportState mode and returns the final results.
+	statsModeImportState = statsMode(2)
+)
+
 type statsFunc interface {
 	// String returns string representation of statsFunc
 	String() string
@@ -60,10 +77,27 @@ type statsProcessor interface {
 
 	// finalizeStats must return the collected stats result from statsProcessor.
 	finalizeStats() string
+
+	// exportState must append the marshaled statsProcessor state to dst and return the result.
+	//
+	// The state is exported at storage nodes, so it could be merged with states from other storage nodes via importState.
+	exportState(dst []byte) []byte
+
+	// importState must merge the state marshaled via exportState at src into statsProcessor state.
+	//
+	// It must return the change of internal state size in bytes for the statsProcessor.
+	importState(src []byte) (int, error)
 }
 
 func (ps *pipeStats) String() string {
 	s := "stats "
+	switch ps.mode {
+	case statsModeExportState:
+		s = "stats_remote "
+	case statsModeImportState:
+		// This mode is used only for merging the results from storage nodes, so it cannot be parsed.
+		s = "stats_local "
+	}
 	if len(ps.byFields) > 0 {
 		a := make([]string, len(ps.byFields))
 		for i := range ps.byFields {
@@ -109,18 +143,39 @@ func (ps *pipeStats) updateNeededFields(neededFields, unneededFields fieldsSet)
 	unneededFields.reset()
 }
 
-const stateSizeBudgetChunk = 1 << 20
+// initRateFuncs initializes rate() and rate_sum() funcs at ps with the given step in nanoseconds.
+func (ps *pipeStats) initRateFuncs(step int64) {
+	stepSeconds := float64(step) / nsecsPerSecond
+	for _, f := range ps.funcs {
+		switch t := f.(type) {
+		case *statsRate:
+			t.stepSeconds = stepSeconds
+		case *statsRateSum:
+			t.stepSeconds = stepSeconds
+		}
+	}
+}
+
+// getTimeBucketStep returns the bucket size in nanoseconds for `_time:step` field at ps `by(...)` clause.
+//
+// It returns false if ps doesn't contain `_time` field with fixed-size bucket.
+func (ps *pipeStats) getTimeBucketStep() (int64, bool) {
+	for _, bf := range ps.byFields {
+		if bf.name == "_time" && bf.bucketSize > 0 {
+			return int64(bf.bucketSize), true
+		}
+	}
+	return 0, false
+}
 
-func (ps *pipeStats) newPipeProcessor(workersCount int, stopCh <-chan struct{}, cancel func(), ppBase pipeProcessor) pipeProcessor {
-	maxStateSize := int64(float64(memory.Allowed()) * 0.3)
+const stateSizeBudgetChunk = 1 << 20
 
+func (ps *pipeStats) newPipeProcessor(workersCount int, stopCh <-chan struct{}, cancel func(), sb *stateSizeBudget, ppBase pipeProcessor) pipeProcessor {
 	shards := make([]pipeStatsProcessorShard, workersCount)
 	for i := range shards {
 		shard := &shards[i]
 		shard.ps = ps
 		shard.m = make(map[string]*pipeStatsGroup)
-		shard.stateSizeBudget = stateSizeBudgetChunk
-		maxStateSize -= stateSizeBudgetChunk
 	}
 
 	psp := &pipeStatsProcessor{
@@ -131,9 +186,9 @@ func (ps *pipeStats) newPipeProcessor(workersCount int, stopCh <-chan struct{},
 
 		shards: shards,
 
-		maxStateSize: maxStateSize,
+		sb:          sb,
+		budgetChunk: getStateSizeBudgetChunk(sb.maxSize, workersCount),
 	}
-	psp.stateSizeBudget.Store(maxStateSize)
 
 	return psp
 }
@@ -146,8 +201,8 @@ type pipeStatsProcessor struct {
 
 	shards []pipeStatsProcessorShard
 
-	maxStateSize    int64
-	stateSizeBudget atomic.Int64
+	sb          *stateSizeBudget
+	budgetChunk int64
 }
 
 type pipeStatsProcessorShard struct {
@@ -164,10 +219,21 @@ type pipeStatsProcessorShardNopad struct {
 	columnValues [][]string
 	keyBuf       []byte
 
+	// stateValues contains the marshaled states for stats functions in statsModeImportState mode.
+	stateValues [][]string
+
+	// importErr is the error occurred when importing states in statsModeImportState mode.
+	importErr error
+
 	stateSizeBudget int
 }
 
 func (shard *pipeStatsProcessorShard) writeBlock(br *blockResult) {
+	if shard.ps.mode == statsModeImportState {
+		shard.importStates(br)
+		return
+	}
+
 	byFields := shard.ps.byFields
 
 	if len(byFields) == 0 {
@@ -266,6 +332,47 @@ func (shard *pipeStatsProcessorShard) writeBlock(br *blockResult) {
 	shard.keyBuf = keyBuf
 }
 
+// importStates merges the states of stats functions from br into shard.
+//
+// br must contain the results of pipeStats in statsModeExportState mode.
+func (shard *pipeStatsProcessorShard) importStates(br *blockResult) {
+	if shard.importErr != nil {
+		return
+	}
+
+	columnValues := shard.columnValues[:0]
+	for _, bf := range shard.ps.byFields {
+		c := br.getColumnByName(bf.name)
+		columnValues = append(columnValues, c.getValues(br))
+	}
+	shard.columnValues = columnValues
+
+	stateValues := shard.stateValues[:0]
+	for _, resultName := range shard.ps.resultNames {
+		c := br.getColumnByName(resultName)
+		stateValues = append(stateValues, c.getValues(br))
+	}
+	shard.stateValues = stateValues
+
+	keyBuf := shard.keyBuf[:0]
+	for rowIdx := range br.timestamps {
+		keyBuf = keyBuf[:0]
+		for _, values := range columnValues {
+			keyBuf = encoding.MarshalBytes(keyBuf, bytesutil.ToUnsafeBytes(values[rowIdx]))
+		}
+		psg := shard.getPipeStatsGroup(keyBuf)
+		for i, sfp := range psg.sfps {
+			stateSizeIncrease, err := sfp.importState(bytesutil.ToUnsafeBytes(stateValues[i][rowIdx]))
+			shard.stateSizeBudget -= stateSizeIncrease
+			if err != nil {
+				shard.importErr = fmt.Errorf("cannot import the state for %s: %w", shard.ps.funcs[i], err)
+				return
+			}
+		}
+	}
+	shard.keyBuf = keyBuf
+}
+
 func (shard *pipeStatsProcessorShard) getPipeStatsGroup(key []byte) *pipeStatsGroup {
 	psg := shard.m[string(key)]
 	if psg != nil {
@@ -316,24 +423,29 @@ func (psp *pipeStatsProcessor) writeBlock(workerID uint, br *blockResult) {
 
 	for shard.stateSizeBudget < 0 {
 		// steal some budget for the state size from the global budget.
-		remaining := psp.stateSizeBudget.Add(-stateSizeBudgetChunk)
+		remaining := psp.sb.remaining.Add(-psp.budgetChunk)
 		if remaining < 0 {
 			// The state size is too big. Stop processing data in order to avoid OOM crash.
-			if remaining+stateSizeBudgetChunk >= 0 {
+			if remaining+psp.budgetChunk >= 0 {
 				// Notify worker goroutines to stop calling writeBlock() in order to save CPU time.
 				psp.cancel()
 			}
 			return
 		}
-		shard.stateSizeBudget += stateSizeBudgetChunk
+		shard.stateSizeBudget += int(psp.budgetChunk)
 	}
 
 	shard.writeBlock(br)
 }
 
 func (psp *pipeStatsProcessor) flush() error {
-	if n := psp.stateSizeBudget.Load(); n <= 0 {
-		return fmt.Errorf("cannot calculate [%s], since it requires more than %dMB of memory", psp.ps.String(), psp.maxStateSize/(1<<20))
+	if n := psp.sb.remaining.Load(); n <= 0 {
+		return newStateSizeLimitError(psp.ps, psp.sb.maxSize)
+	}
+	for i := range psp.shards {
+		if err := psp.shards[i].importErr; err != nil {
+			return err
+		}
 	}
 
 	// Merge states across shards
@@ -364,8 +476,9 @@ func (psp *pipeStatsProcessor) flush() error {
 	byFields := psp.ps.byFields
 	if len(byFields) == 0 && len(m) == 0 {
 		// Special case - zero matching rows.
-		_ = shards[0].getPipeStatsGroup(nil)
-		m = shards[0].m
+		shard := &psp.shards[0]
+		_ = shard.getPipeStatsGroup(nil)
+		m = shard.m
 	}
 
 	rcs := make([]resultColumn, 0, len(byFields)+len(psp.ps.resultNames))
@@ -407,7 +520,12 @@ func (psp *pipeStatsProcessor) flush() error {
 
 		// calculate values for stats functions
 		for _, sfp := range psg.sfps {
-			value := sfp.finalizeStats()
+			var value string
+			if psp.ps.mode == statsModeExportState {
+				value = string(sfp.exportState(nil))
+			} else {
+				value = sfp.finalizeStats()
+			}
 			values = append(values, value)
 		}
 
@@ -436,13 +554,18 @@ func (psp *pipeStatsProcessor) flush() error {
 }
 
 func parsePipeStats(lex *lexer) (*pipeStats, error) {
-	if !lex.isKeyword("stats") {
+	var ps pipeStats
+	switch {
+	case lex.isKeyword("stats"):
+	case lex.allowRemotePipes && lex.isKeyword("stats_remote"):
+		// `stats_remote` is sent to storage nodes in cluster setup - see Query.splitToRemoteAndLocal.
+		ps.mode = statsModeExportState
+	default:
 		return nil, fmt.Errorf("expecting 'stats'; got %q", lex.token)
 	}
 
 	lex.nextToken()
 
-	var ps pipeStats
 	if lex.isKeyword("by") {
 		lex.nextToken()
 		bfs, err := parseByStatsFields(lex)
@@ -536,6 +659,18 @@ func parseStatsFunc(lex *lexer) (statsFunc, string, error) {
 			return nil, "", fmt.Errorf("cannot parse 'sum_len' func: %w", err)
 		}
 		sf = sss
+	case lex.isKeyword("rate"):
+		srs, err := parseStatsRate(lex)
+		if err != nil {
+			return nil, "", fmt.Errorf("cannot parse 'rate' func: %w", err)
+		}
+		sf = srs
+	case lex.isKeyword("rate_sum"):
+		srs, err := parseStatsRateSum(lex)
+		if err != nil {
+			return nil, "", fmt.Errorf("cannot parse 'rate_sum' func: %w", err)
+		}
+		sf = srs
 	case lex.isKeyword("quantile"):
 		sqs, err := parseStatsQuantile(lex)
 		if err != nil {
@@ -0,0 +1,279 @@
+package logstorage
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unsafe"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
+)
+
+// pipeStreamLabels processes '| stream_label_names' and '| stream_label_values <label>' pipes.
+//
+// The pipe returns stream label names or values for the given stream label together with the number of hits per each returned item.
+// Only unique names and values are kept in memory, so the pipe can be used over big number of streams.
+type pipeStreamLabels struct {
+	// labelName is the name of the stream label to return values for.
+	//
+	// Stream label names are returned if labelName is empty.
+	labelName string
+}
+
+func (pl *pipeStreamLabels) String() string {
+	if pl.labelName == "" {
+		return "stream_label_names"
+	}
+	return "stream_label_values " + quoteTokenIfNeeded(pl.labelName)
+}
+
+// resultName returns the name of the column with the returned stream label names or values.
+func (pl *pipeStreamLabels) resultName() string {
+	if pl.labelName == "" {
+		return "name"
+	}
+	return "value"
+}
+
+func (pl *pipeStreamLabels) updateNeededFields(neededFields, unneededFields fieldsSet) {
+	neededFields.reset()
+	neededFields.add("_stream")
+	unneededFields.reset()
+}
+
+func (pl *pipeStreamLabels) newPipeProcessor(workersCount int, stopCh <-chan struct{}, cancel func(), sb *stateSizeBudget, ppBase pipeProcessor) pipeProcessor {
+	shards := make([]pipeStreamLabelsProcessorShard, workersCount)
+	for i := range shards {
+		shard := &shards[i]
+		shard.pl = pl
+		shard.m = make(map[string]*uint64)
+	}
+
+	plp := &pipeStreamLabelsProcessor{
+		pl:     pl,
+		stopCh: stopCh,
+		cancel: cancel,
+		ppBase: ppBase,
+
+		shards: shards,
+
+		sb:          sb,
+		budgetChunk: getStateSizeBudgetChunk(sb.maxSize, workersCount),
+	}
+
+	return plp
+}
+
+type pipeStreamLabelsProcessor struct {
+	pl     *pipeStreamLabels
+	stopCh <-chan struct{}
+	cancel func()
+	ppBase pipeProcessor
+
+	shards []pipeStreamLabelsProcessorShard
+
+	sb          *stateSizeBudget
+	budgetChunk int64
+}
+
+type pipeStreamLabelsProcessorShard struct {
+	pipeStreamLabelsProcessorShardNopad
+
+	// The padding prevents false sharing on widespread platforms with 128 mod (cache line size) = 0 .
+	_ [128 - unsafe.Sizeof(pipeStreamLabelsProcessorShardNopad{})%128]byte
+}
+
+type pipeStreamLabelsProcessorShardNopad struct {
+	// pl points to the parent pipeStreamLabels.
+	pl *pipeStreamLabels
+
+	// m holds the number of hits per each stream label name or value.
+	m map[string]*uint64
+
+	// fields is a temporary buffer for parsed stream fields.
+	fields []Field
+
+	// stateSizeBudget is the remaining budget for the whole state size for the shard.
+	// The per-shard budget is provided in chunks from the parent pipeStreamLabelsProcessor.
+	stateSizeBudget int
+}
+
+// writeBlock writes br to shard.
+func (shard *pipeStreamLabelsProcessorShard) writeBlock(br *blockResult) {
+	c := br.getColumnByName("_stream")
+	if c.isConst {
+		// Fast path - all the rows in the block belong to the same stream.
+		shard.updateState(c.encodedValues[0], uint64(len(br.timestamps)))
+		return
+	}
+
+	values := c.getValues(br)
+	hits := uint64(0)
+	for i, v := range values {
+		if i > 0 && values[i-1] == v {
+			hits++
+			continue
+		}
+		if hits > 0 {
+			shard.updateState(values[i-1], hits)
+		}
+		hits = 1
+	}
+	if hits > 0 {
+		shard.updateState(values[len(values)-1], hits)
+	}
+}
+
+// updateState registers stream label names or values from the given stream with the given hits.
+func (shard *pipeStreamLabelsProcessorShard) updateState(stream string, hits uint64) {
+	fields, err := parseStreamFields(shard.fields[:0], stream)
+	shard.fields = fields
+	if err != nil {
+		// The _stream field is missing or it has been modified by the preceding pipes. Skip it.
+		return
+	}
+	for _, f := range fields {
+		if shard.pl.labelName == "" {
+			shard.updateHits(f.Name, hits)
+		} else if f.Name == shard.pl.labelName {
+			shard.updateHits(f.Value, hits)
+		}
+	}
+}
+
+func (shard *pipeStreamLabelsProcessorShard) updateHits(v string, hits uint64) {
+	pHits, ok := shard.m[v]
+	if !ok {
+		vCopy := strings.Clone(v)
+		pHits = new(uint64)
+		shard.m[vCopy] = pHits
+		shard.stateSizeBudget -= len(vCopy) + int(unsafe.Sizeof(vCopy)+unsafe.Sizeof(*pHits)+unsafe.Sizeof(pHits))
+	}
+	*pHits += hits
+}
+
+func (plp *pipeStreamLabelsProcessor) writeBlock(workerID uint, br *blockResult) {
+	if len(br.timestamps) == 0 {
+		return
+	}
+
+	shard := &plp.shards[workerID]
+
+	for shard.stateSizeBudget < 0 {
+		// steal some budget for the state size from the global budget.
+		remaining := plp.sb.remaining.Add(-plp.budgetChunk)
+		if remaining < 0 {
+			// The state size is too big. Stop processing data in order to avoid OOM crash.
+			if remaining+plp.budgetChunk >= 0 {
+				// Notify worker goroutines to stop calling writeBlock() in order to save CPU time.
+				plp.cancel()
+			}
+			return
+		}
+		shard.stateSizeBudget += int(plp.budgetChunk)
+	}
+
+	shard.writeBlock(br)
+}
+
+func (plp *pipeStreamLabelsProcessor) flush() error {
+	if n := plp.sb.remaining.Load(); n <= 0 {
+		return newStateSizeLimitError(plp.pl, plp.sb.maxSize)
+	}
+	if needStop(plp.stopCh) {
+		return nil
+	}
+
+	// merge state across shards
+	shards := plp.shards
+	m := shards[0].m
+	shards = shards[1:]
+	for i := range shards {
+		for v, pHitsSrc := range shards[i].m {
+			pHits, ok := m[v]
+			if !ok {
+				m[v] = pHitsSrc
+			} else {
+				*pHits += *pHitsSrc
+			}
+		}
+	}
+
+	// sort the results by hits in descending order, so the most frequent items go first.
+	results := make([]ValueWithHits, 0, len(m))
+	for v, pHits := range m {
+		results = append(results, ValueWithHits{
+			Value: v,
+			Hits:  *pHits,
+		})
+	}
+	sortValuesWithHitsByHits(results)
+
+	// write results
+	rcs := [2]resultColumn{
+		{
+			name: plp.pl.resultName(),
+		},
+		{
+			name: "hits",
+		},
+	}
+	var br blockResult
+	valuesLen := 0
+	flush := func() {
+		if len(rcs[0].values) == 0 {
+			return
+		}
+		br.setResultColumns(rcs[:])
+		plp.ppBase.writeBlock(0, &br)
+		br.reset()
+		for i := range rcs {
+			rcs[i].resetKeepName()
+		}
+		valuesLen = 0
+	}
+	var hitsBuf []byte
+	for _, r := range results {
+		hitsBuf = marshalUint64(hitsBuf[:0], r.Hits)
+		rcs[0].addValue(r.Value)
+		rcs[1].addValue(bytesutil.ToUnsafeString(hitsBuf))
+		valuesLen += len(r.Value) + len(hitsBuf)
+		if valuesLen >= 1_000_000 {
+			flush()
+		}
+	}
+	flush()
+
+	return nil
+}
+
+// sortValuesWithHitsByHits sorts results by hits in descending order. Results with equal hits are sorted by value.
+func sortValuesWithHitsByHits(results []ValueWithHits) {
+	sort.Slice(results, func(i, j int) bool {
+		a, b := &results[i], &results[j]
+		if a.Hits != b.Hits {
+			return a.Hits > b.Hits
+		}
+		return a.Value < b.Value
+	})
+}
+
+func parsePipeStreamLabels(lex *lexer) (*pipeStreamLabels, error) {
+	switch {
+	case lex.isKeyword("stream_label_names"):
+		lex.nextToken()
+		return &pipeStreamLabels{}, nil
+	case lex.isKeyword("stream_label_values"):
+		lex.nextToken()
+		labelName, err := parseFieldName(lex)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse label name: %w", err)
+		}
+		pl := &pipeStreamLabels{
+			labelName: labelName,
+		}
+		return pl, nil
+	default:
+		return nil, fmt.Errorf("expecting 'stream_label_names' or 'stream_label_values'; got %q", lex.token)
+	}
+}
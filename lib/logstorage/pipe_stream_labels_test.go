@@ -0,0 +1,99 @@
+package logstorage
+
+import (
+	"testing"
+)
+
+func TestPipeStreamLabels(t *testing.T) {
+	f := func(pipeStr string, rows, rowsExpected [][]Field) {
+		t.Helper()
+		expectPipeResults(t, pipeStr, rows, rowsExpected)
+	}
+
+	rows := [][]Field{
+		{
+			{"_stream", `{host="h1",job="foo"}`},
+			{"_msg", "a"},
+		},
+		{
+			{"_stream", `{host="h1",job="foo"}`},
+			{"_msg", "b"},
+		},
+		{
+			{"_stream", `{host="h2",job="foo"}`},
+			{"_msg", "c"},
+		},
+		{
+			{"_stream", `{app="x",host="h2"}`},
+			{"_msg", "d"},
+		},
+		{
+			{"_stream", ``},
+			{"_msg", "e"},
+		},
+	}
+
+	f("stream_label_names", rows, [][]Field{
+		{
+			{"name", "host"},
+			{"hits", "4"},
+		},
+		{
+			{"name", "job"},
+			{"hits", "3"},
+		},
+		{
+			{"name", "app"},
+			{"hits", "1"},
+		},
+	})
+
+	f("stream_label_values host", rows, [][]Field{
+		{
+			{"value", "h1"},
+			{"hits", "2"},
+		},
+		{
+			{"value", "h2"},
+			{"hits", "2"},
+		},
+	})
+
+	f("stream_label_values job", rows, [][]Field{
+		{
+			{"value", "foo"},
+			{"hits", "3"},
+		},
+	})
+
+	// missing label
+	f("stream_label_values foo", rows, nil)
+}
+
+func TestPipeStreamLabelsUpdateNeededFields(t *testing.T) {
+	f := func(s, neededFields, unneededFields, neededFieldsExpected, unneededFieldsExpected string) {
+		t.Helper()
+
+		nfs := newTestFieldsSet(neededFields)
+		unfs := newTestFieldsSet(unneededFields)
+
+		lex := newLexer(s)
+		p, err := parsePipeStreamLabels(lex)
+		if err != nil {
+			t.Fatalf("cannot parse %s: %s", s, err)
+		}
+		p.updateNeededFields(nfs, unfs)
+
+		assertNeededFields(t, nfs, unfs, neededFieldsExpected, unneededFieldsExpected)
+	}
+
+	// all the needed fields
+	f("stream_label_names", "*", "", "_stream", "")
+	f("stream_label_values host", "*", "", "_stream", "")
+
+	// all the needed fields, plus unneeded fields
+	f("stream_label_names", "*", "_stream,name", "_stream", "")
+
+	// needed fields
+	f("stream_label_values host", "value,hits", "", "_stream", "")
+}
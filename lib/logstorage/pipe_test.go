@@ -0,0 +1,184 @@
+package logstorage
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSetMaxMemoryPerQuery(t *testing.T) {
+	f := func(pipeStr string, maxMemory int64, resultExpected bool) {
+		t.Helper()
+
+		SetMaxMemoryPerQuery(maxMemory)
+		defer SetMaxMemoryPerQuery(0)
+
+		lex := newLexer("| " + pipeStr)
+		pipes, err := parsePipes(lex)
+		if err != nil {
+			t.Fatalf("cannot parse %q: %s", pipeStr, err)
+		}
+
+		canceled := false
+		cancel := func() {
+			canceled = true
+		}
+		ppResult := newDefaultPipeProcessor(func(_ uint, _ *blockResult) {})
+		pp := pipes[0].newPipeProcessor(1, nil, cancel, newStateSizeBudget(), ppResult)
+		for i := 0; i < 10; i++ {
+			rc := resultColumn{
+				name: "a",
+			}
+			for j := 0; j < 1000; j++ {
+				rc.addValue(fmt.Sprintf("value_%d_%d_%s", i, j, strings.Repeat("x", 100)))
+			}
+			var br blockResult
+			br.setResultColumns([]resultColumn{rc})
+			pp.writeBlock(0, &br)
+		}
+
+		err = pp.flush()
+		if resultExpected {
+			if err != nil {
+				t.Fatalf("unexpected error for %q: %s", pipeStr, err)
+			}
+			if canceled {
+				t.Fatalf("unexpected cancel() call for %q", pipeStr)
+			}
+			return
+		}
+		if err == nil {
+			t.Fatalf("expecting non-nil error for %q", pipeStr)
+		}
+		if !strings.Contains(err.Error(), "-search.maxMemoryPerQuery") {
+			t.Fatalf("missing -search.maxMemoryPerQuery in the error for %q: %s", pipeStr, err)
+		}
+		if !canceled {
+			t.Fatalf("expecting cancel() call for %q", pipeStr)
+		}
+	}
+
+	// The default limit
+	f("stats by (a) count() hits", 0, true)
+	f("sort by (a)", 0, true)
+	f("sort by (a) limit 100000", 0, true)
+	f("uniq by (a)", 0, true)
+
+	// Too small limit
+	f("stats by (a) count() hits", 1, false)
+	f("sort by (a)", 1, false)
+	f("sort by (a) limit 100000", 1, false)
+	f("uniq by (a)", 1, false)
+
+	// Big enough limit
+	f("stats by (a) count() hits", 1<<30, true)
+	f("sort by (a)", 1<<30, true)
+	f("sort by (a) limit 100000", 1<<30, true)
+	f("uniq by (a)", 1<<30, true)
+}
+
+func TestSetMaxMemoryPerQuerySharedAmongPipes(t *testing.T) {
+	SetMaxMemoryPerQuery(2 << 20)
+	defer SetMaxMemoryPerQuery(0)
+
+	// All the pipes in a single query share the same state size budget.
+	sb := newStateSizeBudget()
+
+	f := func(pipeStr string, resultExpected bool) {
+		t.Helper()
+
+		lex := newLexer("| " + pipeStr)
+		pipes, err := parsePipes(lex)
+		if err != nil {
+			t.Fatalf("cannot parse %q: %s", pipeStr, err)
+		}
+
+		ppResult := newDefaultPipeProcessor(func(_ uint, _ *blockResult) {})
+		pp := pipes[0].newPipeProcessor(1, nil, func() {}, sb, ppResult)
+		for i := 0; i < 10; i++ {
+			rc := resultColumn{
+				name: "a",
+			}
+			for j := 0; j < 1000; j++ {
+				rc.addValue(fmt.Sprintf("value_%d_%d_%s", i, j, strings.Repeat("x", 100)))
+			}
+			var br blockResult
+			br.setResultColumns([]resultColumn{rc})
+			pp.writeBlock(0, &br)
+		}
+
+		err = pp.flush()
+		if resultExpected && err != nil {
+			t.Fatalf("unexpected error for %q: %s", pipeStr, err)
+		}
+		if !resultExpected && err == nil {
+			t.Fatalf("expecting non-nil error for %q", pipeStr)
+		}
+	}
+
+	// The first pipe fits the limit
+	f("uniq by (a)", true)
+
+	// The second pipe exceeds the limit, since the first pipe already used the most of the budget
+	f("uniq by (a)", false)
+}
+
+// expectPipeResults verifies that the pipe from pipeStr returns rowsExpected for the given rows.
+//
+// All the rows must have the same set of fields.
+func expectPipeResults(t *testing.T, pipeStr string, rows, rowsExpected [][]Field) {
+	t.Helper()
+
+	lex := newLexer("| " + pipeStr)
+	pipes, err := parsePipes(lex)
+	if err != nil {
+		t.Fatalf("cannot parse %q: %s", pipeStr, err)
+	}
+	if !lex.isEnd() {
+		t.Fatalf("unexpected tail after parsing %q: %q", pipeStr, lex.s)
+	}
+	if len(pipes) != 1 {
+		t.Fatalf("unexpected number of pipes in %q; got %d; want 1", pipeStr, len(pipes))
+	}
+
+	var rcs []resultColumn
+	for _, field := range rows[0] {
+		rcs = append(rcs, resultColumn{
+			name: field.Name,
+		})
+	}
+	for _, row := range rows {
+		for i, field := range row {
+			rcs[i].addValue(field.Value)
+		}
+	}
+
+	var br blockResult
+	br.setResultColumns(rcs)
+
+	var result [][]Field
+	ppResult := newDefaultPipeProcessor(func(_ uint, br *blockResult) {
+		cs := br.getColumns()
+		for i := range br.timestamps {
+			var row []Field
+			for _, c := range cs {
+				row = append(row, Field{
+					Name:  c.name,
+					Value: c.getValueAtRow(br, i),
+				})
+			}
+			result = append(result, row)
+		}
+	})
+
+	pp := pipes[0].newPipeProcessor(1, nil, nil, newStateSizeBudget(), ppResult)
+	pp.writeBlock(0, &br)
+	if err := pp.flush(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if !reflect.DeepEqual(result, rowsExpected) {
+		t.Fatalf("unexpected result for %q;\ngot\n%v\nwant\n%v", pipeStr, result, rowsExpected)
+	}
+}
@@ -2,26 +2,19 @@ package logstorage
 
 import (
 	"container/heap"
-	"fmt"
 	"strings"
 	"sync"
-	"sync/atomic"
 	"unsafe"
 
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
-	"github.com/VictoriaMetrics/VictoriaMetrics/lib/memory"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/stringsutil"
 )
 
-func newPipeTopkProcessor(ps *pipeSort, workersCount int, stopCh <-chan struct{}, cancel func(), ppBase pipeProcessor) pipeProcessor {
-	maxStateSize := int64(float64(memory.Allowed()) * 0.2)
-
+func newPipeTopkProcessor(ps *pipeSort, workersCount int, stopCh <-chan struct{}, cancel func(), sb *stateSizeBudget, ppBase pipeProcessor) pipeProcessor {
 	shards := make([]pipeTopkProcessorShard, workersCount)
 	for i := range shards {
 		shard := &shards[i]
 		shard.ps = ps
-		shard.stateSizeBudget = stateSizeBudgetChunk
-		maxStateSize -= stateSizeBudgetChunk
 	}
 
 	ptp := &pipeTopkProcessor{
@@ -32,9 +25,9 @@ func newPipeTopkProcessor(ps *pipeSort, workersCount int, stopCh <-chan struct{}
 
 		shards: shards,
 
-		maxStateSize: maxStateSize,
+		sb:          sb,
+		budgetChunk: getStateSizeBudgetChunk(sb.maxSize, workersCount),
 	}
-	ptp.stateSizeBudget.Store(maxStateSize)
 
 	return ptp
 }
@@ -47,8 +40,8 @@ type pipeTopkProcessor struct {
 
 	shards []pipeTopkProcessorShard
 
-	maxStateSize    int64
-	stateSizeBudget atomic.Int64
+	sb          *stateSizeBudget
+	budgetChunk int64
 }
 
 type pipeTopkProcessorShard struct {
@@ -255,7 +248,8 @@ func (shard *pipeTopkProcessorShard) addRow(byColumns []string, otherColumns []F
 	r.otherColumns = otherColumns
 
 	rows := shard.rows
-	if len(rows) > 0 && !topkLess(shard.ps, r, rows[0]) {
+	maxRows := shard.ps.offset + shard.ps.limit
+	if uint64(len(rows)) >= maxRows && !topkLess(shard.ps, r, rows[0]) {
 		// Fast path - nothing to add.
 		return
 	}
@@ -263,7 +257,7 @@ func (shard *pipeTopkProcessorShard) addRow(byColumns []string, otherColumns []F
 	// Slow path - add r to shard.rows.
 	r = r.clone()
 	shard.stateSizeBudget -= r.sizeBytes()
-	if uint64(len(rows)) < shard.ps.limit {
+	if uint64(len(rows)) < maxRows {
 		heap.Push(shard, r)
 		shard.stateSizeBudget -= int(unsafe.Sizeof(r))
 	} else {
@@ -295,24 +289,24 @@ func (ptp *pipeTopkProcessor) writeBlock(workerID uint, br *blockResult) {
 
 	for shard.stateSizeBudget < 0 {
 		// steal some budget for the state size from the global budget.
-		remaining := ptp.stateSizeBudget.Add(-stateSizeBudgetChunk)
+		remaining := ptp.sb.remaining.Add(-ptp.budgetChunk)
 		if remaining < 0 {
 			// The state size is too big. Stop processing data in order to avoid OOM crash.
-			if remaining+stateSizeBudgetChunk >= 0 {
+			if remaining+ptp.budgetChunk >= 0 {
 				// Notify worker goroutines to stop calling writeBlock() in order to save CPU time.
 				ptp.cancel()
 			}
 			return
 		}
-		shard.stateSizeBudget += stateSizeBudgetChunk
+		shard.stateSizeBudget += int(ptp.budgetChunk)
 	}
 
 	shard.writeBlock(br)
 }
 
 func (ptp *pipeTopkProcessor) flush() error {
-	if n := ptp.stateSizeBudget.Load(); n <= 0 {
-		return fmt.Errorf("cannot calculate [%s], since it requires more than %dMB of memory", ptp.ps.String(), ptp.maxStateSize/(1<<20))
+	if n := ptp.sb.remaining.Load(); n <= 0 {
+		return newStateSizeLimitError(ptp.ps, ptp.sb.maxSize)
 	}
 
 	if needStop(ptp.stopCh) {
@@ -3,13 +3,13 @@ package logstorage
 import (
 	"fmt"
 	"slices"
-	"sync/atomic"
+	"strings"
 	"unsafe"
 
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/encoding"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
-	"github.com/VictoriaMetrics/VictoriaMetrics/lib/memory"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/slicesutil"
 )
 
 // pipeUniq processes '| uniq ...' queries.
@@ -19,6 +19,9 @@ type pipeUniq struct {
 	// fields contains field names for returning unique values
 	byFields []string
 
+	// if hitsFieldName isn't empty, then the number of hits per each unique value is returned in this field.
+	hitsFieldName string
+
 	limit uint64
 }
 
@@ -27,6 +30,9 @@ func (pu *pipeUniq) String() string {
 	if len(pu.byFields) > 0 {
 		s += " by (" + fieldNamesString(pu.byFields) + ")"
 	}
+	if pu.hitsFieldName != "" {
+		s += " with hits"
+	}
 	if pu.limit > 0 {
 		s += fmt.Sprintf(" limit %d", pu.limit)
 	}
@@ -44,16 +50,12 @@ func (pu *pipeUniq) updateNeededFields(neededFields, unneededFields fieldsSet) {
 	}
 }
 
-func (pu *pipeUniq) newPipeProcessor(workersCount int, stopCh <-chan struct{}, cancel func(), ppBase pipeProcessor) pipeProcessor {
-	maxStateSize := int64(float64(memory.Allowed()) * 0.2)
-
+func (pu *pipeUniq) newPipeProcessor(workersCount int, stopCh <-chan struct{}, cancel func(), sb *stateSizeBudget, ppBase pipeProcessor) pipeProcessor {
 	shards := make([]pipeUniqProcessorShard, workersCount)
 	for i := range shards {
 		shard := &shards[i]
 		shard.pu = pu
-		shard.m = make(map[string]struct{})
-		shard.stateSizeBudget = stateSizeBudgetChunk
-		maxStateSize -= stateSizeBudgetChunk
+		shard.m = make(map[string]*uint64)
 	}
 
 	pup := &pipeUniqProcessor{
@@ -64,9 +66,9 @@ func (pu *pipeUniq) newPipeProcessor(workersCount int, stopCh <-chan struct{}, c
 
 		shards: shards,
 
-		maxStateSize: maxStateSize,
+		sb:          sb,
+		budgetChunk: getStateSizeBudgetChunk(sb.maxSize, workersCount),
 	}
-	pup.stateSizeBudget.Store(maxStateSize)
 
 	return pup
 }
@@ -79,8 +81,8 @@ type pipeUniqProcessor struct {
 
 	shards []pipeUniqProcessorShard
 
-	maxStateSize    int64
-	stateSizeBudget atomic.Int64
+	sb          *stateSizeBudget
+	budgetChunk int64
 }
 
 type pipeUniqProcessorShard struct {
@@ -94,8 +96,8 @@ type pipeUniqProcessorShardNopad struct {
 	// pu points to the parent pipeUniq.
 	pu *pipeUniq
 
-	// m holds unique rows.
-	m map[string]struct{}
+	// m holds per-row hits.
+	m map[string]*uint64
 
 	// keyBuf is a temporary buffer for building keys for m.
 	keyBuf []byte
@@ -103,6 +105,9 @@ type pipeUniqProcessorShardNopad struct {
 	// columnValues is a temporary buffer for the processed column values.
 	columnValues [][]string
 
+	// dictHits is a temporary buffer for counting hits per each dictionary value.
+	dictHits []uint64
+
 	// stateSizeBudget is the remaining budget for the whole state size for the shard.
 	// The per-shard budget is provided in chunks from the parent pipeUniqProcessor.
 	stateSizeBudget int
@@ -112,11 +117,16 @@ type pipeUniqProcessorShardNopad struct {
 //
 // It returns false if the block cannot be written because of the exceeded limit.
 func (shard *pipeUniqProcessorShard) writeBlock(br *blockResult) bool {
-	if limit := shard.pu.limit; limit > 0 && uint64(len(shard.m)) >= limit {
-		return false
+	if limit := shard.pu.limit; limit > 0 {
+		if shard.pu.hitsFieldName != "" {
+			// Collect an additional row, so flush() could detect whether the limit is exceeded.
+			limit++
+		}
+		if uint64(len(shard.m)) >= limit {
+			return false
+		}
 	}
 
-	m := shard.m
 	byFields := shard.pu.byFields
 	if len(byFields) == 0 {
 		// Take into account all the columns in br.
@@ -129,15 +139,41 @@ func (shard *pipeUniqProcessorShard) writeBlock(br *blockResult) bool {
 				keyBuf = encoding.MarshalBytes(keyBuf, bytesutil.ToUnsafeBytes(c.name))
 				keyBuf = encoding.MarshalBytes(keyBuf, bytesutil.ToUnsafeBytes(v))
 			}
-			if _, ok := m[string(keyBuf)]; !ok {
-				m[string(keyBuf)] = struct{}{}
-				shard.stateSizeBudget -= len(keyBuf) + int(unsafe.Sizeof(""))
-			}
+			shard.updateState(bytesutil.ToUnsafeString(keyBuf), 1)
 		}
 		shard.keyBuf = keyBuf
 		return true
 	}
 
+	if len(byFields) == 1 {
+		// Fast path for a single field.
+		c := br.getColumnByName(byFields[0])
+		if c.isConst {
+			v := c.encodedValues[0]
+			shard.keyBuf = encoding.MarshalBytes(shard.keyBuf[:0], bytesutil.ToUnsafeBytes(v))
+			shard.updateState(bytesutil.ToUnsafeString(shard.keyBuf), uint64(len(br.timestamps)))
+			return true
+		}
+		if c.valueType == valueTypeDict {
+			// Count hits per each dictionary value without decoding the column values.
+			dictHits := slicesutil.SetLength(shard.dictHits, len(c.dictValues))
+			clear(dictHits)
+			for _, v := range c.encodedValues {
+				dictHits[v[0]]++
+			}
+			keyBuf := shard.keyBuf
+			for i, v := range c.dictValues {
+				if hits := dictHits[i]; hits > 0 {
+					keyBuf = encoding.MarshalBytes(keyBuf[:0], bytesutil.ToUnsafeBytes(v))
+					shard.updateState(bytesutil.ToUnsafeString(keyBuf), hits)
+				}
+			}
+			shard.keyBuf = keyBuf
+			shard.dictHits = dictHits
+			return true
+		}
+	}
+
 	// Take into account only the selected columns.
 	columnValues := shard.columnValues[:0]
 	for _, f := range byFields {
@@ -147,6 +183,7 @@ func (shard *pipeUniqProcessorShard) writeBlock(br *blockResult) bool {
 	shard.columnValues = columnValues
 
 	keyBuf := shard.keyBuf
+	hits := uint64(0)
 	for i := range br.timestamps {
 		seenValue := true
 		for _, values := range columnValues {
@@ -156,23 +193,39 @@ func (shard *pipeUniqProcessorShard) writeBlock(br *blockResult) bool {
 			}
 		}
 		if seenValue {
+			hits++
 			continue
 		}
 
+		if hits > 0 {
+			shard.updateState(bytesutil.ToUnsafeString(keyBuf), hits)
+		}
+
 		keyBuf = keyBuf[:0]
 		for _, values := range columnValues {
 			keyBuf = encoding.MarshalBytes(keyBuf, bytesutil.ToUnsafeBytes(values[i]))
 		}
-		if _, ok := m[string(keyBuf)]; !ok {
-			m[string(keyBuf)] = struct{}{}
-			shard.stateSizeBudget -= len(keyBuf) + int(unsafe.Sizeof(""))
-		}
+		hits = 1
+	}
+	if hits > 0 {
+		shard.updateState(bytesutil.ToUnsafeString(keyBuf), hits)
 	}
 	shard.keyBuf = keyBuf
 
 	return true
 }
 
+func (shard *pipeUniqProcessorShard) updateState(v string, hits uint64) {
+	pHits, ok := shard.m[v]
+	if !ok {
+		vCopy := strings.Clone(v)
+		pHits = new(uint64)
+		shard.m[vCopy] = pHits
+		shard.stateSizeBudget -= len(vCopy) + int(unsafe.Sizeof(vCopy)+unsafe.Sizeof(*pHits)+unsafe.Sizeof(pHits))
+	}
+	*pHits += hits
+}
+
 func (pup *pipeUniqProcessor) writeBlock(workerID uint, br *blockResult) {
 	if len(br.timestamps) == 0 {
 		return
@@ -182,16 +235,16 @@ func (pup *pipeUniqProcessor) writeBlock(workerID uint, br *blockResult) {
 
 	for shard.stateSizeBudget < 0 {
 		// steal some budget for the state size from the global budget.
-		remaining := pup.stateSizeBudget.Add(-stateSizeBudgetChunk)
+		remaining := pup.sb.remaining.Add(-pup.budgetChunk)
 		if remaining < 0 {
 			// The state size is too big. Stop processing data in order to avoid OOM crash.
-			if remaining+stateSizeBudgetChunk >= 0 {
+			if remaining+pup.budgetChunk >= 0 {
 				// Notify worker goroutines to stop calling writeBlock() in order to save CPU time.
 				pup.cancel()
 			}
 			return
 		}
-		shard.stateSizeBudget += stateSizeBudgetChunk
+		shard.stateSizeBudget += int(pup.budgetChunk)
 	}
 
 	if !shard.writeBlock(br) {
@@ -200,8 +253,8 @@ func (pup *pipeUniqProcessor) writeBlock(workerID uint, br *blockResult) {
 }
 
 func (pup *pipeUniqProcessor) flush() error {
-	if n := pup.stateSizeBudget.Load(); n <= 0 {
-		return fmt.Errorf("cannot calculate [%s], since it requires more than %dMB of memory", pup.pu.String(), pup.maxStateSize/(1<<20))
+	if n := pup.sb.remaining.Load(); n <= 0 {
+		return newStateSizeLimitError(pup.pu, pup.sb.maxSize)
 	}
 
 	// merge state across shards
@@ -213,11 +266,20 @@ func (pup *pipeUniqProcessor) flush() error {
 			return nil
 		}
 
-		for k := range shards[i].m {
-			m[k] = struct{}{}
+		for k, pHitsSrc := range shards[i].m {
+			pHits, ok := m[k]
+			if !ok {
+				m[k] = pHitsSrc
+			} else {
+				*pHits += *pHitsSrc
+			}
 		}
 	}
 
+	// There is little sense in returning partial hits when the limit on the number of unique entries is reached.
+	// It is better from UX experience to return zero hits instead.
+	resetHits := pup.pu.limit > 0 && uint64(len(m)) > pup.pu.limit
+
 	// write result
 	wctx := &pipeUniqWriteContext{
 		pup: pup,
@@ -225,8 +287,24 @@ func (pup *pipeUniqProcessor) flush() error {
 	byFields := pup.pu.byFields
 	var rowFields []Field
 
+	var hitsBuf []byte
+	addHitsFieldIfNeeded := func(dst []Field, hits uint64) []Field {
+		if pup.pu.hitsFieldName == "" {
+			return dst
+		}
+		if resetHits {
+			hits = 0
+		}
+		hitsBuf = marshalUint64(hitsBuf[:0], hits)
+		dst = append(dst, Field{
+			Name:  pup.pu.hitsFieldName,
+			Value: bytesutil.ToUnsafeString(hitsBuf),
+		})
+		return dst
+	}
+
 	if len(byFields) == 0 {
-		for k := range m {
+		for k, pHits := range m {
 			if needStop(pup.stopCh) {
 				return nil
 			}
@@ -251,10 +329,11 @@ func (pup *pipeUniqProcessor) flush() error {
 					Value: bytesutil.ToUnsafeString(value),
 				})
 			}
+			rowFields = addHitsFieldIfNeeded(rowFields, *pHits)
 			wctx.writeRow(rowFields)
 		}
 	} else {
-		for k := range m {
+		for k, pHits := range m {
 			if needStop(pup.stopCh) {
 				return nil
 			}
@@ -275,6 +354,7 @@ func (pup *pipeUniqProcessor) flush() error {
 				})
 				fieldIdx++
 			}
+			rowFields = addHitsFieldIfNeeded(rowFields, *pHits)
 			wctx.writeRow(rowFields)
 		}
 	}
@@ -372,6 +452,19 @@ func parsePipeUniq(lex *lexer) (*pipeUniq, error) {
 		pu.byFields = bfs
 	}
 
+	if lex.isKeyword("with") {
+		lex.nextToken()
+		if !lex.isKeyword("hits") {
+			return nil, fmt.Errorf("missing 'hits' after 'with'")
+		}
+		lex.nextToken()
+		hitsFieldName := "hits"
+		for slices.Contains(pu.byFields, hitsFieldName) {
+			hitsFieldName += "s"
+		}
+		pu.hitsFieldName = hitsFieldName
+	}
+
 	if lex.isKeyword("limit") {
 		lex.nextToken()
 		n, ok := tryParseUint64(lex.token)
@@ -0,0 +1,298 @@
+package logstorage
+
+import (
+	"fmt"
+	"slices"
+	"unsafe"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/slicesutil"
+)
+
+// fieldsUnpackerContext holds fields unpacked from a single value by unpack_* pipes.
+type fieldsUnpackerContext struct {
+	// fieldPrefix is added to the names of the unpacked fields.
+	fieldPrefix string
+
+	// fields contains the unpacked fields.
+	fields []Field
+
+	// buf holds the backing data for fields.
+	buf []byte
+}
+
+func (uctx *fieldsUnpackerContext) reset() {
+	clear(uctx.fields)
+	uctx.fields = uctx.fields[:0]
+
+	uctx.buf = uctx.buf[:0]
+}
+
+// addField adds (name, value) field to uctx.
+//
+// name and value are copied to uctx, so they can be changed after returning from the function.
+func (uctx *fieldsUnpackerContext) addField(name, value string) {
+	buf := uctx.buf
+
+	bufLen := len(buf)
+	buf = append(buf, uctx.fieldPrefix...)
+	buf = append(buf, name...)
+	nameCopy := bytesutil.ToUnsafeString(buf[bufLen:])
+
+	bufLen = len(buf)
+	buf = append(buf, value...)
+	valueCopy := bytesutil.ToUnsafeString(buf[bufLen:])
+
+	uctx.buf = buf
+
+	uctx.fields = append(uctx.fields, Field{
+		Name:  nameCopy,
+		Value: valueCopy,
+	})
+}
+
+// updateNeededFieldsForUnpackPipe updates neededFields and unneededFields for unpack_* pipe,
+// which unpacks fields from fromField.
+//
+// If fields is empty, then the pipe may produce arbitrary output fields. Otherwise it produces only the given fields prefixed with resultPrefix.
+func updateNeededFieldsForUnpackPipe(fromField string, fields []string, resultPrefix string, neededFields, unneededFields fieldsSet) {
+	if len(fields) == 0 {
+		// The pipe may produce arbitrary fields, so fromField is always needed.
+		if neededFields.contains("*") {
+			unneededFields.remove(fromField)
+		} else {
+			neededFields.add(fromField)
+		}
+		return
+	}
+
+	if neededFields.contains("*") {
+		needFromField := false
+		for _, f := range fields {
+			resultName := resultPrefix + f
+			if !unneededFields.contains(resultName) {
+				needFromField = true
+			}
+			unneededFields.add(resultName)
+		}
+		if needFromField {
+			unneededFields.remove(fromField)
+		}
+	} else {
+		needFromField := false
+		for _, f := range fields {
+			resultName := resultPrefix + f
+			if neededFields.contains(resultName) {
+				needFromField = true
+				neededFields.remove(resultName)
+			}
+		}
+		if needFromField {
+			neededFields.add(fromField)
+		}
+	}
+}
+
+func newPipeUnpackProcessor(workersCount int, unpackFunc func(uctx *fieldsUnpackerContext, s string), ppBase pipeProcessor,
+	fromField string, fields []string, resultPrefix string) *pipeUnpackProcessor {
+
+	shards := make([]pipeUnpackProcessorShard, workersCount)
+	for i := range shards {
+		shard := &shards[i]
+		shard.uctx.fieldPrefix = resultPrefix
+		shard.m = make(map[string]int)
+	}
+
+	return &pipeUnpackProcessor{
+		unpackFunc: unpackFunc,
+		ppBase:     ppBase,
+
+		shards: shards,
+
+		fromField:    fromField,
+		fields:       fields,
+		resultPrefix: resultPrefix,
+	}
+}
+
+type pipeUnpackProcessor struct {
+	unpackFunc func(uctx *fieldsUnpackerContext, s string)
+	ppBase     pipeProcessor
+
+	shards []pipeUnpackProcessorShard
+
+	fromField    string
+	fields       []string
+	resultPrefix string
+}
+
+type pipeUnpackProcessorShard struct {
+	pipeUnpackProcessorShardNopad
+
+	// The padding prevents false sharing on widespread platforms with 128 mod (cache line size) = 0 .
+	_ [128 - unsafe.Sizeof(pipeUnpackProcessorShardNopad{})%128]byte
+}
+
+type pipeUnpackProcessorShardNopad struct {
+	// uctx holds unpacked fields for all the rows in the currently processed block.
+	uctx fieldsUnpackerContext
+
+	// rowStarts and rowEnds contain the range of uctx.fields for every row in the currently processed block.
+	rowStarts []int
+	rowEnds   []int
+
+	// m maps the output field name to its index at rcs.
+	m map[string]int
+
+	// rowValues holds the output values for the currently processed row.
+	rowValues []string
+
+	// rcs holds the output columns.
+	rcs []resultColumn
+}
+
+func (shard *pipeUnpackProcessorShard) getColumnIdx(name string) int {
+	idx, ok := shard.m[name]
+	if !ok {
+		idx = len(shard.rcs)
+		shard.rcs = slicesutil.SetLength(shard.rcs, idx+1)
+		shard.rcs[idx].name = name
+		shard.m[name] = idx
+	}
+	return idx
+}
+
+func (pup *pipeUnpackProcessor) writeBlock(workerID uint, br *blockResult) {
+	if len(br.timestamps) == 0 {
+		return
+	}
+
+	shard := &pup.shards[workerID]
+	uctx := &shard.uctx
+	uctx.reset()
+
+	// Unpack fields for every row
+	rowStarts := shard.rowStarts[:0]
+	rowEnds := shard.rowEnds[:0]
+	c := br.getColumnByNameIfExists(pup.fromField)
+	if c == nil || c.isConst {
+		v := ""
+		if c != nil {
+			v = c.encodedValues[0]
+		}
+		pup.unpackFunc(uctx, v)
+		for range br.timestamps {
+			rowStarts = append(rowStarts, 0)
+			rowEnds = append(rowEnds, len(uctx.fields))
+		}
+	} else {
+		values := c.getValues(br)
+		for i, v := range values {
+			if i > 0 && values[i-1] == v {
+				rowStarts = append(rowStarts, rowStarts[i-1])
+				rowEnds = append(rowEnds, rowEnds[i-1])
+				continue
+			}
+			rowStart := len(uctx.fields)
+			pup.unpackFunc(uctx, v)
+			rowStarts = append(rowStarts, rowStart)
+			rowEnds = append(rowEnds, len(uctx.fields))
+		}
+	}
+	shard.rowStarts = rowStarts
+	shard.rowEnds = rowEnds
+
+	// Prepare output columns
+	clear(shard.m)
+	for i := range shard.rcs {
+		shard.rcs[i].resetKeepName()
+	}
+	shard.rcs = shard.rcs[:0]
+	for _, f := range pup.fields {
+		shard.getColumnIdx(pup.resultPrefix + f)
+	}
+	if len(pup.fields) == 0 {
+		for _, f := range uctx.fields {
+			shard.getColumnIdx(f.Name)
+		}
+	}
+
+	// Fill output columns with the unpacked values
+	rowValues := shard.rowValues
+	for rowIdx := range br.timestamps {
+		rowValues = slicesutil.SetLength(rowValues, len(shard.rcs))
+		clear(rowValues)
+		for _, f := range uctx.fields[rowStarts[rowIdx]:rowEnds[rowIdx]] {
+			if idx, ok := shard.m[f.Name]; ok {
+				rowValues[idx] = f.Value
+			}
+		}
+		for i, v := range rowValues {
+			shard.rcs[i].addValue(v)
+		}
+	}
+	shard.rowValues = rowValues
+
+	for i := range shard.rcs {
+		br.addResultColumn(&shard.rcs[i])
+	}
+	pup.ppBase.writeBlock(workerID, br)
+}
+
+func (pup *pipeUnpackProcessor) flush() error {
+	return nil
+}
+
+func unpackPipeOptionsString(fromField string, fields []string, resultPrefix string) string {
+	s := ""
+	if !isMsgFieldName(fromField) {
+		s += " from " + quoteTokenIfNeeded(fromField)
+	}
+	if len(fields) > 0 {
+		s += " fields (" + fieldNamesString(fields) + ")"
+	}
+	if resultPrefix != "" {
+		s += " result_prefix " + quoteTokenIfNeeded(resultPrefix)
+	}
+	return s
+}
+
+// parseUnpackPipeOptions parses '[from field] [fields (f1, ..., fN)] [result_prefix prefix]' options for unpack_* pipe.
+func parseUnpackPipeOptions(lex *lexer) (string, []string, string, error) {
+	fromField := "_msg"
+	if lex.isKeyword("from") {
+		lex.nextToken()
+		f, err := parseFieldName(lex)
+		if err != nil {
+			return "", nil, "", fmt.Errorf("cannot parse 'from' field name: %w", err)
+		}
+		fromField = f
+	}
+
+	var fields []string
+	if lex.isKeyword("fields") {
+		lex.nextToken()
+		fs, err := parseFieldNamesInParens(lex)
+		if err != nil {
+			return "", nil, "", fmt.Errorf("cannot parse 'fields': %w", err)
+		}
+		if len(fs) == 0 {
+			return "", nil, "", fmt.Errorf("'fields' list cannot be empty")
+		}
+		if !slices.Contains(fs, "*") {
+			fields = fs
+		}
+	}
+
+	resultPrefix := ""
+	if lex.isKeyword("result_prefix") {
+		lex.nextToken()
+		if lex.isKeyword("|", ")", "") {
+			return "", nil, "", fmt.Errorf("missing 'result_prefix' value")
+		}
+		resultPrefix = lex.token
+		lex.nextToken()
+	}
+
+	return fromField, fields, resultPrefix, nil
+}
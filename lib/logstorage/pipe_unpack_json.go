@@ -0,0 +1,68 @@
+package logstorage
+
+import (
+	"fmt"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
+)
+
+// pipeUnpackJSON processes '| unpack_json ...' pipe.
+//
+// See https://docs.victoriametrics.com/victorialogs/logsql/#unpack_json-pipe
+type pipeUnpackJSON struct {
+	// fromField is the field to unpack JSON from
+	fromField string
+
+	// fields is an optional list of fields to extract from JSON.
+	//
+	// if it is empty, then all the fields are extracted.
+	fields []string
+
+	// resultPrefix is prefix to add to the unpacked field names
+	resultPrefix string
+}
+
+func (pu *pipeUnpackJSON) String() string {
+	return "unpack_json" + unpackPipeOptionsString(pu.fromField, pu.fields, pu.resultPrefix)
+}
+
+func (pu *pipeUnpackJSON) updateNeededFields(neededFields, unneededFields fieldsSet) {
+	updateNeededFieldsForUnpackPipe(pu.fromField, pu.fields, pu.resultPrefix, neededFields, unneededFields)
+}
+
+func (pu *pipeUnpackJSON) newPipeProcessor(workersCount int, _ <-chan struct{}, _ func(), _ *stateSizeBudget, ppBase pipeProcessor) pipeProcessor {
+	return newPipeUnpackProcessor(workersCount, unpackJSON, ppBase, pu.fromField, pu.fields, pu.resultPrefix)
+}
+
+func unpackJSON(uctx *fieldsUnpackerContext, s string) {
+	if len(s) == 0 || s[0] != '{' {
+		// This isn't a JSON object
+		return
+	}
+	p := GetJSONParser()
+	if err := p.ParseLogMessage(bytesutil.ToUnsafeBytes(s)); err == nil {
+		for _, f := range p.Fields {
+			uctx.addField(f.Name, f.Value)
+		}
+	}
+	PutJSONParser(p)
+}
+
+func parsePipeUnpackJSON(lex *lexer) (*pipeUnpackJSON, error) {
+	if !lex.isKeyword("unpack_json") {
+		return nil, fmt.Errorf("unexpected token: %q; want %q", lex.token, "unpack_json")
+	}
+	lex.nextToken()
+
+	fromField, fields, resultPrefix, err := parseUnpackPipeOptions(lex)
+	if err != nil {
+		return nil, err
+	}
+
+	pu := &pipeUnpackJSON{
+		fromField:    fromField,
+		fields:       fields,
+		resultPrefix: resultPrefix,
+	}
+	return pu, nil
+}
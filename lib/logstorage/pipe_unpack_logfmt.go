@@ -0,0 +1,105 @@
+package logstorage
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// pipeUnpackLogfmt processes '| unpack_logfmt ...' pipe.
+//
+// See https://docs.victoriametrics.com/victorialogs/logsql/#unpack_logfmt-pipe
+type pipeUnpackLogfmt struct {
+	// fromField is the field to unpack logfmt fields from
+	fromField string
+
+	// fields is an optional list of fields to extract from logfmt.
+	//
+	// if it is empty, then all the fields are extracted.
+	fields []string
+
+	// resultPrefix is prefix to add to the unpacked field names
+	resultPrefix string
+}
+
+func (pu *pipeUnpackLogfmt) String() string {
+	return "unpack_logfmt" + unpackPipeOptionsString(pu.fromField, pu.fields, pu.resultPrefix)
+}
+
+func (pu *pipeUnpackLogfmt) updateNeededFields(neededFields, unneededFields fieldsSet) {
+	updateNeededFieldsForUnpackPipe(pu.fromField, pu.fields, pu.resultPrefix, neededFields, unneededFields)
+}
+
+func (pu *pipeUnpackLogfmt) newPipeProcessor(workersCount int, _ <-chan struct{}, _ func(), _ *stateSizeBudget, ppBase pipeProcessor) pipeProcessor {
+	return newPipeUnpackProcessor(workersCount, unpackLogfmt, ppBase, pu.fromField, pu.fields, pu.resultPrefix)
+}
+
+// unpackLogfmt unpacks logfmt fields from s into uctx.
+//
+// See https://brandur.org/logfmt
+func unpackLogfmt(uctx *fieldsUnpackerContext, s string) {
+	for {
+		// Search for field name
+		s = strings.TrimLeft(s, " ")
+		if s == "" {
+			return
+		}
+		n := strings.IndexAny(s, "= ")
+		if n < 0 {
+			// field name without value
+			uctx.addField(s, "")
+			return
+		}
+		if s[n] == ' ' {
+			// field name without value
+			uctx.addField(s[:n], "")
+			s = s[n+1:]
+			continue
+		}
+		name := s[:n]
+		s = s[n+1:]
+
+		// Search for field value
+		if strings.HasPrefix(s, `"`) {
+			qs, err := strconv.QuotedPrefix(s)
+			if err != nil {
+				// Invalid quoted value - return the rest of s as is
+				uctx.addField(name, s)
+				return
+			}
+			value, err := strconv.Unquote(qs)
+			if err != nil {
+				value = qs
+			}
+			uctx.addField(name, value)
+			s = s[len(qs):]
+			continue
+		}
+		n = strings.IndexByte(s, ' ')
+		if n < 0 {
+			uctx.addField(name, s)
+			return
+		}
+		uctx.addField(name, s[:n])
+		s = s[n+1:]
+	}
+}
+
+func parsePipeUnpackLogfmt(lex *lexer) (*pipeUnpackLogfmt, error) {
+	if !lex.isKeyword("unpack_logfmt") {
+		return nil, fmt.Errorf("unexpected token: %q; want %q", lex.token, "unpack_logfmt")
+	}
+	lex.nextToken()
+
+	fromField, fields, resultPrefix, err := parseUnpackPipeOptions(lex)
+	if err != nil {
+		return nil, err
+	}
+
+	pu := &pipeUnpackLogfmt{
+		fromField:    fromField,
+		fields:       fields,
+		resultPrefix: resultPrefix,
+	}
+	return pu, nil
+}
@@ -0,0 +1,178 @@
+package logstorage
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPipeUnpackJSONUpdateNeededFields(t *testing.T) {
+	f := func(s string, neededFields, unneededFields, neededFieldsExpected, unneededFieldsExpected string) {
+		t.Helper()
+
+		nfs := newTestFieldsSet(neededFields)
+		unfs := newTestFieldsSet(unneededFields)
+
+		lex := newLexer(s)
+		p, err := parsePipeUnpackJSON(lex)
+		if err != nil {
+			t.Fatalf("cannot parse %s: %s", s, err)
+		}
+		p.updateNeededFields(nfs, unfs)
+
+		assertNeededFields(t, nfs, unfs, neededFieldsExpected, unneededFieldsExpected)
+	}
+
+	// all the needed fields
+	f("unpack_json from x", "*", "", "*", "")
+	f("unpack_json from x fields (f1, f2)", "*", "", "*", "f1,f2")
+	f("unpack_json from x fields (f1, f2) result_prefix p_", "*", "", "*", "p_f1,p_f2")
+
+	// all the needed fields, unneeded fields do not intersect with src
+	f("unpack_json from x", "*", "f1,f2", "*", "f1,f2")
+	f("unpack_json from x fields (f3)", "*", "f1,f2", "*", "f1,f2,f3")
+
+	// all the needed fields, unneeded fields intersect with src
+	f("unpack_json from x", "*", "f2,x", "*", "f2")
+	f("unpack_json from x fields (f1)", "*", "f2,x", "*", "f1,f2")
+	f("unpack_json from x fields (f1)", "*", "f1,f2,x", "*", "f1,f2,x")
+
+	// needed fields do not intersect with src
+	f("unpack_json from x", "f1,f2", "", "f1,f2,x", "")
+	f("unpack_json from x fields (f3)", "f1,f2", "", "f1,f2", "")
+	f("unpack_json from x fields (f1) result_prefix p_", "f1,f2", "", "f1,f2", "")
+
+	// needed fields intersect with src
+	f("unpack_json from x", "f2,x", "", "f2,x", "")
+	f("unpack_json from x fields (f2)", "f2,f3", "", "f3,x", "")
+	f("unpack_json from x fields (f2) result_prefix p_", "p_f2,f3", "", "f3,x", "")
+}
+
+func TestPipeUnpackLogfmtUpdateNeededFields(t *testing.T) {
+	f := func(s string, neededFields, unneededFields, neededFieldsExpected, unneededFieldsExpected string) {
+		t.Helper()
+
+		nfs := newTestFieldsSet(neededFields)
+		unfs := newTestFieldsSet(unneededFields)
+
+		lex := newLexer(s)
+		p, err := parsePipeUnpackLogfmt(lex)
+		if err != nil {
+			t.Fatalf("cannot parse %s: %s", s, err)
+		}
+		p.updateNeededFields(nfs, unfs)
+
+		assertNeededFields(t, nfs, unfs, neededFieldsExpected, unneededFieldsExpected)
+	}
+
+	// all the needed fields
+	f("unpack_logfmt from x", "*", "", "*", "")
+	f("unpack_logfmt from x fields (f1)", "*", "", "*", "f1")
+
+	// all the needed fields, unneeded fields intersect with src
+	f("unpack_logfmt from x", "*", "f2,x", "*", "f2")
+
+	// needed fields do not intersect with src
+	f("unpack_logfmt from x", "f1,f2", "", "f1,f2,x", "")
+
+	// needed fields intersect with src
+	f("unpack_logfmt from x fields (f2)", "f2,f3", "", "f3,x", "")
+}
+
+func TestUnpackJSON(t *testing.T) {
+	f := func(s, prefix string, fieldsExpected []Field) {
+		t.Helper()
+
+		var uctx fieldsUnpackerContext
+		uctx.fieldPrefix = prefix
+		unpackJSON(&uctx, s)
+		if !reflect.DeepEqual(uctx.fields, fieldsExpected) {
+			t.Fatalf("unexpected fields for %q\ngot\n%q\nwant\n%q", s, uctx.fields, fieldsExpected)
+		}
+	}
+
+	// invalid JSON
+	f("", "", nil)
+	f("foo", "", nil)
+	f("[1,2]", "", nil)
+	f(`{"foo":"bar"`, "", nil)
+
+	// valid JSON
+	f(`{}`, "", nil)
+	f(`{"foo":"bar","a":{"b":[1,2]},"c":123,"d":null}`, "", []Field{
+		{
+			Name:  "foo",
+			Value: "bar",
+		},
+		{
+			Name:  "a.b",
+			Value: "[1,2]",
+		},
+		{
+			Name:  "c",
+			Value: "123",
+		},
+	})
+	f(`{"foo":"bar"}`, "p_", []Field{
+		{
+			Name:  "p_foo",
+			Value: "bar",
+		},
+	})
+}
+
+func TestUnpackLogfmt(t *testing.T) {
+	f := func(s string, fieldsExpected []Field) {
+		t.Helper()
+
+		var uctx fieldsUnpackerContext
+		unpackLogfmt(&uctx, s)
+		if !reflect.DeepEqual(uctx.fields, fieldsExpected) {
+			t.Fatalf("unexpected fields for %q\ngot\n%q\nwant\n%q", s, uctx.fields, fieldsExpected)
+		}
+	}
+
+	f("", nil)
+	f("   ", nil)
+	f("foo", []Field{
+		{
+			Name:  "foo",
+			Value: "",
+		},
+	})
+	f("foo=bar", []Field{
+		{
+			Name:  "foo",
+			Value: "bar",
+		},
+	})
+	f(`  level=info msg="hello, \"world\"" took=5ms  x= y `, []Field{
+		{
+			Name:  "level",
+			Value: "info",
+		},
+		{
+			Name:  "msg",
+			Value: `hello, "world"`,
+		},
+		{
+			Name:  "took",
+			Value: "5ms",
+		},
+		{
+			Name:  "x",
+			Value: "",
+		},
+		{
+			Name:  "y",
+			Value: "",
+		},
+	})
+
+	// invalid quoted value
+	f(`foo="bar baz`, []Field{
+		{
+			Name:  "foo",
+			Value: `"bar baz`,
+		},
+	})
+}
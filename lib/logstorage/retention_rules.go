@@ -0,0 +1,310 @@
+package logstorage
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fs"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promutils"
+)
+
+// RetentionRule is a retention rule for log entries, which belong to the given tenant and match the given stream filter.
+//
+// Log entries matching the rule are deleted during background merges after their timestamps become older than now-Retention.
+type RetentionRule struct {
+	// TenantID is the tenant the rule applies to.
+	//
+	// The rule applies to all the tenants if TenantID is nil.
+	TenantID *TenantID
+
+	// StreamFilter is the filter for log streams the rule applies to.
+	//
+	// The rule applies to all the log streams if StreamFilter is nil.
+	StreamFilter *StreamFilter
+
+	// Retention is the retention for log entries matching the rule.
+	Retention time.Duration
+}
+
+// String returns string representation of rr.
+func (rr *RetentionRule) String() string {
+	var a []string
+	if rr.TenantID != nil {
+		a = append(a, fmt.Sprintf("tenant=%d:%d", rr.TenantID.AccountID, rr.TenantID.ProjectID))
+	}
+	if rr.StreamFilter != nil {
+		a = append(a, rr.StreamFilter.String())
+	}
+	return strings.Join(a, " ") + ":" + rr.Retention.String()
+}
+
+// ParseRetentionRule parses retention rule from s.
+//
+// s must be in the form `[tenant=<accountID>:<projectID>] [{<stream filter>}]:<retention>`,
+// for example, `tenant=12:34 {app="nginx"}:5d`.
+func ParseRetentionRule(s string) (*RetentionRule, error) {
+	s = strings.TrimSpace(s)
+	n := strings.LastIndexByte(s, ':')
+	if n < 0 {
+		return nil, fmt.Errorf("missing `:<retention>` suffix in %q", s)
+	}
+	retentionStr := s[n+1:]
+	retention, err := promutils.ParseDuration(retentionStr)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse retention %q: %w", retentionStr, err)
+	}
+	if retention <= 0 {
+		return nil, fmt.Errorf("retention must be positive; got %s", retentionStr)
+	}
+	rr := &RetentionRule{
+		Retention: retention,
+	}
+
+	s = strings.TrimSpace(s[:n])
+	if strings.HasPrefix(s, "tenant=") {
+		tenantStr := s[len("tenant="):]
+		tail := ""
+		if n := strings.IndexAny(tenantStr, " \t"); n >= 0 {
+			tail = tenantStr[n:]
+			tenantStr = tenantStr[:n]
+		}
+		tenantID, err := GetTenantIDFromString(tenantStr)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse tenant: %w", err)
+		}
+		rr.TenantID = &tenantID
+		s = strings.TrimSpace(tail)
+	}
+	if s != "" {
+		lex := newLexer(s)
+		fsf, err := parseFilterStream(lex)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse stream filter %q: %w", s, err)
+		}
+		if !lex.isEnd() {
+			return nil, fmt.Errorf("unexpected token after stream filter in %q: %q", s, lex.token)
+		}
+		if !fsf.f.isEmpty() {
+			rr.StreamFilter = fsf.f
+		}
+	}
+	return rr, nil
+}
+
+// retentionMatcher determines the retention deadline for log streams according to the storage retention rules.
+//
+// It is used for dropping log entries outside the retention rules during background merges.
+type retentionMatcher struct {
+	pt *partition
+
+	// rules contains the retention rules to apply.
+	rules []*RetentionRule
+
+	// deadlines contains the deadline in nanoseconds per each rule.
+	//
+	// Log entries with timestamps smaller than the deadline must be dropped.
+	deadlines []int64
+
+	// maxDeadline is the maximum deadline across deadlines.
+	maxDeadline int64
+
+	// streamIDsByTenant contains lazily initialized streamIDs matching rules with stream filters per each tenant.
+	streamIDsByTenant map[TenantID][]map[u128]struct{}
+
+	// lastStreamID and lastDeadline cache the deadline for the last seen streamID.
+	//
+	// They are valid only if hasLastStreamID is set.
+	lastStreamID    streamID
+	lastDeadline    int64
+	hasLastStreamID bool
+
+	// rowsDropped is the number of log entries dropped according to the retention rules.
+	rowsDropped uint64
+}
+
+// newRetentionMatcher returns retentionMatcher for log entries at pt with timestamps starting from minTimestamp.
+//
+// nil is returned if no log entries can be dropped according to the retention rules.
+func newRetentionMatcher(pt *partition, minTimestamp int64) *retentionMatcher {
+	rules := pt.s.retentionRules
+	if len(rules) == 0 {
+		return nil
+	}
+
+	now := time.Now().UnixNano()
+	deadlines := make([]int64, len(rules))
+	maxDeadline := int64(math.MinInt64)
+	for i, rr := range rules {
+		deadline := now - rr.Retention.Nanoseconds()
+		deadlines[i] = deadline
+		if deadline > maxDeadline {
+			maxDeadline = deadline
+		}
+	}
+	if minTimestamp >= maxDeadline {
+		// Fast path - all the log entries are inside the retention rules.
+		return nil
+	}
+
+	rm := &retentionMatcher{
+		pt:          pt,
+		rules:       rules,
+		deadlines:   deadlines,
+		maxDeadline: maxDeadline,
+	}
+	return rm
+}
+
+// needMergePart returns true if p contains log entries outside the retention rules.
+//
+// Only block headers are read from p, so the check is much cheaper than the merge of p.
+func (rm *retentionMatcher) needMergePart(p *part) bool {
+	if rm == nil {
+		return false
+	}
+	var bhs []blockHeader
+	for i := range p.indexBlockHeaders {
+		ih := &p.indexBlockHeaders[i]
+		if ih.minTimestamp >= rm.maxDeadline {
+			continue
+		}
+		bhs = ih.mustReadBlockHeaders(bhs[:0], p)
+		for j := range bhs {
+			bh := &bhs[j]
+			if bh.timestampsHeader.minTimestamp < rm.getDeadline(&bh.streamID) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// getDeadline returns the deadline for log entries with the given sid.
+//
+// Log entries with timestamps smaller than the returned deadline must be dropped.
+// math.MinInt64 is returned if sid doesn't match any retention rule.
+func (rm *retentionMatcher) getDeadline(sid *streamID) int64 {
+	if rm == nil {
+		return math.MinInt64
+	}
+	if !rm.hasLastStreamID || !sid.equal(&rm.lastStreamID) {
+		rm.lastStreamID = *sid
+		rm.lastDeadline = rm.getDeadlineSlow(sid)
+		rm.hasLastStreamID = true
+	}
+	return rm.lastDeadline
+}
+
+func (rm *retentionMatcher) getDeadlineSlow(sid *streamID) int64 {
+	// The first matching rule wins.
+	for i, rr := range rm.rules {
+		if rr.TenantID != nil && !rr.TenantID.equal(&sid.tenantID) {
+			continue
+		}
+		if rr.StreamFilter == nil {
+			return rm.deadlines[i]
+		}
+		streamIDs := rm.getStreamIDs(sid.tenantID, i)
+		if _, ok := streamIDs[sid.id]; ok {
+			return rm.deadlines[i]
+		}
+	}
+	return math.MinInt64
+}
+
+func (rm *retentionMatcher) getStreamIDs(tenantID TenantID, ruleIdx int) map[u128]struct{} {
+	if rm.streamIDsByTenant == nil {
+		rm.streamIDsByTenant = make(map[TenantID][]map[u128]struct{})
+	}
+	a := rm.streamIDsByTenant[tenantID]
+	if a == nil {
+		a = make([]map[u128]struct{}, len(rm.rules))
+		rm.streamIDsByTenant[tenantID] = a
+	}
+	m := a[ruleIdx]
+	if m == nil {
+		streamIDs := rm.pt.idb.searchStreamIDs([]TenantID{tenantID}, rm.rules[ruleIdx].StreamFilter)
+		m = make(map[u128]struct{}, len(streamIDs))
+		for _, sid := range streamIDs {
+			m[sid.id] = struct{}{}
+		}
+		a[ruleIdx] = m
+	}
+	return m
+}
+
+// dropRows drops log entries with timestamps smaller than the deadline from rs, starting from the rowsOffset position.
+func (rm *retentionMatcher) dropRows(rs *rows, rowsOffset int, deadline int64) {
+	if rm == nil {
+		return
+	}
+	timestamps := rs.timestamps[rowsOffset:]
+	rows := rs.rows[rowsOffset:]
+	dst := 0
+	for i, timestamp := range timestamps {
+		if timestamp < deadline {
+			continue
+		}
+		timestamps[dst] = timestamp
+		rows[dst] = rows[i]
+		dst++
+	}
+	clear(rows[dst:])
+	rs.timestamps = rs.timestamps[:rowsOffset+dst]
+	rs.rows = rs.rows[:rowsOffset+dst]
+	rm.rowsDropped += uint64(len(timestamps) - dst)
+}
+
+// addRowsDropped registers n log entries dropped according to the retention rules.
+func (rm *retentionMatcher) addRowsDropped(n uint64) {
+	if rm != nil {
+		rm.rowsDropped += n
+	}
+}
+
+// mustReadAppliedRetentionRules returns retention rules, which were already applied to the partition at the given path.
+func mustReadAppliedRetentionRules(path string) []string {
+	rulesPath := filepath.Join(path, appliedRetentionRulesFilename)
+	if !fs.IsPathExist(rulesPath) {
+		return nil
+	}
+	data, err := os.ReadFile(rulesPath)
+	if err != nil {
+		logger.Panicf("FATAL: cannot read %s: %s", rulesPath, err)
+	}
+	var rules []string
+	if err := json.Unmarshal(data, &rules); err != nil {
+		logger.Panicf("FATAL: cannot parse %s: %s", rulesPath, err)
+	}
+	return rules
+}
+
+// mustWriteAppliedRetentionRules stores rules, which were applied to the partition at the given path.
+func mustWriteAppliedRetentionRules(path string, rules []string) {
+	data, err := json.Marshal(rules)
+	if err != nil {
+		logger.Panicf("BUG: cannot marshal retention rules to JSON: %s", err)
+	}
+	rulesPath := filepath.Join(path, appliedRetentionRulesFilename)
+	fs.MustWriteAtomic(rulesPath, data, true)
+}
+
+// containsAllStrings returns true if a contains all the items from b.
+func containsAllStrings(a, b []string) bool {
+	m := make(map[string]struct{}, len(a))
+	for _, s := range a {
+		m[s] = struct{}{}
+	}
+	for _, s := range b {
+		if _, ok := m[s]; !ok {
+			return false
+		}
+	}
+	return true
+}
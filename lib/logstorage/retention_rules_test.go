@@ -0,0 +1,249 @@
+package logstorage
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseRetentionRuleSuccess(t *testing.T) {
+	f := func(s, resultExpected string) {
+		t.Helper()
+		rr, err := ParseRetentionRule(s)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		result := rr.String()
+		if result != resultExpected {
+			t.Fatalf("unexpected result; got %q; want %q", result, resultExpected)
+		}
+	}
+
+	f(`{app="nginx"}:5d`, `{app="nginx"}:120h0m0s`)
+	f(` {app="nginx",env=~"dev|staging" or job!="foo"}:1h `, `{app="nginx",env=~"dev|staging" or job!="foo"}:1h0m0s`)
+	f(`{host="a:b"}:1w`, `{host="a:b"}:168h0m0s`)
+	f(`tenant=12:34:1y`, `tenant=12:34:8760h0m0s`)
+	f(`tenant=12 {app="nginx"}:3d`, `tenant=12:0 {app="nginx"}:72h0m0s`)
+	f(`tenant=12:34 {}:30m`, `tenant=12:34:30m0s`)
+	f(`:2d`, `:48h0m0s`)
+}
+
+func TestParseRetentionRuleFailure(t *testing.T) {
+	f := func(s string) {
+		t.Helper()
+		rr, err := ParseRetentionRule(s)
+		if err == nil {
+			t.Fatalf("expecting non-nil error")
+		}
+		if rr != nil {
+			t.Fatalf("expecting nil result; got %s", rr)
+		}
+	}
+
+	// missing retention
+	f(``)
+	f(`{app="nginx"}`)
+	f(`{app="nginx"}:`)
+
+	// invalid retention
+	f(`{app="nginx"}:foo`)
+	f(`{app="nginx"}:-1d`)
+	f(`{app="nginx"}:0`)
+
+	// invalid tenant
+	f(`tenant=foo:1d`)
+	f(`tenant=1:bar {app="nginx"}:1d`)
+
+	// invalid stream filter
+	f(`app="nginx":1d`)
+	f(`{app="nginx":1d`)
+	f(`{app="nginx"} foo:1d`)
+}
+
+func TestRetentionRulesMerge(t *testing.T) {
+	const path = "TestRetentionRulesMerge"
+
+	s := newTestStorage()
+	for _, rule := range []string{`tenant=12:34:5d`, `{app="debug"}:3d`} {
+		rr, err := ParseRetentionRule(rule)
+		if err != nil {
+			t.Fatalf("cannot parse retention rule %q: %s", rule, err)
+		}
+		s.retentionRules = append(s.retentionRules, rr)
+	}
+
+	mustCreatePartition(path)
+	pt := mustOpenPartition(s, path)
+
+	timestamp := time.Now().Add(-10 * 24 * time.Hour).UnixNano()
+	lr := GetLogRows([]string{"app"}, nil)
+	addRows := func(tenantID TenantID, app string, rowsCount int) {
+		for i := 0; i < rowsCount; i++ {
+			fields := []Field{
+				{
+					Name:  "app",
+					Value: app,
+				},
+				{
+					Name:  "_msg",
+					Value: "some message",
+				},
+			}
+			lr.MustAdd(tenantID, timestamp+int64(i), fields)
+		}
+	}
+	addRows(TenantID{}, "debug", 10)
+	addRows(TenantID{}, "audit", 20)
+	addRows(TenantID{AccountID: 12, ProjectID: 34}, "audit", 30)
+	pt.mustAddRows(lr)
+	PutLogRows(lr)
+
+	pt.debugFlush()
+	pt.ddb.mustFlushInmemoryPartsToFiles(true)
+
+	rules := []string{`tenant=12:34:120h0m0s`, `{app="debug"}:72h0m0s`}
+	pt.ddb.retentionRulesMerger(rules)
+
+	var ddbStats DatadbStats
+	pt.ddb.updateStats(&ddbStats)
+	if n := ddbStats.RowsCount(); n != 20 {
+		t.Fatalf("unexpected number of rows after applying retention rules; got %d; want %d", n, 20)
+	}
+	if n := s.rowsDroppedByRetentionRules.Load(); n != 40 {
+		t.Fatalf("unexpected number of rows dropped by retention rules; got %d; want %d", n, 40)
+	}
+	appliedRules := mustReadAppliedRetentionRules(path)
+	if !reflect.DeepEqual(appliedRules, rules) {
+		t.Fatalf("unexpected applied retention rules; got %q; want %q", appliedRules, rules)
+	}
+
+	mustClosePartition(pt)
+	mustDeletePartition(path)
+
+	closeTestStorage(s)
+}
+
+func TestRetentionRulesFinalFlush(t *testing.T) {
+	const path = "TestRetentionRulesFinalFlush"
+
+	s := newTestStorage()
+	rr, err := ParseRetentionRule(`{app="debug"}:3d`)
+	if err != nil {
+		t.Fatalf("cannot parse retention rule: %s", err)
+	}
+	s.retentionRules = append(s.retentionRules, rr)
+
+	mustCreatePartition(path)
+	pt := mustOpenPartition(s, path)
+
+	timestamp := time.Now().Add(-10 * 24 * time.Hour).UnixNano()
+	lr := GetLogRows([]string{"app"}, nil)
+	for i, app := range []string{"debug", "audit", "debug"} {
+		fields := []Field{
+			{
+				Name:  "app",
+				Value: app,
+			},
+			{
+				Name:  "_msg",
+				Value: "some message",
+			},
+		}
+		lr.MustAdd(TenantID{}, timestamp+int64(i), fields)
+	}
+	pt.mustAddRows(lr)
+	PutLogRows(lr)
+
+	// Log entries outside the retention rules mustn't be written to disk when flushing in-memory parts on shutdown.
+	pt.debugFlush()
+	pt.ddb.mustFlushInmemoryPartsToFiles(true)
+
+	var ddbStats DatadbStats
+	pt.ddb.updateStats(&ddbStats)
+	if n := ddbStats.RowsCount(); n != 1 {
+		t.Fatalf("unexpected number of rows after the final flush; got %d; want %d", n, 1)
+	}
+	if n := s.rowsDroppedByRetentionRules.Load(); n != 2 {
+		t.Fatalf("unexpected number of rows dropped by retention rules; got %d; want %d", n, 2)
+	}
+
+	mustClosePartition(pt)
+	mustDeletePartition(path)
+
+	closeTestStorage(s)
+}
+
+func TestRetentionRulesMergePartiallyExpiredBlocks(t *testing.T) {
+	const path = "TestRetentionRulesMergePartiallyExpiredBlocks"
+
+	s := newTestStorage()
+	rr, err := ParseRetentionRule(`{app="debug"}:3d`)
+	if err != nil {
+		t.Fatalf("cannot parse retention rule: %s", err)
+	}
+	s.retentionRules = append(s.retentionRules, rr)
+	rules := []string{`{app="debug"}:72h0m0s`}
+
+	f := func(app string, rowsCountExpected int) {
+		t.Helper()
+
+		mustCreatePartition(path)
+		pt := mustOpenPartition(s, path)
+		s.rowsDroppedByRetentionRules.Store(0)
+
+		// Spread log entries evenly on the time range [now-4d ... now-2d), so a half of them is outside the retention rule.
+		// Shift timestamps by a half of step in order to avoid log entries at the retention deadline.
+		const rowsCount = 100
+		lr := GetLogRows([]string{"app"}, nil)
+		step := int64(48*time.Hour) / rowsCount
+		minTimestamp := time.Now().Add(-4*24*time.Hour).UnixNano() + step/2
+		for i := 0; i < rowsCount; i++ {
+			fields := []Field{
+				{
+					Name:  "app",
+					Value: app,
+				},
+				{
+					Name:  "_msg",
+					Value: "some message",
+				},
+			}
+			lr.MustAdd(TenantID{}, minTimestamp+int64(i)*step, fields)
+		}
+		pt.mustAddRows(lr)
+		PutLogRows(lr)
+
+		pt.debugFlush()
+		pt.ddb.mustFlushInmemoryPartsToFiles(true)
+
+		pwsPrev := append([]*partWrapper{}, pt.ddb.smallParts...)
+		pt.ddb.retentionRulesMerger(rules)
+
+		var ddbStats DatadbStats
+		pt.ddb.updateStats(&ddbStats)
+		if n := ddbStats.RowsCount(); n != uint64(rowsCountExpected) {
+			t.Fatalf("unexpected number of rows after applying retention rules; got %d; want %d", n, rowsCountExpected)
+		}
+		if n := s.rowsDroppedByRetentionRules.Load(); n != uint64(rowsCount-rowsCountExpected) {
+			t.Fatalf("unexpected number of rows dropped by retention rules; got %d; want %d", n, rowsCount-rowsCountExpected)
+		}
+		if rowsCountExpected == rowsCount && !reflect.DeepEqual(pt.ddb.smallParts, pwsPrev) {
+			t.Fatalf("parts without log entries outside the retention rules mustn't be re-written")
+		}
+		appliedRules := mustReadAppliedRetentionRules(path)
+		if !reflect.DeepEqual(appliedRules, rules) {
+			t.Fatalf("unexpected applied retention rules; got %q; want %q", appliedRules, rules)
+		}
+
+		mustClosePartition(pt)
+		mustDeletePartition(path)
+	}
+
+	// Log entries outside the retention rule must be dropped from the block, while the rest of log entries must be kept.
+	f("debug", 50)
+
+	// The part without log entries outside the retention rule must be kept as is.
+	f("audit", 100)
+
+	closeTestStorage(s)
+}
@@ -1,10 +1,13 @@
 package logstorage
 
 import (
+	"fmt"
 	"math"
 	"slices"
 	"strconv"
 	"unsafe"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/encoding"
 )
 
 type statsAvg struct {
@@ -89,6 +92,25 @@ func (sap *statsAvgProcessor) finalizeStats() string {
 	return strconv.FormatFloat(avg, 'f', -1, 64)
 }
 
+func (sap *statsAvgProcessor) exportState(dst []byte) []byte {
+	dst = marshalStatsStateFloat64(dst, sap.sum)
+	return encoding.MarshalVarUint64(dst, sap.count)
+}
+
+func (sap *statsAvgProcessor) importState(src []byte) (int, error) {
+	sum, tail, err := unmarshalStatsStateFloat64(src)
+	if err != nil {
+		return 0, fmt.Errorf("cannot unmarshal sum: %w", err)
+	}
+	count, tail, err := unmarshalStatsStateUint64(tail)
+	if err != nil {
+		return 0, fmt.Errorf("cannot unmarshal count: %w", err)
+	}
+	sap.sum += sum
+	sap.count += count
+	return 0, checkStatsStateTail(tail)
+}
+
 func parseStatsAvg(lex *lexer) (*statsAvg, error) {
 	fields, err := parseFieldNamesForStatsFunc(lex, "avg")
 	if err != nil {
@@ -5,6 +5,7 @@ import (
 	"strconv"
 	"unsafe"
 
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/encoding"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
 )
 
@@ -194,6 +195,19 @@ func (scp *statsCountProcessor) finalizeStats() string {
 	return strconv.FormatUint(scp.rowsCount, 10)
 }
 
+func (scp *statsCountProcessor) exportState(dst []byte) []byte {
+	return encoding.MarshalVarUint64(dst, scp.rowsCount)
+}
+
+func (scp *statsCountProcessor) importState(src []byte) (int, error) {
+	n, tail, err := unmarshalStatsStateUint64(src)
+	if err != nil {
+		return 0, err
+	}
+	scp.rowsCount += n
+	return 0, checkStatsStateTail(tail)
+}
+
 func parseStatsCount(lex *lexer) (*statsCount, error) {
 	fields, err := parseFieldNamesForStatsFunc(lex, "count")
 	if err != nil {
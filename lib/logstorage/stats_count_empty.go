@@ -5,6 +5,7 @@ import (
 	"strconv"
 	"unsafe"
 
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/encoding"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
 )
 
@@ -193,6 +194,19 @@ func (scp *statsCountEmptyProcessor) finalizeStats() string {
 	return strconv.FormatUint(scp.rowsCount, 10)
 }
 
+func (scp *statsCountEmptyProcessor) exportState(dst []byte) []byte {
+	return encoding.MarshalVarUint64(dst, scp.rowsCount)
+}
+
+func (scp *statsCountEmptyProcessor) importState(src []byte) (int, error) {
+	n, tail, err := unmarshalStatsStateUint64(src)
+	if err != nil {
+		return 0, err
+	}
+	scp.rowsCount += n
+	return 0, checkStatsStateTail(tail)
+}
+
 func parseStatsCountEmpty(lex *lexer) (*statsCountEmpty, error) {
 	fields, err := parseFieldNamesForStatsFunc(lex, "count_empty")
 	if err != nil {
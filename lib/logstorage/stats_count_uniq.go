@@ -375,6 +375,32 @@ func (sup *statsCountUniqProcessor) finalizeStats() string {
 	return strconv.FormatUint(n, 10)
 }
 
+func (sup *statsCountUniqProcessor) exportState(dst []byte) []byte {
+	dst = encoding.MarshalVarUint64(dst, uint64(len(sup.m)))
+	for k := range sup.m {
+		dst = encoding.MarshalBytes(dst, bytesutil.ToUnsafeBytes(k))
+	}
+	return dst
+}
+
+func (sup *statsCountUniqProcessor) importState(src []byte) (int, error) {
+	stateSizeIncrease := 0
+	m := sup.m
+	tail, err := unmarshalStatsStateStrings(src, func(k []byte) {
+		if sup.limitReached() {
+			return
+		}
+		if _, ok := m[string(k)]; !ok {
+			m[string(k)] = struct{}{}
+			stateSizeIncrease += len(k) + int(unsafe.Sizeof(""))
+		}
+	})
+	if err != nil {
+		return stateSizeIncrease, err
+	}
+	return stateSizeIncrease, checkStatsStateTail(tail)
+}
+
 func (sup *statsCountUniqProcessor) limitReached() bool {
 	limit := sup.su.limit
 	return limit > 0 && uint64(len(sup.m)) >= limit
@@ -80,7 +80,7 @@ func (smp *statsMaxProcessor) updateStatsForRow(br *blockResult, rowIdx int) int
 
 func (smp *statsMaxProcessor) mergeState(sfp statsProcessor) {
 	src := sfp.(*statsMaxProcessor)
-	if src.max > smp.max {
+	if src.max > smp.max || math.IsNaN(smp.max) {
 		smp.max = src.max
 	}
 }
@@ -89,6 +89,21 @@ func (smp *statsMaxProcessor) finalizeStats() string {
 	return strconv.FormatFloat(smp.max, 'f', -1, 64)
 }
 
+func (smp *statsMaxProcessor) exportState(dst []byte) []byte {
+	return marshalStatsStateFloat64(dst, smp.max)
+}
+
+func (smp *statsMaxProcessor) importState(src []byte) (int, error) {
+	f, tail, err := unmarshalStatsStateFloat64(src)
+	if err != nil {
+		return 0, err
+	}
+	smp.mergeState(&statsMaxProcessor{
+		max: f,
+	})
+	return 0, checkStatsStateTail(tail)
+}
+
 func parseStatsMax(lex *lexer) (*statsMax, error) {
 	fields, err := parseFieldNamesForStatsFunc(lex, "max")
 	if err != nil {
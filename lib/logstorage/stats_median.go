@@ -52,6 +52,14 @@ func (smp *statsMedianProcessor) finalizeStats() string {
 	return smp.sqp.finalizeStats()
 }
 
+func (smp *statsMedianProcessor) exportState(dst []byte) []byte {
+	return smp.sqp.exportState(dst)
+}
+
+func (smp *statsMedianProcessor) importState(src []byte) (int, error) {
+	return smp.sqp.importState(src)
+}
+
 func parseStatsMedian(lex *lexer) (*statsMedian, error) {
 	fields, err := parseFieldNamesForStatsFunc(lex, "median")
 	if err != nil {
@@ -80,7 +80,7 @@ func (smp *statsMinProcessor) updateStatsForRow(br *blockResult, rowIdx int) int
 
 func (smp *statsMinProcessor) mergeState(sfp statsProcessor) {
 	src := sfp.(*statsMinProcessor)
-	if src.min < smp.min {
+	if src.min < smp.min || math.IsNaN(smp.min) {
 		smp.min = src.min
 	}
 }
@@ -89,6 +89,21 @@ func (smp *statsMinProcessor) finalizeStats() string {
 	return strconv.FormatFloat(smp.min, 'f', -1, 64)
 }
 
+func (smp *statsMinProcessor) exportState(dst []byte) []byte {
+	return marshalStatsStateFloat64(dst, smp.min)
+}
+
+func (smp *statsMinProcessor) importState(src []byte) (int, error) {
+	f, tail, err := unmarshalStatsStateFloat64(src)
+	if err != nil {
+		return 0, err
+	}
+	smp.mergeState(&statsMinProcessor{
+		min: f,
+	})
+	return 0, checkStatsStateTail(tail)
+}
+
 func parseStatsMin(lex *lexer) (*statsMin, error) {
 	fields, err := parseFieldNamesForStatsFunc(lex, "min")
 	if err != nil {
@@ -8,6 +8,8 @@ import (
 	"unsafe"
 
 	"github.com/valyala/fastrand"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/encoding"
 )
 
 type statsQuantile struct {
@@ -100,6 +102,21 @@ func (sqp *statsQuantileProcessor) finalizeStats() string {
 	return strconv.FormatFloat(q, 'f', -1, 64)
 }
 
+func (sqp *statsQuantileProcessor) exportState(dst []byte) []byte {
+	return sqp.h.marshal(dst)
+}
+
+func (sqp *statsQuantileProcessor) importState(src []byte) (int, error) {
+	var h histogram
+	tail, err := h.unmarshal(src)
+	if err != nil {
+		return 0, err
+	}
+	sqp.h.mergeState(&h)
+	stateSizeIncrease := len(h.a) * int(unsafe.Sizeof(h.a[0]))
+	return stateSizeIncrease, checkStatsStateTail(tail)
+}
+
 func parseStatsQuantile(lex *lexer) (*statsQuantile, error) {
 	if !lex.isKeyword("quantile") {
 		return nil, fmt.Errorf("unexpected token: %q; want %q", lex.token, "quantile")
@@ -192,6 +209,54 @@ func (h *histogram) mergeState(src *histogram) {
 	h.count += src.count
 }
 
+// marshal appends marshaled h to dst and returns the result.
+func (h *histogram) marshal(dst []byte) []byte {
+	dst = encoding.MarshalVarUint64(dst, h.count)
+	dst = marshalStatsStateFloat64(dst, h.min)
+	dst = marshalStatsStateFloat64(dst, h.max)
+	dst = encoding.MarshalVarUint64(dst, uint64(len(h.a)))
+	for _, f := range h.a {
+		dst = marshalStatsStateFloat64(dst, f)
+	}
+	return dst
+}
+
+// unmarshal unmarshals h from src and returns the tail left after unmarshaling.
+func (h *histogram) unmarshal(src []byte) ([]byte, error) {
+	count, src, err := unmarshalStatsStateUint64(src)
+	if err != nil {
+		return src, fmt.Errorf("cannot unmarshal count: %w", err)
+	}
+	h.count = count
+
+	h.min, src, err = unmarshalStatsStateFloat64(src)
+	if err != nil {
+		return src, fmt.Errorf("cannot unmarshal min: %w", err)
+	}
+	h.max, src, err = unmarshalStatsStateFloat64(src)
+	if err != nil {
+		return src, fmt.Errorf("cannot unmarshal max: %w", err)
+	}
+
+	samplesLen, src, err := unmarshalStatsStateUint64(src)
+	if err != nil {
+		return src, fmt.Errorf("cannot unmarshal the number of samples: %w", err)
+	}
+	if samplesLen > uint64(len(src))/8 {
+		return src, fmt.Errorf("too big number of samples: %d; it cannot exceed %d", samplesLen, len(src)/8)
+	}
+	h.a = slices.Grow(h.a[:0], int(samplesLen))
+	for i := uint64(0); i < samplesLen; i++ {
+		var f float64
+		f, src, err = unmarshalStatsStateFloat64(src)
+		if err != nil {
+			return src, fmt.Errorf("cannot unmarshal sample #%d: %w", i, err)
+		}
+		h.a = append(h.a, f)
+	}
+	return src, nil
+}
+
 func (h *histogram) quantile(phi float64) float64 {
 	if len(h.a) == 0 {
 		return nan
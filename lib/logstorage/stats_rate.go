@@ -0,0 +1,96 @@
+package logstorage
+
+import (
+	"fmt"
+	"strconv"
+	"unsafe"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/encoding"
+)
+
+// statsRate calculates the average per-second rate of logs.
+//
+// See https://docs.victoriametrics.com/victorialogs/logsql/#rate-stats
+type statsRate struct {
+	// stepSeconds is the duration in seconds for calculating the per-second rate.
+	//
+	// It is initialized via pipeStats.initRateFuncs() call.
+	stepSeconds float64
+}
+
+func (sr *statsRate) String() string {
+	return "rate()"
+}
+
+func (sr *statsRate) neededFields() []string {
+	// There is no need in fetching any columns for rate() - the number of matching rows can be calculated as len(blockResult.timestamps)
+	return nil
+}
+
+func (sr *statsRate) newStatsProcessor() (statsProcessor, int) {
+	srp := &statsRateProcessor{
+		sr: sr,
+	}
+	return srp, int(unsafe.Sizeof(*srp))
+}
+
+type statsRateProcessor struct {
+	sr *statsRate
+
+	rowsCount uint64
+}
+
+func (srp *statsRateProcessor) updateStatsForAllRows(br *blockResult) int {
+	srp.rowsCount += uint64(len(br.timestamps))
+	return 0
+}
+
+func (srp *statsRateProcessor) updateStatsForRow(_ *blockResult, _ int) int {
+	srp.rowsCount++
+	return 0
+}
+
+func (srp *statsRateProcessor) mergeState(sfp statsProcessor) {
+	src := sfp.(*statsRateProcessor)
+	srp.rowsCount += src.rowsCount
+}
+
+func (srp *statsRateProcessor) finalizeStats() string {
+	rate := getPerSecondRate(float64(srp.rowsCount), srp.sr.stepSeconds)
+	return strconv.FormatFloat(rate, 'f', -1, 64)
+}
+
+func (srp *statsRateProcessor) exportState(dst []byte) []byte {
+	return encoding.MarshalVarUint64(dst, srp.rowsCount)
+}
+
+func (srp *statsRateProcessor) importState(src []byte) (int, error) {
+	n, tail, err := unmarshalStatsStateUint64(src)
+	if err != nil {
+		return 0, err
+	}
+	srp.rowsCount += n
+	return 0, checkStatsStateTail(tail)
+}
+
+// getPerSecondRate returns per-second rate for v over the given stepSeconds.
+//
+// NaN is returned if stepSeconds isn't positive.
+func getPerSecondRate(v, stepSeconds float64) float64 {
+	if stepSeconds <= 0 {
+		return nan
+	}
+	return v / stepSeconds
+}
+
+func parseStatsRate(lex *lexer) (*statsRate, error) {
+	fields, err := parseFieldNamesForStatsFunc(lex, "rate")
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) != 1 || fields[0] != "*" {
+		return nil, fmt.Errorf("unexpected args for 'rate' func: %q; it mustn't contain args", fields)
+	}
+	sr := &statsRate{}
+	return sr, nil
+}
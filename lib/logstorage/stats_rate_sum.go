@@ -0,0 +1,84 @@
+package logstorage
+
+import (
+	"slices"
+	"strconv"
+	"unsafe"
+)
+
+// statsRateSum calculates the average per-second rate for the sum of the given fields.
+//
+// See https://docs.victoriametrics.com/victorialogs/logsql/#rate_sum-stats
+type statsRateSum struct {
+	ss *statsSum
+
+	// stepSeconds is the duration in seconds for calculating the per-second rate.
+	//
+	// It is initialized via pipeStats.initRateFuncs() call.
+	stepSeconds float64
+}
+
+func (sr *statsRateSum) String() string {
+	return "rate_sum(" + fieldNamesString(sr.ss.fields) + ")"
+}
+
+func (sr *statsRateSum) neededFields() []string {
+	return sr.ss.neededFields()
+}
+
+func (sr *statsRateSum) newStatsProcessor() (statsProcessor, int) {
+	srp := &statsRateSumProcessor{
+		sr: sr,
+		ssp: statsSumProcessor{
+			ss:  sr.ss,
+			sum: nan,
+		},
+	}
+	return srp, int(unsafe.Sizeof(*srp))
+}
+
+type statsRateSumProcessor struct {
+	sr *statsRateSum
+
+	ssp statsSumProcessor
+}
+
+func (srp *statsRateSumProcessor) updateStatsForAllRows(br *blockResult) int {
+	return srp.ssp.updateStatsForAllRows(br)
+}
+
+func (srp *statsRateSumProcessor) updateStatsForRow(br *blockResult, rowIdx int) int {
+	return srp.ssp.updateStatsForRow(br, rowIdx)
+}
+
+func (srp *statsRateSumProcessor) mergeState(sfp statsProcessor) {
+	src := sfp.(*statsRateSumProcessor)
+	srp.ssp.mergeState(&src.ssp)
+}
+
+func (srp *statsRateSumProcessor) finalizeStats() string {
+	rate := getPerSecondRate(srp.ssp.sum, srp.sr.stepSeconds)
+	return strconv.FormatFloat(rate, 'f', -1, 64)
+}
+
+func (srp *statsRateSumProcessor) exportState(dst []byte) []byte {
+	return srp.ssp.exportState(dst)
+}
+
+func (srp *statsRateSumProcessor) importState(src []byte) (int, error) {
+	return srp.ssp.importState(src)
+}
+
+func parseStatsRateSum(lex *lexer) (*statsRateSum, error) {
+	fields, err := parseFieldNamesForStatsFunc(lex, "rate_sum")
+	if err != nil {
+		return nil, err
+	}
+	sr := &statsRateSum{
+		ss: &statsSum{
+			fields:       fields,
+			containsStar: slices.Contains(fields, "*"),
+		},
+	}
+	return sr, nil
+}
@@ -0,0 +1,60 @@
+package logstorage
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/encoding"
+)
+
+// The functions below are used by statsProcessor implementations for marshaling and unmarshaling their states
+// in exportState and importState methods.
+
+func unmarshalStatsStateUint64(src []byte) (uint64, []byte, error) {
+	n, nSize := encoding.UnmarshalVarUint64(src)
+	if nSize <= 0 {
+		return 0, src, fmt.Errorf("cannot unmarshal uint64 value")
+	}
+	return n, src[nSize:], nil
+}
+
+func marshalStatsStateFloat64(dst []byte, f float64) []byte {
+	return encoding.MarshalUint64(dst, math.Float64bits(f))
+}
+
+func unmarshalStatsStateFloat64(src []byte) (float64, []byte, error) {
+	if len(src) < 8 {
+		return 0, src, fmt.Errorf("cannot unmarshal float64 value from %d bytes; need at least 8 bytes", len(src))
+	}
+	f := math.Float64frombits(encoding.UnmarshalUint64(src))
+	return f, src[8:], nil
+}
+
+// unmarshalStatsStateStrings calls f for every string marshaled into src via encoding.MarshalVarUint64(len(a)) followed by encoding.MarshalBytes() for every item.
+//
+// f mustn't hold references to v after returning.
+func unmarshalStatsStateStrings(src []byte, f func(v []byte)) ([]byte, error) {
+	n, src, err := unmarshalStatsStateUint64(src)
+	if err != nil {
+		return src, fmt.Errorf("cannot unmarshal the number of items: %w", err)
+	}
+	if n > uint64(len(src)) {
+		return src, fmt.Errorf("too big number of items: %d; it cannot exceed %d", n, len(src))
+	}
+	for i := uint64(0); i < n; i++ {
+		v, nSize := encoding.UnmarshalBytes(src)
+		if nSize <= 0 {
+			return src, fmt.Errorf("cannot unmarshal item #%d", i)
+		}
+		src = src[nSize:]
+		f(v)
+	}
+	return src, nil
+}
+
+func checkStatsStateTail(tail []byte) error {
+	if len(tail) > 0 {
+		return fmt.Errorf("unexpected non-empty tail left after unmarshaling stats state; len(tail)=%d", len(tail))
+	}
+	return nil
+}
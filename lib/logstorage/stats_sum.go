@@ -96,13 +96,35 @@ func (ssp *statsSumProcessor) updateStatsForRow(br *blockResult, rowIdx int) int
 
 func (ssp *statsSumProcessor) mergeState(sfp statsProcessor) {
 	src := sfp.(*statsSumProcessor)
-	ssp.sum += src.sum
+	if math.IsNaN(src.sum) {
+		return
+	}
+	if math.IsNaN(ssp.sum) {
+		ssp.sum = src.sum
+	} else {
+		ssp.sum += src.sum
+	}
 }
 
 func (ssp *statsSumProcessor) finalizeStats() string {
 	return strconv.FormatFloat(ssp.sum, 'f', -1, 64)
 }
 
+func (ssp *statsSumProcessor) exportState(dst []byte) []byte {
+	return marshalStatsStateFloat64(dst, ssp.sum)
+}
+
+func (ssp *statsSumProcessor) importState(src []byte) (int, error) {
+	f, tail, err := unmarshalStatsStateFloat64(src)
+	if err != nil {
+		return 0, err
+	}
+	ssp.mergeState(&statsSumProcessor{
+		sum: f,
+	})
+	return 0, checkStatsStateTail(tail)
+}
+
 func parseStatsSum(lex *lexer) (*statsSum, error) {
 	fields, err := parseFieldNamesForStatsFunc(lex, "sum")
 	if err != nil {
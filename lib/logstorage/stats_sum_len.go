@@ -4,6 +4,8 @@ import (
 	"slices"
 	"strconv"
 	"unsafe"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/encoding"
 )
 
 type statsSumLen struct {
@@ -76,6 +78,19 @@ func (ssp *statsSumLenProcessor) finalizeStats() string {
 	return strconv.FormatUint(ssp.sumLen, 10)
 }
 
+func (ssp *statsSumLenProcessor) exportState(dst []byte) []byte {
+	return encoding.MarshalVarUint64(dst, ssp.sumLen)
+}
+
+func (ssp *statsSumLenProcessor) importState(src []byte) (int, error) {
+	n, tail, err := unmarshalStatsStateUint64(src)
+	if err != nil {
+		return 0, err
+	}
+	ssp.sumLen += n
+	return 0, checkStatsStateTail(tail)
+}
+
 func parseStatsSumLen(lex *lexer) (*statsSumLen, error) {
 	fields, err := parseFieldNamesForStatsFunc(lex, "sum_len")
 	if err != nil {
@@ -8,6 +8,7 @@ import (
 	"unsafe"
 
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/encoding"
 )
 
 type statsUniqValues struct {
@@ -214,6 +215,33 @@ func (sup *statsUniqValuesProcessor) finalizeStats() string {
 	return marshalJSONArray(items)
 }
 
+func (sup *statsUniqValuesProcessor) exportState(dst []byte) []byte {
+	dst = encoding.MarshalVarUint64(dst, uint64(len(sup.m)))
+	for k := range sup.m {
+		dst = encoding.MarshalBytes(dst, bytesutil.ToUnsafeBytes(k))
+	}
+	return dst
+}
+
+func (sup *statsUniqValuesProcessor) importState(src []byte) (int, error) {
+	stateSizeIncrease := 0
+	m := sup.m
+	tail, err := unmarshalStatsStateStrings(src, func(v []byte) {
+		if sup.limitReached() {
+			return
+		}
+		if _, ok := m[string(v)]; !ok {
+			vCopy := string(v)
+			m[vCopy] = struct{}{}
+			stateSizeIncrease += len(vCopy) + int(unsafe.Sizeof(vCopy))
+		}
+	})
+	if err != nil {
+		return stateSizeIncrease, err
+	}
+	return stateSizeIncrease, checkStatsStateTail(tail)
+}
+
 func (sup *statsUniqValuesProcessor) limitReached() bool {
 	limit := sup.su.limit
 	return limit > 0 && uint64(len(sup.m)) >= limit
@@ -5,6 +5,9 @@ import (
 	"slices"
 	"strings"
 	"unsafe"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/encoding"
 )
 
 type statsValues struct {
@@ -181,6 +184,30 @@ func (svp *statsValuesProcessor) finalizeStats() string {
 	return marshalJSONArray(items)
 }
 
+func (svp *statsValuesProcessor) exportState(dst []byte) []byte {
+	dst = encoding.MarshalVarUint64(dst, uint64(len(svp.values)))
+	for _, v := range svp.values {
+		dst = encoding.MarshalBytes(dst, bytesutil.ToUnsafeBytes(v))
+	}
+	return dst
+}
+
+func (svp *statsValuesProcessor) importState(src []byte) (int, error) {
+	stateSizeIncrease := 0
+	tail, err := unmarshalStatsStateStrings(src, func(v []byte) {
+		if svp.limitReached() {
+			return
+		}
+		vCopy := string(v)
+		svp.values = append(svp.values, vCopy)
+		stateSizeIncrease += len(vCopy) + int(unsafe.Sizeof(vCopy))
+	})
+	if err != nil {
+		return stateSizeIncrease, err
+	}
+	return stateSizeIncrease, checkStatsStateTail(tail)
+}
+
 func (svp *statsValuesProcessor) limitReached() bool {
 	limit := svp.sv.limit
 	return limit > 0 && uint64(len(svp.values)) >= limit
@@ -8,6 +8,7 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/backup/backupnames"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fs"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/memory"
@@ -23,6 +24,12 @@ type StorageStats struct {
 	// RowsDroppedTooSmallTimestamp is the number of rows dropped during data ingestion because their timestamp is bigger than the maximum allowed
 	RowsDroppedTooSmallTimestamp uint64
 
+	// RowsDroppedByRetentionRules is the number of rows dropped during background merges because they are outside the retention rules
+	RowsDroppedByRetentionRules uint64
+
+	// RowsDeleted is the number of rows physically deleted during background merges after Storage.DeleteRows calls
+	RowsDeleted uint64
+
 	// PartitionsCount is the number of partitions in the storage
 	PartitionsCount uint64
 
@@ -45,6 +52,12 @@ type StorageConfig struct {
 	// Older data is automatically deleted.
 	Retention time.Duration
 
+	// RetentionRules contains optional retention rules for log entries matching the given tenants and stream filters.
+	//
+	// The first matching rule is applied to every log stream. Log entries outside the matching rule are deleted during background merges.
+	// Rules with retention exceeding Retention are ineffective, since all the data older than Retention is deleted.
+	RetentionRules []*RetentionRule
+
 	// FlushInterval is the interval for flushing the in-memory data to disk at the Storage
 	FlushInterval time.Duration
 
@@ -72,6 +85,8 @@ type StorageConfig struct {
 type Storage struct {
 	rowsDroppedTooBigTimestamp   atomic.Uint64
 	rowsDroppedTooSmallTimestamp atomic.Uint64
+	rowsDroppedByRetentionRules  atomic.Uint64
+	rowsDeleted                  atomic.Uint64
 
 	// path is the path to the Storage directory
 	path string
@@ -81,6 +96,25 @@ type Storage struct {
 	// older data is automatically deleted
 	retention time.Duration
 
+	// retentionRules contains retention rules for log entries matching the given tenants and stream filters
+	retentionRules []*RetentionRule
+
+	// deleteTasks contains tasks for deleting log entries registered via DeleteRows.
+	//
+	// It must be accessed under deleteTasksLock. The slice mustn't be modified in place, since it may be shared with readers.
+	deleteTasks []*deleteTask
+
+	// deleteTasksSeq is the sequence number of the last registered delete task.
+	//
+	// It must be accessed under deleteTasksLock.
+	deleteTasksSeq uint64
+
+	// deleteTasksLock protects deleteTasks and deleteTasksSeq.
+	deleteTasksLock sync.Mutex
+
+	// snapshotLock prevents from concurrent creation of snapshots.
+	snapshotLock sync.Mutex
+
 	// flushInterval is the interval for flushing in-memory data to disk
 	flushInterval time.Duration
 
@@ -237,6 +271,17 @@ func MustOpenStorage(path string, cfg *StorageConfig) *Storage {
 
 	flockF := fs.MustCreateFlockFile(path)
 
+	// Check whether restore process finished successfully
+	restoreLockF := filepath.Join(path, backupnames.RestoreInProgressFilename)
+	if fs.IsPathExist(restoreLockF) {
+		logger.Panicf("FATAL: incomplete vmrestore run; run vmrestore again or remove lock file %q", restoreLockF)
+	}
+
+	// Pre-create snapshots directory if it is missing.
+	snapshotsPath := filepath.Join(path, snapshotsDirname)
+	fs.MustMkdirIfNotExist(snapshotsPath)
+	fs.MustRemoveTemporaryDirs(snapshotsPath)
+
 	// Load caches
 	mem := memory.Allowed()
 	streamIDCachePath := filepath.Join(path, cacheDirname, streamIDCacheFilename)
@@ -249,6 +294,7 @@ func MustOpenStorage(path string, cfg *StorageConfig) *Storage {
 	s := &Storage{
 		path:                  path,
 		retention:             retention,
+		retentionRules:        cfg.RetentionRules,
 		flushInterval:         flushInterval,
 		futureRetention:       futureRetention,
 		minFreeDiskSpaceBytes: minFreeDiskSpaceBytes,
@@ -262,6 +308,12 @@ func MustOpenStorage(path string, cfg *StorageConfig) *Storage {
 		filterStreamCache: filterStreamCache,
 	}
 
+	// Load delete tasks before opening partitions, since they are used during background merges.
+	s.deleteTasks = mustReadDeleteTasks(path)
+	for _, dt := range s.deleteTasks {
+		s.deleteTasksSeq = max(s.deleteTasksSeq, dt.Seq)
+	}
+
 	partitionsPath := filepath.Join(path, partitionsDirname)
 	fs.MustMkdirIfNotExist(partitionsPath)
 	des := fs.MustReadDir(partitionsPath)
@@ -304,6 +356,12 @@ func MustOpenStorage(path string, cfg *StorageConfig) *Storage {
 	ptws = ptws[:j]
 
 	s.partitions = ptws
+
+	// Resume re-writing parts with log entries deleted before the restart.
+	for _, dt := range s.deleteTasks {
+		s.startDeleteMergers(dt)
+	}
+
 	s.runRetentionWatcher()
 	return s
 }
@@ -352,6 +410,11 @@ func (s *Storage) watchRetention() {
 			ptw.decRef()
 		}
 
+		s.applyRetentionRules()
+
+		// Delete tasks for the deleted partitions are no longer needed.
+		s.removeOutdatedDeleteTasks(minAllowedDay * nsecPerDay)
+
 		select {
 		case <-s.stopCh:
 			return
@@ -360,6 +423,37 @@ func (s *Storage) watchRetention() {
 	}
 }
 
+// applyRetentionRules starts background merges for partitions, which fully went out of some retention rules.
+//
+// Partitions, which went out of retention rules only partially, are processed by regular background merges.
+func (s *Storage) applyRetentionRules() {
+	if len(s.retentionRules) == 0 {
+		return
+	}
+
+	s.partitionsLock.Lock()
+	ptws := append([]*partitionWrapper{}, s.partitions...)
+	for _, ptw := range ptws {
+		ptw.incRef()
+	}
+	s.partitionsLock.Unlock()
+
+	now := time.Now().UnixNano()
+	for _, ptw := range ptws {
+		maxTimestamp := (ptw.day + 1) * nsecPerDay
+		var rules []string
+		for _, rr := range s.retentionRules {
+			if now-rr.Retention.Nanoseconds() >= maxTimestamp {
+				rules = append(rules, rr.String())
+			}
+		}
+		if len(rules) > 0 {
+			ptw.pt.ddb.mustApplyRetentionRules(rules)
+		}
+		ptw.decRef()
+	}
+}
+
 func (s *Storage) getMinAllowedDay() int64 {
 	return time.Now().UTC().Add(-s.retention).UnixNano() / nsecPerDay
 }
@@ -529,6 +623,8 @@ func (s *Storage) getPartitionForDay(day int64) *partitionWrapper {
 func (s *Storage) UpdateStats(ss *StorageStats) {
 	ss.RowsDroppedTooBigTimestamp += s.rowsDroppedTooBigTimestamp.Load()
 	ss.RowsDroppedTooSmallTimestamp += s.rowsDroppedTooSmallTimestamp.Load()
+	ss.RowsDroppedByRetentionRules += s.rowsDroppedByRetentionRules.Load()
+	ss.RowsDeleted += s.rowsDeleted.Load()
 
 	s.partitionsLock.Lock()
 	ss.PartitionsCount += uint64(len(s.partitions))
@@ -0,0 +1,506 @@
+package logstorage
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fs"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+)
+
+// deleteTask is a task for deleting log entries matching the given filter at the given tenant.
+//
+// Log entries matching the task are hidden from queries and are physically deleted during background merges.
+type deleteTask struct {
+	// TenantID is the tenant for the deleted log entries.
+	TenantID TenantID `json:"tenantID"`
+
+	// Filter is LogsQL filter for the deleted log entries.
+	Filter string `json:"filter"`
+
+	// Timestamp is the time in nanoseconds when the task has been created.
+	//
+	// It is used for evaluating relative time filters in the Filter.
+	Timestamp int64 `json:"timestamp"`
+
+	// Seq is the sequence number of the task at Storage.
+	//
+	// Parts with the DeleteTasksSeq smaller than Seq may contain log entries deleted by the task.
+	// Sequence numbers are persisted together with the task, since parts persist DeleteTasksSeq in their metadata.
+	Seq uint64 `json:"seq"`
+
+	// f is the parsed Filter.
+	f filter
+
+	// minTimestamp and maxTimestamp is the time range for the deleted log entries.
+	minTimestamp int64
+	maxTimestamp int64
+
+	// pendingPartitions is the number of partitions, which must be re-written before the task can be removed.
+	pendingPartitions atomic.Int64
+}
+
+func newDeleteTask(tenantID TenantID, filterStr string, timestamp int64) (*deleteTask, error) {
+	q, err := ParseQueryAtTimestamp(filterStr, timestamp)
+	if err != nil {
+		return nil, err
+	}
+	if len(q.pipes) > 0 {
+		return nil, fmt.Errorf("pipes aren't allowed in the filter for deleted logs; got [%s]", q)
+	}
+	ft, _ := getCommonFilterTime(q.f)
+
+	// Log entries ingested after the task creation mustn't be deleted.
+	maxTimestamp := min(ft.maxTimestamp, timestamp)
+
+	dt := &deleteTask{
+		TenantID:  tenantID,
+		Filter:    q.String(),
+		Timestamp: timestamp,
+
+		f:            q.f,
+		minTimestamp: ft.minTimestamp,
+		maxTimestamp: maxTimestamp,
+	}
+	return dt, nil
+}
+
+// DeleteRows deletes log entries matching q at the given tenantID.
+//
+// The deleted log entries become invisible to queries immediately after the call,
+// while they are physically deleted from the storage during background merges.
+// Log entries with timestamps bigger than the call time aren't deleted.
+// Log entries ingested after the call aren't deleted, even if their timestamps are inside the deleted time range.
+//
+// q mustn't contain pipes. It is recommended to limit the time range for the deleted log entries via _time filter at q,
+// since this reduces the number of parts, which must be re-written.
+func (s *Storage) DeleteRows(tenantID TenantID, q *Query) error {
+	dt, err := newDeleteTask(tenantID, q.String(), time.Now().UnixNano())
+	if err != nil {
+		return err
+	}
+
+	s.registerDeleteTask(dt)
+
+	// Start re-writing parts with the deleted log entries in the background.
+	s.startDeleteMergers(dt)
+
+	return nil
+}
+
+// registerDeleteTask registers dt at s and persists it to disk.
+func (s *Storage) registerDeleteTask(dt *deleteTask) {
+	s.deleteTasksLock.Lock()
+	// Use the task creation time as the sequence number, so it remains bigger than DeleteTasksSeq
+	// at the existing parts after the restart, when all the previous delete tasks are removed.
+	s.deleteTasksSeq = max(s.deleteTasksSeq+1, uint64(dt.Timestamp))
+	dt.Seq = s.deleteTasksSeq
+	deleteTasks := append([]*deleteTask{}, s.deleteTasks...)
+	deleteTasks = append(deleteTasks, dt)
+	mustWriteDeleteTasks(s.path, deleteTasks)
+	s.deleteTasks = deleteTasks
+	s.deleteTasksLock.Unlock()
+}
+
+// startDeleteMergers starts re-writing parts with log entries deleted by dt at all the partitions on the dt time range.
+//
+// dt is removed from s after all these partitions are re-written.
+func (s *Storage) startDeleteMergers(dt *deleteTask) {
+	s.partitionsLock.Lock()
+	ptws := s.partitions
+	minDay := dt.minTimestamp / nsecPerDay
+	n := sort.Search(len(ptws), func(i int) bool {
+		return ptws[i].day >= minDay
+	})
+	ptws = ptws[n:]
+	maxDay := dt.maxTimestamp / nsecPerDay
+	n = sort.Search(len(ptws), func(i int) bool {
+		return ptws[i].day > maxDay
+	})
+	ptws = ptws[:n]
+	dt.pendingPartitions.Store(int64(len(ptws)))
+	for _, ptw := range ptws {
+		ptw.pt.ddb.startDeleteMerger(dt)
+	}
+	s.partitionsLock.Unlock()
+
+	if len(ptws) == 0 {
+		// There are no log entries to delete.
+		s.removeDeleteTask(dt)
+	}
+}
+
+// markDeleteTaskPartitionDone must be called when all the parts with log entries deleted by dt are re-written at some partition.
+//
+// dt is removed from s after all the partitions on the dt time range are re-written.
+func (s *Storage) markDeleteTaskPartitionDone(dt *deleteTask) {
+	if dt.pendingPartitions.Add(-1) > 0 {
+		return
+	}
+	s.removeDeleteTask(dt)
+}
+
+// removeDeleteTask removes dt from s, since the log entries deleted by dt are physically removed from the storage.
+func (s *Storage) removeDeleteTask(dt *deleteTask) {
+	s.deleteTasksLock.Lock()
+	defer s.deleteTasksLock.Unlock()
+
+	var deleteTasks []*deleteTask
+	for _, dtExisting := range s.deleteTasks {
+		if dtExisting != dt {
+			deleteTasks = append(deleteTasks, dtExisting)
+		}
+	}
+	if len(deleteTasks) == len(s.deleteTasks) {
+		return
+	}
+	mustWriteDeleteTasks(s.path, deleteTasks)
+	s.deleteTasks = deleteTasks
+}
+
+func (s *Storage) getDeleteTasks() []*deleteTask {
+	s.deleteTasksLock.Lock()
+	deleteTasks := s.deleteTasks
+	s.deleteTasksLock.Unlock()
+
+	return deleteTasks
+}
+
+// getDeleteTasksWithSeq returns the registered delete tasks together with the sequence number of the last registered task.
+func (s *Storage) getDeleteTasksWithSeq() ([]*deleteTask, uint64) {
+	s.deleteTasksLock.Lock()
+	deleteTasks := s.deleteTasks
+	seq := s.deleteTasksSeq
+	s.deleteTasksLock.Unlock()
+
+	return deleteTasks, seq
+}
+
+// removeOutdatedDeleteTasks removes delete tasks for log entries with timestamps smaller than minTimestamp.
+func (s *Storage) removeOutdatedDeleteTasks(minTimestamp int64) {
+	s.deleteTasksLock.Lock()
+	defer s.deleteTasksLock.Unlock()
+
+	var deleteTasks []*deleteTask
+	for _, dt := range s.deleteTasks {
+		if dt.maxTimestamp >= minTimestamp {
+			deleteTasks = append(deleteTasks, dt)
+		}
+	}
+	if len(deleteTasks) == len(s.deleteTasks) {
+		return
+	}
+	mustWriteDeleteTasks(s.path, deleteTasks)
+	s.deleteTasks = deleteTasks
+}
+
+func mustReadDeleteTasks(path string) []*deleteTask {
+	deleteTasksPath := filepath.Join(path, deleteTasksFilename)
+	if !fs.IsPathExist(deleteTasksPath) {
+		return nil
+	}
+	data, err := os.ReadFile(deleteTasksPath)
+	if err != nil {
+		logger.Panicf("FATAL: cannot read %s: %s", deleteTasksPath, err)
+	}
+	var a []*deleteTask
+	if err := json.Unmarshal(data, &a); err != nil {
+		logger.Panicf("FATAL: cannot parse %s: %s", deleteTasksPath, err)
+	}
+	deleteTasks := make([]*deleteTask, len(a))
+	for i, dtRaw := range a {
+		dt, err := newDeleteTask(dtRaw.TenantID, dtRaw.Filter, dtRaw.Timestamp)
+		if err != nil {
+			logger.Panicf("FATAL: cannot parse filter [%s] at %s: %s", dtRaw.Filter, deleteTasksPath, err)
+		}
+		dt.Seq = dtRaw.Seq
+		if dt.Seq == 0 {
+			// The task has been created by the previous version without sequence numbers.
+			// Use the task creation time as the sequence number in the same way as registerDeleteTask does.
+			dt.Seq = uint64(dt.Timestamp)
+		}
+		deleteTasks[i] = dt
+	}
+	return deleteTasks
+}
+
+func mustWriteDeleteTasks(path string, deleteTasks []*deleteTask) {
+	data, err := json.Marshal(deleteTasks)
+	if err != nil {
+		logger.Panicf("BUG: cannot marshal delete tasks to JSON: %s", err)
+	}
+	deleteTasksPath := filepath.Join(path, deleteTasksFilename)
+	fs.MustWriteAtomic(deleteTasksPath, data, true)
+}
+
+// filterDeleteTask matches log entries deleted by the given delete task.
+type filterDeleteTask struct {
+	dt *deleteTask
+
+	// f is the filter from dt with initialized stream filters.
+	f filter
+}
+
+func (fd *filterDeleteTask) String() string {
+	return fmt.Sprintf("delete_task(tenant=%d:%d, %s)", fd.dt.TenantID.AccountID, fd.dt.TenantID.ProjectID, fd.f)
+}
+
+func (fd *filterDeleteTask) updateNeededFields(neededFields fieldsSet) {
+	fd.f.updateNeededFields(neededFields)
+}
+
+func (fd *filterDeleteTask) applyToBlockResult(br *blockResult, bm *bitmap) {
+	if !br.streamID.tenantID.equal(&fd.dt.TenantID) {
+		bm.resetBits()
+		return
+	}
+	fd.f.applyToBlockResult(br, bm)
+}
+
+func (fd *filterDeleteTask) apply(bs *blockSearch, bm *bitmap) {
+	if bs.bsw.p.ph.DeleteTasksSeq >= fd.dt.Seq {
+		// The part has been created after the delete task registration, so its log entries mustn't be deleted by the task.
+		bm.resetBits()
+		return
+	}
+	bh := &bs.bsw.bh
+	if !fd.dt.matchBlock(&bh.streamID, bh.timestampsHeader.minTimestamp, bh.timestampsHeader.maxTimestamp) {
+		bm.resetBits()
+		return
+	}
+	fd.f.apply(bs, bm)
+}
+
+// matchBlock returns true if the block with the given sid and the given time range may contain log entries deleted by dt.
+func (dt *deleteTask) matchBlock(sid *streamID, minTimestamp, maxTimestamp int64) bool {
+	if !sid.tenantID.equal(&dt.TenantID) {
+		return false
+	}
+	return minTimestamp <= dt.maxTimestamp && maxTimestamp >= dt.minTimestamp
+}
+
+// addDeleteFilter returns f with additional filter, which excludes log entries deleted via Storage.DeleteRows.
+//
+// tenantIDs, minTimestamp and maxTimestamp are used for limiting the number of delete tasks to check.
+// All the tenants are checked if tenantIDs is empty.
+func (pt *partition) addDeleteFilter(f filter, tenantIDs []TenantID, minTimestamp, maxTimestamp int64) filter {
+	var filters []filter
+	for _, dt := range pt.s.getDeleteTasks() {
+		if dt.maxTimestamp < minTimestamp || dt.minTimestamp > maxTimestamp {
+			continue
+		}
+		if len(tenantIDs) > 0 && !hasTenantID(tenantIDs, &dt.TenantID) {
+			continue
+		}
+		fdt := dt.f
+		if hasStreamFilters(fdt) {
+			fdt = initStreamFilters([]TenantID{dt.TenantID}, pt.idb, fdt)
+		}
+		filters = append(filters, &filterDeleteTask{
+			dt: dt,
+			f:  fdt,
+		})
+	}
+	if len(filters) == 0 {
+		return f
+	}
+	return &filterAnd{
+		filters: []filter{
+			f,
+			&filterNot{
+				f: &filterOr{
+					filters: filters,
+				},
+			},
+		},
+	}
+}
+
+func hasTenantID(tenantIDs []TenantID, tenantID *TenantID) bool {
+	for i := range tenantIDs {
+		if tenantIDs[i].equal(tenantID) {
+			return true
+		}
+	}
+	return false
+}
+
+// deleteMatcher deletes log entries matching delete tasks during background merges.
+type deleteMatcher struct {
+	pt *partition
+
+	// deleteTasks contains delete tasks for the merged parts.
+	deleteTasks []*deleteTask
+
+	// filters contains filters for deleteTasks applicable to the current block.
+	filters []filter
+
+	// rcs and br are used for applying filters to log entries.
+	rcs []resultColumn
+	br  blockResult
+
+	// rowsDeleted is the number of log entries deleted during the merge.
+	rowsDeleted uint64
+}
+
+// newDeleteMatcher returns deleteMatcher for log entries at pt on the given time range from the given deleteTasksAll.
+//
+// Delete tasks with sequence numbers smaller or equal to minDeleteTasksSeq are skipped, since they are already applied to all the merged parts.
+// nil is returned if there are no delete tasks for the given time range.
+func newDeleteMatcher(pt *partition, deleteTasksAll []*deleteTask, minDeleteTasksSeq uint64, minTimestamp, maxTimestamp int64) *deleteMatcher {
+	var deleteTasks []*deleteTask
+	for _, dt := range deleteTasksAll {
+		if dt.Seq > minDeleteTasksSeq && dt.maxTimestamp >= minTimestamp && dt.minTimestamp <= maxTimestamp {
+			deleteTasks = append(deleteTasks, dt)
+		}
+	}
+	if len(deleteTasks) == 0 {
+		return nil
+	}
+	return &deleteMatcher{
+		pt:          pt,
+		deleteTasks: deleteTasks,
+	}
+}
+
+// needDeleteRows returns true if bd may contain log entries, which must be deleted.
+//
+// deleteTasksSeq is the sequence number of the last delete task applied to the part with bd.
+func (dm *deleteMatcher) needDeleteRows(bd *blockData, deleteTasksSeq uint64) bool {
+	if dm == nil {
+		return false
+	}
+	for _, dt := range dm.deleteTasks {
+		if dt.Seq > deleteTasksSeq && dt.matchBlock(&bd.streamID, bd.timestampsData.minTimestamp, bd.timestampsData.maxTimestamp) {
+			return true
+		}
+	}
+	return false
+}
+
+// deleteRows deletes log entries matching dm delete tasks from rs, starting from the rowsOffset position.
+//
+// All the log entries in rs starting from rowsOffset must belong to the given sid and to the part
+// with the given deleteTasksSeq. Delete tasks with sequence numbers up to deleteTasksSeq are skipped,
+// since the log entries have been ingested after these tasks.
+func (dm *deleteMatcher) deleteRows(rs *rows, rowsOffset int, sid *streamID, deleteTasksSeq uint64) {
+	if dm == nil {
+		return
+	}
+	timestamps := rs.timestamps[rowsOffset:]
+	rows := rs.rows[rowsOffset:]
+	if len(timestamps) == 0 {
+		return
+	}
+
+	dm.filters = dm.filters[:0]
+	for _, dt := range dm.deleteTasks {
+		if dt.Seq > deleteTasksSeq && dt.matchBlock(sid, timestamps[0], timestamps[len(timestamps)-1]) {
+			dm.filters = append(dm.filters, &filterDeleteTask{
+				dt: dt,
+				f:  dt.f,
+			})
+		}
+	}
+	if len(dm.filters) == 0 {
+		return
+	}
+
+	br := dm.initBlockResult(sid, timestamps, rows)
+	bm := getBitmap(len(timestamps))
+	bm.setBits()
+	fo := &filterOr{
+		filters: dm.filters,
+	}
+	fo.applyToBlockResult(br, bm)
+	if bm.isZero() {
+		putBitmap(bm)
+		return
+	}
+
+	// Remove the matching log entries from rs.
+	bmKeep := getBitmap(len(timestamps))
+	bmKeep.setBits()
+	bmKeep.andNot(bm)
+	putBitmap(bm)
+
+	dst := 0
+	bmKeep.forEachSetBitReadonly(func(idx int) {
+		timestamps[dst] = timestamps[idx]
+		rows[dst] = rows[idx]
+		dst++
+	})
+	putBitmap(bmKeep)
+
+	deleted := len(timestamps) - dst
+	clear(rows[dst:])
+	rs.timestamps = rs.timestamps[:rowsOffset+dst]
+	rs.rows = rs.rows[:rowsOffset+dst]
+	dm.rowsDeleted += uint64(deleted)
+}
+
+func (dm *deleteMatcher) initBlockResult(sid *streamID, timestamps []int64, rows [][]Field) *blockResult {
+	columnIdxs := make(map[string]int)
+	rcs := dm.rcs[:0]
+	for rowIdx, fields := range rows {
+		for _, f := range fields {
+			name := getCanonicalColumnName(f.Name)
+			if name == "_time" || name == "_stream" {
+				continue
+			}
+			idx, ok := columnIdxs[name]
+			if !ok {
+				idx = len(rcs)
+				columnIdxs[name] = idx
+				rcs = append(rcs, resultColumn{
+					name: name,
+				})
+			}
+			rc := &rcs[idx]
+			for len(rc.values) < rowIdx {
+				// Fill missing values for the previous rows.
+				rc.addValue("")
+			}
+			rc.addValue(f.Value)
+		}
+	}
+	for i := range rcs {
+		rc := &rcs[i]
+		for len(rc.values) < len(rows) {
+			rc.addValue("")
+		}
+	}
+	dm.rcs = rcs
+
+	br := &dm.br
+	br.setResultColumns(rcs)
+	br.streamID = *sid
+	br.timestamps = append(br.timestamps[:0], timestamps...)
+	br.addTimeColumn()
+
+	bb := bbPool.Get()
+	bb.B = dm.pt.appendStreamTagsByStreamID(bb.B[:0], sid)
+	if len(bb.B) > 0 {
+		st := GetStreamTags()
+		mustUnmarshalStreamTags(st, bb.B)
+		bb.B = st.marshalString(bb.B[:0])
+		PutStreamTags(st)
+		br.addConstColumn("_stream", bytesutil.ToUnsafeString(bb.B))
+	}
+	bbPool.Put(bb)
+
+	return br
+}
+
+// addRowsDeleted registers the deleted log entries at the storage.
+func (dm *deleteMatcher) addRowsDeleted() {
+	if dm != nil {
+		dm.pt.s.rowsDeleted.Add(dm.rowsDeleted)
+	}
+}
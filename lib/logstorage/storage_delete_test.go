@@ -0,0 +1,296 @@
+package logstorage
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fs"
+)
+
+func TestStorageDeleteRows(t *testing.T) {
+	const path = "TestStorageDeleteRows"
+
+	sc := &StorageConfig{
+		Retention: 24 * time.Hour,
+	}
+	s := MustOpenStorage(path, sc)
+
+	tenantID1 := TenantID{AccountID: 1, ProjectID: 2}
+	tenantID2 := TenantID{AccountID: 3, ProjectID: 4}
+	baseTimestamp := time.Now().UnixNano() - 3600*1e9
+	for _, tenantID := range []TenantID{tenantID1, tenantID2} {
+		for _, app := range []string{"foo", "bar"} {
+			lr := GetLogRows([]string{"app"}, nil)
+			for i := 0; i < 100; i++ {
+				fields := []Field{
+					{
+						Name:  "app",
+						Value: app,
+					},
+					{
+						Name:  "_msg",
+						Value: fmt.Sprintf("message %d", i%10),
+					},
+				}
+				lr.MustAdd(tenantID, baseTimestamp+int64(i)*1e6, fields)
+			}
+			s.MustAddRows(lr)
+			PutLogRows(lr)
+		}
+	}
+	s.debugFlush()
+
+	var pts []*partition
+	s.partitionsLock.Lock()
+	for _, ptw := range s.partitions {
+		pts = append(pts, ptw.pt)
+	}
+	s.partitionsLock.Unlock()
+	for _, pt := range pts {
+		pt.ddb.mustFlushInmemoryPartsToFiles(true)
+	}
+
+	getRowsCount := func(tenantID TenantID, qStr string) uint32 {
+		t.Helper()
+
+		q := mustParseQuery(qStr)
+		var rowsCount atomic.Uint32
+		writeBlock := func(_ uint, timestamps []int64, _ []BlockColumn) {
+			rowsCount.Add(uint32(len(timestamps)))
+		}
+		checkErr(t, s.RunQuery(context.Background(), []TenantID{tenantID}, q, writeBlock))
+		return rowsCount.Load()
+	}
+
+	// Pipes aren't allowed in the filter for deleted logs
+	if err := s.DeleteRows(tenantID1, mustParseQuery(`* | limit 10`)); err == nil {
+		t.Fatalf("expecting non-nil error for query with pipes")
+	}
+
+	// Register the delete task without starting background merges, so they can be verified below.
+	dtTimestamp := time.Now().UnixNano()
+	dt, err := newDeleteTask(tenantID1, `_stream:{app="foo"} ("message 3" or "message 5")`, dtTimestamp)
+	if err != nil {
+		t.Fatalf("cannot create delete task: %s", err)
+	}
+	if dt.maxTimestamp != dtTimestamp {
+		t.Fatalf("unexpected maxTimestamp for the delete task; got %d; want %d", dt.maxTimestamp, dtTimestamp)
+	}
+	s.registerDeleteTask(dt)
+
+	// Deleted log entries must be hidden from queries
+	f := func(tenantID TenantID, qStr string, rowsCountExpected uint32) {
+		t.Helper()
+		if n := getRowsCount(tenantID, qStr); n != rowsCountExpected {
+			t.Fatalf("unexpected number of rows for tenant %s and query [%s]; got %d; want %d", tenantID.String(), qStr, n, rowsCountExpected)
+		}
+	}
+	checkQueries := func() {
+		t.Helper()
+		f(tenantID1, `*`, 180)
+		f(tenantID1, `_stream:{app="foo"}`, 80)
+		f(tenantID1, `_stream:{app="bar"}`, 100)
+		f(tenantID1, `"message 3"`, 10)
+		f(tenantID2, `*`, 200)
+		f(tenantID2, `_stream:{app="foo"} "message 3"`, 10)
+	}
+	checkQueries()
+
+	// Deleted log entries must be physically removed by background merges
+	for _, pt := range pts {
+		if !pt.ddb.deleteMerger(dt) {
+			t.Fatalf("unexpected incomplete re-writing of parts with deleted log entries")
+		}
+	}
+	var ss StorageStats
+	s.UpdateStats(&ss)
+	if n := ss.RowsCount(); n != 380 {
+		t.Fatalf("unexpected number of rows after deleting log entries; got %d; want %d", n, 380)
+	}
+	if ss.RowsDeleted != 20 {
+		t.Fatalf("unexpected number of deleted rows; got %d; want %d", ss.RowsDeleted, 20)
+	}
+	checkQueries()
+
+	// Delete tasks must survive storage restart
+	s.MustClose()
+	deleteTasks := mustReadDeleteTasks(path)
+	if len(deleteTasks) != 1 {
+		t.Fatalf("unexpected number of delete tasks after restart; got %d; want 1", len(deleteTasks))
+	}
+	if deleteTasks[0].Filter != dt.Filter {
+		t.Fatalf("unexpected filter for the delete task after restart; got %s; want %s", deleteTasks[0].Filter, dt.Filter)
+	}
+
+	// Delete tasks must be removed after re-writing all the parts with deleted log entries
+	s = MustOpenStorage(path, sc)
+	waitForDeleteTasksRemoval(t, s)
+	checkQueries()
+
+	// Log entries ingested after the deletion must be visible
+	dt, err = newDeleteTask(tenantID1, `"message 7"`, time.Now().UnixNano())
+	if err != nil {
+		t.Fatalf("cannot create delete task: %s", err)
+	}
+	s.registerDeleteTask(dt)
+	f(tenantID1, `"message 7"`, 0)
+	lr := GetLogRows([]string{"app"}, nil)
+	lr.MustAdd(tenantID1, time.Now().UnixNano(), []Field{
+		{
+			Name:  "app",
+			Value: "foo",
+		},
+		{
+			Name:  "_msg",
+			Value: "message 7",
+		},
+	})
+	s.MustAddRows(lr)
+	PutLogRows(lr)
+	s.debugFlush()
+	f(tenantID1, `"message 7"`, 1)
+	s.startDeleteMergers(dt)
+	waitForDeleteTasksRemoval(t, s)
+	f(tenantID1, `"message 7"`, 1)
+	if err := s.DeleteRows(tenantID1, mustParseQuery(`"message 7"`)); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	f(tenantID1, `"message 7"`, 0)
+	waitForDeleteTasksRemoval(t, s)
+	f(tenantID1, `"message 7"`, 0)
+
+	s.MustClose()
+	fs.MustRemoveAll(path)
+}
+
+func TestStorageDeleteRowsIngestedAfterDeletion(t *testing.T) {
+	const path = "TestStorageDeleteRowsIngestedAfterDeletion"
+
+	sc := &StorageConfig{
+		Retention: 24 * time.Hour,
+	}
+	s := MustOpenStorage(path, sc)
+
+	tenantID := TenantID{AccountID: 1, ProjectID: 2}
+	baseTimestamp := time.Now().UnixNano() - 3600*1e9
+	addRows := func(msg string) {
+		lr := GetLogRows([]string{"app"}, nil)
+		for i := 0; i < 10; i++ {
+			fields := []Field{
+				{
+					Name:  "app",
+					Value: "foo",
+				},
+				{
+					Name:  "_msg",
+					Value: msg,
+				},
+			}
+			lr.MustAdd(tenantID, baseTimestamp+int64(i)*1e6, fields)
+		}
+		s.MustAddRows(lr)
+		PutLogRows(lr)
+		s.debugFlush()
+	}
+	getPartitions := func() []*partition {
+		var pts []*partition
+		s.partitionsLock.Lock()
+		for _, ptw := range s.partitions {
+			pts = append(pts, ptw.pt)
+		}
+		s.partitionsLock.Unlock()
+		return pts
+	}
+	f := func(qStr string, rowsCountExpected uint32) {
+		t.Helper()
+
+		q := mustParseQuery(qStr)
+		var rowsCount atomic.Uint32
+		writeBlock := func(_ uint, timestamps []int64, _ []BlockColumn) {
+			rowsCount.Add(uint32(len(timestamps)))
+		}
+		checkErr(t, s.RunQuery(context.Background(), []TenantID{tenantID}, q, writeBlock))
+		if n := rowsCount.Load(); n != rowsCountExpected {
+			t.Fatalf("unexpected number of rows for query [%s]; got %d; want %d", qStr, n, rowsCountExpected)
+		}
+	}
+
+	addRows("old message")
+	for _, pt := range getPartitions() {
+		pt.ddb.mustFlushInmemoryPartsToFiles(true)
+	}
+
+	// Register the delete task without starting background merges, so the old log entries remain on disk.
+	dt, err := newDeleteTask(tenantID, `_stream:{app="foo"}`, time.Now().UnixNano())
+	if err != nil {
+		t.Fatalf("cannot create delete task: %s", err)
+	}
+	s.registerDeleteTask(dt)
+	f(`*`, 0)
+
+	// Log entries ingested after the deletion with timestamps inside the deleted time range must be visible.
+	addRows("new message")
+	f(`*`, 10)
+	f(`"new message"`, 10)
+
+	// Merging the old and the new parts together must drop only the old log entries.
+	for _, pt := range getPartitions() {
+		pt.ddb.mustFlushInmemoryPartsToFiles(true)
+
+		pt.ddb.partsLock.Lock()
+		pws := append([]*partWrapper{}, pt.ddb.smallParts...)
+		for _, pw := range pws {
+			pw.isInMerge = true
+		}
+		pt.ddb.partsLock.Unlock()
+		if len(pws) != 2 {
+			t.Fatalf("unexpected number of parts before the merge; got %d; want 2", len(pws))
+		}
+		pt.ddb.mustMergeParts(pws, false)
+	}
+	var ss StorageStats
+	s.UpdateStats(&ss)
+	if n := ss.RowsCount(); n != 10 {
+		t.Fatalf("unexpected number of rows after the merge; got %d; want %d", n, 10)
+	}
+	f(`*`, 10)
+
+	// The new log entries must remain visible after the delete task removal and after the restart.
+	s.startDeleteMergers(dt)
+	waitForDeleteTasksRemoval(t, s)
+	f(`*`, 10)
+	s.MustClose()
+	s = MustOpenStorage(path, sc)
+	f(`*`, 10)
+
+	// The new delete task must be applied to parts created before the restart.
+	if err := s.DeleteRows(tenantID, mustParseQuery(`"new message"`)); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	f(`*`, 0)
+	waitForDeleteTasksRemoval(t, s)
+	f(`*`, 0)
+	ss = StorageStats{}
+	s.UpdateStats(&ss)
+	if n := ss.RowsCount(); n != 0 {
+		t.Fatalf("unexpected number of rows after deleting all the log entries; got %d; want %d", n, 0)
+	}
+
+	s.MustClose()
+	fs.MustRemoveAll(path)
+}
+
+func waitForDeleteTasksRemoval(t *testing.T, s *Storage) {
+	t.Helper()
+
+	deadline := time.Now().Add(10 * time.Second)
+	for len(s.getDeleteTasks()) > 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("timeout when waiting for removal of %d delete tasks", len(s.getDeleteTasks()))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -5,9 +5,11 @@ import (
 	"math"
 	"slices"
 	"sort"
+	"strings"
 	"sync"
 
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/cgroup"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
 )
 
 // genericSearchOptions contain options used for search.
@@ -73,6 +75,19 @@ func (s *Storage) RunQuery(ctx context.Context, tenantIDs []TenantID, q *Query,
 
 	workersCount := cgroup.AvailableCPUs()
 
+	search := func(stopCh <-chan struct{}, writeBlock func(workerID uint, br *blockResult)) error {
+		s.search(workersCount, so, stopCh, writeBlock)
+		return nil
+	}
+	return runPipes(ctx, workersCount, q.pipes, search, writeBlock)
+}
+
+// runPipes passes the data blocks obtained from search through the given pipes and calls writeBlock for the results.
+//
+// search must stop sending new data blocks to the passed writeBlock callback as soon as stopCh is closed.
+func runPipes(ctx context.Context, workersCount int, pipes []pipe, search func(stopCh <-chan struct{}, writeBlock func(workerID uint, br *blockResult)) error,
+	writeBlock func(workerID uint, timestamps []int64, columns []BlockColumn)) error {
+
 	pp := newDefaultPipeProcessor(func(workerID uint, br *blockResult) {
 		brs := getBlockRows()
 		csDst := brs.cs
@@ -92,12 +107,13 @@ func (s *Storage) RunQuery(ctx context.Context, tenantIDs []TenantID, q *Query,
 
 	ppMain := pp
 	stopCh := ctx.Done()
-	cancels := make([]func(), len(q.pipes))
-	pps := make([]pipeProcessor, len(q.pipes))
-	for i := len(q.pipes) - 1; i >= 0; i-- {
-		p := q.pipes[i]
+	sb := newStateSizeBudget()
+	cancels := make([]func(), len(pipes))
+	pps := make([]pipeProcessor, len(pipes))
+	for i := len(pipes) - 1; i >= 0; i-- {
+		p := pipes[i]
 		ctxChild, cancel := context.WithCancel(ctx)
-		pp = p.newPipeProcessor(workersCount, stopCh, cancel, pp)
+		pp = p.newPipeProcessor(workersCount, stopCh, cancel, sb, pp)
 		stopCh = ctxChild.Done()
 		ctx = ctxChild
 
@@ -105,7 +121,7 @@ func (s *Storage) RunQuery(ctx context.Context, tenantIDs []TenantID, q *Query,
 		pps[i] = pp
 	}
 
-	s.search(workersCount, so, stopCh, pp.writeBlock)
+	errSearch := search(stopCh, pp.writeBlock)
 
 	var errFlush error
 	for i, pp := range pps {
@@ -118,9 +134,188 @@ func (s *Storage) RunQuery(ctx context.Context, tenantIDs []TenantID, q *Query,
 	if err := ppMain.flush(); err != nil && errFlush == nil {
 		errFlush = err
 	}
+	if errSearch != nil {
+		return errSearch
+	}
 	return errFlush
 }
 
+// RunQueryFunc must run q for the given tenantIDs and call writeBlock for the returned data blocks.
+//
+// writeBlock may be called concurrently from multiple goroutines.
+type RunQueryFunc func(ctx context.Context, tenantIDs []TenantID, q *Query, writeBlock func(workerID uint, timestamps []int64, columns []BlockColumn)) error
+
+// ValueWithHits contains value and hits.
+type ValueWithHits struct {
+	Value string
+	Hits  uint64
+}
+
+// GetFieldNames returns field names from q results for the given tenantIDs.
+//
+// The number of log entries with non-empty value is returned in Hits per each field name.
+func (s *Storage) GetFieldNames(ctx context.Context, tenantIDs []TenantID, q *Query) ([]ValueWithHits, error) {
+	return RunQueryFunc(s.RunQuery).GetFieldNames(ctx, tenantIDs, q)
+}
+
+// GetFieldValues returns unique values for the given fieldName returned by q for the given tenantIDs.
+//
+// The number of log entries with the given value is returned in Hits per each value.
+// If limit > 0, then up to limit unique values are returned. Hits are set to zero if the number of unique values exceeds the limit,
+// since they cannot be calculated reliably in this case.
+func (s *Storage) GetFieldValues(ctx context.Context, tenantIDs []TenantID, q *Query, fieldName string, limit uint64) ([]ValueWithHits, error) {
+	return RunQueryFunc(s.RunQuery).GetFieldValues(ctx, tenantIDs, q, fieldName, limit)
+}
+
+// GetStreams returns streams from q results for the given tenantIDs.
+//
+// The number of log entries per each stream is returned in Hits.
+// If limit > 0, then up to limit unique streams are returned. Hits are set to zero if the number of unique streams exceeds the limit.
+func (s *Storage) GetStreams(ctx context.Context, tenantIDs []TenantID, q *Query, limit uint64) ([]ValueWithHits, error) {
+	return RunQueryFunc(s.RunQuery).GetStreams(ctx, tenantIDs, q, limit)
+}
+
+// GetStreamLabelNames returns stream label names from q results for the given tenantIDs.
+//
+// The number of log entries per each stream label name is returned in Hits.
+// The results are sorted by Hits in descending order.
+func (s *Storage) GetStreamLabelNames(ctx context.Context, tenantIDs []TenantID, q *Query) ([]ValueWithHits, error) {
+	return RunQueryFunc(s.RunQuery).GetStreamLabelNames(ctx, tenantIDs, q)
+}
+
+// GetStreamLabelValues returns stream label values for the given labelName from q results for the given tenantIDs.
+//
+// The number of log entries per each stream label value is returned in Hits.
+// The results are sorted by Hits in descending order. If limit > 0, then up to limit label values with the biggest hits are returned.
+func (s *Storage) GetStreamLabelValues(ctx context.Context, tenantIDs []TenantID, q *Query, labelName string, limit uint64) ([]ValueWithHits, error) {
+	return RunQueryFunc(s.RunQuery).GetStreamLabelValues(ctx, tenantIDs, q, labelName, limit)
+}
+
+// GetFieldNames returns field names from q results for the given tenantIDs.
+//
+// The number of log entries with non-empty value is returned in Hits per each field name.
+func (runQuery RunQueryFunc) GetFieldNames(ctx context.Context, tenantIDs []TenantID, q *Query) ([]ValueWithHits, error) {
+	pipes := append([]pipe{}, q.pipes...)
+	pipes = append(pipes, &pipeFieldNames{
+		resultName: "name",
+	})
+	qNew := &Query{
+		f:         q.f,
+		pipes:     pipes,
+		timestamp: q.timestamp,
+	}
+	return runQuery.runValuesWithHitsQuery(ctx, tenantIDs, qNew)
+}
+
+// GetFieldValues returns unique values for the given fieldName returned by q for the given tenantIDs.
+//
+// The number of log entries with the given value is returned in Hits per each value.
+// If limit > 0, then up to limit unique values are returned. Hits are set to zero if the number of unique values exceeds the limit,
+// since they cannot be calculated reliably in this case.
+func (runQuery RunQueryFunc) GetFieldValues(ctx context.Context, tenantIDs []TenantID, q *Query, fieldName string, limit uint64) ([]ValueWithHits, error) {
+	hitsFieldName := "hits"
+	if fieldName == hitsFieldName {
+		hitsFieldName = "hitss"
+	}
+	pipes := append([]pipe{}, q.pipes...)
+	pipes = append(pipes, &pipeUniq{
+		byFields:      []string{fieldName},
+		hitsFieldName: hitsFieldName,
+		limit:         limit,
+	})
+	qNew := &Query{
+		f:         q.f,
+		pipes:     pipes,
+		timestamp: q.timestamp,
+	}
+	return runQuery.runValuesWithHitsQuery(ctx, tenantIDs, qNew)
+}
+
+// GetStreams returns streams from q results for the given tenantIDs.
+//
+// The number of log entries per each stream is returned in Hits.
+// If limit > 0, then up to limit unique streams are returned. Hits are set to zero if the number of unique streams exceeds the limit.
+func (runQuery RunQueryFunc) GetStreams(ctx context.Context, tenantIDs []TenantID, q *Query, limit uint64) ([]ValueWithHits, error) {
+	return runQuery.GetFieldValues(ctx, tenantIDs, q, "_stream", limit)
+}
+
+// GetStreamLabelNames returns stream label names from q results for the given tenantIDs.
+//
+// The number of log entries per each stream label name is returned in Hits.
+// The results are sorted by Hits in descending order.
+func (runQuery RunQueryFunc) GetStreamLabelNames(ctx context.Context, tenantIDs []TenantID, q *Query) ([]ValueWithHits, error) {
+	return runQuery.runStreamLabelsQuery(ctx, tenantIDs, q, "")
+}
+
+// GetStreamLabelValues returns stream label values for the given labelName from q results for the given tenantIDs.
+//
+// The number of log entries per each stream label value is returned in Hits.
+// The results are sorted by Hits in descending order. If limit > 0, then up to limit label values with the biggest hits are returned.
+func (runQuery RunQueryFunc) GetStreamLabelValues(ctx context.Context, tenantIDs []TenantID, q *Query, labelName string, limit uint64) ([]ValueWithHits, error) {
+	results, err := runQuery.runStreamLabelsQuery(ctx, tenantIDs, q, labelName)
+	if err != nil {
+		return nil, err
+	}
+	if limit > 0 && uint64(len(results)) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+// runStreamLabelsQuery returns stream label names if labelName is empty. Otherwise values for the given stream labelName are returned.
+//
+// Stream labels are aggregated while reading the matching logs, so only unique stream label names or values are kept in memory.
+func (runQuery RunQueryFunc) runStreamLabelsQuery(ctx context.Context, tenantIDs []TenantID, q *Query, labelName string) ([]ValueWithHits, error) {
+	pipes := append([]pipe{}, q.pipes...)
+	pipes = append(pipes, &pipeStreamLabels{
+		labelName: labelName,
+	})
+	qNew := &Query{
+		f:         q.f,
+		pipes:     pipes,
+		timestamp: q.timestamp,
+	}
+	results, err := runQuery.runValuesWithHitsQuery(ctx, tenantIDs, qNew)
+	if err != nil {
+		return nil, err
+	}
+	sortValuesWithHitsByHits(results)
+	return results, nil
+}
+
+// runValuesWithHitsQuery runs q, which must return (value, hits) columns, and returns the results sorted by value.
+func (runQuery RunQueryFunc) runValuesWithHitsQuery(ctx context.Context, tenantIDs []TenantID, q *Query) ([]ValueWithHits, error) {
+	var results []ValueWithHits
+	var resultsLock sync.Mutex
+	writeBlock := func(_ uint, _ []int64, columns []BlockColumn) {
+		if len(columns) != 2 {
+			logger.Panicf("BUG: expecting two columns; got %d columns", len(columns))
+		}
+
+		values := columns[0].Values
+		hitsValues := columns[1].Values
+
+		resultsLock.Lock()
+		for i, v := range values {
+			hits, _ := tryParseUint64(hitsValues[i])
+			results = append(results, ValueWithHits{
+				Value: strings.Clone(v),
+				Hits:  hits,
+			})
+		}
+		resultsLock.Unlock()
+	}
+
+	if err := runQuery(ctx, tenantIDs, q, writeBlock); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Value < results[j].Value
+	})
+	return results, nil
+}
+
 type blockRows struct {
 	cs []BlockColumn
 }
@@ -278,6 +473,7 @@ func (pt *partition) search(ft *filterTime, sf *StreamFilter, f filter, so *gene
 	if hasStreamFilters(f) {
 		f = initStreamFilters(tenantIDs, pt.idb, f)
 	}
+	f = pt.addDeleteFilter(f, so.tenantIDs, ft.minTimestamp, ft.maxTimestamp)
 	soInternal := &searchOptions{
 		tenantIDs:           tenantIDs,
 		streamIDs:           streamIDs,
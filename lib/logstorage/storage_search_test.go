@@ -3,6 +3,7 @@ package logstorage
 import (
 	"context"
 	"fmt"
+	"reflect"
 	"regexp"
 	"sync/atomic"
 	"testing"
@@ -303,6 +304,206 @@ func TestStorageRunQuery(t *testing.T) {
 		checkErr(t, s.RunQuery(context.Background(), tenantIDs, q, writeBlock))
 	})
 
+	t.Run("count-by-time", func(t *testing.T) {
+		q := mustParseQuery(`"log message"`)
+		q.AddCountByTimePipe(nsecsPerDay*365*100, 0, []string{"stream-id"})
+		tenantID := TenantID{
+			AccountID: 1,
+			ProjectID: 11,
+		}
+		var rowsCountTotal, hitsTotal atomic.Uint32
+		writeBlock := func(_ uint, timestamps []int64, columns []BlockColumn) {
+			if len(columns) != 3 {
+				panic(fmt.Errorf("unexpected number of columns; got %d; want 3", len(columns)))
+			}
+			for _, v := range columns[2].Values {
+				n, ok := tryParseUint64(v)
+				if !ok {
+					panic(fmt.Errorf("cannot parse hits=%q", v))
+				}
+				hitsTotal.Add(uint32(n))
+			}
+			rowsCountTotal.Add(uint32(len(timestamps)))
+		}
+		tenantIDs := []TenantID{tenantID}
+		checkErr(t, s.RunQuery(context.Background(), tenantIDs, q, writeBlock))
+
+		if n := rowsCountTotal.Load(); n != streamsPerTenant {
+			t.Fatalf("unexpected number of rows; got %d; want %d", n, streamsPerTenant)
+		}
+		expectedHits := streamsPerTenant * blocksPerStream * rowsPerBlock
+		if n := hitsTotal.Load(); n != uint32(expectedHits) {
+			t.Fatalf("unexpected number of hits; got %d; want %d", n, expectedHits)
+		}
+	})
+	t.Run("filter-pipe-over-time-field", func(t *testing.T) {
+		tenantID := TenantID{
+			AccountID: 1,
+			ProjectID: 11,
+		}
+		tenantIDs := []TenantID{tenantID}
+		timestampStr := string(marshalTimestampRFC3339Nano(nil, baseTimestamp+3*1e9))
+
+		f := func(qStr, timeField string) {
+			t.Helper()
+
+			q := mustParseQuery(qStr)
+			var rowsCount atomic.Uint32
+			writeBlock := func(_ uint, timestamps []int64, columns []BlockColumn) {
+				for i := range timestamps {
+					var timeValue, msg string
+					for _, c := range columns {
+						switch c.Name {
+						case timeField:
+							timeValue = c.Values[i]
+						case "_msg":
+							msg = c.Values[i]
+						}
+					}
+					if timeValue != timestampStr {
+						panic(fmt.Errorf("unexpected %s value; got %q; want %q", timeField, timeValue, timestampStr))
+					}
+					if msg != "log message 3 at block 0" {
+						panic(fmt.Errorf("unexpected _msg value; got %q; want %q", msg, "log message 3 at block 0"))
+					}
+				}
+				rowsCount.Add(uint32(len(timestamps)))
+			}
+			checkErr(t, s.RunQuery(context.Background(), tenantIDs, q, writeBlock))
+
+			if n := rowsCount.Load(); n != streamsPerTenant {
+				t.Fatalf("unexpected number of matching rows for [%s]; got %d; want %d", qStr, n, streamsPerTenant)
+			}
+		}
+
+		timestampRe := regexp.QuoteMeta(timestampStr)
+		// The format pipe caches _time values before the filter pipe.
+		f(fmt.Sprintf(`* | format "<_time>" as x | filter x:re(%q) | fields _time, _msg`, timestampRe), "_time")
+		f(fmt.Sprintf(`* | copy _time t | filter t:re(%q) | fields t, _msg`, timestampRe), "t")
+	})
+	t.Run("field_names", func(t *testing.T) {
+		q := mustParseQuery(`"log message"`)
+		tenantID := TenantID{
+			AccountID: 1,
+			ProjectID: 11,
+		}
+		tenantIDs := []TenantID{tenantID}
+		results, err := s.GetFieldNames(context.Background(), tenantIDs, q)
+		checkErr(t, err)
+
+		const hits = streamsPerTenant * blocksPerStream * rowsPerBlock
+		resultsExpected := []ValueWithHits{
+			{"_msg", hits},
+			{"_stream", hits},
+			{"_time", hits},
+			{"instance", hits},
+			{"job", hits},
+			{"source-file", hits},
+			{"stream-id", hits},
+			{"tenant.id", hits},
+		}
+		if !reflect.DeepEqual(results, resultsExpected) {
+			t.Fatalf("unexpected results\ngot\n%v\nwant\n%v", results, resultsExpected)
+		}
+	})
+	t.Run("field_values", func(t *testing.T) {
+		q := mustParseQuery(`"log message"`)
+		tenantID := TenantID{
+			AccountID: 1,
+			ProjectID: 11,
+		}
+		tenantIDs := []TenantID{tenantID}
+		results, err := s.GetFieldValues(context.Background(), tenantIDs, q, "stream-id", 0)
+		checkErr(t, err)
+
+		const hits = blocksPerStream * rowsPerBlock
+		resultsExpected := []ValueWithHits{
+			{"stream_id=0", hits},
+			{"stream_id=1", hits},
+			{"stream_id=2", hits},
+		}
+		if !reflect.DeepEqual(results, resultsExpected) {
+			t.Fatalf("unexpected results\ngot\n%v\nwant\n%v", results, resultsExpected)
+		}
+	})
+	t.Run("field_values-exceeded-limit", func(t *testing.T) {
+		q := mustParseQuery(`"log message"`)
+		tenantID := TenantID{
+			AccountID: 1,
+			ProjectID: 11,
+		}
+		tenantIDs := []TenantID{tenantID}
+		results, err := s.GetFieldValues(context.Background(), tenantIDs, q, "stream-id", 2)
+		checkErr(t, err)
+
+		if len(results) != 2 {
+			t.Fatalf("unexpected number of results; got %d; want 2; results: %v", len(results), results)
+		}
+		for _, r := range results {
+			if r.Hits != 0 {
+				t.Fatalf("expecting zero hits when the limit is exceeded; got %v", results)
+			}
+		}
+	})
+	t.Run("streams", func(t *testing.T) {
+		q := mustParseQuery(`"log message"`)
+		tenantID := TenantID{
+			AccountID: 1,
+			ProjectID: 11,
+		}
+		tenantIDs := []TenantID{tenantID}
+		results, err := s.GetStreams(context.Background(), tenantIDs, q, 0)
+		checkErr(t, err)
+
+		const hits = blocksPerStream * rowsPerBlock
+		resultsExpected := []ValueWithHits{
+			{`{instance="host-0:234",job="foobar"}`, hits},
+			{`{instance="host-1:234",job="foobar"}`, hits},
+			{`{instance="host-2:234",job="foobar"}`, hits},
+		}
+		if !reflect.DeepEqual(results, resultsExpected) {
+			t.Fatalf("unexpected results\ngot\n%v\nwant\n%v", results, resultsExpected)
+		}
+	})
+	t.Run("stream_label_names", func(t *testing.T) {
+		q := mustParseQuery(`"log message"`)
+		tenantID := TenantID{
+			AccountID: 1,
+			ProjectID: 11,
+		}
+		tenantIDs := []TenantID{tenantID}
+		results, err := s.GetStreamLabelNames(context.Background(), tenantIDs, q)
+		checkErr(t, err)
+
+		const hits = streamsPerTenant * blocksPerStream * rowsPerBlock
+		resultsExpected := []ValueWithHits{
+			{"instance", hits},
+			{"job", hits},
+		}
+		if !reflect.DeepEqual(results, resultsExpected) {
+			t.Fatalf("unexpected results\ngot\n%v\nwant\n%v", results, resultsExpected)
+		}
+	})
+	t.Run("stream_label_values", func(t *testing.T) {
+		q := mustParseQuery(`"log message"`)
+		tenantID := TenantID{
+			AccountID: 1,
+			ProjectID: 11,
+		}
+		tenantIDs := []TenantID{tenantID}
+		results, err := s.GetStreamLabelValues(context.Background(), tenantIDs, q, "instance", 2)
+		checkErr(t, err)
+
+		const hits = blocksPerStream * rowsPerBlock
+		resultsExpected := []ValueWithHits{
+			{"host-0:234", hits},
+			{"host-1:234", hits},
+		}
+		if !reflect.DeepEqual(results, resultsExpected) {
+			t.Fatalf("unexpected results\ngot\n%v\nwant\n%v", results, resultsExpected)
+		}
+	})
+
 	// Close the storage and delete its data
 	s.MustClose()
 	fs.MustRemoveAll(path)
@@ -0,0 +1,125 @@
+package logstorage
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fs"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/snapshot/snapshotutil"
+)
+
+// CreateSnapshot creates a snapshot for s and returns the snapshot name.
+//
+// The snapshot is created at <path>/snapshots/<name> and has the same directory layout as the Storage,
+// so it can be backed up with vmbackup and restored with vmrestore.
+// See https://docs.victoriametrics.com/VictoriaLogs/#backup-and-restore
+func (s *Storage) CreateSnapshot() (string, error) {
+	logger.Infof("creating Storage snapshot for %q...", s.path)
+	startTime := time.Now()
+
+	s.snapshotLock.Lock()
+	defer s.snapshotLock.Unlock()
+
+	snapshotName := snapshotutil.NewName()
+	dstDir := filepath.Join(s.path, snapshotsDirname, snapshotName)
+	fs.MustMkdirFailIfExist(dstDir)
+
+	s.partitionsLock.Lock()
+	ptws := append([]*partitionWrapper{}, s.partitions...)
+	for _, ptw := range ptws {
+		ptw.incRef()
+	}
+	s.partitionsLock.Unlock()
+
+	defer func() {
+		for _, ptw := range ptws {
+			ptw.decRef()
+		}
+	}()
+
+	dstPartitionsDir := filepath.Join(dstDir, partitionsDirname)
+	fs.MustMkdirFailIfExist(dstPartitionsDir)
+	for _, ptw := range ptws {
+		dstPartitionDir := filepath.Join(dstPartitionsDir, ptw.pt.name)
+		ptw.pt.mustCreateSnapshotAt(dstPartitionDir)
+	}
+	fs.MustSyncPath(dstPartitionsDir)
+
+	if deleteTasks := s.getDeleteTasks(); len(deleteTasks) > 0 {
+		mustWriteDeleteTasks(dstDir, deleteTasks)
+	}
+
+	fs.MustSyncPath(dstDir)
+	fs.MustSyncPath(filepath.Dir(dstDir))
+
+	logger.Infof("created Storage snapshot for %q at %q in %.3f seconds", s.path, dstDir, time.Since(startTime).Seconds())
+	return snapshotName, nil
+}
+
+// ListSnapshots returns sorted list of existing snapshots for s.
+func (s *Storage) ListSnapshots() ([]string, error) {
+	snapshotsPath := filepath.Join(s.path, snapshotsDirname)
+	d, err := os.Open(snapshotsPath)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open snapshots directory: %w", err)
+	}
+	defer fs.MustClose(d)
+
+	fnames, err := d.Readdirnames(-1)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read snapshots directory at %q: %w", snapshotsPath, err)
+	}
+	snapshotNames := make([]string, 0, len(fnames))
+	for _, fname := range fnames {
+		if err := snapshotutil.Validate(fname); err != nil {
+			continue
+		}
+		snapshotNames = append(snapshotNames, fname)
+	}
+	sort.Strings(snapshotNames)
+	return snapshotNames, nil
+}
+
+// DeleteSnapshot deletes the given snapshot.
+func (s *Storage) DeleteSnapshot(snapshotName string) error {
+	if err := snapshotutil.Validate(snapshotName); err != nil {
+		return fmt.Errorf("invalid snapshotName %q: %w", snapshotName, err)
+	}
+	snapshotPath := filepath.Join(s.path, snapshotsDirname, snapshotName)
+	if !fs.IsPathExist(snapshotPath) {
+		return fmt.Errorf("cannot find snapshot %q", snapshotName)
+	}
+
+	logger.Infof("deleting snapshot %q...", snapshotPath)
+	startTime := time.Now()
+
+	fs.MustRemoveDirAtomic(snapshotPath)
+
+	logger.Infof("deleted snapshot %q in %.3f seconds", snapshotPath, time.Since(startTime).Seconds())
+	return nil
+}
+
+// DeleteStaleSnapshots deletes snapshots older than the given maxAge.
+func (s *Storage) DeleteStaleSnapshots(maxAge time.Duration) error {
+	list, err := s.ListSnapshots()
+	if err != nil {
+		return err
+	}
+	expireDeadline := time.Now().UTC().Add(-maxAge)
+	for _, snapshotName := range list {
+		t, err := snapshotutil.Time(snapshotName)
+		if err != nil {
+			return fmt.Errorf("cannot parse snapshot date from %q: %w", snapshotName, err)
+		}
+		if t.Before(expireDeadline) {
+			if err := s.DeleteSnapshot(snapshotName); err != nil {
+				return fmt.Errorf("cannot delete snapshot %q: %w", snapshotName, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package logstorage
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fs"
+)
+
+func TestStorageSnapshot(t *testing.T) {
+	const path = "TestStorageSnapshot"
+
+	sc := &StorageConfig{
+		Retention: 24 * time.Hour,
+	}
+	s := MustOpenStorage(path, sc)
+
+	tenantID := TenantID{AccountID: 1, ProjectID: 2}
+	baseTimestamp := time.Now().UnixNano() - 3600*1e9
+	addRows := func(rowsCount int) {
+		lr := GetLogRows([]string{"app"}, nil)
+		for i := 0; i < rowsCount; i++ {
+			fields := []Field{
+				{
+					Name:  "app",
+					Value: fmt.Sprintf("app_%d", i%3),
+				},
+				{
+					Name:  "_msg",
+					Value: fmt.Sprintf("message %d", i),
+				},
+			}
+			lr.MustAdd(tenantID, baseTimestamp+int64(i)*1e6, fields)
+		}
+		s.MustAddRows(lr)
+		PutLogRows(lr)
+	}
+	getRowsCount := func(s *Storage) uint32 {
+		t.Helper()
+
+		var rowsCount atomic.Uint32
+		writeBlock := func(_ uint, timestamps []int64, _ []BlockColumn) {
+			rowsCount.Add(uint32(len(timestamps)))
+		}
+		checkErr(t, s.RunQuery(context.Background(), []TenantID{tenantID}, mustParseQuery(`*`), writeBlock))
+		return rowsCount.Load()
+	}
+
+	// The snapshot must contain recently ingested rows
+	addRows(100)
+	snapshotName, err := s.CreateSnapshot()
+	if err != nil {
+		t.Fatalf("cannot create snapshot: %s", err)
+	}
+
+	// Rows added after the snapshot creation mustn't be visible in the snapshot
+	addRows(50)
+	s.debugFlush()
+	if n := getRowsCount(s); n != 150 {
+		t.Fatalf("unexpected number of rows in the storage; got %d; want %d", n, 150)
+	}
+
+	snapshots, err := s.ListSnapshots()
+	if err != nil {
+		t.Fatalf("cannot list snapshots: %s", err)
+	}
+	if len(snapshots) != 1 || snapshots[0] != snapshotName {
+		t.Fatalf("unexpected snapshots; got %q; want [%q]", snapshots, snapshotName)
+	}
+
+	// The snapshot must be openable as a regular storage
+	snapshotPath := filepath.Join(path, snapshotsDirname, snapshotName)
+	sSnapshot := MustOpenStorage(snapshotPath, sc)
+	if n := getRowsCount(sSnapshot); n != 100 {
+		t.Fatalf("unexpected number of rows in the snapshot; got %d; want %d", n, 100)
+	}
+	sSnapshot.MustClose()
+
+	// Stale snapshots must be deleted
+	if err := s.DeleteStaleSnapshots(time.Hour); err != nil {
+		t.Fatalf("cannot delete stale snapshots: %s", err)
+	}
+	if !fs.IsPathExist(snapshotPath) {
+		t.Fatalf("the snapshot %q mustn't be deleted, since it isn't stale", snapshotName)
+	}
+
+	if err := s.DeleteSnapshot(snapshotName); err != nil {
+		t.Fatalf("cannot delete snapshot: %s", err)
+	}
+	if fs.IsPathExist(snapshotPath) {
+		t.Fatalf("the snapshot %q must be deleted", snapshotName)
+	}
+	if err := s.DeleteSnapshot(snapshotName); err == nil {
+		t.Fatalf("expecting non-nil error when deleting missing snapshot")
+	}
+	if err := s.DeleteSnapshot("invalid-name"); err == nil {
+		t.Fatalf("expecting non-nil error when deleting snapshot with invalid name")
+	}
+
+	s.MustClose()
+	fs.MustRemoveAll(path)
+}
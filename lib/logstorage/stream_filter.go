@@ -6,6 +6,7 @@ import (
 
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/encoding"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/regexutil"
 )
 
@@ -76,3 +77,48 @@ func (tf *streamTagFilter) getRegexp() *regexutil.PromRegex {
 func (tf *streamTagFilter) String() string {
 	return quoteTokenIfNeeded(tf.tagName) + tf.op + strconv.Quote(tf.value)
 }
+
+// matchStreamFields returns true if sf matches the given stream fields.
+//
+// Missing fields are treated as fields with empty values.
+func (sf *StreamFilter) matchStreamFields(fields []Field) bool {
+	for _, af := range sf.orFilters {
+		if af.matchStreamFields(fields) {
+			return true
+		}
+	}
+	return false
+}
+
+func (af *andStreamFilter) matchStreamFields(fields []Field) bool {
+	for _, tf := range af.tagFilters {
+		if !tf.matchStreamFields(fields) {
+			return false
+		}
+	}
+	return true
+}
+
+func (tf *streamTagFilter) matchStreamFields(fields []Field) bool {
+	v := ""
+	for _, f := range fields {
+		if f.Name == tf.tagName {
+			v = f.Value
+			break
+		}
+	}
+
+	switch tf.op {
+	case "=":
+		return v == tf.value
+	case "!=":
+		return v != tf.value
+	case "=~":
+		return tf.regexp.MatchString(v)
+	case "!~":
+		return !tf.regexp.MatchString(v)
+	default:
+		logger.Panicf("BUG: unexpected op %q", tf.op)
+		return false
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"sort"
 	"strconv"
+	"strings"
 	"sync"
 
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
@@ -296,3 +297,43 @@ func unmarshalTagValue(dst, src []byte) ([]byte, []byte, error) {
 		b = b[1:]
 	}
 }
+
+// parseStreamFields parses s in the form returned by StreamTags.String(), appends the parsed fields to dst and returns the result.
+//
+// For example, `{job="foo",instance="bar"}`.
+func parseStreamFields(dst []Field, s string) ([]Field, error) {
+	if len(s) < 2 || s[0] != '{' || s[len(s)-1] != '}' {
+		return dst, fmt.Errorf("missing curly braces around stream fields")
+	}
+	s = s[1 : len(s)-1]
+	for len(s) > 0 {
+		n := strings.IndexByte(s, '=')
+		if n < 0 {
+			return dst, fmt.Errorf("missing '=' after stream field name %q", s)
+		}
+		name := s[:n]
+		s = s[n+1:]
+
+		qValue, err := strconv.QuotedPrefix(s)
+		if err != nil {
+			return dst, fmt.Errorf("cannot find quoted value for stream field %q: %w", name, err)
+		}
+		s = s[len(qValue):]
+		value, err := strconv.Unquote(qValue)
+		if err != nil {
+			return dst, fmt.Errorf("cannot unquote value for stream field %q: %w", name, err)
+		}
+		dst = append(dst, Field{
+			Name:  name,
+			Value: value,
+		})
+
+		if len(s) > 0 {
+			if s[0] != ',' {
+				return dst, fmt.Errorf("missing ',' after the value for stream field %q", name)
+			}
+			s = s[1:]
+		}
+	}
+	return dst, nil
+}
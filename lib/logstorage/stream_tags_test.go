@@ -0,0 +1,57 @@
+package logstorage
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseStreamFieldsSuccess(t *testing.T) {
+	f := func(s string, resultExpected []Field) {
+		t.Helper()
+
+		result, err := parseStreamFields(nil, s)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if !reflect.DeepEqual(result, resultExpected) {
+			t.Fatalf("unexpected result\ngot\n%v\nwant\n%v", result, resultExpected)
+		}
+	}
+
+	f(`{}`, nil)
+	f(`{foo="bar"}`, []Field{
+		{
+			Name:  "foo",
+			Value: "bar",
+		},
+	})
+	f(`{a="b",c="d=e,f\"}"}`, []Field{
+		{
+			Name:  "a",
+			Value: "b",
+		},
+		{
+			Name:  "c",
+			Value: `d=e,f"}`,
+		},
+	})
+}
+
+func TestParseStreamFieldsFailure(t *testing.T) {
+	f := func(s string) {
+		t.Helper()
+
+		_, err := parseStreamFields(nil, s)
+		if err == nil {
+			t.Fatalf("expecting non-nil error")
+		}
+	}
+
+	f(``)
+	f(`{`)
+	f(`foo="bar"`)
+	f(`{foo}`)
+	f(`{foo=bar}`)
+	f(`{foo="bar}`)
+	f(`{foo="bar"baz="x"}`)
+}
@@ -0,0 +1,403 @@
+package logstorage
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SyslogParser parses syslog messages into Fields.
+//
+// Both RFC3164 and RFC5424 messages are supported:
+//
+//   - https://datatracker.ietf.org/doc/html/rfc3164
+//   - https://datatracker.ietf.org/doc/html/rfc5424
+//
+// Use GetSyslogParser() for obtaining the parser.
+type SyslogParser struct {
+	// Fields contains the parsed fields after Parse() call.
+	//
+	// The Fields are valid until the next call to Parse()
+	// or until the parser is returned to the pool with PutSyslogParser() call.
+	Fields []Field
+
+	// currentYear is used as the year for RFC3164 timestamps, since they do not contain the year.
+	currentYear int
+
+	// timezone is used for RFC3164 timestamps, since they do not contain timezone.
+	timezone *time.Location
+}
+
+func (p *SyslogParser) reset() {
+	p.resetFields()
+
+	p.currentYear = 0
+	p.timezone = nil
+}
+
+func (p *SyslogParser) resetFields() {
+	clear(p.Fields)
+	p.Fields = p.Fields[:0]
+}
+
+func (p *SyslogParser) addField(name, value string) {
+	p.Fields = append(p.Fields, Field{
+		Name:  name,
+		Value: value,
+	})
+}
+
+// GetSyslogParser returns syslog parser from the pool.
+//
+// currentYear must contain the current year. It is used for properly setting timestamp
+// field for RFC3164 format, which doesn't contain year.
+//
+// the timezone is used for RFC3164 format for setting the desired timezone.
+//
+// Return back the parser to the pool by calling PutSyslogParser when it is no longer needed.
+func GetSyslogParser(currentYear int, timezone *time.Location) *SyslogParser {
+	v := syslogParserPool.Get()
+	if v == nil {
+		v = &SyslogParser{}
+	}
+	p := v.(*SyslogParser)
+	p.currentYear = currentYear
+	p.timezone = timezone
+	return p
+}
+
+// PutSyslogParser returns back syslog parser to the pool.
+//
+// p cannot be used after returning to the pool.
+func PutSyslogParser(p *SyslogParser) {
+	p.reset()
+	syslogParserPool.Put(p)
+}
+
+var syslogParserPool sync.Pool
+
+// Parse parses syslog message from s into p.Fields.
+//
+// p.Fields remain valid until s is modified or p is returned to the pool.
+func (p *SyslogParser) Parse(s string) {
+	p.resetFields()
+
+	if len(s) == 0 {
+		// Cannot parse syslog message
+		return
+	}
+
+	if s[0] != '<' {
+		p.parseNoHeader(s)
+		return
+	}
+
+	// parse priority
+	n := strings.IndexByte(s, '>')
+	if n < 0 {
+		// Cannot parse priority. Treat the whole s as a message without the header.
+		p.parseNoHeader(s)
+		return
+	}
+	priorityStr := s[1:n]
+	priority, ok := tryParseUint64(priorityStr)
+	if !ok {
+		// Cannot parse priority. Treat the whole s as a message without the header.
+		p.parseNoHeader(s)
+		return
+	}
+	s = s[n+1:]
+
+	p.addField("priority", priorityStr)
+	facility := priority / 8
+	severity := priority % 8
+
+	p.addField("facility", strconv.FormatUint(facility, 10))
+	p.addField("severity", strconv.FormatUint(severity, 10))
+
+	p.parseNoHeader(s)
+}
+
+func (p *SyslogParser) parseNoHeader(s string) {
+	if len(s) == 0 {
+		return
+	}
+	if strings.HasPrefix(s, "1 ") {
+		p.parseRFC5424(s[2:])
+	} else {
+		p.parseRFC3164(s)
+	}
+}
+
+func (p *SyslogParser) parseRFC5424(s string) {
+	// See https://datatracker.ietf.org/doc/html/rfc5424
+
+	p.addField("format", "rfc5424")
+
+	if len(s) == 0 {
+		return
+	}
+
+	// Parse timestamp
+	n := strings.IndexByte(s, ' ')
+	if n < 0 {
+		p.addNilField("timestamp", s)
+		return
+	}
+	p.addNilField("timestamp", s[:n])
+	s = s[n+1:]
+
+	// Parse hostname
+	n = strings.IndexByte(s, ' ')
+	if n < 0 {
+		p.addNilField("hostname", s)
+		return
+	}
+	p.addNilField("hostname", s[:n])
+	s = s[n+1:]
+
+	// Parse app-name
+	n = strings.IndexByte(s, ' ')
+	if n < 0 {
+		p.addNilField("app_name", s)
+		return
+	}
+	p.addNilField("app_name", s[:n])
+	s = s[n+1:]
+
+	// Parse procid
+	n = strings.IndexByte(s, ' ')
+	if n < 0 {
+		p.addNilField("proc_id", s)
+		return
+	}
+	p.addNilField("proc_id", s[:n])
+	s = s[n+1:]
+
+	// Parse msgID
+	n = strings.IndexByte(s, ' ')
+	if n < 0 {
+		p.addNilField("msg_id", s)
+		return
+	}
+	p.addNilField("msg_id", s[:n])
+	s = s[n+1:]
+
+	// Parse structured data
+	tail, ok := p.parseRFC5424SD(s)
+	if !ok {
+		// Cannot parse structured data. Put the rest of s into message.
+		p.addField("message", s)
+		return
+	}
+	s = tail
+
+	// Parse message
+	s = strings.TrimPrefix(s, " ")
+	s = strings.TrimPrefix(s, "\xef\xbb\xbf")
+	p.addField("message", s)
+}
+
+// addNilField adds (name, value) field to p.Fields unless the value is RFC5424 NILVALUE ('-').
+func (p *SyslogParser) addNilField(name, value string) {
+	if value == "-" {
+		return
+	}
+	p.addField(name, value)
+}
+
+// parseRFC5424SD parses RFC5424 STRUCTURED-DATA from s and returns the tail after it.
+//
+// Every SD-PARAM is stored as `SD-ID.PARAM-NAME` field.
+func (p *SyslogParser) parseRFC5424SD(s string) (string, bool) {
+	if strings.HasPrefix(s, "-") {
+		return s[1:], true
+	}
+
+	for {
+		tail, ok := p.parseRFC5424SDLine(s)
+		if !ok {
+			return s, false
+		}
+		s = tail
+		if !strings.HasPrefix(s, "[") {
+			return s, true
+		}
+	}
+}
+
+func (p *SyslogParser) parseRFC5424SDLine(s string) (string, bool) {
+	if !strings.HasPrefix(s, "[") {
+		return s, false
+	}
+	s = s[1:]
+
+	// Parse SD-ID
+	n := strings.IndexAny(s, " ]")
+	if n < 0 {
+		return s, false
+	}
+	sdID := s[:n]
+	s = s[n:]
+	if sdID == "" {
+		return s, false
+	}
+
+	// Parse SD-PARAMs
+	paramsCount := 0
+	for {
+		s = strings.TrimPrefix(s, " ")
+		if strings.HasPrefix(s, "]") {
+			if paramsCount == 0 {
+				p.addField(sdID, "")
+			}
+			return s[1:], true
+		}
+
+		n = strings.IndexByte(s, '=')
+		if n < 0 {
+			return s, false
+		}
+		paramName := s[:n]
+		s = s[n+1:]
+
+		if !strings.HasPrefix(s, `"`) {
+			return s, false
+		}
+		value, tail, ok := unquoteSyslogSDParamValue(s[1:])
+		if !ok {
+			return s, false
+		}
+		s = tail
+
+		p.addField(sdID+"."+paramName, value)
+		paramsCount++
+	}
+}
+
+// unquoteSyslogSDParamValue reads PARAM-VALUE from s until the closing quote.
+//
+// It returns the unescaped value and the tail after the closing quote.
+// See https://datatracker.ietf.org/doc/html/rfc5424#section-6.3.3
+func unquoteSyslogSDParamValue(s string) (string, string, bool) {
+	n := strings.IndexAny(s, `"\`)
+	if n < 0 {
+		return "", s, false
+	}
+	if s[n] == '"' {
+		// Fast path - the value has no escape chars
+		return s[:n], s[n+1:], true
+	}
+
+	// Slow path - unescape the value
+	var b strings.Builder
+	for {
+		n = strings.IndexAny(s, `"\`)
+		if n < 0 {
+			return "", s, false
+		}
+		b.WriteString(s[:n])
+		if s[n] == '"' {
+			return b.String(), s[n+1:], true
+		}
+
+		// Escape char
+		s = s[n+1:]
+		if len(s) == 0 {
+			return "", s, false
+		}
+		switch s[0] {
+		case '"', '\\', ']':
+		default:
+			// RFC5424 says the backslash must be preserved if it doesn't escape '"', '\' or ']'
+			b.WriteByte('\\')
+		}
+		b.WriteByte(s[0])
+		s = s[1:]
+	}
+}
+
+func (p *SyslogParser) parseRFC3164(s string) {
+	// See https://datatracker.ietf.org/doc/html/rfc3164
+
+	p.addField("format", "rfc3164")
+
+	// Parse timestamp
+	n := len(time.Stamp)
+	if len(s) < n {
+		p.addField("message", s)
+		return
+	}
+
+	timezone := p.timezone
+	if timezone == nil {
+		timezone = time.Local
+	}
+	t, err := time.ParseInLocation(time.Stamp, s[:n], timezone)
+	if err == nil {
+		t = t.AddDate(p.currentYear, 0, 0)
+		s = s[n:]
+	} else {
+		// Some syslog clients send RFC3339 timestamp instead of RFC3164 timestamp.
+		n = strings.IndexByte(s, ' ')
+		if n < 0 {
+			n = len(s)
+		}
+		t, err = time.Parse(time.RFC3339, s[:n])
+		if err != nil {
+			// Cannot parse timestamp. Put the whole s into message.
+			p.addField("message", s)
+			return
+		}
+		s = s[n:]
+	}
+	p.addField("timestamp", t.Format(time.RFC3339Nano))
+
+	if len(s) == 0 || s[0] != ' ' {
+		// Missing space after the time field
+		if len(s) > 0 {
+			p.addField("message", s)
+		}
+		return
+	}
+	s = s[1:]
+
+	// Parse hostname
+	n = strings.IndexByte(s, ' ')
+	if n < 0 {
+		p.addField("hostname", s)
+		return
+	}
+	p.addField("hostname", s[:n])
+	s = s[n+1:]
+
+	// Parse tag (aka app_name)
+	n = strings.IndexAny(s, "[: ")
+	if n < 0 {
+		p.addField("app_name", s)
+		return
+	}
+	p.addField("app_name", s[:n])
+	s = s[n:]
+
+	// Parse proc_id
+	if len(s) > 0 && s[0] == '[' {
+		s = s[1:]
+		n = strings.IndexByte(s, ']')
+		if n < 0 {
+			p.addField("message", s)
+			return
+		}
+		p.addField("proc_id", s[:n])
+		s = s[n+1:]
+	}
+
+	// Skip optional ':' after the tag
+	s = strings.TrimPrefix(s, ":")
+	s = strings.TrimPrefix(s, " ")
+
+	if len(s) > 0 {
+		p.addField("message", s)
+	}
+}
@@ -0,0 +1,66 @@
+package logstorage
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSyslogParser(t *testing.T) {
+	f := func(s, resultExpected string) {
+		t.Helper()
+
+		const currentYear = 2024
+		p := GetSyslogParser(currentYear, time.UTC)
+		defer PutSyslogParser(p)
+
+		p.Parse(s)
+		rf := RowFormatter(p.Fields)
+		result := rf.String()
+		if result != resultExpected {
+			t.Fatalf("unexpected result when parsing [%s]; got\n%s\nwant\n%s\n", s, result, resultExpected)
+		}
+	}
+
+	// RFC 3164
+	f("Jun  3 12:08:33 abcd systemd[1]: Starting Update the local ESM caches...",
+		`{"format":"rfc3164","timestamp":"2024-06-03T12:08:33Z","hostname":"abcd","app_name":"systemd","proc_id":"1","message":"Starting Update the local ESM caches..."}`)
+	f("<165>Jun  3 12:08:33 abcd systemd[1]: Starting Update the local ESM caches...",
+		`{"priority":"165","facility":"20","severity":"5","format":"rfc3164","timestamp":"2024-06-03T12:08:33Z","hostname":"abcd","app_name":"systemd","proc_id":"1","message":"Starting Update the local ESM caches..."}`)
+	f("Mar 13 12:08:33 abcd systemd: Starting Update the local ESM caches...",
+		`{"format":"rfc3164","timestamp":"2024-03-13T12:08:33Z","hostname":"abcd","app_name":"systemd","message":"Starting Update the local ESM caches..."}`)
+	f("Jun  3 12:08:33 abcd - Starting Update the local ESM caches...",
+		`{"format":"rfc3164","timestamp":"2024-06-03T12:08:33Z","hostname":"abcd","app_name":"-","message":"Starting Update the local ESM caches..."}`)
+	f("Jun  3 12:08:33 - - Starting Update the local ESM caches...",
+		`{"format":"rfc3164","timestamp":"2024-06-03T12:08:33Z","hostname":"-","app_name":"-","message":"Starting Update the local ESM caches..."}`)
+
+	// RFC 3164 with RFC3339 timestamp
+	f("<34>2024-06-03T12:08:33.123+02:00 mymachine su: 'su root' failed for lonvick on /dev/pts/8",
+		`{"priority":"34","facility":"4","severity":"2","format":"rfc3164","timestamp":"2024-06-03T12:08:33.123+02:00","hostname":"mymachine","app_name":"su","message":"'su root' failed for lonvick on /dev/pts/8"}`)
+
+	// RFC 5424
+	f(`<165>1 2023-06-03T17:42:32.123456789Z mymachine.example.com appname 12345 ID47 - This is a test message with structured data.`,
+		`{"priority":"165","facility":"20","severity":"5","format":"rfc5424","timestamp":"2023-06-03T17:42:32.123456789Z","hostname":"mymachine.example.com","app_name":"appname","proc_id":"12345","msg_id":"ID47","message":"This is a test message with structured data."}`)
+	f(`1 2023-06-03T17:42:32.123456789Z mymachine.example.com appname 12345 ID47 - This is a test message with structured data.`,
+		`{"format":"rfc5424","timestamp":"2023-06-03T17:42:32.123456789Z","hostname":"mymachine.example.com","app_name":"appname","proc_id":"12345","msg_id":"ID47","message":"This is a test message with structured data."}`)
+	f(`<165>1 2023-06-03T17:42:00.000Z mymachine.example.com appname 12345 ID47 [exampleSDID@32473 iut="3" eventSource="Application 123 = ] 56" eventID="11211"] This is a test message with structured data.`,
+		`{"priority":"165","facility":"20","severity":"5","format":"rfc5424","timestamp":"2023-06-03T17:42:00.000Z","hostname":"mymachine.example.com","app_name":"appname","proc_id":"12345","msg_id":"ID47","exampleSDID@32473.iut":"3","exampleSDID@32473.eventSource":"Application 123 = ] 56","exampleSDID@32473.eventID":"11211","message":"This is a test message with structured data."}`)
+	f(`<165>1 2023-06-03T17:42:00.000Z mymachine.example.com appname 12345 ID47 [foo@123 a="b\"c\]d\\e\x"][bar@456][baz@789 x="y"]`,
+		`{"priority":"165","facility":"20","severity":"5","format":"rfc5424","timestamp":"2023-06-03T17:42:00.000Z","hostname":"mymachine.example.com","app_name":"appname","proc_id":"12345","msg_id":"ID47","foo@123.a":"b\"c]d\\e\\x","bar@456":"","baz@789.x":"y","message":""}`)
+	f("<165>1 2003-10-11T22:14:15.003Z - - - - - \xef\xbb\xbfmessage with BOM",
+		`{"priority":"165","facility":"20","severity":"5","format":"rfc5424","timestamp":"2003-10-11T22:14:15.003Z","message":"message with BOM"}`)
+
+	// Incomplete RFC 5424
+	f(`<165>1 2023-06-03T17:42:32.123456789Z mymachine.example.com appname 12345 ID47 [foo@123 a="b`,
+		`{"priority":"165","facility":"20","severity":"5","format":"rfc5424","timestamp":"2023-06-03T17:42:32.123456789Z","hostname":"mymachine.example.com","app_name":"appname","proc_id":"12345","msg_id":"ID47","message":"[foo@123 a=\"b"}`)
+	f(`<165>1 2023-06-03T17:42:32.123456789Z mymachine.example.com appname`,
+		`{"priority":"165","facility":"20","severity":"5","format":"rfc5424","timestamp":"2023-06-03T17:42:32.123456789Z","hostname":"mymachine.example.com","app_name":"appname"}`)
+	f(`<165>1 `,
+		`{"priority":"165","facility":"20","severity":"5","format":"rfc5424"}`)
+
+	// Invalid messages
+	f(``, `{}`)
+	f(`<165`, `{"format":"rfc3164","message":"<165"}`)
+	f(`<foo>bar`, `{"format":"rfc3164","message":"<foo>bar"}`)
+	f(`foo bar baz`, `{"format":"rfc3164","message":"foo bar baz"}`)
+	f(`<12>some message without timestamp and other fields`, `{"priority":"12","facility":"1","severity":"4","format":"rfc3164","message":"some message without timestamp and other fields"}`)
+}
@@ -0,0 +1,399 @@
+package prompb
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/VictoriaMetrics/easyproto"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/decimal"
+)
+
+// Histogram is Prometheus native histogram.
+//
+// See https://prometheus.io/docs/concepts/metric_types/#histogram
+type Histogram struct {
+	// Count is the total number of observations in the histogram.
+	Count float64
+
+	// Sum is the sum of observations in the histogram.
+	Sum float64
+
+	// Schema defines bucket boundaries for the histogram.
+	//
+	// Bucket boundaries are calculated as base^i, where base = 2^(2^-Schema).
+	// Schema=-53 means custom bucket boundaries defined in CustomValues.
+	Schema int32
+
+	// ZeroThreshold is the width of the zero bucket - [-ZeroThreshold ... ZeroThreshold].
+	ZeroThreshold float64
+
+	// ZeroCount is the number of observations in the zero bucket.
+	ZeroCount float64
+
+	// NegativeSpans, NegativeDeltas and NegativeCounts describe buckets for negative observations.
+	//
+	// NegativeDeltas are used for integer histograms, while NegativeCounts are used for float histograms.
+	NegativeSpans  []BucketSpan
+	NegativeDeltas []int64
+	NegativeCounts []float64
+
+	// PositiveSpans, PositiveDeltas and PositiveCounts describe buckets for positive observations.
+	//
+	// PositiveDeltas are used for integer histograms, while PositiveCounts are used for float histograms.
+	PositiveSpans  []BucketSpan
+	PositiveDeltas []int64
+	PositiveCounts []float64
+
+	// Timestamp is unix timestamp for the histogram in milliseconds.
+	Timestamp int64
+
+	// CustomValues contains upper bounds for buckets if Schema=-53.
+	CustomValues []float64
+}
+
+// BucketSpan defines a span of consecutive buckets in native histogram.
+type BucketSpan struct {
+	// Offset is the gap between the previous span and the current span.
+	//
+	// It is the index of the first bucket for the first span.
+	Offset int32
+
+	// Length is the number of consecutive buckets in the span.
+	Length uint32
+}
+
+// customBucketsSchema is the schema for native histograms with custom bucket boundaries.
+const customBucketsSchema = -53
+
+func (h *Histogram) reset() {
+	h.Count = 0
+	h.Sum = 0
+	h.Schema = 0
+	h.ZeroThreshold = 0
+	h.ZeroCount = 0
+
+	h.NegativeSpans = h.NegativeSpans[:0]
+	h.NegativeDeltas = h.NegativeDeltas[:0]
+	h.NegativeCounts = h.NegativeCounts[:0]
+
+	h.PositiveSpans = h.PositiveSpans[:0]
+	h.PositiveDeltas = h.PositiveDeltas[:0]
+	h.PositiveCounts = h.PositiveCounts[:0]
+
+	h.Timestamp = 0
+	h.CustomValues = h.CustomValues[:0]
+}
+
+// IsStale returns true if h is Prometheus staleness mark.
+func (h *Histogram) IsStale() bool {
+	return decimal.IsStaleNaN(h.Sum)
+}
+
+// VisitVMRangeBuckets calls f for each non-empty bucket in h.
+//
+// vmrange is the bucket range in VictoriaMetrics histogram format - `start...end`.
+// See https://valyala.medium.com/improving-histogram-usability-for-prometheus-and-grafana-bc7e5df0e350
+//
+// vmrange is valid only during f call, so it must be copied if it is used after f returns.
+// count is the number of observations in the bucket.
+func (h *Histogram) VisitVMRangeBuckets(f func(vmrange string, count float64)) {
+	var buf []byte
+	visit := func(start, end, count float64) {
+		if count <= 0 {
+			return
+		}
+		buf = appendVMRange(buf[:0], start, end)
+		f(bytesutil.ToUnsafeString(buf), count)
+	}
+
+	h.visitBuckets(h.NegativeSpans, h.NegativeDeltas, h.NegativeCounts, func(lower, upper, count float64) {
+		visit(-upper, -lower, count)
+	})
+
+	if h.Schema != customBucketsSchema {
+		zt := h.ZeroThreshold
+		start := float64(0)
+		if zt > 0 {
+			start = -zt
+		}
+		visit(start, zt, h.ZeroCount)
+	}
+
+	h.visitBuckets(h.PositiveSpans, h.PositiveDeltas, h.PositiveCounts, visit)
+}
+
+// ConvertHistograms converts native histograms in wr.Timeseries to VictoriaMetrics histograms and appends them to wr.Timeseries.
+//
+// Every native histogram is converted to `<name>_bucket{vmrange="..."}` series for non-empty buckets
+// plus `<name>_sum` and `<name>_count` series, where `<name>` is the metric name of the original time series.
+// This allows querying native histograms with histogram_quantile() and other histogram functions.
+//
+// Histograms are removed from the original time series after the conversion.
+func (wr *WriteRequest) ConvertHistograms() {
+	tss := wr.Timeseries
+	labelsPool := wr.labelsPool
+	samplesPool := wr.samplesPool
+
+	tssLen := len(tss)
+	for i := 0; i < tssLen; i++ {
+		hs := tss[i].Histograms
+		if len(hs) == 0 {
+			continue
+		}
+		tss[i].Histograms = nil
+		labels := tss[i].Labels
+		name := ""
+		for _, label := range labels {
+			if label.Name == "__name__" {
+				name = label.Value
+				break
+			}
+		}
+		countName := name + "_count"
+		sumName := name + "_sum"
+		bucketName := name + "_bucket"
+		for j := range hs {
+			h := &hs[j]
+			count := h.Count
+			if h.IsStale() {
+				count = decimal.StaleNaN
+			}
+			tss, labelsPool, samplesPool = appendConvertedSeries(tss, labelsPool, samplesPool, labels, countName, "", count, h.Timestamp)
+			tss, labelsPool, samplesPool = appendConvertedSeries(tss, labelsPool, samplesPool, labels, sumName, "", h.Sum, h.Timestamp)
+			if h.IsStale() {
+				continue
+			}
+			h.VisitVMRangeBuckets(func(vmrange string, count float64) {
+				vmrange = strings.Clone(vmrange)
+				tss, labelsPool, samplesPool = appendConvertedSeries(tss, labelsPool, samplesPool, labels, bucketName, vmrange, count, h.Timestamp)
+			})
+		}
+	}
+
+	wr.Timeseries = tss
+	wr.labelsPool = labelsPool
+	wr.samplesPool = samplesPool
+}
+
+// appendConvertedSeries appends time series with the given name, vmrange and labels plus a single sample with the given value and timestamp to tss.
+//
+// vmrange label isn't added if it is empty.
+func appendConvertedSeries(tss []TimeSeries, labelsPool []Label, samplesPool []Sample, labels []Label, name, vmrange string, value float64, timestamp int64) ([]TimeSeries, []Label, []Sample) {
+	labelsPoolLen := len(labelsPool)
+	labelsPool = append(labelsPool, Label{
+		Name:  "__name__",
+		Value: name,
+	})
+	for _, label := range labels {
+		if label.Name != "__name__" {
+			labelsPool = append(labelsPool, label)
+		}
+	}
+	if vmrange != "" {
+		labelsPool = append(labelsPool, Label{
+			Name:  "vmrange",
+			Value: vmrange,
+		})
+	}
+
+	samplesPoolLen := len(samplesPool)
+	samplesPool = append(samplesPool, Sample{
+		Value:     value,
+		Timestamp: timestamp,
+	})
+
+	tss = append(tss, TimeSeries{
+		Labels:  labelsPool[labelsPoolLen:],
+		Samples: samplesPool[samplesPoolLen:],
+	})
+	return tss, labelsPool, samplesPool
+}
+
+// visitBuckets calls f for each bucket described by spans and deltas or counts.
+func (h *Histogram) visitBuckets(spans []BucketSpan, deltas []int64, counts []float64, f func(lower, upper, count float64)) {
+	idx := int32(0)
+	n := 0
+	count := int64(0)
+	for _, span := range spans {
+		idx += span.Offset
+		for j := uint32(0); j < span.Length; j++ {
+			var v float64
+			if n < len(deltas) {
+				count += deltas[n]
+				v = float64(count)
+			} else if n < len(counts) {
+				v = counts[n]
+			} else {
+				// Malformed histogram - the number of buckets in spans exceeds the number of counts.
+				return
+			}
+			lower, upper := h.getBucketBounds(idx)
+			f(lower, upper, v)
+			n++
+			idx++
+		}
+	}
+}
+
+// getBucketBounds returns lower and upper bounds for the bucket with the given idx.
+func (h *Histogram) getBucketBounds(idx int32) (float64, float64) {
+	if h.Schema == customBucketsSchema {
+		cvs := h.CustomValues
+		lower := math.Inf(-1)
+		if idx > 0 && int(idx) <= len(cvs) {
+			lower = cvs[idx-1]
+		}
+		upper := math.Inf(1)
+		if idx >= 0 && int(idx) < len(cvs) {
+			upper = cvs[idx]
+		}
+		return lower, upper
+	}
+	return getBucketUpperBound(idx-1, h.Schema), getBucketUpperBound(idx, h.Schema)
+}
+
+// getBucketUpperBound returns the upper bound for the bucket with the given idx for the given schema.
+func getBucketUpperBound(idx, schema int32) float64 {
+	if schema <= 0 {
+		return math.Ldexp(1, int(idx)<<uint(-schema))
+	}
+	return math.Exp2(float64(idx) / float64(int64(1)<<uint(schema)))
+}
+
+// appendVMRange appends VictoriaMetrics histogram bucket range for the given start and end to dst and returns the result.
+//
+// The format is compatible with github.com/VictoriaMetrics/metrics.Histogram.
+func appendVMRange(dst []byte, start, end float64) []byte {
+	dst = strconv.AppendFloat(dst, start, 'e', 3, 64)
+	dst = append(dst, "..."...)
+	dst = strconv.AppendFloat(dst, end, 'e', 3, 64)
+	return dst
+}
+
+func (h *Histogram) unmarshalProtobuf(src []byte) (err error) {
+	// message Histogram {
+	//   oneof count {
+	//     uint64 count_int   = 1;
+	//     double count_float = 2;
+	//   }
+	//   double sum = 3;
+	//   sint32 schema = 4;
+	//   double zero_threshold = 5;
+	//   oneof zero_count {
+	//     uint64 zero_count_int   = 6;
+	//     double zero_count_float = 7;
+	//   }
+	//   repeated BucketSpan negative_spans = 8;
+	//   repeated sint64 negative_deltas = 9;
+	//   repeated double negative_counts = 10;
+	//   repeated BucketSpan positive_spans = 11;
+	//   repeated sint64 positive_deltas = 12;
+	//   repeated double positive_counts = 13;
+	//   ResetHint reset_hint = 14;
+	//   int64 timestamp = 15;
+	//   repeated double custom_values = 16;
+	// }
+	var fc easyproto.FieldContext
+	for len(src) > 0 {
+		src, err = fc.NextField(src)
+		if err != nil {
+			return fmt.Errorf("cannot read the next field: %w", err)
+		}
+		var ok bool
+		switch fc.FieldNum {
+		case 1:
+			var count uint64
+			count, ok = fc.Uint64()
+			h.Count = float64(count)
+		case 2:
+			h.Count, ok = fc.Double()
+		case 3:
+			h.Sum, ok = fc.Double()
+		case 4:
+			h.Schema, ok = fc.Sint32()
+		case 5:
+			h.ZeroThreshold, ok = fc.Double()
+		case 6:
+			var zeroCount uint64
+			zeroCount, ok = fc.Uint64()
+			h.ZeroCount = float64(zeroCount)
+		case 7:
+			h.ZeroCount, ok = fc.Double()
+		case 8:
+			h.NegativeSpans, err = appendBucketSpan(h.NegativeSpans, &fc)
+			if err != nil {
+				return fmt.Errorf("cannot unmarshal negative span: %w", err)
+			}
+			ok = true
+		case 9:
+			h.NegativeDeltas, ok = fc.UnpackSint64s(h.NegativeDeltas)
+		case 10:
+			h.NegativeCounts, ok = fc.UnpackDoubles(h.NegativeCounts)
+		case 11:
+			h.PositiveSpans, err = appendBucketSpan(h.PositiveSpans, &fc)
+			if err != nil {
+				return fmt.Errorf("cannot unmarshal positive span: %w", err)
+			}
+			ok = true
+		case 12:
+			h.PositiveDeltas, ok = fc.UnpackSint64s(h.PositiveDeltas)
+		case 13:
+			h.PositiveCounts, ok = fc.UnpackDoubles(h.PositiveCounts)
+		case 15:
+			h.Timestamp, ok = fc.Int64()
+		case 16:
+			h.CustomValues, ok = fc.UnpackDoubles(h.CustomValues)
+		default:
+			ok = true
+		}
+		if !ok {
+			return fmt.Errorf("cannot read histogram field #%d", fc.FieldNum)
+		}
+	}
+	return nil
+}
+
+func appendBucketSpan(dst []BucketSpan, fc *easyproto.FieldContext) ([]BucketSpan, error) {
+	data, ok := fc.MessageData()
+	if !ok {
+		return dst, fmt.Errorf("cannot read bucket span data")
+	}
+	var span BucketSpan
+	if err := span.unmarshalProtobuf(data); err != nil {
+		return dst, fmt.Errorf("cannot unmarshal bucket span: %w", err)
+	}
+	return append(dst, span), nil
+}
+
+func (span *BucketSpan) unmarshalProtobuf(src []byte) (err error) {
+	// message BucketSpan {
+	//   sint32 offset = 1;
+	//   uint32 length = 2;
+	// }
+	var fc easyproto.FieldContext
+	for len(src) > 0 {
+		src, err = fc.NextField(src)
+		if err != nil {
+			return fmt.Errorf("cannot read the next field: %w", err)
+		}
+		switch fc.FieldNum {
+		case 1:
+			offset, ok := fc.Sint32()
+			if !ok {
+				return fmt.Errorf("cannot read span offset")
+			}
+			span.Offset = offset
+		case 2:
+			length, ok := fc.Uint32()
+			if !ok {
+				return fmt.Errorf("cannot read span length")
+			}
+			span.Length = length
+		}
+	}
+	return nil
+}
@@ -0,0 +1,215 @@
+package prompb
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/VictoriaMetrics/easyproto"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/decimal"
+)
+
+func TestHistogramVisitVMRangeBuckets(t *testing.T) {
+	f := func(h *Histogram, resultExpected string) {
+		t.Helper()
+		var a []string
+		h.VisitVMRangeBuckets(func(vmrange string, count float64) {
+			a = append(a, fmt.Sprintf("%s %g", vmrange, count))
+		})
+		result := strings.Join(a, "\n")
+		if result != resultExpected {
+			t.Fatalf("unexpected result\ngot\n%s\nwant\n%s", result, resultExpected)
+		}
+	}
+
+	// empty histogram
+	f(&Histogram{}, "")
+
+	// integer histogram with schema=0
+	f(&Histogram{
+		ZeroThreshold: 0.001,
+		ZeroCount:     1,
+		NegativeSpans: []BucketSpan{
+			{Offset: 1, Length: 1},
+		},
+		NegativeDeltas: []int64{5},
+		PositiveSpans: []BucketSpan{
+			{Offset: 0, Length: 2},
+			{Offset: 1, Length: 1},
+		},
+		PositiveDeltas: []int64{2, -1, 3},
+	}, `-2.000e+00...-1.000e+00 5
+-1.000e-03...1.000e-03 1
+5.000e-01...1.000e+00 2
+1.000e+00...2.000e+00 1
+4.000e+00...8.000e+00 4`)
+
+	// float histogram with empty buckets
+	f(&Histogram{
+		PositiveSpans: []BucketSpan{
+			{Offset: -1, Length: 3},
+		},
+		PositiveCounts: []float64{1.5, 0, 2.5},
+	}, `2.500e-01...5.000e-01 1.5
+1.000e+00...2.000e+00 2.5`)
+
+	// positive schema
+	f(&Histogram{
+		Schema: 1,
+		PositiveSpans: []BucketSpan{
+			{Offset: 1, Length: 2},
+		},
+		PositiveDeltas: []int64{3, 1},
+	}, `1.000e+00...1.414e+00 3
+1.414e+00...2.000e+00 4`)
+
+	// negative schema
+	f(&Histogram{
+		Schema: -1,
+		PositiveSpans: []BucketSpan{
+			{Offset: 1, Length: 2},
+		},
+		PositiveDeltas: []int64{3, -2},
+	}, `1.000e+00...4.000e+00 3
+4.000e+00...1.600e+01 1`)
+
+	// custom buckets
+	f(&Histogram{
+		Schema:       customBucketsSchema,
+		CustomValues: []float64{1, 5},
+		PositiveSpans: []BucketSpan{
+			{Offset: 0, Length: 3},
+		},
+		PositiveDeltas: []int64{1, 0, 1},
+	}, `-Inf...1.000e+00 1
+1.000e+00...5.000e+00 1
+5.000e+00...+Inf 2`)
+
+	// malformed histogram with missing counts
+	f(&Histogram{
+		PositiveSpans: []BucketSpan{
+			{Offset: 1, Length: 3},
+		},
+		PositiveDeltas: []int64{3},
+	}, `1.000e+00...2.000e+00 3`)
+}
+
+func TestWriteRequestConvertHistograms(t *testing.T) {
+	f := func(histograms []Histogram, resultExpected string) {
+		t.Helper()
+
+		m := mp.Get()
+		mm := m.MessageMarshaler()
+		tsm := mm.AppendMessage(1)
+		for _, label := range []Label{{Name: "__name__", Value: "foo"}, {Name: "job", Value: "bar"}} {
+			lm := tsm.AppendMessage(1)
+			lm.AppendString(1, label.Name)
+			lm.AppendString(2, label.Value)
+		}
+		for i := range histograms {
+			histograms[i].marshalProtobuf(tsm.AppendMessage(4))
+		}
+		data := m.Marshal(nil)
+		mp.Put(m)
+
+		var wr WriteRequest
+		if err := wr.UnmarshalProtobuf(data); err != nil {
+			t.Fatalf("cannot unmarshal protobuf: %s", err)
+		}
+		wr.ConvertHistograms()
+
+		var a []string
+		for _, ts := range wr.Timeseries {
+			if len(ts.Histograms) > 0 {
+				t.Fatalf("unexpected histograms left after the conversion: %d", len(ts.Histograms))
+			}
+			var labels []string
+			for _, label := range ts.Labels {
+				labels = append(labels, fmt.Sprintf("%s=%q", label.Name, label.Value))
+			}
+			for _, s := range ts.Samples {
+				a = append(a, fmt.Sprintf("{%s} %g %d", strings.Join(labels, ","), s.Value, s.Timestamp))
+			}
+		}
+		result := strings.Join(a, "\n")
+		if result != resultExpected {
+			t.Fatalf("unexpected result\ngot\n%s\nwant\n%s", result, resultExpected)
+		}
+	}
+
+	f([]Histogram{
+		{
+			Count:         4,
+			Sum:           5.5,
+			ZeroThreshold: 0.001,
+			ZeroCount:     1,
+			PositiveSpans: []BucketSpan{
+				{Offset: 0, Length: 2},
+			},
+			PositiveDeltas: []int64{2, -1},
+			Timestamp:      1000,
+		},
+		{
+			Count:     0,
+			Sum:       decimal.StaleNaN,
+			Timestamp: 2000,
+		},
+	}, `{__name__="foo_count",job="bar"} 4 1000
+{__name__="foo_sum",job="bar"} 5.5 1000
+{__name__="foo_bucket",job="bar",vmrange="-1.000e-03...1.000e-03"} 1 1000
+{__name__="foo_bucket",job="bar",vmrange="5.000e-01...1.000e+00"} 2 1000
+{__name__="foo_bucket",job="bar",vmrange="1.000e+00...2.000e+00"} 1 1000
+{__name__="foo_count",job="bar"} NaN 2000
+{__name__="foo_sum",job="bar"} NaN 2000`)
+
+	// float histogram with custom buckets
+	f([]Histogram{
+		{
+			Count:        3.5,
+			Sum:          math.Pi,
+			Schema:       customBucketsSchema,
+			CustomValues: []float64{0.5},
+			PositiveSpans: []BucketSpan{
+				{Offset: 0, Length: 2},
+			},
+			PositiveCounts: []float64{1.5, 2},
+			Timestamp:      123,
+		},
+	}, `{__name__="foo_count",job="bar"} 3.5 123
+{__name__="foo_sum",job="bar"} 3.141592653589793 123
+{__name__="foo_bucket",job="bar",vmrange="-Inf...5.000e-01"} 1.5 123
+{__name__="foo_bucket",job="bar",vmrange="5.000e-01...+Inf"} 2 123`)
+}
+
+var mp easyproto.MarshalerPool
+
+func (h *Histogram) marshalProtobuf(mm *easyproto.MessageMarshaler) {
+	if h.Count == math.Trunc(h.Count) {
+		mm.AppendUint64(1, uint64(h.Count))
+	} else {
+		mm.AppendDouble(2, h.Count)
+	}
+	mm.AppendDouble(3, h.Sum)
+	mm.AppendSint32(4, h.Schema)
+	mm.AppendDouble(5, h.ZeroThreshold)
+	mm.AppendUint64(6, uint64(h.ZeroCount))
+	for _, span := range h.NegativeSpans {
+		span.marshalProtobuf(mm.AppendMessage(8))
+	}
+	mm.AppendSint64s(9, h.NegativeDeltas)
+	mm.AppendDoubles(10, h.NegativeCounts)
+	for _, span := range h.PositiveSpans {
+		span.marshalProtobuf(mm.AppendMessage(11))
+	}
+	mm.AppendSint64s(12, h.PositiveDeltas)
+	mm.AppendDoubles(13, h.PositiveCounts)
+	mm.AppendInt64(15, h.Timestamp)
+	mm.AppendDoubles(16, h.CustomValues)
+}
+
+func (span *BucketSpan) marshalProtobuf(mm *easyproto.MessageMarshaler) {
+	mm.AppendSint32(1, span.Offset)
+	mm.AppendUint32(2, span.Length)
+}
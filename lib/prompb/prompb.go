@@ -14,6 +14,14 @@ type WriteRequest struct {
 	exemplarLabelsPool []Label
 	samplesPool        []Sample
 	exemplarsPool      []Exemplar
+	histogramsPool     []Histogram
+
+	// symbolsPool and refsBuf are used for unmarshaling Prometheus remote write 2.0 requests
+	symbolsPool []string
+	refsBuf     []uint32
+
+	// Metadata is a list of metric metadata in the given WriteRequest
+	Metadata []MetricMetadata
 }
 
 // Reset resets wr for subsequent re-use.
@@ -34,7 +42,8 @@ func (wr *WriteRequest) Reset() {
 	for i := range exemplarLabelsPool {
 		exemplarLabelsPool[i] = Label{}
 	}
-	wr.labelsPool = labelsPool[:0]
+	wr.exemplarLabelsPool = exemplarLabelsPool[:0]
+
 	samplesPool := wr.samplesPool
 	for i := range samplesPool {
 		samplesPool[i] = Sample{}
@@ -45,6 +54,23 @@ func (wr *WriteRequest) Reset() {
 		exemplarsPool[i] = Exemplar{}
 	}
 	wr.exemplarsPool = exemplarsPool[:0]
+
+	// Do not clear histogramsPool items, since they do not refer to external memory
+	// and their slices can be re-used for the subsequent unmarshaling.
+	histogramsPool := wr.histogramsPool
+	for i := range histogramsPool {
+		histogramsPool[i].reset()
+	}
+	wr.histogramsPool = histogramsPool[:0]
+
+	clear(wr.symbolsPool)
+	wr.symbolsPool = wr.symbolsPool[:0]
+
+	mms := wr.Metadata
+	for i := range mms {
+		mms[i] = MetricMetadata{}
+	}
+	wr.Metadata = mms[:0]
 }
 
 // Exemplar is an exemplar
@@ -67,6 +93,16 @@ type TimeSeries struct {
 	// Samples is a list of samples for the given TimeSeries
 	Samples   []Sample
 	Exemplars []Exemplar
+
+	// Histograms is a list of native histograms for the given TimeSeries
+	Histograms []Histogram
+
+	// CreatedTimestamp is the time in milliseconds when the counter, summary or histogram for the given TimeSeries has been created.
+	//
+	// It is set only for Prometheus remote write 2.0 requests. Zero value means the created timestamp is unknown.
+	//
+	// VictoriaMetrics and vmagent do not use it yet, e.g. created timestamps are neither stored nor forwarded to -remoteWrite.url.
+	CreatedTimestamp int64
 }
 
 // Sample is a timeseries sample.
@@ -87,6 +123,69 @@ type Label struct {
 	Value string
 }
 
+// MetricMetadata represents additional meta information for the given metric family.
+type MetricMetadata struct {
+	// Type is the metric type.
+	Type MetricType
+
+	// MetricFamilyName is the name of the metric family the metadata belongs to.
+	MetricFamilyName string
+
+	// Help is the help text for the metric family.
+	Help string
+
+	// Unit is the unit of the metric family.
+	Unit string
+}
+
+// MetricType is the type of the metric family.
+//
+// See https://github.com/OpenObservability/OpenMetrics/blob/main/specification/OpenMetrics.md#metric-types
+type MetricType uint32
+
+// Metric types in the order defined by Prometheus remote write protocol.
+const (
+	MetricTypeUnknown        MetricType = 0
+	MetricTypeCounter        MetricType = 1
+	MetricTypeGauge          MetricType = 2
+	MetricTypeHistogram      MetricType = 3
+	MetricTypeGaugeHistogram MetricType = 4
+	MetricTypeSummary        MetricType = 5
+	MetricTypeInfo           MetricType = 6
+	MetricTypeStateset       MetricType = 7
+)
+
+var metricTypeNames = []string{
+	MetricTypeUnknown:        "unknown",
+	MetricTypeCounter:        "counter",
+	MetricTypeGauge:          "gauge",
+	MetricTypeHistogram:      "histogram",
+	MetricTypeGaugeHistogram: "gaugehistogram",
+	MetricTypeSummary:        "summary",
+	MetricTypeInfo:           "info",
+	MetricTypeStateset:       "stateset",
+}
+
+// String returns the name of mt as used in Prometheus text exposition format and in Prometheus querying API.
+func (mt MetricType) String() string {
+	if int(mt) >= len(metricTypeNames) {
+		return metricTypeNames[MetricTypeUnknown]
+	}
+	return metricTypeNames[mt]
+}
+
+// GetMetricType returns MetricType for the given type name from `# TYPE` comment in Prometheus text exposition format.
+//
+// MetricTypeUnknown is returned for unsupported type names such as `untyped`.
+func GetMetricType(s string) MetricType {
+	for i, name := range metricTypeNames {
+		if name == s {
+			return MetricType(i)
+		}
+	}
+	return MetricTypeUnknown
+}
+
 // UnmarshalProtobuf unmarshals wr from src.
 //
 // src mustn't change while wr is in use, since wr points to src.
@@ -95,12 +194,15 @@ func (wr *WriteRequest) UnmarshalProtobuf(src []byte) (err error) {
 
 	// message WriteRequest {
 	//    repeated TimeSeries timeseries = 1;
+	//    repeated MetricMetadata metadata = 3;
 	// }
 	tss := wr.Timeseries
+	mms := wr.Metadata
 	labelsPool := wr.labelsPool
 	exemplarLabelsPool := wr.exemplarLabelsPool
 	samplesPool := wr.samplesPool
 	exemplarsPool := wr.exemplarsPool
+	histogramsPool := wr.histogramsPool
 
 	var fc easyproto.FieldContext
 	for len(src) > 0 {
@@ -120,40 +222,59 @@ func (wr *WriteRequest) UnmarshalProtobuf(src []byte) (err error) {
 				tss = append(tss, TimeSeries{})
 			}
 			ts := &tss[len(tss)-1]
-			labelsPool, exemplarLabelsPool, samplesPool, exemplarsPool, err = ts.unmarshalProtobuf(data, labelsPool, exemplarLabelsPool, samplesPool, exemplarsPool)
+			labelsPool, exemplarLabelsPool, samplesPool, exemplarsPool, histogramsPool, err = ts.unmarshalProtobuf(data, labelsPool, exemplarLabelsPool, samplesPool, exemplarsPool, histogramsPool)
 			if err != nil {
 				return fmt.Errorf("cannot unmarshal timeseries: %w", err)
 			}
+		case 3:
+			data, ok := fc.MessageData()
+			if !ok {
+				return fmt.Errorf("cannot read metadata data")
+			}
+			if len(mms) < cap(mms) {
+				mms = mms[:len(mms)+1]
+			} else {
+				mms = append(mms, MetricMetadata{})
+			}
+			mm := &mms[len(mms)-1]
+			if err := mm.unmarshalProtobuf(data); err != nil {
+				return fmt.Errorf("cannot unmarshal metadata: %w", err)
+			}
 		}
 	}
 	wr.Timeseries = tss
+	wr.Metadata = mms
 	wr.labelsPool = labelsPool
+	wr.exemplarLabelsPool = exemplarLabelsPool
 	wr.samplesPool = samplesPool
 	wr.exemplarsPool = exemplarsPool
+	wr.histogramsPool = histogramsPool
 	return nil
 }
 
-func (ts *TimeSeries) unmarshalProtobuf(src []byte, labelsPool []Label, exemplarLabelsPool []Label, samplesPool []Sample, exemplarsPool []Exemplar) ([]Label, []Label, []Sample, []Exemplar, error) {
+func (ts *TimeSeries) unmarshalProtobuf(src []byte, labelsPool []Label, exemplarLabelsPool []Label, samplesPool []Sample, exemplarsPool []Exemplar, histogramsPool []Histogram) ([]Label, []Label, []Sample, []Exemplar, []Histogram, error) {
 	// message TimeSeries {
 	//   repeated Label labels   = 1;
 	//   repeated Sample samples = 2;
-	//   repeated Exemplar exemplars = 3
+	//   repeated Exemplar exemplars = 3;
+	//   repeated Histogram histograms = 4;
 	// }
 	labelsPoolLen := len(labelsPool)
 	samplesPoolLen := len(samplesPool)
 	exemplarsPoolLen := len(exemplarsPool)
+	histogramsPoolLen := len(histogramsPool)
 	var fc easyproto.FieldContext
 	for len(src) > 0 {
 		var err error
 		src, err = fc.NextField(src)
 		if err != nil {
-			return labelsPool, exemplarLabelsPool, samplesPool, exemplarsPool, fmt.Errorf("cannot read the next field: %w", err)
+			return labelsPool, exemplarLabelsPool, samplesPool, exemplarsPool, histogramsPool, fmt.Errorf("cannot read the next field: %w", err)
 		}
 		switch fc.FieldNum {
 		case 1:
 			data, ok := fc.MessageData()
 			if !ok {
-				return labelsPool, exemplarLabelsPool, samplesPool, exemplarsPool, fmt.Errorf("cannot read label data")
+				return labelsPool, exemplarLabelsPool, samplesPool, exemplarsPool, histogramsPool, fmt.Errorf("cannot read label data")
 			}
 			if len(labelsPool) < cap(labelsPool) {
 				labelsPool = labelsPool[:len(labelsPool)+1]
@@ -162,12 +283,12 @@ func (ts *TimeSeries) unmarshalProtobuf(src []byte, labelsPool []Label, exemplar
 			}
 			label := &labelsPool[len(labelsPool)-1]
 			if err := label.unmarshalProtobuf(data); err != nil {
-				return labelsPool, exemplarLabelsPool, samplesPool, exemplarsPool, fmt.Errorf("cannot unmarshal label: %w", err)
+				return labelsPool, exemplarLabelsPool, samplesPool, exemplarsPool, histogramsPool, fmt.Errorf("cannot unmarshal label: %w", err)
 			}
 		case 2:
 			data, ok := fc.MessageData()
 			if !ok {
-				return labelsPool, exemplarLabelsPool, samplesPool, exemplarsPool, fmt.Errorf("cannot read the sample data")
+				return labelsPool, exemplarLabelsPool, samplesPool, exemplarsPool, histogramsPool, fmt.Errorf("cannot read the sample data")
 			}
 			if len(samplesPool) < cap(samplesPool) {
 				samplesPool = samplesPool[:len(samplesPool)+1]
@@ -176,12 +297,12 @@ func (ts *TimeSeries) unmarshalProtobuf(src []byte, labelsPool []Label, exemplar
 			}
 			sample := &samplesPool[len(samplesPool)-1]
 			if err := sample.unmarshalProtobuf(data); err != nil {
-				return labelsPool, exemplarLabelsPool, samplesPool, exemplarsPool, fmt.Errorf("cannot unmarshal sample: %w", err)
+				return labelsPool, exemplarLabelsPool, samplesPool, exemplarsPool, histogramsPool, fmt.Errorf("cannot unmarshal sample: %w", err)
 			}
 		case 3:
 			data, ok := fc.MessageData()
 			if !ok {
-				return labelsPool, exemplarLabelsPool, samplesPool, exemplarsPool, fmt.Errorf("cannot read the exemplar data")
+				return labelsPool, exemplarLabelsPool, samplesPool, exemplarsPool, histogramsPool, fmt.Errorf("cannot read the exemplar data")
 			}
 			if len(exemplarsPool) < cap(exemplarsPool) {
 				exemplarsPool = exemplarsPool[:len(exemplarsPool)+1]
@@ -190,14 +311,29 @@ func (ts *TimeSeries) unmarshalProtobuf(src []byte, labelsPool []Label, exemplar
 			}
 			exemplar := &exemplarsPool[len(exemplarsPool)-1]
 			if exemplarLabelsPool, err = exemplar.unmarshalProtobuf(data, exemplarLabelsPool); err != nil {
-				return labelsPool, exemplarLabelsPool, samplesPool, exemplarsPool, fmt.Errorf("cannot unmarshal exemplar: %w", err)
+				return labelsPool, exemplarLabelsPool, samplesPool, exemplarsPool, histogramsPool, fmt.Errorf("cannot unmarshal exemplar: %w", err)
+			}
+		case 4:
+			data, ok := fc.MessageData()
+			if !ok {
+				return labelsPool, exemplarLabelsPool, samplesPool, exemplarsPool, histogramsPool, fmt.Errorf("cannot read the histogram data")
+			}
+			if len(histogramsPool) < cap(histogramsPool) {
+				histogramsPool = histogramsPool[:len(histogramsPool)+1]
+			} else {
+				histogramsPool = append(histogramsPool, Histogram{})
+			}
+			h := &histogramsPool[len(histogramsPool)-1]
+			if err := h.unmarshalProtobuf(data); err != nil {
+				return labelsPool, exemplarLabelsPool, samplesPool, exemplarsPool, histogramsPool, fmt.Errorf("cannot unmarshal histogram: %w", err)
 			}
 		}
 	}
 	ts.Labels = labelsPool[labelsPoolLen:]
 	ts.Samples = samplesPool[samplesPoolLen:]
 	ts.Exemplars = exemplarsPool[exemplarsPoolLen:]
-	return labelsPool, exemplarLabelsPool, samplesPool, exemplarsPool, nil
+	ts.Histograms = histogramsPool[histogramsPoolLen:]
+	return labelsPool, exemplarLabelsPool, samplesPool, exemplarsPool, histogramsPool, nil
 }
 
 func (exemplar *Exemplar) unmarshalProtobuf(src []byte, labelsPool []Label) ([]Label, error) {
@@ -248,6 +384,49 @@ func (exemplar *Exemplar) unmarshalProtobuf(src []byte, labelsPool []Label) ([]L
 	exemplar.Labels = labelsPool[labelsPoolLen:]
 	return labelsPool, nil
 }
+func (mm *MetricMetadata) unmarshalProtobuf(src []byte) (err error) {
+	// message MetricMetadata {
+	//   MetricType type = 1;
+	//   string metric_family_name = 2;
+	//   string help = 4;
+	//   string unit = 5;
+	// }
+	var fc easyproto.FieldContext
+	for len(src) > 0 {
+		src, err = fc.NextField(src)
+		if err != nil {
+			return fmt.Errorf("cannot read the next field: %w", err)
+		}
+		switch fc.FieldNum {
+		case 1:
+			typ, ok := fc.Uint32()
+			if !ok {
+				return fmt.Errorf("cannot read metric type")
+			}
+			mm.Type = MetricType(typ)
+		case 2:
+			name, ok := fc.String()
+			if !ok {
+				return fmt.Errorf("cannot read metric family name")
+			}
+			mm.MetricFamilyName = name
+		case 4:
+			help, ok := fc.String()
+			if !ok {
+				return fmt.Errorf("cannot read help")
+			}
+			mm.Help = help
+		case 5:
+			unit, ok := fc.String()
+			if !ok {
+				return fmt.Errorf("cannot read unit")
+			}
+			mm.Unit = unit
+		}
+	}
+	return nil
+}
+
 func (lbl *Label) unmarshalProtobuf(src []byte) (err error) {
 	// message Label {
 	//   string name  = 1;
@@ -57,6 +57,14 @@ func TestWriteRequestUnmarshalProtobuf(t *testing.T) {
 				Exemplars: exemplars,
 			})
 		}
+		for _, mm := range wr.Metadata {
+			wrm.Metadata = append(wrm.Metadata, prompbmarshal.MetricMetadata{
+				Type:             uint32(mm.Type),
+				MetricFamilyName: mm.MetricFamilyName,
+				Help:             mm.Help,
+				Unit:             mm.Unit,
+			})
+		}
 		dataResult := wrm.MarshalProtobuf(nil)
 		if !bytes.Equal(dataResult, data) {
 			t.Fatalf("unexpected data obtained after marshaling\ngot\n%X\nwant\n%X", dataResult, data)
@@ -227,4 +235,61 @@ func TestWriteRequestUnmarshalProtobuf(t *testing.T) {
 	}
 	data = wrm.MarshalProtobuf(data[:0])
 	f(data)
+	wrm.Reset()
+	wrm.Timeseries = []prompbmarshal.TimeSeries{
+		{
+			Labels: []prompbmarshal.Label{
+				{
+					Name:  "__name__",
+					Value: "process_cpu_seconds_total",
+				},
+			},
+			Samples: []prompbmarshal.Sample{
+				{
+					Value:     123.3434,
+					Timestamp: 8939432423,
+				},
+			},
+		},
+	}
+	wrm.Metadata = []prompbmarshal.MetricMetadata{
+		{
+			Type:             uint32(prompb.MetricTypeCounter),
+			MetricFamilyName: "process_cpu_seconds_total",
+			Help:             "Total user and system CPU time spent in seconds.",
+			Unit:             "seconds",
+		},
+		{
+			MetricFamilyName: "foo",
+		},
+	}
+	data = wrm.MarshalProtobuf(data[:0])
+	f(data)
+}
+
+func TestMetricTypeString(t *testing.T) {
+	f := func(s string, mtExpected prompb.MetricType, sExpected string) {
+		t.Helper()
+		mt := prompb.GetMetricType(s)
+		if mt != mtExpected {
+			t.Fatalf("unexpected metric type for %q; got %d; want %d", s, mt, mtExpected)
+		}
+		if mt.String() != sExpected {
+			t.Fatalf("unexpected string representation for %q; got %q; want %q", s, mt.String(), sExpected)
+		}
+	}
+	f("", prompb.MetricTypeUnknown, "unknown")
+	f("untyped", prompb.MetricTypeUnknown, "unknown")
+	f("unknown", prompb.MetricTypeUnknown, "unknown")
+	f("counter", prompb.MetricTypeCounter, "counter")
+	f("gauge", prompb.MetricTypeGauge, "gauge")
+	f("histogram", prompb.MetricTypeHistogram, "histogram")
+	f("gaugehistogram", prompb.MetricTypeGaugeHistogram, "gaugehistogram")
+	f("summary", prompb.MetricTypeSummary, "summary")
+	f("info", prompb.MetricTypeInfo, "info")
+	f("stateset", prompb.MetricTypeStateset, "stateset")
+
+	if s := prompb.MetricType(100).String(); s != "unknown" {
+		t.Fatalf("unexpected string representation for unsupported metric type; got %q; want %q", s, "unknown")
+	}
 }
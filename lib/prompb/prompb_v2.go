@@ -0,0 +1,307 @@
+package prompb
+
+import (
+	"fmt"
+
+	"github.com/VictoriaMetrics/easyproto"
+)
+
+// UnmarshalProtobufV2 unmarshals wr from src containing Prometheus remote write 2.0 request.
+//
+// The request is converted into remote write 1.0 representation:
+// label references are resolved via the symbols table, while per-series metadata is put into wr.Metadata.
+//
+// See https://prometheus.io/docs/specs/remote_write_spec_2_0/
+//
+// src mustn't change while wr is in use, since wr points to src.
+func (wr *WriteRequest) UnmarshalProtobufV2(src []byte) (err error) {
+	wr.Reset()
+
+	// message Request {
+	//   repeated string symbols = 4;
+	//   repeated TimeSeries timeseries = 5;
+	// }
+	//
+	// Time series refer to symbols, while protobuf allows arbitrary order of fields.
+	// So read symbols at the first pass and time series at the second pass.
+	symbols := wr.symbolsPool
+	var fc easyproto.FieldContext
+	tail := src
+	for len(tail) > 0 {
+		tail, err = fc.NextField(tail)
+		if err != nil {
+			return fmt.Errorf("cannot read the next field: %w", err)
+		}
+		if fc.FieldNum == 4 {
+			symbol, ok := fc.String()
+			if !ok {
+				return fmt.Errorf("cannot read symbol")
+			}
+			symbols = append(symbols, symbol)
+		}
+	}
+	wr.symbolsPool = symbols
+	if len(symbols) > 0 && symbols[0] != "" {
+		return fmt.Errorf("the first item in the symbols table must be an empty string; got %q", symbols[0])
+	}
+
+	tss := wr.Timeseries
+	for len(src) > 0 {
+		src, err = fc.NextField(src)
+		if err != nil {
+			return fmt.Errorf("cannot read the next field: %w", err)
+		}
+		if fc.FieldNum != 5 {
+			continue
+		}
+		data, ok := fc.MessageData()
+		if !ok {
+			return fmt.Errorf("cannot read timeseries data")
+		}
+		if len(tss) < cap(tss) {
+			tss = tss[:len(tss)+1]
+		} else {
+			tss = append(tss, TimeSeries{})
+		}
+		ts := &tss[len(tss)-1]
+		if err := ts.unmarshalProtobufV2(data, wr, symbols); err != nil {
+			return fmt.Errorf("cannot unmarshal timeseries: %w", err)
+		}
+	}
+	wr.Timeseries = tss
+	return nil
+}
+
+func (ts *TimeSeries) unmarshalProtobufV2(src []byte, wr *WriteRequest, symbols []string) (err error) {
+	// message TimeSeries {
+	//   repeated uint32 labels_refs = 1;
+	//   repeated Sample samples = 2;
+	//   repeated Histogram histograms = 3;
+	//   repeated Exemplar exemplars = 4;
+	//   Metadata metadata = 5;
+	//   int64 created_timestamp = 6;
+	// }
+	labelsPoolLen := len(wr.labelsPool)
+	samplesPoolLen := len(wr.samplesPool)
+	exemplarsPoolLen := len(wr.exemplarsPool)
+	histogramsPoolLen := len(wr.histogramsPool)
+
+	refs := wr.refsBuf[:0]
+	var mm MetricMetadata
+	hasMetadata := false
+	var fc easyproto.FieldContext
+	for len(src) > 0 {
+		src, err = fc.NextField(src)
+		if err != nil {
+			return fmt.Errorf("cannot read the next field: %w", err)
+		}
+		switch fc.FieldNum {
+		case 1:
+			var ok bool
+			refs, ok = fc.UnpackUint32s(refs)
+			if !ok {
+				return fmt.Errorf("cannot read labels_refs")
+			}
+		case 2:
+			data, ok := fc.MessageData()
+			if !ok {
+				return fmt.Errorf("cannot read the sample data")
+			}
+			if len(wr.samplesPool) < cap(wr.samplesPool) {
+				wr.samplesPool = wr.samplesPool[:len(wr.samplesPool)+1]
+			} else {
+				wr.samplesPool = append(wr.samplesPool, Sample{})
+			}
+			sample := &wr.samplesPool[len(wr.samplesPool)-1]
+			if err := sample.unmarshalProtobuf(data); err != nil {
+				return fmt.Errorf("cannot unmarshal sample: %w", err)
+			}
+		case 3:
+			data, ok := fc.MessageData()
+			if !ok {
+				return fmt.Errorf("cannot read the histogram data")
+			}
+			if len(wr.histogramsPool) < cap(wr.histogramsPool) {
+				wr.histogramsPool = wr.histogramsPool[:len(wr.histogramsPool)+1]
+			} else {
+				wr.histogramsPool = append(wr.histogramsPool, Histogram{})
+			}
+			h := &wr.histogramsPool[len(wr.histogramsPool)-1]
+			if err := h.unmarshalProtobuf(data); err != nil {
+				return fmt.Errorf("cannot unmarshal histogram: %w", err)
+			}
+		case 4:
+			data, ok := fc.MessageData()
+			if !ok {
+				return fmt.Errorf("cannot read the exemplar data")
+			}
+			if len(wr.exemplarsPool) < cap(wr.exemplarsPool) {
+				wr.exemplarsPool = wr.exemplarsPool[:len(wr.exemplarsPool)+1]
+			} else {
+				wr.exemplarsPool = append(wr.exemplarsPool, Exemplar{})
+			}
+			exemplar := &wr.exemplarsPool[len(wr.exemplarsPool)-1]
+			if err := exemplar.unmarshalProtobufV2(data, wr, symbols); err != nil {
+				return fmt.Errorf("cannot unmarshal exemplar: %w", err)
+			}
+		case 5:
+			data, ok := fc.MessageData()
+			if !ok {
+				return fmt.Errorf("cannot read the metadata data")
+			}
+			if err := mm.unmarshalProtobufV2(data, symbols); err != nil {
+				return fmt.Errorf("cannot unmarshal metadata: %w", err)
+			}
+			hasMetadata = true
+		case 6:
+			createdTimestamp, ok := fc.Int64()
+			if !ok {
+				return fmt.Errorf("cannot read created_timestamp")
+			}
+			ts.CreatedTimestamp = createdTimestamp
+		}
+	}
+	wr.refsBuf = refs
+
+	wr.labelsPool, err = appendLabelsByRefs(wr.labelsPool, refs, symbols)
+	if err != nil {
+		return fmt.Errorf("cannot resolve labels_refs: %w", err)
+	}
+	ts.Labels = wr.labelsPool[labelsPoolLen:]
+	ts.Samples = wr.samplesPool[samplesPoolLen:]
+	ts.Exemplars = wr.exemplarsPool[exemplarsPoolLen:]
+	ts.Histograms = wr.histogramsPool[histogramsPoolLen:]
+
+	if hasMetadata && (mm.Type != MetricTypeUnknown || mm.Help != "" || mm.Unit != "") {
+		for _, label := range ts.Labels {
+			if label.Name == "__name__" {
+				mm.MetricFamilyName = label.Value
+				break
+			}
+		}
+		// Time series for the same metric family usually go one after another and carry identical metadata.
+		// Skip the duplicate metadata in this case.
+		if n := len(wr.Metadata); n == 0 || wr.Metadata[n-1] != mm {
+			wr.Metadata = append(wr.Metadata, mm)
+		}
+	}
+	return nil
+}
+
+func (exemplar *Exemplar) unmarshalProtobufV2(src []byte, wr *WriteRequest, symbols []string) (err error) {
+	// message Exemplar {
+	//   repeated uint32 labels_refs = 1;
+	//   double value = 2;
+	//   int64 timestamp = 3;
+	// }
+	refs := wr.refsBuf[:0]
+	var fc easyproto.FieldContext
+	for len(src) > 0 {
+		src, err = fc.NextField(src)
+		if err != nil {
+			return fmt.Errorf("cannot read the next field: %w", err)
+		}
+		switch fc.FieldNum {
+		case 1:
+			var ok bool
+			refs, ok = fc.UnpackUint32s(refs)
+			if !ok {
+				return fmt.Errorf("cannot read labels_refs")
+			}
+		case 2:
+			value, ok := fc.Double()
+			if !ok {
+				return fmt.Errorf("cannot read exemplar value")
+			}
+			exemplar.Value = value
+		case 3:
+			timestamp, ok := fc.Int64()
+			if !ok {
+				return fmt.Errorf("cannot read exemplar timestamp")
+			}
+			exemplar.Timestamp = timestamp
+		}
+	}
+	wr.refsBuf = refs
+
+	labelsPoolLen := len(wr.exemplarLabelsPool)
+	wr.exemplarLabelsPool, err = appendLabelsByRefs(wr.exemplarLabelsPool, refs, symbols)
+	if err != nil {
+		return fmt.Errorf("cannot resolve labels_refs: %w", err)
+	}
+	exemplar.Labels = wr.exemplarLabelsPool[labelsPoolLen:]
+	return nil
+}
+
+func (mm *MetricMetadata) unmarshalProtobufV2(src []byte, symbols []string) (err error) {
+	// message Metadata {
+	//   MetricType type = 1;
+	//   uint32 help_ref = 3;
+	//   uint32 unit_ref = 4;
+	// }
+	var fc easyproto.FieldContext
+	for len(src) > 0 {
+		src, err = fc.NextField(src)
+		if err != nil {
+			return fmt.Errorf("cannot read the next field: %w", err)
+		}
+		switch fc.FieldNum {
+		case 1:
+			typ, ok := fc.Uint32()
+			if !ok {
+				return fmt.Errorf("cannot read metric type")
+			}
+			mm.Type = MetricType(typ)
+		case 3:
+			ref, ok := fc.Uint32()
+			if !ok {
+				return fmt.Errorf("cannot read help_ref")
+			}
+			mm.Help, err = getSymbol(symbols, ref)
+			if err != nil {
+				return fmt.Errorf("cannot resolve help_ref: %w", err)
+			}
+		case 4:
+			ref, ok := fc.Uint32()
+			if !ok {
+				return fmt.Errorf("cannot read unit_ref")
+			}
+			mm.Unit, err = getSymbol(symbols, ref)
+			if err != nil {
+				return fmt.Errorf("cannot resolve unit_ref: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// appendLabelsByRefs appends labels for the given refs to dst and returns the result.
+//
+// refs must contain name and value references into symbols for every label.
+func appendLabelsByRefs(dst []Label, refs []uint32, symbols []string) ([]Label, error) {
+	if len(refs)%2 != 0 {
+		return dst, fmt.Errorf("odd number of references: %d; references must go in name, value pairs", len(refs))
+	}
+	for i := 0; i < len(refs); i += 2 {
+		name, err := getSymbol(symbols, refs[i])
+		if err != nil {
+			return dst, fmt.Errorf("cannot resolve label name: %w", err)
+		}
+		value, err := getSymbol(symbols, refs[i+1])
+		if err != nil {
+			return dst, fmt.Errorf("cannot resolve label value: %w", err)
+		}
+		dst = append(dst, Label{
+			Name:  name,
+			Value: value,
+		})
+	}
+	return dst, nil
+}
+
+func getSymbol(symbols []string, ref uint32) (string, error) {
+	if uint64(ref) >= uint64(len(symbols)) {
+		return "", fmt.Errorf("reference %d is out of the symbols table with %d items", ref, len(symbols))
+	}
+	return symbols[ref], nil
+}
@@ -0,0 +1,109 @@
+package prompb
+
+import (
+	"testing"
+)
+
+func TestWriteRequestUnmarshalProtobufV2(t *testing.T) {
+	m := mp.Get()
+	mm := m.MessageMarshaler()
+	for _, symbol := range []string{"", "__name__", "request_duration_seconds", "job", "foo", "trace_id", "abc", "Request duration"} {
+		mm.AppendString(4, symbol)
+	}
+	tsm := mm.AppendMessage(5)
+	tsm.AppendUint32s(1, []uint32{1, 2, 3, 4})
+	h := &Histogram{
+		Count:         3,
+		Sum:           1.5,
+		ZeroThreshold: 0.001,
+		ZeroCount:     1,
+		PositiveSpans: []BucketSpan{
+			{Offset: 0, Length: 1},
+		},
+		PositiveDeltas: []int64{2},
+		Timestamp:      1000,
+	}
+	h.marshalProtobuf(tsm.AppendMessage(3))
+	em := tsm.AppendMessage(4)
+	em.AppendUint32s(1, []uint32{5, 6})
+	em.AppendDouble(2, 0.5)
+	em.AppendInt64(3, 999)
+	mdm := tsm.AppendMessage(5)
+	mdm.AppendUint32(1, uint32(MetricTypeHistogram))
+	mdm.AppendUint32(3, 7)
+	tsm.AppendInt64(6, 100)
+	data := m.Marshal(nil)
+	mp.Put(m)
+
+	var wr WriteRequest
+	if err := wr.UnmarshalProtobufV2(data); err != nil {
+		t.Fatalf("cannot unmarshal protobuf: %s", err)
+	}
+	if len(wr.Timeseries) != 1 {
+		t.Fatalf("unexpected number of time series; got %d; want 1", len(wr.Timeseries))
+	}
+	ts := &wr.Timeseries[0]
+	labelsExpected := []Label{
+		{Name: "__name__", Value: "request_duration_seconds"},
+		{Name: "job", Value: "foo"},
+	}
+	if len(ts.Labels) != len(labelsExpected) {
+		t.Fatalf("unexpected labels; got %v; want %v", ts.Labels, labelsExpected)
+	}
+	for i := range labelsExpected {
+		if ts.Labels[i] != labelsExpected[i] {
+			t.Fatalf("unexpected labels; got %v; want %v", ts.Labels, labelsExpected)
+		}
+	}
+	if len(ts.Histograms) != 1 || ts.Histograms[0].Count != 3 || ts.Histograms[0].Timestamp != 1000 {
+		t.Fatalf("unexpected histograms: %+v", ts.Histograms)
+	}
+	if len(ts.Exemplars) != 1 {
+		t.Fatalf("unexpected number of exemplars; got %d; want 1", len(ts.Exemplars))
+	}
+	e := &ts.Exemplars[0]
+	if len(e.Labels) != 1 || e.Labels[0] != (Label{Name: "trace_id", Value: "abc"}) || e.Value != 0.5 || e.Timestamp != 999 {
+		t.Fatalf("unexpected exemplar: %+v", e)
+	}
+	if ts.CreatedTimestamp != 100 {
+		t.Fatalf("unexpected created timestamp; got %d; want 100", ts.CreatedTimestamp)
+	}
+	mmExpected := MetricMetadata{
+		Type:             MetricTypeHistogram,
+		MetricFamilyName: "request_duration_seconds",
+		Help:             "Request duration",
+	}
+	if len(wr.Metadata) != 1 || wr.Metadata[0] != mmExpected {
+		t.Fatalf("unexpected metadata; got %+v; want %+v", wr.Metadata, mmExpected)
+	}
+}
+
+func TestWriteRequestUnmarshalProtobufV2Failure(t *testing.T) {
+	f := func(symbols []string, labelsRefs []uint32) {
+		t.Helper()
+
+		m := mp.Get()
+		mm := m.MessageMarshaler()
+		for _, symbol := range symbols {
+			mm.AppendString(4, symbol)
+		}
+		tsm := mm.AppendMessage(5)
+		tsm.AppendUint32s(1, labelsRefs)
+		data := m.Marshal(nil)
+		mp.Put(m)
+
+		var wr WriteRequest
+		if err := wr.UnmarshalProtobufV2(data); err == nil {
+			t.Fatalf("expecting non-nil error")
+		}
+	}
+
+	// the first symbol isn't empty
+	f([]string{"__name__", "foo"}, []uint32{0, 1})
+
+	// odd number of labels refs
+	f([]string{"", "__name__", "foo"}, []uint32{1, 2, 1})
+
+	// reference out of symbols table
+	f([]string{"", "__name__", "foo"}, []uint32{1, 3})
+}
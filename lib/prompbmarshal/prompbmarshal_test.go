@@ -107,3 +107,158 @@ func TestWriteRequestMarshalProtobuf(t *testing.T) {
 		t.Fatalf("unexpected data obtained after marshaling\ngot\n%X\nwant\n%X", dataResult, data)
 	}
 }
+
+func TestWriteRequestMarshalProtobufV2(t *testing.T) {
+	wrm := &prompbmarshal.WriteRequest{
+		Timeseries: []prompbmarshal.TimeSeries{
+			{
+				Labels: []prompbmarshal.Label{
+					{
+						Name:  "__name__",
+						Value: "http_requests_total",
+					},
+					{
+						Name:  "path",
+						Value: "/foo",
+					},
+					{
+						Name:  "empty",
+						Value: "",
+					},
+				},
+				Samples: []prompbmarshal.Sample{
+					{
+						Value:     123,
+						Timestamp: 8939432423,
+					},
+				},
+				Exemplars: []prompbmarshal.Exemplar{
+					{
+						Labels: []prompbmarshal.Label{
+							{
+								Name:  "trace_id",
+								Value: "/foo",
+							},
+						},
+						Value:     1,
+						Timestamp: 8939432400,
+					},
+				},
+			},
+			{
+				Labels: []prompbmarshal.Label{
+					{
+						Name:  "__name__",
+						Value: "http_requests_total",
+					},
+					{
+						Name:  "path",
+						Value: "/bar",
+					},
+				},
+				Samples: []prompbmarshal.Sample{
+					{
+						Value:     -1.5,
+						Timestamp: 8939432423,
+					},
+					{
+						Value:     2.5,
+						Timestamp: 8939442423,
+					},
+				},
+			},
+			{
+				Labels: []prompbmarshal.Label{
+					{
+						Name:  "__name__",
+						Value: "process_cpu_seconds_total",
+					},
+				},
+				Samples: []prompbmarshal.Sample{
+					{
+						Value:     0,
+						Timestamp: 0,
+					},
+				},
+			},
+		},
+		Metadata: []prompbmarshal.MetricMetadata{
+			{
+				Type:             uint32(prompb.MetricTypeCounter),
+				MetricFamilyName: "http_requests_total",
+				Help:             "The number of http requests",
+			},
+			{
+				Type:             uint32(prompb.MetricTypeGauge),
+				MetricFamilyName: "missing_metric",
+				Unit:             "bytes",
+			},
+		},
+	}
+	data := wrm.MarshalProtobufV2(nil)
+
+	// Verify that the marshaled protobuf is unmarshaled properly
+	var wr prompb.WriteRequest
+	if err := wr.UnmarshalProtobufV2(data); err != nil {
+		t.Fatalf("cannot unmarshal protobuf: %s", err)
+	}
+
+	// Metadata for metrics without time series cannot be passed via remote write 2.0 protocol.
+	wrm.Metadata = wrm.Metadata[:1]
+	dataExpected := wrm.MarshalProtobuf(nil)
+
+	// Compare the unmarshaled wr with the original wrm.
+	dataResult := newWriteRequestMarshal(&wr).MarshalProtobuf(nil)
+	if !bytes.Equal(dataResult, dataExpected) {
+		t.Fatalf("unexpected data obtained after marshaling\ngot\n%X\nwant\n%X", dataResult, dataExpected)
+	}
+}
+
+func newWriteRequestMarshal(wr *prompb.WriteRequest) *prompbmarshal.WriteRequest {
+	var wrm prompbmarshal.WriteRequest
+	for _, ts := range wr.Timeseries {
+		var labels []prompbmarshal.Label
+		for _, label := range ts.Labels {
+			labels = append(labels, prompbmarshal.Label{
+				Name:  label.Name,
+				Value: label.Value,
+			})
+		}
+		var samples []prompbmarshal.Sample
+		for _, sample := range ts.Samples {
+			samples = append(samples, prompbmarshal.Sample{
+				Value:     sample.Value,
+				Timestamp: sample.Timestamp,
+			})
+		}
+		var exemplars []prompbmarshal.Exemplar
+		for _, exemplar := range ts.Exemplars {
+			var exemplarLabels []prompbmarshal.Label
+			for _, label := range exemplar.Labels {
+				exemplarLabels = append(exemplarLabels, prompbmarshal.Label{
+					Name:  label.Name,
+					Value: label.Value,
+				})
+			}
+			exemplars = append(exemplars, prompbmarshal.Exemplar{
+				Labels:    exemplarLabels,
+				Value:     exemplar.Value,
+				Timestamp: exemplar.Timestamp,
+			})
+		}
+		wrm.Timeseries = append(wrm.Timeseries, prompbmarshal.TimeSeries{
+			Labels:    labels,
+			Samples:   samples,
+			Exemplars: exemplars,
+		})
+	}
+	for _, mm := range wr.Metadata {
+		wrm.Metadata = append(wrm.Metadata, prompbmarshal.MetricMetadata{
+			Type:             uint32(mm.Type),
+			MetricFamilyName: mm.MetricFamilyName,
+			Help:             mm.Help,
+			Unit:             mm.Unit,
+		})
+	}
+	return &wrm
+}
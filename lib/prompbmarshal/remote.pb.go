@@ -9,10 +9,21 @@ import (
 
 type WriteRequest struct {
 	Timeseries []TimeSeries
+	Metadata   []MetricMetadata
 }
 
 func (m *WriteRequest) MarshalToSizedBuffer(dst []byte) (int, error) {
 	i := len(dst)
+	for j := len(m.Metadata) - 1; j >= 0; j-- {
+		size, err := m.Metadata[j].MarshalToSizedBuffer(dst[:i])
+		if err != nil {
+			return 0, err
+		}
+		i -= size
+		i = encodeVarint(dst, i, uint64(size))
+		i--
+		dst[i] = 0x1a
+	}
 	for j := len(m.Timeseries) - 1; j >= 0; j-- {
 		size, err := m.Timeseries[j].MarshalToSizedBuffer(dst[:i])
 		if err != nil {
@@ -45,6 +56,10 @@ func (m *WriteRequest) Size() (n int) {
 		l := e.Size()
 		n += 1 + l + sov(uint64(l))
 	}
+	for _, e := range m.Metadata {
+		l := e.Size()
+		n += 1 + l + sov(uint64(l))
+	}
 	return n
 }
 
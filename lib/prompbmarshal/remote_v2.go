@@ -0,0 +1,155 @@
+package prompbmarshal
+
+import (
+	"sync"
+
+	"github.com/VictoriaMetrics/easyproto"
+)
+
+// MarshalProtobufV2 marshals wr to dst in Prometheus remote write 2.0 format and returns the result.
+//
+// Label names and values are interned in the symbols table. wr.Metadata is attached
+// to time series with the metric name matching MetricFamilyName.
+//
+// See https://prometheus.io/docs/specs/remote_write_spec_2_0/
+func (wr *WriteRequest) MarshalProtobufV2(dst []byte) []byte {
+	ctx := getMarshalV2Ctx()
+	defer putMarshalV2Ctx(ctx)
+
+	for i := range wr.Metadata {
+		mm := &wr.Metadata[i]
+		ctx.metadata[mm.MetricFamilyName] = mm
+	}
+
+	// message Request {
+	//   repeated string symbols = 4;
+	//   repeated TimeSeries timeseries = 5;
+	// }
+	//
+	// The symbols table is known only after all the time series are marshaled,
+	// so marshal time series and symbols separately and then concatenate them.
+	// This is valid protobuf, since a message is a concatenation of its fields.
+	m := mp.Get()
+	mm := m.MessageMarshaler()
+	for i := range wr.Timeseries {
+		ctx.marshalTimeSeries(mm.AppendMessage(5), &wr.Timeseries[i])
+	}
+	tssData := m.Marshal(ctx.buf[:0])
+	ctx.buf = tssData
+
+	m.Reset()
+	mm = m.MessageMarshaler()
+	for _, symbol := range ctx.symbols {
+		mm.AppendString(4, symbol)
+	}
+	dst = m.Marshal(dst)
+	mp.Put(m)
+
+	return append(dst, tssData...)
+}
+
+func (ctx *marshalV2Ctx) marshalTimeSeries(mm *easyproto.MessageMarshaler, ts *TimeSeries) {
+	// message TimeSeries {
+	//   repeated uint32 labels_refs = 1;
+	//   repeated Sample samples = 2;
+	//   repeated Exemplar exemplars = 4;
+	//   Metadata metadata = 5;
+	// }
+	metricName := ""
+	refs := ctx.refs[:0]
+	for i := range ts.Labels {
+		label := &ts.Labels[i]
+		if label.Name == "__name__" {
+			metricName = label.Value
+		}
+		refs = append(refs, ctx.symbolRef(label.Name), ctx.symbolRef(label.Value))
+	}
+	mm.AppendUint32s(1, refs)
+
+	for i := range ts.Samples {
+		s := &ts.Samples[i]
+		sm := mm.AppendMessage(2)
+		sm.AppendDouble(1, s.Value)
+		sm.AppendInt64(2, s.Timestamp)
+	}
+
+	for i := range ts.Exemplars {
+		e := &ts.Exemplars[i]
+		em := mm.AppendMessage(4)
+		refs = refs[:0]
+		for j := range e.Labels {
+			label := &e.Labels[j]
+			refs = append(refs, ctx.symbolRef(label.Name), ctx.symbolRef(label.Value))
+		}
+		em.AppendUint32s(1, refs)
+		em.AppendDouble(2, e.Value)
+		em.AppendInt64(3, e.Timestamp)
+	}
+	ctx.refs = refs
+
+	if md := ctx.metadata[metricName]; md != nil {
+		// message Metadata {
+		//   MetricType type = 1;
+		//   uint32 help_ref = 3;
+		//   uint32 unit_ref = 4;
+		// }
+		mdm := mm.AppendMessage(5)
+		mdm.AppendUint32(1, md.Type)
+		mdm.AppendUint32(3, ctx.symbolRef(md.Help))
+		mdm.AppendUint32(4, ctx.symbolRef(md.Unit))
+	}
+}
+
+type marshalV2Ctx struct {
+	// symbols is the symbols table. The first item is always an empty string according to the spec.
+	symbols    []string
+	symbolRefs map[string]uint32
+
+	metadata map[string]*MetricMetadata
+
+	refs []uint32
+	buf  []byte
+}
+
+func (ctx *marshalV2Ctx) reset() {
+	clear(ctx.symbols)
+	ctx.symbols = append(ctx.symbols[:0], "")
+	clear(ctx.symbolRefs)
+	clear(ctx.metadata)
+	ctx.refs = ctx.refs[:0]
+	ctx.buf = ctx.buf[:0]
+}
+
+func (ctx *marshalV2Ctx) symbolRef(s string) uint32 {
+	if s == "" {
+		return 0
+	}
+	if ref, ok := ctx.symbolRefs[s]; ok {
+		return ref
+	}
+	ref := uint32(len(ctx.symbols))
+	ctx.symbols = append(ctx.symbols, s)
+	ctx.symbolRefs[s] = ref
+	return ref
+}
+
+func getMarshalV2Ctx() *marshalV2Ctx {
+	v := marshalV2CtxPool.Get()
+	if v == nil {
+		return &marshalV2Ctx{
+			symbols:    []string{""},
+			symbolRefs: make(map[string]uint32),
+			metadata:   make(map[string]*MetricMetadata),
+		}
+	}
+	return v.(*marshalV2Ctx)
+}
+
+func putMarshalV2Ctx(ctx *marshalV2Ctx) {
+	ctx.reset()
+	marshalV2CtxPool.Put(ctx)
+}
+
+var marshalV2CtxPool sync.Pool
+
+var mp easyproto.MarshalerPool
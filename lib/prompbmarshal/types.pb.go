@@ -186,6 +186,64 @@ func (m *TimeSeries) Size() (n int) {
 	return n
 }
 
+type MetricMetadata struct {
+	// Type is the metric type. See prompb.MetricType for possible values.
+	Type             uint32
+	MetricFamilyName string
+	Help             string
+	Unit             string
+}
+
+func (m *MetricMetadata) MarshalToSizedBuffer(dst []byte) (int, error) {
+	i := len(dst)
+	if len(m.Unit) > 0 {
+		i -= len(m.Unit)
+		copy(dst[i:], m.Unit)
+		i = encodeVarint(dst, i, uint64(len(m.Unit)))
+		i--
+		dst[i] = 0x2a
+	}
+	if len(m.Help) > 0 {
+		i -= len(m.Help)
+		copy(dst[i:], m.Help)
+		i = encodeVarint(dst, i, uint64(len(m.Help)))
+		i--
+		dst[i] = 0x22
+	}
+	if len(m.MetricFamilyName) > 0 {
+		i -= len(m.MetricFamilyName)
+		copy(dst[i:], m.MetricFamilyName)
+		i = encodeVarint(dst, i, uint64(len(m.MetricFamilyName)))
+		i--
+		dst[i] = 0x12
+	}
+	if m.Type != 0 {
+		i = encodeVarint(dst, i, uint64(m.Type))
+		i--
+		dst[i] = 0x8
+	}
+	return len(dst) - i, nil
+}
+
+func (m *MetricMetadata) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	if m.Type != 0 {
+		n += 1 + sov(uint64(m.Type))
+	}
+	if l := len(m.MetricFamilyName); l > 0 {
+		n += 1 + l + sov(uint64(l))
+	}
+	if l := len(m.Help); l > 0 {
+		n += 1 + l + sov(uint64(l))
+	}
+	if l := len(m.Unit); l > 0 {
+		n += 1 + l + sov(uint64(l))
+	}
+	return n
+}
+
 func (m *Label) Size() (n int) {
 	if m == nil {
 		return 0
@@ -14,6 +14,7 @@ import (
 
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/flagutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/protoparser/prometheus/pb"
 )
 
 var (
@@ -30,7 +31,11 @@ var (
 	streamParse = flag.Bool("promscrape.streamParse", false, "Whether to enable stream parsing for metrics obtained from scrape targets. This may be useful "+
 		"for reducing memory usage when millions of metrics are exposed per each scrape target. "+
 		"It is possible to set 'stream_parse: true' individually per each 'scrape_config' section in '-promscrape.config' for fine-grained control")
-	scrapeExemplars = flag.Bool("promscrape.scrapeExemplars", false, "Whether to enable scraping of exemplars from scrape targets.")
+	scrapeExemplars        = flag.Bool("promscrape.scrapeExemplars", false, "Whether to enable scraping of exemplars from scrape targets.")
+	scrapeNativeHistograms = flag.Bool("promscrape.scrapeNativeHistograms", false, "Whether to request Prometheus protobuf exposition format from scrape targets "+
+		"in order to obtain native histograms. Native histograms are converted to VictoriaMetrics histograms with vmrange buckets. "+
+		"Targets, which do not support protobuf format, are scraped in Prometheus text exposition format. "+
+		"This flag cannot be used together with -promscrape.scrapeExemplars. See https://docs.victoriametrics.com/vmagent/#native-histograms")
 )
 
 type client struct {
@@ -114,6 +119,11 @@ func (c *client) ReadData(dst *bytesutil.ByteBuffer) error {
 	if *scrapeExemplars {
 		req.Header.Set("Accept", "application/openmetrics-text")
 	}
+	// Native histograms are exposed only in Prometheus protobuf format.
+	// See https://prometheus.io/docs/concepts/metric_types/#histogram
+	if *scrapeNativeHistograms {
+		req.Header.Set("Accept", pb.ContentType+";q=1,text/plain;version=0.0.4;q=0.5,*/*;q=0.1")
+	}
 	// Set X-Prometheus-Scrape-Timeout-Seconds like Prometheus does, since it is used by some exporters such as PushProx.
 	// See https://github.com/VictoriaMetrics/VictoriaMetrics/issues/1179#issuecomment-813117162
 	req.Header.Set("X-Prometheus-Scrape-Timeout-Seconds", c.scrapeTimeoutSecondsStr)
@@ -152,6 +162,7 @@ func (c *client) ReadData(dst *bytesutil.ByteBuffer) error {
 	}
 	_, err = dst.ReadFrom(r)
 	_ = resp.Body.Close()
+	isProtobuf := pb.IsProtobufContentType(resp.Header.Get("Content-Type"))
 	cancel()
 	if err != nil {
 		if ue, ok := err.(*url.Error); ok && ue.Timeout() {
@@ -164,6 +175,19 @@ func (c *client) ReadData(dst *bytesutil.ByteBuffer) error {
 		return fmt.Errorf("the response from %q exceeds -promscrape.maxScrapeSize=%d; "+
 			"either reduce the response size for the target or increase -promscrape.maxScrapeSize command-line flag value", c.scrapeURL, maxScrapeSize.N)
 	}
+	if isProtobuf {
+		// Convert the response to Prometheus text exposition format,
+		// so it could be processed in the same way as responses from the rest of targets.
+		bb := bbPool.Get()
+		bb.B, err = pb.AppendText(bb.B[:0], dst.B)
+		if err == nil {
+			dst.B = append(dst.B[:0], bb.B...)
+		}
+		bbPool.Put(bb)
+		if err != nil {
+			return fmt.Errorf("cannot parse protobuf response from %q: %w", c.scrapeURL, err)
+		}
+	}
 	return nil
 }
 
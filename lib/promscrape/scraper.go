@@ -62,6 +62,12 @@ func CheckConfig() error {
 //
 // Scraped data is passed to pushData.
 func Init(pushData func(at *auth.Token, wr *prompbmarshal.WriteRequest)) {
+	if *scrapeExemplars && *scrapeNativeHistograms {
+		// Exemplars are requested via OpenMetrics text format, while native histograms are exposed only in Prometheus protobuf format,
+		// which is converted to Prometheus text format without exemplars. So these flags cannot be used together.
+		logger.Fatalf("-promscrape.scrapeExemplars cannot be used together with -promscrape.scrapeNativeHistograms; " +
+			"disable one of these command-line flags")
+	}
 	mustInitClusterMemberID()
 	globalStopChan = make(chan struct{})
 	scraperWG.Add(1)
@@ -20,6 +20,7 @@ import (
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/leveledbytebufferpool"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promauth"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompb"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promrelabel"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promutils"
@@ -231,6 +232,9 @@ type scrapeWork struct {
 
 	// successRequestsCount is the number of success requests during the last suppressScrapeErrorsDelay
 	successRequestsCount int
+
+	// nextMetadataSendTime is the timestamp in milliseconds when the metric metadata should be sent next time.
+	nextMetadataSendTime int64
 }
 
 func (sw *scrapeWork) loadLastScrape() string {
@@ -475,6 +479,9 @@ func (sw *scrapeWork) processDataOneShot(scrapeTimestamp, realTimestamp int64, b
 	for i := range srcRows {
 		sw.addRowToTimeseries(wc, &srcRows[i], scrapeTimestamp, true)
 	}
+	if len(wc.rows.Metadata) > 0 && sw.needSendMetadata(realTimestamp) {
+		sw.addMetadata(wc, wc.rows.Metadata)
+	}
 	samplesPostRelabeling := len(wc.writeRequest.Timeseries)
 	if sw.Config.SampleLimit > 0 && samplesPostRelabeling > sw.Config.SampleLimit {
 		wc.resetNoRows()
@@ -533,9 +540,11 @@ func (sw *scrapeWork) processDataInStreamMode(scrapeTimestamp, realTimestamp int
 	areIdenticalSeries := sw.areIdenticalSeries(lastScrape, bodyString)
 	samplesDropped := 0
 
+	sendMetadata := sw.needSendMetadata(realTimestamp)
+
 	r := body.NewReader()
 	var mu sync.Mutex
-	err := stream.Parse(r, scrapeTimestamp, false, false, func(rows []parser.Row) error {
+	err := stream.Parse(r, scrapeTimestamp, false, false, func(rows []parser.Row, mds []parser.Metadata) error {
 		mu.Lock()
 		defer mu.Unlock()
 
@@ -543,6 +552,9 @@ func (sw *scrapeWork) processDataInStreamMode(scrapeTimestamp, realTimestamp int
 		for i := range rows {
 			sw.addRowToTimeseries(wc, &rows[i], scrapeTimestamp, true)
 		}
+		if sendMetadata {
+			sw.addMetadata(wc, mds)
+		}
 		samplesPostRelabeling += len(wc.writeRequest.Timeseries)
 		if sw.Config.SampleLimit > 0 && samplesPostRelabeling > sw.Config.SampleLimit {
 			wc.resetNoRows()
@@ -754,7 +766,7 @@ func (sw *scrapeWork) sendStaleSeries(lastScrape, currScrape string, timestamp i
 		// and https://github.com/VictoriaMetrics/VictoriaMetrics/issues/3675
 		var mu sync.Mutex
 		br := bytes.NewBufferString(bodyString)
-		err := stream.Parse(br, timestamp, false, false, func(rows []parser.Row) error {
+		err := stream.Parse(br, timestamp, false, false, func(rows []parser.Row, _ []parser.Metadata) error {
 			mu.Lock()
 			defer mu.Unlock()
 			for i := range rows {
@@ -930,6 +942,54 @@ func (sw *scrapeWork) addRowToTimeseries(wc *writeRequestCtx, r *parser.Row, tim
 
 var bbPool bytesutil.ByteBufferPool
 
+// metadataSendInterval is the interval for sending metric metadata per each scrape target.
+//
+// Metric metadata rarely changes, so there is no need in sending it on every scrape.
+const metadataSendInterval = time.Minute
+
+// needSendMetadata returns true if metric metadata must be sent for the scrape at the given timestamp in milliseconds.
+func (sw *scrapeWork) needSendMetadata(timestamp int64) bool {
+	if timestamp < sw.nextMetadataSendTime {
+		return false
+	}
+	sw.nextMetadataSendTime = timestamp + metadataSendInterval.Milliseconds()
+	return true
+}
+
+// addMetadata adds metric metadata from mds to wc.
+//
+// metric_relabel_configs are applied to the metric family name together with target labels,
+// so metadata for the dropped metric families isn't sent, while metadata for the renamed metric families is sent with the new name.
+func (sw *scrapeWork) addMetadata(wc *writeRequestCtx, mds []parser.Metadata) {
+	pcs := sw.Config.MetricRelabelConfigs
+	targetLabels := sw.Config.Labels.GetLabels()
+	for i := range mds {
+		md := &mds[i]
+		metricFamilyName := md.Metric
+		if pcs.Len() > 0 {
+			labelsLen := len(wc.labels)
+			wc.labels = appendLabels(wc.labels, metricFamilyName, nil, targetLabels, sw.Config.HonorLabels)
+			wc.labels = pcs.Apply(wc.labels, labelsLen)
+			label := promrelabel.GetLabelByName(wc.labels[labelsLen:], "__name__")
+			if label != nil {
+				metricFamilyName = label.Value
+			}
+			clear(wc.labels[labelsLen:])
+			wc.labels = wc.labels[:labelsLen]
+			if label == nil || metricFamilyName == "" {
+				// The metric family has been dropped by relabeling.
+				continue
+			}
+		}
+		wc.writeRequest.Metadata = append(wc.writeRequest.Metadata, prompbmarshal.MetricMetadata{
+			Type:             uint32(prompb.GetMetricType(md.Type)),
+			MetricFamilyName: metricFamilyName,
+			Help:             md.Help,
+			Unit:             md.Unit,
+		})
+	}
+}
+
 func appendLabels(dst []prompbmarshal.Label, metric string, src []parser.Tag, extraLabels []prompbmarshal.Label, honorLabels bool) []prompbmarshal.Label {
 	dstLen := len(dst)
 	dst = append(dst, prompbmarshal.Label{
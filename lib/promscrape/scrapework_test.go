@@ -2,12 +2,14 @@ package promscrape
 
 import (
 	"fmt"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/auth"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompb"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promrelabel"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promutils"
@@ -474,6 +476,87 @@ func TestScrapeWorkScrapeInternalSuccess(t *testing.T) {
 	`)
 }
 
+func TestScrapeWorkScrapeInternalMetadata(t *testing.T) {
+	data := `
+# HELP foo foo help
+# TYPE foo counter
+foo 1
+# HELP bar bar help
+# TYPE bar gauge
+bar 2
+# HELP dropme dropme help
+dropme 3
+`
+	var sw scrapeWork
+	sw.Config = &ScrapeWork{
+		ScrapeTimeout: time.Second * 42,
+		Labels: promutils.NewLabelsFromMap(map[string]string{
+			"job": "xx",
+		}),
+		MetricRelabelConfigs: mustParseRelabelConfigs(`
+- action: drop
+  source_labels: [__name__]
+  regex: dropme
+- action: replace
+  source_labels: [__name__]
+  regex: bar
+  target_label: __name__
+  replacement: baz
+`),
+	}
+	sw.ReadData = func(dst *bytesutil.ByteBuffer) error {
+		dst.B = append(dst.B, data...)
+		return nil
+	}
+	var mms []prompbmarshal.MetricMetadata
+	sw.PushData = func(_ *auth.Token, wr *prompbmarshal.WriteRequest) {
+		for _, mm := range wr.Metadata {
+			mms = append(mms, prompbmarshal.MetricMetadata{
+				Type:             mm.Type,
+				MetricFamilyName: strings.Clone(mm.MetricFamilyName),
+				Help:             strings.Clone(mm.Help),
+				Unit:             strings.Clone(mm.Unit),
+			})
+		}
+	}
+
+	f := func(timestamp int64, mmsExpected []prompbmarshal.MetricMetadata) {
+		t.Helper()
+
+		mms = nil
+		if err := sw.scrapeInternal(timestamp, timestamp); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if !reflect.DeepEqual(mms, mmsExpected) {
+			t.Fatalf("unexpected metadata\ngot\n%+v\nwant\n%+v", mms, mmsExpected)
+		}
+	}
+
+	tsmGlobal.Register(&sw)
+	defer tsmGlobal.Unregister(&sw)
+
+	// metric_relabel_configs must be applied to metadata
+	mmsExpected := []prompbmarshal.MetricMetadata{
+		{
+			Type:             uint32(prompb.MetricTypeCounter),
+			MetricFamilyName: "foo",
+			Help:             "foo help",
+		},
+		{
+			Type:             uint32(prompb.MetricTypeGauge),
+			MetricFamilyName: "baz",
+			Help:             "bar help",
+		},
+	}
+	f(123000, mmsExpected)
+
+	// Metadata mustn't be sent on every scrape
+	f(123000+metadataSendInterval.Milliseconds()/2, nil)
+
+	// Metadata must be sent again after metadataSendInterval
+	f(123000+metadataSendInterval.Milliseconds(), mmsExpected)
+}
+
 func TestAddRowToTimeseriesNoRelabeling(t *testing.T) {
 	f := func(row string, cfg *ScrapeWork, dataExpected string) {
 		t.Helper()
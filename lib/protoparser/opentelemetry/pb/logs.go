@@ -0,0 +1,306 @@
+package pb
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/VictoriaMetrics/easyproto"
+)
+
+// ExportLogsServiceRequest represents the corresponding OTEL protobuf message
+type ExportLogsServiceRequest struct {
+	ResourceLogs []*ResourceLogs
+}
+
+// UnmarshalProtobuf unmarshals r from protobuf message at src.
+func (r *ExportLogsServiceRequest) UnmarshalProtobuf(src []byte) error {
+	r.ResourceLogs = nil
+	return r.unmarshalProtobuf(src)
+}
+
+// MarshalProtobuf marshals r to protobuf message, appends it to dst and returns the result.
+func (r *ExportLogsServiceRequest) MarshalProtobuf(dst []byte) []byte {
+	m := mp.Get()
+	r.marshalProtobuf(m.MessageMarshaler())
+	dst = m.Marshal(dst)
+	mp.Put(m)
+	return dst
+}
+
+func (r *ExportLogsServiceRequest) marshalProtobuf(mm *easyproto.MessageMarshaler) {
+	for _, rl := range r.ResourceLogs {
+		rl.marshalProtobuf(mm.AppendMessage(1))
+	}
+}
+
+func (r *ExportLogsServiceRequest) unmarshalProtobuf(src []byte) (err error) {
+	// message ExportLogsServiceRequest {
+	//   repeated ResourceLogs resource_logs = 1;
+	// }
+	var fc easyproto.FieldContext
+	for len(src) > 0 {
+		src, err = fc.NextField(src)
+		if err != nil {
+			return fmt.Errorf("cannot read next field in ExportLogsServiceRequest: %w", err)
+		}
+		switch fc.FieldNum {
+		case 1:
+			data, ok := fc.MessageData()
+			if !ok {
+				return fmt.Errorf("cannot read ResourceLogs data")
+			}
+			r.ResourceLogs = append(r.ResourceLogs, &ResourceLogs{})
+			rl := r.ResourceLogs[len(r.ResourceLogs)-1]
+			if err := rl.unmarshalProtobuf(data); err != nil {
+				return fmt.Errorf("cannot unmarshal ResourceLogs: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// ResourceLogs represents the corresponding OTEL protobuf message
+type ResourceLogs struct {
+	Resource  *Resource
+	ScopeLogs []*ScopeLogs
+}
+
+func (rl *ResourceLogs) marshalProtobuf(mm *easyproto.MessageMarshaler) {
+	if rl.Resource != nil {
+		rl.Resource.marshalProtobuf(mm.AppendMessage(1))
+	}
+	for _, sl := range rl.ScopeLogs {
+		sl.marshalProtobuf(mm.AppendMessage(2))
+	}
+}
+
+func (rl *ResourceLogs) unmarshalProtobuf(src []byte) (err error) {
+	// message ResourceLogs {
+	//   Resource resource = 1;
+	//   repeated ScopeLogs scope_logs = 2;
+	// }
+	var fc easyproto.FieldContext
+	for len(src) > 0 {
+		src, err = fc.NextField(src)
+		if err != nil {
+			return fmt.Errorf("cannot read next field in ResourceLogs: %w", err)
+		}
+		switch fc.FieldNum {
+		case 1:
+			data, ok := fc.MessageData()
+			if !ok {
+				return fmt.Errorf("cannot read Resource data")
+			}
+			rl.Resource = &Resource{}
+			if err := rl.Resource.unmarshalProtobuf(data); err != nil {
+				return fmt.Errorf("cannot umarshal Resource: %w", err)
+			}
+		case 2:
+			data, ok := fc.MessageData()
+			if !ok {
+				return fmt.Errorf("cannot read ScopeLogs data")
+			}
+			rl.ScopeLogs = append(rl.ScopeLogs, &ScopeLogs{})
+			sl := rl.ScopeLogs[len(rl.ScopeLogs)-1]
+			if err := sl.unmarshalProtobuf(data); err != nil {
+				return fmt.Errorf("cannot unmarshal ScopeLogs: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// ScopeLogs represents the corresponding OTEL protobuf message
+type ScopeLogs struct {
+	LogRecords []*LogRecord
+}
+
+func (sl *ScopeLogs) marshalProtobuf(mm *easyproto.MessageMarshaler) {
+	for _, lr := range sl.LogRecords {
+		lr.marshalProtobuf(mm.AppendMessage(2))
+	}
+}
+
+func (sl *ScopeLogs) unmarshalProtobuf(src []byte) (err error) {
+	// message ScopeLogs {
+	//   repeated LogRecord log_records = 2;
+	// }
+	var fc easyproto.FieldContext
+	for len(src) > 0 {
+		src, err = fc.NextField(src)
+		if err != nil {
+			return fmt.Errorf("cannot read next field in ScopeLogs: %w", err)
+		}
+		switch fc.FieldNum {
+		case 2:
+			data, ok := fc.MessageData()
+			if !ok {
+				return fmt.Errorf("cannot read LogRecord data")
+			}
+			sl.LogRecords = append(sl.LogRecords, &LogRecord{})
+			lr := sl.LogRecords[len(sl.LogRecords)-1]
+			if err := lr.unmarshalProtobuf(data); err != nil {
+				return fmt.Errorf("cannot unmarshal LogRecord: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// LogRecord represents the corresponding OTEL protobuf message
+type LogRecord struct {
+	TimeUnixNano         uint64
+	ObservedTimeUnixNano uint64
+	SeverityNumber       SeverityNumber
+	SeverityText         string
+	Body                 *AnyValue
+	Attributes           []*KeyValue
+
+	// TraceID contains hex-encoded trace_id
+	TraceID string
+
+	// SpanID contains hex-encoded span_id
+	SpanID string
+}
+
+func (lr *LogRecord) marshalProtobuf(mm *easyproto.MessageMarshaler) {
+	mm.AppendFixed64(1, lr.TimeUnixNano)
+	mm.AppendInt64(2, int64(lr.SeverityNumber))
+	mm.AppendString(3, lr.SeverityText)
+	if lr.Body != nil {
+		lr.Body.marshalProtobuf(mm.AppendMessage(5))
+	}
+	for _, a := range lr.Attributes {
+		a.marshalProtobuf(mm.AppendMessage(6))
+	}
+	if traceID, err := hex.DecodeString(lr.TraceID); err == nil && len(traceID) > 0 {
+		mm.AppendBytes(9, traceID)
+	}
+	if spanID, err := hex.DecodeString(lr.SpanID); err == nil && len(spanID) > 0 {
+		mm.AppendBytes(10, spanID)
+	}
+	mm.AppendFixed64(11, lr.ObservedTimeUnixNano)
+}
+
+func (lr *LogRecord) unmarshalProtobuf(src []byte) (err error) {
+	// message LogRecord {
+	//   fixed64 time_unix_nano = 1;
+	//   fixed64 observed_time_unix_nano = 11;
+	//   SeverityNumber severity_number = 2;
+	//   string severity_text = 3;
+	//   AnyValue body = 5;
+	//   repeated KeyValue attributes = 6;
+	//   bytes trace_id = 9;
+	//   bytes span_id = 10;
+	// }
+	var fc easyproto.FieldContext
+	for len(src) > 0 {
+		src, err = fc.NextField(src)
+		if err != nil {
+			return fmt.Errorf("cannot read next field in LogRecord: %w", err)
+		}
+		switch fc.FieldNum {
+		case 1:
+			ts, ok := fc.Fixed64()
+			if !ok {
+				return fmt.Errorf("cannot read TimeUnixNano")
+			}
+			lr.TimeUnixNano = ts
+		case 11:
+			ts, ok := fc.Fixed64()
+			if !ok {
+				return fmt.Errorf("cannot read ObservedTimeUnixNano")
+			}
+			lr.ObservedTimeUnixNano = ts
+		case 2:
+			severityNumber, ok := fc.Int32()
+			if !ok {
+				return fmt.Errorf("cannot read SeverityNumber")
+			}
+			lr.SeverityNumber = SeverityNumber(severityNumber)
+		case 3:
+			severityText, ok := fc.String()
+			if !ok {
+				return fmt.Errorf("cannot read SeverityText")
+			}
+			lr.SeverityText = strings.Clone(severityText)
+		case 5:
+			data, ok := fc.MessageData()
+			if !ok {
+				return fmt.Errorf("cannot read Body")
+			}
+			lr.Body = &AnyValue{}
+			if err := lr.Body.unmarshalProtobuf(data); err != nil {
+				return fmt.Errorf("cannot unmarshal Body: %w", err)
+			}
+		case 6:
+			data, ok := fc.MessageData()
+			if !ok {
+				return fmt.Errorf("cannot read Attribute")
+			}
+			lr.Attributes = append(lr.Attributes, &KeyValue{})
+			a := lr.Attributes[len(lr.Attributes)-1]
+			if err := a.unmarshalProtobuf(data); err != nil {
+				return fmt.Errorf("cannot unmarshal Attribute: %w", err)
+			}
+		case 9:
+			traceID, ok := fc.Bytes()
+			if !ok {
+				return fmt.Errorf("cannot read TraceID")
+			}
+			lr.TraceID = hex.EncodeToString(traceID)
+		case 10:
+			spanID, ok := fc.Bytes()
+			if !ok {
+				return fmt.Errorf("cannot read SpanID")
+			}
+			lr.SpanID = hex.EncodeToString(spanID)
+		}
+	}
+	return nil
+}
+
+// ExtractTimestampNano returns timestamp in nanoseconds for lr.
+//
+// ObservedTimeUnixNano is returned if TimeUnixNano is missing.
+// Zero is returned if both timestamps are missing.
+func (lr *LogRecord) ExtractTimestampNano() int64 {
+	if lr.TimeUnixNano > 0 {
+		return int64(lr.TimeUnixNano)
+	}
+	return int64(lr.ObservedTimeUnixNano)
+}
+
+// FormatSeverity returns string representation of the severity for lr.
+//
+// SeverityText is returned if it is set. Otherwise the short name for SeverityNumber is returned.
+func (lr *LogRecord) FormatSeverity() string {
+	if lr.SeverityText != "" {
+		return lr.SeverityText
+	}
+	return lr.SeverityNumber.String()
+}
+
+// SeverityNumber represents the corresponding OTEL protobuf enum
+//
+// See https://opentelemetry.io/docs/specs/otel/logs/data-model/#field-severitynumber
+type SeverityNumber int32
+
+// String returns the short name for sn according to https://opentelemetry.io/docs/specs/otel/logs/data-model/#displaying-severity
+//
+// An empty string is returned for unspecified or unknown sn.
+func (sn SeverityNumber) String() string {
+	if sn < 1 || sn > 24 {
+		return ""
+	}
+	n := int(sn - 1)
+	name := severityNames[n/4]
+	if idx := n % 4; idx > 0 {
+		name += strconv.Itoa(idx + 1)
+	}
+	return name
+}
+
+var severityNames = [...]string{"TRACE", "DEBUG", "INFO", "WARN", "ERROR", "FATAL"}
@@ -0,0 +1,307 @@
+package pb
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/valyala/fastjson"
+)
+
+var jsonParserPool fastjson.ParserPool
+
+// UnmarshalJSON unmarshals r from JSON-encoded OTLP message at src.
+//
+// See https://opentelemetry.io/docs/specs/otlp/#json-protobuf-encoding
+func (r *ExportLogsServiceRequest) UnmarshalJSON(src []byte) error {
+	r.ResourceLogs = nil
+
+	p := jsonParserPool.Get()
+	defer jsonParserPool.Put(p)
+
+	v, err := p.ParseBytes(src)
+	if err != nil {
+		return fmt.Errorf("cannot parse JSON: %w", err)
+	}
+	o, err := v.Object()
+	if err != nil {
+		return fmt.Errorf("ExportLogsServiceRequest must be JSON object; got %s", v.Type())
+	}
+	rlsV := o.Get("resourceLogs")
+	if rlsV == nil {
+		return nil
+	}
+	rlsA, err := rlsV.Array()
+	if err != nil {
+		return fmt.Errorf("`resourceLogs` must be JSON array; got %s", rlsV.Type())
+	}
+	for _, rlV := range rlsA {
+		rl := &ResourceLogs{}
+		if err := rl.unmarshalJSON(rlV); err != nil {
+			return fmt.Errorf("cannot unmarshal ResourceLogs: %w", err)
+		}
+		r.ResourceLogs = append(r.ResourceLogs, rl)
+	}
+	return nil
+}
+
+func (rl *ResourceLogs) unmarshalJSON(v *fastjson.Value) error {
+	if rV := v.Get("resource"); rV != nil {
+		attributes, err := unmarshalJSONKeyValues(rV.Get("attributes"))
+		if err != nil {
+			return fmt.Errorf("cannot unmarshal Resource attributes: %w", err)
+		}
+		rl.Resource = &Resource{
+			Attributes: attributes,
+		}
+	}
+
+	slsV := v.Get("scopeLogs")
+	if slsV == nil {
+		return nil
+	}
+	slsA, err := slsV.Array()
+	if err != nil {
+		return fmt.Errorf("`scopeLogs` must be JSON array; got %s", slsV.Type())
+	}
+	for _, slV := range slsA {
+		sl := &ScopeLogs{}
+		if err := sl.unmarshalJSON(slV); err != nil {
+			return fmt.Errorf("cannot unmarshal ScopeLogs: %w", err)
+		}
+		rl.ScopeLogs = append(rl.ScopeLogs, sl)
+	}
+	return nil
+}
+
+func (sl *ScopeLogs) unmarshalJSON(v *fastjson.Value) error {
+	lrsV := v.Get("logRecords")
+	if lrsV == nil {
+		return nil
+	}
+	lrsA, err := lrsV.Array()
+	if err != nil {
+		return fmt.Errorf("`logRecords` must be JSON array; got %s", lrsV.Type())
+	}
+	for _, lrV := range lrsA {
+		lr := &LogRecord{}
+		if err := lr.unmarshalJSON(lrV); err != nil {
+			return fmt.Errorf("cannot unmarshal LogRecord: %w", err)
+		}
+		sl.LogRecords = append(sl.LogRecords, lr)
+	}
+	return nil
+}
+
+func (lr *LogRecord) unmarshalJSON(v *fastjson.Value) (err error) {
+	if lr.TimeUnixNano, err = getJSONUint64(v, "timeUnixNano"); err != nil {
+		return err
+	}
+	if lr.ObservedTimeUnixNano, err = getJSONUint64(v, "observedTimeUnixNano"); err != nil {
+		return err
+	}
+	severityNumber, err := getJSONUint64(v, "severityNumber")
+	if err != nil {
+		return err
+	}
+	lr.SeverityNumber = SeverityNumber(severityNumber)
+	if lr.SeverityText, err = getJSONString(v, "severityText"); err != nil {
+		return err
+	}
+	if bodyV := v.Get("body"); bodyV != nil {
+		lr.Body = &AnyValue{}
+		if err := lr.Body.unmarshalJSON(bodyV); err != nil {
+			return fmt.Errorf("cannot unmarshal `body`: %w", err)
+		}
+	}
+	if lr.Attributes, err = unmarshalJSONKeyValues(v.Get("attributes")); err != nil {
+		return fmt.Errorf("cannot unmarshal `attributes`: %w", err)
+	}
+	if lr.TraceID, err = getJSONHexString(v, "traceId"); err != nil {
+		return err
+	}
+	if lr.SpanID, err = getJSONHexString(v, "spanId"); err != nil {
+		return err
+	}
+	return nil
+}
+
+func unmarshalJSONKeyValues(v *fastjson.Value) ([]*KeyValue, error) {
+	if v == nil {
+		return nil, nil
+	}
+	a, err := v.Array()
+	if err != nil {
+		return nil, fmt.Errorf("key-value list must be JSON array; got %s", v.Type())
+	}
+	kvs := make([]*KeyValue, 0, len(a))
+	for _, kvV := range a {
+		key, err := getJSONString(kvV, "key")
+		if err != nil {
+			return nil, err
+		}
+		kv := &KeyValue{
+			Key: key,
+		}
+		if valueV := kvV.Get("value"); valueV != nil {
+			kv.Value = &AnyValue{}
+			if err := kv.Value.unmarshalJSON(valueV); err != nil {
+				return nil, fmt.Errorf("cannot unmarshal value for key %q: %w", key, err)
+			}
+		}
+		kvs = append(kvs, kv)
+	}
+	return kvs, nil
+}
+
+func (av *AnyValue) unmarshalJSON(v *fastjson.Value) error {
+	o, err := v.Object()
+	if err != nil {
+		return fmt.Errorf("AnyValue must be JSON object; got %s", v.Type())
+	}
+	var visitErr error
+	o.Visit(func(k []byte, v *fastjson.Value) {
+		if visitErr != nil {
+			return
+		}
+		switch string(k) {
+		case "stringValue":
+			b, err := v.StringBytes()
+			if err != nil {
+				visitErr = fmt.Errorf("`stringValue` must contain JSON string; got %s", v.Type())
+				return
+			}
+			s := string(b)
+			av.StringValue = &s
+		case "boolValue":
+			b, err := v.Bool()
+			if err != nil {
+				visitErr = fmt.Errorf("`boolValue` must contain JSON bool; got %s", v.Type())
+				return
+			}
+			av.BoolValue = &b
+		case "intValue":
+			n, err := parseJSONInt64(v)
+			if err != nil {
+				visitErr = fmt.Errorf("cannot parse `intValue`: %w", err)
+				return
+			}
+			av.IntValue = &n
+		case "doubleValue":
+			f, err := v.Float64()
+			if err != nil {
+				visitErr = fmt.Errorf("`doubleValue` must contain JSON number; got %s", v.Type())
+				return
+			}
+			av.DoubleValue = &f
+		case "arrayValue":
+			valuesV := v.Get("values")
+			av.ArrayValue = &ArrayValue{}
+			if valuesV == nil {
+				return
+			}
+			a, err := valuesV.Array()
+			if err != nil {
+				visitErr = fmt.Errorf("`arrayValue.values` must be JSON array; got %s", valuesV.Type())
+				return
+			}
+			for _, itemV := range a {
+				item := &AnyValue{}
+				if err := item.unmarshalJSON(itemV); err != nil {
+					visitErr = fmt.Errorf("cannot unmarshal `arrayValue` item: %w", err)
+					return
+				}
+				av.ArrayValue.Values = append(av.ArrayValue.Values, item)
+			}
+		case "kvlistValue":
+			kvs, err := unmarshalJSONKeyValues(v.Get("values"))
+			if err != nil {
+				visitErr = fmt.Errorf("cannot unmarshal `kvlistValue`: %w", err)
+				return
+			}
+			av.KeyValueList = &KeyValueList{
+				Values: kvs,
+			}
+		case "bytesValue":
+			b, err := v.StringBytes()
+			if err != nil {
+				visitErr = fmt.Errorf("`bytesValue` must contain JSON string; got %s", v.Type())
+				return
+			}
+			data, err := base64.StdEncoding.DecodeString(string(b))
+			if err != nil {
+				visitErr = fmt.Errorf("cannot base64-decode `bytesValue`: %w", err)
+				return
+			}
+			av.BytesValue = &data
+		}
+	})
+	return visitErr
+}
+
+func getJSONString(v *fastjson.Value, key string) (string, error) {
+	fv := v.Get(key)
+	if fv == nil {
+		return "", nil
+	}
+	b, err := fv.StringBytes()
+	if err != nil {
+		return "", fmt.Errorf("`%s` must contain JSON string; got %s", key, fv.Type())
+	}
+	return string(b), nil
+}
+
+// getJSONHexString returns hex-encoded string from the given key at v.
+//
+// OTLP JSON encoding represents trace_id and span_id as case-insensitive hex-encoded strings.
+func getJSONHexString(v *fastjson.Value, key string) (string, error) {
+	s, err := getJSONString(v, key)
+	if err != nil {
+		return "", err
+	}
+	if _, err := hex.DecodeString(s); err != nil {
+		return "", fmt.Errorf("`%s` must contain hex-encoded string; got %q", key, s)
+	}
+	return strings.ToLower(s), nil
+}
+
+// getJSONUint64 returns uint64 value for the given key at v.
+//
+// OTLP JSON encoding allows representing 64-bit integers either as JSON numbers or as JSON strings.
+func getJSONUint64(v *fastjson.Value, key string) (uint64, error) {
+	fv := v.Get(key)
+	if fv == nil {
+		return 0, nil
+	}
+	switch fv.Type() {
+	case fastjson.TypeString:
+		b, _ := fv.StringBytes()
+		n, err := strconv.ParseUint(string(b), 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("cannot parse `%s`: %w", key, err)
+		}
+		return n, nil
+	case fastjson.TypeNumber:
+		n, err := fv.Uint64()
+		if err != nil {
+			return 0, fmt.Errorf("cannot parse `%s`: %w", key, err)
+		}
+		return n, nil
+	default:
+		return 0, fmt.Errorf("`%s` must contain JSON number or string; got %s", key, fv.Type())
+	}
+}
+
+func parseJSONInt64(v *fastjson.Value) (int64, error) {
+	switch v.Type() {
+	case fastjson.TypeString:
+		b, _ := v.StringBytes()
+		return strconv.ParseInt(string(b), 10, 64)
+	case fastjson.TypeNumber:
+		return v.Int64()
+	default:
+		return 0, fmt.Errorf("unexpected JSON type %s; want number or string", v.Type())
+	}
+}
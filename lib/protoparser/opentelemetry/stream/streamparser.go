@@ -12,18 +12,19 @@ import (
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/decimal"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fasttime"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompb"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/protoparser/common"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/protoparser/opentelemetry/pb"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/writeconcurrencylimiter"
 )
 
-// ParseStream parses OpenTelemetry protobuf or json data from r and calls callback for the parsed rows.
+// ParseStream parses OpenTelemetry protobuf or json data from r and calls callback for the parsed rows and metric metadata.
 //
-// callback shouldn't hold tss items after returning.
+// callback shouldn't hold tss and mms items after returning.
 //
 // optional processBody can be used for pre-processing the read request body from r before parsing it in OpenTelemetry format.
-func ParseStream(r io.Reader, isGzipped bool, processBody func([]byte) ([]byte, error), callback func(tss []prompbmarshal.TimeSeries) error) error {
+func ParseStream(r io.Reader, isGzipped bool, processBody func([]byte) ([]byte, error), callback func(tss []prompbmarshal.TimeSeries, mms []prompbmarshal.MetricMetadata) error) error {
 	wcr := writeconcurrencylimiter.GetReader(r)
 	defer writeconcurrencylimiter.PutReader(wcr)
 	r = wcr
@@ -45,7 +46,7 @@ func ParseStream(r io.Reader, isGzipped bool, processBody func([]byte) ([]byte,
 	}
 	wr.parseRequestToTss(req)
 
-	if err := callback(wr.tss); err != nil {
+	if err := callback(wr.tss, wr.mms); err != nil {
 		return fmt.Errorf("error when processing OpenTelemetry samples: %w", err)
 	}
 
@@ -61,6 +62,7 @@ func (wr *writeContext) appendSamplesFromScopeMetrics(sc *pb.ScopeMetrics) {
 		metricName := sanitizeMetricName(m)
 		switch {
 		case m.Gauge != nil:
+			wr.appendMetadata(metricName, prompb.MetricTypeGauge, m)
 			for _, p := range m.Gauge.DataPoints {
 				wr.appendSampleFromNumericPoint(metricName, p)
 			}
@@ -69,10 +71,16 @@ func (wr *writeContext) appendSamplesFromScopeMetrics(sc *pb.ScopeMetrics) {
 				rowsDroppedUnsupportedSum.Inc()
 				continue
 			}
+			metricType := prompb.MetricTypeGauge
+			if m.Sum.IsMonotonic {
+				metricType = prompb.MetricTypeCounter
+			}
+			wr.appendMetadata(metricName, metricType, m)
 			for _, p := range m.Sum.DataPoints {
 				wr.appendSampleFromNumericPoint(metricName, p)
 			}
 		case m.Summary != nil:
+			wr.appendMetadata(metricName, prompb.MetricTypeSummary, m)
 			for _, p := range m.Summary.DataPoints {
 				wr.appendSamplesFromSummary(metricName, p)
 			}
@@ -81,6 +89,7 @@ func (wr *writeContext) appendSamplesFromScopeMetrics(sc *pb.ScopeMetrics) {
 				rowsDroppedUnsupportedHistogram.Inc()
 				continue
 			}
+			wr.appendMetadata(metricName, prompb.MetricTypeHistogram, m)
 			for _, p := range m.Histogram.DataPoints {
 				wr.appendSamplesFromHistogram(metricName, p)
 			}
@@ -91,6 +100,16 @@ func (wr *writeContext) appendSamplesFromScopeMetrics(sc *pb.ScopeMetrics) {
 	}
 }
 
+// appendMetadata appends metadata for m with the given metricName and metricType to wr.mms
+func (wr *writeContext) appendMetadata(metricName string, metricType prompb.MetricType, m *pb.Metric) {
+	wr.mms = append(wr.mms, prompbmarshal.MetricMetadata{
+		Type:             uint32(metricType),
+		MetricFamilyName: metricName,
+		Help:             m.Description,
+		Unit:             m.Unit,
+	})
+}
+
 // appendSampleFromNumericPoint appends p to wr.tss
 func (wr *writeContext) appendSampleFromNumericPoint(metricName string, p *pb.NumberDataPoint) {
 	var v float64
@@ -230,6 +249,9 @@ type writeContext struct {
 	// pointLabels are labels, which must be added to the ingested OpenTelemetry points
 	pointLabels []prompbmarshal.Label
 
+	// mms holds metric metadata for the parsed metrics
+	mms []prompbmarshal.MetricMetadata
+
 	// pools are used for reducing memory allocations when parsing time series
 	labelsPool  []prompbmarshal.Label
 	samplesPool []prompbmarshal.Sample
@@ -244,6 +266,9 @@ func (wr *writeContext) reset() {
 	wr.baseLabels = resetLabels(wr.baseLabels)
 	wr.pointLabels = resetLabels(wr.pointLabels)
 
+	clear(wr.mms)
+	wr.mms = wr.mms[:0]
+
 	wr.labelsPool = resetLabels(wr.labelsPool)
 	wr.samplesPool = wr.samplesPool[:0]
 }
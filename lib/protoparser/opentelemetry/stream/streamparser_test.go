@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fasttime"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompb"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/protoparser/opentelemetry/pb"
 )
@@ -195,8 +196,12 @@ func TestParseStream(t *testing.T) {
 }
 
 func checkParseStream(data []byte, checkSeries func(tss []prompbmarshal.TimeSeries) error) error {
+	callback := func(tss []prompbmarshal.TimeSeries, _ []prompbmarshal.MetricMetadata) error {
+		return checkSeries(tss)
+	}
+
 	// Verify parsing without compression
-	if err := ParseStream(bytes.NewBuffer(data), false, nil, checkSeries); err != nil {
+	if err := ParseStream(bytes.NewBuffer(data), false, nil, callback); err != nil {
 		return fmt.Errorf("error when parsing data: %w", err)
 	}
 
@@ -209,13 +214,69 @@ func checkParseStream(data []byte, checkSeries func(tss []prompbmarshal.TimeSeri
 	if err := zw.Close(); err != nil {
 		return fmt.Errorf("cannot close gzip writer: %w", err)
 	}
-	if err := ParseStream(&bb, true, nil, checkSeries); err != nil {
+	if err := ParseStream(&bb, true, nil, callback); err != nil {
 		return fmt.Errorf("error when parsing compressed data: %w", err)
 	}
 
 	return nil
 }
 
+func TestParseStreamMetadata(t *testing.T) {
+	gauge := generateGauge("my-gauge", "")
+	gauge.Description = "gauge description"
+	sum := generateSum("my-counter", "s", true)
+	sum.Description = "counter description"
+	nonMonotonicSum := generateSum("my-sum", "", false)
+	histogram := generateHistogram("my-histogram", "")
+	summary := generateSummary("my-summary", "")
+	deltaSum := generateSum("my-delta-sum", "", true)
+	deltaSum.Sum.AggregationTemporality = pb.AggregationTemporalityDelta
+
+	req := &pb.ExportMetricsServiceRequest{
+		ResourceMetrics: []*pb.ResourceMetrics{
+			generateOTLPSamples([]*pb.Metric{gauge, sum, nonMonotonicSum, histogram, summary, deltaSum}),
+		},
+	}
+	data := req.MarshalProtobuf(nil)
+
+	var mms []prompbmarshal.MetricMetadata
+	err := ParseStream(bytes.NewBuffer(data), false, nil, func(_ []prompbmarshal.TimeSeries, mmsParsed []prompbmarshal.MetricMetadata) error {
+		mms = append(mms, mmsParsed...)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("cannot parse protobuf: %s", err)
+	}
+	mmsExpected := []prompbmarshal.MetricMetadata{
+		{
+			Type:             uint32(prompb.MetricTypeGauge),
+			MetricFamilyName: "my-gauge",
+			Help:             "gauge description",
+		},
+		{
+			Type:             uint32(prompb.MetricTypeCounter),
+			MetricFamilyName: "my-counter",
+			Help:             "counter description",
+			Unit:             "s",
+		},
+		{
+			Type:             uint32(prompb.MetricTypeGauge),
+			MetricFamilyName: "my-sum",
+		},
+		{
+			Type:             uint32(prompb.MetricTypeHistogram),
+			MetricFamilyName: "my-histogram",
+		},
+		{
+			Type:             uint32(prompb.MetricTypeSummary),
+			MetricFamilyName: "my-summary",
+		},
+	}
+	if !reflect.DeepEqual(mms, mmsExpected) {
+		t.Fatalf("unexpected metadata\ngot\n%+v\nwant\n%+v", mms, mmsExpected)
+	}
+}
+
 func attributesFromKV(k, v string) []*pb.KeyValue {
 	return []*pb.KeyValue{
 		{
@@ -16,6 +16,9 @@ import (
 type Rows struct {
 	Rows []Row
 
+	// Metadata contains metric metadata obtained from `# HELP`, `# TYPE` and `# UNIT` comments.
+	Metadata []Metadata
+
 	tagsPool []Tag
 }
 
@@ -28,6 +31,9 @@ func (rs *Rows) Reset() {
 	}
 	rs.Rows = rs.Rows[:0]
 
+	clear(rs.Metadata)
+	rs.Metadata = rs.Metadata[:0]
+
 	for i := range rs.tagsPool {
 		rs.tagsPool[i].reset()
 	}
@@ -54,7 +60,7 @@ func stdErrLogger(s string) {
 // s shouldn't be modified while rs is in use.
 func (rs *Rows) UnmarshalWithErrLogger(s string, errLogger func(s string)) {
 	noEscapes := strings.IndexByte(s, '\\') < 0
-	rs.Rows, rs.tagsPool = unmarshalRows(rs.Rows[:0], s, rs.tagsPool[:0], noEscapes, errLogger)
+	rs.Rows, rs.tagsPool, rs.Metadata = unmarshalRows(rs.Rows[:0], s, rs.tagsPool[:0], rs.Metadata[:0], noEscapes, errLogger)
 }
 
 const tagsPrefix = '{'
@@ -86,6 +92,23 @@ type Row struct {
 	Exemplar  Exemplar
 }
 
+// Metadata is metric metadata obtained from `# HELP`, `# TYPE` and `# UNIT` comments.
+//
+// See https://github.com/OpenObservability/OpenMetrics/blob/main/specification/OpenMetrics.md#metricfamily
+type Metadata struct {
+	// Metric is the metric family name the metadata belongs to.
+	Metric string
+
+	// Type is the metric type from `# TYPE` comment such as `counter` or `gauge`.
+	Type string
+
+	// Help is the unescaped help text from `# HELP` comment.
+	Help string
+
+	// Unit is the metric unit from `# UNIT` comment.
+	Unit string
+}
+
 func (r *Row) reset() {
 	r.Metric = ""
 	r.Tags = nil
@@ -341,20 +364,107 @@ func (r *Row) unmarshal(s string, tagsPool []Tag, noEscapes bool) ([]Tag, error)
 
 var rowsReadScrape = metrics.NewCounter(`vm_protoparser_rows_read_total{type="promscrape"}`)
 
-func unmarshalRows(dst []Row, s string, tagsPool []Tag, noEscapes bool, errLogger func(s string)) ([]Row, []Tag) {
+func unmarshalRows(dst []Row, s string, tagsPool []Tag, mds []Metadata, noEscapes bool, errLogger func(s string)) ([]Row, []Tag, []Metadata) {
 	dstLen := len(dst)
 	for len(s) > 0 {
 		n := strings.IndexByte(s, '\n')
 		if n < 0 {
 			// The last line.
+			mds = unmarshalMetadata(mds, s)
 			dst, tagsPool = unmarshalRow(dst, s, tagsPool, noEscapes, errLogger)
 			break
 		}
+		mds = unmarshalMetadata(mds, s[:n])
 		dst, tagsPool = unmarshalRow(dst, s[:n], tagsPool, noEscapes, errLogger)
 		s = s[n+1:]
 	}
 	rowsReadScrape.Add(len(dst) - dstLen)
-	return dst, tagsPool
+	return dst, tagsPool, mds
+}
+
+// unmarshalMetadata appends metadata from `# HELP`, `# TYPE` and `# UNIT` comment at s to dst and returns the result.
+//
+// dst is returned unchanged if s doesn't contain such a comment.
+// Consecutive comments for the same metric are merged into a single Metadata entry.
+func unmarshalMetadata(dst []Metadata, s string) []Metadata {
+	if len(s) > 0 && s[len(s)-1] == '\r' {
+		s = s[:len(s)-1]
+	}
+	s = skipLeadingWhitespace(s)
+	if len(s) == 0 || s[0] != '#' {
+		return dst
+	}
+	s = skipLeadingWhitespace(s[1:])
+	n := nextWhitespace(s)
+	if n < 0 {
+		return dst
+	}
+	kind := s[:n]
+	if kind != "HELP" && kind != "TYPE" && kind != "UNIT" {
+		// Regular comment
+		return dst
+	}
+	s = skipLeadingWhitespace(s[n+1:])
+	metric := s
+	value := ""
+	if n := nextWhitespace(s); n >= 0 {
+		metric = s[:n]
+		value = skipLeadingWhitespace(s[n+1:])
+	}
+	if len(metric) == 0 {
+		return dst
+	}
+
+	if len(dst) == 0 || dst[len(dst)-1].Metric != metric {
+		dst = append(dst, Metadata{
+			Metric: metric,
+		})
+	}
+	md := &dst[len(dst)-1]
+	switch kind {
+	case "HELP":
+		md.Help = unescapeHelp(value)
+	case "TYPE":
+		md.Type = skipTrailingWhitespace(value)
+	case "UNIT":
+		md.Unit = skipTrailingWhitespace(value)
+	}
+	return dst
+}
+
+// unescapeHelp unescapes `\\` and `\n` sequences in help text from `# HELP` comment.
+//
+// See https://github.com/prometheus/docs/blob/master/content/docs/instrumenting/exposition_formats.md#comments-help-text-and-type-information
+func unescapeHelp(s string) string {
+	n := strings.IndexByte(s, '\\')
+	if n < 0 {
+		// Fast path - nothing to unescape
+		return s
+	}
+	b := make([]byte, 0, len(s))
+	for {
+		b = append(b, s[:n]...)
+		s = s[n+1:]
+		if len(s) == 0 {
+			b = append(b, '\\')
+			break
+		}
+		switch s[0] {
+		case 'n':
+			b = append(b, '\n')
+		case '\\':
+			b = append(b, '\\')
+		default:
+			b = append(b, '\\', s[0])
+		}
+		s = s[1:]
+		n = strings.IndexByte(s, '\\')
+		if n < 0 {
+			b = append(b, s...)
+			break
+		}
+	}
+	return string(b)
 }
 
 func unmarshalRow(dst []Row, s string, tagsPool []Tag, noEscapes bool, errLogger func(s string)) ([]Row, []Tag) {
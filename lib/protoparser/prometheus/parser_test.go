@@ -164,6 +164,24 @@ func TestUnescapeValue(t *testing.T) {
 	f(`foo\`, "foo\\")
 }
 
+func TestUnescapeHelp(t *testing.T) {
+	f := func(s, resultExpected string) {
+		t.Helper()
+		result := unescapeHelp(s)
+		if result != resultExpected {
+			t.Fatalf("unexpected result; got %q; want %q", result, resultExpected)
+		}
+	}
+	f(``, "")
+	f(`foobar`, "foobar")
+	f(`foo\\bar\nbaz`, "foo\\bar\nbaz")
+	f(`"quoted"`, `"quoted"`)
+
+	// Edge cases
+	f(`foo\bar`, "foo\\bar")
+	f(`foo\`, "foo\\")
+}
+
 func TestAppendEscapedValue(t *testing.T) {
 	f := func(s, resultExpected string) {
 		t.Helper()
@@ -243,6 +261,62 @@ func TestRowsUnmarshalFailure(t *testing.T) {
 	f("foo 123 bar")
 }
 
+func TestRowsUnmarshalMetadata(t *testing.T) {
+	f := func(s string, mdsExpected []Metadata) {
+		t.Helper()
+		var rows Rows
+		rows.Unmarshal(s)
+		if !reflect.DeepEqual(rows.Metadata, mdsExpected) {
+			t.Fatalf("unexpected metadata;\ngot\n%+v;\nwant\n%+v", rows.Metadata, mdsExpected)
+		}
+
+		// Try unmarshaling again
+		rows.Unmarshal(s)
+		if !reflect.DeepEqual(rows.Metadata, mdsExpected) {
+			t.Fatalf("unexpected metadata;\ngot\n%+v;\nwant\n%+v", rows.Metadata, mdsExpected)
+		}
+
+		rows.Reset()
+		if len(rows.Metadata) != 0 {
+			t.Fatalf("non-empty metadata after reset: %+v", rows.Metadata)
+		}
+	}
+
+	// No metadata
+	f("", nil)
+	f("foo 1", nil)
+	f("# foo bar\n#HELP\n# TYPE\n# EOF", nil)
+
+	// Metadata for a single metric
+	f(`# HELP foo_seconds Total time spent in foo.
+# TYPE foo_seconds counter
+# UNIT foo_seconds seconds
+foo_seconds_total 123`, []Metadata{
+		{
+			Metric: "foo_seconds",
+			Type:   "counter",
+			Help:   "Total time spent in foo.",
+			Unit:   "seconds",
+		},
+	})
+
+	// Metadata for multiple metrics with escaped help, whitespace and CRLF line endings
+	f("\t#  TYPE   foo  gauge \r\n# HELP foo multi\\nline \\\\ help\r\nfoo 1\n#TYPE bar untyped\nbar 2\n# HELP baz\nbaz 3", []Metadata{
+		{
+			Metric: "foo",
+			Type:   "gauge",
+			Help:   "multi\nline \\ help",
+		},
+		{
+			Metric: "bar",
+			Type:   "untyped",
+		},
+		{
+			Metric: "baz",
+		},
+	})
+}
+
 func TestRowsUnmarshalSuccess(t *testing.T) {
 	f := func(s string, rowsExpected *Rows) {
 		t.Helper()
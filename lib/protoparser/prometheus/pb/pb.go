@@ -0,0 +1,658 @@
+package pb
+
+import (
+	"fmt"
+
+	"github.com/VictoriaMetrics/easyproto"
+)
+
+// MetricType represents the corresponding Prometheus protobuf enum
+type MetricType int32
+
+// Metric types supported by Prometheus protobuf format
+const (
+	MetricTypeCounter        MetricType = 0
+	MetricTypeGauge          MetricType = 1
+	MetricTypeSummary        MetricType = 2
+	MetricTypeUntyped        MetricType = 3
+	MetricTypeHistogram      MetricType = 4
+	MetricTypeGaugeHistogram MetricType = 5
+)
+
+// MetricFamily represents the corresponding Prometheus protobuf message
+type MetricFamily struct {
+	Name    string
+	Help    string
+	Type    MetricType
+	Metrics []*Metric
+	Unit    string
+}
+
+// UnmarshalProtobuf unmarshals mf from protobuf message at src.
+//
+// mf refers to src, so src mustn't change while mf is in use.
+func (mf *MetricFamily) UnmarshalProtobuf(src []byte) error {
+	*mf = MetricFamily{}
+	return mf.unmarshalProtobuf(src)
+}
+
+// MarshalProtobuf marshals mf to protobuf message, appends it to dst and returns the result.
+func (mf *MetricFamily) MarshalProtobuf(dst []byte) []byte {
+	m := mp.Get()
+	mf.marshalProtobuf(m.MessageMarshaler())
+	dst = m.Marshal(dst)
+	mp.Put(m)
+	return dst
+}
+
+var mp easyproto.MarshalerPool
+
+func (mf *MetricFamily) marshalProtobuf(mm *easyproto.MessageMarshaler) {
+	mm.AppendString(1, mf.Name)
+	mm.AppendString(2, mf.Help)
+	mm.AppendInt32(3, int32(mf.Type))
+	for _, m := range mf.Metrics {
+		m.marshalProtobuf(mm.AppendMessage(4))
+	}
+	mm.AppendString(5, mf.Unit)
+}
+
+func (mf *MetricFamily) unmarshalProtobuf(src []byte) (err error) {
+	// message MetricFamily {
+	//   string name = 1;
+	//   string help = 2;
+	//   MetricType type = 3;
+	//   repeated Metric metric = 4;
+	//   string unit = 5;
+	// }
+	var fc easyproto.FieldContext
+	for len(src) > 0 {
+		src, err = fc.NextField(src)
+		if err != nil {
+			return fmt.Errorf("cannot read next field in MetricFamily: %w", err)
+		}
+		switch fc.FieldNum {
+		case 1:
+			name, ok := fc.String()
+			if !ok {
+				return fmt.Errorf("cannot read metric family name")
+			}
+			mf.Name = name
+		case 2:
+			help, ok := fc.String()
+			if !ok {
+				return fmt.Errorf("cannot read metric family help")
+			}
+			mf.Help = help
+		case 3:
+			typ, ok := fc.Int32()
+			if !ok {
+				return fmt.Errorf("cannot read metric family type")
+			}
+			mf.Type = MetricType(typ)
+		case 4:
+			data, ok := fc.MessageData()
+			if !ok {
+				return fmt.Errorf("cannot read Metric data")
+			}
+			mf.Metrics = append(mf.Metrics, &Metric{})
+			m := mf.Metrics[len(mf.Metrics)-1]
+			if err := m.unmarshalProtobuf(data); err != nil {
+				return fmt.Errorf("cannot unmarshal Metric: %w", err)
+			}
+		case 5:
+			unit, ok := fc.String()
+			if !ok {
+				return fmt.Errorf("cannot read metric family unit")
+			}
+			mf.Unit = unit
+		}
+	}
+	return nil
+}
+
+// Metric represents the corresponding Prometheus protobuf message
+type Metric struct {
+	Labels      []*LabelPair
+	Gauge       *Gauge
+	Counter     *Counter
+	Summary     *Summary
+	Untyped     *Untyped
+	Histogram   *Histogram
+	TimestampMs int64
+}
+
+func (m *Metric) marshalProtobuf(mm *easyproto.MessageMarshaler) {
+	for _, lp := range m.Labels {
+		lp.marshalProtobuf(mm.AppendMessage(1))
+	}
+	switch {
+	case m.Gauge != nil:
+		m.Gauge.marshalProtobuf(mm.AppendMessage(2))
+	case m.Counter != nil:
+		m.Counter.marshalProtobuf(mm.AppendMessage(3))
+	case m.Summary != nil:
+		m.Summary.marshalProtobuf(mm.AppendMessage(4))
+	case m.Untyped != nil:
+		m.Untyped.marshalProtobuf(mm.AppendMessage(5))
+	case m.Histogram != nil:
+		m.Histogram.marshalProtobuf(mm.AppendMessage(7))
+	}
+	mm.AppendInt64(6, m.TimestampMs)
+}
+
+func (m *Metric) unmarshalProtobuf(src []byte) (err error) {
+	// message Metric {
+	//   repeated LabelPair label = 1;
+	//   Gauge gauge = 2;
+	//   Counter counter = 3;
+	//   Summary summary = 4;
+	//   Untyped untyped = 5;
+	//   Histogram histogram = 7;
+	//   int64 timestamp_ms = 6;
+	// }
+	var fc easyproto.FieldContext
+	for len(src) > 0 {
+		src, err = fc.NextField(src)
+		if err != nil {
+			return fmt.Errorf("cannot read next field in Metric: %w", err)
+		}
+		switch fc.FieldNum {
+		case 1:
+			data, ok := fc.MessageData()
+			if !ok {
+				return fmt.Errorf("cannot read LabelPair data")
+			}
+			m.Labels = append(m.Labels, &LabelPair{})
+			lp := m.Labels[len(m.Labels)-1]
+			if err := lp.unmarshalProtobuf(data); err != nil {
+				return fmt.Errorf("cannot unmarshal LabelPair: %w", err)
+			}
+		case 2:
+			data, ok := fc.MessageData()
+			if !ok {
+				return fmt.Errorf("cannot read Gauge data")
+			}
+			m.Gauge = &Gauge{}
+			if err := m.Gauge.unmarshalProtobuf(data); err != nil {
+				return fmt.Errorf("cannot unmarshal Gauge: %w", err)
+			}
+		case 3:
+			data, ok := fc.MessageData()
+			if !ok {
+				return fmt.Errorf("cannot read Counter data")
+			}
+			m.Counter = &Counter{}
+			if err := m.Counter.unmarshalProtobuf(data); err != nil {
+				return fmt.Errorf("cannot unmarshal Counter: %w", err)
+			}
+		case 4:
+			data, ok := fc.MessageData()
+			if !ok {
+				return fmt.Errorf("cannot read Summary data")
+			}
+			m.Summary = &Summary{}
+			if err := m.Summary.unmarshalProtobuf(data); err != nil {
+				return fmt.Errorf("cannot unmarshal Summary: %w", err)
+			}
+		case 5:
+			data, ok := fc.MessageData()
+			if !ok {
+				return fmt.Errorf("cannot read Untyped data")
+			}
+			m.Untyped = &Untyped{}
+			if err := m.Untyped.unmarshalProtobuf(data); err != nil {
+				return fmt.Errorf("cannot unmarshal Untyped: %w", err)
+			}
+		case 6:
+			timestampMs, ok := fc.Int64()
+			if !ok {
+				return fmt.Errorf("cannot read timestamp_ms")
+			}
+			m.TimestampMs = timestampMs
+		case 7:
+			data, ok := fc.MessageData()
+			if !ok {
+				return fmt.Errorf("cannot read Histogram data")
+			}
+			m.Histogram = &Histogram{}
+			if err := m.Histogram.unmarshalProtobuf(data); err != nil {
+				return fmt.Errorf("cannot unmarshal Histogram: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// LabelPair represents the corresponding Prometheus protobuf message
+type LabelPair struct {
+	Name  string
+	Value string
+}
+
+func (lp *LabelPair) marshalProtobuf(mm *easyproto.MessageMarshaler) {
+	mm.AppendString(1, lp.Name)
+	mm.AppendString(2, lp.Value)
+}
+
+func (lp *LabelPair) unmarshalProtobuf(src []byte) (err error) {
+	// message LabelPair {
+	//   string name = 1;
+	//   string value = 2;
+	// }
+	var fc easyproto.FieldContext
+	for len(src) > 0 {
+		src, err = fc.NextField(src)
+		if err != nil {
+			return fmt.Errorf("cannot read next field in LabelPair: %w", err)
+		}
+		switch fc.FieldNum {
+		case 1:
+			name, ok := fc.String()
+			if !ok {
+				return fmt.Errorf("cannot read label name")
+			}
+			lp.Name = name
+		case 2:
+			value, ok := fc.String()
+			if !ok {
+				return fmt.Errorf("cannot read label value")
+			}
+			lp.Value = value
+		}
+	}
+	return nil
+}
+
+// Gauge represents the corresponding Prometheus protobuf message
+type Gauge struct {
+	Value float64
+}
+
+func (g *Gauge) marshalProtobuf(mm *easyproto.MessageMarshaler) {
+	mm.AppendDouble(1, g.Value)
+}
+
+func (g *Gauge) unmarshalProtobuf(src []byte) (err error) {
+	// message Gauge {
+	//   double value = 1;
+	// }
+	g.Value, err = unmarshalValue(src, "Gauge")
+	return err
+}
+
+// Counter represents the corresponding Prometheus protobuf message
+type Counter struct {
+	Value float64
+}
+
+func (c *Counter) marshalProtobuf(mm *easyproto.MessageMarshaler) {
+	mm.AppendDouble(1, c.Value)
+}
+
+func (c *Counter) unmarshalProtobuf(src []byte) (err error) {
+	// message Counter {
+	//   double value = 1;
+	// }
+	c.Value, err = unmarshalValue(src, "Counter")
+	return err
+}
+
+// Untyped represents the corresponding Prometheus protobuf message
+type Untyped struct {
+	Value float64
+}
+
+func (u *Untyped) marshalProtobuf(mm *easyproto.MessageMarshaler) {
+	mm.AppendDouble(1, u.Value)
+}
+
+func (u *Untyped) unmarshalProtobuf(src []byte) (err error) {
+	// message Untyped {
+	//   double value = 1;
+	// }
+	u.Value, err = unmarshalValue(src, "Untyped")
+	return err
+}
+
+// unmarshalValue unmarshals `double value = 1` field from src for the message with the given name.
+func unmarshalValue(src []byte, name string) (v float64, err error) {
+	var fc easyproto.FieldContext
+	for len(src) > 0 {
+		src, err = fc.NextField(src)
+		if err != nil {
+			return 0, fmt.Errorf("cannot read next field in %s: %w", name, err)
+		}
+		if fc.FieldNum == 1 {
+			value, ok := fc.Double()
+			if !ok {
+				return 0, fmt.Errorf("cannot read %s value", name)
+			}
+			v = value
+		}
+	}
+	return v, nil
+}
+
+// Summary represents the corresponding Prometheus protobuf message
+type Summary struct {
+	SampleCount uint64
+	SampleSum   float64
+	Quantiles   []*Quantile
+}
+
+func (s *Summary) marshalProtobuf(mm *easyproto.MessageMarshaler) {
+	mm.AppendUint64(1, s.SampleCount)
+	mm.AppendDouble(2, s.SampleSum)
+	for _, q := range s.Quantiles {
+		q.marshalProtobuf(mm.AppendMessage(3))
+	}
+}
+
+func (s *Summary) unmarshalProtobuf(src []byte) (err error) {
+	// message Summary {
+	//   uint64 sample_count = 1;
+	//   double sample_sum = 2;
+	//   repeated Quantile quantile = 3;
+	// }
+	var fc easyproto.FieldContext
+	for len(src) > 0 {
+		src, err = fc.NextField(src)
+		if err != nil {
+			return fmt.Errorf("cannot read next field in Summary: %w", err)
+		}
+		switch fc.FieldNum {
+		case 1:
+			sampleCount, ok := fc.Uint64()
+			if !ok {
+				return fmt.Errorf("cannot read sample_count")
+			}
+			s.SampleCount = sampleCount
+		case 2:
+			sampleSum, ok := fc.Double()
+			if !ok {
+				return fmt.Errorf("cannot read sample_sum")
+			}
+			s.SampleSum = sampleSum
+		case 3:
+			data, ok := fc.MessageData()
+			if !ok {
+				return fmt.Errorf("cannot read Quantile data")
+			}
+			s.Quantiles = append(s.Quantiles, &Quantile{})
+			q := s.Quantiles[len(s.Quantiles)-1]
+			if err := q.unmarshalProtobuf(data); err != nil {
+				return fmt.Errorf("cannot unmarshal Quantile: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// Quantile represents the corresponding Prometheus protobuf message
+type Quantile struct {
+	Quantile float64
+	Value    float64
+}
+
+func (q *Quantile) marshalProtobuf(mm *easyproto.MessageMarshaler) {
+	mm.AppendDouble(1, q.Quantile)
+	mm.AppendDouble(2, q.Value)
+}
+
+func (q *Quantile) unmarshalProtobuf(src []byte) (err error) {
+	// message Quantile {
+	//   double quantile = 1;
+	//   double value = 2;
+	// }
+	var fc easyproto.FieldContext
+	for len(src) > 0 {
+		src, err = fc.NextField(src)
+		if err != nil {
+			return fmt.Errorf("cannot read next field in Quantile: %w", err)
+		}
+		switch fc.FieldNum {
+		case 1:
+			quantile, ok := fc.Double()
+			if !ok {
+				return fmt.Errorf("cannot read quantile")
+			}
+			q.Quantile = quantile
+		case 2:
+			value, ok := fc.Double()
+			if !ok {
+				return fmt.Errorf("cannot read quantile value")
+			}
+			q.Value = value
+		}
+	}
+	return nil
+}
+
+// Histogram represents the corresponding Prometheus protobuf message
+//
+// It may contain either classic buckets or native histogram buckets.
+type Histogram struct {
+	SampleCount      uint64
+	SampleCountFloat float64
+	SampleSum        float64
+
+	// Buckets contains classic histogram buckets
+	Buckets []*Bucket
+
+	// The fields below are used by native histograms
+	Schema         int32
+	ZeroThreshold  float64
+	ZeroCount      uint64
+	ZeroCountFloat float64
+	NegativeSpans  []*BucketSpan
+	NegativeDeltas []int64
+	NegativeCounts []float64
+	PositiveSpans  []*BucketSpan
+	PositiveDeltas []int64
+	PositiveCounts []float64
+}
+
+func (h *Histogram) marshalProtobuf(mm *easyproto.MessageMarshaler) {
+	mm.AppendUint64(1, h.SampleCount)
+	mm.AppendDouble(2, h.SampleSum)
+	for _, b := range h.Buckets {
+		b.marshalProtobuf(mm.AppendMessage(3))
+	}
+	mm.AppendDouble(4, h.SampleCountFloat)
+	mm.AppendSint32(5, h.Schema)
+	mm.AppendDouble(6, h.ZeroThreshold)
+	mm.AppendUint64(7, h.ZeroCount)
+	mm.AppendDouble(8, h.ZeroCountFloat)
+	for _, span := range h.NegativeSpans {
+		span.marshalProtobuf(mm.AppendMessage(9))
+	}
+	mm.AppendSint64s(10, h.NegativeDeltas)
+	mm.AppendDoubles(11, h.NegativeCounts)
+	for _, span := range h.PositiveSpans {
+		span.marshalProtobuf(mm.AppendMessage(12))
+	}
+	mm.AppendSint64s(13, h.PositiveDeltas)
+	mm.AppendDoubles(14, h.PositiveCounts)
+}
+
+func (h *Histogram) unmarshalProtobuf(src []byte) (err error) {
+	// message Histogram {
+	//   uint64 sample_count = 1;
+	//   double sample_count_float = 4;
+	//   double sample_sum = 2;
+	//   repeated Bucket bucket = 3;
+	//   sint32 schema = 5;
+	//   double zero_threshold = 6;
+	//   uint64 zero_count = 7;
+	//   double zero_count_float = 8;
+	//   repeated BucketSpan negative_span = 9;
+	//   repeated sint64 negative_delta = 10;
+	//   repeated double negative_count = 11;
+	//   repeated BucketSpan positive_span = 12;
+	//   repeated sint64 positive_delta = 13;
+	//   repeated double positive_count = 14;
+	// }
+	var fc easyproto.FieldContext
+	for len(src) > 0 {
+		src, err = fc.NextField(src)
+		if err != nil {
+			return fmt.Errorf("cannot read next field in Histogram: %w", err)
+		}
+		var ok bool
+		switch fc.FieldNum {
+		case 1:
+			h.SampleCount, ok = fc.Uint64()
+		case 2:
+			h.SampleSum, ok = fc.Double()
+		case 3:
+			data, ok := fc.MessageData()
+			if !ok {
+				return fmt.Errorf("cannot read Bucket data")
+			}
+			h.Buckets = append(h.Buckets, &Bucket{})
+			b := h.Buckets[len(h.Buckets)-1]
+			if err := b.unmarshalProtobuf(data); err != nil {
+				return fmt.Errorf("cannot unmarshal Bucket: %w", err)
+			}
+			continue
+		case 4:
+			h.SampleCountFloat, ok = fc.Double()
+		case 5:
+			h.Schema, ok = fc.Sint32()
+		case 6:
+			h.ZeroThreshold, ok = fc.Double()
+		case 7:
+			h.ZeroCount, ok = fc.Uint64()
+		case 8:
+			h.ZeroCountFloat, ok = fc.Double()
+		case 9:
+			h.NegativeSpans, err = appendBucketSpan(h.NegativeSpans, &fc)
+			if err != nil {
+				return err
+			}
+			continue
+		case 10:
+			h.NegativeDeltas, ok = fc.UnpackSint64s(h.NegativeDeltas)
+		case 11:
+			h.NegativeCounts, ok = fc.UnpackDoubles(h.NegativeCounts)
+		case 12:
+			h.PositiveSpans, err = appendBucketSpan(h.PositiveSpans, &fc)
+			if err != nil {
+				return err
+			}
+			continue
+		case 13:
+			h.PositiveDeltas, ok = fc.UnpackSint64s(h.PositiveDeltas)
+		case 14:
+			h.PositiveCounts, ok = fc.UnpackDoubles(h.PositiveCounts)
+		default:
+			continue
+		}
+		if !ok {
+			return fmt.Errorf("cannot read Histogram field #%d", fc.FieldNum)
+		}
+	}
+	return nil
+}
+
+// Bucket represents the corresponding Prometheus protobuf message
+type Bucket struct {
+	CumulativeCount      uint64
+	CumulativeCountFloat float64
+	UpperBound           float64
+}
+
+func (b *Bucket) marshalProtobuf(mm *easyproto.MessageMarshaler) {
+	mm.AppendUint64(1, b.CumulativeCount)
+	mm.AppendDouble(2, b.UpperBound)
+	mm.AppendDouble(4, b.CumulativeCountFloat)
+}
+
+func (b *Bucket) unmarshalProtobuf(src []byte) (err error) {
+	// message Bucket {
+	//   uint64 cumulative_count = 1;
+	//   double cumulative_count_float = 4;
+	//   double upper_bound = 2;
+	// }
+	var fc easyproto.FieldContext
+	for len(src) > 0 {
+		src, err = fc.NextField(src)
+		if err != nil {
+			return fmt.Errorf("cannot read next field in Bucket: %w", err)
+		}
+		switch fc.FieldNum {
+		case 1:
+			cumulativeCount, ok := fc.Uint64()
+			if !ok {
+				return fmt.Errorf("cannot read cumulative_count")
+			}
+			b.CumulativeCount = cumulativeCount
+		case 2:
+			upperBound, ok := fc.Double()
+			if !ok {
+				return fmt.Errorf("cannot read upper_bound")
+			}
+			b.UpperBound = upperBound
+		case 4:
+			cumulativeCountFloat, ok := fc.Double()
+			if !ok {
+				return fmt.Errorf("cannot read cumulative_count_float")
+			}
+			b.CumulativeCountFloat = cumulativeCountFloat
+		}
+	}
+	return nil
+}
+
+// BucketSpan represents the corresponding Prometheus protobuf message
+type BucketSpan struct {
+	Offset int32
+	Length uint32
+}
+
+func (span *BucketSpan) marshalProtobuf(mm *easyproto.MessageMarshaler) {
+	mm.AppendSint32(1, span.Offset)
+	mm.AppendUint32(2, span.Length)
+}
+
+func appendBucketSpan(dst []*BucketSpan, fc *easyproto.FieldContext) ([]*BucketSpan, error) {
+	data, ok := fc.MessageData()
+	if !ok {
+		return dst, fmt.Errorf("cannot read BucketSpan data")
+	}
+	span := &BucketSpan{}
+	if err := span.unmarshalProtobuf(data); err != nil {
+		return dst, fmt.Errorf("cannot unmarshal BucketSpan: %w", err)
+	}
+	return append(dst, span), nil
+}
+
+func (span *BucketSpan) unmarshalProtobuf(src []byte) (err error) {
+	// message BucketSpan {
+	//   sint32 offset = 1;
+	//   uint32 length = 2;
+	// }
+	var fc easyproto.FieldContext
+	for len(src) > 0 {
+		src, err = fc.NextField(src)
+		if err != nil {
+			return fmt.Errorf("cannot read next field in BucketSpan: %w", err)
+		}
+		switch fc.FieldNum {
+		case 1:
+			offset, ok := fc.Sint32()
+			if !ok {
+				return fmt.Errorf("cannot read span offset")
+			}
+			span.Offset = offset
+		case 2:
+			length, ok := fc.Uint32()
+			if !ok {
+				return fmt.Errorf("cannot read span length")
+			}
+			span.Length = length
+		}
+	}
+	return nil
+}
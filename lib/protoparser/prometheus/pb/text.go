@@ -0,0 +1,263 @@
+package pb
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompb"
+)
+
+// ContentType is the content type for Prometheus protobuf exposition format.
+//
+// See https://github.com/prometheus/docs/blob/main/content/docs/instrumenting/exposition_formats.md#protobuf-format
+const ContentType = "application/vnd.google.protobuf; proto=io.prometheus.client.MetricFamily; encoding=delimited"
+
+// IsProtobufContentType returns true if contentType corresponds to Prometheus protobuf exposition format.
+func IsProtobufContentType(contentType string) bool {
+	return strings.HasPrefix(contentType, "application/vnd.google.protobuf") &&
+		strings.Contains(contentType, "io.prometheus.client.MetricFamily")
+}
+
+// AppendText appends MetricFamily messages from src in Prometheus text exposition format to dst and returns the result.
+//
+// src must contain length-delimited MetricFamily messages as returned by scrape targets for ContentType.
+//
+// Native histograms are converted to VictoriaMetrics histograms with `vmrange` buckets,
+// so they could be queried with histogram_quantile() and other histogram functions.
+// See https://valyala.medium.com/improving-histogram-usability-for-prometheus-and-grafana-bc7e5df0e350
+func AppendText(dst, src []byte) ([]byte, error) {
+	var mf MetricFamily
+	for len(src) > 0 {
+		msgLen, n := binary.Uvarint(src)
+		if n <= 0 {
+			return dst, fmt.Errorf("cannot read MetricFamily message length")
+		}
+		src = src[n:]
+		if uint64(len(src)) < msgLen {
+			return dst, fmt.Errorf("unexpected end of data when reading MetricFamily message; got %d bytes; want %d bytes", len(src), msgLen)
+		}
+		if err := mf.UnmarshalProtobuf(src[:msgLen]); err != nil {
+			return dst, fmt.Errorf("cannot unmarshal MetricFamily: %w", err)
+		}
+		src = src[msgLen:]
+		dst = mf.appendText(dst)
+	}
+	return dst, nil
+}
+
+func (mf *MetricFamily) appendText(dst []byte) []byte {
+	if mf.Name == "" {
+		return dst
+	}
+	if mf.Help != "" {
+		dst = append(dst, "# HELP "...)
+		dst = append(dst, mf.Name...)
+		dst = append(dst, ' ')
+		dst = appendEscapedHelp(dst, mf.Help)
+		dst = append(dst, '\n')
+	}
+	dst = append(dst, "# TYPE "...)
+	dst = append(dst, mf.Name...)
+	dst = append(dst, ' ')
+	dst = append(dst, mf.Type.String()...)
+	dst = append(dst, '\n')
+	if mf.Unit != "" {
+		dst = append(dst, "# UNIT "...)
+		dst = append(dst, mf.Name...)
+		dst = append(dst, ' ')
+		dst = append(dst, mf.Unit...)
+		dst = append(dst, '\n')
+	}
+
+	for _, m := range mf.Metrics {
+		dst = m.appendText(dst, mf.Name)
+	}
+	return dst
+}
+
+// String returns the type name as used in `# TYPE` comment of Prometheus text exposition format.
+func (mt MetricType) String() string {
+	switch mt {
+	case MetricTypeCounter:
+		return "counter"
+	case MetricTypeGauge:
+		return "gauge"
+	case MetricTypeSummary:
+		return "summary"
+	case MetricTypeHistogram:
+		return "histogram"
+	case MetricTypeGaugeHistogram:
+		return "gaugehistogram"
+	default:
+		return "untyped"
+	}
+}
+
+func (m *Metric) appendText(dst []byte, name string) []byte {
+	switch {
+	case m.Counter != nil:
+		dst = m.appendSample(dst, name, "", "", m.Counter.Value)
+	case m.Gauge != nil:
+		dst = m.appendSample(dst, name, "", "", m.Gauge.Value)
+	case m.Untyped != nil:
+		dst = m.appendSample(dst, name, "", "", m.Untyped.Value)
+	case m.Summary != nil:
+		s := m.Summary
+		for _, q := range s.Quantiles {
+			dst = m.appendSample(dst, name, "quantile", formatFloat(q.Quantile), q.Value)
+		}
+		dst = m.appendSample(dst, name+"_sum", "", "", s.SampleSum)
+		dst = m.appendSample(dst, name+"_count", "", "", float64(s.SampleCount))
+	case m.Histogram != nil:
+		dst = m.appendHistogram(dst, name)
+	}
+	return dst
+}
+
+func (m *Metric) appendHistogram(dst []byte, name string) []byte {
+	h := m.Histogram
+	count := float64(h.SampleCount)
+	if h.SampleCountFloat > 0 {
+		count = h.SampleCountFloat
+	}
+
+	bucketName := name + "_bucket"
+	isNative := h.isNative()
+	if isNative {
+		ph := h.toPrompbHistogram()
+		ph.VisitVMRangeBuckets(func(vmrange string, count float64) {
+			dst = m.appendSample(dst, bucketName, "vmrange", vmrange, count)
+		})
+	}
+	// Targets may expose classic buckets together with native buckets for the same histogram.
+	// Keep the classic buckets in this case, since they may be used by the existing queries.
+	if !isNative || len(h.Buckets) > 0 {
+		hasInf := false
+		for _, b := range h.Buckets {
+			bucketCount := float64(b.CumulativeCount)
+			if b.CumulativeCountFloat > 0 {
+				bucketCount = b.CumulativeCountFloat
+			}
+			if math.IsInf(b.UpperBound, 1) {
+				hasInf = true
+			}
+			dst = m.appendSample(dst, bucketName, "le", formatFloat(b.UpperBound), bucketCount)
+		}
+		if !hasInf {
+			dst = m.appendSample(dst, bucketName, "le", "+Inf", count)
+		}
+	}
+	dst = m.appendSample(dst, name+"_sum", "", "", h.SampleSum)
+	dst = m.appendSample(dst, name+"_count", "", "", count)
+	return dst
+}
+
+// isNative returns true if h contains native histogram buckets.
+func (h *Histogram) isNative() bool {
+	return h.Schema != 0 || h.ZeroThreshold > 0 || h.ZeroCount > 0 || h.ZeroCountFloat > 0 ||
+		len(h.NegativeSpans) > 0 || len(h.PositiveSpans) > 0
+}
+
+func (h *Histogram) toPrompbHistogram() *prompb.Histogram {
+	ph := &prompb.Histogram{
+		Count:          float64(h.SampleCount),
+		Sum:            h.SampleSum,
+		Schema:         h.Schema,
+		ZeroThreshold:  h.ZeroThreshold,
+		ZeroCount:      float64(h.ZeroCount),
+		NegativeDeltas: h.NegativeDeltas,
+		NegativeCounts: h.NegativeCounts,
+		PositiveDeltas: h.PositiveDeltas,
+		PositiveCounts: h.PositiveCounts,
+	}
+	if h.SampleCountFloat > 0 {
+		ph.Count = h.SampleCountFloat
+	}
+	if h.ZeroCountFloat > 0 {
+		ph.ZeroCount = h.ZeroCountFloat
+	}
+	for _, span := range h.NegativeSpans {
+		ph.NegativeSpans = append(ph.NegativeSpans, prompb.BucketSpan{
+			Offset: span.Offset,
+			Length: span.Length,
+		})
+	}
+	for _, span := range h.PositiveSpans {
+		ph.PositiveSpans = append(ph.PositiveSpans, prompb.BucketSpan{
+			Offset: span.Offset,
+			Length: span.Length,
+		})
+	}
+	return ph
+}
+
+// appendSample appends a sample with the given name, value and m labels to dst in Prometheus text exposition format.
+//
+// The extraName=extraValue label is added to m labels if extraName isn't empty.
+func (m *Metric) appendSample(dst []byte, name, extraName, extraValue string, value float64) []byte {
+	dst = append(dst, name...)
+	if len(m.Labels) > 0 || extraName != "" {
+		dst = append(dst, '{')
+		for i, lp := range m.Labels {
+			if i > 0 {
+				dst = append(dst, ',')
+			}
+			dst = appendLabel(dst, lp.Name, lp.Value)
+		}
+		if extraName != "" {
+			if len(m.Labels) > 0 {
+				dst = append(dst, ',')
+			}
+			dst = appendLabel(dst, extraName, extraValue)
+		}
+		dst = append(dst, '}')
+	}
+	dst = append(dst, ' ')
+	dst = strconv.AppendFloat(dst, value, 'g', -1, 64)
+	if m.TimestampMs != 0 {
+		dst = append(dst, ' ')
+		dst = strconv.AppendInt(dst, m.TimestampMs, 10)
+	}
+	dst = append(dst, '\n')
+	return dst
+}
+
+func appendLabel(dst []byte, name, value string) []byte {
+	dst = append(dst, name...)
+	dst = append(dst, `="`...)
+	for i := 0; i < len(value); i++ {
+		switch c := value[i]; c {
+		case '\\':
+			dst = append(dst, `\\`...)
+		case '"':
+			dst = append(dst, `\"`...)
+		case '\n':
+			dst = append(dst, `\n`...)
+		default:
+			dst = append(dst, c)
+		}
+	}
+	dst = append(dst, '"')
+	return dst
+}
+
+func appendEscapedHelp(dst []byte, help string) []byte {
+	for i := 0; i < len(help); i++ {
+		switch c := help[i]; c {
+		case '\\':
+			dst = append(dst, `\\`...)
+		case '\n':
+			dst = append(dst, `\n`...)
+		default:
+			dst = append(dst, c)
+		}
+	}
+	return dst
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
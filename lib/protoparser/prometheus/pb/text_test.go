@@ -0,0 +1,250 @@
+package pb
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+)
+
+func TestAppendText(t *testing.T) {
+	f := func(mfs []*MetricFamily, resultExpected string) {
+		t.Helper()
+
+		var data []byte
+		for _, mf := range mfs {
+			msg := mf.MarshalProtobuf(nil)
+			data = binary.AppendUvarint(data, uint64(len(msg)))
+			data = append(data, msg...)
+		}
+		result, err := AppendText(nil, data)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if string(result) != resultExpected {
+			t.Fatalf("unexpected result\ngot\n%s\nwant\n%s", result, resultExpected)
+		}
+	}
+
+	// empty data
+	f(nil, "")
+
+	// counter and gauge
+	f([]*MetricFamily{
+		{
+			Name: "http_requests_total",
+			Help: "Total number of \\ requests\nper path",
+			Type: MetricTypeCounter,
+			Metrics: []*Metric{
+				{
+					Labels: []*LabelPair{
+						{Name: "path", Value: `/foo"bar`},
+						{Name: "code", Value: "200"},
+					},
+					Counter: &Counter{
+						Value: 123,
+					},
+				},
+				{
+					Counter: &Counter{
+						Value: 1.5,
+					},
+					TimestampMs: 1234,
+				},
+			},
+		},
+		{
+			Name: "temperature",
+			Type: MetricTypeGauge,
+			Unit: "celsius",
+			Metrics: []*Metric{
+				{
+					Gauge: &Gauge{
+						Value: -3.25,
+					},
+				},
+			},
+		},
+		{
+			Name: "untyped_metric",
+			Type: MetricTypeUntyped,
+			Metrics: []*Metric{
+				{
+					Untyped: &Untyped{
+						Value: math.Inf(1),
+					},
+				},
+			},
+		},
+	}, `# HELP http_requests_total Total number of \\ requests\nper path
+# TYPE http_requests_total counter
+http_requests_total{path="/foo\"bar",code="200"} 123
+http_requests_total 1.5 1234
+# TYPE temperature gauge
+# UNIT temperature celsius
+temperature -3.25
+# TYPE untyped_metric untyped
+untyped_metric +Inf
+`)
+
+	// summary
+	f([]*MetricFamily{
+		{
+			Name: "rpc_duration_seconds",
+			Type: MetricTypeSummary,
+			Metrics: []*Metric{
+				{
+					Labels: []*LabelPair{
+						{Name: "service", Value: "foo"},
+					},
+					Summary: &Summary{
+						SampleCount: 10,
+						SampleSum:   2.5,
+						Quantiles: []*Quantile{
+							{Quantile: 0.5, Value: 0.2},
+							{Quantile: 0.99, Value: 0.8},
+						},
+					},
+				},
+			},
+		},
+	}, `# TYPE rpc_duration_seconds summary
+rpc_duration_seconds{service="foo",quantile="0.5"} 0.2
+rpc_duration_seconds{service="foo",quantile="0.99"} 0.8
+rpc_duration_seconds_sum{service="foo"} 2.5
+rpc_duration_seconds_count{service="foo"} 10
+`)
+
+	// classic histogram
+	f([]*MetricFamily{
+		{
+			Name: "request_size_bytes",
+			Type: MetricTypeHistogram,
+			Metrics: []*Metric{
+				{
+					Histogram: &Histogram{
+						SampleCount: 5,
+						SampleSum:   1234,
+						Buckets: []*Bucket{
+							{UpperBound: 100, CumulativeCount: 1},
+							{UpperBound: 1000, CumulativeCount: 4},
+						},
+					},
+				},
+			},
+		},
+	}, `# TYPE request_size_bytes histogram
+request_size_bytes_bucket{le="100"} 1
+request_size_bytes_bucket{le="1000"} 4
+request_size_bytes_bucket{le="+Inf"} 5
+request_size_bytes_sum 1234
+request_size_bytes_count 5
+`)
+
+	// native histogram
+	f([]*MetricFamily{
+		{
+			Name: "request_duration_seconds",
+			Help: "Request duration",
+			Type: MetricTypeHistogram,
+			Metrics: []*Metric{
+				{
+					Labels: []*LabelPair{
+						{Name: "path", Value: "/"},
+					},
+					Histogram: &Histogram{
+						SampleCount:   6,
+						SampleSum:     3.5,
+						Schema:        1,
+						ZeroThreshold: 1e-10,
+						ZeroCount:     1,
+						NegativeSpans: []*BucketSpan{
+							{Offset: 0, Length: 1},
+						},
+						NegativeDeltas: []int64{1},
+						PositiveSpans: []*BucketSpan{
+							{Offset: 1, Length: 2},
+						},
+						PositiveDeltas: []int64{3, -2},
+					},
+				},
+			},
+		},
+	}, `# HELP request_duration_seconds Request duration
+# TYPE request_duration_seconds histogram
+request_duration_seconds_bucket{path="/",vmrange="-1.000e+00...-7.071e-01"} 1
+request_duration_seconds_bucket{path="/",vmrange="-1.000e-10...1.000e-10"} 1
+request_duration_seconds_bucket{path="/",vmrange="1.000e+00...1.414e+00"} 3
+request_duration_seconds_bucket{path="/",vmrange="1.414e+00...2.000e+00"} 1
+request_duration_seconds_sum{path="/"} 3.5
+request_duration_seconds_count{path="/"} 6
+`)
+
+	// histogram with both classic and native buckets
+	f([]*MetricFamily{
+		{
+			Name: "rpc_duration_seconds",
+			Type: MetricTypeHistogram,
+			Metrics: []*Metric{
+				{
+					Histogram: &Histogram{
+						SampleCount: 4,
+						SampleSum:   3,
+						Buckets: []*Bucket{
+							{CumulativeCount: 1, UpperBound: 1},
+							{CumulativeCount: 4, UpperBound: 2},
+						},
+						Schema:        1,
+						ZeroThreshold: 1e-10,
+						ZeroCount:     1,
+						PositiveSpans: []*BucketSpan{
+							{Offset: 1, Length: 1},
+						},
+						PositiveDeltas: []int64{3},
+					},
+				},
+			},
+		},
+	}, `# TYPE rpc_duration_seconds histogram
+rpc_duration_seconds_bucket{vmrange="-1.000e-10...1.000e-10"} 1
+rpc_duration_seconds_bucket{vmrange="1.000e+00...1.414e+00"} 3
+rpc_duration_seconds_bucket{le="1"} 1
+rpc_duration_seconds_bucket{le="2"} 4
+rpc_duration_seconds_bucket{le="+Inf"} 4
+rpc_duration_seconds_sum 3
+rpc_duration_seconds_count 4
+`)
+}
+
+func TestAppendTextFailure(t *testing.T) {
+	f := func(data []byte) {
+		t.Helper()
+		if _, err := AppendText(nil, data); err == nil {
+			t.Fatalf("expecting non-nil error")
+		}
+	}
+
+	// invalid message length
+	f([]byte{0xff})
+
+	// too short message
+	f([]byte{10, 1, 2})
+
+	// invalid message
+	f([]byte{2, 0xff, 0xff})
+}
+
+func TestIsProtobufContentType(t *testing.T) {
+	f := func(contentType string, resultExpected bool) {
+		t.Helper()
+		result := IsProtobufContentType(contentType)
+		if result != resultExpected {
+			t.Fatalf("unexpected result for %q; got %v; want %v", contentType, result, resultExpected)
+		}
+	}
+
+	f("", false)
+	f("text/plain; version=0.0.4", false)
+	f("application/openmetrics-text", false)
+	f(ContentType, true)
+	f("application/vnd.google.protobuf;proto=io.prometheus.client.MetricFamily;encoding=delimited", true)
+}
@@ -4,6 +4,9 @@ import (
 	"bufio"
 	"fmt"
 	"io"
+	"mime"
+	"net/http"
+	"strconv"
 	"sync"
 
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
@@ -18,10 +21,59 @@ import (
 
 var maxInsertRequestSize = flagutil.NewBytes("maxInsertRequestSize", 32*1024*1024, "The maximum size in bytes of a single Prometheus remote_write API request")
 
-// Parse parses Prometheus remote_write message from reader and calls callback for the parsed timeseries.
+// ContentTypeV2 is the Content-Type for Prometheus remote write 2.0 requests.
 //
-// callback shouldn't hold tss after returning.
-func Parse(r io.Reader, isVMRemoteWrite bool, callback func(tss []prompb.TimeSeries) error) error {
+// See https://prometheus.io/docs/specs/remote_write_spec_2_0/#protocol
+const ContentTypeV2 = "application/x-protobuf;proto=io.prometheus.write.v2.Request"
+
+// IsRemoteWriteV2 returns true if contentType corresponds to Prometheus remote write 2.0 protocol.
+//
+// An error is returned if contentType refers to unsupported protobuf message.
+// The caller must respond with http.StatusUnsupportedMediaType in this case, so the client could fall back to another protocol.
+func IsRemoteWriteV2(contentType string) (bool, error) {
+	if contentType == "" {
+		return false, nil
+	}
+	_, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		// Be lenient to clients with invalid Content-Type header, since remote write 1.0 doesn't require it.
+		return false, nil
+	}
+	switch proto := params["proto"]; proto {
+	case "", "prometheus.WriteRequest":
+		return false, nil
+	case "io.prometheus.write.v2.Request":
+		return true, nil
+	default:
+		return false, fmt.Errorf("unsupported protobuf message in Content-Type=%q: %q; supported messages: prometheus.WriteRequest, io.prometheus.write.v2.Request", contentType, proto)
+	}
+}
+
+// WriteStats contains the number of samples, histograms and exemplars in the parsed request.
+type WriteStats struct {
+	Samples    int
+	Histograms int
+	Exemplars  int
+}
+
+// SetResponseHeaders sets response headers with ws stats as required by Prometheus remote write 2.0 protocol.
+//
+// See https://prometheus.io/docs/specs/remote_write_spec_2_0/#required-written-response-headers
+func (ws *WriteStats) SetResponseHeaders(h http.Header) {
+	h.Set("X-Prometheus-Remote-Write-Samples-Written", strconv.Itoa(ws.Samples))
+	h.Set("X-Prometheus-Remote-Write-Histograms-Written", strconv.Itoa(ws.Histograms))
+	h.Set("X-Prometheus-Remote-Write-Exemplars-Written", strconv.Itoa(ws.Exemplars))
+}
+
+// Parse parses Prometheus remote_write message from reader and calls callback for the parsed timeseries and metric metadata.
+//
+// Prometheus remote write 2.0 message is parsed if isRemoteWriteV2 is set. Otherwise remote write 1.0 message is parsed.
+// The returned stats contain the number of samples, histograms and exemplars in the parsed message.
+//
+// callback shouldn't hold tss and mms after returning.
+func Parse(r io.Reader, isVMRemoteWrite, isRemoteWriteV2 bool, callback func(tss []prompb.TimeSeries, mms []prompb.MetricMetadata) error) (WriteStats, error) {
+	var ws WriteStats
+
 	wcr := writeconcurrencylimiter.GetReader(r)
 	defer writeconcurrencylimiter.PutReader(wcr)
 	r = wcr
@@ -29,7 +81,7 @@ func Parse(r io.Reader, isVMRemoteWrite bool, callback func(tss []prompb.TimeSer
 	ctx := getPushCtx(r)
 	defer putPushCtx(ctx)
 	if err := ctx.Read(); err != nil {
-		return err
+		return ws, err
 	}
 
 	// Synchronously process the request in order to properly return errors to Parse caller,
@@ -47,7 +99,7 @@ func Parse(r io.Reader, isVMRemoteWrite bool, callback func(tss []prompb.TimeSer
 			zstdErr := err
 			bb.B, err = snappy.Decode(bb.B[:cap(bb.B)], ctx.reqBuf.B)
 			if err != nil {
-				return fmt.Errorf("cannot decompress zstd-encoded request with length %d: %w", len(ctx.reqBuf.B), zstdErr)
+				return ws, fmt.Errorf("cannot decompress zstd-encoded request with length %d: %w", len(ctx.reqBuf.B), zstdErr)
 			}
 		}
 	} else {
@@ -59,31 +111,49 @@ func Parse(r io.Reader, isVMRemoteWrite bool, callback func(tss []prompb.TimeSer
 			snappyErr := err
 			bb.B, err = zstd.Decompress(bb.B[:0], ctx.reqBuf.B)
 			if err != nil {
-				return fmt.Errorf("cannot decompress snappy-encoded request with length %d: %w", len(ctx.reqBuf.B), snappyErr)
+				return ws, fmt.Errorf("cannot decompress snappy-encoded request with length %d: %w", len(ctx.reqBuf.B), snappyErr)
 			}
 		}
 	}
 	if int64(len(bb.B)) > maxInsertRequestSize.N {
-		return fmt.Errorf("too big unpacked request; mustn't exceed `-maxInsertRequestSize=%d` bytes; got %d bytes", maxInsertRequestSize.N, len(bb.B))
+		return ws, fmt.Errorf("too big unpacked request; mustn't exceed `-maxInsertRequestSize=%d` bytes; got %d bytes", maxInsertRequestSize.N, len(bb.B))
 	}
 	wr := getWriteRequest()
 	defer putWriteRequest(wr)
-	if err := wr.UnmarshalProtobuf(bb.B); err != nil {
-		unmarshalErrors.Inc()
-		return fmt.Errorf("cannot unmarshal prompb.WriteRequest with size %d bytes: %w", len(bb.B), err)
+	if isRemoteWriteV2 {
+		if err := wr.UnmarshalProtobufV2(bb.B); err != nil {
+			unmarshalErrors.Inc()
+			return ws, fmt.Errorf("cannot unmarshal io.prometheus.write.v2.Request with size %d bytes: %w", len(bb.B), err)
+		}
+	} else {
+		if err := wr.UnmarshalProtobuf(bb.B); err != nil {
+			unmarshalErrors.Inc()
+			return ws, fmt.Errorf("cannot unmarshal prompb.WriteRequest with size %d bytes: %w", len(bb.B), err)
+		}
+	}
+	tss := wr.Timeseries
+	for i := range tss {
+		ts := &tss[i]
+		ws.Samples += len(ts.Samples)
+		ws.Histograms += len(ts.Histograms)
+		ws.Exemplars += len(ts.Exemplars)
 	}
 
+	// Convert native histograms to VictoriaMetrics histograms with `vmrange` buckets,
+	// so they could be queried with the existing histogram functions.
+	wr.ConvertHistograms()
+
 	rows := 0
-	tss := wr.Timeseries
+	tss = wr.Timeseries
 	for i := range tss {
 		rows += len(tss[i].Samples)
 	}
 	rowsRead.Add(rows)
 
-	if err := callback(tss); err != nil {
-		return fmt.Errorf("error when processing imported data: %w", err)
+	if err := callback(tss, wr.Metadata); err != nil {
+		return ws, fmt.Errorf("error when processing imported data: %w", err)
 	}
-	return nil
+	return ws, nil
 }
 
 var bodyBufferPool bytesutil.ByteBufferPool
@@ -0,0 +1,37 @@
+package stream
+
+import (
+	"testing"
+)
+
+func TestIsRemoteWriteV2(t *testing.T) {
+	f := func(contentType string, resultExpected bool) {
+		t.Helper()
+		result, err := IsRemoteWriteV2(contentType)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if result != resultExpected {
+			t.Fatalf("unexpected result for %q; got %v; want %v", contentType, result, resultExpected)
+		}
+	}
+
+	f("", false)
+	f("application/x-protobuf", false)
+	f("application/x-protobuf;proto=prometheus.WriteRequest", false)
+	f("invalid content type", false)
+	f(ContentTypeV2, true)
+	f("application/x-protobuf; proto=io.prometheus.write.v2.Request", true)
+}
+
+func TestIsRemoteWriteV2Failure(t *testing.T) {
+	f := func(contentType string) {
+		t.Helper()
+		if _, err := IsRemoteWriteV2(contentType); err == nil {
+			t.Fatalf("expecting non-nil error for %q", contentType)
+		}
+	}
+
+	f("application/x-protobuf;proto=io.prometheus.write.v3.Request")
+	f("application/x-protobuf;proto=foobar")
+}
@@ -0,0 +1,676 @@
+package storage
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/encoding"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fasttime"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fs"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompb"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/querytracer"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/slicesutil"
+)
+
+// Exemplar is an exemplar for a time series.
+//
+// See https://github.com/OpenObservability/OpenMetrics/blob/main/specification/OpenMetrics.md#exemplars
+type Exemplar struct {
+	// Labels contains exemplar labels such as trace_id.
+	Labels []prompb.Label
+
+	// Value is exemplar value.
+	Value float64
+
+	// Timestamp is exemplar timestamp in milliseconds.
+	Timestamp int64
+}
+
+func (e *Exemplar) equal(other *Exemplar) bool {
+	if e.Timestamp != other.Timestamp || math.Float64bits(e.Value) != math.Float64bits(other.Value) {
+		return false
+	}
+	if len(e.Labels) != len(other.Labels) {
+		return false
+	}
+	for i, label := range e.Labels {
+		if label != other.Labels[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func (e *Exemplar) sizeBytes() int {
+	n := 32
+	for _, label := range e.Labels {
+		n += 32 + len(label.Name) + len(label.Value)
+	}
+	return n
+}
+
+// ExemplarRow is an exemplar to insert into storage.
+type ExemplarRow struct {
+	// MetricNameRaw contains raw metric name for the time series the exemplar belongs to.
+	// It must be decoded with MetricName.UnmarshalRaw.
+	MetricNameRaw []byte
+
+	// Exemplar is the exemplar for the time series.
+	//
+	// Exemplar labels may refer to the memory owned by the caller, since they are copied when stored.
+	Exemplar Exemplar
+}
+
+// ExemplarSeries contains exemplars for a single time series.
+type ExemplarSeries struct {
+	// MetricName is marshaled MetricName for the time series. It can be decoded with MetricName.UnmarshalString.
+	MetricName string
+
+	// Exemplars contains exemplars for the time series sorted by timestamp.
+	Exemplars []Exemplar
+}
+
+// maxExemplars is the maximum number of exemplars, which can be stored in the storage.
+var maxExemplars = 100_000
+
+// SetMaxExemplars sets the maximum number of exemplars, which can be stored in the storage.
+//
+// Exemplars aren't stored if n <= 0.
+//
+// This function must be called before initializing the storage.
+func SetMaxExemplars(n int) {
+	maxExemplars = n
+}
+
+// exemplarTable holds exemplars split into per-hour partitions.
+//
+// The number of stored exemplars is limited by maxExemplars. When the limit is reached,
+// the partition with the oldest exemplars is dropped in order to free space for new exemplars.
+// If the oldest partition belongs to the same hour as new exemplars, then the oldest exemplars are evicted from it.
+type exemplarTable struct {
+	exemplarsAdded   atomic.Uint64
+	exemplarsDropped atomic.Uint64
+
+	path           string
+	retentionMsecs int64
+
+	// flushMu prevents from concurrent flushes of exemplars to disk.
+	//
+	// It must be locked before mu.
+	flushMu sync.Mutex
+
+	// mu protects the fields below.
+	mu sync.Mutex
+
+	// ptws contains partitions sorted by hour.
+	ptws []*exemplarPartition
+
+	// exemplarsCount is the number of exemplars across ptws.
+	exemplarsCount int
+
+	stopCh chan struct{}
+
+	flusherWG sync.WaitGroup
+}
+
+// exemplarPartition holds exemplars with timestamps for the given hour.
+type exemplarPartition struct {
+	// hour is the number of hours since unix epoch.
+	hour uint64
+
+	// m maps marshaled MetricName to exemplars sorted by timestamp.
+	m map[string][]Exemplar
+
+	exemplarsCount int
+	sizeBytes      int
+
+	// minTimestamp is the minimum timestamp across exemplars in the partition.
+	minTimestamp int64
+
+	// isDirty is set to true if the partition has been changed since the last flush to disk.
+	isDirty bool
+
+	// isDropped is set to true when the partition is dropped from the table.
+	isDropped bool
+}
+
+const exemplarPartitionNameLayout = "2006_01_02_15"
+
+func (ptw *exemplarPartition) name() string {
+	t := time.Unix(int64(ptw.hour)*3600, 0).UTC()
+	return t.Format(exemplarPartitionNameLayout)
+}
+
+func (ptw *exemplarPartition) path(tablePath string) string {
+	return filepath.Join(tablePath, ptw.name()+".bin")
+}
+
+func mustOpenExemplarTable(path string, retentionMsecs int64) *exemplarTable {
+	fs.MustMkdirIfNotExist(path)
+
+	t := &exemplarTable{
+		path:           path,
+		retentionMsecs: retentionMsecs,
+		stopCh:         make(chan struct{}),
+	}
+	for _, de := range fs.MustReadDir(path) {
+		fn := de.Name()
+		if fs.IsTemporaryFileName(fn) {
+			fs.MustRemoveAll(filepath.Join(path, fn))
+			continue
+		}
+		ptw := mustLoadExemplarPartition(path, fn)
+		if ptw == nil {
+			continue
+		}
+		t.ptws = append(t.ptws, ptw)
+		t.exemplarsCount += ptw.exemplarsCount
+	}
+	sort.Slice(t.ptws, func(i, j int) bool {
+		return t.ptws[i].hour < t.ptws[j].hour
+	})
+
+	t.flusherWG.Add(1)
+	go func() {
+		t.flusher()
+		t.flusherWG.Done()
+	}()
+	return t
+}
+
+// MustClose stops background flusher and flushes the exemplars to disk.
+func (t *exemplarTable) MustClose() {
+	close(t.stopCh)
+	t.flusherWG.Wait()
+
+	t.mustFlush()
+}
+
+func (t *exemplarTable) flusher() {
+	d := dataFlushInterval
+	ticker := time.NewTicker(d)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-t.stopCh:
+			return
+		case <-ticker.C:
+			minTimestamp := int64(fasttime.UnixTimestamp()*1000) - t.retentionMsecs
+			t.mu.Lock()
+			t.dropStalePartitionsLocked(minTimestamp)
+			t.mu.Unlock()
+			t.mustFlush()
+		}
+	}
+}
+
+// mustFlush writes dirty partitions to disk.
+//
+// The partitions are marshaled under t.mu lock, while the marshaled data is compressed and written to disk without holding t.mu,
+// so concurrent add and search calls aren't blocked by disk IO.
+func (t *exemplarTable) mustFlush() {
+	t.flushMu.Lock()
+	defer t.flushMu.Unlock()
+
+	type dirtyPartition struct {
+		ptw  *exemplarPartition
+		data []byte
+	}
+	var dps []dirtyPartition
+
+	t.mu.Lock()
+	for _, ptw := range t.ptws {
+		if !ptw.isDirty {
+			continue
+		}
+		dps = append(dps, dirtyPartition{
+			ptw:  ptw,
+			data: ptw.marshal(nil),
+		})
+		ptw.isDirty = false
+	}
+	t.mu.Unlock()
+
+	for _, dp := range dps {
+		path := dp.ptw.path(t.path)
+		data := encoding.CompressZSTDLevel(nil, dp.data, 1)
+		fs.MustWriteAtomic(path, data, true)
+
+		// The partition could be dropped while it was written to disk. Remove its file then.
+		t.mu.Lock()
+		if dp.ptw.isDropped {
+			fs.MustRemoveAll(path)
+		}
+		t.mu.Unlock()
+	}
+}
+
+// mustCreateSnapshotAt copies exemplars to dstDir.
+func (t *exemplarTable) mustCreateSnapshotAt(dstDir string) {
+	t.mustFlush()
+
+	// Prevent from writing and dropping partitions while their files are copied.
+	t.flushMu.Lock()
+	defer t.flushMu.Unlock()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	fs.MustCopyDirectory(t.path, dstDir)
+}
+
+// dropStalePartitionsLocked drops partitions with exemplars older than minTimestamp.
+func (t *exemplarTable) dropStalePartitionsLocked(minTimestamp int64) {
+	for len(t.ptws) > 0 {
+		ptw := t.ptws[0]
+		if int64(ptw.hour+1)*3600*1000 > minTimestamp {
+			return
+		}
+		t.dropOldestPartitionLocked()
+	}
+}
+
+// dropOldestPartitionLocked drops the partition with the oldest exemplars and returns the number of dropped exemplars.
+func (t *exemplarTable) dropOldestPartitionLocked() int {
+	ptw := t.ptws[0]
+	t.ptws[0] = nil
+	t.ptws = t.ptws[1:]
+	t.exemplarsCount -= ptw.exemplarsCount
+	ptw.isDropped = true
+	fs.MustRemoveAll(ptw.path(t.path))
+	return ptw.exemplarsCount
+}
+
+// evictOldestExemplarsLocked evicts the oldest exemplars from the partition with the oldest exemplars.
+//
+// It returns the number of evicted exemplars.
+func (t *exemplarTable) evictOldestExemplarsLocked() int {
+	// Evict exemplars in batches, so the eviction cost is amortized across the added exemplars.
+	n := maxExemplars / 10
+	if n < 1 {
+		n = 1
+	}
+	ptw := t.ptws[0]
+	evictedCount := ptw.evictOldest(n)
+	if ptw.exemplarsCount == 0 {
+		t.dropOldestPartitionLocked()
+	}
+	t.exemplarsCount -= evictedCount
+	return evictedCount
+}
+
+func (t *exemplarTable) getOrCreatePartitionLocked(hour uint64) *exemplarPartition {
+	n := sort.Search(len(t.ptws), func(i int) bool {
+		return t.ptws[i].hour >= hour
+	})
+	if n < len(t.ptws) && t.ptws[n].hour == hour {
+		return t.ptws[n]
+	}
+	ptw := &exemplarPartition{
+		hour: hour,
+		m:    make(map[string][]Exemplar),
+	}
+	t.ptws = append(t.ptws, nil)
+	copy(t.ptws[n+1:], t.ptws[n:])
+	t.ptws[n] = ptw
+	return ptw
+}
+
+// add adds ers with timestamps in the range [minTimestamp ... maxTimestamp] to t.
+//
+// Exemplars for the same time series with identical timestamps, values and labels are deduplicated.
+func (t *exemplarTable) add(ers []ExemplarRow, minTimestamp, maxTimestamp int64) {
+	mn := GetMetricName()
+	defer PutMetricName(mn)
+
+	var metricNameBuf []byte
+	addedCount := 0
+	droppedCount := 0
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for i := range ers {
+		er := &ers[i]
+		e := &er.Exemplar
+		if e.Timestamp < minTimestamp || e.Timestamp > maxTimestamp {
+			droppedCount++
+			continue
+		}
+		if err := mn.UnmarshalRaw(er.MetricNameRaw); err != nil {
+			logger.Panicf("BUG: cannot umarshal MetricNameRaw %q: %s", er.MetricNameRaw, err)
+		}
+		mn.sortTags()
+		metricNameBuf = mn.Marshal(metricNameBuf[:0])
+
+		hour := uint64(e.Timestamp) / (3600 * 1000)
+		for t.exemplarsCount >= maxExemplars && len(t.ptws) > 0 && t.ptws[0].hour < hour {
+			droppedCount += t.dropOldestPartitionLocked()
+		}
+		if t.exemplarsCount >= maxExemplars && len(t.ptws) > 0 && t.ptws[0].hour == hour {
+			if e.Timestamp <= t.ptws[0].minTimestamp {
+				// There is no space for e, since all the stored exemplars are newer than e.
+				droppedCount++
+				continue
+			}
+			// The oldest stored exemplars belong to the hour of e. Evict them in order to free space for e.
+			droppedCount += t.evictOldestExemplarsLocked()
+		}
+		if t.exemplarsCount >= maxExemplars {
+			// There is no space for e, since all the stored exemplars are newer than e.
+			droppedCount++
+			continue
+		}
+		ptw := t.getOrCreatePartitionLocked(hour)
+		if ptw.add(metricNameBuf, e) {
+			t.exemplarsCount++
+			addedCount++
+		}
+	}
+	t.exemplarsAdded.Add(uint64(addedCount))
+	t.exemplarsDropped.Add(uint64(droppedCount))
+}
+
+// add adds e for the given metricName to ptw.
+//
+// It returns false if ptw already contains e for the given metricName.
+func (ptw *exemplarPartition) add(metricName []byte, e *Exemplar) bool {
+	es := ptw.m[string(metricName)]
+	n := sort.Search(len(es), func(i int) bool {
+		return es[i].Timestamp > e.Timestamp
+	})
+	for j := n - 1; j >= 0 && es[j].Timestamp == e.Timestamp; j-- {
+		if es[j].equal(e) {
+			return false
+		}
+	}
+
+	// Copy labels, since they may refer to the memory owned by the caller.
+	var labels []prompb.Label
+	if len(e.Labels) > 0 {
+		labels = make([]prompb.Label, len(e.Labels))
+		for i, label := range e.Labels {
+			labels[i] = prompb.Label{
+				Name:  strings.Clone(label.Name),
+				Value: strings.Clone(label.Value),
+			}
+		}
+	}
+	es = append(es, Exemplar{})
+	copy(es[n+1:], es[n:])
+	es[n] = Exemplar{
+		Labels:    labels,
+		Value:     e.Value,
+		Timestamp: e.Timestamp,
+	}
+	if len(es) == 1 {
+		ptw.sizeBytes += len(metricName)
+	}
+	if ptw.exemplarsCount == 0 || e.Timestamp < ptw.minTimestamp {
+		ptw.minTimestamp = e.Timestamp
+	}
+	ptw.m[string(metricName)] = es
+	ptw.exemplarsCount++
+	ptw.sizeBytes += e.sizeBytes()
+	ptw.isDirty = true
+	return true
+}
+
+// evictOldest evicts at least n exemplars with the smallest timestamps from ptw.
+//
+// It returns the number of evicted exemplars.
+func (ptw *exemplarPartition) evictOldest(n int) int {
+	timestamps := make([]int64, 0, ptw.exemplarsCount)
+	for _, es := range ptw.m {
+		for i := range es {
+			timestamps = append(timestamps, es[i].Timestamp)
+		}
+	}
+	if len(timestamps) == 0 {
+		return 0
+	}
+	slices.Sort(timestamps)
+	if n > len(timestamps) {
+		n = len(timestamps)
+	}
+	maxEvictedTimestamp := timestamps[n-1]
+
+	evictedCount := 0
+	for metricName, es := range ptw.m {
+		m := sort.Search(len(es), func(i int) bool {
+			return es[i].Timestamp > maxEvictedTimestamp
+		})
+		if m == 0 {
+			continue
+		}
+		for i := range es[:m] {
+			ptw.sizeBytes -= es[i].sizeBytes()
+		}
+		evictedCount += m
+		if m == len(es) {
+			delete(ptw.m, metricName)
+			ptw.sizeBytes -= len(metricName)
+			continue
+		}
+		esNew := append(es[:0], es[m:]...)
+		clear(es[len(esNew):])
+		ptw.m[metricName] = esNew
+	}
+	ptw.exemplarsCount -= evictedCount
+	if evictedCount < len(timestamps) {
+		ptw.minTimestamp = timestamps[evictedCount]
+	}
+	ptw.isDirty = true
+	return evictedCount
+}
+
+// search returns exemplars for the given metricNames on the given tr.
+//
+// metricNames must contain marshaled MetricName values.
+func (t *exemplarTable) search(metricNames []string, tr TimeRange) []ExemplarSeries {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	minHour := uint64(0)
+	if tr.MinTimestamp > 0 {
+		minHour = uint64(tr.MinTimestamp) / (3600 * 1000)
+	}
+	if tr.MaxTimestamp < 0 {
+		return nil
+	}
+	maxHour := uint64(tr.MaxTimestamp) / (3600 * 1000)
+
+	var ess []ExemplarSeries
+	for _, metricName := range metricNames {
+		var dst []Exemplar
+		for _, ptw := range t.ptws {
+			if ptw.hour < minHour || ptw.hour > maxHour {
+				continue
+			}
+			for _, e := range ptw.m[metricName] {
+				if e.Timestamp >= tr.MinTimestamp && e.Timestamp <= tr.MaxTimestamp {
+					dst = append(dst, e)
+				}
+			}
+		}
+		if len(dst) > 0 {
+			ess = append(ess, ExemplarSeries{
+				MetricName: metricName,
+				Exemplars:  dst,
+			})
+		}
+	}
+	return ess
+}
+
+func (t *exemplarTable) updateMetrics(m *Metrics) {
+	m.ExemplarsAddedTotal += t.exemplarsAdded.Load()
+	m.ExemplarsDroppedTotal += t.exemplarsDropped.Load()
+
+	t.mu.Lock()
+	m.ExemplarsCount += uint64(t.exemplarsCount)
+	for _, ptw := range t.ptws {
+		m.ExemplarsSizeBytes += uint64(ptw.sizeBytes)
+	}
+	m.ExemplarsPartitions += uint64(len(t.ptws))
+	t.mu.Unlock()
+}
+
+func (ptw *exemplarPartition) marshal(dst []byte) []byte {
+	dst = encoding.MarshalVarUint64(dst, uint64(len(ptw.m)))
+	for metricName, es := range ptw.m {
+		dst = encoding.MarshalBytes(dst, []byte(metricName))
+		dst = encoding.MarshalVarUint64(dst, uint64(len(es)))
+		for i := range es {
+			e := &es[i]
+			dst = encoding.MarshalVarUint64(dst, uint64(len(e.Labels)))
+			for _, label := range e.Labels {
+				dst = encoding.MarshalBytes(dst, []byte(label.Name))
+				dst = encoding.MarshalBytes(dst, []byte(label.Value))
+			}
+			dst = encoding.MarshalUint64(dst, math.Float64bits(e.Value))
+			dst = encoding.MarshalVarInt64(dst, e.Timestamp)
+		}
+	}
+	return dst
+}
+
+func (ptw *exemplarPartition) unmarshal(src []byte) error {
+	seriesCount, nSize := encoding.UnmarshalVarUint64(src)
+	if nSize <= 0 {
+		return fmt.Errorf("cannot unmarshal series count")
+	}
+	src = src[nSize:]
+	for i := uint64(0); i < seriesCount; i++ {
+		metricName, nSize := encoding.UnmarshalBytes(src)
+		if nSize <= 0 {
+			return fmt.Errorf("cannot unmarshal metric name for series #%d", i)
+		}
+		src = src[nSize:]
+		exemplarsCount, nSize := encoding.UnmarshalVarUint64(src)
+		if nSize <= 0 {
+			return fmt.Errorf("cannot unmarshal exemplars count for series #%d", i)
+		}
+		src = src[nSize:]
+		var e Exemplar
+		for j := uint64(0); j < exemplarsCount; j++ {
+			labelsCount, nSize := encoding.UnmarshalVarUint64(src)
+			if nSize <= 0 {
+				return fmt.Errorf("cannot unmarshal labels count for exemplar #%d at series #%d", j, i)
+			}
+			src = src[nSize:]
+			if labelsCount > uint64(len(src)) {
+				return fmt.Errorf("too big labels count for exemplar #%d at series #%d: %d", j, i, labelsCount)
+			}
+			e.Labels = slicesutil.SetLength(e.Labels, int(labelsCount))
+			for k := range e.Labels {
+				name, nSize := encoding.UnmarshalBytes(src)
+				if nSize <= 0 {
+					return fmt.Errorf("cannot unmarshal label name for exemplar #%d at series #%d", j, i)
+				}
+				src = src[nSize:]
+				value, nSize := encoding.UnmarshalBytes(src)
+				if nSize <= 0 {
+					return fmt.Errorf("cannot unmarshal label value for exemplar #%d at series #%d", j, i)
+				}
+				src = src[nSize:]
+				// There is no need in copying name and value, since ptw.add copies them.
+				e.Labels[k] = prompb.Label{
+					Name:  bytesutil.ToUnsafeString(name),
+					Value: bytesutil.ToUnsafeString(value),
+				}
+			}
+			if len(src) < 8 {
+				return fmt.Errorf("cannot unmarshal value for exemplar #%d at series #%d; want 8 bytes; have %d bytes", j, i, len(src))
+			}
+			e.Value = math.Float64frombits(encoding.UnmarshalUint64(src))
+			src = src[8:]
+			timestamp, nSize := encoding.UnmarshalVarInt64(src)
+			if nSize <= 0 {
+				return fmt.Errorf("cannot unmarshal timestamp for exemplar #%d at series #%d", j, i)
+			}
+			src = src[nSize:]
+			e.Timestamp = timestamp
+			ptw.add(metricName, &e)
+		}
+	}
+	if len(src) > 0 {
+		return fmt.Errorf("unexpected non-empty tail left after unmarshaling exemplars; len(tail)=%d", len(src))
+	}
+	ptw.isDirty = false
+	return nil
+}
+
+// mustLoadExemplarPartition loads exemplar partition from the file with the given name at tablePath.
+//
+// nil is returned if the file cannot be loaded.
+func mustLoadExemplarPartition(tablePath, fn string) *exemplarPartition {
+	path := filepath.Join(tablePath, fn)
+	name, ok := strings.CutSuffix(fn, ".bin")
+	if !ok {
+		logger.Errorf("skipping unexpected file %s in the exemplars directory", path)
+		return nil
+	}
+	t, err := time.Parse(exemplarPartitionNameLayout, name)
+	if err != nil {
+		logger.Errorf("skipping file %s with unexpected name in the exemplars directory: %s", path, err)
+		return nil
+	}
+	ptw := &exemplarPartition{
+		hour: uint64(t.Unix()) / 3600,
+		m:    make(map[string][]Exemplar),
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		logger.Panicf("FATAL: cannot read %s: %s", path, err)
+	}
+	data, err = encoding.DecompressZSTD(nil, data)
+	if err != nil {
+		logger.Errorf("discarding %s, since it cannot be decompressed: %s", path, err)
+		fs.MustRemoveAll(path)
+		return nil
+	}
+	if err := ptw.unmarshal(data); err != nil {
+		logger.Errorf("discarding %s, since it cannot be unmarshaled: %s", path, err)
+		fs.MustRemoveAll(path)
+		return nil
+	}
+	return ptw
+}
+
+// AddExemplars adds the given ers to s.
+//
+// Exemplars with timestamps outside the retention are dropped.
+// The number of stored exemplars is limited by SetMaxExemplars.
+func (s *Storage) AddExemplars(ers []ExemplarRow) {
+	if len(ers) == 0 || maxExemplars <= 0 {
+		return
+	}
+	minTimestamp, maxTimestamp := s.tb.getMinMaxTimestamps()
+	s.exemplars.add(ers, minTimestamp, maxTimestamp)
+}
+
+// SearchExemplars returns exemplars for time series matching the given tfss on the given tr.
+//
+// maxMetrics limits the number of time series, which can be scanned during the search.
+func (s *Storage) SearchExemplars(qt *querytracer.Tracer, tfss []*TagFilters, tr TimeRange, maxMetrics int, deadline uint64) ([]ExemplarSeries, error) {
+	qt = qt.NewChild("search for exemplars: filters=%s, timeRange=%s", tfss, &tr)
+	defer qt.Done()
+
+	metricNames, err := s.SearchMetricNames(qt, tfss, tr, maxMetrics, deadline)
+	if err != nil {
+		return nil, err
+	}
+	ess := s.exemplars.search(metricNames, tr)
+	qt.Printf("found exemplars for %d out of %d series", len(ess), len(metricNames))
+	return ess, nil
+}
@@ -0,0 +1,336 @@
+package storage
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fs"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompb"
+)
+
+func TestExemplarTable(t *testing.T) {
+	path := "TestExemplarTable"
+	defer fs.MustRemoveAll(path)
+
+	origMaxExemplars := maxExemplars
+	defer SetMaxExemplars(origMaxExemplars)
+	SetMaxExemplars(5)
+
+	const hourMsecs = 3600 * 1000
+	metricNameRawFoo := MarshalMetricNameRaw(nil, []prompb.Label{
+		{Name: "job", Value: "app"},
+		{Name: "__name__", Value: "foo"},
+	})
+	metricNameRawBar := MarshalMetricNameRaw(nil, []prompb.Label{
+		{Name: "__name__", Value: "bar"},
+	})
+	newExemplarRow := func(metricNameRaw []byte, traceID string, value float64, timestamp int64) ExemplarRow {
+		return ExemplarRow{
+			MetricNameRaw: metricNameRaw,
+			Exemplar: Exemplar{
+				Labels: []prompb.Label{
+					{Name: "trace_id", Value: traceID},
+				},
+				Value:     value,
+				Timestamp: timestamp,
+			},
+		}
+	}
+	metricNameFoo := getExemplarMetricName(metricNameRawFoo)
+	metricNameBar := getExemplarMetricName(metricNameRawBar)
+	fullTimeRange := TimeRange{
+		MinTimestamp: 0,
+		MaxTimestamp: 10 * hourMsecs,
+	}
+
+	tb := mustOpenExemplarTable(path, 10*hourMsecs)
+	tb.add([]ExemplarRow{
+		newExemplarRow(metricNameRawFoo, "a", 1, hourMsecs+20),
+		newExemplarRow(metricNameRawFoo, "b", 2, hourMsecs+10),
+		newExemplarRow(metricNameRawBar, "c", 3, 2*hourMsecs),
+
+		// duplicate exemplar must be ignored
+		newExemplarRow(metricNameRawFoo, "a", 1, hourMsecs+20),
+
+		// exemplar outside the allowed time range must be dropped
+		newExemplarRow(metricNameRawFoo, "d", 4, 11*hourMsecs),
+	}, 0, 10*hourMsecs)
+	if tb.exemplarsCount != 3 {
+		t.Fatalf("unexpected number of exemplars; got %d; want 3", tb.exemplarsCount)
+	}
+
+	f := func(metricNames []string, tr TimeRange, essExpected []ExemplarSeries) {
+		t.Helper()
+		ess := tb.search(metricNames, tr)
+		if !reflect.DeepEqual(ess, essExpected) {
+			t.Fatalf("unexpected exemplars\ngot\n%v\nwant\n%v", ess, essExpected)
+		}
+	}
+	essFoo := ExemplarSeries{
+		MetricName: metricNameFoo,
+		Exemplars: []Exemplar{
+			newExemplarRow(nil, "b", 2, hourMsecs+10).Exemplar,
+			newExemplarRow(nil, "a", 1, hourMsecs+20).Exemplar,
+		},
+	}
+	essBar := ExemplarSeries{
+		MetricName: metricNameBar,
+		Exemplars: []Exemplar{
+			newExemplarRow(nil, "c", 3, 2*hourMsecs).Exemplar,
+		},
+	}
+	f([]string{metricNameFoo, metricNameBar}, fullTimeRange, []ExemplarSeries{essFoo, essBar})
+	f([]string{metricNameBar}, fullTimeRange, []ExemplarSeries{essBar})
+	f([]string{metricNameFoo}, TimeRange{
+		MinTimestamp: hourMsecs + 15,
+		MaxTimestamp: 2 * hourMsecs,
+	}, []ExemplarSeries{
+		{
+			MetricName: metricNameFoo,
+			Exemplars:  essFoo.Exemplars[1:],
+		},
+	})
+	f([]string{metricNameFoo, metricNameBar}, TimeRange{
+		MinTimestamp: 3 * hourMsecs,
+		MaxTimestamp: 4 * hourMsecs,
+	}, nil)
+
+	// Verify that exemplars are persisted
+	tb.MustClose()
+	tb = mustOpenExemplarTable(path, 10*hourMsecs)
+	if tb.exemplarsCount != 3 {
+		t.Fatalf("unexpected number of exemplars after re-opening; got %d; want 3", tb.exemplarsCount)
+	}
+	f([]string{metricNameFoo, metricNameBar}, fullTimeRange, []ExemplarSeries{essFoo, essBar})
+
+	// Verify that the oldest partition is dropped when the limit is reached
+	tb.add([]ExemplarRow{
+		newExemplarRow(metricNameRawBar, "e", 5, 3*hourMsecs),
+		newExemplarRow(metricNameRawBar, "f", 6, 3*hourMsecs+1),
+		newExemplarRow(metricNameRawBar, "g", 7, 3*hourMsecs+2),
+	}, 0, 10*hourMsecs)
+	if tb.exemplarsCount != 4 {
+		t.Fatalf("unexpected number of exemplars after reaching the limit; got %d; want 4", tb.exemplarsCount)
+	}
+	f([]string{metricNameFoo}, fullTimeRange, nil)
+
+	// Verify that exemplars older than the stored exemplars are dropped when the limit is reached
+	tb.add([]ExemplarRow{
+		newExemplarRow(metricNameRawFoo, "h", 8, 3*hourMsecs),
+		newExemplarRow(metricNameRawFoo, "i", 9, 2*hourMsecs),
+	}, 0, 10*hourMsecs)
+	if tb.exemplarsCount != 5 {
+		t.Fatalf("unexpected number of exemplars; got %d; want 5", tb.exemplarsCount)
+	}
+	f([]string{metricNameFoo}, fullTimeRange, []ExemplarSeries{
+		{
+			MetricName: metricNameFoo,
+			Exemplars: []Exemplar{
+				newExemplarRow(nil, "h", 8, 3*hourMsecs).Exemplar,
+			},
+		},
+	})
+
+	// Verify that stale partitions are dropped
+	tb.mu.Lock()
+	tb.dropStalePartitionsLocked(3 * hourMsecs)
+	tb.mu.Unlock()
+	if tb.exemplarsCount != 4 {
+		t.Fatalf("unexpected number of exemplars after dropping stale partitions; got %d; want 4", tb.exemplarsCount)
+	}
+	tb.mu.Lock()
+	tb.dropStalePartitionsLocked(4 * hourMsecs)
+	tb.mu.Unlock()
+	if tb.exemplarsCount != 0 {
+		t.Fatalf("unexpected number of exemplars after dropping all the partitions; got %d; want 0", tb.exemplarsCount)
+	}
+	tb.MustClose()
+
+	tb = mustOpenExemplarTable(path, 10*hourMsecs)
+	if tb.exemplarsCount != 0 {
+		t.Fatalf("unexpected number of exemplars after re-opening; got %d; want 0", tb.exemplarsCount)
+	}
+	tb.MustClose()
+}
+
+func TestExemplarTableEvictOldest(t *testing.T) {
+	path := "TestExemplarTableEvictOldest"
+	defer fs.MustRemoveAll(path)
+
+	origMaxExemplars := maxExemplars
+	defer SetMaxExemplars(origMaxExemplars)
+	SetMaxExemplars(20)
+
+	const hourMsecs = 3600 * 1000
+	metricNameRaw := MarshalMetricNameRaw(nil, []prompb.Label{
+		{Name: "__name__", Value: "foo"},
+	})
+	newExemplarRow := func(timestamp int64) ExemplarRow {
+		return ExemplarRow{
+			MetricNameRaw: metricNameRaw,
+			Exemplar: Exemplar{
+				Labels: []prompb.Label{
+					{Name: "trace_id", Value: fmt.Sprintf("trace-%d", timestamp)},
+				},
+				Timestamp: timestamp,
+			},
+		}
+	}
+	tr := TimeRange{
+		MinTimestamp: 0,
+		MaxTimestamp: 10 * hourMsecs,
+	}
+
+	tb := mustOpenExemplarTable(path, 10*hourMsecs)
+
+	// Fill the limit with exemplars for the same hour
+	var ers []ExemplarRow
+	for i := 0; i < 20; i++ {
+		ers = append(ers, newExemplarRow(hourMsecs+int64(i)))
+	}
+	tb.add(ers, 0, 10*hourMsecs)
+	if tb.exemplarsCount != 20 {
+		t.Fatalf("unexpected number of exemplars; got %d; want 20", tb.exemplarsCount)
+	}
+
+	// New exemplars for the same hour must evict the oldest exemplars instead of being rejected
+	tb.add([]ExemplarRow{
+		newExemplarRow(hourMsecs + 100),
+		newExemplarRow(hourMsecs + 101),
+		newExemplarRow(hourMsecs + 102),
+	}, 0, 10*hourMsecs)
+	ess := tb.search([]string{getExemplarMetricName(metricNameRaw)}, tr)
+	if len(ess) != 1 {
+		t.Fatalf("unexpected number of series; got %d; want 1", len(ess))
+	}
+	es := ess[0].Exemplars
+	if len(es) != tb.exemplarsCount || len(es) > 20 {
+		t.Fatalf("unexpected number of exemplars; got %d; want %d", len(es), tb.exemplarsCount)
+	}
+	if n := es[len(es)-1].Timestamp; n != hourMsecs+102 {
+		t.Fatalf("unexpected timestamp for the newest exemplar; got %d; want %d", n, hourMsecs+102)
+	}
+	if n := es[0].Timestamp; n == hourMsecs {
+		t.Fatalf("the oldest exemplar must be evicted")
+	}
+
+	// Fill the limit again
+	for tb.exemplarsCount < 20 {
+		tb.add([]ExemplarRow{
+			newExemplarRow(hourMsecs + 200 + int64(tb.exemplarsCount)),
+		}, 0, 10*hourMsecs)
+	}
+
+	// Exemplars older than the stored exemplars must be dropped when the limit is reached
+	tb.add([]ExemplarRow{
+		newExemplarRow(hourMsecs),
+		newExemplarRow(hourMsecs - 1),
+	}, 0, 10*hourMsecs)
+	if tb.exemplarsCount != 20 {
+		t.Fatalf("unexpected number of exemplars; got %d; want 20", tb.exemplarsCount)
+	}
+	ess = tb.search([]string{getExemplarMetricName(metricNameRaw)}, tr)
+	if n := ess[0].Exemplars[0].Timestamp; n <= hourMsecs {
+		t.Fatalf("unexpected exemplar with timestamp %d", n)
+	}
+
+	// Verify that the remaining exemplars are persisted
+	exemplarsCount := tb.exemplarsCount
+	tb.MustClose()
+	tb = mustOpenExemplarTable(path, 10*hourMsecs)
+	if tb.exemplarsCount != exemplarsCount {
+		t.Fatalf("unexpected number of exemplars after re-opening; got %d; want %d", tb.exemplarsCount, exemplarsCount)
+	}
+	tb.MustClose()
+}
+
+func getExemplarMetricName(metricNameRaw []byte) string {
+	var mn MetricName
+	if err := mn.UnmarshalRaw(metricNameRaw); err != nil {
+		panic(fmt.Errorf("cannot unmarshal metricNameRaw: %w", err))
+	}
+	mn.sortTags()
+	return string(mn.Marshal(nil))
+}
+
+func TestStorageSearchExemplars(t *testing.T) {
+	path := "TestStorageSearchExemplars"
+	defer fs.MustRemoveAll(path)
+
+	s := MustOpenStorage(path, 0, 0, 0)
+
+	timestamp := time.Now().UnixMilli()
+	var mrs []MetricRow
+	var ers []ExemplarRow
+	for i := 0; i < 10; i++ {
+		metricNameRaw := MarshalMetricNameRaw(nil, []prompb.Label{
+			{Name: "__name__", Value: "http_request_duration_seconds_bucket"},
+			{Name: "instance", Value: fmt.Sprintf("host-%d", i)},
+		})
+		mrs = append(mrs, MetricRow{
+			MetricNameRaw: metricNameRaw,
+			Timestamp:     timestamp,
+			Value:         float64(i),
+		})
+		if i%2 == 0 {
+			ers = append(ers, ExemplarRow{
+				MetricNameRaw: metricNameRaw,
+				Exemplar: Exemplar{
+					Labels: []prompb.Label{
+						{Name: "trace_id", Value: fmt.Sprintf("trace-%d", i)},
+					},
+					Value:     float64(i),
+					Timestamp: timestamp,
+				},
+			})
+		}
+	}
+	if err := s.AddRows(mrs, defaultPrecisionBits); err != nil {
+		t.Fatalf("unexpected error when adding rows: %s", err)
+	}
+	s.AddExemplars(ers)
+	s.DebugFlush()
+
+	tfs := NewTagFilters()
+	if err := tfs.Add(nil, []byte("http_request_duration_seconds_bucket"), false, false); err != nil {
+		t.Fatalf("cannot add tag filter: %s", err)
+	}
+	if err := tfs.Add([]byte("instance"), []byte("host-[0-3]"), false, true); err != nil {
+		t.Fatalf("cannot add tag filter: %s", err)
+	}
+	tr := TimeRange{
+		MinTimestamp: timestamp - 3600*1000,
+		MaxTimestamp: timestamp + 3600*1000,
+	}
+	ess, err := s.SearchExemplars(nil, []*TagFilters{tfs}, tr, 1e5, noDeadline)
+	if err != nil {
+		t.Fatalf("unexpected error when searching for exemplars: %s", err)
+	}
+	var traceIDs []string
+	for _, es := range ess {
+		for _, e := range es.Exemplars {
+			traceIDs = append(traceIDs, e.Labels[0].Value)
+		}
+	}
+	traceIDsExpected := map[string]bool{
+		"trace-0": true,
+		"trace-2": true,
+	}
+	if len(traceIDs) != len(traceIDsExpected) {
+		t.Fatalf("unexpected exemplars found; got %q; want %v", traceIDs, traceIDsExpected)
+	}
+	for _, traceID := range traceIDs {
+		if !traceIDsExpected[traceID] {
+			t.Fatalf("unexpected exemplar with trace_id=%q", traceID)
+		}
+	}
+
+	var m Metrics
+	s.UpdateMetrics(&m)
+	if m.ExemplarsCount != uint64(len(ers)) {
+		t.Fatalf("unexpected number of stored exemplars; got %d; want %d", m.ExemplarsCount, len(ers))
+	}
+	s.MustClose()
+}
@@ -16,9 +16,11 @@ const (
 	smallDirname = "small"
 	bigDirname   = "big"
 
-	indexdbDirname   = "indexdb"
-	dataDirname      = "data"
-	metadataDirname  = "metadata"
-	snapshotsDirname = "snapshots"
-	cacheDirname     = "cache"
+	indexdbDirname        = "indexdb"
+	dataDirname           = "data"
+	metadataDirname       = "metadata"
+	exemplarsDirname      = "exemplars"
+	metricMetadataDirname = "metricMetadata"
+	snapshotsDirname      = "snapshots"
+	cacheDirname          = "cache"
 )
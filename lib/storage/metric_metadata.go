@@ -0,0 +1,455 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/encoding"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fasttime"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fs"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompb"
+)
+
+// MetricMetadata contains metadata for a metric family.
+type MetricMetadata struct {
+	// MetricFamilyName is the name of the metric family.
+	MetricFamilyName string
+
+	// Type is the metric type.
+	Type prompb.MetricType
+
+	// Help is the help text for the metric family.
+	Help string
+
+	// Unit is the unit of the metric family.
+	Unit string
+
+	// LastSeenTimestamp is unix timestamp in seconds when the metadata has been received last time.
+	LastSeenTimestamp uint64
+}
+
+// maxMetricMetadataPerMetric is the maximum number of distinct metadata entries, which can be stored per metric family.
+//
+// Distinct metadata entries for the same metric family may be received from different targets.
+// The least recently seen entry is replaced with the new entry when the limit is reached.
+const maxMetricMetadataPerMetric = 10
+
+// metricMetadataLastSeenPrecisionSecs is the precision for lastSeenTimestamp of metric metadata entries.
+//
+// This reduces the frequency of flushing metric metadata to disk when the same metadata is received on every scrape.
+const metricMetadataLastSeenPrecisionSecs = 60
+
+const metricMetadataFilename = "metric_metadata.bin"
+
+// maxMetricMetadataFamilies is the maximum number of metric families, which can have metadata in the storage.
+var maxMetricMetadataFamilies = 100_000
+
+// SetMaxMetricMetadataFamilies sets the maximum number of metric families, which can have metadata in the storage.
+//
+// Metadata for new metric families is dropped when the limit is reached. Metric metadata isn't stored if n <= 0.
+//
+// This function must be called before initializing the storage.
+func SetMaxMetricMetadataFamilies(n int) {
+	maxMetricMetadataFamilies = n
+}
+
+// metricMetadataTable holds metadata for metric families.
+type metricMetadataTable struct {
+	path           string
+	retentionMsecs int64
+
+	// flushMu prevents from concurrent flushes of metric metadata to disk.
+	//
+	// It must be locked before mu.
+	flushMu sync.Mutex
+
+	// mu protects the fields below.
+	mu sync.Mutex
+
+	// m maps metric family name to metadata entries for it.
+	m map[string][]metricMetadataEntry
+
+	entriesCount int
+	sizeBytes    int
+
+	// droppedCount is the number of metadata entries dropped because of maxMetricMetadataFamilies limit.
+	droppedCount uint64
+
+	// isDirty is set to true if m has been changed since the last flush to disk.
+	isDirty bool
+
+	stopCh chan struct{}
+
+	flusherWG sync.WaitGroup
+}
+
+type metricMetadataEntry struct {
+	typ               prompb.MetricType
+	help              string
+	unit              string
+	lastSeenTimestamp uint64
+}
+
+func (e *metricMetadataEntry) sizeBytes() int {
+	return 48 + len(e.help) + len(e.unit)
+}
+
+func mustOpenMetricMetadataTable(path string, retentionMsecs int64) *metricMetadataTable {
+	fs.MustMkdirIfNotExist(path)
+
+	t := &metricMetadataTable{
+		path:           path,
+		retentionMsecs: retentionMsecs,
+		m:              make(map[string][]metricMetadataEntry),
+		stopCh:         make(chan struct{}),
+	}
+	for _, de := range fs.MustReadDir(path) {
+		fn := de.Name()
+		if fs.IsTemporaryFileName(fn) {
+			fs.MustRemoveAll(filepath.Join(path, fn))
+		}
+	}
+	t.mustLoad()
+
+	t.flusherWG.Add(1)
+	go func() {
+		t.flusher()
+		t.flusherWG.Done()
+	}()
+	return t
+}
+
+// MustClose stops background flusher and flushes metric metadata to disk.
+func (t *metricMetadataTable) MustClose() {
+	close(t.stopCh)
+	t.flusherWG.Wait()
+
+	t.mustFlush()
+}
+
+func (t *metricMetadataTable) flusher() {
+	d := dataFlushInterval
+	ticker := time.NewTicker(d)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-t.stopCh:
+			return
+		case <-ticker.C:
+			minTimestamp := int64(fasttime.UnixTimestamp()) - t.retentionMsecs/1000
+			t.mu.Lock()
+			t.dropStaleEntriesLocked(minTimestamp)
+			t.mu.Unlock()
+			t.mustFlush()
+		}
+	}
+}
+
+// mustFlush writes metric metadata to disk if it has been changed since the last flush.
+//
+// The metadata is marshaled under t.mu lock, while the marshaled data is compressed and written to disk without holding t.mu,
+// so concurrent add and search calls aren't blocked by disk IO.
+func (t *metricMetadataTable) mustFlush() {
+	t.flushMu.Lock()
+	defer t.flushMu.Unlock()
+
+	t.mu.Lock()
+	if !t.isDirty {
+		t.mu.Unlock()
+		return
+	}
+	data := t.marshalLocked(nil)
+	t.isDirty = false
+	t.mu.Unlock()
+
+	data = encoding.CompressZSTDLevel(nil, data, 1)
+	fs.MustWriteAtomic(filepath.Join(t.path, metricMetadataFilename), data, true)
+}
+
+// mustCreateSnapshotAt copies metric metadata to dstDir.
+func (t *metricMetadataTable) mustCreateSnapshotAt(dstDir string) {
+	t.mustFlush()
+
+	// Prevent from writing metric metadata file while it is copied.
+	t.flushMu.Lock()
+	defer t.flushMu.Unlock()
+
+	fs.MustCopyDirectory(t.path, dstDir)
+}
+
+// dropStaleEntriesLocked drops entries, which weren't seen since the given minTimestamp in seconds.
+func (t *metricMetadataTable) dropStaleEntriesLocked(minTimestamp int64) {
+	if minTimestamp <= 0 {
+		return
+	}
+	for name, es := range t.m {
+		esNew := es[:0]
+		for _, e := range es {
+			if int64(e.lastSeenTimestamp) >= minTimestamp {
+				esNew = append(esNew, e)
+				continue
+			}
+			t.entriesCount--
+			t.sizeBytes -= e.sizeBytes()
+			t.isDirty = true
+		}
+		clear(es[len(esNew):])
+		if len(esNew) == 0 {
+			delete(t.m, name)
+			t.sizeBytes -= len(name)
+			continue
+		}
+		t.m[name] = esNew
+	}
+}
+
+// add adds mms seen at the given timestamp in seconds to t.
+func (t *metricMetadataTable) add(mms []prompb.MetricMetadata, timestamp uint64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for i := range mms {
+		t.addLocked(&mms[i], timestamp)
+	}
+}
+
+func (t *metricMetadataTable) addLocked(mm *prompb.MetricMetadata, timestamp uint64) {
+	if len(mm.MetricFamilyName) == 0 {
+		return
+	}
+	es := t.m[mm.MetricFamilyName]
+	for i := range es {
+		e := &es[i]
+		if e.typ == mm.Type && e.help == mm.Help && e.unit == mm.Unit {
+			if timestamp >= e.lastSeenTimestamp+metricMetadataLastSeenPrecisionSecs {
+				e.lastSeenTimestamp = timestamp
+				t.isDirty = true
+			}
+			return
+		}
+	}
+
+	if len(es) == 0 && len(t.m) >= maxMetricMetadataFamilies {
+		// There is no space for a new metric family.
+		t.droppedCount++
+		return
+	}
+
+	// Copy strings, since they may refer to the memory owned by the caller.
+	e := metricMetadataEntry{
+		typ:               mm.Type,
+		help:              strings.Clone(mm.Help),
+		unit:              strings.Clone(mm.Unit),
+		lastSeenTimestamp: timestamp,
+	}
+	// Always copy the name, since the map assignment below replaces the existing key with name,
+	// while name may refer to the memory owned by the caller.
+	name := strings.Clone(mm.MetricFamilyName)
+	if len(es) == 0 {
+		t.sizeBytes += len(name)
+	}
+	if len(es) >= maxMetricMetadataPerMetric {
+		// Replace the least recently seen entry.
+		idx := 0
+		for i := range es {
+			if es[i].lastSeenTimestamp < es[idx].lastSeenTimestamp {
+				idx = i
+			}
+		}
+		t.sizeBytes -= es[idx].sizeBytes()
+		es[idx] = e
+	} else {
+		es = append(es, e)
+		t.entriesCount++
+	}
+	t.sizeBytes += e.sizeBytes()
+	t.m[name] = es
+	t.isDirty = true
+}
+
+// search returns metadata for the given metricFamilyName.
+//
+// Metadata for all the metric families is returned if metricFamilyName is empty.
+// limit limits the number of returned metric families, while limitPerMetric limits the number of returned entries per metric family.
+// There are no limits if they are set to values smaller or equal to 0.
+//
+// The returned metadata is sorted by metric family name. Entries for the same metric family are sorted by LastSeenTimestamp in descending order.
+func (t *metricMetadataTable) search(metricFamilyName string, limit, limitPerMetric int) []MetricMetadata {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var names []string
+	if metricFamilyName != "" {
+		if _, ok := t.m[metricFamilyName]; ok {
+			names = append(names, metricFamilyName)
+		}
+	} else {
+		names = make([]string, 0, len(t.m))
+		for name := range t.m {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	}
+	if limit > 0 && len(names) > limit {
+		names = names[:limit]
+	}
+
+	var mms []MetricMetadata
+	for _, name := range names {
+		mmsLen := len(mms)
+		for _, e := range t.m[name] {
+			mms = append(mms, MetricMetadata{
+				MetricFamilyName:  name,
+				Type:              e.typ,
+				Help:              e.help,
+				Unit:              e.unit,
+				LastSeenTimestamp: e.lastSeenTimestamp,
+			})
+		}
+		a := mms[mmsLen:]
+		sort.SliceStable(a, func(i, j int) bool {
+			return a[i].LastSeenTimestamp > a[j].LastSeenTimestamp
+		})
+		if limitPerMetric > 0 && len(a) > limitPerMetric {
+			mms = mms[:mmsLen+limitPerMetric]
+		}
+	}
+	return mms
+}
+
+func (t *metricMetadataTable) updateMetrics(m *Metrics) {
+	t.mu.Lock()
+	m.MetricMetadataEntries += uint64(t.entriesCount)
+	m.MetricMetadataSizeBytes += uint64(t.sizeBytes)
+	m.MetricMetadataDroppedTotal += t.droppedCount
+	t.mu.Unlock()
+}
+
+func (t *metricMetadataTable) marshalLocked(dst []byte) []byte {
+	dst = encoding.MarshalVarUint64(dst, uint64(len(t.m)))
+	for name, es := range t.m {
+		dst = encoding.MarshalBytes(dst, []byte(name))
+		dst = encoding.MarshalVarUint64(dst, uint64(len(es)))
+		for i := range es {
+			e := &es[i]
+			dst = encoding.MarshalVarUint64(dst, uint64(e.typ))
+			dst = encoding.MarshalBytes(dst, []byte(e.help))
+			dst = encoding.MarshalBytes(dst, []byte(e.unit))
+			dst = encoding.MarshalVarUint64(dst, e.lastSeenTimestamp)
+		}
+	}
+	return dst
+}
+
+func (t *metricMetadataTable) unmarshal(src []byte) error {
+	namesCount, nSize := encoding.UnmarshalVarUint64(src)
+	if nSize <= 0 {
+		return fmt.Errorf("cannot unmarshal metric families count")
+	}
+	src = src[nSize:]
+	var mm prompb.MetricMetadata
+	for i := uint64(0); i < namesCount; i++ {
+		name, nSize := encoding.UnmarshalBytes(src)
+		if nSize <= 0 {
+			return fmt.Errorf("cannot unmarshal name for metric family #%d", i)
+		}
+		src = src[nSize:]
+		entriesCount, nSize := encoding.UnmarshalVarUint64(src)
+		if nSize <= 0 {
+			return fmt.Errorf("cannot unmarshal entries count for metric family #%d", i)
+		}
+		src = src[nSize:]
+		for j := uint64(0); j < entriesCount; j++ {
+			typ, nSize := encoding.UnmarshalVarUint64(src)
+			if nSize <= 0 {
+				return fmt.Errorf("cannot unmarshal type for entry #%d at metric family #%d", j, i)
+			}
+			src = src[nSize:]
+			help, nSize := encoding.UnmarshalBytes(src)
+			if nSize <= 0 {
+				return fmt.Errorf("cannot unmarshal help for entry #%d at metric family #%d", j, i)
+			}
+			src = src[nSize:]
+			unit, nSize := encoding.UnmarshalBytes(src)
+			if nSize <= 0 {
+				return fmt.Errorf("cannot unmarshal unit for entry #%d at metric family #%d", j, i)
+			}
+			src = src[nSize:]
+			lastSeenTimestamp, nSize := encoding.UnmarshalVarUint64(src)
+			if nSize <= 0 {
+				return fmt.Errorf("cannot unmarshal last seen timestamp for entry #%d at metric family #%d", j, i)
+			}
+			src = src[nSize:]
+
+			// There is no need in copying name, help and unit, since addLocked copies them.
+			mm = prompb.MetricMetadata{
+				Type:             prompb.MetricType(typ),
+				MetricFamilyName: bytesutil.ToUnsafeString(name),
+				Help:             bytesutil.ToUnsafeString(help),
+				Unit:             bytesutil.ToUnsafeString(unit),
+			}
+			t.addLocked(&mm, lastSeenTimestamp)
+		}
+	}
+	if len(src) > 0 {
+		return fmt.Errorf("unexpected non-empty tail left after unmarshaling metric metadata; len(tail)=%d", len(src))
+	}
+	t.isDirty = false
+	return nil
+}
+
+// mustLoad loads metric metadata from t.path.
+//
+// The metric metadata file is discarded if it cannot be loaded.
+func (t *metricMetadataTable) mustLoad() {
+	path := filepath.Join(t.path, metricMetadataFilename)
+	if !fs.IsPathExist(path) {
+		return
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		logger.Panicf("FATAL: cannot read %s: %s", path, err)
+	}
+	data, err = encoding.DecompressZSTD(nil, data)
+	if err != nil {
+		logger.Errorf("discarding %s, since it cannot be decompressed: %s", path, err)
+		fs.MustRemoveAll(path)
+		return
+	}
+	if err := t.unmarshal(data); err != nil {
+		logger.Errorf("discarding %s, since it cannot be unmarshaled: %s", path, err)
+		fs.MustRemoveAll(path)
+		t.m = make(map[string][]metricMetadataEntry)
+		t.entriesCount = 0
+		t.sizeBytes = 0
+		return
+	}
+}
+
+// AddMetricMetadata adds the given mms to s.
+//
+// The last seen timestamp for the stored metadata is updated if it already exists in s.
+func (s *Storage) AddMetricMetadata(mms []prompb.MetricMetadata) {
+	if len(mms) == 0 {
+		return
+	}
+	s.metricMetadata.add(mms, fasttime.UnixTimestamp())
+}
+
+// SearchMetricMetadata returns metadata for the given metricFamilyName.
+//
+// Metadata for all the metric families is returned if metricFamilyName is empty.
+// limit limits the number of returned metric families, while limitPerMetric limits the number of returned entries per metric family.
+// There are no limits if they are set to values smaller or equal to 0.
+//
+// The returned metadata is sorted by metric family name. Entries for the same metric family are sorted by LastSeenTimestamp in descending order.
+func (s *Storage) SearchMetricMetadata(metricFamilyName string, limit, limitPerMetric int) []MetricMetadata {
+	return s.metricMetadata.search(metricFamilyName, limit, limitPerMetric)
+}
@@ -0,0 +1,224 @@
+package storage
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fs"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompb"
+)
+
+func TestMetricMetadataTable(t *testing.T) {
+	path := "TestMetricMetadataTable"
+	defer fs.MustRemoveAll(path)
+
+	tb := mustOpenMetricMetadataTable(path, 0)
+	tb.add([]prompb.MetricMetadata{
+		{
+			MetricFamilyName: "foo",
+			Type:             prompb.MetricTypeCounter,
+			Help:             "foo help",
+		},
+		{
+			MetricFamilyName: "bar",
+			Type:             prompb.MetricTypeGauge,
+			Help:             "bar help",
+			Unit:             "seconds",
+		},
+
+		// metadata without metric family name must be ignored
+		{
+			Type: prompb.MetricTypeGauge,
+			Help: "missing name",
+		},
+	}, 1000)
+
+	// duplicate metadata must update the last seen timestamp only
+	tb.add([]prompb.MetricMetadata{
+		{
+			MetricFamilyName: "foo",
+			Type:             prompb.MetricTypeCounter,
+			Help:             "foo help",
+		},
+	}, 2000)
+
+	// distinct metadata for the same metric family must be stored separately
+	tb.add([]prompb.MetricMetadata{
+		{
+			MetricFamilyName: "foo",
+			Type:             prompb.MetricTypeCounter,
+			Help:             "another foo help",
+		},
+	}, 1500)
+	if tb.entriesCount != 3 {
+		t.Fatalf("unexpected number of entries; got %d; want 3", tb.entriesCount)
+	}
+
+	f := func(metricFamilyName string, limit, limitPerMetric int, mmsExpected []MetricMetadata) {
+		t.Helper()
+		mms := tb.search(metricFamilyName, limit, limitPerMetric)
+		if !reflect.DeepEqual(mms, mmsExpected) {
+			t.Fatalf("unexpected metadata\ngot\n%v\nwant\n%v", mms, mmsExpected)
+		}
+	}
+	mmBar := MetricMetadata{
+		MetricFamilyName:  "bar",
+		Type:              prompb.MetricTypeGauge,
+		Help:              "bar help",
+		Unit:              "seconds",
+		LastSeenTimestamp: 1000,
+	}
+	mmFoo := MetricMetadata{
+		MetricFamilyName:  "foo",
+		Type:              prompb.MetricTypeCounter,
+		Help:              "foo help",
+		LastSeenTimestamp: 2000,
+	}
+	mmFooAnother := MetricMetadata{
+		MetricFamilyName:  "foo",
+		Type:              prompb.MetricTypeCounter,
+		Help:              "another foo help",
+		LastSeenTimestamp: 1500,
+	}
+	f("", 0, 0, []MetricMetadata{mmBar, mmFoo, mmFooAnother})
+	f("", 1, 0, []MetricMetadata{mmBar})
+	f("", 0, 1, []MetricMetadata{mmBar, mmFoo})
+	f("foo", 0, 0, []MetricMetadata{mmFoo, mmFooAnother})
+	f("foo", 0, 1, []MetricMetadata{mmFoo})
+	f("missing", 0, 0, nil)
+
+	// Verify that metadata is persisted
+	tb.MustClose()
+	tb = mustOpenMetricMetadataTable(path, 0)
+	if tb.entriesCount != 3 {
+		t.Fatalf("unexpected number of entries after re-opening; got %d; want 3", tb.entriesCount)
+	}
+	f("", 0, 0, []MetricMetadata{mmBar, mmFoo, mmFooAnother})
+
+	// Verify that the least recently seen entry is replaced when the limit per metric family is reached
+	var mms []prompb.MetricMetadata
+	for i := 0; i < maxMetricMetadataPerMetric; i++ {
+		mms = append(mms, prompb.MetricMetadata{
+			MetricFamilyName: "bar",
+			Type:             prompb.MetricTypeGauge,
+			Help:             fmt.Sprintf("bar help %d", i),
+		})
+	}
+	tb.add(mms, 3000)
+	if tb.entriesCount != maxMetricMetadataPerMetric+2 {
+		t.Fatalf("unexpected number of entries; got %d; want %d", tb.entriesCount, maxMetricMetadataPerMetric+2)
+	}
+	for _, mm := range tb.search("bar", 0, 0) {
+		if mm.Help == mmBar.Help {
+			t.Fatalf("the least recently seen entry must be replaced; got %v", mm)
+		}
+	}
+
+	// Verify that stale entries are dropped
+	tb.mu.Lock()
+	tb.dropStaleEntriesLocked(1800)
+	tb.mu.Unlock()
+	if tb.entriesCount != maxMetricMetadataPerMetric+1 {
+		t.Fatalf("unexpected number of entries after dropping stale entries; got %d; want %d", tb.entriesCount, maxMetricMetadataPerMetric+1)
+	}
+	f("foo", 0, 0, []MetricMetadata{mmFoo})
+	tb.mu.Lock()
+	tb.dropStaleEntriesLocked(4000)
+	tb.mu.Unlock()
+	if tb.entriesCount != 0 || tb.sizeBytes != 0 {
+		t.Fatalf("unexpected state after dropping all the entries; entriesCount=%d, sizeBytes=%d; want zeros", tb.entriesCount, tb.sizeBytes)
+	}
+	tb.MustClose()
+
+	tb = mustOpenMetricMetadataTable(path, 0)
+	if tb.entriesCount != 0 {
+		t.Fatalf("unexpected number of entries after re-opening; got %d; want 0", tb.entriesCount)
+	}
+	tb.MustClose()
+}
+
+func TestMetricMetadataTableAddNameFromBuffer(t *testing.T) {
+	path := "TestMetricMetadataTableAddNameFromBuffer"
+	defer fs.MustRemoveAll(path)
+
+	tb := mustOpenMetricMetadataTable(path, 0)
+	defer tb.MustClose()
+
+	// Add distinct metadata entries for the same metric family with the name referring to the reused buffer.
+	buf := []byte("foo")
+	for i := 0; i < 3; i++ {
+		copy(buf, "foo")
+		tb.add([]prompb.MetricMetadata{
+			{
+				MetricFamilyName: bytesutil.ToUnsafeString(buf),
+				Type:             prompb.MetricTypeCounter,
+				Help:             fmt.Sprintf("help %d", i),
+			},
+		}, 1000)
+	}
+
+	// Modify the buffer. This mustn't affect the stored metric family name.
+	copy(buf, "bar")
+
+	mms := tb.search("", 0, 0)
+	if len(mms) != 3 {
+		t.Fatalf("unexpected number of entries; got %d; want 3", len(mms))
+	}
+	for _, mm := range mms {
+		if mm.MetricFamilyName != "foo" {
+			t.Fatalf("unexpected metric family name; got %q; want %q", mm.MetricFamilyName, "foo")
+		}
+	}
+	if mms := tb.search("foo", 0, 0); len(mms) != 3 {
+		t.Fatalf("unexpected number of entries for foo; got %d; want 3", len(mms))
+	}
+}
+
+func TestMetricMetadataTableMaxFamilies(t *testing.T) {
+	path := "TestMetricMetadataTableMaxFamilies"
+	defer fs.MustRemoveAll(path)
+
+	origMaxMetricMetadataFamilies := maxMetricMetadataFamilies
+	defer SetMaxMetricMetadataFamilies(origMaxMetricMetadataFamilies)
+	SetMaxMetricMetadataFamilies(2)
+
+	tb := mustOpenMetricMetadataTable(path, 0)
+	defer tb.MustClose()
+
+	tb.add([]prompb.MetricMetadata{
+		{
+			MetricFamilyName: "foo",
+			Type:             prompb.MetricTypeCounter,
+		},
+		{
+			MetricFamilyName: "bar",
+			Type:             prompb.MetricTypeGauge,
+		},
+
+		// metadata for a new metric family must be dropped when the limit is reached
+		{
+			MetricFamilyName: "baz",
+			Type:             prompb.MetricTypeGauge,
+		},
+
+		// metadata for the existing metric family must be stored when the limit is reached
+		{
+			MetricFamilyName: "foo",
+			Type:             prompb.MetricTypeGauge,
+		},
+	}, 1000)
+
+	var m Metrics
+	tb.updateMetrics(&m)
+	if m.MetricMetadataEntries != 3 {
+		t.Fatalf("unexpected number of entries; got %d; want 3", m.MetricMetadataEntries)
+	}
+	if m.MetricMetadataDroppedTotal != 1 {
+		t.Fatalf("unexpected number of dropped entries; got %d; want 1", m.MetricMetadataDroppedTotal)
+	}
+	if mms := tb.search("baz", 0, 0); len(mms) != 0 {
+		t.Fatalf("unexpected entries for baz; got %d; want 0", len(mms))
+	}
+}
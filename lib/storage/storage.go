@@ -80,6 +80,12 @@ type Storage struct {
 
 	tb *table
 
+	// exemplars contains exemplars for the stored time series.
+	exemplars *exemplarTable
+
+	// metricMetadata contains metadata for metric families.
+	metricMetadata *metricMetadataTable
+
 	// Series cardinality limiters.
 	hourlySeriesLimiter *bloomfilter.Limiter
 	dailySeriesLimiter  *bloomfilter.Limiter
@@ -280,6 +286,14 @@ func MustOpenStorage(path string, retention time.Duration, maxHourlySeries, maxD
 	tb := mustOpenTable(tablePath, s)
 	s.tb = tb
 
+	// Load exemplars
+	exemplarsPath := filepath.Join(path, exemplarsDirname)
+	s.exemplars = mustOpenExemplarTable(exemplarsPath, s.retentionMsecs)
+
+	// Load metric metadata
+	metricMetadataPath := filepath.Join(path, metricMetadataDirname)
+	s.metricMetadata = mustOpenMetricMetadataTable(metricMetadataPath, s.retentionMsecs)
+
 	s.startCurrHourMetricIDsUpdater()
 	s.startNextDayMetricIDsUpdater()
 	s.startRetentionWatcher()
@@ -374,6 +388,12 @@ func (s *Storage) CreateSnapshot() (string, error) {
 	dstMetadataDir := filepath.Join(dstDir, metadataDirname)
 	fs.MustCopyDirectory(srcMetadataDir, dstMetadataDir)
 
+	dstExemplarsDir := filepath.Join(dstDir, exemplarsDirname)
+	s.exemplars.mustCreateSnapshotAt(dstExemplarsDir)
+
+	dstMetricMetadataDir := filepath.Join(dstDir, metricMetadataDirname)
+	s.metricMetadata.mustCreateSnapshotAt(dstMetricMetadataDir)
+
 	idbSnapshot := filepath.Join(srcDir, indexdbDirname, snapshotsDirname, snapshotName)
 	idb := s.idb()
 	currSnapshot := filepath.Join(idbSnapshot, idb.name)
@@ -541,6 +561,16 @@ type Metrics struct {
 
 	NextRetentionSeconds uint64
 
+	ExemplarsCount        uint64
+	ExemplarsSizeBytes    uint64
+	ExemplarsPartitions   uint64
+	ExemplarsAddedTotal   uint64
+	ExemplarsDroppedTotal uint64
+
+	MetricMetadataEntries      uint64
+	MetricMetadataSizeBytes    uint64
+	MetricMetadataDroppedTotal uint64
+
 	IndexDBMetrics IndexDBMetrics
 	TableMetrics   TableMetrics
 }
@@ -639,6 +669,9 @@ func (s *Storage) UpdateMetrics(m *Metrics) {
 	}
 	m.NextRetentionSeconds = uint64(d)
 
+	s.exemplars.updateMetrics(m)
+	s.metricMetadata.updateMetrics(m)
+
 	s.idb().UpdateMetrics(&m.IndexDBMetrics)
 	s.tb.UpdateMetrics(&m.TableMetrics)
 }
@@ -865,6 +898,8 @@ func (s *Storage) MustClose() {
 
 	s.tb.MustClose()
 	s.idb().MustClose()
+	s.exemplars.MustClose()
+	s.metricMetadata.MustClose()
 
 	// Save caches.
 	s.mustSaveCache(s.tsidCache, "metricName_tsid")